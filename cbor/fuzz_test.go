@@ -0,0 +1,34 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzDecode(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"\xa2\x64name\x65angle\x64list\x83\x01\x20\xf5",
+		"\x9f\x01\x82\x02\x03\xff",
+		"\xbf\x61a\xf6\x61b\xf9\x3c\x00\xff",
+		"\xc1\x1a\x51\x4b\x67\xb0\xc2\x42\x01\x00",
+		"\x7f\x62ab\x61c\xff\x5f\x41\x01\xff",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			list, err := DecodeAll(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			ws := NewWriter(&buf)
+			for _, v := range list {
+				if err := ws.Write(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,426 @@
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	errSyntax = errors.New("syntax error")
+	errType   = errors.New("invalid type")
+)
+
+const (
+	majorUint byte = iota
+	majorNint
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+const (
+	simpleFalse     = 20
+	simpleTrue      = 21
+	simpleNull      = 22
+	simpleUndefined = 23
+	simpleFloat16   = 25
+	simpleFloat32   = 26
+	simpleFloat64   = 27
+	indefinite      = 31
+	breakCode       = 0xff
+)
+
+const (
+	tagDateString = 0
+	tagDateEpoch  = 1
+	tagBignum     = 2
+	tagNegBignum  = 3
+)
+
+const maxPrealloc = 1024
+
+func Decode(r io.Reader) (any, error) {
+	return NewParser(r).Parse()
+}
+
+func DecodeAll(r io.Reader) ([]any, error) {
+	return NewParser(r).ParseAll()
+}
+
+type Parser struct {
+	reader *bufio.Reader
+	offset int
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		reader: bufio.NewReader(r),
+	}
+}
+
+func (p *Parser) Parse() (any, error) {
+	if _, err := p.reader.Peek(1); err != nil {
+		return nil, err
+	}
+	return p.parse()
+}
+
+func (p *Parser) ParseAll() ([]any, error) {
+	var list []any
+	for {
+		v, err := p.Parse()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (p *Parser) parse() (any, error) {
+	b, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if b == breakCode {
+		return nil, p.syntaxError("unexpected break")
+	}
+	return p.parseItem(b)
+}
+
+func (p *Parser) parseItem(b byte) (any, error) {
+	major, info := b>>5, b&0x1f
+	if major == majorSimple {
+		return p.parseSimple(info)
+	}
+	if info == indefinite {
+		return p.parseIndefinite(major)
+	}
+	arg, err := p.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		return float64(arg), nil
+	case majorNint:
+		return -1 - float64(arg), nil
+	case majorBytes:
+		buf, err := p.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(buf), nil
+	case majorText:
+		buf, err := p.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(buf) {
+			return nil, p.syntaxError("invalid utf-8 string")
+		}
+		return string(buf), nil
+	case majorArray:
+		arr := make([]any, 0, min(arg, maxPrealloc))
+		for range arg {
+			v, err := p.parse()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case majorMap:
+		obj := make(map[string]any)
+		for range arg {
+			if err := p.parseEntry(obj); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case majorTag:
+		return p.parseTag(arg)
+	default:
+		return nil, p.syntaxError("unknown major type")
+	}
+}
+
+func (p *Parser) parseIndefinite(major byte) (any, error) {
+	switch major {
+	case majorBytes, majorText:
+		var buf bytes.Buffer
+		for {
+			b, err := p.readByte()
+			if err != nil {
+				return nil, err
+			}
+			if b == breakCode {
+				break
+			}
+			if b>>5 != major || b&0x1f == indefinite {
+				return nil, p.syntaxError("invalid chunk in indefinite string")
+			}
+			arg, err := p.readArgument(b & 0x1f)
+			if err != nil {
+				return nil, err
+			}
+			chunk, err := p.readBytes(arg)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(chunk)
+		}
+		if major == majorBytes {
+			return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+		}
+		if !utf8.Valid(buf.Bytes()) {
+			return nil, p.syntaxError("invalid utf-8 string")
+		}
+		return buf.String(), nil
+	case majorArray:
+		var arr []any
+		for {
+			b, err := p.readByte()
+			if err != nil {
+				return nil, err
+			}
+			if b == breakCode {
+				break
+			}
+			v, err := p.parseItem(b)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if arr == nil {
+			arr = []any{}
+		}
+		return arr, nil
+	case majorMap:
+		obj := make(map[string]any)
+		for {
+			b, err := p.reader.Peek(1)
+			if err != nil {
+				return nil, p.unexpectedEOF(err)
+			}
+			if b[0] == breakCode {
+				p.readByte()
+				break
+			}
+			if err := p.parseEntry(obj); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	default:
+		return nil, p.syntaxError("invalid indefinite length")
+	}
+}
+
+func (p *Parser) parseEntry(obj map[string]any) error {
+	key, err := p.parse()
+	if err != nil {
+		return err
+	}
+	value, err := p.parse()
+	if err != nil {
+		return err
+	}
+	obj[keyString(key)] = value
+	return nil
+}
+
+func (p *Parser) parseTag(tag uint64) (any, error) {
+	value, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagDateEpoch:
+		if f, ok := value.(float64); ok {
+			sec, frac := math.Modf(f)
+			t := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+			return t.Format(time.RFC3339Nano), nil
+		}
+	case tagBignum, tagNegBignum:
+		str, ok := value.(string)
+		if !ok {
+			break
+		}
+		buf, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			break
+		}
+		n := new(big.Int).SetBytes(buf)
+		if tag == tagNegBignum {
+			n.Neg(n).Sub(n, big.NewInt(1))
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, nil
+	default:
+	}
+	return value, nil
+}
+
+func (p *Parser) parseSimple(info byte) (any, error) {
+	switch info {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull, simpleUndefined:
+		return nil, nil
+	case simpleFloat16:
+		buf, err := p.readN(2)
+		if err != nil {
+			return nil, err
+		}
+		return float16(binary.BigEndian.Uint16(buf)), nil
+	case simpleFloat32:
+		buf, err := p.readN(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case simpleFloat64:
+		buf, err := p.readN(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	case 24:
+		if _, err := p.readByte(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	case 28, 29, 30, indefinite:
+		return nil, p.syntaxError("invalid simple value")
+	default:
+		return nil, nil
+	}
+}
+
+func (p *Parser) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := p.readByte()
+		return uint64(b), err
+	case info == 25:
+		buf, err := p.readN(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(buf)), nil
+	case info == 26:
+		buf, err := p.readN(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(buf)), nil
+	case info == 27:
+		buf, err := p.readN(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(buf), nil
+	default:
+		return 0, p.syntaxError("invalid additional information")
+	}
+}
+
+func (p *Parser) readBytes(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, p.syntaxError("string too long")
+	}
+	var buf bytes.Buffer
+	c, err := io.CopyN(&buf, p.reader, int64(n))
+	p.offset += int(c)
+	if err != nil {
+		return nil, p.unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *Parser) readN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	c, err := io.ReadFull(p.reader, buf)
+	p.offset += c
+	if err != nil {
+		return nil, p.unexpectedEOF(err)
+	}
+	return buf, nil
+}
+
+func (p *Parser) readByte() (byte, error) {
+	b, err := p.reader.ReadByte()
+	if err != nil {
+		return 0, p.unexpectedEOF(err)
+	}
+	p.offset++
+	return b, nil
+}
+
+func (p *Parser) unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return p.syntaxError("unexpected end of input")
+	}
+	return err
+}
+
+func (p *Parser) syntaxError(msg string) error {
+	return fmt.Errorf("offset %d: %w: %s", p.offset, errSyntax, msg)
+}
+
+func keyString(key any) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case float64:
+		return strconv.FormatFloat(k, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(k)
+	}
+}
+
+func float16(bits uint16) float64 {
+	var (
+		sign = float64(1)
+		exp  = int(bits>>10) & 0x1f
+		frac = float64(bits & 0x3ff)
+	)
+	if bits&0x8000 != 0 {
+		sign = -1
+	}
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	default:
+		return sign * math.Ldexp(frac+1024, exp-25)
+	}
+}
@@ -0,0 +1,135 @@
+package cbor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+)
+
+type Writer struct {
+	ws *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		ws: bufio.NewWriter(w),
+	}
+}
+
+func (w *Writer) Write(value any) error {
+	if err := w.writeValue(value); err != nil {
+		return err
+	}
+	return w.ws.Flush()
+}
+
+func (w *Writer) writeValue(value any) error {
+	switch v := value.(type) {
+	case nil:
+		w.ws.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if v {
+			w.ws.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			w.ws.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case string:
+		w.writeHead(majorText, uint64(len(v)))
+		w.ws.WriteString(v)
+	case []byte:
+		w.writeHead(majorBytes, uint64(len(v)))
+		w.ws.Write(v)
+	case float64:
+		w.writeFloat(v)
+	case float32:
+		w.writeFloat(float64(v))
+	case int:
+		w.writeInt(int64(v))
+	case int64:
+		w.writeInt(v)
+	case uint64:
+		w.writeHead(majorUint, v)
+	case []any:
+		w.writeHead(majorArray, uint64(len(v)))
+		for i := range v {
+			if err := w.writeValue(v[i]); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		w.writeHead(majorMap, uint64(len(v)))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			w.writeHead(majorText, uint64(len(k)))
+			w.ws.WriteString(k)
+			if err := w.writeValue(v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported cbor type %T", errType, value)
+	}
+	return nil
+}
+
+func (w *Writer) writeFloat(f float64) {
+	if isInteger(f) {
+		w.writeInt(int64(f))
+		return
+	}
+	if float64(float32(f)) == f || math.IsNaN(f) {
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], math.Float32bits(float32(f)))
+		w.ws.WriteByte(majorSimple<<5 | simpleFloat32)
+		w.ws.Write(buf[:])
+		return
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], math.Float64bits(f))
+	w.ws.WriteByte(majorSimple<<5 | simpleFloat64)
+	w.ws.Write(buf[:])
+}
+
+func (w *Writer) writeInt(n int64) {
+	if n < 0 {
+		w.writeHead(majorNint, uint64(-1-n))
+		return
+	}
+	w.writeHead(majorUint, uint64(n))
+}
+
+func (w *Writer) writeHead(major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		w.ws.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		w.ws.WriteByte(major | 24)
+		w.ws.WriteByte(byte(arg))
+	case arg <= math.MaxUint16:
+		var buf [2]byte
+		binary.BigEndian.PutUint16(buf[:], uint16(arg))
+		w.ws.WriteByte(major | 25)
+		w.ws.Write(buf[:])
+	case arg <= math.MaxUint32:
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], uint32(arg))
+		w.ws.WriteByte(major | 26)
+		w.ws.Write(buf[:])
+	default:
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], arg)
+		w.ws.WriteByte(major | 27)
+		w.ws.Write(buf[:])
+	}
+}
+
+func isInteger(f float64) bool {
+	if f == 0 && math.Signbit(f) {
+		return false
+	}
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"iter"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/midbel/cli"
 	"github.com/midbel/codecs/sch"
+	"github.com/midbel/codecs/xml"
 )
 
 var compileCmd = cli.Command{
@@ -33,14 +40,41 @@ var assertCmd = cli.Command{
 	Handler: &SchAssertCmd{},
 }
 
-type SchCompileCmd struct{}
+type SchCompileCmd struct {
+	phase string
+	file  string
+}
 
-func (a SchCompileCmd) Run(args []string) error {
+func (a *SchCompileCmd) flags() *flag.FlagSet {
 	set := cli.NewFlagSet("compile")
+	set.StringVar(&a.phase, "p", "", "phase")
+	set.StringVar(&a.file, "f", "", "output file")
+	return set
+}
+
+func (a *SchCompileCmd) Run(args []string) error {
+	set := a.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	return nil
+	schema, err := parseSchemaFile(set.Arg(0))
+	if err != nil {
+		return err
+	}
+	doc, err := schema.Compile(a.phase)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if a.file != "" {
+		f, err := os.Create(a.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return xml.NewWriter(w).Write(doc)
 }
 
 type SchInfoCmd struct{}
@@ -62,24 +96,61 @@ func (a SchInfoCmd) Run(args []string) error {
 }
 
 type SchAssertCmd struct {
-	phase   string
-	quiet   bool
-	erronly bool
+	phase    string
+	quiet    bool
+	erronly  bool
+	report   string
+	format   string
+	xslt     bool
+	fix      string
+	fixDir   string
+	metrics  string
+	policy   sch.FailPolicy
+	failFast bool
+	csv      sch.Reporter
 	ParserOptions
 }
 
-func (a *SchAssertCmd) Run(args []string) error {
+func (a *SchAssertCmd) flags() *flag.FlagSet {
 	set := cli.NewFlagSet("assert")
 	set.StringVar(&a.phase, "p", "", "phase")
 	set.BoolVar(&a.quiet, "q", false, "quiet")
 	set.BoolVar(&a.erronly, "e", false, "print only errors")
+	set.StringVar(&a.report, "r", "", "directory where html reports are written")
+	set.StringVar(&a.format, "f", "", "output format (text, csv, xml)")
+	set.BoolVar(&a.xslt, "xslt", false, "run schematron compiled to xslt")
+	set.StringVar(&a.fix, "fix", "", "apply the given quick fix (* for the first available) and write the fixed document")
+	set.StringVar(&a.fixDir, "fix-dir", "", "directory where fixed documents are written")
+	set.StringVar(&a.metrics, "metrics", "", "address where metrics are exposed (/metrics) during the run")
+	set.BoolVar(&a.failFast, "fail-fast", false, "stop at the first failed assertion")
+	set.IntVar(&a.policy.MaxErrors, "max-errors", 0, "stop after the given number of failed assertions (0 runs all)")
+	set.BoolVar(&a.policy.StopOnFatal, "stop-on-fatal", false, "stop at the first failed fatal assertion, continue on warnings")
+	set.BoolVar(&a.policy.SkipPattern, "skip-pattern", false, "skip remaining rules of a pattern once one of its rules fails")
+	return set
+}
+
+func (a *SchAssertCmd) Run(args []string) error {
+	set := a.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
+	if a.failFast {
+		a.policy.MaxErrors = sch.FailFast().MaxErrors
+	}
 	schema, err := parseSchemaFile(set.Arg(0))
 	if err != nil {
 		return err
 	}
+	schema.SetFailPolicy(a.policy)
+	if a.metrics != "" {
+		m := sch.NewMetrics()
+		schema.SetHook(m)
+		stop, err := serveMetrics(a.metrics, m)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
 	var w io.Writer = os.Stdout
 	if a.quiet {
 		w = io.Discard
@@ -104,18 +175,144 @@ func (a *SchAssertCmd) assertFile(w io.Writer, schema *sch.Schema, file string)
 	spin := cli.NewSpinner()
 	spin.SetMessage(fmt.Sprintf("processing %s", filepath.Base(file)))
 	spin.Run(func() {
-		results, err = schema.RunPhase(a.phase, doc)
+		if a.xslt {
+			results, err = schema.RunPhaseXSLT(a.phase, doc)
+		} else {
+			results, err = schema.RunPhase(a.phase, doc)
+		}
 	})
-	if err != nil {
+	if err != nil && !errors.Is(err, sch.ErrStopped) {
+		return err
+	}
+	stopped := err
+	if err := a.reportFile(w, schema, doc, file, results, now); err != nil {
 		return err
 	}
+	return stopped
+}
+
+func (a *SchAssertCmd) reportFile(w io.Writer, schema *sch.Schema, doc *xml.Document, file string, results []sch.Result, now time.Time) error {
+	if a.fix != "" {
+		return a.fixFile(doc, file, results)
+	}
+	switch a.format {
+	case "", "text":
+	case "csv":
+		if a.csv == nil {
+			a.csv = sch.CsvReporter()
+		}
+		return a.csv.Report(w, file, results)
+	case "xml", "svrl":
+		return sch.SvrlReporter(schema).Report(w, file, results)
+	default:
+		return fmt.Errorf("%s: unsupported output format", a.format)
+	}
 	var (
 		elapsed  = time.Since(now)
 		failures = printResults(w, results, a.erronly)
 	)
 	fmt.Printf("done %s: %d failure(s) on %d assertion(s) (elapsed time: %s)", filepath.Base(file), failures, len(results), elapsed)
 	fmt.Println()
-	return nil
+	if a.report == "" {
+		return nil
+	}
+	ctx := assertReport{
+		File:     filepath.Base(file),
+		Failures: failures,
+		Elapsed:  elapsed,
+		Results:  results,
+		Style:    template.CSS(xml.HTMLStyle()),
+	}
+	var (
+		buf  bytes.Buffer
+		opts xml.HTMLOptions
+	)
+	opts.Collapse = 2
+	for _, r := range results {
+		opts.Marked = append(opts.Marked, r.Nodes...)
+	}
+	if err := xml.RenderHTML(&buf, doc, opts); err != nil {
+		return err
+	}
+	ctx.Document = template.HTML(buf.String())
+	return writeReport(a.report, ctx)
+}
+
+func (a *SchAssertCmd) fixFile(doc *xml.Document, file string, results []sch.Result) error {
+	for _, r := range results {
+		if r.Fail == 0 || len(r.Fixes) == 0 {
+			continue
+		}
+		ident := a.fix
+		if ident == "*" {
+			ident = r.Fixes[0]
+		} else if !slices.Contains(r.Fixes, ident) {
+			continue
+		}
+		if err := r.Fix(ident); err != nil {
+			return err
+		}
+	}
+	var out string
+	if a.fixDir != "" {
+		if err := os.MkdirAll(a.fixDir, 0755); err != nil {
+			return err
+		}
+		out = filepath.Join(a.fixDir, filepath.Base(file))
+	}
+	return writeDocument(doc, out, WriterOptions{})
+}
+
+func serveMetrics(addr string, handler http.Handler) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	srv := http.Server{
+		Handler: mux,
+	}
+	go srv.Serve(ln)
+	return func() { srv.Close() }, nil
+}
+
+type assertReport struct {
+	File     string
+	Failures int
+	Elapsed  time.Duration
+	Results  []sch.Result
+	Document template.HTML
+	Style    template.CSS
+}
+
+func writeReport(dir string, ctx assertReport) error {
+	str, err := readResource("templates/report.html")
+	if err != nil {
+		return err
+	}
+	funcs := template.FuncMap{
+		"path": func(n xml.Node) string {
+			return xml.PathOf(n, xml.PathOptions{})
+		},
+		"anchor": func(n xml.Node) string {
+			return xml.PathOf(n, xml.PathOptions{Indexed: true})
+		},
+	}
+	tpl, err := template.New("report").Funcs(funcs).Parse(string(str))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file := strings.TrimSuffix(ctx.File, filepath.Ext(ctx.File))
+	f, err := os.Create(filepath.Join(dir, file+".html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return tpl.Execute(f, ctx)
 }
 
 func printResults(w io.Writer, results []sch.Result, errOnly bool) int {
@@ -175,6 +372,14 @@ func getFiles(files []string) iter.Seq[string] {
 }
 
 func parseSchemaFile(file string) (*sch.Schema, error) {
+	if isResource(file) {
+		r, err := openResource(resourceName(file))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return sch.New(r)
+	}
 	u, err := url.Parse(file)
 	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
 		return sch.Open(file)
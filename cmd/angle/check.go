@@ -6,36 +6,84 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/midbel/cli"
 	"github.com/midbel/codecs/relax"
 	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xsd"
 )
 
+var checkCmd = cli.Command{
+	Name:    "check",
+	Alias:   []string{"validate"},
+	Summary: "validate xml document(s) against a relax ng or xsd schema",
+	Handler: &CheckCmd{},
+}
+
+type Validator interface {
+	Validate(xml.Node) error
+}
+
 type CheckCmd struct {
-	FailFast bool
+	FailFast  bool
+	MaxErrors int
+	Xsd       string
 }
 
-func (c *CheckCmd) Run(args []string) error {
-	set := flag.NewFlagSet("format", flag.ExitOnError)
+func (c *CheckCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("check")
 	set.BoolVar(&c.FailFast, "fail-fast", false, "stop checking files as soon as first error is encountered")
+	set.IntVar(&c.MaxErrors, "max-errors", 0, "maximum number of errors reported per document (0 reports all)")
+	set.StringVar(&c.Xsd, "xsd", "", "validate documents against the given xsd schema")
+	return set
+}
+
+func (c *CheckCmd) Run(args []string) error {
+	set := c.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
 
-	schema, err := parseSchema(set.Arg(0))
+	var (
+		schema Validator
+		err    error
+	)
+	args = set.Args()
+	if c.Xsd != "" {
+		schema, err = parseXsdSchema(c.Xsd)
+	} else {
+		var file string
+		if len(args) > 0 {
+			file, args = args[0], args[1:]
+		}
+		var pattern relax.Pattern
+		if pattern, err = parseSchema(file); err == nil {
+			schema = relax.Validator{
+				Schema:    pattern,
+				MaxErrors: c.MaxErrors,
+			}
+		}
+	}
 	if err != nil {
 		return err
 	}
-	args = set.Args()
-	for doc, err := range iterDocuments(args[1:]) {
+	for doc, err := range iterDocuments(args) {
 		if err != nil {
 			if errors.Is(err, ErrDocument) {
-				fmt.Fprintf(os.Stderr, "%s: %w", doc.File, ErrDocument)
+				fmt.Fprintln(os.Stderr, err)
 				continue
 			}
 			return err
 		}
 		if err := schema.Validate(doc.Root()); err != nil {
-			if err, ok := err.(relax.NodeError); ok {
+			switch err := err.(type) {
+			case relax.ErrorList:
+				for _, e := range err {
+					printNodeError(e)
+				}
+			case relax.NodeError:
+				printNodeError(err)
+			case xsd.NodeError:
+				fmt.Fprintln(os.Stderr, err.Cause)
 				fmt.Fprintln(os.Stderr, xml.WriteNode(err.Node))
 				fmt.Fprintln(os.Stderr)
 			}
@@ -52,6 +100,14 @@ func (c *CheckCmd) Run(args []string) error {
 	return nil
 }
 
+func printNodeError(err relax.NodeError) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	if err.Node != nil {
+		fmt.Fprintln(os.Stderr, xml.WriteNode(err.Node))
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
 func parseSchema(file string) (relax.Pattern, error) {
 	if file == "" {
 		return relax.Valid(), nil
@@ -65,3 +121,15 @@ func parseSchema(file string) (relax.Pattern, error) {
 	p := relax.Parse(r)
 	return p.Parse()
 }
+
+func parseXsdSchema(file string) (*xsd.Schema, error) {
+	if isResource(file) {
+		r, err := openResource(resourceName(file))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return xsd.New(r)
+	}
+	return xsd.Open(file)
+}
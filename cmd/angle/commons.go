@@ -11,7 +11,9 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 
+	"github.com/midbel/codecs/html"
 	"github.com/midbel/codecs/xml"
 )
 
@@ -28,7 +30,11 @@ type WriterOptions struct {
 	NoProlog    bool
 	NoComment   bool
 	Compact     bool
+	ASCII       bool
+	NoCharData  bool
 	CaseType    string
+	CharData    string
+	Lossless    bool
 }
 
 type Document struct {
@@ -80,10 +86,13 @@ func iterDocuments(files []string) iter.Seq2[*Document, error] {
 
 type ParserOptions struct {
 	Include    bool
+	XInclude   bool
 	StrictNS   bool
 	KeepEmpty  bool
 	OmitProlog bool
 	Transform  bool
+	HtmlInput  bool
+	Lossless   bool
 }
 
 func parseDocument(file string, opts ParserOptions) (*xml.Document, error) {
@@ -97,7 +106,16 @@ func parseDocument(file string, opts ParserOptions) (*xml.Document, error) {
 	}
 	defer r.Close()
 
-	p := xml.NewParser(r)
+	if ext := filepath.Ext(file); opts.HtmlInput || ext == ".html" || ext == ".htm" {
+		return html.Parse(r)
+	}
+
+	var p *xml.Parser
+	if opts.Lossless {
+		p = xml.NewLosslessParser(r)
+	} else {
+		p = xml.NewParser(r)
+	}
 	p.OmitProlog = opts.OmitProlog
 	p.StrictNS = opts.StrictNS
 	p.KeepEmpty = opts.KeepEmpty
@@ -105,7 +123,15 @@ func parseDocument(file string, opts ParserOptions) (*xml.Document, error) {
 	if opts.Include {
 		p.RegisterPI("angle-include", piInclude)
 	}
-	return p.Parse()
+	doc, err := p.Parse()
+	if err != nil {
+		return doc, err
+	}
+	doc.URI = documentURI(file)
+	if !opts.XInclude {
+		return doc, nil
+	}
+	return doc, xml.ResolveIncludes(doc, file)
 }
 
 func piInclude(_ string, attrs []xml.Attribute) (xml.Node, error) {
@@ -149,7 +175,10 @@ func writeDocument(doc *xml.Document, file string, options WriterOptions) error
 			w = z
 		}
 	}
+	return formatDocument(w, doc, options)
+}
 
+func formatDocument(w io.Writer, doc *xml.Document, options WriterOptions) error {
 	ws := xml.NewWriter(w)
 	if options.NoNamespace {
 		ws.WriterOptions |= xml.OptionNoNamespace
@@ -160,6 +189,18 @@ func writeDocument(doc *xml.Document, file string, options WriterOptions) error
 	if options.Compact {
 		ws.WriterOptions |= xml.OptionCompact
 	}
+	if options.ASCII {
+		ws.WriterOptions |= xml.OptionASCII
+	}
+	if options.NoCharData {
+		ws.WriterOptions |= xml.OptionCharDataToText
+	}
+	if options.Lossless {
+		ws.WriterOptions |= xml.OptionLossless
+	}
+	if options.CharData != "" {
+		ws.CharDataElements = strings.Split(options.CharData, ",")
+	}
 	switch options.CaseType {
 	case snakeCaseType:
 		ws.WriterOptions |= xml.OptionNamespaceSnakeCase | xml.OptionNameSnakeCase
@@ -172,6 +213,13 @@ func writeDocument(doc *xml.Document, file string, options WriterOptions) error
 	return ws.Write(doc)
 }
 
+func documentURI(file string) string {
+	if u, err := url.Parse(file); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return file
+	}
+	return xml.FileURI(file)
+}
+
 func openFile(file string) (io.ReadCloser, error) {
 	u, err := url.Parse(file)
 	if err != nil {
@@ -192,6 +240,8 @@ func openFile(file string) (io.ReadCloser, error) {
 			return nil, fmt.Errorf("fail to retrieve remote file")
 		}
 		return res.Body, nil
+	case resourceScheme:
+		return openResource(resourceName(file))
 	default:
 		return os.Open(file)
 	}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/codecs/cbor"
+	"github.com/midbel/codecs/json"
+	"github.com/midbel/codecs/jsonata"
+	"github.com/midbel/codecs/msgpack"
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/yaml"
+)
+
+var convertCmd = cli.Command{
+	Name:    "convert",
+	Summary: "convert yaml, json, cbor or msgpack document to another format",
+	Handler: &ConvertCmd{},
+}
+
+type ConvertCmd struct {
+	From    string
+	To      string
+	Query   string
+	Root    string
+	OutFile string
+	WriterOptions
+}
+
+func (c *ConvertCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("convert")
+	set.StringVar(&c.From, "from", "", "input format (yaml, json, cbor, msgpack) - guessed from the file extension by default")
+	set.StringVar(&c.To, "to", "json", "output format (json, xml, yaml, cbor, msgpack)")
+	set.StringVar(&c.Query, "q", "", "query applied on each document before conversion")
+	set.StringVar(&c.Root, "root", "root", "name of the root element when converting to xml")
+	set.StringVar(&c.OutFile, "f", "", "specify the path to the file where the document will be written")
+	set.BoolVar(&c.Compact, "compact", false, "write compact output")
+	return set
+}
+
+func (c *ConvertCmd) Run(args []string) error {
+	set := c.flags()
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	r, err := openFile(set.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	from := c.From
+	if from == "" {
+		from = strings.TrimPrefix(filepath.Ext(set.Arg(0)), ".")
+	}
+	list, err := decodeValues(r, from)
+	if err != nil {
+		return err
+	}
+	if c.Query != "" {
+		if list, err = queryValues(list, c.Query); err != nil {
+			return err
+		}
+	}
+	if c.To == "xml" {
+		doc := yamlToXML(list, c.Root)
+		return writeDocument(doc, c.OutFile, c.WriterOptions)
+	}
+	return c.writeValues(list)
+}
+
+func (c *ConvertCmd) writeValues(list []any) error {
+	var w io.Writer = os.Stdout
+	if c.OutFile != "" {
+		f, err := os.Create(c.OutFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	var write func(any) error
+	switch c.To {
+	case "json":
+		ws := json.NewWriter(w)
+		ws.Compact = c.Compact
+		write = func(v any) error {
+			if err := ws.Write(v); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintln(w)
+			return err
+		}
+	case "yaml", "yml":
+		write = yaml.NewWriter(w).Write
+	case "cbor":
+		write = cbor.NewWriter(w).Write
+	case "msgpack":
+		write = msgpack.NewWriter(w).Write
+	default:
+		return fmt.Errorf("%s: unsupported output format", c.To)
+	}
+	for _, v := range list {
+		if err := write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeValues(r io.Reader, format string) ([]any, error) {
+	switch format {
+	case "", "yaml", "yml":
+		return yaml.DecodeAll(r)
+	case "json":
+		doc, err := json.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return []any{doc}, nil
+	case "cbor":
+		return cbor.DecodeAll(r)
+	case "msgpack", "mpk":
+		return msgpack.DecodeAll(r)
+	default:
+		return nil, fmt.Errorf("%s: unsupported input format", format)
+	}
+}
+
+func queryValues(list []any, query string) ([]any, error) {
+	q, err := jsonata.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i], err = q.Get(list[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func yamlToXML(list []any, root string) *xml.Document {
+	el := xml.NewElement(xml.LocalName(root))
+	if len(list) == 1 {
+		appendValue(el, list[0])
+	} else {
+		for _, v := range list {
+			doc := xml.NewElement(xml.LocalName("document"))
+			appendValue(doc, v)
+			el.Append(doc)
+		}
+	}
+	return xml.NewDocument(el)
+}
+
+func appendValue(el *xml.Element, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			var child *xml.Element
+			if isElementName(k) {
+				child = xml.NewElement(xml.LocalName(k))
+			} else {
+				child = xml.NewElement(xml.LocalName("entry"))
+				child.SetAttribute(xml.NewAttribute(xml.LocalName("key"), k))
+			}
+			appendValue(child, v[k])
+			el.Append(child)
+		}
+	case []any:
+		for i := range v {
+			child := xml.NewElement(xml.LocalName("item"))
+			appendValue(child, v[i])
+			el.Append(child)
+		}
+	case nil:
+	case float64:
+		el.Append(xml.NewText(strconv.FormatFloat(v, 'f', -1, 64)))
+	default:
+		el.Append(xml.NewText(fmt.Sprint(v)))
+	}
+}
+
+func isElementName(name string) bool {
+	if name == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(r) && r != '_' {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/midbel/cli"
+	"github.com/midbel/codecs/xml"
 )
 
 var formatCmd = cli.Command{
@@ -13,30 +22,272 @@ var formatCmd = cli.Command{
 
 type FormatCmd struct {
 	OutFile string
+	Html    bool
+	C14N    bool
+	Check   bool
 	WriterOptions
 	ParserOptions
 }
 
-func (f *FormatCmd) Run(args []string) error {
+func (f *FormatCmd) flags() *flag.FlagSet {
 	set := cli.NewFlagSet("format")
 
 	set.BoolVar(&f.NoNamespace, "no-namespace", false, "don't write xml namespace into the output document")
 	set.BoolVar(&f.NoProlog, "no-prolog", false, "don't write the xml prolog into the output document")
 	set.BoolVar(&f.NoComment, "no-comment", false, "dont't write the comment present in the input document")
 	set.BoolVar(&f.Compact, "compact", false, "write compact output")
+	set.BoolVar(&f.ASCII, "ascii", false, "write non ascii characters as character references")
+	set.BoolVar(&f.NoCharData, "no-cdata", false, "write CDATA sections as escaped text")
+	set.StringVar(&f.CharData, "cdata", "", "comma separated list of elements whose text is written as CDATA sections")
 	set.BoolVar(&f.StrictNS, "strict-ns", false, "strict namespace checking")
 	set.BoolVar(&f.KeepEmpty, "keep-empty", false, "keep empty element")
 	set.BoolVar(&f.OmitProlog, "omit-prolog", false, "omit xml prolog")
+	set.BoolVar(&f.XInclude, "xinclude", false, "process xinclude elements")
+	set.BoolVar(&f.HtmlInput, "html-input", false, "parse the input document as html")
 	set.StringVar(&f.CaseType, "case-type", "", "rewrite element/attribute name to given case family")
 	set.StringVar(&f.OutFile, "f", "", "specify the path to the file where the document will be written")
+	set.BoolVar(&f.Html, "html", false, "render the document as syntax highlighted html")
+	set.BoolVar(&f.C14N, "c14n", false, "write the document in exclusive canonical form")
+	set.BoolVar(&f.Check, "check", false, "report files that are not formatted and print the diff of the changes")
+	set.BoolVar(&f.WriterOptions.Lossless, "lossless", false, "preserve the original bytes of the nodes that are not modified")
+	return set
+}
 
+func (f *FormatCmd) Run(args []string) error {
+	set := f.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
+	f.ParserOptions.Lossless = f.WriterOptions.Lossless
+	if f.Check {
+		return f.check(os.Stdout, set.Args())
+	}
 
 	doc, err := parseDocument(set.Arg(0), f.ParserOptions)
 	if err != nil {
 		return err
 	}
+	if f.Html {
+		return writeHTML(doc, filepath.Base(set.Arg(0)), f.OutFile)
+	}
+	if f.C14N {
+		return writeCanonical(doc, f.OutFile, !f.NoComment)
+	}
 	return writeDocument(doc, f.OutFile, f.WriterOptions)
 }
+
+func (f *FormatCmd) check(w io.Writer, files []string) error {
+	var failed bool
+	for _, file := range files {
+		before, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		doc, err := parseDocument(file, f.ParserOptions)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		var after bytes.Buffer
+		if err := formatDocument(&after, doc, f.WriterOptions); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if !bytes.HasSuffix(after.Bytes(), []byte("\n")) {
+			after.WriteByte('\n')
+		}
+		if bytes.Equal(before, after.Bytes()) {
+			continue
+		}
+		failed = true
+		writeUnifiedDiff(w, file, splitLines(before), splitLines(after.Bytes()))
+	}
+	if failed {
+		return errFail
+	}
+	return nil
+}
+
+func writeHTML(doc *xml.Document, title, file string) error {
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	opts := xml.HTMLOptions{
+		Title:      title,
+		Standalone: true,
+	}
+	return xml.RenderHTML(w, doc, opts)
+}
+
+func writeCanonical(doc *xml.Document, file string, comments bool) error {
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	c := xml.NewCanonicalizer(w)
+	c.WithComments = comments
+	return c.Write(doc)
+}
+
+const diffContext = 3
+
+const maxDiffCells = 1 << 24
+
+type diffLine struct {
+	op   byte
+	line string
+}
+
+func splitLines(buf []byte) []string {
+	if len(buf) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(buf), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func diffLines(before, after []string) []diffLine {
+	var prefix, suffix int
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	for suffix < len(before)-prefix && suffix < len(after)-prefix && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	var (
+		list []diffLine
+		from = before[prefix : len(before)-suffix]
+		to   = after[prefix : len(after)-suffix]
+	)
+	for _, line := range before[:prefix] {
+		list = append(list, diffLine{op: ' ', line: line})
+	}
+	if len(from)*len(to) > maxDiffCells {
+		for _, line := range from {
+			list = append(list, diffLine{op: '-', line: line})
+		}
+		for _, line := range to {
+			list = append(list, diffLine{op: '+', line: line})
+		}
+	} else {
+		list = append(list, diffCommon(from, to)...)
+	}
+	for _, line := range before[len(before)-suffix:] {
+		list = append(list, diffLine{op: ' ', line: line})
+	}
+	return list
+}
+
+func diffCommon(before, after []string) []diffLine {
+	var (
+		rows = len(before) + 1
+		cols = len(after) + 1
+		lcs  = make([]int32, rows*cols)
+	)
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i*cols+j] = lcs[(i+1)*cols+j+1] + 1
+			} else {
+				lcs[i*cols+j] = max(lcs[(i+1)*cols+j], lcs[i*cols+j+1])
+			}
+		}
+	}
+	var (
+		list []diffLine
+		i, j int
+	)
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			list = append(list, diffLine{op: ' ', line: before[i]})
+			i++
+			j++
+		case i < len(before) && (j == len(after) || lcs[(i+1)*cols+j] >= lcs[i*cols+j+1]):
+			list = append(list, diffLine{op: '-', line: before[i]})
+			i++
+		default:
+			list = append(list, diffLine{op: '+', line: after[j]})
+			j++
+		}
+	}
+	return list
+}
+
+func writeUnifiedDiff(w io.Writer, file string, before, after []string) {
+	list := diffLines(before, after)
+	fmt.Fprintf(w, "--- %s\n", file)
+	fmt.Fprintf(w, "+++ %s\n", file)
+	for start := 0; start < len(list); {
+		if list[start].op == ' ' {
+			start++
+			continue
+		}
+		var (
+			beg = max(start-diffContext, 0)
+			end = start
+		)
+		for end < len(list) {
+			if list[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(list) && list[next].op == ' ' {
+				next++
+			}
+			if next == len(list) || next-end > 2*diffContext {
+				end = min(end+diffContext, len(list))
+				break
+			}
+			end = next
+		}
+		writeHunk(w, list, beg, end)
+		start = end
+	}
+}
+
+func writeHunk(w io.Writer, list []diffLine, beg, end int) {
+	var oldLine, newLine, oldCount, newCount int
+	for _, d := range list[:beg] {
+		if d.op != '+' {
+			oldLine++
+		}
+		if d.op != '-' {
+			newLine++
+		}
+	}
+	for _, d := range list[beg:end] {
+		if d.op != '+' {
+			oldCount++
+		}
+		if d.op != '-' {
+			newCount++
+		}
+	}
+	if oldCount > 0 {
+		oldLine++
+	}
+	if newCount > 0 {
+		newLine++
+	}
+	fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, d := range list[beg:end] {
+		fmt.Fprintf(w, "%c%s", d.op, d.line)
+		if !strings.HasSuffix(d.line, "\n") {
+			fmt.Fprintf(w, "\n\\ No newline at end of file\n")
+		}
+	}
+}
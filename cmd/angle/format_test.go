@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Before string
+		After  string
+		Want   string
+	}{
+		{
+			Name:   "change",
+			Before: "a\nb\nc\n",
+			After:  "a\nx\nc\n",
+			Want:   "--- f\n+++ f\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			Name:   "insert",
+			Before: "a\nc\n",
+			After:  "a\nb\nc\n",
+			Want:   "--- f\n+++ f\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+		{
+			Name:   "delete-all",
+			Before: "a\nb\n",
+			After:  "",
+			Want:   "--- f\n+++ f\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			Name:   "no-newline",
+			Before: "a\nb",
+			After:  "a\nb\n",
+			Want:   "--- f\n+++ f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			Name:   "hunks",
+			Before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			After:  "x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			Want:   "--- f\n+++ f\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			var str strings.Builder
+			writeUnifiedDiff(&str, "f", splitLines([]byte(c.Before)), splitLines([]byte(c.After)))
+			if got := str.String(); got != c.Want {
+				t.Errorf("diff mismatched!\nwant:\n%s\ngot:\n%s", c.Want, got)
+			}
+		})
+	}
+}
+
+func TestFormatCheck(t *testing.T) {
+	var (
+		dir       = t.TempDir()
+		formatted = filepath.Join(dir, "formatted.xml")
+		messy     = filepath.Join(dir, "messy.xml")
+	)
+	if err := os.WriteFile(messy, []byte(`<root><item>a</item></root>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		cmd FormatCmd
+		str strings.Builder
+	)
+	err := cmd.check(&str, []string{messy})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("unformatted file should fail the check, got %v", err)
+	}
+	diff := str.String()
+	if !strings.HasPrefix(diff, "--- "+messy+"\n+++ "+messy+"\n@@ ") || !strings.Contains(diff, "-<root><item>a</item></root>\n") {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+	var after strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			after.WriteString(line[1:])
+		}
+	}
+	if err := os.WriteFile(formatted, []byte(after.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	str.Reset()
+	if err := cmd.check(&str, []string{formatted}); err != nil {
+		t.Errorf("formatted file should pass the check, got %v\n%s", err, str.String())
+	}
+	if str.Len() != 0 {
+		t.Errorf("no diff expected for formatted file, got:\n%s", str.String())
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"maps"
 	"os"
@@ -40,18 +41,25 @@ var infosCmd = cli.Command{
 	Handler: &InfoCmd{},
 }
 
-type CompareCmd struct{}
+type CompareCmd struct {
+	Ordered bool
+}
+
+func (c *CompareCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("compare")
+	set.BoolVar(&c.Ordered, "o", false, "ordered comparison")
+	return set
+}
 
 func (c *CompareCmd) Run(args []string) error {
 	var (
-		mode    = inspect.CmpUnordered
-		set     = cli.NewFlagSet("compare")
-		ordered = set.Bool("o", false, "ordered comparison")
+		mode = inspect.CmpUnordered
+		set  = c.flags()
 	)
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-	if *ordered {
+	if c.Ordered {
 		mode = inspect.CmpOrdered
 	}
 	_, err := inspect.Compare(set.Arg(0), set.Arg(1), mode)
@@ -78,14 +86,20 @@ func (c *SortCmd) Run(args []string) error {
 	return fmt.Errorf("not yet implemented")
 }
 
-type InfoCmd struct{}
+type InfoCmd struct {
+	Qualified bool
+	Verbose   bool
+}
+
+func (c *InfoCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("infos")
+	set.BoolVar(&c.Qualified, "q", false, "show only qualified name")
+	set.BoolVar(&c.Verbose, "v", false, "show all informations")
+	return set
+}
 
 func (c *InfoCmd) Run(args []string) error {
-	var (
-		set       = cli.NewFlagSet("infos")
-		qualified = set.Bool("q", false, "show only qualified name")
-		verbose   = set.Bool("v", false, "show all informations")
-	)
+	set := c.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
@@ -95,12 +109,12 @@ func (c *InfoCmd) Run(args []string) error {
 	}
 
 	rd := cli.NewTableRenderer(os.Stdout)
-	if *verbose {
+	if c.Verbose {
 		var (
 			elements   = stats.Elements
 			attributes = stats.Attributes
 		)
-		if *qualified {
+		if c.Qualified {
 			elements = stats.QualifiedEls
 			attributes = stats.QualifiedAttrs
 		}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/midbel/cli"
 )
@@ -23,13 +24,25 @@ func main() {
 	)
 	root.SetSummary(summary)
 	root.SetHelp(help)
+	set.StringVar(&resourceDir, "resources", resourceDir, "directory with resources overriding the embedded ones")
+	set.StringVar(&recordDir, "record", recordDir, "directory where responses of remote fetches are recorded")
+	set.StringVar(&replayDir, "replay", replayDir, "directory with recorded responses to use instead of remote fetches")
 	if err := set.Parse(os.Args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			root.Help()
 			os.Exit(2)
 		}
 	}
-	err := root.Execute(set.Args())
+	if err := setupTransport(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var err error
+	if cmd, args, ok := findHidden(set.Args()); ok {
+		err = cmd.Run(args)
+	} else {
+		err = root.Execute(set.Args())
+	}
 	if err != nil {
 		if s, ok := err.(cli.SuggestionError); ok && len(s.Others) > 0 {
 			fmt.Fprintln(os.Stderr, "similar command(s)")
@@ -44,23 +57,51 @@ func main() {
 	}
 }
 
+type commandEntry struct {
+	Path []string
+	Cmd  *cli.Command
+}
+
+var commands = []commandEntry{
+	{Path: []string{"format"}, Cmd: &formatCmd},
+	{Path: []string{"exec"}, Cmd: &queryCmd},
+	{Path: []string{"query"}, Cmd: &queryCmd},
+	{Path: []string{"query", "execute"}, Cmd: &queryCmd},
+	{Path: []string{"query", "debug"}, Cmd: &debugCmd},
+	{Path: []string{"assert"}, Cmd: &assertCmd},
+	{Path: []string{"assert", "execute"}, Cmd: &assertCmd},
+	{Path: []string{"assert", "info"}, Cmd: &infoSchemaCmd},
+	{Path: []string{"assert", "compile"}, Cmd: &compileCmd},
+	{Path: []string{"transform"}, Cmd: &transformCmd},
+	{Path: []string{"check"}, Cmd: &checkCmd},
+	{Path: []string{"compare"}, Cmd: &compareCmd},
+	{Path: []string{"diff"}, Cmd: &diffCmd},
+	{Path: []string{"sort"}, Cmd: &sortCmd},
+	{Path: []string{"convert"}, Cmd: &convertCmd},
+	{Path: []string{"infos"}, Cmd: &infosCmd},
+	{Path: []string{"studio", "query"}, Cmd: &terminalQueryCmd},
+}
+
+// hidden commands are not registered in the command tree: they are not
+// listed in the help nor in the catalog of commands.
+var hidden = []commandEntry{
+	{Path: []string{"meta", "commands"}, Cmd: &metaCommandsCmd},
+	{Path: []string{"meta", "docs"}, Cmd: &metaDocsCmd},
+}
+
 func prepare() *cli.CommandTrie {
 	root := cli.New()
-	root.Register([]string{"format"}, &formatCmd)
-	root.Register([]string{"exec"}, &queryCmd)
-	root.Register([]string{"query"}, &queryCmd)
-	root.Register([]string{"query", "execute"}, &queryCmd)
-	root.Register([]string{"query", "debug"}, &debugCmd)
-	root.Register([]string{"assert"}, &assertCmd)
-	root.Register([]string{"assert", "execute"}, &assertCmd)
-	root.Register([]string{"assert", "info"}, &infoSchemaCmd)
-	root.Register([]string{"assert", "compile"}, &compileCmd)
-	root.Register([]string{"transform"}, &transformCmd)
-	root.Register([]string{"compare"}, &compareCmd)
-	root.Register([]string{"diff"}, &diffCmd)
-	root.Register([]string{"sort"}, &sortCmd)
-	root.Register([]string{"infos"}, &infosCmd)
-	root.Register([]string{"studio", "query"}, &terminalQueryCmd)
-
+	for _, c := range commands {
+		root.Register(c.Path, c.Cmd)
+	}
 	return root
 }
+
+func findHidden(args []string) (*cli.Command, []string, bool) {
+	for _, c := range hidden {
+		if len(args) >= len(c.Path) && slices.Equal(args[:len(c.Path)], c.Path) {
+			return c.Cmd, args[len(c.Path):], true
+		}
+	}
+	return nil, nil, false
+}
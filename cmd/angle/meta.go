@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/midbel/cli"
+)
+
+var metaCommandsCmd = cli.Command{
+	Name:    "commands",
+	Summary: "dump the catalog of angle commands",
+	Handler: &MetaCommandsCmd{},
+}
+
+var metaDocsCmd = cli.Command{
+	Name:    "docs",
+	Summary: "generate man pages or markdown documentation of angle commands",
+	Handler: &MetaDocsCmd{},
+}
+
+type flagInfo struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+type commandInfo struct {
+	Command string     `json:"command"`
+	Path    []string   `json:"path"`
+	Name    string     `json:"name"`
+	Alias   []string   `json:"alias,omitempty"`
+	Summary string     `json:"summary,omitempty"`
+	Help    string     `json:"help,omitempty"`
+	Flags   []flagInfo `json:"flags,omitempty"`
+}
+
+// flagger is implemented by the handlers of the commands accepting options.
+type flagger interface {
+	flags() *flag.FlagSet
+}
+
+func getCommandInfo(c commandEntry) commandInfo {
+	path := append([]string{"angle"}, c.Path...)
+	info := commandInfo{
+		Command: strings.Join(path, " "),
+		Path:    c.Path,
+		Name:    c.Cmd.Name,
+		Alias:   c.Cmd.Alias,
+		Summary: c.Cmd.Summary,
+		Help:    c.Cmd.Help,
+	}
+	if f, ok := c.Cmd.Handler.(flagger); ok {
+		info.Flags = getFlags(f.flags())
+	}
+	return info
+}
+
+func getFlags(set *flag.FlagSet) []flagInfo {
+	var list []flagInfo
+	set.VisitAll(func(f *flag.Flag) {
+		info := flagInfo{
+			Name:  f.Name,
+			Usage: f.Usage,
+		}
+		switch f.DefValue {
+		case "", "0", "false":
+		default:
+			info.Default = f.DefValue
+		}
+		list = append(list, info)
+	})
+	return list
+}
+
+func getCatalog() []commandInfo {
+	var list []commandInfo
+	for _, c := range commands {
+		list = append(list, getCommandInfo(c))
+	}
+	return list
+}
+
+type MetaCommandsCmd struct {
+	Json bool
+}
+
+func (m *MetaCommandsCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("commands")
+	set.BoolVar(&m.Json, "json", false, "write the catalog as json")
+	return set
+}
+
+func (m *MetaCommandsCmd) Run(args []string) error {
+	set := m.flags()
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	list := getCatalog()
+	if m.Json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+	for _, c := range list {
+		fmt.Fprintf(os.Stdout, "%-24s %s", c.Command, c.Summary)
+		fmt.Fprintln(os.Stdout)
+	}
+	return nil
+}
+
+type MetaDocsCmd struct {
+	Format string
+	Dir    string
+}
+
+func (m *MetaDocsCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("docs")
+	set.StringVar(&m.Format, "format", "markdown", "format of the generated documentation (man, markdown)")
+	set.StringVar(&m.Dir, "d", "", "directory where the documentation will be written")
+	return set
+}
+
+func (m *MetaDocsCmd) Run(args []string) error {
+	set := m.flags()
+	if err := set.Parse(args); err != nil {
+		return err
+	}
+	switch m.Format {
+	case "man":
+		return m.writeMan(getCatalog())
+	case "markdown", "md":
+		return m.writeMarkdown(getCatalog())
+	default:
+		return fmt.Errorf("%s: unsupported documentation format", m.Format)
+	}
+}
+
+func (m *MetaDocsCmd) writeMarkdown(list []commandInfo) error {
+	var w io.Writer = os.Stdout
+	if m.Dir != "" {
+		if err := os.MkdirAll(m.Dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(m.Dir, "angle.md"))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	fmt.Fprintln(w, "# angle")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, summary)
+	for _, c := range list {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "## %s", c.Command)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w)
+		if c.Summary != "" {
+			fmt.Fprintln(w, c.Summary)
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "```")
+		fmt.Fprintf(w, "%s [options] [arguments]", c.Command)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "```")
+		if len(c.Alias) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "aliases: %s", strings.Join(c.Alias, ", "))
+			fmt.Fprintln(w)
+		}
+		if len(c.Flags) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "options:")
+			fmt.Fprintln(w)
+			for _, f := range c.Flags {
+				fmt.Fprintf(w, "* `-%s`: %s", f.Name, f.Usage)
+				if f.Default != "" {
+					fmt.Fprintf(w, " (default: %s)", f.Default)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+		if c.Help != "" {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, c.Help)
+		}
+	}
+	return nil
+}
+
+func (m *MetaDocsCmd) writeMan(list []commandInfo) error {
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, c := range list {
+		name := strings.ReplaceAll(c.Command, " ", "-")
+		f, err := os.Create(filepath.Join(dir, name+".1"))
+		if err != nil {
+			return err
+		}
+		writeManPage(f, name, c)
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeManPage(w io.Writer, name string, c commandInfo) {
+	fmt.Fprintf(w, ".TH %s 1", strings.ToUpper(name))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- %s", name, escapeRoff(c.Summary))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s", c.Command)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "[options] [arguments]")
+	if len(c.Alias) > 0 {
+		fmt.Fprintln(w, ".SH ALIASES")
+		fmt.Fprintln(w, escapeRoff(strings.Join(c.Alias, ", ")))
+	}
+	if len(c.Flags) > 0 {
+		fmt.Fprintln(w, ".SH OPTIONS")
+		for _, f := range c.Flags {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".B \\-%s", f.Name)
+			fmt.Fprintln(w)
+			usage := f.Usage
+			if f.Default != "" {
+				usage += fmt.Sprintf(" (default: %s)", f.Default)
+			}
+			fmt.Fprintln(w, escapeRoff(usage))
+		}
+	}
+	if c.Help != "" {
+		fmt.Fprintln(w, ".SH DESCRIPTION")
+		fmt.Fprintln(w, escapeRoff(c.Help))
+	}
+}
+
+func escapeRoff(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\e`)
+	lines := strings.Split(str, "\n")
+	for i := range lines {
+		if strings.HasPrefix(lines[i], ".") || strings.HasPrefix(lines[i], "'") {
+			lines[i] = `\&` + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	list := getCatalog()
+	if len(list) != len(commands) {
+		t.Fatalf("catalog mismatched! want %d commands, got %d", len(commands), len(list))
+	}
+	seen := make(map[string]struct{})
+	for i, c := range list {
+		want := "angle " + strings.Join(commands[i].Path, " ")
+		if c.Command != want {
+			t.Errorf("command mismatched! want %q, got %q", want, c.Command)
+		}
+		if len(c.Path) == 0 {
+			t.Errorf("%s: command without path", c.Command)
+		}
+		if _, ok := seen[c.Command]; ok {
+			t.Errorf("%s: command registered twice", c.Command)
+		}
+		seen[c.Command] = struct{}{}
+	}
+	for _, c := range []string{"angle meta commands", "angle meta docs"} {
+		if _, ok := seen[c]; ok {
+			t.Errorf("%s: hidden command listed in catalog", c)
+		}
+	}
+	for _, c := range list {
+		if c.Command != "angle convert" {
+			continue
+		}
+		ix := slices.IndexFunc(c.Flags, func(f flagInfo) bool {
+			return f.Name == "to"
+		})
+		if ix < 0 {
+			t.Fatalf("%s: flag to missing from catalog", c.Command)
+		}
+		if f := c.Flags[ix]; f.Default != "json" || f.Usage == "" {
+			t.Errorf("%s: flag to mismatched! got %+v", c.Command, f)
+		}
+	}
+}
+
+func TestFindHidden(t *testing.T) {
+	cmd, args, ok := findHidden([]string{"meta", "docs", "-format", "man"})
+	if !ok || cmd != &metaDocsCmd {
+		t.Fatalf("meta docs should be found in hidden commands")
+	}
+	if want := []string{"-format", "man"}; !slices.Equal(args, want) {
+		t.Errorf("arguments mismatched! want %q, got %q", want, args)
+	}
+	if _, _, ok := findHidden([]string{"meta"}); ok {
+		t.Errorf("incomplete path should not match hidden command")
+	}
+	if _, _, ok := findHidden([]string{"format", "meta", "docs"}); ok {
+		t.Errorf("regular command should not match hidden command")
+	}
+}
+
+func TestEscapeRoff(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+	}{
+		{
+			Input: "plain text",
+			Want:  "plain text",
+		},
+		{
+			Input: `a\b`,
+			Want:  `a\eb`,
+		},
+		{
+			Input: ".start\n'quote\nmiddle.",
+			Want:  "\\&.start\n\\&'quote\nmiddle.",
+		},
+	}
+	for _, c := range tests {
+		if got := escapeRoff(c.Input); got != c.Want {
+			t.Errorf("%q: result mismatched! want %q, got %q", c.Input, c.Want, got)
+		}
+	}
+}
+
+func TestMetaDocs(t *testing.T) {
+	list := []commandInfo{
+		{
+			Command: "angle check",
+			Path:    []string{"check"},
+			Name:    "check",
+			Alias:   []string{"validate"},
+			Summary: "validate documents",
+			Help:    ".hidden help",
+			Flags: []flagInfo{
+				{Name: "xsd", Usage: "xsd schema"},
+				{Name: "max-errors", Usage: "maximum errors", Default: "10"},
+			},
+		},
+	}
+	dir := t.TempDir()
+	cmd := MetaDocsCmd{Dir: dir}
+	if err := cmd.writeMan(list); err != nil {
+		t.Fatalf("fail to write man pages: %s", err)
+	}
+	man, err := os.ReadFile(filepath.Join(dir, "angle-check.1"))
+	if err != nil {
+		t.Fatalf("man page not written: %s", err)
+	}
+	want := `.TH ANGLE-CHECK 1
+.SH NAME
+angle-check \- validate documents
+.SH SYNOPSIS
+.B angle check
+[options] [arguments]
+.SH ALIASES
+validate
+.SH OPTIONS
+.TP
+.B \-xsd
+xsd schema
+.TP
+.B \-max-errors
+maximum errors (default: 10)
+.SH DESCRIPTION
+\&.hidden help
+`
+	if string(man) != want {
+		t.Errorf("man page mismatched!\nwant:\n%s\ngot:\n%s", want, man)
+	}
+
+	if err := cmd.writeMarkdown(list); err != nil {
+		t.Fatalf("fail to write markdown: %s", err)
+	}
+	md, err := os.ReadFile(filepath.Join(dir, "angle.md"))
+	if err != nil {
+		t.Fatalf("markdown not written: %s", err)
+	}
+	for _, str := range []string{"# angle\n", "## angle check\n", "validate documents\n", "aliases: validate\n", "angle check [options] [arguments]\n", "* `-max-errors`: maximum errors (default: 10)\n"} {
+		if !strings.Contains(string(md), str) {
+			t.Errorf("markdown should contain %q", str)
+		}
+	}
+}
@@ -25,13 +25,18 @@ var queryCmd = cli.Command{
 	Handler: &QueryCmd{},
 }
 
-type DebugCmd struct{}
+type DebugCmd struct {
+	Rooted bool
+}
+
+func (q *DebugCmd) flags() *flag.FlagSet {
+	set := cli.NewFlagSet("debug")
+	set.BoolVar(&q.Rooted, "r", false, "from root")
+	return set
+}
 
 func (q *DebugCmd) Run(args []string) error {
-	var (
-		set    = flag.NewFlagSet("debug", flag.ExitOnError)
-		rooted = flag.Bool("r", false, "from root")
-	)
+	set := q.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
@@ -42,7 +47,7 @@ func (q *DebugCmd) Run(args []string) error {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
 	}
-	if *rooted {
+	if q.Rooted {
 		expr = xpath.FromRoot(expr)
 	}
 	str := xpath.Debug(expr)
@@ -165,8 +170,8 @@ func (q *QueryCmd) configureNS(doc *xml.Document) error {
 		if !ok {
 			continue
 		}
-		a := el.GetAttribute("prefix")
-		q.eval.RegisterNS(a.Value(), el.Value())
+		prefix, _ := el.GetAttribute("prefix")
+		q.eval.RegisterNS(prefix, el.Value())
 	}
 	return nil
 }
@@ -229,17 +234,18 @@ func (q *QueryCmd) configureVars(doc *xml.Document) error {
 		if !ok {
 			continue
 		}
-		a := el.GetAttribute("name")
-		q.eval.Define(a.Value(), el.Value())
+		name, _ := el.GetAttribute("name")
+		q.eval.Define(name, el.Value())
 	}
 	return nil
 }
 
-func (q *QueryCmd) parseArgs(args []string) error {
+func (q *QueryCmd) flags() *flag.FlagSet {
 	set := cli.NewFlagSet("query")
 	set.BoolVar(&q.Quiet, "quiet", false, "suppress output")
 	set.BoolVar(&q.StrictNS, "strict-namespace", false, "strict namespace checking")
 	set.BoolVar(&q.OmitProlog, "omit-prolog", false, "omit xml prolog")
+	set.BoolVar(&q.XInclude, "xinclude", false, "process xinclude elements")
 	set.IntVar(&q.Limit, "limit", 0, "limit number of results returned by query")
 	set.IntVar(&q.Depth, "level", 0, "print n level of matching node")
 	set.IntVar(&q.Depth, "depth", 0, "print n level of matching node")
@@ -257,6 +263,11 @@ func (q *QueryCmd) parseArgs(args []string) error {
 		return nil
 	})
 	set.Func("config", "configuration file", q.configure)
+	return set
+}
+
+func (q *QueryCmd) parseArgs(args []string) error {
+	set := q.flags()
 	err := set.Parse(args)
 	if err == nil {
 		q.query = set.Arg(0)
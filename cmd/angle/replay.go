@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+)
+
+var ErrReplay = errors.New("no recorded response")
+
+var (
+	recordDir = os.Getenv("ANGLE_RECORD")
+	replayDir = os.Getenv("ANGLE_REPLAY")
+)
+
+func setupTransport() error {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return fmt.Errorf("record and replay can not be used together")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0o755); err != nil {
+			return err
+		}
+		http.DefaultClient.Transport = recordTransport{
+			dir:  recordDir,
+			next: http.DefaultTransport,
+		}
+	case replayDir != "":
+		http.DefaultClient.Transport = replayTransport{
+			dir: replayDir,
+		}
+	default:
+	}
+	return nil
+}
+
+type recordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(t.dir, key), dump, 0o644); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	dump, err := os.ReadFile(filepath.Join(t.dir, key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("%w: %s %s", ErrReplay, req.Method, req.URL)
+		}
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
+
+func requestKey(req *http.Request) (string, error) {
+	sum := sha256.New()
+	io.WriteString(sum, req.Method)
+	io.WriteString(sum, " ")
+	io.WriteString(sum, req.URL.String())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		sum.Write(body)
+	}
+	return hex.EncodeToString(sum.Sum(nil)) + ".http", nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, "<response path=\""+r.URL.Path+"\">"+string(body)+"</response>")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	record := &http.Client{
+		Transport: recordTransport{
+			dir:  dir,
+			next: http.DefaultTransport,
+		},
+	}
+	replay := &http.Client{
+		Transport: replayTransport{
+			dir: dir,
+		},
+	}
+
+	first := fetchBody(t, record, http.MethodGet, srv.URL+"/doc.xml", "")
+	second := fetchBody(t, record, http.MethodPost, srv.URL+"/doc.xml", "<query/>")
+	if hits != 2 {
+		t.Fatalf("server should be hit once per request! got %d", hits)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("fail to read record directory: %s", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("recorded responses mismatched! want 2, got %d", len(files))
+	}
+
+	if got := fetchBody(t, replay, http.MethodGet, srv.URL+"/doc.xml", ""); got != first {
+		t.Errorf("replayed response mismatched! want %q, got %q", first, got)
+	}
+	if got := fetchBody(t, replay, http.MethodPost, srv.URL+"/doc.xml", "<query/>"); got != second {
+		t.Errorf("replayed response mismatched! want %q, got %q", second, got)
+	}
+	if hits != 2 {
+		t.Errorf("replay should not hit the server! got %d hits", hits)
+	}
+
+	_, err = replay.Get(srv.URL + "/other.xml")
+	if !errors.Is(err, ErrReplay) {
+		t.Errorf("expected %v, got %v", ErrReplay, err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/doc.xml", strings.NewReader("<other/>"))
+	if _, err := replay.Do(req); !errors.Is(err, ErrReplay) {
+		t.Errorf("different body should not be replayed! expected %v, got %v", ErrReplay, err)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "http://example.com/doc.xml", nil)
+	post, _ := http.NewRequest(http.MethodPost, "http://example.com/doc.xml", strings.NewReader("body"))
+
+	k1, err := requestKey(get)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	k2, err := requestKey(post)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if k1 == k2 {
+		t.Errorf("method and body should be part of the key")
+	}
+	if !strings.HasSuffix(k1, ".http") {
+		t.Errorf("key should have .http extension: %s", k1)
+	}
+	body, _ := io.ReadAll(post.Body)
+	if string(body) != "body" {
+		t.Errorf("request body should be preserved! got %q", body)
+	}
+}
+
+func fetchBody(t *testing.T, client *http.Client, method, url, body string) string {
+	t.Helper()
+	var rs io.Reader
+	if body != "" {
+		rs = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, rs)
+	if err != nil {
+		t.Fatalf("fail to create request: %s", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: unexpected error: %s", method, url, err)
+	}
+	defer res.Body.Close()
+	buf, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("fail to read response: %s", err)
+	}
+	return string(buf)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+const resourceScheme = "res"
+
+var (
+	//go:embed resources
+	embedded embed.FS
+
+	resourceDir = os.Getenv("ANGLE_RESOURCES")
+)
+
+type resourceFS struct {
+	dir  fs.FS
+	base fs.FS
+}
+
+func getResources() fs.FS {
+	base, _ := fs.Sub(embedded, "resources")
+	if resourceDir == "" {
+		return base
+	}
+	return resourceFS{
+		dir:  os.DirFS(resourceDir),
+		base: base,
+	}
+}
+
+func (r resourceFS) Open(name string) (fs.File, error) {
+	f, err := r.dir.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return r.base.Open(name)
+}
+
+func openResource(name string) (io.ReadCloser, error) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	return getResources().Open(name)
+}
+
+func readResource(name string) ([]byte, error) {
+	r, err := openResource(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func isResource(file string) bool {
+	return strings.HasPrefix(file, resourceScheme+":")
+}
+
+func resourceName(file string) string {
+	return strings.TrimPrefix(file, resourceScheme+":")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResources(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "templates"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "report.html"), []byte("custom"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	embeddedReport, err := fs.ReadFile(embedded, "resources/templates/report.html")
+	if err != nil {
+		t.Fatalf("report template should be embedded: %s", err)
+	}
+
+	tests := []struct {
+		Name string
+		Dir  string
+		File string
+		Want string
+	}{
+		{
+			Name: "embedded",
+			File: "templates/report.html",
+			Want: string(embeddedReport),
+		},
+		{
+			Name: "override",
+			Dir:  dir,
+			File: "templates/report.html",
+			Want: "custom",
+		},
+		{
+			Name: "override-absolute",
+			Dir:  dir,
+			File: "/templates/report.html",
+			Want: "custom",
+		},
+		{
+			Name: "fallback",
+			Dir:  dir,
+			File: "schemas/catalog.rnc",
+		},
+	}
+	defer func(dir string) {
+		resourceDir = dir
+	}(resourceDir)
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			resourceDir = c.Dir
+			got, err := readResource(c.File)
+			if err != nil {
+				t.Fatalf("fail to read resource: %s", err)
+			}
+			if c.Want != "" && string(got) != c.Want {
+				t.Errorf("resource mismatched! want %q, got %q", c.Want, got)
+			}
+			if c.Want == "" && len(got) == 0 {
+				t.Errorf("embedded resource expected")
+			}
+		})
+	}
+}
+
+func TestResourcesMissing(t *testing.T) {
+	defer func(dir string) {
+		resourceDir = dir
+	}(resourceDir)
+	resourceDir = t.TempDir()
+	if _, err := readResource("templates/missing.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	tests := []struct {
+		File     string
+		Resource bool
+		Name     string
+	}{
+		{
+			File:     "res:schemas/catalog.rnc",
+			Resource: true,
+			Name:     "schemas/catalog.rnc",
+		},
+		{
+			File: "schemas/catalog.rnc",
+			Name: "schemas/catalog.rnc",
+		},
+	}
+	for _, c := range tests {
+		if got := isResource(c.File); got != c.Resource {
+			t.Errorf("%s: resource mismatched! want %t, got %t", c.File, c.Resource, got)
+		}
+		if got := resourceName(c.File); got != c.Name {
+			t.Errorf("%s: name mismatched! want %s, got %s", c.File, c.Name, got)
+		}
+	}
+}
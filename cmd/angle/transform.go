@@ -1,8 +1,14 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/midbel/cli"
 	"github.com/midbel/codecs/xslt"
@@ -15,41 +21,68 @@ var transformCmd = cli.Command{
 }
 
 type TransformCmd struct {
-	Context  string
-	Mode     string
-	Trace    bool
-	Quiet    bool
-	WrapRoot bool
-	File     string
+	Context    string
+	Mode       string
+	Trace      bool
+	Quiet      bool
+	WrapRoot   bool
+	Permissive bool
+	Check      bool
+	Allow      []string
+	Then       []string
+	File       string
+	Base       string
+	Coverage   string
 	ParserOptions
 }
 
-func (c *TransformCmd) Run(args []string) error {
+func (c *TransformCmd) flags() *flag.FlagSet {
 	set := cli.NewFlagSet("transform")
 	set.BoolVar(&c.Quiet, "q", false, "quiet")
 	set.StringVar(&c.Mode, "m", "", "default mode")
 	set.BoolVar(&c.WrapRoot, "w", false, "wrap nodes under a single root element")
 	set.StringVar(&c.Context, "d", "", "context directory")
 	set.StringVar(&c.File, "f", "", "output file")
+	set.StringVar(&c.Base, "base", "", "base output uri used to resolve result documents")
+	set.BoolVar(&c.Permissive, "permissive", false, "continue transformation after recoverable errors")
+	set.BoolVar(&c.Check, "check", false, "check stylesheet(s) for errors without running the transformation")
+	set.StringVar(&c.Coverage, "coverage", "", "write template coverage report of the main stylesheet (json or html)")
 	set.BoolVar(&c.StrictNS, "strict-ns", false, "strict namespace checking")
 	set.BoolVar(&c.KeepEmpty, "keep-empty", false, "keep empty element")
 	set.BoolVar(&c.OmitProlog, "omit-prolog", false, "omit xml prolog")
+	set.BoolVar(&c.XInclude, "xinclude", false, "process xinclude elements")
+	set.Func("allow", "allow extension function set requested by stylesheet", func(str string) error {
+		c.Allow = append(c.Allow, strings.Split(str, ",")...)
+		return nil
+	})
+	set.Func("then", "apply stylesheet on the result of the previous transformation", func(str string) error {
+		c.Then = append(c.Then, str)
+		return nil
+	})
+	return set
+}
 
+func (c *TransformCmd) Run(args []string) error {
+	set := c.flags()
 	if err := set.Parse(args); err != nil {
 		return err
 	}
-
-	doc, err := parseDocument(set.Arg(1), c.ParserOptions)
-	if err != nil {
-		return err
+	if c.Check {
+		return c.check(append([]string{set.Arg(0)}, c.Then...))
 	}
 
-	sheet, err := xslt.Load(set.Arg(0), c.Context)
-	if err != nil {
-		return err
+	var sheets []*xslt.Stylesheet
+	for _, file := range append([]string{set.Arg(0)}, c.Then...) {
+		sheet, err := c.load(file)
+		if err != nil {
+			return err
+		}
+		sheets = append(sheets, sheet)
+	}
+	var cov *xslt.Coverage
+	if c.Coverage != "" {
+		cov = sheets[0].EnableCoverage()
 	}
-	sheet.Mode = c.Mode
-	sheet.WrapRoot = c.WrapRoot
 	var w io.Writer = os.Stdout
 	if c.Quiet {
 		w = io.Discard
@@ -61,5 +94,102 @@ func (c *TransformCmd) Run(args []string) error {
 		defer f.Close()
 		w = f
 	}
-	return sheet.Generate(w, doc)
+	files := set.Args()[1:]
+	switch {
+	case len(files) == 0:
+		files = append(files, "")
+	case cov == nil:
+		files = files[:1]
+	}
+	run := xslt.Chain(sheets...)
+	for _, file := range files {
+		doc, err := parseDocument(file, c.ParserOptions)
+		if err != nil {
+			return err
+		}
+		err = run.Generate(w, doc)
+		for _, d := range run.Diagnostics() {
+			fmt.Fprintln(os.Stderr, d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if cov == nil {
+		return nil
+	}
+	return writeCoverage(c.Coverage, set.Arg(0), cov.Report())
+}
+
+func (c *TransformCmd) check(files []string) error {
+	var invalid int
+	for _, file := range files {
+		sheet, err := c.load(file)
+		if err != nil {
+			return err
+		}
+		var problems xslt.ProblemList
+		if err := sheet.Check(); errors.As(err, &problems) {
+			for _, p := range problems {
+				fmt.Fprintln(os.Stderr, p)
+			}
+			invalid++
+		} else if err != nil {
+			return err
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d stylesheet(s) with problems", invalid)
+	}
+	return nil
+}
+
+func (c *TransformCmd) load(file string) (*xslt.Stylesheet, error) {
+	sheet, err := xslt.Load(file, c.Context)
+	if err != nil {
+		return nil, err
+	}
+	sheet.Mode = c.Mode
+	sheet.WrapRoot = c.WrapRoot
+	sheet.Permissive = c.Permissive
+	sheet.OutputBase = c.Base
+	if sheet.OutputBase == "" {
+		sheet.OutputBase = c.File
+	}
+	if err := sheet.ApplyPolicy(xslt.AllowExtensions(c.Allow...)); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+type coverageReport struct {
+	File string
+	xslt.CoverageReport
+}
+
+func writeCoverage(file, sheet string, report xslt.CoverageReport) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch filepath.Ext(file) {
+	case ".html", ".htm":
+	default:
+		return report.WriteJSON(f)
+	}
+	str, err := readResource("templates/coverage.html")
+	if err != nil {
+		return err
+	}
+	tpl, err := template.New("coverage").Parse(string(str))
+	if err != nil {
+		return err
+	}
+	ctx := coverageReport{
+		File:           filepath.Base(sheet),
+		CoverageReport: report,
+	}
+	return tpl.Execute(f, ctx)
 }
@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/midbel/codecs/csv"
 	"github.com/midbel/codecs/json"
 	"github.com/midbel/codecs/jsonata"
 )
@@ -13,6 +15,8 @@ func main() {
 	var (
 		query   = flag.String("q", "", "query")
 		compact = flag.Bool("c", false, "compact")
+		output  = flag.Bool("csv", false, "write result as csv")
+		schema  = flag.String("schema", "", "type of csv columns (name:type,...)")
 	)
 	flag.Parse()
 
@@ -23,12 +27,45 @@ func main() {
 	}
 	defer r.Close()
 
-	doc, err := jsonata.Find(r, *query)
+	var doc any
+	if filepath.Ext(flag.Arg(0)) == ".csv" {
+		doc, err = findCSV(r, *query, *schema)
+	} else {
+		doc, err = jsonata.Find(r, *query)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *output {
+		err = csv.NewWriter(os.Stdout).Write(doc)
+	} else {
+		ws := json.NewWriter(os.Stdout)
+		ws.Compact = *compact
+		err = ws.Write(doc)
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	ws := json.NewWriter(os.Stdout)
-	ws.Compact = *compact
-	ws.Write(doc)
+}
+
+func findCSV(r *os.File, query, schema string) (any, error) {
+	p := csv.NewParser(r)
+	if schema != "" {
+		s, err := csv.ParseSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		p.Schema = s
+	}
+	doc, err := p.Parse()
+	if err != nil || query == "" {
+		return doc, err
+	}
+	q, err := jsonata.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	return q.Get(doc)
 }
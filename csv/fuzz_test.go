@@ -0,0 +1,27 @@
+package csv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzDecode(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"name,age,active\nfoo,42,true\nbar,,false\n",
+		"a;b\n\"quoted; field\";\"with \"\"quote\"\"\"\n",
+		"date,value\n2024-01-05,1e3\r\n2024-01-05T10:00:00Z,-0.5\r\n",
+		"a,b\n\"multi\nline\",2\n",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			doc, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			return NewWriter(&buf).Write(doc)
+		})
+	})
+}
@@ -0,0 +1,403 @@
+package csv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	errSyntax = errors.New("syntax error")
+	errType   = errors.New("invalid type")
+)
+
+type Type int8
+
+const (
+	Auto Type = iota
+	String
+	Number
+	Boolean
+	Date
+)
+
+func (t Type) String() string {
+	switch t {
+	case Auto:
+		return "auto"
+	case String:
+		return "string"
+	case Number:
+		return "number"
+	case Boolean:
+		return "boolean"
+	case Date:
+		return "date"
+	default:
+		return "<unknown>"
+	}
+}
+
+func ParseType(str string) (Type, error) {
+	switch strings.ToLower(str) {
+	case "", "auto":
+		return Auto, nil
+	case "string", "str":
+		return String, nil
+	case "number", "num", "float", "int":
+		return Number, nil
+	case "boolean", "bool":
+		return Boolean, nil
+	case "date", "datetime":
+		return Date, nil
+	default:
+		return Auto, fmt.Errorf("%w: %s", errType, str)
+	}
+}
+
+type Schema map[string]Type
+
+func ParseSchema(str string) (Schema, error) {
+	schema := make(Schema)
+	for _, field := range strings.Split(str, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, kind, ok := strings.Cut(field, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s: missing type", field)
+		}
+		t, err := ParseType(strings.TrimSpace(kind))
+		if err != nil {
+			return nil, err
+		}
+		schema[strings.TrimSpace(name)] = t
+	}
+	return schema, nil
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+}
+
+const (
+	dateLayout     = "2006-01-02"
+	datetimeLayout = time.RFC3339
+)
+
+func Decode(r io.Reader) (any, error) {
+	return NewParser(r).Parse()
+}
+
+type Parser struct {
+	reader *bufio.Reader
+	line   int
+
+	Delimiter rune
+	Quote     rune
+	Comment   rune
+	NoHeader  bool
+	NoInfer   bool
+	Schema
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		reader:    bufio.NewReader(r),
+		line:      1,
+		Delimiter: ',',
+		Quote:     '"',
+	}
+}
+
+func (p *Parser) Parse() (any, error) {
+	var (
+		list    []any
+		headers []string
+	)
+	if !p.NoHeader {
+		row, err := p.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return list, nil
+			}
+			return nil, err
+		}
+		headers = getHeaders(row)
+	}
+	for {
+		line := p.line
+		row, err := p.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if p.NoHeader {
+			arr := make([]any, 0, len(row))
+			for i := range row {
+				v, err := p.convert(strconv.Itoa(i+1), row[i])
+				if err != nil {
+					return nil, fmt.Errorf("line %d, field %d: %w", line, i+1, err)
+				}
+				arr = append(arr, v)
+			}
+			list = append(list, arr)
+			continue
+		}
+		if len(row) > len(headers) {
+			return nil, fmt.Errorf("line %d: %w: too many fields (want %d, got %d)", line, errSyntax, len(headers), len(row))
+		}
+		obj := make(map[string]any)
+		for i, h := range headers {
+			if i >= len(row) {
+				obj[h] = nil
+				continue
+			}
+			v, err := p.convert(h, row[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d, field %s: %w", line, h, err)
+			}
+			obj[h] = v
+		}
+		list = append(list, obj)
+	}
+	return list, nil
+}
+
+func (p *Parser) ReadRecord() ([]string, error) {
+	for {
+		c, _, err := p.reader.ReadRune()
+		if err != nil {
+			return nil, err
+		}
+		if c == '\n' {
+			p.line++
+			continue
+		}
+		if c == '\r' {
+			continue
+		}
+		if p.Comment != 0 && c == p.Comment {
+			if _, err := p.reader.ReadString('\n'); err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			p.line++
+			continue
+		}
+		p.reader.UnreadRune()
+		break
+	}
+	var row []string
+	for {
+		field, more, err := p.readField()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, field)
+		if !more {
+			break
+		}
+	}
+	return row, nil
+}
+
+func (p *Parser) readField() (string, bool, error) {
+	var (
+		str    strings.Builder
+		quoted bool
+	)
+	c, _, err := p.reader.ReadRune()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if c == p.Quote {
+		quoted = true
+	} else {
+		p.reader.UnreadRune()
+	}
+	for {
+		c, _, err := p.reader.ReadRune()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return "", false, err
+			}
+			if quoted {
+				return "", false, fmt.Errorf("line %d: %w: unterminated quoted field", p.line, errSyntax)
+			}
+			return str.String(), false, nil
+		}
+		if quoted {
+			if c == '\n' {
+				p.line++
+			}
+			if c != p.Quote {
+				str.WriteRune(c)
+				continue
+			}
+			next, _, err := p.reader.ReadRune()
+			if err == nil && next == p.Quote {
+				str.WriteRune(c)
+				continue
+			}
+			if err == nil {
+				p.reader.UnreadRune()
+			}
+			quoted = false
+			continue
+		}
+		switch c {
+		case p.Delimiter:
+			return str.String(), true, nil
+		case '\n':
+			p.line++
+			return str.String(), false, nil
+		case '\r':
+			if next, _, err := p.reader.ReadRune(); err == nil && next != '\n' {
+				p.reader.UnreadRune()
+			} else if err == nil {
+				p.line++
+			}
+			return str.String(), false, nil
+		case p.Quote:
+			return "", false, fmt.Errorf("line %d: %w: unexpected quote in unquoted field", p.line, errSyntax)
+		default:
+			str.WriteRune(c)
+		}
+	}
+}
+
+func (p *Parser) convert(name, value string) (any, error) {
+	if p.NoInfer {
+		return value, nil
+	}
+	switch p.Schema[name] {
+	case String:
+		return value, nil
+	case Number:
+		if value == "" {
+			return nil, nil
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a number", errType, value)
+		}
+		return n, nil
+	case Boolean:
+		if value == "" {
+			return nil, nil
+		}
+		b, ok := parseBool(value)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a boolean", errType, value)
+		}
+		return b, nil
+	case Date:
+		if value == "" {
+			return nil, nil
+		}
+		d, ok := parseDate(value)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a date", errType, value)
+		}
+		return d, nil
+	default:
+		return inferValue(value), nil
+	}
+}
+
+func inferValue(value string) any {
+	if value == "" {
+		return nil
+	}
+	if b, ok := parseBool(value); ok {
+		return b
+	}
+	if isNumber(value) {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	if d, ok := parseDate(value); ok {
+		return d
+	}
+	return value
+}
+
+func isNumber(str string) bool {
+	digits := strings.TrimLeft(str, "+-")
+	if digits == "" || len(str)-len(digits) > 1 {
+		return false
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	return strings.IndexFunc(digits, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != 'e' && r != 'E' && r != '-' && r != '+'
+	}) < 0
+}
+
+func parseBool(str string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func parseDate(str string) (string, bool) {
+	str = strings.TrimSpace(str)
+	if len(str) < len(dateLayout) || !utf8.ValidString(str) {
+		return "", false
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, str)
+		if err != nil {
+			continue
+		}
+		if len(str) == len(dateLayout) {
+			return t.Format(dateLayout), true
+		}
+		return t.Format(datetimeLayout), true
+	}
+	return "", false
+}
+
+func getHeaders(row []string) []string {
+	var (
+		list = make([]string, 0, len(row))
+		seen = make(map[string]int)
+	)
+	for i, h := range row {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			h = fmt.Sprintf("column%d", i+1)
+		}
+		if n := seen[h]; n > 0 {
+			seen[h]++
+			h = fmt.Sprintf("%s_%d", h, n+1)
+		} else {
+			seen[h] = 1
+		}
+		list = append(list, h)
+	}
+	return list
+}
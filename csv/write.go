@@ -0,0 +1,192 @@
+package csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/codecs/json"
+)
+
+const valueColumn = "value"
+
+type Writer struct {
+	ws *bufio.Writer
+
+	Delimiter rune
+	Quote     rune
+	NoHeader  bool
+	Columns   []string
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		ws:        bufio.NewWriter(w),
+		Delimiter: ',',
+		Quote:     '"',
+	}
+}
+
+func (w *Writer) Write(value any) error {
+	defer w.ws.Flush()
+
+	var rows []any
+	switch v := value.(type) {
+	case []any:
+		rows = v
+	case nil:
+		return nil
+	default:
+		rows = append(rows, v)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if isTable(rows) {
+		for _, r := range rows {
+			record, err := getFields(r.([]any))
+			if err != nil {
+				return err
+			}
+			w.writeRecord(record)
+		}
+		return nil
+	}
+	columns := w.getColumns(rows)
+	if !w.NoHeader {
+		w.writeRecord(columns)
+	}
+	for _, r := range rows {
+		obj, ok := r.(map[string]any)
+		if !ok {
+			obj = map[string]any{
+				valueColumn: r,
+			}
+		}
+		record := make([]string, 0, len(columns))
+		for _, c := range columns {
+			str, err := formatValue(obj[c])
+			if err != nil {
+				return err
+			}
+			record = append(record, str)
+		}
+		w.writeRecord(record)
+	}
+	return nil
+}
+
+func (w *Writer) WriteRecord(record []string) error {
+	w.writeRecord(record)
+	return w.ws.Flush()
+}
+
+func (w *Writer) writeRecord(record []string) {
+	for i, field := range record {
+		if i > 0 {
+			w.ws.WriteRune(w.Delimiter)
+		}
+		w.writeField(field)
+	}
+	w.ws.WriteByte('\n')
+}
+
+func (w *Writer) writeField(field string) {
+	if !w.needQuote(field) {
+		w.ws.WriteString(field)
+		return
+	}
+	var (
+		quote = string(w.Quote)
+		str   = strings.ReplaceAll(field, quote, quote+quote)
+	)
+	w.ws.WriteString(quote)
+	w.ws.WriteString(str)
+	w.ws.WriteString(quote)
+}
+
+func (w *Writer) needQuote(field string) bool {
+	if field == "" {
+		return false
+	}
+	if strings.ContainsRune(field, w.Delimiter) || strings.ContainsRune(field, w.Quote) {
+		return true
+	}
+	return strings.ContainsAny(field, "\r\n") || field != strings.TrimSpace(field)
+}
+
+func (w *Writer) getColumns(rows []any) []string {
+	if len(w.Columns) > 0 {
+		return w.Columns
+	}
+	var (
+		seen   = make(map[string]struct{})
+		scalar bool
+	)
+	for _, r := range rows {
+		obj, ok := r.(map[string]any)
+		if !ok {
+			scalar = true
+			continue
+		}
+		for k := range obj {
+			seen[k] = struct{}{}
+		}
+	}
+	if scalar {
+		seen[valueColumn] = struct{}{}
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+func isTable(rows []any) bool {
+	for _, r := range rows {
+		if _, ok := r.([]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func getFields(values []any) ([]string, error) {
+	record := make([]string, 0, len(values))
+	for _, v := range values {
+		str, err := formatValue(v)
+		if err != nil {
+			return nil, err
+		}
+		record = append(record, str)
+	}
+	return record, nil
+}
+
+func formatValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case map[string]any, []any:
+		var str strings.Builder
+		ws := json.Compact(&str)
+		ws.SortKeys = true
+		if err := ws.Write(v); err != nil {
+			return "", err
+		}
+		return str.String(), nil
+	default:
+		return "", fmt.Errorf("%w: unsupported csv type %T", errType, value)
+	}
+}
@@ -0,0 +1,170 @@
+package html
+
+import (
+	"slices"
+	"strings"
+)
+
+var voidElements = []string{
+	"area",
+	"base",
+	"br",
+	"col",
+	"embed",
+	"hr",
+	"img",
+	"input",
+	"link",
+	"meta",
+	"param",
+	"source",
+	"track",
+	"wbr",
+}
+
+var rawTextElements = []string{
+	"script",
+	"style",
+	"xmp",
+	"iframe",
+	"noembed",
+	"noframes",
+}
+
+var escapableRawTextElements = []string{
+	"textarea",
+	"title",
+}
+
+var booleanAttributes = []string{
+	"allowfullscreen",
+	"async",
+	"autofocus",
+	"autoplay",
+	"checked",
+	"controls",
+	"default",
+	"defer",
+	"disabled",
+	"formnovalidate",
+	"hidden",
+	"inert",
+	"ismap",
+	"itemscope",
+	"loop",
+	"multiple",
+	"muted",
+	"nomodule",
+	"novalidate",
+	"open",
+	"playsinline",
+	"readonly",
+	"required",
+	"reversed",
+	"selected",
+}
+
+var headElements = []string{
+	"base",
+	"link",
+	"meta",
+	"noscript",
+	"script",
+	"style",
+	"template",
+	"title",
+}
+
+var impliedEnds = map[string][]string{
+	"li":       {"li", "p"},
+	"dt":       {"dt", "dd", "p"},
+	"dd":       {"dt", "dd", "p"},
+	"tr":       {"td", "th", "tr"},
+	"td":       {"td", "th"},
+	"th":       {"td", "th"},
+	"thead":    {"td", "th", "tr", "tbody", "thead", "tfoot"},
+	"tbody":    {"td", "th", "tr", "tbody", "thead", "tfoot"},
+	"tfoot":    {"td", "th", "tr", "tbody", "thead", "tfoot"},
+	"option":   {"option"},
+	"optgroup": {"option", "optgroup"},
+}
+
+var closeParagraph = []string{
+	"address",
+	"article",
+	"aside",
+	"blockquote",
+	"details",
+	"div",
+	"dl",
+	"fieldset",
+	"figcaption",
+	"figure",
+	"footer",
+	"form",
+	"h1",
+	"h2",
+	"h3",
+	"h4",
+	"h5",
+	"h6",
+	"header",
+	"hr",
+	"main",
+	"menu",
+	"nav",
+	"ol",
+	"p",
+	"pre",
+	"section",
+	"table",
+	"ul",
+}
+
+var (
+	defaultScope = []string{"html", "body", "table", "td", "th", "caption", "template", "button"}
+	listScope    = []string{"html", "body", "table", "td", "th", "caption", "template", "ul", "ol"}
+	tableScope   = []string{"html", "table", "template", "select"}
+)
+
+func IsVoid(name string) bool {
+	return slices.Contains(voidElements, strings.ToLower(name))
+}
+
+func IsBooleanAttr(name string) bool {
+	return slices.Contains(booleanAttributes, strings.ToLower(name))
+}
+
+func isRawText(name string) bool {
+	name = strings.ToLower(name)
+	return slices.Contains(rawTextElements, name) || slices.Contains(escapableRawTextElements, name)
+}
+
+func isEscapableRawText(name string) bool {
+	return slices.Contains(escapableRawTextElements, strings.ToLower(name))
+}
+
+func isHeadElement(name string) bool {
+	return slices.Contains(headElements, name)
+}
+
+func getImpliedEnds(name string) []string {
+	if list, ok := impliedEnds[name]; ok {
+		return list
+	}
+	if slices.Contains(closeParagraph, name) {
+		return []string{"p"}
+	}
+	return nil
+}
+
+func getScope(name string) []string {
+	switch name {
+	case "li":
+		return listScope
+	case "td", "th", "tr", "thead", "tbody", "tfoot", "option", "optgroup":
+		return tableScope
+	default:
+		return defaultScope
+	}
+}
@@ -0,0 +1,237 @@
+package html
+
+import (
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+type Parser struct {
+	scan *Scanner
+
+	doc   *xml.Document
+	html  *xml.Element
+	head  *xml.Element
+	body  *xml.Element
+	stack []*xml.Element
+
+	KeepComment bool
+}
+
+func NewParser(r io.Reader) (*Parser, error) {
+	scan, err := Scan(r)
+	if err != nil {
+		return nil, err
+	}
+	p := Parser{
+		scan:        scan,
+		KeepComment: true,
+	}
+	return &p, nil
+}
+
+func Parse(r io.Reader) (*xml.Document, error) {
+	p, err := NewParser(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse()
+}
+
+func ParseString(str string) (*xml.Document, error) {
+	return Parse(strings.NewReader(str))
+}
+
+func (p *Parser) Parse() (*xml.Document, error) {
+	p.html = xml.NewElement(xml.LocalName("html"))
+	p.doc = xml.NewDocument(p.html)
+	p.head = nil
+	p.body = nil
+	p.stack = []*xml.Element{p.html}
+
+	for {
+		tok := p.scan.Scan()
+		switch tok.Type {
+		case EOF:
+			p.ensureBody()
+			return p.doc, nil
+		case Doctype:
+			p.setDoctype(tok)
+		case Comment:
+			if p.KeepComment {
+				p.current().Append(xml.NewComment(tok.Data))
+			}
+		case Text:
+			p.insertText(tok.Data)
+		case StartTag, SelfClosingTag:
+			p.insertElement(tok)
+		case EndTag:
+			p.closeElement(tok.Name)
+		}
+	}
+}
+
+func (p *Parser) setDoctype(tok Token) {
+	if p.doc.DocType != nil {
+		return
+	}
+	name := "html"
+	if parts := strings.Fields(tok.Data); len(parts) > 0 {
+		name = strings.ToLower(parts[0])
+	}
+	p.doc.DocType = xml.NewDocType(name, "", "")
+}
+
+func (p *Parser) insertText(str string) {
+	curr := p.current()
+	if p.body == nil && (curr == p.html || curr == p.head) {
+		if strings.TrimSpace(str) == "" {
+			return
+		}
+		p.ensureBody()
+		curr = p.current()
+	}
+	if n := len(curr.Nodes); n > 0 {
+		if t, ok := curr.Nodes[n-1].(*xml.Text); ok {
+			t.Content += str
+			return
+		}
+	}
+	curr.Append(xml.NewText(str))
+}
+
+func (p *Parser) insertElement(tok Token) {
+	switch tok.Name {
+	case "html":
+		setAttributes(p.html, tok.Attrs, false)
+		return
+	case "head":
+		if p.head == nil && p.body == nil {
+			p.head = xml.NewElement(xml.LocalName("head"))
+			setAttributes(p.head, tok.Attrs, true)
+			p.html.Append(p.head)
+			p.push(p.head)
+		}
+		return
+	case "body":
+		p.ensureBody()
+		setAttributes(p.body, tok.Attrs, false)
+		return
+	}
+	if p.body == nil {
+		if isHeadElement(tok.Name) {
+			p.ensureHead()
+		} else {
+			p.ensureBody()
+		}
+	}
+	for _, name := range getImpliedEnds(tok.Name) {
+		if ix := p.inScope(name); ix > 0 {
+			p.stack = p.stack[:ix]
+		}
+	}
+	el := xml.NewElement(xml.LocalName(tok.Name))
+	setAttributes(el, tok.Attrs, true)
+	p.current().Append(el)
+
+	if IsVoid(tok.Name) {
+		return
+	}
+	if tok.Type == SelfClosingTag && p.isForeign(tok.Name) {
+		return
+	}
+	p.push(el)
+}
+
+func (p *Parser) closeElement(name string) {
+	switch name {
+	case "html", "body":
+		return
+	case "head":
+		if ix := p.indexOf(name); ix > 0 {
+			p.stack = p.stack[:ix]
+		}
+		return
+	case "br":
+		p.insertElement(Token{Type: StartTag, Name: name})
+		return
+	}
+	ix := p.indexOf(name)
+	if ix <= 0 || p.stack[ix] == p.body {
+		return
+	}
+	p.stack = p.stack[:ix]
+}
+
+func (p *Parser) ensureHead() {
+	if p.head == nil {
+		p.head = xml.NewElement(xml.LocalName("head"))
+		p.html.Insert(p.head, 0)
+		p.push(p.head)
+		return
+	}
+	if !slices.Contains(p.stack, p.head) {
+		p.push(p.head)
+	}
+}
+
+func (p *Parser) ensureBody() {
+	if p.body != nil {
+		return
+	}
+	p.body = xml.NewElement(xml.LocalName("body"))
+	p.html.Append(p.body)
+	p.stack = []*xml.Element{p.html, p.body}
+}
+
+func (p *Parser) inScope(name string) int {
+	scope := getScope(name)
+	for i := len(p.stack) - 1; i > 0; i-- {
+		curr := p.stack[i].LocalName()
+		if curr == name {
+			return i
+		}
+		if slices.Contains(scope, curr) {
+			break
+		}
+	}
+	return -1
+}
+
+func (p *Parser) indexOf(name string) int {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].LocalName() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Parser) isForeign(name string) bool {
+	if name == "svg" || name == "math" {
+		return true
+	}
+	return slices.ContainsFunc(p.stack, func(el *xml.Element) bool {
+		n := el.LocalName()
+		return n == "svg" || n == "math"
+	})
+}
+
+func (p *Parser) push(el *xml.Element) {
+	p.stack = append(p.stack, el)
+}
+
+func (p *Parser) current() *xml.Element {
+	return p.stack[len(p.stack)-1]
+}
+
+func setAttributes(el *xml.Element, attrs []Attr, override bool) {
+	for _, a := range attrs {
+		if _, ok := el.GetAttribute(a.Name); ok && !override {
+			continue
+		}
+		el.SetAttribute(xml.NewAttribute(xml.LocalName(a.Name), a.Value))
+	}
+}
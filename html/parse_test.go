@@ -0,0 +1,75 @@
+package html_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/html"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+	}{
+		{
+			Input: `<p>one<p>two`,
+			Want:  `<html><body><p>one</p><p>two</p></body></html>`,
+		},
+		{
+			Input: `<ul><li>a<li>b</ul>`,
+			Want:  `<html><body><ul><li>a</li><li>b</li></ul></body></html>`,
+		},
+		{
+			Input: `<table><tr><td>a<td>b</table>`,
+			Want:  `<html><body><table><tr><td>a</td><td>b</td></tr></table></body></html>`,
+		},
+		{
+			Input: `<script>if (a < b && c) {}</script>`,
+			Want:  `<html><head><script>if (a < b && c) {}</script></head><body></body></html>`,
+		},
+		{
+			Input: `<br><img src="a.png"><input disabled>`,
+			Want:  `<html><body><br><img src="a.png"><input disabled></body></html>`,
+		},
+		{
+			Input: `<p>a &amp; b &lt; &eacute;</p>`,
+			Want:  `<html><body><p>a &amp; b &lt; é</p></body></html>`,
+		},
+		{
+			Input: `<!DOCTYPE html><title>a &amp; b</title><p>x`,
+			Want:  `<!DOCTYPE html><html><head><title>a &amp; b</title></head><body><p>x</p></body></html>`,
+		},
+		{
+			Input: `<`,
+			Want:  `<html><body>&lt;</body></html>`,
+		},
+		{
+			Input: `<!--`,
+			Want:  `<html><!----><body></body></html>`,
+		},
+		{
+			Input: `<a href="x`,
+			Want:  `<html><body><a href="x"></a></body></html>`,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Input, func(t *testing.T) {
+			doc, err := html.ParseString(c.Input)
+			if err != nil {
+				t.Fatalf("error parsing document: %s", err)
+			}
+			var (
+				str strings.Builder
+				ws  = html.NewWriter(&str)
+			)
+			ws.Compact = true
+			if err := ws.Write(doc); err != nil {
+				t.Fatalf("error writing document: %s", err)
+			}
+			if got := str.String(); got != c.Want {
+				t.Errorf("document mismatched!\nwant: %s\ngot:  %s", c.Want, got)
+			}
+		})
+	}
+}
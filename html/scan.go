@@ -0,0 +1,332 @@
+package html
+
+import (
+	"html"
+	"io"
+	"strings"
+)
+
+type TokenType int8
+
+const (
+	EOF TokenType = iota
+	Text
+	StartTag
+	EndTag
+	SelfClosingTag
+	Comment
+	Doctype
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case EOF:
+		return "<eof>"
+	case Text:
+		return "text"
+	case StartTag:
+		return "start-tag"
+	case EndTag:
+		return "end-tag"
+	case SelfClosingTag:
+		return "self-closing-tag"
+	case Comment:
+		return "comment"
+	case Doctype:
+		return "doctype"
+	default:
+		return "<unknown>"
+	}
+}
+
+type Attr struct {
+	Name  string
+	Value string
+}
+
+type Token struct {
+	Type  TokenType
+	Name  string
+	Data  string
+	Attrs []Attr
+}
+
+type Scanner struct {
+	input string
+	pos   int
+	raw   string
+}
+
+func Scan(r io.Reader) (*Scanner, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ScanString(string(buf)), nil
+}
+
+func ScanString(str string) *Scanner {
+	str = strings.TrimPrefix(str, "\uFEFF")
+	str = strings.ReplaceAll(str, "\r\n", "\n")
+	return &Scanner{
+		input: strings.ReplaceAll(str, "\r", "\n"),
+	}
+}
+
+func (s *Scanner) Scan() Token {
+	if s.raw != "" {
+		if tok, ok := s.scanRawText(); ok {
+			return tok
+		}
+	}
+	if s.done() {
+		return Token{Type: EOF}
+	}
+	if s.input[s.pos] != '<' {
+		return s.scanText()
+	}
+	rest := s.input[s.pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		return s.scanComment()
+	case hasPrefixFold(rest, "<!doctype"):
+		return s.scanDoctype()
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		return s.scanBogusComment()
+	case strings.HasPrefix(rest, "</") && len(rest) > 2 && isLetter(rest[2]):
+		return s.scanEndTag()
+	case len(rest) > 1 && isLetter(rest[1]):
+		return s.scanStartTag()
+	default:
+		s.pos++
+		tok := s.scanText()
+		tok.Data = "<" + tok.Data
+		return tok
+	}
+}
+
+func (s *Scanner) scanText() Token {
+	ix := strings.IndexByte(s.input[s.pos:], '<')
+	if ix < 0 {
+		ix = len(s.input) - s.pos
+	}
+	str := s.input[s.pos : s.pos+ix]
+	s.pos += ix
+	return Token{
+		Type: Text,
+		Data: html.UnescapeString(str),
+	}
+}
+
+func (s *Scanner) scanRawText() (Token, bool) {
+	var (
+		name = s.raw
+		rest = s.input[s.pos:]
+		end  = len(rest)
+	)
+	s.raw = ""
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '<' || !hasPrefixFold(rest[i:], "</"+name) {
+			continue
+		}
+		if j := i + 2 + len(name); j >= len(rest) || isSpace(rest[j]) || rest[j] == '>' || rest[j] == '/' {
+			end = i
+			break
+		}
+	}
+	str := rest[:end]
+	s.pos += end
+	if str == "" {
+		return Token{}, false
+	}
+	if isEscapableRawText(name) {
+		str = html.UnescapeString(str)
+	}
+	tok := Token{
+		Type: Text,
+		Data: str,
+	}
+	return tok, true
+}
+
+func (s *Scanner) scanComment() Token {
+	s.pos += len("<!--")
+	var (
+		rest = s.input[s.pos:]
+		ix   = strings.Index(rest, "-->")
+		tok  = Token{Type: Comment}
+	)
+	if ix < 0 {
+		tok.Data = rest
+		s.pos = len(s.input)
+	} else {
+		tok.Data = rest[:ix]
+		s.pos += ix + len("-->")
+	}
+	return tok
+}
+
+func (s *Scanner) scanBogusComment() Token {
+	s.pos += 2
+	tok := s.scanUntilEnd()
+	tok.Type = Comment
+	return tok
+}
+
+func (s *Scanner) scanDoctype() Token {
+	s.pos += len("<!doctype")
+	tok := s.scanUntilEnd()
+	tok.Type = Doctype
+	tok.Data = strings.TrimSpace(tok.Data)
+	return tok
+}
+
+func (s *Scanner) scanUntilEnd() Token {
+	var (
+		rest = s.input[s.pos:]
+		ix   = strings.IndexByte(rest, '>')
+		tok  Token
+	)
+	if ix < 0 {
+		tok.Data = rest
+		s.pos = len(s.input)
+	} else {
+		tok.Data = rest[:ix]
+		s.pos += ix + 1
+	}
+	return tok
+}
+
+func (s *Scanner) scanEndTag() Token {
+	s.pos += 2
+	tok := Token{
+		Type: EndTag,
+		Name: s.scanName(),
+	}
+	if ix := strings.IndexByte(s.input[s.pos:], '>'); ix < 0 {
+		s.pos = len(s.input)
+	} else {
+		s.pos += ix + 1
+	}
+	return tok
+}
+
+func (s *Scanner) scanStartTag() Token {
+	s.pos++
+	tok := Token{
+		Type: StartTag,
+		Name: s.scanName(),
+	}
+	for !s.done() {
+		s.skipBlank()
+		if s.done() {
+			break
+		}
+		if c := s.input[s.pos]; c == '>' {
+			s.pos++
+			break
+		} else if c == '/' {
+			s.pos++
+			if !s.done() && s.input[s.pos] == '>' {
+				tok.Type = SelfClosingTag
+				s.pos++
+				break
+			}
+			continue
+		}
+		a := s.scanAttr()
+		if a.Name == "" {
+			s.pos++
+			continue
+		}
+		if !hasAttr(tok.Attrs, a.Name) {
+			tok.Attrs = append(tok.Attrs, a)
+		}
+	}
+	if tok.Type == StartTag && isRawText(tok.Name) {
+		s.raw = tok.Name
+	}
+	return tok
+}
+
+func (s *Scanner) scanAttr() Attr {
+	var (
+		a   Attr
+		beg = s.pos
+	)
+	for !s.done() && !isSpace(s.input[s.pos]) && !strings.ContainsRune("=>/", rune(s.input[s.pos])) {
+		s.pos++
+	}
+	if s.pos == beg && !s.done() && s.input[s.pos] == '=' {
+		s.pos++
+	}
+	a.Name = strings.ToLower(s.input[beg:s.pos])
+	s.skipBlank()
+	if s.done() || s.input[s.pos] != '=' {
+		return a
+	}
+	s.pos++
+	s.skipBlank()
+	if s.done() {
+		return a
+	}
+	if q := s.input[s.pos]; q == '"' || q == '\'' {
+		s.pos++
+		ix := strings.IndexByte(s.input[s.pos:], q)
+		if ix < 0 {
+			ix = len(s.input) - s.pos
+		}
+		a.Value = s.input[s.pos : s.pos+ix]
+		s.pos += ix
+		if !s.done() {
+			s.pos++
+		}
+	} else {
+		beg := s.pos
+		for !s.done() && !isSpace(s.input[s.pos]) && s.input[s.pos] != '>' {
+			s.pos++
+		}
+		a.Value = s.input[beg:s.pos]
+	}
+	a.Value = html.UnescapeString(a.Value)
+	return a
+}
+
+func (s *Scanner) scanName() string {
+	beg := s.pos
+	for !s.done() && !isSpace(s.input[s.pos]) && s.input[s.pos] != '>' && s.input[s.pos] != '/' {
+		s.pos++
+	}
+	return strings.ToLower(s.input[beg:s.pos])
+}
+
+func (s *Scanner) skipBlank() {
+	for !s.done() && isSpace(s.input[s.pos]) {
+		s.pos++
+	}
+}
+
+func (s *Scanner) done() bool {
+	return s.pos >= len(s.input)
+}
+
+func hasAttr(attrs []Attr, name string) bool {
+	for _, a := range attrs {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixFold(str, prefix string) bool {
+	return len(str) >= len(prefix) && strings.EqualFold(str[:len(prefix)], prefix)
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\f' || b == '\r'
+}
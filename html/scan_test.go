@@ -0,0 +1,106 @@
+package html_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/midbel/codecs/html"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  []html.Token
+	}{
+		{
+			Input: `<p class=intro>hello</p>`,
+			Want: []html.Token{
+				{Type: html.StartTag, Name: "p", Attrs: []html.Attr{{Name: "class", Value: "intro"}}},
+				{Type: html.Text, Data: "hello"},
+				{Type: html.EndTag, Name: "p"},
+			},
+		},
+		{
+			Input: `<script>if (a < b && c) {}</script>`,
+			Want: []html.Token{
+				{Type: html.StartTag, Name: "script"},
+				{Type: html.Text, Data: "if (a < b && c) {}"},
+				{Type: html.EndTag, Name: "script"},
+			},
+		},
+		{
+			Input: `<title>a &amp; b</title>`,
+			Want: []html.Token{
+				{Type: html.StartTag, Name: "title"},
+				{Type: html.Text, Data: "a & b"},
+				{Type: html.EndTag, Name: "title"},
+			},
+		},
+		{
+			Input: `<input disabled checked="checked">`,
+			Want: []html.Token{
+				{Type: html.StartTag, Name: "input", Attrs: []html.Attr{{Name: "disabled"}, {Name: "checked", Value: "checked"}}},
+			},
+		},
+		{
+			Input: `<br/>`,
+			Want: []html.Token{
+				{Type: html.SelfClosingTag, Name: "br"},
+			},
+		},
+		{
+			Input: `a &amp; b &lt; &eacute;`,
+			Want: []html.Token{
+				{Type: html.Text, Data: "a & b < é"},
+			},
+		},
+		{
+			Input: `<!DOCTYPE html><!-- note -->`,
+			Want: []html.Token{
+				{Type: html.Doctype, Data: "html"},
+				{Type: html.Comment, Data: " note "},
+			},
+		},
+		{
+			Input: `<`,
+			Want: []html.Token{
+				{Type: html.Text, Data: "<"},
+			},
+		},
+		{
+			Input: `<!--`,
+			Want: []html.Token{
+				{Type: html.Comment},
+			},
+		},
+		{
+			Input: `<a href="x`,
+			Want: []html.Token{
+				{Type: html.StartTag, Name: "a", Attrs: []html.Attr{{Name: "href", Value: "x"}}},
+			},
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Input, func(t *testing.T) {
+			var (
+				scan = html.ScanString(c.Input)
+				got  []html.Token
+			)
+			for tok := scan.Scan(); tok.Type != html.EOF; tok = scan.Scan() {
+				got = append(got, tok)
+			}
+			if len(got) != len(c.Want) {
+				t.Fatalf("tokens mismatched! want %d, got %d (%v)", len(c.Want), len(got), got)
+			}
+			for i := range c.Want {
+				if !equalToken(got[i], c.Want[i]) {
+					t.Errorf("token %d mismatched! want %v, got %v", i, c.Want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func equalToken(got, want html.Token) bool {
+	return got.Type == want.Type && got.Name == want.Name && got.Data == want.Data && slices.Equal(got.Attrs, want.Attrs)
+}
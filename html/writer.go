@@ -0,0 +1,170 @@
+package html
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+type Writer struct {
+	writer *bufio.Writer
+
+	Indent  string
+	Compact bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		writer: bufio.NewWriter(w),
+		Indent: "  ",
+	}
+}
+
+func (w *Writer) Write(doc *xml.Document) error {
+	if doc.DocType != nil {
+		w.writeDoctype(doc.DocType)
+	}
+	for _, n := range doc.Nodes {
+		if err := w.writeNode(n, 0); err != nil {
+			return err
+		}
+	}
+	if !w.Compact {
+		w.writer.WriteByte('\n')
+	}
+	return w.writer.Flush()
+}
+
+func (w *Writer) WriteNode(node xml.Node) error {
+	if err := w.writeNode(node, 0); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+func (w *Writer) writeDoctype(doctype *xml.DocType) {
+	w.writer.WriteString("<!DOCTYPE ")
+	w.writer.WriteString(doctype.Name)
+	if doctype.PublicID != "" {
+		fmt.Fprintf(w.writer, " PUBLIC \"%s\"", doctype.PublicID)
+		if doctype.SystemID != "" {
+			fmt.Fprintf(w.writer, " \"%s\"", doctype.SystemID)
+		}
+	} else if doctype.SystemID != "" {
+		fmt.Fprintf(w.writer, " SYSTEM \"%s\"", doctype.SystemID)
+	}
+	w.writer.WriteByte('>')
+	if !w.Compact {
+		w.writer.WriteByte('\n')
+	}
+}
+
+func (w *Writer) writeNode(node xml.Node, depth int) error {
+	switch n := node.(type) {
+	case *xml.Document:
+		return w.writeNode(n.Root(), depth)
+	case *xml.Element:
+		return w.writeElement(n, depth)
+	case *xml.Text:
+		w.writer.WriteString(escapeText(n.Content))
+	case *xml.CharData:
+		w.writer.WriteString(escapeText(n.Content))
+	case *xml.Comment:
+		w.writer.WriteString("<!--")
+		w.writer.WriteString(n.Content)
+		w.writer.WriteString("-->")
+	case *xml.Instruction:
+		w.writer.WriteString("<?")
+		w.writer.WriteString(n.QualifiedName())
+		for _, a := range n.Attrs {
+			fmt.Fprintf(w.writer, " %s=\"%s\"", a.QualifiedName(), escapeAttr(a.Value()))
+		}
+		w.writer.WriteByte('>')
+	case *xml.Attribute:
+		w.writer.WriteString(escapeText(n.Value()))
+	default:
+		return fmt.Errorf("node: unknown type (%T)", node)
+	}
+	return nil
+}
+
+func (w *Writer) writeElement(el *xml.Element, depth int) error {
+	name := el.QualifiedName()
+	w.writer.WriteByte('<')
+	w.writer.WriteString(name)
+	for _, a := range el.Attrs {
+		w.writeAttr(a)
+	}
+	w.writer.WriteByte('>')
+	if IsVoid(name) {
+		return nil
+	}
+	if isRawText(name) && !isEscapableRawText(name) {
+		for _, n := range el.Nodes {
+			w.writer.WriteString(n.Value())
+		}
+	} else {
+		indent := !w.Compact && len(el.Nodes) > 0 && !slices.ContainsFunc(el.Nodes, isInline)
+		for _, n := range el.Nodes {
+			if indent {
+				w.writeNL(depth + 1)
+			}
+			if err := w.writeNode(n, depth+1); err != nil {
+				return err
+			}
+		}
+		if indent {
+			w.writeNL(depth)
+		}
+	}
+	w.writer.WriteString("</")
+	w.writer.WriteString(name)
+	w.writer.WriteByte('>')
+	return nil
+}
+
+func (w *Writer) writeAttr(a xml.Attribute) {
+	var (
+		name  = a.QualifiedName()
+		value = a.Value()
+	)
+	w.writer.WriteByte(' ')
+	w.writer.WriteString(name)
+	if IsBooleanAttr(name) && (value == "" || strings.EqualFold(value, name)) {
+		return
+	}
+	w.writer.WriteString("=\"")
+	w.writer.WriteString(escapeAttr(value))
+	w.writer.WriteByte('"')
+}
+
+func (w *Writer) writeNL(depth int) {
+	w.writer.WriteByte('\n')
+	w.writer.WriteString(strings.Repeat(w.Indent, depth))
+}
+
+func isInline(node xml.Node) bool {
+	switch node.(type) {
+	case *xml.Text, *xml.CharData:
+		return true
+	default:
+		return false
+	}
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\u00a0", "&nbsp;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "\"", "&quot;", "\u00a0", "&nbsp;")
+)
+
+func escapeText(str string) string {
+	return textEscaper.Replace(str)
+}
+
+func escapeAttr(str string) string {
+	return attrEscaper.Replace(str)
+}
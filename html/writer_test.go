@@ -0,0 +1,64 @@
+package html_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/html"
+	"github.com/midbel/codecs/xml"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+	}{
+		{
+			Input: `<div><br/><hr/></div>`,
+			Want:  `<div><br><hr></div>`,
+		},
+		{
+			Input: `<input type="checkbox" checked="checked" disabled=""/>`,
+			Want:  `<input type="checkbox" checked disabled>`,
+		},
+		{
+			Input: `<option selected="no">a</option>`,
+			Want:  `<option selected="no">a</option>`,
+		},
+		{
+			Input: `<script>if (a &lt; b &amp;&amp; c) {}</script>`,
+			Want:  `<script>if (a < b && c) {}</script>`,
+		},
+		{
+			Input: `<title>a &amp; b</title>`,
+			Want:  `<title>a &amp; b</title>`,
+		},
+		{
+			Input: `<p title="say &quot;hi&quot;">a &lt; b</p>`,
+			Want:  `<p title="say &quot;hi&quot;">a &lt; b</p>`,
+		},
+		{
+			Input: `<p></p>`,
+			Want:  `<p></p>`,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Input, func(t *testing.T) {
+			doc, err := xml.ParseString(c.Input)
+			if err != nil {
+				t.Fatalf("error parsing document: %s", err)
+			}
+			var (
+				str strings.Builder
+				ws  = html.NewWriter(&str)
+			)
+			ws.Compact = true
+			if err := ws.WriteNode(doc.Root()); err != nil {
+				t.Fatalf("error writing node: %s", err)
+			}
+			if got := str.String(); got != c.Want {
+				t.Errorf("output mismatched!\nwant: %s\ngot:  %s", c.Want, got)
+			}
+		})
+	}
+}
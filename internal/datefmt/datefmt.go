@@ -0,0 +1,382 @@
+package datefmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DateComponents     = "YMDdFWwCEZz"
+	TimeComponents     = "HhPmsfCEZz"
+	DateTimeComponents = "YMDdFWwHhPmsfCEZz"
+)
+
+var NoZone = time.FixedZone("", 0)
+
+type token struct {
+	text   string
+	marker string
+}
+
+func (t token) component() bool {
+	return t.marker != ""
+}
+
+func Format(value time.Time, picture, allowed string) (string, error) {
+	tokens, err := splitPicture(picture, allowed)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, t := range tokens {
+		if !t.component() {
+			out.WriteString(t.text)
+			continue
+		}
+		str, err := formatComponent(value, t.marker[0], t.marker[1:])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(str)
+	}
+	return out.String(), nil
+}
+
+func splitPicture(picture, allowed string) ([]token, error) {
+	var (
+		tokens []token
+		text   strings.Builder
+	)
+	for len(picture) > 0 {
+		ix := strings.IndexAny(picture, "[]")
+		if ix < 0 {
+			text.WriteString(picture)
+			break
+		}
+		text.WriteString(picture[:ix])
+		if ix+1 < len(picture) && picture[ix+1] == picture[ix] {
+			text.WriteByte(picture[ix])
+			picture = picture[ix+2:]
+			continue
+		}
+		if picture[ix] == ']' {
+			return nil, fmt.Errorf("unexpected ] in picture")
+		}
+		end := strings.IndexByte(picture[ix:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("missing ] in picture")
+		}
+		marker := strings.Join(strings.Fields(picture[ix+1:ix+end]), "")
+		picture = picture[ix+end+1:]
+		if marker == "" {
+			return nil, fmt.Errorf("empty component in picture")
+		}
+		if !strings.ContainsRune(allowed, rune(marker[0])) {
+			return nil, fmt.Errorf("component %c not available", marker[0])
+		}
+		if text.Len() > 0 {
+			tokens = append(tokens, token{text: text.String()})
+			text.Reset()
+		}
+		tokens = append(tokens, token{marker: marker})
+	}
+	if text.Len() > 0 {
+		tokens = append(tokens, token{text: text.String()})
+	}
+	return tokens, nil
+}
+
+var (
+	monthNames = []string{
+		"january",
+		"february",
+		"march",
+		"april",
+		"may",
+		"june",
+		"july",
+		"august",
+		"september",
+		"october",
+		"november",
+		"december",
+	}
+	dayNames = []string{
+		"monday",
+		"tuesday",
+		"wednesday",
+		"thursday",
+		"friday",
+		"saturday",
+		"sunday",
+	}
+)
+
+func formatComponent(value time.Time, component byte, modifier string) (string, error) {
+	modifier, width, _ := strings.Cut(modifier, ",")
+	minWidth, maxWidth, err := parseWidth(width)
+	if err != nil {
+		return "", err
+	}
+	var ordinal bool
+	if n := len(modifier); n > 1 {
+		switch modifier[n-1] {
+		case 'o':
+			ordinal = true
+			modifier = modifier[:n-1]
+		case 'c', 't':
+			modifier = modifier[:n-1]
+		default:
+		}
+	}
+	var (
+		number int
+		name   string
+	)
+	switch component {
+	case 'Y':
+		number = value.Year()
+		if number < 0 {
+			number = -number
+		}
+		if maxWidth == 0 && countDigits(modifier) == 2 {
+			maxWidth = 2
+		}
+	case 'M':
+		number = int(value.Month())
+		name = monthNames[number-1]
+	case 'D':
+		number = value.Day()
+	case 'd':
+		number = value.YearDay()
+	case 'F':
+		number = (int(value.Weekday())+6)%7 + 1
+		name = dayNames[number-1]
+		if modifier == "" {
+			modifier = "n"
+		}
+	case 'W':
+		_, number = value.ISOWeek()
+	case 'w':
+		first := time.Date(value.Year(), value.Month(), 1, 0, 0, 0, 0, value.Location())
+		number = (value.Day()-1+(int(first.Weekday())+6)%7)/7 + 1
+	case 'H':
+		number = value.Hour()
+	case 'h':
+		number = value.Hour() % 12
+		if number == 0 {
+			number = 12
+		}
+	case 'P':
+		name = "am"
+		if value.Hour() >= 12 {
+			name = "pm"
+		}
+		if modifier == "" {
+			modifier = "n"
+		}
+	case 'm', 's':
+		number = value.Minute()
+		if component == 's' {
+			number = value.Second()
+		}
+		if modifier == "" {
+			modifier = "01"
+		}
+	case 'f':
+		return formatFraction(value.Nanosecond(), modifier, minWidth, maxWidth), nil
+	case 'Z', 'z':
+		return formatTimezone(value, component, modifier), nil
+	case 'E':
+		name = "ad"
+		if value.Year() <= 0 {
+			name = "bc"
+		}
+		if modifier == "" {
+			modifier = "N"
+		}
+	case 'C':
+		name = "iso"
+		if modifier == "" {
+			modifier = "N"
+		}
+	default:
+		return "", fmt.Errorf("unknown component %c", component)
+	}
+	if name != "" {
+		switch modifier {
+		case "N":
+			name = strings.ToUpper(name)
+		case "n":
+		case "Nn":
+			name = strings.ToUpper(name[:1]) + name[1:]
+		default:
+			if component != 'M' && component != 'F' {
+				return "", fmt.Errorf("invalid presentation modifier for component %c", component)
+			}
+			name = ""
+		}
+		if name != "" {
+			if maxWidth > 0 && len(name) > maxWidth {
+				name = name[:maxWidth]
+			}
+			return padRight(name, minWidth), nil
+		}
+	}
+	return formatComponentNumber(number, modifier, ordinal, minWidth, maxWidth), nil
+}
+
+func formatComponentNumber(number int, modifier string, ordinal bool, minWidth, maxWidth int) string {
+	switch modifier {
+	case "I":
+		return strings.ToUpper(formatRoman(number))
+	case "i":
+		return formatRoman(number)
+	default:
+	}
+	minWidth = max(minWidth, countDigits(modifier))
+	str := strconv.Itoa(number)
+	if maxWidth > 0 && len(str) > maxWidth {
+		str = str[len(str)-maxWidth:]
+	}
+	if n := minWidth - len(str); n > 0 {
+		str = strings.Repeat("0", n) + str
+	}
+	if ordinal {
+		str += ordinalSuffix(number)
+	}
+	return str
+}
+
+func formatFraction(nsec int, modifier string, minWidth, maxWidth int) string {
+	str := fmt.Sprintf("%09d", nsec)
+	if n := countDigits(modifier); n > 1 {
+		minWidth = max(minWidth, n)
+		maxWidth = max(maxWidth, n)
+	}
+	str = strings.TrimRight(str, "0")
+	if maxWidth > 0 && len(str) > maxWidth {
+		str = str[:maxWidth]
+	}
+	if n := max(minWidth, 1) - len(str); n > 0 {
+		str += strings.Repeat("0", n)
+	}
+	return str
+}
+
+func formatTimezone(value time.Time, component byte, modifier string) string {
+	if value.Location() == NoZone {
+		return ""
+	}
+	_, offset := value.Zone()
+	if offset == 0 && modifier == "Z" {
+		return "Z"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	var (
+		hours   = offset / 3600
+		minutes = offset % 3600 / 60
+		str     string
+	)
+	switch {
+	case modifier == "" || modifier == "Z" || strings.Contains(modifier, ":"):
+		str = fmt.Sprintf("%c%02d:%02d", sign, hours, minutes)
+	case countDigits(modifier) <= 2:
+		str = fmt.Sprintf("%c%d", sign, hours)
+		if minutes != 0 {
+			str += fmt.Sprintf(":%02d", minutes)
+		}
+	default:
+		str = fmt.Sprintf("%c%02d%02d", sign, hours, minutes)
+	}
+	if component == 'z' {
+		str = "GMT" + str
+	}
+	return str
+}
+
+func parseWidth(width string) (int, int, error) {
+	if width == "" {
+		return 0, 0, nil
+	}
+	lower, upper, _ := strings.Cut(width, "-")
+	parse := func(str string) (int, error) {
+		if str == "" || str == "*" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(str)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid width modifier %q", width)
+		}
+		return n, nil
+	}
+	minWidth, err := parse(lower)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxWidth, err := parse(upper)
+	if err != nil {
+		return 0, 0, err
+	}
+	if maxWidth > 0 && maxWidth < minWidth {
+		return 0, 0, fmt.Errorf("invalid width modifier %q", width)
+	}
+	return minWidth, maxWidth, nil
+}
+
+func countDigits(str string) int {
+	var n int
+	for _, c := range str {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+func padRight(str string, width int) string {
+	if n := width - len(str); n > 0 {
+		str += strings.Repeat(" ", n)
+	}
+	return str
+}
+
+func ordinalSuffix(n int) string {
+	if n%100 >= 11 && n%100 <= 13 {
+		return "th"
+	}
+	switch n % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	default:
+		return "th"
+	}
+}
+
+func formatRoman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	var (
+		values  = []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+		symbols = []string{"m", "cm", "d", "cd", "c", "xc", "l", "xl", "x", "ix", "v", "iv", "i"}
+		str     strings.Builder
+	)
+	for i := range values {
+		for n >= values[i] {
+			str.WriteString(symbols[i])
+			n -= values[i]
+		}
+	}
+	return str.String()
+}
@@ -0,0 +1,288 @@
+package datefmt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrValue = errors.New("value does not match picture")
+
+type fields struct {
+	year   int
+	month  int
+	day    int
+	yday   int
+	hour   int
+	minute int
+	second int
+	nsec   int
+	pm     int
+	zone   *time.Location
+}
+
+func (f fields) Time(loc *time.Location) time.Time {
+	if f.zone != nil {
+		loc = f.zone
+	}
+	hour := f.hour
+	if f.pm != 0 {
+		hour %= 12
+		if f.pm > 0 {
+			hour += 12
+		}
+	}
+	month, day := time.Month(f.month), f.day
+	if f.yday > 0 {
+		month, day = time.January, f.yday
+	}
+	return time.Date(f.year, month, day, hour, f.minute, f.second, f.nsec, loc)
+}
+
+func Parse(str, picture string, loc *time.Location) (time.Time, error) {
+	tokens, err := splitPicture(picture, DateTimeComponents)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	var (
+		input = str
+		res   = fields{
+			year:  1970,
+			month: 1,
+			day:   1,
+		}
+	)
+	for i, t := range tokens {
+		if !t.component() {
+			rest, ok := strings.CutPrefix(str, t.text)
+			if !ok {
+				return time.Time{}, fmt.Errorf("%w: %q: expected %q", ErrValue, input, t.text)
+			}
+			str = rest
+			continue
+		}
+		var width int
+		if i+1 < len(tokens) && tokens[i+1].component() {
+			width = fixedWidth(t.marker[0], t.marker[1:])
+		}
+		str, err = parseComponent(str, t.marker[0], t.marker[1:], width, &res)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %q: %s", ErrValue, input, err)
+		}
+	}
+	if str != "" {
+		return time.Time{}, fmt.Errorf("%w: %q: unexpected %q", ErrValue, input, str)
+	}
+	return res.Time(loc), nil
+}
+
+func fixedWidth(component byte, modifier string) int {
+	modifier, width, _ := strings.Cut(modifier, ",")
+	_, maxWidth, _ := parseWidth(width)
+	if n := max(countDigits(modifier), maxWidth); n > 0 {
+		return n
+	}
+	if component == 'Y' {
+		return 4
+	}
+	return 2
+}
+
+func parseComponent(str string, component byte, modifier string, width int, res *fields) (string, error) {
+	modifier, _, _ = strings.Cut(modifier, ",")
+	var ordinal bool
+	if n := len(modifier); n > 1 {
+		switch modifier[n-1] {
+		case 'o':
+			ordinal = true
+			modifier = modifier[:n-1]
+		case 'c', 't':
+			modifier = modifier[:n-1]
+		default:
+		}
+	}
+	var (
+		number int
+		err    error
+	)
+	switch component {
+	case 'M', 'F':
+		names := monthNames
+		if component == 'F' {
+			names = dayNames
+		}
+		if (modifier == "" && component == 'F') || isNameModifier(modifier) {
+			var word string
+			word, str = splitFunc(str, isLetter)
+			ix := lookupName(names, word)
+			if ix < 0 {
+				return "", fmt.Errorf("unknown name %q", word)
+			}
+			if component == 'M' {
+				res.month = ix + 1
+			}
+			return str, nil
+		}
+	case 'P':
+		var word string
+		word, str = splitFunc(str, func(c rune) bool {
+			return isLetter(c) || c == '.'
+		})
+		switch strings.ToLower(strings.ReplaceAll(word, ".", "")) {
+		case "am":
+			res.pm = -1
+		case "pm":
+			res.pm = 1
+		default:
+			return "", fmt.Errorf("invalid am/pm marker %q", word)
+		}
+		return str, nil
+	case 'E', 'C':
+		_, str = splitFunc(str, isLetter)
+		return str, nil
+	case 'Z', 'z':
+		return parseTimezone(str, component, res)
+	case 'f':
+		var digits string
+		if digits, str = splitDigits(str, width); digits == "" {
+			return "", fmt.Errorf("fractional seconds expected")
+		}
+		if len(digits) > 9 {
+			digits = digits[:9]
+		}
+		res.nsec, _ = strconv.Atoi(digits + strings.Repeat("0", 9-len(digits)))
+		return str, nil
+	default:
+	}
+	if number, str, err = parseNumber(str, width); err != nil {
+		return "", err
+	}
+	if ordinal {
+		_, str = splitFunc(str, isLetter)
+	}
+	switch component {
+	case 'Y':
+		res.year = number
+	case 'M':
+		res.month = number
+	case 'D':
+		res.day = number
+	case 'd':
+		res.yday = number
+	case 'H', 'h':
+		res.hour = number
+	case 'm':
+		res.minute = number
+	case 's':
+		res.second = number
+	default:
+	}
+	return str, nil
+}
+
+func parseTimezone(str string, component byte, res *fields) (string, error) {
+	if component == 'z' {
+		str, _ = strings.CutPrefix(str, "GMT")
+	}
+	if rest, ok := strings.CutPrefix(str, "Z"); ok {
+		res.zone = time.UTC
+		return rest, nil
+	}
+	if str == "" || (str[0] != '+' && str[0] != '-') {
+		return "", fmt.Errorf("timezone expected")
+	}
+	sign := 1
+	if str[0] == '-' {
+		sign = -1
+	}
+	digits, str := splitDigits(str[1:], 4)
+	var hours, minutes int
+	switch len(digits) {
+	case 1, 2:
+		hours, _ = strconv.Atoi(digits)
+		if rest, ok := strings.CutPrefix(str, ":"); ok {
+			digits, str = splitDigits(rest, 2)
+			if len(digits) != 2 {
+				return "", fmt.Errorf("invalid timezone minutes")
+			}
+			minutes, _ = strconv.Atoi(digits)
+		}
+	case 4:
+		hours, _ = strconv.Atoi(digits[:2])
+		minutes, _ = strconv.Atoi(digits[2:])
+	default:
+		return "", fmt.Errorf("invalid timezone offset")
+	}
+	res.zone = time.FixedZone("", sign*(hours*3600+minutes*60))
+	return str, nil
+}
+
+func ParseZone(str string) (*time.Location, error) {
+	var res fields
+	rest, err := parseTimezone(str, 'Z', &res)
+	if err != nil || rest != "" {
+		return nil, fmt.Errorf("%q: invalid timezone", str)
+	}
+	return res.zone, nil
+}
+
+func parseNumber(str string, width int) (int, string, error) {
+	digits, rest := splitDigits(str, width)
+	if digits == "" {
+		return 0, "", fmt.Errorf("digit expected")
+	}
+	n, err := strconv.Atoi(digits)
+	return n, rest, err
+}
+
+func splitDigits(str string, width int) (string, string) {
+	var n int
+	for n < len(str) && str[n] >= '0' && str[n] <= '9' {
+		if width > 0 && n >= width {
+			break
+		}
+		n++
+	}
+	return str[:n], str[n:]
+}
+
+func splitFunc(str string, accept func(rune) bool) (string, string) {
+	ix := strings.IndexFunc(str, func(c rune) bool {
+		return !accept(c)
+	})
+	if ix < 0 {
+		return str, ""
+	}
+	return str[:ix], str[ix:]
+}
+
+func lookupName(names []string, word string) int {
+	word = strings.ToLower(word)
+	if len(word) < 3 {
+		return -1
+	}
+	for i := range names {
+		if strings.HasPrefix(names[i], word) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNameModifier(modifier string) bool {
+	switch modifier {
+	case "N", "n", "Nn":
+		return true
+	default:
+		return false
+	}
+}
+
+func isLetter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,77 @@
+package fuzzing
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+const maxInputLength = 256
+
+func AddSeeds(f *testing.F, seeds ...string) {
+	f.Helper()
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+}
+
+func AddFiles(f *testing.F, dir string, exts ...string) {
+	f.Helper()
+	err := filepath.WalkDir(dir, func(file string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		if len(exts) > 0 && !slices.Contains(exts, filepath.Ext(file)) {
+			return nil
+		}
+		buf, err := os.ReadFile(file)
+		if err == nil {
+			f.Add(buf)
+		}
+		return err
+	})
+	if err != nil && !os.IsNotExist(err) {
+		f.Fatalf("loading seeds from %s: %s", dir, err)
+	}
+}
+
+func Run(t *testing.T, input []byte, fn func() error) {
+	t.Helper()
+	if err := Recover(fn); err != nil {
+		t.Fatalf("input %s: %s", Quote(input), err)
+	}
+}
+
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+func Recover(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	fn()
+	return nil
+}
+
+func Quote(input []byte) string {
+	if len(input) <= maxInputLength {
+		return strconv.Quote(string(input))
+	}
+	return fmt.Sprintf("%s... (%d bytes)", strconv.Quote(string(input[:maxInputLength])), len(input))
+}
@@ -68,6 +68,10 @@ func (t Token) String() string {
 		return "<descend>"
 	case Range:
 		return "<range>"
+	case Index:
+		return "<index>"
+	case Bind:
+		return "<bind>"
 	case EOF:
 		return "<eof>"
 	case BegArr:
@@ -141,6 +145,8 @@ const (
 	Descent
 	Range
 	Transform
+	Index
+	Bind
 	// common
 	Invalid
 )
@@ -51,7 +51,7 @@ func IsNL(c rune) bool {
 func IsOperator(c rune) bool {
 	return c == '!' || c == '=' || c == '<' || c == '>' ||
 		c == '&' || c == '*' || c == '/' || c == '%' || c == '-' ||
-		c == '+' || c == '.' || c == '?' || c == ':'
+		c == '+' || c == '.' || c == '?' || c == ':' || c == '#' || c == '@'
 }
 
 func IsTransform(c rune) bool {
@@ -0,0 +1,388 @@
+package numfmt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrPicture  = errors.New("invalid picture")
+	ErrProperty = errors.New("invalid decimal format property")
+)
+
+type DecimalFormat struct {
+	DecimalSeparator  rune
+	GroupingSeparator rune
+	ExponentSeparator rune
+	Percent           rune
+	PerMille          rune
+	ZeroDigit         rune
+	Digit             rune
+	PatternSeparator  rune
+	MinusSign         rune
+	Infinity          string
+	NaN               string
+}
+
+func Default() DecimalFormat {
+	return DecimalFormat{
+		DecimalSeparator:  '.',
+		GroupingSeparator: ',',
+		ExponentSeparator: 'e',
+		Percent:           '%',
+		PerMille:          '\u2030',
+		ZeroDigit:         '0',
+		Digit:             '#',
+		PatternSeparator:  ';',
+		MinusSign:         '-',
+		Infinity:          "Infinity",
+		NaN:               "NaN",
+	}
+}
+
+func (d *DecimalFormat) Set(name, value string) error {
+	switch name {
+	case "infinity":
+		d.Infinity = value
+		return nil
+	case "NaN":
+		d.NaN = value
+		return nil
+	default:
+	}
+	char, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) {
+		return fmt.Errorf("%w: %s should be a single character", ErrProperty, name)
+	}
+	switch name {
+	case "decimal-separator":
+		d.DecimalSeparator = char
+	case "grouping-separator":
+		d.GroupingSeparator = char
+	case "exponent-separator":
+		d.ExponentSeparator = char
+	case "percent":
+		d.Percent = char
+	case "per-mille":
+		d.PerMille = char
+	case "zero-digit":
+		d.ZeroDigit = char
+	case "digit":
+		d.Digit = char
+	case "pattern-separator":
+		d.PatternSeparator = char
+	case "minus-sign":
+		d.MinusSign = char
+	default:
+		return fmt.Errorf("%w: %s", ErrProperty, name)
+	}
+	return nil
+}
+
+func (d DecimalFormat) Format(value float64, picture string) (string, error) {
+	pics := strings.Split(picture, string(d.PatternSeparator))
+	if len(pics) > 2 {
+		return "", fmt.Errorf("%w: too many pattern separators", ErrPicture)
+	}
+	list := make([]subPicture, 0, len(pics))
+	for i := range pics {
+		sub, err := d.parse(pics[i])
+		if err != nil {
+			return "", err
+		}
+		list = append(list, sub)
+	}
+	if math.IsNaN(value) {
+		return d.NaN, nil
+	}
+	sub := list[0]
+	if value < 0 || (value == 0 && math.Signbit(value)) {
+		if len(list) > 1 {
+			sub = list[1]
+		} else {
+			sub.prefix = string(d.MinusSign) + sub.prefix
+		}
+	}
+	return sub.prefix + d.format(math.Abs(value), sub) + sub.suffix, nil
+}
+
+type subPicture struct {
+	prefix string
+	suffix string
+
+	minInt     int
+	minFrac    int
+	maxFrac    int
+	minExp     int
+	expInt     int
+	scale      int
+	intGroups  []int
+	fracGroups []int
+}
+
+func (d DecimalFormat) isDigit(c rune) bool {
+	return c >= d.ZeroDigit && c <= d.ZeroDigit+9
+}
+
+func (d DecimalFormat) isActive(c rune) bool {
+	switch c {
+	case d.DecimalSeparator, d.GroupingSeparator, d.Digit:
+		return true
+	default:
+		return d.isDigit(c)
+	}
+}
+
+func (d DecimalFormat) activeChars(chars []rune) []bool {
+	active := make([]bool, len(chars))
+	for i, c := range chars {
+		if c == d.ExponentSeparator {
+			active[i] = i > 0 && i+1 < len(chars) && d.isActive(chars[i-1]) && d.isDigit(chars[i+1])
+		} else {
+			active[i] = d.isActive(c)
+		}
+	}
+	return active
+}
+
+func (d DecimalFormat) parse(picture string) (subPicture, error) {
+	var (
+		sub    subPicture
+		chars  = []rune(picture)
+		active = d.activeChars(chars)
+		first  = slices.Index(active, true)
+		last   = len(active) - 1
+	)
+	for last >= 0 && !active[last] {
+		last--
+	}
+	if first < 0 {
+		return sub, fmt.Errorf("%w: %q has no digit", ErrPicture, picture)
+	}
+	sub.prefix = string(chars[:first])
+	sub.suffix = string(chars[last+1:])
+	for _, c := range sub.prefix + sub.suffix {
+		switch c {
+		case d.Percent:
+			if sub.scale != 0 {
+				return sub, fmt.Errorf("%w: %q has multiple percent/per-mille", ErrPicture, picture)
+			}
+			sub.scale = 2
+		case d.PerMille:
+			if sub.scale != 0 {
+				return sub, fmt.Errorf("%w: %q has multiple percent/per-mille", ErrPicture, picture)
+			}
+			sub.scale = 3
+		default:
+		}
+	}
+	if slices.Contains(active[first:last+1], false) {
+		return sub, fmt.Errorf("%w: %q has passive character between digits", ErrPicture, picture)
+	}
+
+	mantissa := chars[first : last+1]
+	if ix := slices.Index(mantissa, d.ExponentSeparator); ix >= 0 {
+		exp := mantissa[ix+1:]
+		if !allFunc(exp, d.isDigit) {
+			return sub, fmt.Errorf("%w: %q has invalid exponent", ErrPicture, picture)
+		}
+		sub.minExp = len(exp)
+		mantissa = mantissa[:ix]
+	}
+	if sub.scale != 0 && sub.minExp != 0 {
+		return sub, fmt.Errorf("%w: %q can not have both an exponent and a percent/per-mille", ErrPicture, picture)
+	}
+
+	intPart, fracPart := mantissa, []rune(nil)
+	if ix := slices.Index(mantissa, d.DecimalSeparator); ix >= 0 {
+		intPart, fracPart = mantissa[:ix], mantissa[ix+1:]
+		if slices.Contains(fracPart, d.DecimalSeparator) {
+			return sub, fmt.Errorf("%w: %q has multiple decimal separators", ErrPicture, picture)
+		}
+		if len(intPart) > 0 && intPart[len(intPart)-1] == d.GroupingSeparator {
+			return sub, fmt.Errorf("%w: %q has grouping separator next to decimal separator", ErrPicture, picture)
+		}
+		if len(fracPart) > 0 && fracPart[0] == d.GroupingSeparator {
+			return sub, fmt.Errorf("%w: %q has grouping separator next to decimal separator", ErrPicture, picture)
+		}
+	}
+	if !slices.ContainsFunc(mantissa, func(c rune) bool { return c == d.Digit || d.isDigit(c) }) {
+		return sub, fmt.Errorf("%w: %q has no digit", ErrPicture, picture)
+	}
+	for i := 1; i < len(mantissa); i++ {
+		if mantissa[i] == d.GroupingSeparator && mantissa[i-1] == d.GroupingSeparator {
+			return sub, fmt.Errorf("%w: %q has adjacent grouping separators", ErrPicture, picture)
+		}
+	}
+
+	var seenZero bool
+	for i, c := range intPart {
+		switch {
+		case d.isDigit(c):
+			seenZero = true
+			sub.minInt++
+		case c == d.Digit && seenZero:
+			return sub, fmt.Errorf("%w: %q has optional digit after mandatory digit", ErrPicture, picture)
+		case c == d.GroupingSeparator:
+			pos := countFunc(intPart[i+1:], func(c rune) bool { return c != d.GroupingSeparator })
+			sub.intGroups = append(sub.intGroups, pos)
+		default:
+		}
+	}
+	var seenOptional bool
+	for i, c := range fracPart {
+		switch {
+		case d.isDigit(c) && seenOptional:
+			return sub, fmt.Errorf("%w: %q has mandatory digit after optional digit", ErrPicture, picture)
+		case d.isDigit(c):
+			sub.minFrac++
+			sub.maxFrac++
+		case c == d.Digit:
+			seenOptional = true
+			sub.maxFrac++
+		case c == d.GroupingSeparator:
+			pos := countFunc(fracPart[:i], func(c rune) bool { return c != d.GroupingSeparator })
+			sub.fracGroups = append(sub.fracGroups, pos)
+		default:
+		}
+	}
+	if sub.minInt == 0 && sub.maxFrac == 0 {
+		if sub.minExp > 0 {
+			sub.minFrac = 1
+			sub.maxFrac = 1
+		} else {
+			sub.minInt = 1
+		}
+	}
+	sub.expInt = sub.minInt
+	if sub.minExp > 0 && sub.minInt == 0 && slices.Contains(intPart, d.Digit) {
+		sub.minInt = 1
+	}
+	sub.intGroups = regularGroups(sub.intGroups)
+	return sub, nil
+}
+
+func (d DecimalFormat) format(value float64, sub subPicture) string {
+	if math.IsInf(value, 0) {
+		return d.Infinity
+	}
+	for range sub.scale {
+		value *= 10
+	}
+	var exp int
+	if sub.minExp > 0 && value != 0 {
+		exp = int(math.Floor(math.Log10(value))) + 1 - sub.expInt
+		value /= math.Pow10(exp)
+	}
+	intDigits, frac := splitDigits(value, sub.maxFrac)
+	if sub.minExp > 0 && len(intDigits) > sub.expInt {
+		exp++
+		intDigits, frac = splitDigits(value/10, sub.maxFrac)
+	}
+	for len(frac) > sub.minFrac && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+	if n := sub.minInt - len(intDigits); n > 0 {
+		intDigits = strings.Repeat("0", n) + intDigits
+	}
+	if intDigits == "" && frac == "" {
+		intDigits = "0"
+	}
+
+	var out strings.Builder
+	d.writeInteger(&out, intDigits, sub.intGroups)
+	if frac != "" {
+		out.WriteRune(d.DecimalSeparator)
+		for i, c := range frac {
+			if slices.Contains(sub.fracGroups, i) && i > 0 {
+				out.WriteRune(d.GroupingSeparator)
+			}
+			out.WriteRune(d.ZeroDigit + (c - '0'))
+		}
+	}
+	if sub.minExp > 0 {
+		out.WriteRune(d.ExponentSeparator)
+		if exp < 0 {
+			out.WriteRune(d.MinusSign)
+			exp = -exp
+		}
+		digits := strconv.Itoa(exp)
+		if n := sub.minExp - len(digits); n > 0 {
+			digits = strings.Repeat("0", n) + digits
+		}
+		for _, c := range digits {
+			out.WriteRune(d.ZeroDigit + (c - '0'))
+		}
+	}
+	return out.String()
+}
+
+func splitDigits(value float64, prec int) (string, string) {
+	var (
+		str        = strconv.FormatFloat(value, 'f', prec, 64)
+		ints, frac = str, ""
+	)
+	if ix := strings.IndexByte(str, '.'); ix >= 0 {
+		ints, frac = str[:ix], str[ix+1:]
+	}
+	return strings.TrimLeft(ints, "0"), frac
+}
+
+func (d DecimalFormat) writeInteger(out *strings.Builder, digits string, groups []int) {
+	for i, c := range digits {
+		pos := len(digits) - i
+		if i > 0 && isGroupPosition(groups, pos) {
+			out.WriteRune(d.GroupingSeparator)
+		}
+		out.WriteRune(d.ZeroDigit + (c - '0'))
+	}
+}
+
+func isGroupPosition(groups []int, pos int) bool {
+	if len(groups) == 1 && groups[0] < 0 {
+		return pos%-groups[0] == 0
+	}
+	return slices.Contains(groups, pos)
+}
+
+func regularGroups(groups []int) []int {
+	if len(groups) == 0 {
+		return nil
+	}
+	step := slices.Min(groups)
+	if step <= 0 {
+		return groups
+	}
+	for _, g := range groups {
+		if g%step != 0 {
+			return groups
+		}
+	}
+	for i := step; i <= slices.Max(groups); i += step {
+		if !slices.Contains(groups, i) {
+			return groups
+		}
+	}
+	return []int{-step}
+}
+
+func allFunc(chars []rune, fn func(rune) bool) bool {
+	return !slices.ContainsFunc(chars, func(c rune) bool {
+		return !fn(c)
+	})
+}
+
+func countFunc(chars []rune, fn func(rune) bool) int {
+	var n int
+	for _, c := range chars {
+		if fn(c) {
+			n++
+		}
+	}
+	return n
+}
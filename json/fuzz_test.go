@@ -0,0 +1,41 @@
+package json
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+var jsonSeeds = []string{
+	`null`,
+	`{"foo": "bar", "list": [1, 2.5, -3e10, true, false, null]}`,
+	`"é😀\n"`,
+	`[[[]], {}, {"": ""}]`,
+	`{"unterminated": [1, 2`,
+}
+
+func FuzzDecode(f *testing.F) {
+	fuzzing.AddSeeds(f, jsonSeeds...)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			_, err := Decode(bytes.NewReader(data))
+			return err
+		})
+	})
+}
+
+func FuzzDecode5(f *testing.F) {
+	fuzzing.AddSeeds(f, jsonSeeds...)
+	fuzzing.AddSeeds(f,
+		`{unquoted: 'single', trailing: [1, 2,],}`,
+		`// comment
+		{hex: 0xFF, inf: Infinity, nan: NaN, num: +.5}`,
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			_, err := Decode5(bytes.NewReader(data))
+			return err
+		})
+	})
+}
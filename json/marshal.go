@@ -0,0 +1,556 @@
+package json
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	errType   = errors.New("type error")
+	errTarget = errors.New("invalid target")
+)
+
+func Marshal(v any) ([]byte, error) {
+	value, err := marshalValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		buf bytes.Buffer
+		ws  = Compact(&buf)
+	)
+	if err := ws.Write(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func Unmarshal(data []byte, v any) error {
+	return unmarshal(data, v, stdMode)
+}
+
+func Unmarshal5(data []byte, v any) error {
+	return unmarshal(data, v, json5Mode)
+}
+
+func unmarshal(data []byte, v any, jm mode) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: non-nil pointer expected (%T)", errTarget, v)
+	}
+	p := createParser(bytes.NewReader(data), jm)
+	p.UseNumber = true
+	doc, err := p.Parse()
+	if err != nil {
+		return err
+	}
+	if !p.done() {
+		return p.syntaxError("unexpected content after value")
+	}
+	return unmarshalValue(rv.Elem(), doc)
+}
+
+// Field is a member of an Object.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Object is a json object whose fields keep the order in which they are
+// defined. Unlike a map, it can hold several fields with the same key.
+type Object []Field
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+func marshalValue(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: unsupported value %v", errType, f)
+		}
+		return f, nil
+	case reflect.String:
+		if v.Type() == numberType {
+			return Number(v.String()), nil
+		}
+		return v.String(), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+		return marshalArray(v)
+	case reflect.Array:
+		return marshalArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalMap(v)
+	case reflect.Struct:
+		return marshalStruct(v)
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %s", errType, v.Type())
+	}
+}
+
+func marshalArray(v reflect.Value) (any, error) {
+	arr := make([]any, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		a, err := marshalValue(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, a)
+	}
+	return arr, nil
+}
+
+func marshalMap(v reflect.Value) (any, error) {
+	var obj Object
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := marshalKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		val, err := marshalValue(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, Field{Key: key, Value: val})
+	}
+	slices.SortFunc(obj, func(a, b Field) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return obj, nil
+}
+
+func marshalKey(v reflect.Value) (string, error) {
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("%w: unsupported map key type %s", errType, v.Type())
+	}
+}
+
+func marshalStruct(v reflect.Value) (any, error) {
+	var obj Object
+	for _, f := range structFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		val, err := marshalValue(fv)
+		if err != nil {
+			return nil, err
+		}
+		if f.quoted {
+			switch val.(type) {
+			case bool, int64, uint64, float64, string:
+				var buf bytes.Buffer
+				Compact(&buf).Write(val)
+				val = buf.String()
+			}
+		}
+		obj = append(obj, Field{Key: f.name, Value: val})
+	}
+	return obj, nil
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	quoted    bool
+}
+
+func structFields(t reflect.Type) []structField {
+	return collectFields(t, nil)
+}
+
+func collectFields(t reflect.Type, index []int) []structField {
+	var (
+		fields []structField
+		direct = make(map[string]struct{})
+	)
+	for i := 0; i < t.NumField(); i++ {
+		if f, ok := getStructField(t.Field(i)); ok {
+			direct[f.name] = struct{}{}
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if ft, ok := embeddedStruct(sf); ok {
+			for _, f := range collectFields(ft, append(slices.Clone(index), i)) {
+				if _, ok := direct[f.name]; ok {
+					continue
+				}
+				direct[f.name] = struct{}{}
+				fields = append(fields, f)
+			}
+			continue
+		}
+		f, ok := getStructField(sf)
+		if !ok {
+			continue
+		}
+		f.index = append(slices.Clone(index), i)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func embeddedStruct(sf reflect.StructField) (reflect.Type, bool) {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if !sf.Anonymous || name != "" || sf.Tag.Get("json") == "-" {
+		return nil, false
+	}
+	ft := sf.Type
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	return ft, ft.Kind() == reflect.Struct
+}
+
+func getStructField(sf reflect.StructField) (structField, bool) {
+	var f structField
+	if _, ok := embeddedStruct(sf); ok || !sf.IsExported() {
+		return f, false
+	}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return f, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = sf.Name
+	}
+	f.name = name
+	for _, o := range strings.Split(opts, ",") {
+		switch o {
+		case "omitempty":
+			f.omitEmpty = true
+		case "string":
+			f.quoted = true
+		}
+	}
+	return f, true
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func unmarshalValue(v reflect.Value, value any) error {
+	if value == nil {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(v.Elem(), value)
+	}
+	if str, ok := value.(string); ok && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return typeMismatch(value, v.Type())
+		}
+		v.Set(reflect.ValueOf(plainNumbers(value)))
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		v.SetBool(b)
+	case reflect.String:
+		if n, ok := value.(Number); ok && v.Type() == numberType {
+			v.SetString(string(n))
+			break
+		}
+		str, ok := value.(string)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		v.SetString(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(Number)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		i, err := n.Int64()
+		if errors.Is(err, strconv.ErrRange) || (err == nil && v.OverflowInt(i)) {
+			return fmt.Errorf("%w: %s overflows %s: %w", errType, n, v.Type(), strconv.ErrRange)
+		}
+		if err != nil {
+			return typeMismatch(value, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := value.(Number)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		i, err := n.Uint64()
+		if errors.Is(err, strconv.ErrRange) || (err == nil && v.OverflowUint(i)) {
+			return fmt.Errorf("%w: %s overflows %s: %w", errType, n, v.Type(), strconv.ErrRange)
+		}
+		if err != nil {
+			return typeMismatch(value, v.Type())
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(Number)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return typeMismatch(value, v.Type())
+		}
+		if v.OverflowFloat(f) {
+			return fmt.Errorf("%w: %v overflows %s", errType, value, v.Type())
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		return unmarshalSlice(v, value)
+	case reflect.Array:
+		arr, ok := value.([]any)
+		if !ok {
+			return typeMismatch(value, v.Type())
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(arr) {
+				v.Index(i).SetZero()
+				continue
+			}
+			if err := unmarshalValue(v.Index(i), arr[i]); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return unmarshalMap(v, value)
+	case reflect.Struct:
+		return unmarshalStruct(v, value)
+	default:
+		return fmt.Errorf("%w: unsupported type %s", errType, v.Type())
+	}
+	return nil
+}
+
+func unmarshalSlice(v reflect.Value, value any) error {
+	if str, ok := value.(string); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+		buf, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(buf)
+		return nil
+	}
+	arr, ok := value.([]any)
+	if !ok {
+		return typeMismatch(value, v.Type())
+	}
+	slice := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+	for i := range arr {
+		if err := unmarshalValue(slice.Index(i), arr[i]); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+func unmarshalMap(v reflect.Value, value any) error {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return typeMismatch(value, v.Type())
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(obj)))
+	}
+	kt := v.Type().Key()
+	for k, a := range obj {
+		key := reflect.New(kt).Elem()
+		switch {
+		case reflect.PointerTo(kt).Implements(textUnmarshalerType):
+			if err := key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(k)); err != nil {
+				return err
+			}
+		case kt.Kind() == reflect.String:
+			key.SetString(k)
+		case key.CanInt():
+			n, err := strconv.ParseInt(k, 10, 64)
+			if err != nil || key.OverflowInt(n) {
+				return typeMismatch(k, kt)
+			}
+			key.SetInt(n)
+		case key.CanUint():
+			n, err := strconv.ParseUint(k, 10, 64)
+			if err != nil || key.OverflowUint(n) {
+				return typeMismatch(k, kt)
+			}
+			key.SetUint(n)
+		default:
+			return fmt.Errorf("%w: unsupported map key type %s", errType, kt)
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := unmarshalValue(elem, a); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func unmarshalStruct(v reflect.Value, value any) error {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return typeMismatch(value, v.Type())
+	}
+	fields := structFields(v.Type())
+	for k, a := range obj {
+		ix := slices.IndexFunc(fields, func(f structField) bool {
+			return f.name == k
+		})
+		if ix < 0 {
+			ix = slices.IndexFunc(fields, func(f structField) bool {
+				return strings.EqualFold(f.name, k)
+			})
+		}
+		if ix < 0 {
+			continue
+		}
+		fv := v
+		for i, x := range fields[ix].index {
+			if i > 0 && fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		if fields[ix].quoted {
+			str, ok := a.(string)
+			if !ok {
+				return typeMismatch(a, fv.Type())
+			}
+			if fv.Kind() != reflect.String {
+				var err error
+				p := createParser(strings.NewReader(str), stdMode)
+				p.UseNumber = true
+				if a, err = p.Parse(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := unmarshalValue(fv, a); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+var numberType = reflect.TypeFor[Number]()
+
+func plainNumbers(value any) any {
+	switch v := value.(type) {
+	case Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = plainNumbers(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = plainNumbers(v[k])
+		}
+	}
+	return value
+}
+
+func typeMismatch(value any, t reflect.Type) error {
+	return fmt.Errorf("%w: can not unmarshal %T into %s", errType, value, t)
+}
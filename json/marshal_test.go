@@ -0,0 +1,294 @@
+package json
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type marshalBase struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind,omitempty"`
+}
+
+type marshalItem struct {
+	marshalBase
+	Name    string         `json:"name"`
+	Price   float64        `json:"price,string"`
+	Tags    []string       `json:"tags,omitempty"`
+	Attrs   map[string]int `json:"attrs,omitempty"`
+	Parent  *marshalItem   `json:"parent,omitempty"`
+	When    time.Time      `json:"when"`
+	Data    []byte         `json:"data,omitempty"`
+	Labels  map[int]string `json:"labels,omitempty"`
+	Skip    string         `json:"-"`
+	Default bool
+	private int
+}
+
+func TestMarshal(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	tests := []struct {
+		Name  string
+		Value any
+		Want  string
+	}{
+		{
+			Name:  "null",
+			Value: nil,
+			Want:  `null`,
+		},
+		{
+			Name:  "scalars",
+			Value: []any{true, 1, uint8(2), 1.5, "foo"},
+			Want:  `[true,1,2,1.5,"foo"]`,
+		},
+		{
+			Name:  "escape",
+			Value: "say \"hi\"\n",
+			Want:  `"say \"hi\"\n"`,
+		},
+		{
+			Name:  "map",
+			Value: map[string]int{"b": 2, "a": 1},
+			Want:  `{"a":1,"b":2}`,
+		},
+		{
+			Name: "struct",
+			Value: marshalItem{
+				marshalBase: marshalBase{ID: 1},
+				Name:        "book",
+				Price:       9.5,
+				Tags:        []string{"a", "b"},
+				When:        when,
+				Data:        []byte("go"),
+				Labels:      map[int]string{2: "two", 1: "one"},
+				Skip:        "skip",
+				private:     1,
+			},
+			Want: `{"id":1,"name":"book","price":"9.5","tags":["a","b"],"when":"2024-05-06T07:08:09Z","data":"Z28=","labels":{"1":"one","2":"two"},"Default":false}`,
+		},
+		{
+			Name: "nested",
+			Value: &marshalItem{
+				marshalBase: marshalBase{ID: 2, Kind: "child"},
+				Parent:      &marshalItem{Name: "root"},
+			},
+			Want: `{"id":2,"kind":"child","name":"","price":"0","parent":{"id":0,"name":"root","price":"0","when":"0001-01-01T00:00:00Z","Default":false},"when":"0001-01-01T00:00:00Z","Default":false}`,
+		},
+		{
+			Name:  "nil-slice",
+			Value: struct{ List []int }{},
+			Want:  `{"List":null}`,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := Marshal(c.Value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != c.Want {
+				t.Errorf("result mismatched!\nwant: %s\ngot:  %s", c.Want, got)
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Value any
+	}{
+		{
+			Name:  "nan",
+			Value: math.NaN(),
+		},
+		{
+			Name:  "infinity",
+			Value: math.Inf(1),
+		},
+		{
+			Name:  "channel",
+			Value: make(chan int),
+		},
+		{
+			Name:  "map-key",
+			Value: map[float64]int{1: 1},
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			_, err := Marshal(c.Value)
+			if !errors.Is(err, errType) {
+				t.Errorf("expected type error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	var item marshalItem
+	data := `{"id":1,"kind":"book","NAME":"go","price":"9.5","tags":["a","b"],"attrs":{"x":1},"parent":{"name":"root"},"when":"2024-05-06T07:08:09Z","data":"Z28=","labels":{"1":"one"},"Skip":"skip","unknown":true}`
+	if err := Unmarshal([]byte(data), &item); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := marshalItem{
+		marshalBase: marshalBase{ID: 1, Kind: "book"},
+		Name:        "go",
+		Price:       9.5,
+		Tags:        []string{"a", "b"},
+		Attrs:       map[string]int{"x": 1},
+		Parent:      &marshalItem{Name: "root"},
+		When:        when,
+		Data:        []byte("go"),
+		Labels:      map[int]string{1: "one"},
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("result mismatched!\nwant: %+v\ngot:  %+v", want, item)
+	}
+}
+
+func TestUnmarshal5(t *testing.T) {
+	var got struct {
+		Name  string
+		Items []int
+	}
+	data := `{
+		// comment
+		name: 'go',
+		items: [1, 2, 3,],
+	}`
+	if err := Unmarshal5([]byte(data), &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Name != "go" || !reflect.DeepEqual(got.Items, []int{1, 2, 3}) {
+		t.Errorf("result mismatched! got %+v", got)
+	}
+	if err := Unmarshal([]byte(data), &got); err == nil {
+		t.Errorf("json5 input should be rejected in standard mode")
+	}
+}
+
+func TestUnmarshalNumbers(t *testing.T) {
+	var got struct {
+		Int    int64
+		Uint   uint64
+		Exp    int
+		Float  float64
+		Number Number
+		Any    any
+	}
+	data := `{"Int":9007199254740993,"Uint":18446744073709551615,"Exp":1e2,"Float":0.5,"Number":12.50,"Any":[1,{"x":2}]}`
+	if err := Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Int != 9007199254740993 {
+		t.Errorf("int mismatched! got %d", got.Int)
+	}
+	if got.Uint != math.MaxUint64 {
+		t.Errorf("uint mismatched! got %d", got.Uint)
+	}
+	if got.Exp != 100 {
+		t.Errorf("exponent mismatched! got %d", got.Exp)
+	}
+	if got.Float != 0.5 {
+		t.Errorf("float mismatched! got %f", got.Float)
+	}
+	if got.Number != "12.50" {
+		t.Errorf("number literal mismatched! got %s", got.Number)
+	}
+	want := []any{float64(1), map[string]any{"x": float64(2)}}
+	if !reflect.DeepEqual(got.Any, want) {
+		t.Errorf("interface mismatched! want %v, got %v", want, got.Any)
+	}
+	out, err := Marshal(got.Number)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(out) != "12.50" {
+		t.Errorf("number should be written as literal! got %s", out)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Data   string
+		Target any
+		Err    error
+	}{
+		{
+			Name:   "not-pointer",
+			Data:   `1`,
+			Target: 0,
+			Err:    errTarget,
+		},
+		{
+			Name:   "nil-pointer",
+			Data:   `1`,
+			Target: (*int)(nil),
+			Err:    errTarget,
+		},
+		{
+			Name:   "string-into-int",
+			Data:   `"1"`,
+			Target: new(int),
+			Err:    errType,
+		},
+		{
+			Name:   "fraction-into-int",
+			Data:   `1.5`,
+			Target: new(int),
+			Err:    errType,
+		},
+		{
+			Name:   "overflow",
+			Data:   `300`,
+			Target: new(int8),
+			Err:    errType,
+		},
+		{
+			Name:   "exponent-overflow",
+			Data:   `1e20`,
+			Target: new(int64),
+			Err:    strconv.ErrRange,
+		},
+		{
+			Name:   "int64-overflow",
+			Data:   `9223372036854775808`,
+			Target: new(int64),
+			Err:    strconv.ErrRange,
+		},
+		{
+			Name:   "uint64-overflow",
+			Data:   `18446744073709551616`,
+			Target: new(uint64),
+			Err:    strconv.ErrRange,
+		},
+		{
+			Name:   "negative-into-uint",
+			Data:   `-1`,
+			Target: new(uint),
+			Err:    errType,
+		},
+		{
+			Name:   "field",
+			Data:   `{"id":"one"}`,
+			Target: new(marshalBase),
+			Err:    errType,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			err := Unmarshal([]byte(c.Data), c.Target)
+			if !errors.Is(err, c.Err) {
+				t.Errorf("expected %v, got %v", c.Err, err)
+			}
+		})
+	}
+}
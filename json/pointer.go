@@ -0,0 +1,70 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errPointer = errors.New("invalid pointer")
+
+type Pointer []string
+
+func ParsePointer(str string) (Pointer, error) {
+	if str == "" {
+		return nil, nil
+	}
+	if str[0] != '/' {
+		return nil, fmt.Errorf("%w: %q should start with /", errPointer, str)
+	}
+	var ptr Pointer
+	for _, tok := range strings.Split(str[1:], "/") {
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tok = strings.ReplaceAll(tok, "~0", "~")
+		ptr = append(ptr, tok)
+	}
+	return ptr, nil
+}
+
+func (p Pointer) String() string {
+	var str strings.Builder
+	for _, tok := range p {
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		tok = strings.ReplaceAll(tok, "/", "~1")
+		str.WriteByte('/')
+		str.WriteString(tok)
+	}
+	return str.String()
+}
+
+func (p Pointer) Append(tok string) Pointer {
+	return append(slices.Clip(p), tok)
+}
+
+func (p Pointer) Index(ix int) Pointer {
+	return p.Append(strconv.Itoa(ix))
+}
+
+func (p Pointer) Get(value any) (any, error) {
+	for i, tok := range p {
+		switch v := value.(type) {
+		case map[string]any:
+			other, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s not found", errPointer, p[:i+1])
+			}
+			value = other
+		case []any:
+			ix, err := strconv.Atoi(tok)
+			if err != nil || ix < 0 || ix >= len(v) {
+				return nil, fmt.Errorf("%w: %s not found", errPointer, p[:i+1])
+			}
+			value = v[ix]
+		default:
+			return nil, fmt.Errorf("%w: %s can not be traversed (%T)", errPointer, p[:i], value)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,84 @@
+package json
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPointer(t *testing.T) {
+	doc, err := Decode(strings.NewReader(`{"a": {"b/c": [1, {"d~e": true}]}, "": "empty"}`))
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	tests := []struct {
+		Pointer string
+		Tokens  []string
+		Want    any
+	}{
+		{
+			Pointer: "",
+			Tokens:  nil,
+		},
+		{
+			Pointer: "/a/b~1c/0",
+			Tokens:  []string{"a", "b/c", "0"},
+			Want:    1.0,
+		},
+		{
+			Pointer: "/a/b~1c/1/d~0e",
+			Tokens:  []string{"a", "b/c", "1", "d~e"},
+			Want:    true,
+		},
+		{
+			Pointer: "/",
+			Tokens:  []string{""},
+			Want:    "empty",
+		},
+	}
+	for _, c := range tests {
+		ptr, err := ParsePointer(c.Pointer)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Pointer, err)
+			continue
+		}
+		if !slices.Equal(ptr, c.Tokens) {
+			t.Errorf("%s: tokens mismatched! want %q, got %q", c.Pointer, c.Tokens, ptr)
+		}
+		if got := ptr.String(); got != c.Pointer {
+			t.Errorf("%s: string mismatched! got %s", c.Pointer, got)
+		}
+		if c.Want == nil {
+			continue
+		}
+		got, err := ptr.Get(doc)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Pointer, err)
+			continue
+		}
+		if got != c.Want {
+			t.Errorf("%s: value mismatched! want %v, got %v", c.Pointer, c.Want, got)
+		}
+	}
+}
+
+func TestPointerErrors(t *testing.T) {
+	doc, err := Decode(strings.NewReader(`{"a": [1, 2], "b": "str"}`))
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	if _, err := ParsePointer("a/b"); !errors.Is(err, errPointer) {
+		t.Errorf("pointer without leading slash should be rejected, got %v", err)
+	}
+	for _, str := range []string{"/c", "/a/2", "/a/-1", "/a/x", "/b/0"} {
+		ptr, err := ParsePointer(str)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", str, err)
+			continue
+		}
+		if _, err := ptr.Get(doc); !errors.Is(err, errPointer) {
+			t.Errorf("%s: expected %v, got %v", str, errPointer, err)
+		}
+	}
+}
@@ -6,14 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"unicode"
+	"unicode/utf16"
 	"unicode/utf8"
 
 	"github.com/midbel/codecs/internal/jsonkit"
 )
 
-var errSyntax = errors.New("syntax error")
+const MaxDepth = 512
+
+var (
+	errSyntax = errors.New("syntax error")
+
+	ErrDepth  = errors.New("maximum depth reached")
+	ErrSize   = errors.New("maximum input size reached")
+	ErrString = errors.New("maximum string length reached")
+)
 
 func Decode(r io.Reader) (any, error) {
 	p := createParser(r, stdMode)
@@ -26,25 +36,53 @@ func Decode5(r io.Reader) (any, error) {
 }
 
 type Parser struct {
-	scan *Scanner
-	curr jsonkit.Token
-	peek jsonkit.Token
+	scan  *Scanner
+	curr  jsonkit.Token
+	peek  jsonkit.Token
+	ready bool
+
+	depth int
+
+	MaxDepth     int
+	MaxBytes     int
+	MaxStringLen int
+	UseNumber    bool
+	// KeepOrder decodes objects as Object instead of map, preserving the
+	// order of their fields and the fields with duplicate keys.
+	KeepOrder bool
 
 	mode
 }
 
+func NewParser(r io.Reader) *Parser {
+	return createParser(r, stdMode)
+}
+
+func NewParser5(r io.Reader) *Parser {
+	return createParser(r, json5Mode)
+}
+
 func createParser(r io.Reader, jm mode) *Parser {
-	p := &Parser{
-		scan: Scan(r, jm),
-		mode: stdMode,
+	return &Parser{
+		scan:     Scan(r, jm),
+		mode:     jm,
+		MaxDepth: MaxDepth,
 	}
-	p.next()
-	p.next()
-	return p
 }
 
 func (p *Parser) Parse() (any, error) {
-	return p.parse()
+	if !p.ready {
+		p.scan.maxBytes = p.MaxBytes
+		p.scan.maxString = p.MaxStringLen
+		p.next()
+		p.next()
+		p.ready = true
+	}
+	doc, err := p.parse()
+	if p.scan.err != nil {
+		return nil, p.scan.err
+	}
+	return doc, err
 }
 
 func (p *Parser) parse() (any, error) {
@@ -83,8 +121,16 @@ func (p *Parser) parseKey() (string, error) {
 }
 
 func (p *Parser) parseObject() (any, error) {
+	p.enter()
+	defer p.leave()
+	if p.MaxDepth > 0 && p.depth > p.MaxDepth {
+		return nil, ErrDepth
+	}
 	p.next()
-	obj := make(map[string]any)
+	var (
+		obj    = make(map[string]any)
+		fields = Object{}
+	)
 	for !p.done() && !p.is(jsonkit.EndObj) {
 		k, err := p.parseKey()
 		if err != nil {
@@ -95,7 +141,11 @@ func (p *Parser) parseObject() (any, error) {
 			return nil, err
 		}
 
-		obj[k] = a
+		if p.KeepOrder {
+			fields = append(fields, Field{Key: k, Value: a})
+		} else {
+			obj[k] = a
+		}
 		switch {
 		case p.is(jsonkit.Comma):
 			p.next()
@@ -111,10 +161,18 @@ func (p *Parser) parseObject() (any, error) {
 		return nil, p.syntaxError("missing '}' at end of object")
 	}
 	p.next()
+	if p.KeepOrder {
+		return fields, nil
+	}
 	return obj, nil
 }
 
 func (p *Parser) parseArray() (any, error) {
+	p.enter()
+	defer p.leave()
+	if p.MaxDepth > 0 && p.depth > p.MaxDepth {
+		return nil, ErrDepth
+	}
 	p.next()
 	var arr []any
 	for !p.done() && !p.is(jsonkit.EndArr) {
@@ -143,12 +201,63 @@ func (p *Parser) parseArray() (any, error) {
 
 func (p *Parser) parseNumber() any {
 	defer p.next()
-	n, err := strconv.ParseFloat(p.currentLiteral(), 64)
+	n := Number(p.currentLiteral())
+	if p.UseNumber {
+		return n
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+type Number string
+
+func (n Number) String() string {
+	return string(n)
+}
+
+func (n Number) Float64() (float64, error) {
+	f, err := strconv.ParseFloat(string(n), 64)
 	if err != nil {
-		n, _ := strconv.ParseInt(p.currentLiteral(), 0, 64)
-		return float64(n)
+		i, err := strconv.ParseInt(string(n), 0, 64)
+		return float64(i), err
 	}
-	return n
+	return f, nil
+}
+
+func (n Number) Int64() (int64, error) {
+	i, err := strconv.ParseInt(string(n), 0, 64)
+	if err == nil || errors.Is(err, strconv.ErrRange) {
+		return i, err
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%w: %s is not an integer", errType, n)
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, &strconv.NumError{Func: "ParseInt", Num: string(n), Err: strconv.ErrRange}
+	}
+	return int64(f), nil
+}
+
+func (n Number) Uint64() (uint64, error) {
+	i, err := strconv.ParseUint(string(n), 0, 64)
+	if err == nil || errors.Is(err, strconv.ErrRange) {
+		return i, err
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%w: %s is not an unsigned integer", errType, n)
+	}
+	if f >= math.MaxUint64 {
+		return 0, &strconv.NumError{Func: "ParseUint", Num: string(n), Err: strconv.ErrRange}
+	}
+	return uint64(f), nil
 }
 
 func (p *Parser) parseBool() any {
@@ -173,6 +282,14 @@ func (p *Parser) done() bool {
 	return p.is(jsonkit.EOF)
 }
 
+func (p *Parser) enter() {
+	p.depth++
+}
+
+func (p *Parser) leave() {
+	p.depth--
+}
+
 func (p *Parser) is(kind rune) bool {
 	return p.curr.Type == kind
 }
@@ -180,6 +297,9 @@ func (p *Parser) is(kind rune) bool {
 func (p *Parser) next() {
 	p.curr = p.peek
 	p.peek = p.scan.Scan()
+	for p.peek.Type == jsonkit.Comment {
+		p.peek = p.scan.Scan()
+	}
 }
 
 func (p *Parser) currentLiteral() string {
@@ -214,6 +334,11 @@ type Scanner struct {
 	old jsonkit.Position
 
 	str bytes.Buffer
+
+	size      int
+	maxBytes  int
+	maxString int
+	err       error
 }
 
 func Scan(r io.Reader, mode mode) *Scanner {
@@ -318,6 +443,11 @@ func (s *Scanner) scanString(tok *jsonkit.Token) {
 		}
 		s.write()
 		s.read()
+		if s.maxString > 0 && s.str.Len() > s.maxString {
+			s.err = fmt.Errorf("%w (%d bytes)", ErrString, s.maxString)
+			tok.Type = jsonkit.Invalid
+			return
+		}
 	}
 	tok.Literal = s.str.String()
 	tok.Type = jsonkit.String
@@ -347,25 +477,54 @@ func (s *Scanner) scanEscape(quote rune) bool {
 	case 't':
 		s.char = '\t'
 	case 'u':
-		s.read()
-		buf := make([]rune, 4)
-		for i := 1; i <= 4; i++ {
-			if !jsonkit.IsHex(s.char) {
+		char, ok := s.scanCodePoint()
+		if !ok {
+			return false
+		}
+		switch {
+		case utf16.IsSurrogate(char) && char < 0xDC00:
+			s.read()
+			if s.char != '\\' {
 				return false
 			}
-			buf[i-1] = s.char
-			if i < 4 {
-				s.read()
+			s.read()
+			if s.char != 'u' {
+				return false
+			}
+			low, ok := s.scanCodePoint()
+			if !ok {
+				return false
 			}
+			char = utf16.DecodeRune(char, low)
+			if char == utf8.RuneError {
+				return false
+			}
+		case utf16.IsSurrogate(char):
+			return false
 		}
-		char, _ := strconv.ParseInt(string(buf), 16, 32)
-		s.char = rune(char)
+		s.char = char
 	default:
 		return false
 	}
 	return true
 }
 
+func (s *Scanner) scanCodePoint() (rune, bool) {
+	s.read()
+	buf := make([]rune, 4)
+	for i := 1; i <= 4; i++ {
+		if !jsonkit.IsHex(s.char) {
+			return 0, false
+		}
+		buf[i-1] = s.char
+		if i < 4 {
+			s.read()
+		}
+	}
+	char, _ := strconv.ParseInt(string(buf), 16, 32)
+	return rune(char), true
+}
+
 func (s *Scanner) scanHexa(tok *jsonkit.Token) {
 	s.read()
 	s.read()
@@ -470,10 +629,17 @@ func (s *Scanner) read() {
 	}
 	s.Column++
 
-	char, _, err := s.input.ReadRune()
+	char, n, err := s.input.ReadRune()
 	if errors.Is(err, io.EOF) {
 		char = utf8.RuneError
 	}
+	s.size += n
+	if s.maxBytes > 0 && s.size > s.maxBytes {
+		if s.err == nil {
+			s.err = fmt.Errorf("%w (%d bytes)", ErrSize, s.maxBytes)
+		}
+		char = utf8.RuneError
+	}
 	s.char = char
 }
 
@@ -0,0 +1,170 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEscape(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+	}{
+		{
+			Input: `"\u00e9"`,
+			Want:  "é",
+		},
+		{
+			Input: `"\uD83D\uDE00"`,
+			Want:  "😀",
+		},
+		{
+			Input: `"a\ud83d\ude00b"`,
+			Want:  "a😀b",
+		},
+		{
+			Input: `"😀"`,
+			Want:  "😀",
+		},
+		{
+			Input: `"\"\\\/\b\f\n\r\t"`,
+			Want:  "\"\\/\b\f\n\r\t",
+		},
+	}
+	for _, c := range tests {
+		got, err := Decode(strings.NewReader(c.Input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Input, err)
+			continue
+		}
+		if got != c.Want {
+			t.Errorf("%s: result mismatched! want %q, got %q", c.Input, c.Want, got)
+		}
+	}
+}
+
+func TestDecodeInvalidEscape(t *testing.T) {
+	tests := []string{
+		`"\uD83D"`,
+		`"\uDE00"`,
+		`"\uD83Dx"`,
+		`"\uD83D\u0041"`,
+		`"\uDE00\uD83D"`,
+		`"\uD83D\n"`,
+		`"\u12"`,
+		`"\x"`,
+	}
+	for _, str := range tests {
+		if got, err := Decode(strings.NewReader(str)); err == nil {
+			t.Errorf("%s: expected error, got %q", str, got)
+		}
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		Input string
+		Setup func(*Parser)
+		Err   error
+	}{
+		{
+			Input: `[[[1]]]`,
+			Setup: func(p *Parser) { p.MaxDepth = 2 },
+			Err:   ErrDepth,
+		},
+		{
+			Input: `{"a": {"b": {"c": 1}}}`,
+			Setup: func(p *Parser) { p.MaxDepth = 2 },
+			Err:   ErrDepth,
+		},
+		{
+			Input: `[[[1]]]`,
+			Setup: func(p *Parser) { p.MaxDepth = 3 },
+		},
+		{
+			Input: `{"a": {"b": {"c": 1}}}`,
+			Setup: func(p *Parser) { p.MaxDepth = 0 },
+		},
+		{
+			Input: `["abcdefghij", "klmnopqrst"]`,
+			Setup: func(p *Parser) { p.MaxBytes = 10 },
+			Err:   ErrSize,
+		},
+		{
+			Input: `["abc"]`,
+			Setup: func(p *Parser) { p.MaxBytes = 10 },
+		},
+		{
+			Input: `{"key": "abcdefghij"}`,
+			Setup: func(p *Parser) { p.MaxStringLen = 5 },
+			Err:   ErrString,
+		},
+		{
+			Input: `{"key": "abcde"}`,
+			Setup: func(p *Parser) { p.MaxStringLen = 5 },
+		},
+	}
+	for _, c := range tests {
+		p := NewParser(strings.NewReader(c.Input))
+		c.Setup(p)
+		_, err := p.Parse()
+		if c.Err == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", c.Input, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.Err) {
+			t.Errorf("%s: expected %v, got %v", c.Input, c.Err, err)
+		}
+	}
+}
+
+func TestParserDefaultDepth(t *testing.T) {
+	input := strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1)
+	_, err := NewParser(strings.NewReader(input)).Parse()
+	if !errors.Is(err, ErrDepth) {
+		t.Errorf("expected %v, got %v", ErrDepth, err)
+	}
+	input = strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth)
+	if _, err := NewParser(strings.NewReader(input)).Parse(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestParserKeepOrder(t *testing.T) {
+	p := NewParser(strings.NewReader(`{"b": 1, "a": {"y": true, "x": null}, "b": 2}`))
+	p.KeepOrder = true
+	got, err := p.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	obj, ok := got.(Object)
+	if !ok {
+		t.Fatalf("object expected, got %T", got)
+	}
+	var keys []string
+	for _, f := range obj {
+		keys = append(keys, f.Key)
+	}
+	if str := strings.Join(keys, ","); str != "b,a,b" {
+		t.Errorf("keys mismatched! want b,a,b, got %s", str)
+	}
+	if obj[2].Value != 2.0 {
+		t.Errorf("duplicate key should keep its value, got %v", obj[2].Value)
+	}
+	if _, ok := obj[1].Value.(Object); !ok {
+		t.Errorf("nested object should keep its order, got %T", obj[1].Value)
+	}
+
+	var str strings.Builder
+	ws := NewWriter(&str)
+	ws.Compact = true
+	if err := ws.Write(got); err != nil {
+		t.Fatalf("fail to write object: %s", err)
+	}
+	if want := `{"b":1,"a":{"y":true,"x":null},"b":2}`; str.String() != want {
+		t.Errorf("output mismatched! want %s, got %s", want, str.String())
+	}
+}
@@ -0,0 +1,89 @@
+package json
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+var (
+	SkipValue   = errors.New("skip value")
+	RemoveValue = errors.New("remove value")
+)
+
+type WalkFunc func(Pointer, any) error
+
+func Walk(value any, fn WalkFunc) error {
+	err := walk(nil, value, fn)
+	if errors.Is(err, SkipValue) {
+		return nil
+	}
+	return err
+}
+
+func walk(ptr Pointer, value any, fn WalkFunc) error {
+	if err := fn(ptr, value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			err := walk(ptr.Append(k), v[k], fn)
+			if err != nil && !errors.Is(err, SkipValue) {
+				return err
+			}
+		}
+	case []any:
+		for i := range v {
+			err := walk(ptr.Index(i), v[i], fn)
+			if err != nil && !errors.Is(err, SkipValue) {
+				return err
+			}
+		}
+	default:
+	}
+	return nil
+}
+
+type RewriteFunc func(Pointer, any) (any, error)
+
+func Rewrite(value any, fn RewriteFunc) (any, error) {
+	res, err := rewrite(nil, value, fn)
+	if errors.Is(err, RemoveValue) {
+		return nil, nil
+	}
+	return res, err
+}
+
+func rewrite(ptr Pointer, value any, fn RewriteFunc) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		obj := make(map[string]any)
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			res, err := rewrite(ptr.Append(k), v[k], fn)
+			if errors.Is(err, RemoveValue) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = res
+		}
+		value = obj
+	case []any:
+		arr := make([]any, 0, len(v))
+		for i := range v {
+			res, err := rewrite(ptr.Index(i), v[i], fn)
+			if errors.Is(err, RemoveValue) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, res)
+		}
+		value = arr
+	default:
+	}
+	return fn(ptr, value)
+}
@@ -0,0 +1,123 @@
+package json
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const walkDocument = `{"name": "root", "items": [1, {"skip": [2, 3], "keep": 4}], "flag": true}`
+
+func TestWalk(t *testing.T) {
+	doc, err := Decode(strings.NewReader(walkDocument))
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	tests := []struct {
+		Name string
+		Stop string
+		Want []string
+	}{
+		{
+			Name: "all",
+			Want: []string{"", "/flag", "/items", "/items/0", "/items/1", "/items/1/keep", "/items/1/skip", "/items/1/skip/0", "/items/1/skip/1", "/name"},
+		},
+		{
+			Name: "skip",
+			Stop: "/items/1/skip",
+			Want: []string{"", "/flag", "/items", "/items/0", "/items/1", "/items/1/keep", "/items/1/skip", "/name"},
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			var got []string
+			err := Walk(doc, func(ptr Pointer, _ any) error {
+				got = append(got, ptr.String())
+				if c.Stop != "" && ptr.String() == c.Stop {
+					return SkipValue
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !slices.Equal(got, c.Want) {
+				t.Errorf("paths mismatched!\nwant: %v\ngot:  %v", c.Want, got)
+			}
+		})
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	doc, err := Decode(strings.NewReader(walkDocument))
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	var (
+		stop  = errors.New("stop")
+		count int
+	)
+	err = Walk(doc, func(ptr Pointer, _ any) error {
+		count++
+		if ptr.String() == "/items/0" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected %v, got %v", stop, err)
+	}
+	if count != 4 {
+		t.Errorf("walk should stop at first error! got %d visits", count)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	doc, err := Decode(strings.NewReader(walkDocument))
+	if err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	got, err := Rewrite(doc, func(ptr Pointer, value any) (any, error) {
+		switch v := value.(type) {
+		case float64:
+			return v * 10, nil
+		case bool:
+			return nil, RemoveValue
+		}
+		if ptr.String() == "/items/1/skip" {
+			return nil, RemoveValue
+		}
+		return value, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want, _ := Decode(strings.NewReader(`{"name": "root", "items": [10, {"keep": 40}]}`))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result mismatched!\nwant: %v\ngot:  %v", want, got)
+	}
+	orig, _ := Decode(strings.NewReader(walkDocument))
+	if !reflect.DeepEqual(doc, orig) {
+		t.Errorf("rewrite should not modify its input")
+	}
+
+	removed, err := Rewrite(doc, func(_ Pointer, _ any) (any, error) {
+		return nil, RemoveValue
+	})
+	if err != nil || removed != nil {
+		t.Errorf("removing root should give nil, got %v (%v)", removed, err)
+	}
+
+	stop := errors.New("stop")
+	_, err = Rewrite(doc, func(_ Pointer, value any) (any, error) {
+		if value == "root" {
+			return nil, stop
+		}
+		return value, nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected %v, got %v", stop, err)
+	}
+}
@@ -4,16 +4,26 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
+const hexDigits = "0123456789abcdef"
+
 type Writer struct {
 	ws *bufio.Writer
 
-	Indent  string
-	Pretty  bool
-	Compact bool
+	Indent     string
+	Pretty     bool
+	Compact    bool
+	EscapeHTML bool
+	ASCII      bool
+	SortKeys   bool
 
 	level int
 }
@@ -33,35 +43,84 @@ func NewWriter(w io.Writer) *Writer {
 }
 
 func (w *Writer) Write(value any) error {
-	defer func() {
-		w.reset()
-		w.ws.Flush()
-	}()
+	defer w.flush()
 	return w.writeValue(value)
 }
 
+func (w *Writer) WriteArrayFrom(seq iter.Seq[any]) error {
+	defer w.flush()
+	return w.writeArrayFrom(seq)
+}
+
+func (w *Writer) WriteObjectFrom(seq iter.Seq2[string, any]) error {
+	defer w.flush()
+	return w.writeObjectFrom(seq)
+}
+
+func (w *Writer) flush() {
+	w.reset()
+	w.ws.Flush()
+}
+
 func (w *Writer) writeValue(value any) error {
 	switch v := value.(type) {
 	case map[string]any:
 		return w.writeObject(v)
+	case Object:
+		return w.writeOrderedObject(v)
 	case []any:
 		return w.writeArray(v)
+	case iter.Seq[any]:
+		return w.writeArrayFrom(v)
+	case func(func(any) bool):
+		return w.writeArrayFrom(v)
+	case iter.Seq2[string, any]:
+		return w.writeObjectFrom(v)
+	case func(func(string, any) bool):
+		return w.writeObjectFrom(v)
 	default:
 		return w.writeLiteral(value)
 	}
 }
 
 func (w *Writer) writeObject(value map[string]any) error {
+	keys := slices.Collect(maps.Keys(value))
+	if w.SortKeys {
+		slices.Sort(keys)
+	}
+	fields := func(yield func(string, any) bool) {
+		for _, k := range keys {
+			if !yield(k, value[k]) {
+				return
+			}
+		}
+	}
+	return w.writeObjectFrom(fields)
+}
+
+func (w *Writer) writeOrderedObject(value Object) error {
+	fields := func(yield func(string, any) bool) {
+		for _, f := range value {
+			if !yield(f.Key, f.Value) {
+				return
+			}
+		}
+	}
+	return w.writeObjectFrom(fields)
+}
+
+func (w *Writer) writeObjectFrom(seq iter.Seq2[string, any]) error {
 	w.enter()
 
 	w.ws.WriteRune('{')
 	w.writeNL()
 	var i int
-	for k, v := range value {
+	for k, v := range seq {
 		if i > 0 {
 			w.ws.WriteRune(',')
 			w.writeNL()
 		}
+		i++
 		w.writePrefix()
 		if err := w.writeKey(k); err != nil {
 			return err
@@ -69,7 +128,6 @@ func (w *Writer) writeObject(value map[string]any) error {
 		if err := w.writeValue(v); err != nil {
 			return err
 		}
-		i++
 	}
 	w.leave()
 	w.writeNL()
@@ -79,17 +137,23 @@ func (w *Writer) writeObject(value map[string]any) error {
 }
 
 func (w *Writer) writeArray(value []any) error {
+	return w.writeArrayFrom(slices.Values(value))
+}
+
+func (w *Writer) writeArrayFrom(seq iter.Seq[any]) error {
 	w.enter()
 
 	w.ws.WriteRune('[')
 	w.writeNL()
-	for i := range value {
+	var i int
+	for v := range seq {
 		if i > 0 {
 			w.ws.WriteRune(',')
 			w.writeNL()
 		}
+		i++
 		w.writePrefix()
-		if err := w.writeValue(value[i]); err != nil {
+		if err := w.writeValue(v); err != nil {
 			return err
 		}
 	}
@@ -115,9 +179,13 @@ func (w *Writer) writeLiteral(value any) error {
 	case float64:
 		w.ws.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
 	case int64:
-		w.ws.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 64))
+		w.ws.WriteString(strconv.FormatInt(v, 10))
+	case uint64:
+		w.ws.WriteString(strconv.FormatUint(v, 10))
 	case string:
 		w.writeString(v)
+	case Number:
+		w.ws.WriteString(string(v))
 	default:
 		return fmt.Errorf("unsupported json type %T", value)
 	}
@@ -135,11 +203,52 @@ func (w *Writer) writeKey(key string) error {
 
 func (w *Writer) writeString(value string) error {
 	w.ws.WriteRune('"')
-	w.ws.WriteString(value)
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		i += size
+		switch {
+		case r == '"':
+			w.ws.WriteString(`\"`)
+		case r == '\\':
+			w.ws.WriteString(`\\`)
+		case r == '\n':
+			w.ws.WriteString(`\n`)
+		case r == '\r':
+			w.ws.WriteString(`\r`)
+		case r == '\t':
+			w.ws.WriteString(`\t`)
+		case r == '\b':
+			w.ws.WriteString(`\b`)
+		case r == '\f':
+			w.ws.WriteString(`\f`)
+		case r == utf8.RuneError && size == 1:
+			w.ws.WriteString(`\ufffd`)
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			w.writeUnicode(r)
+		case w.EscapeHTML && (r == '<' || r == '>' || r == '&'):
+			w.writeUnicode(r)
+		case w.ASCII && r >= utf8.RuneSelf:
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				w.writeUnicode(r1)
+				w.writeUnicode(r2)
+			} else {
+				w.writeUnicode(r)
+			}
+		default:
+			w.ws.WriteRune(r)
+		}
+	}
 	w.ws.WriteRune('"')
 	return nil
 }
 
+func (w *Writer) writeUnicode(r rune) {
+	w.ws.WriteString(`\u`)
+	for shift := 12; shift >= 0; shift -= 4 {
+		w.ws.WriteByte(hexDigits[(r>>shift)&0xF])
+	}
+}
+
 func (w *Writer) writePrefix() {
 	if w.Compact || w.level == 0 {
 		return
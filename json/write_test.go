@@ -0,0 +1,297 @@
+package json
+
+import (
+	"iter"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriterEscape(t *testing.T) {
+	tests := []struct {
+		Value      string
+		EscapeHTML bool
+		Want       string
+	}{
+		{
+			Value: `plain`,
+			Want:  `"plain"`,
+		},
+		{
+			Value: `say "hi" \ bye`,
+			Want:  `"say \"hi\" \\ bye"`,
+		},
+		{
+			Value: "\n\r\t\b\f",
+			Want:  `"\n\r\t\b\f"`,
+		},
+		{
+			Value: "\x00\x1f",
+			Want:  `"\u0000\u001f"`,
+		},
+		{
+			Value: "\u2028\u2029",
+			Want:  `"\u2028\u2029"`,
+		},
+		{
+			Value: "bad\xffbyte",
+			Want:  `"bad\ufffdbyte"`,
+		},
+		{
+			Value: "é😀",
+			Want:  `"é😀"`,
+		},
+		{
+			Value: `<a href="x">&</a>`,
+			Want:  `"<a href=\"x\">&</a>"`,
+		},
+		{
+			Value:      `<a href="x">&</a>`,
+			EscapeHTML: true,
+			Want:       `"\u003ca href=\"x\"\u003e\u0026\u003c/a\u003e"`,
+		},
+	}
+	for _, c := range tests {
+		var (
+			str strings.Builder
+			ws  = Compact(&str)
+		)
+		ws.EscapeHTML = c.EscapeHTML
+		if err := ws.Write(c.Value); err != nil {
+			t.Errorf("%q: unexpected error: %s", c.Value, err)
+			continue
+		}
+		if got := str.String(); got != c.Want {
+			t.Errorf("%q: result mismatched! want %s, got %s", c.Value, c.Want, got)
+		}
+		if c.Value == "bad\xffbyte" {
+			continue
+		}
+		got, err := Decode(strings.NewReader(str.String()))
+		if err != nil {
+			t.Errorf("%q: fail to decode written string: %s", c.Value, err)
+			continue
+		}
+		if got != c.Value {
+			t.Errorf("%q: round trip mismatched! got %q", c.Value, got)
+		}
+	}
+}
+
+func TestWriterSortKeys(t *testing.T) {
+	value := map[string]any{
+		"zeta":  1.0,
+		"alpha": map[string]any{"b": true, "a": nil},
+		"mid":   []any{"x", map[string]any{"y": 2.0, "x": 1.0}},
+	}
+	tests := []struct {
+		Compact bool
+		Want    string
+	}{
+		{
+			Compact: true,
+			Want:    `{"alpha":{"a":null,"b":true},"mid":["x",{"x":1,"y":2}],"zeta":1}`,
+		},
+		{
+			Want: `{
+  "alpha": {
+    "a": null,
+    "b": true
+  },
+  "mid": [
+    "x",
+    {
+      "x": 1,
+      "y": 2
+    }
+  ],
+  "zeta": 1
+}`,
+		},
+	}
+	for _, c := range tests {
+		var (
+			str strings.Builder
+			ws  = NewWriter(&str)
+		)
+		ws.Compact = c.Compact
+		ws.SortKeys = true
+		if err := ws.Write(value); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := str.String(); got != c.Want {
+			t.Errorf("result mismatched!\nwant: %s\ngot:  %s", c.Want, got)
+		}
+		got, err := Decode(strings.NewReader(str.String()))
+		if err != nil {
+			t.Fatalf("fail to decode written object: %s", err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("round trip mismatched! want %v, got %v", value, got)
+		}
+	}
+}
+
+func TestWriterASCII(t *testing.T) {
+	tests := []struct {
+		Value string
+		Want  string
+	}{
+		{
+			Value: "plain",
+			Want:  `"plain"`,
+		},
+		{
+			Value: "é",
+			Want:  `"\u00e9"`,
+		},
+		{
+			Value: "\u20ac",
+			Want:  `"\u20ac"`,
+		},
+		{
+			Value: "a😀b",
+			Want:  `"a\ud83d\ude00b"`,
+		},
+	}
+	for _, c := range tests {
+		var (
+			str strings.Builder
+			ws  = Compact(&str)
+		)
+		ws.ASCII = true
+		if err := ws.Write(c.Value); err != nil {
+			t.Errorf("%q: unexpected error: %s", c.Value, err)
+			continue
+		}
+		if got := str.String(); got != c.Want {
+			t.Errorf("%q: result mismatched! want %s, got %s", c.Value, c.Want, got)
+		}
+		got, err := Decode(strings.NewReader(str.String()))
+		if err != nil {
+			t.Errorf("%q: fail to decode written string: %s", c.Value, err)
+			continue
+		}
+		if got != c.Value {
+			t.Errorf("%q: round trip mismatched! got %q", c.Value, got)
+		}
+	}
+}
+
+func TestWriterArrayFrom(t *testing.T) {
+	numbers := func(yield func(any) bool) {
+		for i := range 3 {
+			if !yield(float64(i + 1)) {
+				return
+			}
+		}
+	}
+	tests := []struct {
+		Seq     iter.Seq[any]
+		Compact bool
+		Want    string
+	}{
+		{
+			Seq:     numbers,
+			Compact: true,
+			Want:    `[1,2,3]`,
+		},
+		{
+			Seq:  numbers,
+			Want: "[\n  1,\n  2,\n  3\n]",
+		},
+		{
+			Seq:     func(func(any) bool) {},
+			Compact: true,
+			Want:    `[]`,
+		},
+		{
+			Seq: func(yield func(any) bool) {
+				_ = yield("a") && yield(iter.Seq[any](numbers)) && yield([]any{true, nil})
+			},
+			Compact: true,
+			Want:    `["a",[1,2,3],[true,null]]`,
+		},
+	}
+	for _, c := range tests {
+		var (
+			str strings.Builder
+			ws  = NewWriter(&str)
+		)
+		ws.Compact = c.Compact
+		if err := ws.WriteArrayFrom(c.Seq); err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if got := str.String(); got != c.Want {
+			t.Errorf("result mismatched! want %q, got %q", c.Want, got)
+		}
+	}
+}
+
+func TestWriterObjectFrom(t *testing.T) {
+	fields := func(yield func(string, any) bool) {
+		_ = yield("z", "last") && yield("a", float64(1)) && yield("items", iter.Seq[any](func(yield func(any) bool) {
+			_ = yield(true) && yield(false)
+		}))
+	}
+	tests := []struct {
+		Seq     iter.Seq2[string, any]
+		Compact bool
+		Want    string
+	}{
+		{
+			Seq:     fields,
+			Compact: true,
+			Want:    `{"z":"last","a":1,"items":[true,false]}`,
+		},
+		{
+			Seq:  fields,
+			Want: "{\n  \"z\": \"last\",\n  \"a\": 1,\n  \"items\": [\n    true,\n    false\n  ]\n}",
+		},
+		{
+			Seq:     func(func(string, any) bool) {},
+			Compact: true,
+			Want:    `{}`,
+		},
+	}
+	for _, c := range tests {
+		var (
+			str strings.Builder
+			ws  = NewWriter(&str)
+		)
+		ws.Compact = c.Compact
+		if err := ws.WriteObjectFrom(c.Seq); err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if got := str.String(); got != c.Want {
+			t.Errorf("result mismatched! want %q, got %q", c.Want, got)
+		}
+	}
+}
+
+func TestWriterFromError(t *testing.T) {
+	var produced int
+	seq := func(yield func(any) bool) {
+		for _, v := range []any{"ok", make(chan int), "never"} {
+			produced++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	var str strings.Builder
+	if err := Compact(&str).WriteArrayFrom(seq); err == nil {
+		t.Errorf("unsupported value should be rejected")
+	}
+	if produced != 2 {
+		t.Errorf("sequence should stop at first error! got %d values produced", produced)
+	}
+	fields := func(yield func(string, any) bool) {
+		_ = yield("ok", true) && yield("bad", func() {})
+	}
+	if err := Compact(&str).WriteObjectFrom(fields); err == nil {
+		t.Errorf("unsupported value should be rejected")
+	}
+}
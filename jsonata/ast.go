@@ -3,9 +3,12 @@ package jsonata
 import (
 	"cmp"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"strconv"
 
+	"github.com/midbel/codecs/environ"
 	"github.com/midbel/codecs/internal/jsonkit"
 )
 
@@ -14,7 +17,7 @@ type Query interface {
 }
 
 type Expr interface {
-	Eval(any) (any, error)
+	Eval(any, environ.Environ[any]) (any, error)
 }
 
 type query struct {
@@ -22,7 +25,7 @@ type query struct {
 }
 
 func (q query) Get(doc any) (any, error) {
-	a, err := q.expr.Eval(doc)
+	a, err := q.expr.Eval(doc, environ.Empty[any]())
 	return a, err
 }
 
@@ -31,14 +34,17 @@ type call struct {
 	args  []Expr
 }
 
-func (c call) Eval(doc any) (any, error) {
+func (c call) Eval(doc any, env environ.Environ[any]) (any, error) {
 	fn, ok := builtins[c.ident]
 	if !ok || fn == nil {
-		return nil, fmt.Errorf("%s function unknown")
+		fn, ok = c.resolve(env)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s function unknown", c.ident)
 	}
 	var arr []any
 	for i := range c.args {
-		a, err := c.args[i].Eval(doc)
+		a, err := c.args[i].Eval(doc, env)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", c.ident, err)
 		}
@@ -51,11 +57,65 @@ func (c call) Eval(doc any) (any, error) {
 	return ret, nil
 }
 
+func (c call) resolve(env environ.Environ[any]) (builtinFunc, bool) {
+	v, err := env.Resolve(c.ident)
+	if err != nil {
+		return nil, false
+	}
+	fn, ok := v.(callable)
+	if !ok {
+		return nil, false
+	}
+	return fn.call, true
+}
+
+func (c call) higherOrder() bool {
+	return slices.Contains(higherOrderFuncs, c.ident)
+}
+
+func isHigherOrder(expr Expr) bool {
+	switch e := expr.(type) {
+	case call:
+		return e.higherOrder()
+	case path:
+		return isHigherOrder(e.expr)
+	default:
+		return false
+	}
+}
+
+type callable interface {
+	call(any, []any) (any, error)
+}
+
+type lambda struct {
+	params []string
+	body   Expr
+	env    environ.Environ[any]
+}
+
+func (f lambda) Eval(_ any, env environ.Environ[any]) (any, error) {
+	f.env = env
+	return f, nil
+}
+
+func (f lambda) call(ctx any, args []any) (any, error) {
+	env := environ.Enclosed(f.env)
+	for i := range f.params {
+		var arg any
+		if i < len(args) {
+			arg = args[i]
+		}
+		env.Define(f.params[i], arg)
+	}
+	return f.body.Eval(ctx, env)
+}
+
 type literal[T string | float64 | bool] struct {
 	value T
 }
 
-func (i literal[T]) Eval(_ any) (any, error) {
+func (i literal[T]) Eval(_ any, _ environ.Environ[any]) (any, error) {
 	return i.value, nil
 }
 
@@ -63,7 +123,7 @@ type identifier struct {
 	ident string
 }
 
-func (i identifier) Eval(doc any) (any, error) {
+func (i identifier) Eval(doc any, env environ.Environ[any]) (any, error) {
 	switch doc := doc.(type) {
 	case map[string]any:
 		a, ok := doc[i.ident]
@@ -74,7 +134,7 @@ func (i identifier) Eval(doc any) (any, error) {
 	case []any:
 		var arr []any
 		for j := range doc {
-			a, err := i.Eval(doc[j])
+			a, err := i.Eval(doc[j], env)
 			if err != nil {
 				continue
 			}
@@ -96,12 +156,44 @@ func (i identifier) Eval(doc any) (any, error) {
 	}
 }
 
+type variable struct {
+	ident string
+}
+
+func (v variable) Eval(_ any, env environ.Environ[any]) (any, error) {
+	a, err := env.Resolve(v.ident)
+	if err != nil {
+		return nil, errUndefined
+	}
+	return a, nil
+}
+
+type binding struct {
+	expr  Expr
+	ident string
+	index bool
+}
+
+func (b binding) Eval(doc any, env environ.Environ[any]) (any, error) {
+	return b.expr.Eval(doc, env)
+}
+
+func (b binding) bind(env environ.Environ[any], pos int, value any) environ.Environ[any] {
+	sub := environ.Enclosed(env)
+	if b.index {
+		sub.Define(b.ident, float64(pos))
+	} else {
+		sub.Define(b.ident, value)
+	}
+	return sub
+}
+
 type reverse struct {
 	expr Expr
 }
 
-func (r reverse) Eval(doc any) (any, error) {
-	v, err := r.expr.Eval(doc)
+func (r reverse) Eval(doc any, env environ.Environ[any]) (any, error) {
+	v, err := r.expr.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
@@ -121,15 +213,15 @@ type ternary struct {
 	alt Expr
 }
 
-func (t ternary) Eval(doc any) (any, error) {
-	ret, err := t.cdt.Eval(doc)
+func (t ternary) Eval(doc any, env environ.Environ[any]) (any, error) {
+	ret, err := t.cdt.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
 	if toBool(ret) {
-		return t.csq.Eval(doc)
+		return t.csq.Eval(doc, env)
 	}
-	return t.alt.Eval(doc)
+	return t.alt.Eval(doc, env)
 }
 
 type binary struct {
@@ -138,12 +230,12 @@ type binary struct {
 	op    rune
 }
 
-func (i binary) Eval(doc any) (any, error) {
-	left, err := i.left.Eval(doc)
+func (i binary) Eval(doc any, env environ.Environ[any]) (any, error) {
+	left, err := i.left.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
-	right, err := i.right.Eval(doc)
+	right, err := i.right.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
@@ -215,8 +307,8 @@ type arrayTransform struct {
 	expr Expr
 }
 
-func (a arrayTransform) Eval(doc any) (any, error) {
-	res, err := a.expr.Eval(doc)
+func (a arrayTransform) Eval(doc any, env environ.Environ[any]) (any, error) {
+	res, err := a.expr.Eval(doc, env)
 	if arr, ok := res.([]any); ok {
 		return arr, err
 	}
@@ -227,21 +319,21 @@ type arrayBuilder struct {
 	expr []Expr
 }
 
-func (b arrayBuilder) Eval(doc any) (any, error) {
-	return b.eval(doc)
+func (b arrayBuilder) Eval(doc any, env environ.Environ[any]) (any, error) {
+	return b.eval(doc, env)
 }
 
-func (b arrayBuilder) eval(doc any) (any, error) {
+func (b arrayBuilder) eval(doc any, env environ.Environ[any]) (any, error) {
 	if arr, ok := doc.([]any); ok {
-		return b.evalArray(arr)
+		return b.evalArray(arr, env)
 	}
-	return b.evalObject(doc)
+	return b.evalObject(doc, env)
 }
 
-func (b arrayBuilder) evalObject(doc any) (any, error) {
+func (b arrayBuilder) evalObject(doc any, env environ.Environ[any]) (any, error) {
 	var arr []any
 	for i := range b.expr {
-		a, err := b.expr[i].Eval(doc)
+		a, err := b.expr[i].Eval(doc, env)
 		if err != nil {
 			continue
 		}
@@ -254,10 +346,10 @@ func (b arrayBuilder) evalObject(doc any) (any, error) {
 	return arr, nil
 }
 
-func (b arrayBuilder) evalArray(doc []any) (any, error) {
+func (b arrayBuilder) evalArray(doc []any, env environ.Environ[any]) (any, error) {
 	var arr []any
 	for i := range doc {
-		a, err := b.eval(doc[i])
+		a, err := b.eval(doc[i], env)
 		if err != nil {
 			return nil, err
 		}
@@ -271,18 +363,18 @@ type objectBuilder struct {
 	list map[Expr]Expr
 }
 
-func (b objectBuilder) Eval(doc any) (any, error) {
+func (b objectBuilder) Eval(doc any, env environ.Environ[any]) (any, error) {
 	if b.expr == nil {
-		return b.evalDefault(doc)
+		return b.evalDefault(doc, env)
 	}
-	return b.evalContext(doc)
+	return b.evalContext(doc, env)
 }
 
-func (b objectBuilder) evalDefault(doc any) (any, error) {
+func (b objectBuilder) evalDefault(doc any, env environ.Environ[any]) (any, error) {
 	if doc, ok := doc.([]any); ok {
 		var arr []any
 		for i := range doc {
-			a, err := b.buildFromObject(doc[i])
+			a, err := b.buildFromObject(doc[i], env)
 			if err != nil {
 				return nil, err
 			}
@@ -290,25 +382,25 @@ func (b objectBuilder) evalDefault(doc any) (any, error) {
 		}
 		return arr, nil
 	}
-	return b.buildFromObject(doc)
+	return b.buildFromObject(doc, env)
 }
 
-func (b objectBuilder) evalContext(doc any) (any, error) {
-	doc, err := b.getContext(doc)
+func (b objectBuilder) evalContext(doc any, env environ.Environ[any]) (any, error) {
+	doc, err := b.getContext(doc, env)
 	if err != nil {
 		return nil, err
 	}
 	if arr, ok := doc.([]any); ok {
-		return b.buildFromArray(arr)
+		return b.buildFromArray(arr, env)
 	}
-	return b.buildFromObject(doc)
+	return b.buildFromObject(doc, env)
 }
 
-func (b objectBuilder) buildFromArray(doc []any) (any, error) {
+func (b objectBuilder) buildFromArray(doc []any, env environ.Environ[any]) (any, error) {
 	obj := make(map[string]any)
 	for i := range doc {
 		for k, v := range b.list {
-			key, err := k.Eval(doc[i])
+			key, err := k.Eval(doc[i], env)
 			if err != nil {
 				return nil, err
 			}
@@ -316,7 +408,7 @@ func (b objectBuilder) buildFromArray(doc []any) (any, error) {
 			if !ok {
 				return nil, errType
 			}
-			val, _ := v.Eval(doc[i])
+			val, _ := v.Eval(doc[i], env)
 			if v, ok := obj[str]; ok {
 				if arr, ok := v.([]any); ok {
 					val = append(arr, val)
@@ -330,10 +422,10 @@ func (b objectBuilder) buildFromArray(doc []any) (any, error) {
 	return obj, nil
 }
 
-func (b objectBuilder) buildFromObject(doc any) (any, error) {
+func (b objectBuilder) buildFromObject(doc any, env environ.Environ[any]) (any, error) {
 	obj := make(map[string]any)
 	for k, v := range b.list {
-		key, err := k.Eval(doc)
+		key, err := k.Eval(doc, env)
 		if err != nil {
 			return nil, err
 		}
@@ -341,7 +433,7 @@ func (b objectBuilder) buildFromObject(doc any) (any, error) {
 		if !ok {
 			return nil, errType
 		}
-		val, _ := v.Eval(doc)
+		val, _ := v.Eval(doc, env)
 		if v, ok := obj[str]; ok {
 			if arr, ok := v.([]any); ok {
 				val = append(arr, val)
@@ -354,11 +446,11 @@ func (b objectBuilder) buildFromObject(doc any) (any, error) {
 	return obj, nil
 }
 
-func (b objectBuilder) getContext(doc any) (any, error) {
+func (b objectBuilder) getContext(doc any, env environ.Environ[any]) (any, error) {
 	if b.expr == nil {
 		return doc, nil
 	}
-	return b.expr.Eval(doc)
+	return b.expr.Eval(doc, env)
 }
 
 type path struct {
@@ -366,27 +458,45 @@ type path struct {
 	next Expr
 }
 
-func (p path) Eval(doc any) (any, error) {
-	return p.eval(doc)
+func (p path) Eval(doc any, env environ.Environ[any]) (any, error) {
+	res, err := p.eval(doc, env)
+	if err != nil || p.keepArray() {
+		return res, err
+	}
+	if arr, ok := res.([]any); ok && len(arr) == 1 {
+		return arr[0], nil
+	}
+	return res, nil
+}
+
+func (p path) keepArray() bool {
+	return keepArray(p.expr) || keepArray(p.next)
 }
 
-func (p path) eval(doc any) (any, error) {
+func (p path) eval(doc any, env environ.Environ[any]) (any, error) {
+	if isHigherOrder(p.expr) {
+		ret, err := p.expr.Eval(doc, env)
+		if err != nil {
+			return nil, err
+		}
+		return p.getNext(ret, env)
+	}
 	var err error
 	switch v := doc.(type) {
 	case map[string]any:
-		doc, err = p.getObject(v)
+		doc, err = p.getObject(v, env)
 	case []any:
-		doc, err = p.getArray(v)
+		doc, err = p.getArray(v, env)
 	default:
-		return nil, fmt.Errorf("%s: %w can not be queried (%T)", errType, doc)
+		return nil, fmt.Errorf("%w: %v can not be queried (%T)", errType, doc, doc)
 	}
 	return doc, err
 }
 
-func (p path) getArray(value []any) (any, error) {
+func (p path) getArray(value []any, env environ.Environ[any]) (any, error) {
 	var arr []any
 	for i := range value {
-		a, err := p.eval(value[i])
+		a, err := p.eval(value[i], env)
 		if err != nil {
 			continue
 		}
@@ -397,51 +507,132 @@ func (p path) getArray(value []any) (any, error) {
 	return arr, nil
 }
 
-func (p path) getObject(value map[string]any) (any, error) {
-	ret, err := p.expr.Eval(value)
+func (p path) getObject(value map[string]any, env environ.Environ[any]) (any, error) {
+	ret, err := p.expr.Eval(value, env)
 	if err != nil {
 		return nil, err
 	}
-	return p.getNext(ret)
+	if b, ok := p.expr.(binding); ok && !b.index {
+		return p.getJoin(b, value, ret, env)
+	}
+	return p.getNext(ret, env)
 
 }
 
-func (p path) getNext(doc any) (any, error) {
+func (p path) getJoin(b binding, ctx, doc any, env environ.Environ[any]) (any, error) {
+	arr, ok := doc.([]any)
+	if !ok {
+		arr = []any{doc}
+	}
+	var ret []any
+	for i := range arr {
+		if p.next == nil {
+			ret = append(ret, ctx)
+			continue
+		}
+		a, err := p.next.Eval(ctx, b.bind(env, i, arr[i]))
+		if err != nil {
+			continue
+		}
+		if as, ok := a.([]any); ok {
+			ret = append(ret, as...)
+		} else {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
+
+func (p path) getNext(doc any, env environ.Environ[any]) (any, error) {
 	if p.next == nil {
 		return doc, nil
 	}
+	if isHigherOrder(p.next) {
+		return p.next.Eval(doc, env)
+	}
+	b, bound := p.expr.(binding)
 	arr, ok := doc.([]any)
 	if !ok {
-		return p.next.Eval(doc)
+		if bound {
+			env = b.bind(env, 0, doc)
+		}
+		return p.next.Eval(doc, env)
 	}
+	_, nested := p.next.(arrayBuilder)
+
 	var ret []any
 	for i := range arr {
-		a, err := p.next.Eval(arr[i])
-		if err != nil {
-			return nil, err
+		sub := env
+		if bound {
+			sub = b.bind(env, i, arr[i])
 		}
-		ret = append(ret, a)
+		a, err := p.next.Eval(arr[i], sub)
+		if err != nil || a == nil {
+			continue
+		}
+		if nested {
+			ret = append(ret, a)
+		} else {
+			ret = appendFlat(ret, a)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, errUndefined
 	}
 	return ret, nil
 }
 
-type wildcard struct{}
+func keepArray(expr Expr) bool {
+	switch e := expr.(type) {
+	case arrayTransform:
+		return true
+	case path:
+		return e.keepArray()
+	case filter:
+		return keepArray(e.expr)
+	case binding:
+		return keepArray(e.expr)
+	default:
+		return false
+	}
+}
 
-func (w wildcard) Eval(doc any) (any, error) {
-	obj, ok := doc.(map[string]any)
-	if !ok {
-		return nil, errType
+func appendFlat(arr []any, value any) []any {
+	if as, ok := value.([]any); ok {
+		return append(arr, as...)
 	}
+	return append(arr, value)
+}
+
+type wildcard struct{}
+
+func (w wildcard) Eval(doc any, env environ.Environ[any]) (any, error) {
 	var arr []any
-	for k := range obj {
-		arr = append(arr, obj[k])
+	switch doc := doc.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(doc)) {
+			arr = appendFlat(arr, doc[k])
+		}
+	case []any:
+		for i := range doc {
+			a, err := w.Eval(doc[i], env)
+			if err != nil {
+				continue
+			}
+			arr = appendFlat(arr, a)
+		}
+	default:
+		return nil, errUndefined
+	}
+	if len(arr) == 0 {
+		return nil, errUndefined
 	}
 	return arr, nil
 }
 
 type descent struct{}
 
-func (d descent) Eval(doc any) (any, error) {
+func (d descent) Eval(doc any, env environ.Environ[any]) (any, error) {
 	return nil, nil
 }
 
@@ -450,12 +641,12 @@ type transform struct {
 	next Expr
 }
 
-func (t transform) Eval(doc any) (any, error) {
-	doc, err := t.expr.Eval(doc)
+func (t transform) Eval(doc any, env environ.Environ[any]) (any, error) {
+	doc, err := t.expr.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
-	return t.next.Eval(doc)
+	return t.next.Eval(doc, env)
 }
 
 type filter struct {
@@ -463,11 +654,11 @@ type filter struct {
 	check Expr
 }
 
-func (i filter) Eval(doc any) (any, error) {
+func (i filter) Eval(doc any, env environ.Environ[any]) (any, error) {
 	if doc, ok := doc.([]any); ok {
 		var arr []any
 		for j := range doc {
-			a, err := i.eval(doc[j])
+			a, err := i.eval(doc[j], env)
 			if err != nil {
 				continue
 			}
@@ -475,73 +666,118 @@ func (i filter) Eval(doc any) (any, error) {
 		}
 		return arr, nil
 	}
-	return i.eval(doc)
+	return i.eval(doc, env)
 }
 
-func (i filter) eval(doc any) (any, error) {
-	doc, err := i.expr.Eval(doc)
+func (i filter) eval(doc any, env environ.Environ[any]) (any, error) {
+	doc, err := i.expr.Eval(doc, env)
 	if err != nil {
 		return nil, err
 	}
-	switch doc := doc.(type) {
-	case map[string]any:
-		ok, err := i.check.Eval(doc)
+	list, ok := doc.([]any)
+	if !ok {
+		list = []any{doc}
+	}
+	var (
+		arr      []any
+		b, bound = i.expr.(binding)
+	)
+	for j := range list {
+		sub := env
+		if bound {
+			sub = b.bind(env, j, list[j])
+		}
+		res, err := i.check.Eval(list[j], sub)
 		if err != nil {
-			return nil, err
+			continue
 		}
-		if !toBool(ok) {
-			return nil, errDiscard
+		if selectItem(res, j, len(list)) {
+			arr = append(arr, list[j])
 		}
-		return doc, nil
+	}
+	if len(arr) == 0 {
+		return nil, errUndefined
+	}
+	if len(arr) == 1 {
+		return arr[0], nil
+	}
+	return arr, nil
+}
+
+func selectItem(res any, pos, size int) bool {
+	switch res := res.(type) {
+	case float64:
+		return isIndex(res, pos, size)
 	case []any:
-		var arr []any
-		for j := range doc {
-			res, err := i.check.Eval(doc[j])
-			if err != nil {
-				continue
+		if len(res) == 0 {
+			return false
+		}
+		var numeric bool
+		for i := range res {
+			n, ok := res[i].(float64)
+			if !ok {
+				break
 			}
-			if n, ok := res.(float64); ok {
-				ix := int(n)
-				if ix < 0 {
-					ix += len(doc)
-				}
-				if ix == j {
-					arr = append(arr, doc[j])
-				}
-			} else if b, ok := res.(bool); ok && b {
-				arr = append(arr, doc[j])
+			numeric = true
+			if isIndex(n, pos, size) {
+				return true
 			}
 		}
-		if len(arr) == 0 {
-			return nil, errUndefined
-		}
-		if len(arr) == 1 {
-			return arr[0], nil
-		}
-		return arr, nil
-	case string, float64, bool:
-		res, err := i.check.Eval(doc)
-		if err != nil {
-			return nil, err
-		}
-		ix, err := toFloat(res)
-		if err != nil {
-			return nil, err
-		}
-		if ix == 0 {
-			return doc, nil
-		}
-		return nil, errDiscard
+		return !numeric
 	default:
-		return nil, errType
+		return toBool(res)
+	}
+}
+
+func isIndex(n float64, pos, size int) bool {
+	ix := int(math.Floor(n))
+	if ix < 0 {
+		ix += size
+	}
+	return ix == pos
+}
+
+type rangeExpr struct {
+	from Expr
+	to   Expr
+}
+
+func (r rangeExpr) Eval(doc any, env environ.Environ[any]) (any, error) {
+	from, err := r.bound(r.from, doc, env)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.bound(r.to, doc, env)
+	if err != nil {
+		return nil, err
+	}
+	var arr []any
+	for i := from; i <= to; i++ {
+		arr = append(arr, float64(i))
+	}
+	return arr, nil
+}
+
+func (r rangeExpr) bound(expr Expr, doc any, env environ.Environ[any]) (int, error) {
+	v, err := expr.Eval(doc, env)
+	if err != nil {
+		return 0, err
+	}
+	if arr, ok := v.([]any); ok && len(arr) == 1 {
+		v = arr[0]
+	}
+	n, ok := v.(float64)
+	if !ok || n != math.Trunc(n) {
+		return 0, fmt.Errorf("%w: range bounds must be integers", errType)
 	}
+	return int(n), nil
 }
 
 type orderby struct {
 	list Expr
 }
 
-func (o orderby) Eval(doc any) (any, error) {
+func (o orderby) Eval(doc any, env environ.Environ[any]) (any, error) {
 	return nil, nil
 }
 
@@ -3,6 +3,7 @@ package jsonata
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand/v2"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/midbel/codecs/internal/datefmt"
+	"github.com/midbel/codecs/internal/numfmt"
 	"github.com/midbel/codecs/json"
 )
 
@@ -37,15 +40,15 @@ var builtins = map[string]builtinFunc{
 	"power":           checkArity(numberPower, 2),
 	"sqrt":            checkArity(numberSqrt, 1),
 	"random":          checkArity(numberRandom, 0),
-	"formatNumber":    checkArity(numberFormatNumber, 1),
+	"formatNumber":    checkArity(numberFormatNumber, 2, nil),
 	"formatInteger":   checkArity(numberFormatInteger, 1),
 	"formatBase":      checkArity(numberFormatBase, 1),
 	"parseInt":        checkArity(numberParseInt, 1),
 	"parseFloat":      checkArity(numberParseFloat, 1),
-	"sum":             nil,
-	"min":             nil,
-	"max":             nil,
-	"average":         nil,
+	"sum":             checkArity(numberSum, 1),
+	"min":             checkArity(numberMin, 1),
+	"max":             checkArity(numberMax, 1),
+	"average":         checkArity(numberAverage, 1),
 	"boolean":         checkArity(boolBoolean, 1),
 	"not":             checkArity(boolNot, 1),
 	"count":           checkArity(arrayCount, 1),
@@ -56,15 +59,20 @@ var builtins = map[string]builtinFunc{
 	"distinct":        checkArity(arrayDistinct, 1),
 	"zip":             checkArity(arrayZip, 2),
 	"join":            checkArity(arrayJoin, 1, ""),
+	"map":             checkFunc(funcMap, 2, 2),
+	"filter":          checkFunc(funcFilter, 2, 2),
+	"reduce":          checkFunc(funcReduce, 2, 3),
+	"single":          checkFunc(funcSingle, 1, 2),
 	"keys":            checkArity(objectKeys, 1),
 	"lookup":          checkArity(objectLookup, 2),
 	"merge":           checkArity(objectMerge, 1),
 	"type":            checkArity(objectType, 1),
 	"values":          checkArity(objectValues, 1),
-	"now":             checkArity(timeNow, 1),
+	"now":             checkArity(timeNow, 0, "", ""),
 	"millis":          checkArity(timeMillis, 0),
-	"fromMillis":      checkArity(timeFromMillis, 1),
-	"toMillis":        checkArity(timeToMillis, 1),
+	"fromMillis":      checkArity(timeFromMillis, 1, "", ""),
+	"toMillis":        checkArity(timeToMillis, 1, ""),
+	"parseTime":       checkArity(timeParseTime, 2, ""),
 }
 
 func typeError(arg string) error {
@@ -96,6 +104,148 @@ func checkArity(fn builtinFunc, minArgsCount int, defaults ...any) builtinFunc {
 	return do
 }
 
+var higherOrderFuncs = []string{
+	"map",
+	"filter",
+	"reduce",
+	"single",
+}
+
+func checkFunc(fn builtinFunc, minArgsCount, maxArgsCount int) builtinFunc {
+	do := func(ctx any, args []any) (any, error) {
+		if len(args) < minArgsCount || (len(args) > 0 && isCallable(args[0])) {
+			args = append([]any{ctx}, args...)
+		}
+		if len(args) < minArgsCount || len(args) > maxArgsCount {
+			return nil, errArgument
+		}
+		if len(args) < maxArgsCount {
+			tmp := make([]any, maxArgsCount)
+			copy(tmp, args)
+			args = tmp
+		}
+		return fn(ctx, args)
+	}
+	return do
+}
+
+func isCallable(v any) bool {
+	_, ok := v.(callable)
+	return ok
+}
+
+func toArray(v any) []any {
+	if v == nil {
+		return nil
+	}
+	if arr, ok := v.([]any); ok {
+		return arr
+	}
+	return []any{v}
+}
+
+func getCallable(v any) (callable, error) {
+	fn, ok := v.(callable)
+	if !ok {
+		return nil, typeError("function")
+	}
+	return fn, nil
+}
+
+func funcMap(ctx any, args []any) (any, error) {
+	fn, err := getCallable(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list = toArray(args[0])
+		arr  []any
+	)
+	for i := range list {
+		a, err := fn.call(list[i], []any{list[i], float64(i), list})
+		if err != nil {
+			if errors.Is(err, errUndefined) {
+				continue
+			}
+			return nil, err
+		}
+		arr = append(arr, a)
+	}
+	return arr, nil
+}
+
+func funcFilter(ctx any, args []any) (any, error) {
+	fn, err := getCallable(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list = toArray(args[0])
+		arr  []any
+	)
+	for i := range list {
+		a, err := fn.call(list[i], []any{list[i], float64(i), list})
+		if err != nil && !errors.Is(err, errUndefined) {
+			return nil, err
+		}
+		if toBool(a) {
+			arr = append(arr, list[i])
+		}
+	}
+	return arr, nil
+}
+
+func funcReduce(ctx any, args []any) (any, error) {
+	fn, err := getCallable(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list = toArray(args[0])
+		acc  = args[2]
+	)
+	if acc == nil {
+		if len(list) == 0 {
+			return nil, errUndefined
+		}
+		acc, list = list[0], list[1:]
+	}
+	for i := range list {
+		acc, err = fn.call(list[i], []any{acc, list[i]})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+func funcSingle(ctx any, args []any) (any, error) {
+	var (
+		list  = toArray(args[0])
+		found []any
+	)
+	for i := range list {
+		if args[1] != nil {
+			fn, err := getCallable(args[1])
+			if err != nil {
+				return nil, err
+			}
+			a, err := fn.call(list[i], []any{list[i], float64(i), list})
+			if err != nil && !errors.Is(err, errUndefined) {
+				return nil, err
+			}
+			if !toBool(a) {
+				continue
+			}
+		}
+		found = append(found, list[i])
+	}
+	if len(found) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one matching value, got %d", errArgument, len(found))
+	}
+	return found[0], nil
+}
+
 func strString(ctx any, args []any) (any, error) {
 	if args[0] == nil {
 		return "null", nil
@@ -391,7 +541,31 @@ func numberRandom(ctx any, args []any) (any, error) {
 }
 
 func numberFormatNumber(ctx any, args []any) (any, error) {
-	return nil, nil
+	f, ok := args[0].(float64)
+	if !ok {
+		return nil, typeError("number")
+	}
+	picture, ok := args[1].(string)
+	if !ok {
+		return nil, typeError("picture")
+	}
+	format := numfmt.Default()
+	if args[2] != nil {
+		options, ok := args[2].(map[string]any)
+		if !ok {
+			return nil, typeError("options")
+		}
+		for k, v := range options {
+			str, ok := v.(string)
+			if !ok {
+				return nil, typeError(k)
+			}
+			if err := format.Set(k, str); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return format.Format(f, picture)
 }
 
 func numberFormatBase(ctx any, args []any) (any, error) {
@@ -448,6 +622,58 @@ func numberParseFloat(ctx any, args []any) (any, error) {
 	}
 }
 
+func getNumbers(v any) ([]float64, error) {
+	var list []float64
+	for _, a := range toArray(v) {
+		f, ok := a.(float64)
+		if !ok {
+			return nil, typeError("number")
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+func numberSum(ctx any, args []any) (any, error) {
+	list, err := getNumbers(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var sum float64
+	for _, f := range list {
+		sum += f
+	}
+	return sum, nil
+}
+
+func numberMin(ctx any, args []any) (any, error) {
+	list, err := getNumbers(args[0])
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return slices.Min(list), nil
+}
+
+func numberMax(ctx any, args []any) (any, error) {
+	list, err := getNumbers(args[0])
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return slices.Max(list), nil
+}
+
+func numberAverage(ctx any, args []any) (any, error) {
+	list, err := getNumbers(args[0])
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	var sum float64
+	for _, f := range list {
+		sum += f
+	}
+	return sum / float64(len(list)), nil
+}
+
 func arrayCount(ctx any, args []any) (any, error) {
 	switch a := args[0].(type) {
 	case string, float64, bool, map[string]any:
@@ -687,8 +913,16 @@ func objectType(ctx any, args []any) (any, error) {
 	}
 }
 
+const isoTimestamp = "2006-01-02T15:04:05.000Z07:00"
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
 func timeNow(ctx any, args []any) (any, error) {
-	return time.Now().Format(time.RFC3339), nil
+	return formatTimestamp(time.Now(), args[0], args[1])
 }
 
 func timeMillis(ctx any, args []any) (any, error) {
@@ -701,18 +935,73 @@ func timeFromMillis(ctx any, args []any) (any, error) {
 	if !ok {
 		return nil, typeError("timestamp")
 	}
-	when := time.UnixMilli(int64(millis))
-	return when.Format(time.RFC3339), nil
+	return formatTimestamp(time.UnixMilli(int64(millis)), args[1], args[2])
 }
 
 func timeToMillis(ctx any, args []any) (any, error) {
-	str, ok := args[0].(string)
+	when, err := parseTimestamp(args[0], args[1], "")
+	if err != nil {
+		return nil, err
+	}
+	return float64(when.UnixMilli()), nil
+}
+
+func timeParseTime(ctx any, args []any) (any, error) {
+	when, err := parseTimestamp(args[0], args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+	return when.Format(isoTimestamp), nil
+}
+
+func formatTimestamp(when time.Time, picture, timezone any) (any, error) {
+	pic, ok := picture.(string)
 	if !ok {
-		return nil, typeError("timestamp")
+		return nil, typeError("picture")
 	}
-	when, err := time.Parse(time.RFC3339, str)
+	loc, err := getTimezone(timezone)
 	if err != nil {
 		return nil, err
 	}
-	return float64(when.UnixMilli()), nil
+	when = when.In(loc)
+	if pic == "" {
+		return when.Format(isoTimestamp), nil
+	}
+	return datefmt.Format(when, pic, datefmt.DateTimeComponents)
+}
+
+func parseTimestamp(value, picture, timezone any) (time.Time, error) {
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}, typeError("timestamp")
+	}
+	pic, ok := picture.(string)
+	if !ok {
+		return time.Time{}, typeError("picture")
+	}
+	loc, err := getTimezone(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if pic != "" {
+		return datefmt.Parse(str, pic, loc)
+	}
+	for _, layout := range timestampLayouts {
+		when, err := time.ParseInLocation(layout, str, loc)
+		if err == nil {
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid timestamp", str)
+}
+
+func getTimezone(timezone any) (*time.Location, error) {
+	str, ok := timezone.(string)
+	if !ok {
+		return nil, typeError("timezone")
+	}
+	if str == "" {
+		return time.UTC, nil
+	}
+	return datefmt.ParseZone(str)
 }
@@ -0,0 +1,26 @@
+package jsonata
+
+import (
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzCompile(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"Account.Order.Product.Price",
+		"$sum(Account.Order.Product.(Price * Quantity))",
+		"Account.Order[0].Product[ProductID = 858383].SKU",
+		"$map([1, 2, 3], function($v) { $v * 2 })",
+		"{ 'name': name, 'total': $count(items) }",
+		"$x := 10; $x > 5 ? 'big' : 'small'",
+		"phone[type='mobile'].number & ' (' & name & ')'",
+		"[1..5]",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			_, err := Compile(string(data))
+			return err
+		})
+	})
+}
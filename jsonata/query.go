@@ -33,6 +33,7 @@ func Find(r io.Reader, q string) (any, error) {
 const (
 	powLowest = iota
 	powComma
+	powRange
 	powTernary
 	powOr
 	powAnd
@@ -45,6 +46,7 @@ const (
 	powGrp
 	powMap
 	powFilter
+	powBind
 	powTransform
 )
 
@@ -72,6 +74,9 @@ var bindings = map[rune]int{
 	jsonkit.Concat:    powAdd,
 	jsonkit.Map:       powMap,
 	jsonkit.Transform: powTransform,
+	jsonkit.Index:     powBind,
+	jsonkit.Bind:      powBind,
+	jsonkit.Range:     powRange,
 }
 
 type compiler struct {
@@ -89,7 +94,7 @@ func Compile(query string) (Query, error) {
 	}
 	cp.prefix = map[rune]func() (Expr, error){
 		jsonkit.Ident:    cp.compileIdent,
-		jsonkit.Func:     cp.compileIdent,
+		jsonkit.Func:     cp.compileVariable,
 		jsonkit.Number:   cp.compileNumber,
 		jsonkit.String:   cp.compileString,
 		jsonkit.Boolean:  cp.compileBool,
@@ -125,6 +130,9 @@ func Compile(query string) (Query, error) {
 		jsonkit.Map:       cp.compileMap,
 		jsonkit.Ternary:   cp.compileTernary,
 		jsonkit.Transform: cp.compileTransform,
+		jsonkit.Index:     cp.compileBinding,
+		jsonkit.Bind:      cp.compileBinding,
+		jsonkit.Range:     cp.compileRange,
 	}
 
 	cp.next()
@@ -160,6 +168,19 @@ func (c *compiler) compileTransform(left Expr) (Expr, error) {
 	return expr, nil
 }
 
+func (c *compiler) compileBinding(left Expr) (Expr, error) {
+	b := binding{
+		expr:  left,
+		index: c.is(jsonkit.Index),
+	}
+	c.next()
+	if !c.is(jsonkit.Func) {
+		return nil, fmt.Errorf("syntax error: variable expected")
+	}
+	b.ident = c.getString()
+	return b, nil
+}
+
 func (c *compiler) compileMap(left Expr) (Expr, error) {
 	c.next()
 	q := path{
@@ -318,12 +339,66 @@ func (c *compiler) compileDescent() (Expr, error) {
 }
 
 func (c *compiler) compileIdent() (Expr, error) {
+	if (c.curr.Literal == "function" || c.curr.Literal == "fn") && c.peek.Type == jsonkit.BegGrp {
+		return c.compileLambda()
+	}
 	i := identifier{
 		ident: c.getString(),
 	}
 	return i, nil
 }
 
+func (c *compiler) compileLambda() (Expr, error) {
+	c.next()
+	c.next()
+	var fn lambda
+	for !c.done() && !c.is(jsonkit.EndGrp) {
+		if !c.is(jsonkit.Func) {
+			return nil, fmt.Errorf("syntax error: parameter expected")
+		}
+		fn.params = append(fn.params, c.getString())
+		switch {
+		case c.is(jsonkit.Comma):
+			c.next()
+			if c.is(jsonkit.EndGrp) {
+				return nil, fmt.Errorf("syntax error: trailing comma")
+			}
+		case c.is(jsonkit.EndGrp):
+		default:
+			return nil, fmt.Errorf("syntax error: unexpected token")
+		}
+	}
+	if !c.is(jsonkit.EndGrp) {
+		return nil, fmt.Errorf("syntax error: missing ')'")
+	}
+	c.next()
+	if !c.is(jsonkit.BegObj) {
+		return nil, fmt.Errorf("syntax error: missing '{'")
+	}
+	c.next()
+
+	body, err := c.compileExpr(powLowest)
+	if err != nil {
+		return nil, err
+	}
+	if !c.is(jsonkit.EndObj) {
+		return nil, fmt.Errorf("syntax error: missing '}'")
+	}
+	c.next()
+	fn.body = body
+	return fn, nil
+}
+
+func (c *compiler) compileVariable() (Expr, error) {
+	if c.peek.Type == jsonkit.BegGrp {
+		return c.compileIdent()
+	}
+	v := variable{
+		ident: c.getString(),
+	}
+	return v, nil
+}
+
 func (c *compiler) compileNumber() (Expr, error) {
 	i := literal[float64]{
 		value: c.getNumber(),
@@ -392,6 +467,19 @@ func (c *compiler) compileTernary(left Expr) (Expr, error) {
 	return t, nil
 }
 
+func (c *compiler) compileRange(left Expr) (Expr, error) {
+	c.next()
+	right, err := c.compileExpr(powRange)
+	if err != nil {
+		return nil, err
+	}
+	r := rangeExpr{
+		from: left,
+		to:   right,
+	}
+	return r, nil
+}
+
 func (c *compiler) compileBinary(left Expr) (Expr, error) {
 	if c.is(jsonkit.Wildcard) {
 		c.curr.Type = jsonkit.Mul
@@ -598,7 +686,7 @@ func (s *QueryScanner) scanNumber(tok *jsonkit.Token) {
 		s.read()
 	}
 	tok.Literal = s.str.String()
-	if s.char == '.' {
+	if s.char == '.' && s.peek() != '.' {
 		s.write()
 		s.read()
 		if !jsonkit.IsNumber(s.char) {
@@ -685,6 +773,10 @@ func (s *QueryScanner) scanOperator(tok *jsonkit.Token) {
 		}
 	case '&':
 		tok.Type = jsonkit.Concat
+	case '#':
+		tok.Type = jsonkit.Index
+	case '@':
+		tok.Type = jsonkit.Bind
 	default:
 		tok.Type = jsonkit.Invalid
 	}
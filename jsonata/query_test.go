@@ -0,0 +1,280 @@
+package jsonata
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/midbel/codecs/internal/datefmt"
+)
+
+func TestPathSelection(t *testing.T) {
+	const doc = `{
+		"obj": {"x": 1, "y": 2},
+		"items": [
+			{"name": "a", "values": [1, 2]},
+			{"name": "b", "values": [3]},
+			{"name": "c", "values": [4]}
+		],
+		"one": [{"name": "z"}],
+		"nums": [10, 20, 30, 40],
+		"str": "text"
+	}`
+
+	tests := []struct {
+		Query string
+		Want  string
+	}{
+		// wildcards: values of objects, mapped over arrays
+		{Query: "obj.*", Want: `[1,2]`},
+		{Query: "items.*", Want: `["a",1,2,"b",3,"c",4]`},
+		{Query: "items[0].*", Want: `["a",1,2]`},
+		// numeric index: positive, negative, rounded down
+		{Query: "nums[0]", Want: `10`},
+		{Query: "nums[-1]", Want: `40`},
+		{Query: "nums[-4]", Want: `10`},
+		{Query: "nums[1.7]", Want: `20`},
+		{Query: "items[-1].name", Want: `"c"`},
+		// singletons are treated as arrays of one item
+		{Query: "obj[0]", Want: `{"x":1,"y":2}`},
+		{Query: "obj[-1]", Want: `{"x":1,"y":2}`},
+		{Query: "str[0]", Want: `"text"`},
+		{Query: "str[-1]", Want: `"text"`},
+		// ranges
+		{Query: "[1..3]", Want: `[1,2,3]`},
+		{Query: "nums[1..2]", Want: `[20,30]`},
+		{Query: "nums[[0..1]]", Want: `[10,20]`},
+		{Query: "nums[-2..-1]", Want: `[30,40]`},
+		{Query: "nums[2..10]", Want: `[30,40]`},
+		// predicates applied per step
+		{Query: "items.values[0]", Want: `[1,3,4]`},
+		{Query: "items.values[-1]", Want: `[2,3,4]`},
+		{Query: "items.values", Want: `[1,2,3,4]`},
+		// singleton results and keep-array
+		{Query: "one.name", Want: `"z"`},
+		{Query: "one[].name", Want: `["z"]`},
+		{Query: "one.name[]", Want: `["z"]`},
+		{Query: "nums[0][]", Want: `[10]`},
+		{Query: "items[name = \"b\"].values", Want: `3`},
+		{Query: "items[name = \"b\"].values[]", Want: `[3]`},
+	}
+	for _, c := range tests {
+		res, err := Find(strings.NewReader(doc), c.Query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Query, err)
+			continue
+		}
+		got, err := json.Marshal(res)
+		if err != nil {
+			t.Errorf("%s: fail to marshal result: %s", c.Query, err)
+			continue
+		}
+		if string(got) != c.Want {
+			t.Errorf("%s: result mismatched! want %s, got %s", c.Query, c.Want, got)
+		}
+	}
+}
+
+func TestPathUndefined(t *testing.T) {
+	const doc = `{"obj": {"x": 1}, "nums": [10, 20]}`
+
+	tests := []string{
+		"nums[2]",
+		"nums[-3]",
+		"obj[1]",
+		"nums.*",
+		"nums[5..6]",
+	}
+	for _, q := range tests {
+		_, err := Find(strings.NewReader(doc), q)
+		if !errors.Is(err, errUndefined) {
+			t.Errorf("%s: expected undefined result, got %v", q, err)
+		}
+	}
+}
+
+type queryTest struct {
+	Query string
+	Want  string
+}
+
+type queryError struct {
+	Query string
+	Err   error
+}
+
+const itemsDoc = `{
+	"items": [
+		{"name": "a", "price": 10, "qty": 1},
+		{"name": "b", "price": 20, "qty": 3},
+		{"name": "c", "price": 5, "qty": 2}
+	],
+	"nums": [3, 1, 2],
+	"mixed": [1, "x", 2],
+	"empty": [],
+	"one": 4,
+	"ts": 1715000000000
+}`
+
+func TestPathBinding(t *testing.T) {
+	tests := []queryTest{
+		{Query: `items#$i.name`, Want: `["a","b","c"]`},
+		{Query: `items#$i.{"n": name, "i": $i}`, Want: `[{"i":0,"n":"a"},{"i":1,"n":"b"},{"i":2,"n":"c"}]`},
+		{Query: `items#$i[$i > 0].name`, Want: `["b","c"]`},
+		{Query: `items#$i[$i = 1].price`, Want: `20`},
+		{Query: `items@$it.($it.name & $it.qty)`, Want: `["a1","b3","c2"]`},
+		{Query: `items@$it.nums`, Want: `[3,1,2,3,1,2,3,1,2]`},
+		{Query: `items#$i.[[10, 20]#$i.$i, $i]`, Want: `[[0,1,0],[0,1,1],[0,1,2]]`},
+	}
+	runQueryTests(t, itemsDoc, tests)
+}
+
+func TestQueryConcurrent(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(itemsDoc), &doc); err != nil {
+		t.Fatalf("fail to decode document: %s", err)
+	}
+	q, err := Compile(`items#$i.{"n": name, "i": $i, "p": $map([1, 2], function($v){ $v * $i })}`)
+	if err != nil {
+		t.Fatalf("fail to compile query: %s", err)
+	}
+	want, err := q.Get(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected, _ := json.Marshal(want)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				res, err := q.Get(doc)
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+					return
+				}
+				if got, _ := json.Marshal(res); string(got) != string(expected) {
+					t.Errorf("result mismatched! want %s, got %s", expected, got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHigherOrderFunctions(t *testing.T) {
+	tests := []queryTest{
+		{Query: `items.map(function($i){ $i.price * 2 })`, Want: `[20,40,10]`},
+		{Query: `$map(items, function($i){ $i.price * 2 })`, Want: `[20,40,10]`},
+		{Query: `$map(items, fn($i){ $i.name })`, Want: `["a","b","c"]`},
+		{Query: `$map(nums, function($v, $i){ $v + $i })`, Want: `[3,2,4]`},
+		{Query: `$map(one, function($v){ $v * 2 })`, Want: `[8]`},
+		{Query: `$filter(items, function($i){ $i.price > 8 }).name`, Want: `["a","b"]`},
+		{Query: `$filter(nums, function($v, $i){ $i > 0 })`, Want: `[1,2]`},
+		{Query: `$reduce(nums, function($a, $b){ $a + $b })`, Want: `6`},
+		{Query: `$reduce(nums, function($a, $b){ $a + $b }, 10)`, Want: `16`},
+		{Query: `$single(items, function($i){ $i.name = "b" }).price`, Want: `20`},
+		{Query: `$single([7])`, Want: `7`},
+		{Query: `$map([1, 2], function($a){ $map([10, 20], function($b){ $a + $b }) })`, Want: `[[11,21],[12,22]]`},
+		{Query: `$map(nums, function($v){ $sum($map([1], function($v){ $v * 100 })) + $v })`, Want: `[103,101,102]`},
+	}
+	runQueryTests(t, itemsDoc, tests)
+}
+
+func TestHigherOrderFunctionsErrors(t *testing.T) {
+	tests := []queryError{
+		{Query: `$single(items, function($i){ $i.price > 8 })`, Err: errArgument},
+		{Query: `$single(nums)`, Err: errArgument},
+		{Query: `$map(nums, 1)`, Err: errType},
+	}
+	runQueryErrors(t, itemsDoc, tests)
+}
+
+func TestAggregateFunctions(t *testing.T) {
+	tests := []queryTest{
+		{Query: `$sum(nums)`, Want: `6`},
+		{Query: `$min(nums)`, Want: `1`},
+		{Query: `$max(nums)`, Want: `3`},
+		{Query: `$average(nums)`, Want: `2`},
+		{Query: `$average([1, 2])`, Want: `1.5`},
+		{Query: `$sum(items.price)`, Want: `35`},
+		{Query: `$sum(one)`, Want: `4`},
+		{Query: `$min(one)`, Want: `4`},
+		{Query: `$sum(empty)`, Want: `0`},
+		{Query: `$max(empty)`, Want: `null`},
+		{Query: `$average(empty)`, Want: `null`},
+	}
+	runQueryTests(t, itemsDoc, tests)
+
+	errs := []queryError{
+		{Query: `$sum(mixed)`, Err: errType},
+		{Query: `$max(mixed)`, Err: errType},
+		{Query: `$sum("x")`, Err: errType},
+	}
+	runQueryErrors(t, itemsDoc, errs)
+}
+
+func TestTimeFunctions(t *testing.T) {
+	tests := []queryTest{
+		{Query: `$fromMillis(0)`, Want: `"1970-01-01T00:00:00.000Z"`},
+		{Query: `$fromMillis(ts)`, Want: `"2024-05-06T12:53:20.000Z"`},
+		{Query: `$fromMillis(ts, "[Y0001]-[M01]-[D01]")`, Want: `"2024-05-06"`},
+		{Query: `$fromMillis(ts, "[Y0001]-[M01]-[D01] [H01]:[m01]", "+0200")`, Want: `"2024-05-06 14:53"`},
+		{Query: `$fromMillis(0, "[D1o] [MNn] [Y]")`, Want: `"1st January 1970"`},
+		{Query: `$toMillis("2024-05-06T13:33:20Z")`, Want: `1715002400000`},
+		{Query: `$toMillis("2024-05-06", "[Y0001]-[M01]-[D01]")`, Want: `1714953600000`},
+		{Query: `$toMillis($fromMillis(ts))`, Want: `1715000000000`},
+		{Query: `$parseTime("06/05/2024", "[D01]/[M01]/[Y0001]")`, Want: `"2024-05-06T00:00:00.000Z"`},
+		{Query: `$parseTime("06/05/2024 10:30", "[D01]/[M01]/[Y0001] [H01]:[m01]", "+0200")`, Want: `"2024-05-06T10:30:00.000+02:00"`},
+		{Query: `$now("[Y0001]") >= "` + time.Now().UTC().Format("2006") + `"`, Want: `true`},
+	}
+	runQueryTests(t, itemsDoc, tests)
+
+	errs := []queryError{
+		{Query: `$parseTime("2024-05-06", "[D01]/[M01]/[Y0001]")`, Err: datefmt.ErrValue},
+		{Query: `$fromMillis(ts, 1)`, Err: errType},
+		{Query: `$fromMillis(ts, "[Y]", 2)`, Err: errType},
+	}
+	runQueryErrors(t, itemsDoc, errs)
+
+	for _, q := range []string{`$toMillis("bad")`, `$fromMillis(ts, "[H01]", "Europe/Paris")`} {
+		if _, err := Find(strings.NewReader(itemsDoc), q); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func runQueryTests(t *testing.T, doc string, tests []queryTest) {
+	t.Helper()
+	for _, c := range tests {
+		res, err := Find(strings.NewReader(doc), c.Query)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Query, err)
+			continue
+		}
+		got, err := json.Marshal(res)
+		if err != nil {
+			t.Errorf("%s: fail to marshal result: %s", c.Query, err)
+			continue
+		}
+		if string(got) != c.Want {
+			t.Errorf("%s: result mismatched! want %s, got %s", c.Query, c.Want, got)
+		}
+	}
+}
+
+func runQueryErrors(t *testing.T, doc string, tests []queryError) {
+	t.Helper()
+	for _, c := range tests {
+		_, err := Find(strings.NewReader(doc), c.Query)
+		if !errors.Is(err, c.Err) {
+			t.Errorf("%s: expected %v, got %v", c.Query, c.Err, err)
+		}
+	}
+}
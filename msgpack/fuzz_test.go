@@ -0,0 +1,33 @@
+package msgpack
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzDecode(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"\x82\xa4name\xa5angle\xa4list\x93\x01\xff\xc3",
+		"\xdc\x00\x02\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00\xc0",
+		"\xd6\xff\x51\x4b\x67\xb0\xc4\x02\x01\x02",
+		"\xd9\x05hello\xd0\x80\xcd\x01\x00",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			list, err := DecodeAll(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			ws := NewWriter(&buf)
+			for _, v := range list {
+				if err := ws.Write(v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,352 @@
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	errSyntax = errors.New("syntax error")
+	errType   = errors.New("invalid type")
+)
+
+const (
+	codeNil      = 0xc0
+	codeNever    = 0xc1
+	codeFalse    = 0xc2
+	codeTrue     = 0xc3
+	codeBin8     = 0xc4
+	codeBin16    = 0xc5
+	codeBin32    = 0xc6
+	codeExt8     = 0xc7
+	codeExt16    = 0xc8
+	codeExt32    = 0xc9
+	codeFloat32  = 0xca
+	codeFloat64  = 0xcb
+	codeUint8    = 0xcc
+	codeUint16   = 0xcd
+	codeUint32   = 0xce
+	codeUint64   = 0xcf
+	codeInt8     = 0xd0
+	codeInt16    = 0xd1
+	codeInt32    = 0xd2
+	codeInt64    = 0xd3
+	codeFixExt1  = 0xd4
+	codeFixExt2  = 0xd5
+	codeFixExt4  = 0xd6
+	codeFixExt8  = 0xd7
+	codeFixExt16 = 0xd8
+	codeStr8     = 0xd9
+	codeStr16    = 0xda
+	codeStr32    = 0xdb
+	codeArray16  = 0xdc
+	codeArray32  = 0xdd
+	codeMap16    = 0xde
+	codeMap32    = 0xdf
+)
+
+const extTimestamp = -1
+
+const maxPrealloc = 1024
+
+func Decode(r io.Reader) (any, error) {
+	return NewParser(r).Parse()
+}
+
+func DecodeAll(r io.Reader) ([]any, error) {
+	return NewParser(r).ParseAll()
+}
+
+type Parser struct {
+	reader *bufio.Reader
+	offset int
+}
+
+func NewParser(r io.Reader) *Parser {
+	return &Parser{
+		reader: bufio.NewReader(r),
+	}
+}
+
+func (p *Parser) Parse() (any, error) {
+	if _, err := p.reader.Peek(1); err != nil {
+		return nil, err
+	}
+	return p.parse()
+}
+
+func (p *Parser) ParseAll() ([]any, error) {
+	var list []any
+	for {
+		v, err := p.Parse()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (p *Parser) parse() (any, error) {
+	c, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= 0x7f:
+		return float64(c), nil
+	case c >= 0xe0:
+		return float64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return p.parseMap(uint64(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return p.parseArray(uint64(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return p.parseString(uint64(c & 0x1f))
+	}
+	switch c {
+	case codeNil:
+		return nil, nil
+	case codeFalse:
+		return false, nil
+	case codeTrue:
+		return true, nil
+	case codeBin8, codeBin16, codeBin32:
+		n, err := p.readLength(c - codeBin8)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := p.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(buf), nil
+	case codeStr8, codeStr16, codeStr32:
+		n, err := p.readLength(c - codeStr8)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseString(n)
+	case codeArray16, codeArray32:
+		n, err := p.readLength(c - codeArray16 + 1)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseArray(n)
+	case codeMap16, codeMap32:
+		n, err := p.readLength(c - codeMap16 + 1)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseMap(n)
+	case codeFloat32:
+		buf, err := p.readN(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case codeFloat64:
+		buf, err := p.readN(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	case codeUint8, codeUint16, codeUint32, codeUint64:
+		buf, err := p.readN(1 << (c - codeUint8))
+		if err != nil {
+			return nil, err
+		}
+		return float64(readUint(buf)), nil
+	case codeInt8, codeInt16, codeInt32, codeInt64:
+		buf, err := p.readN(1 << (c - codeInt8))
+		if err != nil {
+			return nil, err
+		}
+		return float64(readInt(buf)), nil
+	case codeFixExt1, codeFixExt2, codeFixExt4, codeFixExt8, codeFixExt16:
+		return p.parseExt(1 << (c - codeFixExt1))
+	case codeExt8, codeExt16, codeExt32:
+		n, err := p.readLength(c - codeExt8)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseExt(n)
+	default:
+		return nil, p.syntaxError(fmt.Sprintf("invalid code 0x%02x", c))
+	}
+}
+
+func (p *Parser) parseString(n uint64) (any, error) {
+	buf, err := p.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(buf) {
+		return nil, p.syntaxError("invalid utf-8 string")
+	}
+	return string(buf), nil
+}
+
+func (p *Parser) parseArray(n uint64) (any, error) {
+	arr := make([]any, 0, min(n, maxPrealloc))
+	for range n {
+		v, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (p *Parser) parseMap(n uint64) (any, error) {
+	obj := make(map[string]any)
+	for range n {
+		key, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		obj[keyString(key)] = value
+	}
+	return obj, nil
+}
+
+func (p *Parser) parseExt(n uint64) (any, error) {
+	kind, err := p.readByte()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := p.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(kind) == extTimestamp {
+		if t, ok := readTimestamp(buf); ok {
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}
+		return nil, p.syntaxError("invalid timestamp")
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
+func (p *Parser) readLength(size byte) (uint64, error) {
+	buf, err := p.readN(1 << size)
+	if err != nil {
+		return 0, err
+	}
+	return readUint(buf), nil
+}
+
+func (p *Parser) readBytes(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, p.syntaxError("length too large")
+	}
+	var buf bytes.Buffer
+	c, err := io.CopyN(&buf, p.reader, int64(n))
+	p.offset += int(c)
+	if err != nil {
+		return nil, p.unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *Parser) readN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	c, err := io.ReadFull(p.reader, buf)
+	p.offset += c
+	if err != nil {
+		return nil, p.unexpectedEOF(err)
+	}
+	return buf, nil
+}
+
+func (p *Parser) readByte() (byte, error) {
+	b, err := p.reader.ReadByte()
+	if err != nil {
+		return 0, p.unexpectedEOF(err)
+	}
+	p.offset++
+	return b, nil
+}
+
+func (p *Parser) unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return p.syntaxError("unexpected end of input")
+	}
+	return err
+}
+
+func (p *Parser) syntaxError(msg string) error {
+	return fmt.Errorf("offset %d: %w: %s", p.offset, errSyntax, msg)
+}
+
+func readUint(buf []byte) uint64 {
+	switch len(buf) {
+	case 1:
+		return uint64(buf[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(buf))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(buf))
+	default:
+		return binary.BigEndian.Uint64(buf)
+	}
+}
+
+func readInt(buf []byte) int64 {
+	switch len(buf) {
+	case 1:
+		return int64(int8(buf[0]))
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(buf)))
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(buf)))
+	default:
+		return int64(binary.BigEndian.Uint64(buf))
+	}
+}
+
+func readTimestamp(buf []byte) (time.Time, bool) {
+	switch len(buf) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(buf)), 0), true
+	case 8:
+		v := binary.BigEndian.Uint64(buf)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)), true
+	case 12:
+		nsec := binary.BigEndian.Uint32(buf)
+		sec := int64(binary.BigEndian.Uint64(buf[4:]))
+		return time.Unix(sec, int64(nsec)), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func keyString(key any) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case float64:
+		return strconv.FormatFloat(k, 'f', -1, 64)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(k)
+	}
+}
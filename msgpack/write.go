@@ -0,0 +1,166 @@
+package msgpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+)
+
+type Writer struct {
+	ws *bufio.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		ws: bufio.NewWriter(w),
+	}
+}
+
+func (w *Writer) Write(value any) error {
+	if err := w.writeValue(value); err != nil {
+		return err
+	}
+	return w.ws.Flush()
+}
+
+func (w *Writer) writeValue(value any) error {
+	switch v := value.(type) {
+	case nil:
+		w.ws.WriteByte(codeNil)
+	case bool:
+		if v {
+			w.ws.WriteByte(codeTrue)
+		} else {
+			w.ws.WriteByte(codeFalse)
+		}
+	case string:
+		w.writeString(v)
+	case []byte:
+		w.writeHead(len(v), 0, codeBin8, codeBin16, codeBin32)
+		w.ws.Write(v)
+	case float64:
+		w.writeFloat(v)
+	case float32:
+		w.writeFloat(float64(v))
+	case int:
+		w.writeInt(int64(v))
+	case int64:
+		w.writeInt(v)
+	case uint64:
+		w.writeUint(v)
+	case []any:
+		w.writeHead(len(v), 0x90, 0, codeArray16, codeArray32)
+		for i := range v {
+			if err := w.writeValue(v[i]); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		w.writeHead(len(v), 0x80, 0, codeMap16, codeMap32)
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			w.writeString(k)
+			if err := w.writeValue(v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unsupported msgpack type %T", errType, value)
+	}
+	return nil
+}
+
+func (w *Writer) writeString(str string) {
+	if len(str) < 32 {
+		w.ws.WriteByte(0xa0 | byte(len(str)))
+	} else {
+		w.writeHead(len(str), 0, codeStr8, codeStr16, codeStr32)
+	}
+	w.ws.WriteString(str)
+}
+
+func (w *Writer) writeHead(n int, fix, code8, code16, code32 byte) {
+	switch {
+	case fix != 0 && n < 16:
+		w.ws.WriteByte(fix | byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		w.ws.WriteByte(code8)
+		w.ws.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.ws.WriteByte(code16)
+		w.writeBytes(2, uint64(n))
+	default:
+		w.ws.WriteByte(code32)
+		w.writeBytes(4, uint64(n))
+	}
+}
+
+func (w *Writer) writeFloat(f float64) {
+	if isInteger(f) {
+		w.writeInt(int64(f))
+		return
+	}
+	if float64(float32(f)) == f || math.IsNaN(f) {
+		w.ws.WriteByte(codeFloat32)
+		w.writeBytes(4, uint64(math.Float32bits(float32(f))))
+		return
+	}
+	w.ws.WriteByte(codeFloat64)
+	w.writeBytes(8, math.Float64bits(f))
+}
+
+func (w *Writer) writeInt(n int64) {
+	switch {
+	case n >= 0:
+		w.writeUint(uint64(n))
+	case n >= -32:
+		w.ws.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8:
+		w.ws.WriteByte(codeInt8)
+		w.writeBytes(1, uint64(n))
+	case n >= math.MinInt16:
+		w.ws.WriteByte(codeInt16)
+		w.writeBytes(2, uint64(n))
+	case n >= math.MinInt32:
+		w.ws.WriteByte(codeInt32)
+		w.writeBytes(4, uint64(n))
+	default:
+		w.ws.WriteByte(codeInt64)
+		w.writeBytes(8, uint64(n))
+	}
+}
+
+func (w *Writer) writeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		w.ws.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		w.ws.WriteByte(codeUint8)
+		w.writeBytes(1, n)
+	case n <= math.MaxUint16:
+		w.ws.WriteByte(codeUint16)
+		w.writeBytes(2, n)
+	case n <= math.MaxUint32:
+		w.ws.WriteByte(codeUint32)
+		w.writeBytes(4, n)
+	default:
+		w.ws.WriteByte(codeUint64)
+		w.writeBytes(8, n)
+	}
+}
+
+func (w *Writer) writeBytes(size int, n uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	w.ws.Write(buf[8-size:])
+}
+
+func isInteger(f float64) bool {
+	if f == 0 && math.Signbit(f) {
+		return false
+	}
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
@@ -0,0 +1,167 @@
+package relax
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+var ErrPath = errors.New("path not allowed by schema")
+
+type Suggestion struct {
+	Name     string
+	Doc      string
+	Required bool
+}
+
+type Completion struct {
+	Elements   []Suggestion
+	Attributes []Suggestion
+}
+
+func Complete(schema Pattern, path string) (Completion, error) {
+	var steps []string
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if s != "" {
+			steps = append(steps, s)
+		}
+	}
+	return completePath(schema, steps)
+}
+
+func CompleteNode(schema Pattern, node xml.Node) (Completion, error) {
+	var steps []string
+	for n := node; n != nil && n.Type() == xml.TypeElement; n = n.Parent() {
+		steps = append(steps, n.QualifiedName())
+	}
+	slices.Reverse(steps)
+
+	cpl, err := completePath(schema, steps)
+	if err != nil {
+		return cpl, err
+	}
+	el, ok := node.(*xml.Element)
+	if !ok {
+		return cpl, nil
+	}
+	cpl.Attributes = slices.DeleteFunc(cpl.Attributes, func(s Suggestion) bool {
+		return slices.ContainsFunc(el.Attrs, func(a xml.Attribute) bool {
+			return a.QualifiedName() == s.Name
+		})
+	})
+	return cpl, nil
+}
+
+func completePath(schema Pattern, steps []string) (Completion, error) {
+	var (
+		cpl  Completion
+		ctx  Resolver = noopResolver
+		list []Pattern
+	)
+	if g, ok := schema.(Grammar); ok {
+		ctx = g
+		schema = g.Start
+	}
+	list = append(list, schema)
+	for i, name := range steps {
+		cands := candidates(list, ctx)
+		ix := slices.IndexFunc(cands, func(p Pattern) bool {
+			el, ok := p.(Element)
+			return ok && el.QualifiedName() == name
+		})
+		if ix < 0 {
+			return cpl, fmt.Errorf("%w: /%s", ErrPath, strings.Join(steps[:i+1], "/"))
+		}
+		list = cands[ix].(Element).Patterns
+	}
+	for _, p := range candidates(list, ctx) {
+		switch p := p.(type) {
+		case Element:
+			cpl.Elements = appendSuggestion(cpl.Elements, Suggestion{
+				Name:     p.QualifiedName(),
+				Doc:      p.Doc,
+				Required: !p.Zero(),
+			})
+		case Attribute:
+			cpl.Attributes = appendSuggestion(cpl.Attributes, Suggestion{
+				Name:     p.QualifiedName(),
+				Doc:      p.Doc,
+				Required: !p.Zero(),
+			})
+		default:
+		}
+	}
+	return cpl, nil
+}
+
+func candidates(list []Pattern, ctx Resolver) []Pattern {
+	var (
+		res  []Pattern
+		seen = make(map[string]bool)
+	)
+	var walk func([]Pattern, bool)
+	walk = func(list []Pattern, optional bool) {
+		for _, p := range list {
+			if k, ok := p.(Link); ok {
+				if seen[k.Ident] {
+					continue
+				}
+				seen[k.Ident] = true
+				r, err := ctx.Resolve(k)
+				if err != nil {
+					continue
+				}
+				if el, ok := r.(Element); ok && k.cardinality != 0 {
+					el.cardinality = k.cardinality
+					r = el
+				}
+				walk([]Pattern{r}, optional)
+				seen[k.Ident] = false
+				continue
+			}
+			switch p := p.(type) {
+			case Element:
+				if optional {
+					p.cardinality = optionalCardinality(p.cardinality)
+				}
+				res = append(res, p)
+			case Attribute:
+				if optional {
+					p.cardinality = optionalCardinality(p.cardinality)
+				}
+				res = append(res, p)
+			case Choice:
+				walk(p.List, optional || len(p.List) > 1)
+			case Group:
+				walk(p.List, optional)
+			default:
+			}
+		}
+	}
+	walk(list, false)
+	return res
+}
+
+func optionalCardinality(c cardinality) cardinality {
+	if c.More() {
+		return zeroOrMore
+	}
+	return zeroOrOne
+}
+
+func appendSuggestion(list []Suggestion, s Suggestion) []Suggestion {
+	ix := slices.IndexFunc(list, func(other Suggestion) bool {
+		return other.Name == s.Name
+	})
+	if ix < 0 {
+		return append(list, s)
+	}
+	list[ix].Required = list[ix].Required && s.Required
+	if list[ix].Doc == "" {
+		list[ix].Doc = s.Doc
+	}
+	return list
+}
@@ -21,19 +21,150 @@ var (
 )
 
 type NodeError struct {
-	Node  xml.Node
-	Cause string
+	Node     xml.Node
+	Cause    string
+	Doc      string
+	Path     string
+	Line     int
+	Column   int
+	Expected []string
 }
 
 func createError(cause string, node xml.Node) error {
-	return NodeError{
+	e := NodeError{
 		Node:  node,
 		Cause: cause,
+		Path:  xml.PathOf(node, xml.PathOptions{}),
 	}
+	pos := xml.LocationOf(node)
+	e.Line, e.Column = pos.Line, pos.Column
+	return e
+}
+
+func createExpectedError(cause string, node xml.Node, expected ...string) error {
+	err := createError(cause, node).(NodeError)
+	err.Expected = expected
+	return err
+}
+
+func annotateError(err error, doc string, node xml.Node) error {
+	if err == nil || doc == "" {
+		return err
+	}
+	if list, ok := err.(ErrorList); ok {
+		for i := range list {
+			if list[i].Doc == "" {
+				list[i].Doc = doc
+			}
+		}
+		return list
+	}
+	var e NodeError
+	if !errors.As(err, &e) {
+		e = createError(err.Error(), node).(NodeError)
+	}
+	if e.Doc == "" {
+		e.Doc = doc
+	}
+	return e
 }
 
 func (n NodeError) Error() string {
-	return n.Cause
+	var str strings.Builder
+	if n.Line > 0 {
+		fmt.Fprintf(&str, "%d:%d: ", n.Line, n.Column)
+	}
+	if n.Path != "" {
+		str.WriteString(n.Path)
+		str.WriteString(": ")
+	}
+	str.WriteString(n.Cause)
+	if len(n.Expected) > 0 {
+		fmt.Fprintf(&str, " (expected %s)", strings.Join(n.Expected, ", "))
+	}
+	if n.Doc != "" {
+		fmt.Fprintf(&str, " (%s)", n.Doc)
+	}
+	return str.String()
+}
+
+type ErrorList []NodeError
+
+func (e ErrorList) Error() string {
+	list := make([]string, 0, len(e))
+	for i := range e {
+		list = append(list, e[i].Error())
+	}
+	return strings.Join(list, "\n")
+}
+
+func (e ErrorList) Unwrap() []error {
+	list := make([]error, 0, len(e))
+	for i := range e {
+		list = append(list, e[i])
+	}
+	return list
+}
+
+func (e ErrorList) err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	default:
+		return e
+	}
+}
+
+func appendError(list ErrorList, err error) ErrorList {
+	switch e := err.(type) {
+	case nil:
+	case ErrorList:
+		list = append(list, e...)
+	case NodeError:
+		list = append(list, e)
+	default:
+		list = append(list, NodeError{Cause: err.Error()})
+	}
+	return list
+}
+
+type Validator struct {
+	Schema    Pattern
+	MaxErrors int
+}
+
+func (v Validator) Validate(node xml.Node) error {
+	var ctx Resolver = noopResolver
+	if g, ok := v.Schema.(Grammar); ok {
+		ctx = g
+	}
+	err := v.Schema.validate(node, limitResolver{
+		Resolver: ctx,
+		max:      v.MaxErrors,
+	})
+	list := appendError(nil, err)
+	if v.MaxErrors > 0 && len(list) > v.MaxErrors {
+		list = list[:v.MaxErrors]
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+type limitResolver struct {
+	Resolver
+	max int
+}
+
+func maxErrorsReached(ctx Resolver, list ErrorList) bool {
+	r, ok := ctx.(limitResolver)
+	if !ok {
+		return len(list) > 0
+	}
+	return r.max > 0 && len(list) >= r.max
 }
 
 type cardinality int8
@@ -151,13 +282,18 @@ type Attribute struct {
 	QName
 	cardinality
 	Value Pattern
+	Doc   string
 }
 
 func (a Attribute) Validate(node xml.Node) error {
 	return a.validate(node, noopResolver)
 }
 
-func (a Attribute) validate(node xml.Node, _ Resolver) error {
+func (a Attribute) validate(node xml.Node, ctx Resolver) error {
+	return annotateError(a.validateAttr(node, ctx), a.Doc, node)
+}
+
+func (a Attribute) validateAttr(node xml.Node, _ Resolver) error {
 	el, ok := node.(*xml.Element)
 	if !ok {
 		return createError("xml element expected", node)
@@ -167,16 +303,21 @@ func (a Attribute) validate(node xml.Node, _ Resolver) error {
 	})
 	if ix < 0 && !a.Zero() {
 		msg := fmt.Sprintf("%s: attribute is missing", a.QualifiedName())
-		return createError(msg, node)
+		return createExpectedError(msg, node, "@"+a.QualifiedName())
 	}
-	if a.Value == nil {
+	if ix < 0 || a.Value == nil {
 		return nil
 	}
 	v, ok := a.Value.(interface{ validateValue(string) error })
 	if !ok {
 		return fmt.Errorf("pattern not applicatble for attribute")
 	}
-	return v.validateValue(el.Attrs[ix].Value())
+	if err := v.validateValue(el.Attrs[ix].Value()); err != nil {
+		e := createError(err.Error(), node).(NodeError)
+		e.Path += "/@" + a.QualifiedName()
+		return e
+	}
+	return nil
 }
 
 type Group struct {
@@ -191,12 +332,12 @@ type Choice struct {
 }
 
 func (c Choice) Validate(node xml.Node) error {
-	_, err := validateChoice([]xml.Node{node}, c, noopResolver)
+	_, err := validateChoice(node.Parent(), []xml.Node{node}, c, noopResolver)
 	return err
 }
 
 func (c Choice) validate(node xml.Node, ctx Resolver) error {
-	_, err := validateChoice([]xml.Node{node}, c, ctx)
+	_, err := validateChoice(node.Parent(), []xml.Node{node}, c, ctx)
 	return err
 }
 
@@ -205,6 +346,7 @@ type Element struct {
 	cardinality
 	Value    Pattern
 	Patterns []Pattern
+	Doc      string
 }
 
 func (e Element) Validate(node xml.Node) error {
@@ -212,9 +354,13 @@ func (e Element) Validate(node xml.Node) error {
 }
 
 func (e Element) validate(node xml.Node, ctx Resolver) error {
+	return annotateError(e.validateElement(node, ctx), e.Doc, node)
+}
+
+func (e Element) validateElement(node xml.Node, ctx Resolver) error {
 	if e.QualifiedName() != node.QualifiedName() {
 		msg := fmt.Sprintf("want %s but got %s", e.QualifiedName(), node.QualifiedName())
-		return createError(msg, node)
+		return createExpectedError(msg, node, e.QualifiedName())
 	}
 	curr, ok := node.(*xml.Element)
 	if !ok {
@@ -223,12 +369,13 @@ func (e Element) validate(node xml.Node, ctx Resolver) error {
 	var (
 		offset int
 		attrs  int
+		errs   ErrorList
 	)
 	for _, el := range e.Patterns {
 		var err error
 		switch el := el.(type) {
 		case Element:
-			step, err1 := validateNodes(curr.Nodes[offset:], el, ctx)
+			step, err1 := validateNodes(curr, curr.Nodes[offset:], el, ctx)
 			offset += step
 			err = err1
 		case Attribute:
@@ -240,27 +387,31 @@ func (e Element) validate(node xml.Node, ctx Resolver) error {
 				attrs++
 				break
 			}
-			step, err1 := validateChoice(curr.Nodes[offset:], el, ctx)
+			step, err1 := validateChoice(curr, curr.Nodes[offset:], el, ctx)
 			offset += step
 			err = err1
 		case Link:
-			step, err1 := validateNodes(curr.Nodes[offset:], el, ctx)
+			step, err1 := validateNodes(curr, curr.Nodes[offset:], el, ctx)
 			offset += step
 			err = err1
 		default:
 			return fmt.Errorf("pattern not applicatble for element")
 		}
-		if err != nil {
-			return err
+		if err == nil {
+			continue
+		}
+		if errs = appendError(errs, err); maxErrorsReached(ctx, errs) {
+			return errs.err()
 		}
+		offset += skipInvalid(curr.Nodes[offset:], el, ctx)
 	}
 	// if len(curr.Attrs) > attrs {
 	// 	return fmt.Errorf("element has more attributes than expected")
 	// }
 	if e.Value != nil {
-		return e.Value.Validate(curr)
+		errs = appendError(errs, e.Value.Validate(curr))
 	}
-	return nil
+	return errs.err()
 }
 
 type Text struct{}
@@ -511,7 +662,7 @@ func reassemble(start Pattern, others map[string]Pattern) (Pattern, error) {
 	}
 	el, ok := others[link.Ident]
 	if !ok {
-		return nil, fmt.Errorf("%s: pattern not defined")
+		return nil, fmt.Errorf("%s: pattern not defined", link.Ident)
 	}
 	switch el := el.(type) {
 	case Element:
@@ -540,14 +691,15 @@ func reassemble(start Pattern, others map[string]Pattern) (Pattern, error) {
 	}
 }
 
-func validateNodes(nodes []xml.Node, elem Pattern, ctx Resolver) (int, error) {
+func validateNodes(parent xml.Node, nodes []xml.Node, elem Pattern, ctx Resolver) (int, error) {
 	if c, ok := elem.(Choice); ok {
-		return validateChoice(nodes, c, ctx)
+		return validateChoice(parent, nodes, c, ctx)
 	}
 	var (
 		count int
 		ptr   int
 		prv   = -1
+		errs  ErrorList
 	)
 	for ; ptr < len(nodes); ptr++ {
 		if _, ok := nodes[ptr].(*xml.Element); !ok {
@@ -560,47 +712,64 @@ func validateNodes(nodes []xml.Node, elem Pattern, ctx Resolver) (int, error) {
 			if a, ok := elem.(Element); ok && a.Zero() {
 				return 0, nil
 			}
-			return 0, err
+			if errs = appendError(errs, err); maxErrorsReached(ctx, errs) {
+				return 0, errs.err()
+			}
 		}
 		count++
 		prv = ptr
 	}
 	a, ok := elem.(Element)
 	if !ok {
-		return ptr, nil
+		return ptr, errs.err()
 	}
 	switch {
 	case count == 0 && a.cardinality.Zero():
 	case count == 1 && a.cardinality.One():
-	case count > 1 && a.cardinality.More():
+	case count > 0 && a.cardinality.More():
+	case count == 0:
+		msg := fmt.Sprintf("%s: element is missing", a.QualifiedName())
+		err := createExpectedError(msg, parent, a.QualifiedName())
+		errs = appendError(errs, annotateError(err, a.Doc, parent))
 	default:
-		return 0, fmt.Errorf("element count mismatched")
+		msg := fmt.Sprintf("%s: element count mismatched (got %d)", a.QualifiedName(), count)
+		err := createExpectedError(msg, parent, a.QualifiedName())
+		errs = appendError(errs, annotateError(err, a.Doc, parent))
 	}
-	return ptr, nil
+	return ptr, errs.err()
 }
 
-func validateChoice(nodes []xml.Node, el Choice, ctx Resolver) (int, error) {
+func validateChoice(parent xml.Node, nodes []xml.Node, el Choice, ctx Resolver) (int, error) {
 	var (
 		step int
 		err  error
 	)
 	for _, el := range el.List {
 		if g, ok := el.(Group); ok {
-			step, err = validateGroup(nodes, g, ctx)
+			step, err = validateGroup(parent, nodes, g, ctx)
 		} else {
-			step, err = validateNodes(nodes, el, ctx)
+			step, err = validateNodes(parent, nodes, el, ctx)
 		}
 		if err == nil {
-			break
+			return step, nil
 		}
 	}
-	return step, err
+	if len(el.List) <= 1 {
+		return step, err
+	}
+	node := parent
+	if ix := slices.IndexFunc(nodes, func(n xml.Node) bool {
+		return n.Type() == xml.TypeElement
+	}); ix >= 0 {
+		node = nodes[ix]
+	}
+	return 0, createExpectedError("no alternative matched", node, expectedNames(el.List, ctx)...)
 }
 
-func validateGroup(nodes []xml.Node, el Group, ctx Resolver) (int, error) {
+func validateGroup(parent xml.Node, nodes []xml.Node, el Group, ctx Resolver) (int, error) {
 	var step int
 	for i := range el.List {
-		x, err := validateNodes(nodes[step:], el.List[i], ctx)
+		x, err := validateNodes(parent, nodes[step:], el.List[i], ctx)
 		if err != nil {
 			return 0, err
 		}
@@ -608,3 +777,54 @@ func validateGroup(nodes []xml.Node, el Group, ctx Resolver) (int, error) {
 	}
 	return step, nil
 }
+
+func skipInvalid(nodes []xml.Node, pattern Pattern, ctx Resolver) int {
+	names := expectedNames([]Pattern{pattern}, ctx)
+	if len(names) != 1 {
+		return 0
+	}
+	var (
+		ptr  int
+		seen bool
+	)
+	for ; ptr < len(nodes); ptr++ {
+		if nodes[ptr].Type() != xml.TypeElement {
+			continue
+		}
+		if nodes[ptr].QualifiedName() != names[0] {
+			break
+		}
+		seen = true
+	}
+	if !seen {
+		return 0
+	}
+	return ptr
+}
+
+func expectedNames(list []Pattern, ctx Resolver) []string {
+	var names []string
+	for _, p := range list {
+		if k, ok := p.(Link); ok {
+			r, err := ctx.Resolve(k)
+			if err != nil {
+				continue
+			}
+			p = r
+		}
+		switch p := p.(type) {
+		case Element:
+			names = append(names, p.QualifiedName())
+		case Attribute:
+			names = append(names, "@"+p.QualifiedName())
+		case Choice:
+			names = append(names, expectedNames(p.List, ctx)...)
+		case Group:
+			if len(p.List) > 0 {
+				names = append(names, expectedNames(p.List[:1], ctx)...)
+			}
+		default:
+		}
+	}
+	return names
+}
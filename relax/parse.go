@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,6 +26,7 @@ type Parser struct {
 
 	spaces map[string]string
 	types  map[string]string
+	docs   []string
 }
 
 func Parse(r io.Reader) *Parser {
@@ -48,7 +50,9 @@ func (p *Parser) parse() (Pattern, error) {
 		return nil, err
 	}
 	p.skipEOL()
-	p.skipComment()
+	if err := p.parseAnnotations(); err != nil {
+		return nil, err
+	}
 	var includes []Pattern
 	for p.isKeyword("include") {
 		inc, err := p.parseInclude()
@@ -162,7 +166,9 @@ func (p *Parser) parseDefinitions() (Pattern, error) {
 	}
 	gram.Links = make(map[string]Pattern)
 	for !p.done() {
-		p.skipComment()
+		if err := p.parseAnnotations(); err != nil {
+			return nil, err
+		}
 		if !p.is(Name) {
 			return nil, p.createError("pattern", "pattern should be a name")
 		}
@@ -204,6 +210,9 @@ func (p *Parser) parseStartPattern() (Pattern, error) {
 		return nil, p.createError("start", "missing assignment operator (\"=\") after start")
 	}
 	p.next()
+	if err := p.parseAnnotations(); err != nil {
+		return nil, err
+	}
 	if p.is(Name) {
 		return p.parseLink()
 	}
@@ -220,7 +229,10 @@ func (p *Parser) parseLink() (Pattern, error) {
 
 func (p *Parser) parseList() (Pattern, error) {
 	var grp Group
-	for p.is(Keyword) || p.is(Name) {
+	for p.is(Keyword) || p.is(Name) || p.is(Documentation) || p.is(BegBracket) {
+		if err := p.parseAnnotations(); err != nil {
+			return nil, err
+		}
 		var (
 			pat Pattern
 			err error
@@ -255,6 +267,9 @@ func (p *Parser) parseGroup() (Pattern, error) {
 	p.next()
 	var grp Group
 	for !p.done() && !p.is(EndParen) {
+		if err := p.parseAnnotations(); err != nil {
+			return nil, err
+		}
 		var (
 			el  Pattern
 			err error
@@ -294,12 +309,15 @@ func (p *Parser) parseChoice() (Pattern, error) {
 	p.next()
 	var ch Choice
 	for !p.done() && !p.is(EndParen) {
+		if err := p.parseAnnotations(); err != nil {
+			return nil, err
+		}
 		var (
 			el  Pattern
 			err error
 		)
 		switch {
-		case p.is(Keyword) || p.is(Name):
+		case p.is(Keyword) || p.is(Name) || p.is(Documentation) || p.is(BegBracket):
 			el, err = p.parseList()
 		case p.is(BegParen):
 			el, err = p.parseGroup()
@@ -331,6 +349,7 @@ func (p *Parser) parseElement() (Pattern, error) {
 		el  Element
 		err error
 	)
+	el.Doc = p.takeDocumentation()
 	if el.QName, err = p.parseName(); err != nil {
 		return nil, err
 	}
@@ -339,8 +358,10 @@ func (p *Parser) parseElement() (Pattern, error) {
 	}
 	p.next()
 	p.skipEOL()
-	p.skipComment()
 	for {
+		if err := p.parseAnnotations(); err != nil {
+			return nil, err
+		}
 		var (
 			pat Pattern
 			err error
@@ -417,6 +438,7 @@ func (p *Parser) parseAttribute() (Pattern, error) {
 		at  Attribute
 		err error
 	)
+	at.Doc = p.takeDocumentation()
 	if at.QName, err = p.parseName(); err != nil {
 		return nil, err
 	}
@@ -656,6 +678,60 @@ func (p *Parser) parseEnum() (Pattern, error) {
 	return pt, nil
 }
 
+func (p *Parser) parseAnnotations() error {
+	for {
+		switch {
+		case p.is(Comment) || p.is(EOL):
+			p.next()
+		case p.is(Documentation):
+			p.docs = append(p.docs, p.curr.Literal)
+			p.next()
+		case p.is(BegBracket):
+			if err := p.parseAnnotation(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *Parser) parseAnnotation() error {
+	p.next()
+	for !p.done() && !p.is(EndBracket) {
+		name, err := p.parseName()
+		if err != nil {
+			return err
+		}
+		if !p.is(BegBracket) {
+			return p.createError("annotation", "missing \"[\" after annotation name")
+		}
+		p.next()
+		for p.is(Literal) {
+			if name.Local == "documentation" {
+				p.docs = append(p.docs, p.curr.Literal)
+			}
+			p.next()
+		}
+		if !p.is(EndBracket) {
+			return p.createError("annotation", "missing \"]\" at end of annotation")
+		}
+		p.next()
+	}
+	if !p.is(EndBracket) {
+		return p.createError("annotation", "missing \"]\" at end of annotation")
+	}
+	p.next()
+	return nil
+}
+
+func (p *Parser) takeDocumentation() string {
+	defer func() {
+		p.docs = p.docs[:0]
+	}()
+	return strings.Join(p.docs, " ")
+}
+
 func (p *Parser) skipComment() {
 	for p.is(Comment) {
 		p.next()
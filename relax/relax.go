@@ -60,6 +60,9 @@ func printSchema(w io.Writer, schema Pattern, depth int) {
 		fmt.Fprintln(w)
 	case Element:
 		fmt.Fprintf(w, "element(%s)", p.QualifiedName())
+		if p.Doc != "" {
+			fmt.Fprintf(w, " %q", p.Doc)
+		}
 		if len(p.Patterns) > 0 {
 			fmt.Fprint(w, "[")
 		}
@@ -73,6 +76,9 @@ func printSchema(w io.Writer, schema Pattern, depth int) {
 		}
 	case Attribute:
 		fmt.Fprintf(w, "attribute(%s)", p.QualifiedName())
+		if p.Doc != "" {
+			fmt.Fprintf(w, " %q", p.Doc)
+		}
 		fmt.Fprintln(w)
 	case Choice:
 		fmt.Fprintf(w, "choice(%d)[", len(p.List))
@@ -0,0 +1,216 @@
+package relax_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/relax"
+	"github.com/midbel/codecs/xml"
+)
+
+const librarySchema = `
+start = library
+
+library = element library {
+	## identifier of the library
+	attribute id { text },
+	attribute lang { text }?,
+	## a book of the library
+	element book {
+		attribute isbn { text },
+		element title { text },
+		element author { text }*
+	}+
+}
+`
+
+func parseSchema(t *testing.T, str string) relax.Pattern {
+	t.Helper()
+	schema, err := relax.Parse(strings.NewReader(str)).Parse()
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	return schema
+}
+
+func parseDocument(t *testing.T, str string) *xml.Document {
+	t.Helper()
+	doc, err := xml.NewParser(strings.NewReader(str)).Parse()
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	return doc
+}
+
+func TestValidatorErrors(t *testing.T) {
+	schema := parseSchema(t, librarySchema)
+	doc := parseDocument(t, `<library id="lib"><book><title>go</title></book><book isbn="2"><author>x</author></book></library>`)
+
+	tests := []struct {
+		MaxErrors int
+		Want      []relax.NodeError
+	}{
+		{
+			Want: []relax.NodeError{
+				{
+					Cause:    "isbn: attribute is missing",
+					Path:     "/library/book[1]",
+					Doc:      "a book of the library",
+					Line:     1,
+					Column:   19,
+					Expected: []string{"@isbn"},
+				},
+				{
+					Cause:    "want title but got author",
+					Path:     "/library/book[2]/author",
+					Doc:      "a book of the library",
+					Line:     1,
+					Column:   64,
+					Expected: []string{"title"},
+				},
+			},
+		},
+		{
+			MaxErrors: 1,
+			Want: []relax.NodeError{
+				{
+					Cause:    "isbn: attribute is missing",
+					Path:     "/library/book[1]",
+					Doc:      "a book of the library",
+					Line:     1,
+					Column:   19,
+					Expected: []string{"@isbn"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		val := relax.Validator{
+			Schema:    schema,
+			MaxErrors: tt.MaxErrors,
+		}
+		err := val.Validate(doc.Root())
+		var list relax.ErrorList
+		if !errors.As(err, &list) {
+			t.Errorf("max %d: expected error list, got %v", tt.MaxErrors, err)
+			continue
+		}
+		if len(list) != len(tt.Want) {
+			t.Errorf("max %d: errors count mismatched! want %d, got %d", tt.MaxErrors, len(tt.Want), len(list))
+			continue
+		}
+		for i, got := range list {
+			want := tt.Want[i]
+			if got.Cause != want.Cause || got.Path != want.Path || got.Doc != want.Doc {
+				t.Errorf("error mismatched! want %q, got %q", want.Error(), got.Error())
+			}
+			if got.Line != want.Line || got.Column != want.Column {
+				t.Errorf("%s: position mismatched! want %d:%d, got %d:%d", got.Path, want.Line, want.Column, got.Line, got.Column)
+			}
+			if !slices.Equal(got.Expected, want.Expected) {
+				t.Errorf("%s: expected mismatched! want %v, got %v", got.Path, want.Expected, got.Expected)
+			}
+			if got.Node == nil {
+				t.Errorf("%s: node expected", got.Path)
+			}
+		}
+		var node relax.NodeError
+		if !errors.As(err, &node) || node.Path != tt.Want[0].Path {
+			t.Errorf("max %d: first error not reachable with errors.As", tt.MaxErrors)
+		}
+	}
+}
+
+func TestValidatorValid(t *testing.T) {
+	schema := parseSchema(t, librarySchema)
+	doc := parseDocument(t, `<library id="lib"><book isbn="1"><title>go</title><author>x</author></book></library>`)
+	if err := (relax.Validator{Schema: schema}).Validate(doc.Root()); err != nil {
+		t.Errorf("expected document to be valid: %s", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	schema := parseSchema(t, librarySchema)
+	tests := []struct {
+		Path       string
+		Elements   []relax.Suggestion
+		Attributes []relax.Suggestion
+	}{
+		{
+			Path: "/",
+			Elements: []relax.Suggestion{
+				{Name: "library", Required: true},
+			},
+		},
+		{
+			Path: "/library",
+			Elements: []relax.Suggestion{
+				{Name: "book", Doc: "a book of the library", Required: true},
+			},
+			Attributes: []relax.Suggestion{
+				{Name: "id", Doc: "identifier of the library", Required: true},
+				{Name: "lang"},
+			},
+		},
+		{
+			Path: "/library/book",
+			Elements: []relax.Suggestion{
+				{Name: "title", Required: true},
+				{Name: "author"},
+			},
+			Attributes: []relax.Suggestion{
+				{Name: "isbn", Required: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		cpl, err := relax.Complete(schema, tt.Path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.Path, err)
+			continue
+		}
+		if !slices.Equal(cpl.Elements, tt.Elements) {
+			t.Errorf("%s: elements mismatched! want %v, got %v", tt.Path, tt.Elements, cpl.Elements)
+		}
+		if !slices.Equal(cpl.Attributes, tt.Attributes) {
+			t.Errorf("%s: attributes mismatched! want %v, got %v", tt.Path, tt.Attributes, cpl.Attributes)
+		}
+	}
+	if _, err := relax.Complete(schema, "/library/shelf"); !errors.Is(err, relax.ErrPath) {
+		t.Errorf("expected ErrPath for unknown path, got %v", err)
+	}
+}
+
+func TestCompleteNode(t *testing.T) {
+	schema := parseSchema(t, librarySchema)
+	doc := parseDocument(t, `<library id="lib"><book isbn="1"/></library>`)
+
+	book := doc.Root().(*xml.Element).Nodes[0]
+	cpl, err := relax.CompleteNode(schema, book)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cpl.Attributes) != 0 {
+		t.Errorf("attributes already set should not be suggested: %v", cpl.Attributes)
+	}
+	want := []relax.Suggestion{
+		{Name: "title", Required: true},
+		{Name: "author"},
+	}
+	if !slices.Equal(cpl.Elements, want) {
+		t.Errorf("elements mismatched! want %v, got %v", want, cpl.Elements)
+	}
+
+	cpl, err = relax.CompleteNode(schema, doc.Root())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want = []relax.Suggestion{
+		{Name: "lang"},
+	}
+	if !slices.Equal(cpl.Attributes, want) {
+		t.Errorf("attributes mismatched! want %v, got %v", want, cpl.Attributes)
+	}
+}
@@ -29,6 +29,8 @@ func (t Token) String() string {
 		return "<eol>"
 	case Comment:
 		prefix = "comment"
+	case Documentation:
+		prefix = "documentation"
 	case Literal:
 		prefix = "literal"
 	case Name:
@@ -39,6 +41,10 @@ func (t Token) String() string {
 		return "<beg-brace>"
 	case EndBrace:
 		return "<end-brace>"
+	case BegBracket:
+		return "<beg-bracket>"
+	case EndBracket:
+		return "<end-bracket>"
 	case BegParen:
 		return "<beg-paren>"
 	case EndParen:
@@ -73,11 +79,14 @@ const (
 	EOF = -(iota + 1)
 	EOL
 	Comment
+	Documentation
 	Literal
 	Name
 	Keyword
 	BegBrace
 	EndBrace
+	BegBracket
+	EndBracket
 	BegParen
 	EndParen
 	Comma
@@ -166,13 +175,17 @@ func (s *Scanner) scanQuote(tok *Token) {
 
 func (s *Scanner) scanComment(tok *Token) {
 	s.read()
+	tok.Type = Comment
+	if isComment(s.char) {
+		s.read()
+		tok.Type = Documentation
+	}
 	s.skipBlank()
 	for !s.done() && !isNL(s.char) {
 		s.write()
 		s.read()
 	}
 	s.read()
-	tok.Type = Comment
 	tok.Literal = s.literal()
 }
 
@@ -227,6 +240,10 @@ func (s *Scanner) scanPunct(tok *Token) {
 	case '}':
 		tok.Type = EndBrace
 		s.nested--
+	case '[':
+		tok.Type = BegBracket
+	case ']':
+		tok.Type = EndBracket
 	case '(':
 		tok.Type = BegParen
 	case ')':
@@ -313,7 +330,7 @@ func isArity(r rune) bool {
 
 func isPunct(r rune) bool {
 	return r == ',' || r == '|' || r == '=' || r == ':' || r == '&' ||
-		r == '(' || r == ')' || r == '{' || r == '}'
+		r == '(' || r == ')' || r == '{' || r == '}' || r == '[' || r == ']'
 }
 
 func isAlpha(r rune) bool {
@@ -0,0 +1,649 @@
+package sch
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xslt"
+)
+
+const (
+	xslNS        = "http://www.w3.org/1999/XSL/Transform"
+	xslPrefix    = "xsl"
+	aglNS        = "http://midbel.org/angle"
+	aglPrefix    = "agl"
+	locationName = "location"
+)
+
+func (s *Schema) Compile(phase string) (*xml.Document, error) {
+	if s.Compiled() {
+		return s.source, nil
+	}
+	patterns, err := s.activePatterns(s.resolvePhase(phase))
+	if err != nil {
+		return nil, err
+	}
+	return s.compilePatterns(patterns), nil
+}
+
+func (s *Schema) compilePatterns(patterns []*Pattern) *xml.Document {
+	root := xslElement("stylesheet")
+	root.SetAttribute(xml.NewAttribute(xml.LocalName("version"), "3.0"))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName(xslPrefix, "xmlns"), xslNS))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("svrl", "xmlns"), svrlNS))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName(aglPrefix, "xmlns"), aglNS))
+	root.SetAttribute(xml.NewAttribute(xml.LocalName("extension-element-prefixes"), aglPrefix))
+	for _, ns := range s.namespaces {
+		root.SetAttribute(xml.NewAttribute(xml.QualifiedName(ns.Prefix, "xmlns"), ns.Uri))
+	}
+
+	output := xslElement("output")
+	output.SetAttribute(xml.NewAttribute(xml.LocalName("method"), "xml"))
+	output.SetAttribute(xml.NewAttribute(xml.LocalName("indent"), "yes"))
+	root.Append(output)
+
+	for _, v := range s.lets {
+		root.Append(xslVariable(v))
+	}
+
+	tpl := xslElement("template")
+	tpl.SetAttribute(xml.NewAttribute(xml.LocalName("match"), "/"))
+	root.Append(tpl)
+
+	out := svrlElement("schematron-output")
+	if s.Title != "" {
+		out.SetAttribute(xml.NewAttribute(xml.LocalName("title"), s.Title))
+	}
+	tpl.Append(out)
+	for i, p := range patterns {
+		el := svrlElement("active-pattern")
+		el.SetAttribute(xml.NewAttribute(xml.LocalName("id"), p.Ident))
+		out.Append(el)
+		out.Append(xslApply("/", patternMode(i)))
+	}
+	for i, p := range patterns {
+		mode := patternMode(i)
+		for j, r := range p.Rules {
+			root.Append(s.compileRule(p, r, ruleIdent(p, j), mode, len(p.Rules)-j))
+		}
+		for _, el := range compileTraversal(mode) {
+			root.Append(el)
+		}
+	}
+	root.Append(compileLocation())
+	return xml.NewDocument(root)
+}
+
+func (s *Schema) RunXSLT(node xml.Node) ([]Result, error) {
+	return s.RunPhaseXSLT("", node)
+}
+
+func (s *Schema) RunPhaseXSLT(phase string, node xml.Node) ([]Result, error) {
+	now := time.Now()
+	res, err := s.runPhaseXSLT(phase, node)
+	s.observe(now, res, err)
+	return res, err
+}
+
+func (s *Schema) runPhaseXSLT(phase string, node xml.Node) ([]Result, error) {
+	if s.Compiled() {
+		return s.runCompiled(phase, node)
+	}
+	patterns, err := s.phasePatterns(phase)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := xslt.FromDocument(s.compilePatterns(patterns), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := sheet.ApplyPolicy(xslt.AllowExtensions(aglPrefix)); err != nil {
+		return nil, err
+	}
+	out, err := executeSvrl(sheet.NewSession(), node)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyPolicy(s.collectResults(node, patterns, out))
+}
+
+func (s *Schema) Compiled() bool {
+	return s.sheet != nil
+}
+
+func (s *Schema) runCompiled(phase string, node xml.Node) ([]Result, error) {
+	if phase == "" {
+		phase = "#DEFAULT"
+	}
+	sess := s.sheet.NewSession()
+	if err := sess.SetParamValue("phase", phase); err != nil {
+		return nil, err
+	}
+	out, err := executeSvrl(sess, node)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyPolicy(s.normalizeResults(node, out))
+}
+
+func executeSvrl(sess *xslt.Session, node xml.Node) (*xml.Element, error) {
+	nodes, err := sess.Execute(node)
+	if err != nil {
+		return nil, err
+	}
+	ix := slices.IndexFunc(nodes, func(n xml.Node) bool {
+		return n.Type() == xml.TypeElement && n.LocalName() == "schematron-output"
+	})
+	if ix < 0 {
+		return nil, fmt.Errorf("svrl output expected")
+	}
+	return getElementFromNode(nodes[ix])
+}
+
+func isCompiledSchema(el *xml.Element) bool {
+	if el.Uri != xslNS {
+		return false
+	}
+	name := el.LocalName()
+	return name == "stylesheet" || name == "transform"
+}
+
+func createSchemaFromStylesheet(doc *xml.Document, contextDir string) (*Schema, error) {
+	sheet, err := xslt.FromDocument(doc, contextDir)
+	if err != nil {
+		return nil, err
+	}
+	sch := Default()
+	sch.sheet = sheet
+	sch.source = doc
+	if root, ok := doc.Root().(*xml.Element); ok {
+		sch.asserts = compiledAsserts(root)
+	}
+	sch.mode = "xslt"
+	return sch, nil
+}
+
+func (s *Schema) normalizeResults(node xml.Node, out *xml.Element) ([]Result, error) {
+	var (
+		list    []Result
+		rules   []string
+		pattern string
+		context string
+		rule    string
+		fired   = make(map[string]int)
+	)
+	lookup := func(ident, test string) int {
+		for j := range list {
+			if rules[j] == rule && list[j].Ident == ident && list[j].Test == test {
+				return j
+			}
+		}
+		return -1
+	}
+	for i, n := range out.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		switch el.LocalName() {
+		case "active-pattern":
+			pattern, _ = getAttribute(el, "id")
+			if pattern == "" {
+				pattern, _ = getAttribute(el, "name")
+			}
+			if pattern == "" {
+				pattern = fmt.Sprintf("pattern-%d", i+1)
+			}
+			context, rule = "", ""
+		case "fired-rule":
+			context, _ = getAttribute(el, "context")
+			ident, _ := getAttribute(el, "id")
+			rule = strings.Join([]string{pattern, ident, context}, "\x00")
+			fired[rule]++
+			for _, a := range s.asserts[ident] {
+				if lookup(a.Ident, a.Source) >= 0 {
+					continue
+				}
+				res := Result{
+					Pattern: pattern,
+					Ident:   a.Ident,
+					Context: context,
+					Test:    a.Source,
+					Flag:    a.Flag,
+					Severe:  a.Flag == LevelFatal,
+					Report:  a.Report,
+					Message: a.Message,
+				}
+				list = append(list, res)
+				rules = append(rules, rule)
+			}
+		case "failed-assert", "successful-report":
+			var (
+				ident, _ = getAttribute(el, "id")
+				test, _  = getAttribute(el, "test")
+				loc, _   = getAttribute(el, "location")
+				text     = svrlText(el)
+			)
+			ix := lookup(ident, test)
+			if ix < 0 {
+				res := Result{
+					Pattern: pattern,
+					Ident:   ident,
+					Context: context,
+					Test:    test,
+					Report:  el.LocalName() == "successful-report",
+				}
+				if res.Flag, _ = getAttribute(el, "flag"); res.Flag == "" {
+					res.Flag, _ = getAttribute(el, "role")
+				}
+				res.Severe = res.Flag == LevelFatal
+				list = append(list, res)
+				rules = append(rules, rule)
+				ix = len(list) - 1
+			}
+			if list[ix].Fail == 0 {
+				list[ix].Message = text
+			}
+			list[ix].Fail++
+			list[ix].Locations = append(list[ix].Locations, loc)
+			list[ix].Details = append(list[ix].Details, text)
+			if seq, err := s.eval.Find(loc, node); err == nil && !seq.Empty() {
+				list[ix].Nodes = append(list[ix].Nodes, seq[0].Node())
+			}
+		}
+	}
+	for i := range list {
+		list[i].Total = max(fired[rules[i]], list[i].Fail)
+		list[i].Pass = list[i].Total - list[i].Fail
+	}
+	return list, nil
+}
+
+// compiledAsserts collects the asserts declared by a compiled schema grouped
+// by the identifier of the rule that fires them, so that passing asserts are
+// also reported when running it.
+func compiledAsserts(root *xml.Element) map[string][]*Assert {
+	asserts := make(map[string][]*Assert)
+	var walk func(*xml.Element)
+	walk = func(el *xml.Element) {
+		ix := slices.IndexFunc(el.Nodes, func(n xml.Node) bool {
+			c, ok := n.(*xml.Element)
+			return ok && c.Uri == svrlNS && c.LocalName() == "fired-rule"
+		})
+		if ix >= 0 {
+			ident := svrlProperty(el.Nodes[ix].(*xml.Element), "id")
+			for _, c := range el.Nodes[ix+1:] {
+				collectAsserts(c, func(a *Assert) {
+					asserts[ident] = append(asserts[ident], a)
+				})
+			}
+			return
+		}
+		for _, c := range el.Nodes {
+			if c, ok := c.(*xml.Element); ok {
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+	return asserts
+}
+
+func collectAsserts(node xml.Node, yield func(*Assert)) {
+	el, ok := node.(*xml.Element)
+	if !ok {
+		return
+	}
+	if el.Uri == svrlNS && (el.LocalName() == "failed-assert" || el.LocalName() == "successful-report") {
+		a := Assert{
+			Ident:  svrlProperty(el, "id"),
+			Source: svrlProperty(el, "test"),
+			Flag:   svrlProperty(el, "flag"),
+			Report: el.LocalName() == "successful-report",
+		}
+		if a.Flag == "" {
+			a.Flag = svrlProperty(el, "role")
+		}
+		for _, c := range el.Nodes {
+			if c, ok := c.(*xml.Element); ok && c.Uri == svrlNS && c.LocalName() == "text" {
+				a.Message = strings.Join(strings.Fields(c.Value()), " ")
+			}
+		}
+		yield(&a)
+		return
+	}
+	for _, c := range el.Nodes {
+		collectAsserts(c, yield)
+	}
+}
+
+// svrlProperty returns the value of an attribute of a svrl element of a
+// compiled schema given either as a literal attribute or as a static
+// xsl:attribute instruction.
+func svrlProperty(el *xml.Element, name string) string {
+	if value, err := getAttribute(el, name); err == nil {
+		return value
+	}
+	for _, c := range el.Nodes {
+		c, ok := c.(*xml.Element)
+		if !ok || c.Uri != xslNS || c.LocalName() != "attribute" {
+			continue
+		}
+		if ident, _ := getAttribute(c, "name"); ident == name {
+			return c.Value()
+		}
+	}
+	return ""
+}
+
+func (s *Schema) activePatterns(phase string) ([]*Pattern, error) {
+	if phase == "" {
+		return s.patterns, nil
+	}
+	names, ok := s.phases[phase]
+	if !ok {
+		return nil, fmt.Errorf("%s: phase not defined", phase)
+	}
+	var list []*Pattern
+	for _, p := range s.patterns {
+		if slices.Contains(names, p.Ident) {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+// compileRule creates the template of a rule in the mode of its pattern. The
+// priority follows the order of the rules so that a node is only checked by
+// the first rule of the pattern matching it.
+func (s *Schema) compileRule(p *Pattern, r *Rule, ident, mode string, priority int) *xml.Element {
+	each := xslElement("template")
+	each.SetAttribute(xml.NewAttribute(xml.LocalName("match"), s.ruleMatch(r.Context)))
+	each.SetAttribute(xml.NewAttribute(xml.LocalName("mode"), mode))
+	each.SetAttribute(xml.NewAttribute(xml.LocalName("priority"), strconv.Itoa(priority)))
+
+	fired := svrlElement("fired-rule")
+	fired.SetAttribute(xml.NewAttribute(xml.LocalName("id"), ident))
+	fired.Append(xslAttribute("context", r.Context))
+	each.Append(fired)
+
+	for _, v := range slices.Concat(p.lets, r.lets) {
+		each.Append(xslVariable(v))
+	}
+
+	for _, t := range r.Tests {
+		var (
+			test = fmt.Sprintf("not(%s)", t.Source)
+			name = "failed-assert"
+		)
+		if t.Report {
+			test, name = t.Source, "successful-report"
+		}
+		cond := xslElement("if")
+		cond.SetAttribute(xml.NewAttribute(xml.LocalName("test"), test))
+
+		failed := svrlElement(name)
+		failed.Append(xslAttribute("id", t.Ident))
+		failed.Append(xslAttribute("test", t.Source))
+		if t.Flag != "" {
+			failed.Append(xslAttribute("flag", t.Flag))
+		}
+		call := xslElement("call-template")
+		call.SetAttribute(xml.NewAttribute(xml.LocalName("name"), locationName))
+		value := xslElement("value-of")
+		value.SetAttribute(xml.NewAttribute(xml.LocalName("separator"), ""))
+		value.Append(call)
+		location := xslElement("attribute")
+		location.SetAttribute(xml.NewAttribute(xml.LocalName("name"), "location"))
+		location.Append(value)
+		failed.Append(location)
+
+		failed.Append(compileMessage(t))
+
+		cond.Append(failed)
+		each.Append(cond)
+	}
+	each.Append(xslApply("@*|node()", mode))
+	return each
+}
+
+// compileTraversal creates the templates of a pattern mode visiting the nodes
+// not matched by any of its rules.
+func compileTraversal(mode string) []*xml.Element {
+	var list []*xml.Element
+	for _, match := range []string{"/", "*", "@*|text()"} {
+		tpl := xslElement("template")
+		tpl.SetAttribute(xml.NewAttribute(xml.LocalName("match"), match))
+		tpl.SetAttribute(xml.NewAttribute(xml.LocalName("mode"), mode))
+		tpl.SetAttribute(xml.NewAttribute(xml.LocalName("priority"), "-2"))
+		if match != "@*|text()" {
+			tpl.Append(xslApply("@*|node()", mode))
+		}
+		list = append(list, tpl)
+	}
+	return list
+}
+
+func compileMessage(t *Assert) *xml.Element {
+	text := svrlElement("text")
+	if !t.dynamic() {
+		text.Append(xslText(strings.Join(strings.Fields(t.Message), " ")))
+		return text
+	}
+	for _, p := range t.parts {
+		if p.expr == nil {
+			text.Append(xslText(p.text))
+			continue
+		}
+		value := xslElement("value-of")
+		value.SetAttribute(xml.NewAttribute(xml.LocalName("select"), p.source))
+		text.Append(value)
+	}
+	return text
+}
+
+func compileLocation() *xml.Element {
+	tpl := xslElement("template")
+	tpl.SetAttribute(xml.NewAttribute(xml.LocalName("name"), locationName))
+
+	call := xslElement("call-template")
+	call.SetAttribute(xml.NewAttribute(xml.LocalName("name"), locationName))
+	each := xslElement("for-each")
+	each.SetAttribute(xml.NewAttribute(xml.LocalName("select"), ".."))
+	each.Append(call)
+	cond := xslElement("if")
+	cond.SetAttribute(xml.NewAttribute(xml.LocalName("test"), "parent::*"))
+	cond.Append(each)
+	tpl.Append(cond)
+
+	name := xslElement("variable")
+	name.SetAttribute(xml.NewAttribute(xml.LocalName("name"), "name"))
+	name.SetAttribute(xml.NewAttribute(xml.LocalName("select"), "name()"))
+	tpl.Append(name)
+
+	value := xslElement("value-of")
+	value.SetAttribute(xml.NewAttribute(xml.LocalName("select"), "concat('/', name(), '[', count(preceding-sibling::*[name() = $name]) + 1, ']')"))
+	tpl.Append(value)
+	return tpl
+}
+
+func ruleIdent(p *Pattern, index int) string {
+	return fmt.Sprintf("%s-%d", p.Ident, index+1)
+}
+
+func patternMode(index int) string {
+	return fmt.Sprintf("M%d", index+1)
+}
+
+func (s *Schema) ruleMatch(context string) string {
+	if s.xslMode() {
+		return context
+	}
+	var list []string
+	for _, part := range splitUnion(context) {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "/") {
+			part = "/" + part
+		}
+		list = append(list, part)
+	}
+	return strings.Join(list, " | ")
+}
+
+func (s *Schema) collectResults(node xml.Node, patterns []*Pattern, out *xml.Element) ([]Result, error) {
+	var (
+		list    []Result
+		pattern = -1
+		rule    = -1
+		results = make(map[*Rule][]Result)
+	)
+	for _, n := range out.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		switch el.LocalName() {
+		case "active-pattern":
+			pattern++
+			rule = -1
+			if pattern >= len(patterns) {
+				return nil, fmt.Errorf("unexpected active pattern in svrl output")
+			}
+		case "fired-rule":
+			if pattern < 0 {
+				return nil, fmt.Errorf("fired rule outside of active pattern")
+			}
+			ident, _ := getAttribute(el, "id")
+			rule = -1
+			for i := range patterns[pattern].Rules {
+				if ruleIdent(patterns[pattern], i) == ident {
+					rule = i
+					break
+				}
+			}
+			if rule < 0 {
+				return nil, fmt.Errorf("%s: unknown rule in svrl output", ident)
+			}
+			r := patterns[pattern].Rules[rule]
+			if _, ok := results[r]; !ok {
+				for _, t := range r.Tests {
+					res := Result{
+						Pattern: patterns[pattern].Ident,
+						Ident:   t.Ident,
+						Context: r.Context,
+						Test:    t.Source,
+						Flag:    t.Flag,
+						Severe:  t.Flag == LevelFatal,
+						Report:  t.Report,
+						Message: t.Message,
+					}
+					results[r] = append(results[r], res)
+				}
+			}
+			for i := range results[r] {
+				results[r][i].Total++
+			}
+		case "failed-assert", "successful-report":
+			if rule < 0 {
+				return nil, fmt.Errorf("%s outside of fired rule", el.LocalName())
+			}
+			var (
+				r        = patterns[pattern].Rules[rule]
+				ident, _ = getAttribute(el, "id")
+				loc, _   = getAttribute(el, "location")
+			)
+			ix := slices.IndexFunc(results[r], func(res Result) bool {
+				return res.Ident == ident
+			})
+			if ix < 0 {
+				return nil, fmt.Errorf("%s: unknown assert in svrl output", ident)
+			}
+			results[r][ix].Fail++
+			results[r][ix].Locations = append(results[r][ix].Locations, loc)
+			results[r][ix].Details = append(results[r][ix].Details, svrlText(el))
+			if seq, err := s.eval.Find(loc, node); err == nil && !seq.Empty() {
+				results[r][ix].Nodes = append(results[r][ix].Nodes, seq[0].Node())
+			}
+		}
+	}
+	for _, p := range patterns {
+		for _, r := range p.Rules {
+			for _, res := range results[r] {
+				res.Pass = res.Total - res.Fail
+				list = append(list, res)
+			}
+		}
+	}
+	return list, nil
+}
+
+func svrlText(el *xml.Element) string {
+	for _, c := range el.Nodes {
+		if c.Type() == xml.TypeElement && c.LocalName() == "text" {
+			return strings.Join(strings.Fields(c.Value()), " ")
+		}
+	}
+	return ""
+}
+
+func splitUnion(str string) []string {
+	var (
+		list  []string
+		depth int
+		quote rune
+		last  int
+	)
+	for i, c := range str {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			list = append(list, str[last:i])
+			last = i + 1
+		}
+	}
+	return append(list, str[last:])
+}
+
+func xslElement(name string) *xml.Element {
+	return xml.NewElement(xml.ExpandedName(name, xslPrefix, xslNS))
+}
+
+func xslApply(selector, mode string) *xml.Element {
+	el := xslElement("apply-templates")
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("select"), selector))
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("mode"), mode))
+	return el
+}
+
+func xslVariable(v letValue) *xml.Element {
+	el := xslElement("variable")
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("name"), v.ident))
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("select"), v.source))
+	return el
+}
+
+func xslAttribute(name, value string) *xml.Element {
+	el := xslElement("attribute")
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("name"), name))
+	el.Append(xslText(value))
+	return el
+}
+
+func xslText(value string) *xml.Element {
+	el := xslElement("text")
+	el.Append(xml.NewText(value))
+	return el
+}
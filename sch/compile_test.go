@@ -0,0 +1,237 @@
+package sch
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const compileSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron" defaultPhase="items">
+	<phase id="items">
+		<active pattern="item"/>
+	</phase>
+	<let name="max" value="2"/>
+	<pattern id="item">
+		<rule context="//item">
+			<let name="qty" value="number(@qty)"/>
+			<assert id="qty" flag="fatal" test="$qty > 0">quantity of <value-of select="@name"/> should be positive</assert>
+			<assert id="name" flag="warning" test="string-length(@name) > 0">name missing</assert>
+		</rule>
+	</pattern>
+	<pattern id="root">
+		<rule context="/root">
+			<assert id="count" flag="warning" test="count(item) &lt;= $max">too many items</assert>
+			<report id="empty" flag="warning" test="count(item) = 0">no items</report>
+		</rule>
+	</pattern>
+</schema>`
+
+var compileDocuments = []string{
+	`<root><item name="a" qty="1"/><item name="b" qty="2"/></root>`,
+	`<root><item name="a" qty="0"/><item qty="2"/><item name="c" qty="-1"/></root>`,
+	`<root/>`,
+}
+
+func TestRunXSLT(t *testing.T) {
+	schema, err := New(strings.NewReader(compileSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	for _, str := range compileDocuments {
+		for _, phase := range []string{"", "#ALL", "items", "unknown"} {
+			t.Run(phase+str, func(t *testing.T) {
+				doc, err := xml.ParseString(str)
+				if err != nil {
+					t.Fatalf("fail to parse document: %s", err)
+				}
+				want, err := schema.RunPhase(phase, doc)
+				if err != nil {
+					t.Fatalf("fail to validate document: %s", err)
+				}
+				got, err := schema.RunPhaseXSLT(phase, doc)
+				if err != nil {
+					t.Fatalf("fail to validate document with xslt: %s", err)
+				}
+				compareResults(t, got, want)
+				compareFailures(t, got, want)
+			})
+		}
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	schema, err := New(strings.NewReader(compileSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	want := []Result{
+		{Pattern: "root", Context: "/root", Test: "count(item) <= $max", Pass: 1, Total: 1},
+		{Pattern: "root", Context: "/root", Test: "count(item) = 0", Report: true, Fail: 1, Total: 1},
+	}
+	for _, run := range []func(string, xml.Node) ([]Result, error){schema.RunPhase, schema.RunPhaseXSLT} {
+		doc, err := xml.ParseString(compileDocuments[2])
+		if err != nil {
+			t.Fatalf("fail to parse document: %s", err)
+		}
+		got, err := run("#ALL", doc)
+		if err != nil {
+			t.Fatalf("fail to validate document: %s", err)
+		}
+		compareResults(t, got, want)
+		if got[1].Message != "no items" {
+			t.Errorf("report message mismatched! want %q, got %q", "no items", got[1].Message)
+		}
+	}
+}
+
+const firstRuleSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+	<pattern id="item">
+		<rule context="/root/item[@special]">
+			<assert id="special" flag="warning" test="@special = 'yes'">invalid special</assert>
+		</rule>
+		<rule context="//item">
+			<assert id="qty" flag="fatal" test="@qty > 0">invalid quantity</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+func TestRunFirstMatchingRule(t *testing.T) {
+	schema, err := New(strings.NewReader(firstRuleSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(`<root><item special="yes"/><item qty="1"/><item qty="0"/></root>`)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	want := []Result{
+		{Pattern: "item", Context: "/root/item[@special]", Test: "@special = 'yes'", Pass: 1, Total: 1},
+		{Pattern: "item", Context: "//item", Test: "@qty > 0", Pass: 1, Fail: 1, Total: 2},
+	}
+	got, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	compareResults(t, got, want)
+
+	got, err = schema.RunXSLT(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document with xslt: %s", err)
+	}
+	compareResults(t, got, want)
+}
+
+func TestCompiledSchema(t *testing.T) {
+	schema, err := New(strings.NewReader(compileSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	for _, phase := range []string{"", "#ALL"} {
+		sheet, err := schema.Compile(phase)
+		if err != nil {
+			t.Fatalf("fail to compile schema: %s", err)
+		}
+		compiled, err := New(strings.NewReader(xml.WriteNode(sheet)))
+		if err != nil {
+			t.Fatalf("fail to load compiled schema: %s", err)
+		}
+		if !compiled.Compiled() {
+			t.Fatalf("schema should be detected as compiled")
+		}
+		for _, str := range compileDocuments {
+			t.Run(phase+str, func(t *testing.T) {
+				doc, err := xml.ParseString(str)
+				if err != nil {
+					t.Fatalf("fail to parse document: %s", err)
+				}
+				want, err := schema.RunPhase(phase, doc)
+				if err != nil {
+					t.Fatalf("fail to validate document: %s", err)
+				}
+				got, err := compiled.Run(doc)
+				if err != nil {
+					t.Fatalf("fail to validate document with compiled schema: %s", err)
+				}
+				compareResults(t, got, want)
+				compareFailures(t, got, want)
+			})
+		}
+	}
+}
+
+const phaseStylesheet = `<xsl:stylesheet version="3.0"
+	xmlns:xsl="http://www.w3.org/1999/XSL/Transform"
+	xmlns:svrl="http://purl.oclc.org/dsdl/svrl">
+	<xsl:param name="phase" select="'#DEFAULT'"/>
+	<xsl:template match="/">
+		<svrl:schematron-output>
+			<svrl:active-pattern id="items"/>
+			<xsl:for-each select="//item">
+				<svrl:fired-rule id="item" context="item"/>
+				<xsl:if test="$phase = 'strict' and not(@name)">
+					<svrl:failed-assert id="name" test="@name" location="/root/item">
+						<svrl:text>name missing</svrl:text>
+					</svrl:failed-assert>
+				</xsl:if>
+			</xsl:for-each>
+		</svrl:schematron-output>
+	</xsl:template>
+</xsl:stylesheet>`
+
+func TestCompiledSchemaPhase(t *testing.T) {
+	schema, err := New(strings.NewReader(phaseStylesheet))
+	if err != nil {
+		t.Fatalf("fail to load compiled schema: %s", err)
+	}
+	doc, err := xml.ParseString(`<root><item/></root>`)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	var (
+		wg    sync.WaitGroup
+		fails = map[string]int{"strict": 1, "": 0, "lax": 0}
+	)
+	for range 4 {
+		for phase, want := range fails {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := schema.RunPhase(phase, doc)
+				if err != nil {
+					t.Errorf("%s: fail to validate document: %s", phase, err)
+					return
+				}
+				var got int
+				for _, r := range res {
+					got += r.Fail
+				}
+				if got != want {
+					t.Errorf("%s: failures mismatched! want %d, got %d", phase, want, got)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func compareFailures(t *testing.T, got, want []Result) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("results mismatched! want %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Pattern != w.Pattern || g.Ident != w.Ident || g.Fail != w.Fail {
+			t.Errorf("result %d: failure mismatched! want %s/%s (%d), got %s/%s (%d)", i, w.Pattern, w.Ident, w.Fail, g.Pattern, g.Ident, g.Fail)
+		}
+		if !slices.Equal(g.Locations, w.Locations) {
+			t.Errorf("result %d: locations mismatched! want %v, got %v", i, w.Locations, g.Locations)
+		}
+		if !slices.Equal(g.Details, w.Details) {
+			t.Errorf("result %d: messages mismatched! want %q, got %q", i, w.Details, g.Details)
+		}
+	}
+}
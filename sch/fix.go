@@ -0,0 +1,452 @@
+package sch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+var ErrFix = errors.New("fix not available")
+
+const (
+	opAdd     = "add"
+	opDelete  = "delete"
+	opReplace = "replace"
+)
+
+const (
+	posBefore     = "before"
+	posAfter      = "after"
+	posFirstChild = "first-child"
+	posLastChild  = "last-child"
+)
+
+const (
+	typeElement   = "element"
+	typeAttribute = "attribute"
+	typeComment   = "comment"
+	typeKeep      = "keep"
+)
+
+type Fix struct {
+	Ident      string
+	Title      string
+	Operations []*Operation
+
+	when xpath.Expr
+}
+
+func (f *Fix) Apply(node xml.Node) error {
+	if f.when != nil {
+		seq, err := f.when.Find(node)
+		if err != nil {
+			return err
+		}
+		if !seq.True() {
+			return nil
+		}
+	}
+	for _, op := range f.Operations {
+		if err := op.Apply(node); err != nil {
+			return fmt.Errorf("%s: %w", f.Ident, err)
+		}
+	}
+	return nil
+}
+
+type Operation struct {
+	Kind     string
+	Position string
+	NodeType string
+	Target   xml.QName
+	Match    xpath.Expr
+	Select   xpath.Expr
+	Content  []xml.Node
+}
+
+func (o *Operation) Apply(node xml.Node) error {
+	targets, err := o.targets(node)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		switch o.Kind {
+		case opDelete:
+			err = deleteNode(t)
+		case opAdd:
+			err = o.add(t)
+		case opReplace:
+			err = o.replace(t)
+		default:
+			err = fmt.Errorf("%s: unsupported operation", o.Kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *Operation) targets(node xml.Node) ([]xml.Node, error) {
+	if o.Match == nil {
+		return []xml.Node{node}, nil
+	}
+	seq, err := o.Match.Find(node)
+	if err != nil {
+		return nil, err
+	}
+	var list []xml.Node
+	for i := range seq {
+		if n := seq[i].Node(); n != nil {
+			list = append(list, n)
+		}
+	}
+	return list, nil
+}
+
+func (o *Operation) add(target xml.Node) error {
+	nodes, err := o.build(target)
+	if err != nil {
+		return err
+	}
+	if o.NodeType == typeAttribute {
+		el, ok := target.(*xml.Element)
+		if !ok {
+			return fmt.Errorf("attribute can only be added to element")
+		}
+		return setAttributes(el, nodes)
+	}
+	switch o.Position {
+	case posBefore:
+		parent, ok := target.Parent().(*xml.Element)
+		if !ok {
+			return xml.ErrElement
+		}
+		for _, n := range nodes {
+			if err := parent.InsertBefore(n, target); err != nil {
+				return err
+			}
+		}
+	case posAfter:
+		parent, ok := target.Parent().(*xml.Element)
+		if !ok {
+			return xml.ErrElement
+		}
+		for _, n := range slices.Backward(nodes) {
+			if err := parent.InsertAfter(n, target); err != nil {
+				return err
+			}
+		}
+	case posFirstChild, posLastChild, "":
+		el, ok := target.(*xml.Element)
+		if !ok {
+			return xml.ErrElement
+		}
+		for i, n := range nodes {
+			if o.Position == posFirstChild {
+				if err := el.Insert(n, i); err != nil {
+					return err
+				}
+			} else {
+				el.Append(n)
+			}
+		}
+	default:
+		return fmt.Errorf("%s: invalid position", o.Position)
+	}
+	return nil
+}
+
+func (o *Operation) replace(target xml.Node) error {
+	nodes, err := o.build(target)
+	if err != nil {
+		return err
+	}
+	if a, ok := target.(*xml.Attribute); ok {
+		el, ok := a.Parent().(*xml.Element)
+		if !ok {
+			return xml.ErrElement
+		}
+		if err := el.RemoveAttribute(a.QName); err != nil {
+			return err
+		}
+		return setAttributes(el, nodes)
+	}
+	if len(nodes) == 0 {
+		return deleteNode(target)
+	}
+	parent, ok := target.Parent().(*xml.Element)
+	if !ok {
+		return xml.ErrElement
+	}
+	for _, n := range nodes {
+		if err := parent.InsertBefore(n, target); err != nil {
+			return err
+		}
+	}
+	return xml.Detach(target)
+}
+
+func (o *Operation) build(target xml.Node) ([]xml.Node, error) {
+	content, err := o.content(target)
+	if err != nil {
+		return nil, err
+	}
+	switch o.NodeType {
+	case "":
+		return content, nil
+	case typeKeep:
+		switch x := target.(type) {
+		case *xml.Element:
+			return []xml.Node{elementNode(x.QName, content)}, nil
+		case *xml.Attribute:
+			return []xml.Node{attributeNode(x.QName, content)}, nil
+		default:
+			return nil, fmt.Errorf("node type keep expects element or attribute")
+		}
+	case typeElement:
+		return []xml.Node{elementNode(o.Target, content)}, nil
+	case typeAttribute:
+		return []xml.Node{attributeNode(o.Target, content)}, nil
+	case typeComment:
+		return []xml.Node{xml.NewComment(textOf(content))}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported node type", o.NodeType)
+	}
+}
+
+func (o *Operation) content(target xml.Node) ([]xml.Node, error) {
+	if o.Select == nil {
+		var list []xml.Node
+		for _, n := range o.Content {
+			if c := copyNode(n); c != nil {
+				list = append(list, c)
+			}
+		}
+		return list, nil
+	}
+	seq, err := o.Select.Find(target)
+	if err != nil {
+		return nil, err
+	}
+	var list []xml.Node
+	for i := range seq {
+		node := seq[i].Node()
+		if !seq[i].Atomic() {
+			node = copyNode(node)
+		}
+		if node != nil {
+			list = append(list, node)
+		}
+	}
+	return list, nil
+}
+
+func elementNode(name xml.QName, content []xml.Node) *xml.Element {
+	el := xml.NewElement(name)
+	for _, n := range content {
+		el.Append(n)
+	}
+	return el
+}
+
+func attributeNode(name xml.QName, content []xml.Node) *xml.Attribute {
+	a := xml.NewAttribute(name, textOf(content))
+	return &a
+}
+
+func setAttributes(el *xml.Element, nodes []xml.Node) error {
+	for _, n := range nodes {
+		a, ok := n.(*xml.Attribute)
+		if !ok {
+			return fmt.Errorf("attribute expected")
+		}
+		if err := el.SetAttribute(*a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteNode(node xml.Node) error {
+	if a, ok := node.(*xml.Attribute); ok {
+		el, ok := a.Parent().(*xml.Element)
+		if !ok {
+			return xml.ErrElement
+		}
+		return el.RemoveAttribute(a.QName)
+	}
+	return xml.Detach(node)
+}
+
+func textOf(nodes []xml.Node) string {
+	var str strings.Builder
+	for _, n := range nodes {
+		str.WriteString(n.Value())
+	}
+	return str.String()
+}
+
+func copyNode(node xml.Node) xml.Node {
+	switch n := node.(type) {
+	case xml.Cloner:
+		return n.Clone()
+	case *xml.Comment:
+		return xml.NewComment(n.Value())
+	case *xml.Attribute:
+		a := xml.NewAttribute(n.QName, n.Value())
+		return &a
+	default:
+		return nil
+	}
+}
+
+func collectFixes(sch *Schema, root *xml.Element) error {
+	for _, n := range root.Nodes {
+		if n.Type() != xml.TypeElement || n.LocalName() != "fixes" {
+			continue
+		}
+		el, err := getElementFromNode(n)
+		if err != nil {
+			return err
+		}
+		if err := loadFixes(sch, el, sch.fixes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadFixes(sch *Schema, el *xml.Element, fixes map[string]*Fix) error {
+	for _, n := range el.Nodes {
+		if n.Type() != xml.TypeElement {
+			continue
+		}
+		sub, err := getElementFromNode(n)
+		if err != nil {
+			return err
+		}
+		switch n.LocalName() {
+		case "fix":
+			err = loadFixFromElement(sch, sub, fixes)
+		case "group":
+			err = loadFixes(sch, sub, fixes)
+		default:
+			err = fmt.Errorf("unexpected element %s in fixes", n.LocalName())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadFixFromElement(sch *Schema, el *xml.Element, fixes map[string]*Fix) error {
+	ident, err := getAttribute(el, "id")
+	if err != nil {
+		return err
+	}
+	fix := Fix{
+		Ident: ident,
+	}
+	if query, err := getAttribute(el, "use-when"); err == nil {
+		if fix.when, err = sch.eval.Create(query); err != nil {
+			return err
+		}
+	}
+	for _, n := range el.Nodes {
+		if n.Type() != xml.TypeElement {
+			continue
+		}
+		sub, err := getElementFromNode(n)
+		if err != nil {
+			return err
+		}
+		switch name := n.LocalName(); name {
+		case "description":
+			for _, c := range sub.Nodes {
+				if c.LocalName() == "title" {
+					fix.Title = strings.TrimSpace(c.Value())
+				}
+			}
+		case opAdd, opDelete, opReplace:
+			op, err := loadOperationFromElement(sch, sub)
+			if err != nil {
+				return err
+			}
+			fix.Operations = append(fix.Operations, op)
+		default:
+			return fmt.Errorf("%s: unsupported fix element", name)
+		}
+	}
+	fixes[ident] = &fix
+	return nil
+}
+
+func loadOperationFromElement(sch *Schema, el *xml.Element) (*Operation, error) {
+	op := Operation{
+		Kind: el.LocalName(),
+	}
+	if query, err := getAttribute(el, "match"); err == nil {
+		if op.Match, err = sch.eval.Create(query); err != nil {
+			return nil, err
+		}
+	} else if op.Kind == opDelete {
+		return nil, err
+	}
+	if op.Kind == opDelete {
+		return &op, nil
+	}
+	op.Position, _ = getAttribute(el, "position")
+	op.NodeType, _ = getAttribute(el, "node-type")
+	if target, err := getAttribute(el, "target"); err == nil {
+		if op.Target, err = sch.resolveName(target); err != nil {
+			return nil, err
+		}
+	} else if op.NodeType != "" && op.NodeType != typeKeep && op.NodeType != typeComment {
+		return nil, err
+	}
+	if query, err := getAttribute(el, "select"); err == nil {
+		if op.Select, err = sch.eval.Create(query); err != nil {
+			return nil, err
+		}
+	} else {
+		op.Content = slices.Clone(el.Nodes)
+	}
+	return &op, nil
+}
+
+func (s *Schema) resolveName(name string) (xml.QName, error) {
+	qn, err := xml.ParseName(name)
+	if err != nil || qn.Space == "" {
+		return qn, err
+	}
+	ix := slices.IndexFunc(s.namespaces, func(ns xml.NS) bool {
+		return ns.Prefix == qn.Space
+	})
+	if ix < 0 {
+		return qn, fmt.Errorf("%s: namespace not declared", qn.Space)
+	}
+	qn.Uri = s.namespaces[ix].Uri
+	return qn, nil
+}
+
+func resolveFixes(rule *Rule, fixes map[string]*Fix) error {
+	for _, t := range rule.Tests {
+		for _, ident := range t.fixes {
+			f, ok := rule.fixes[ident]
+			if !ok {
+				f, ok = fixes[ident]
+			}
+			if !ok {
+				return fmt.Errorf("%s: %w", ident, ErrFix)
+			}
+			t.Fixes = append(t.Fixes, f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package sch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const fixSchema = `<sch:schema xmlns:sch="http://purl.oclc.org/dsdl/schematron" xmlns:sqf="http://www.schematron-quickfix.com/validator/process">
+	<sqf:fixes>
+		<sqf:fix id="delete-item">
+			<sqf:description>
+				<sqf:title>delete item</sqf:title>
+			</sqf:description>
+			<sqf:delete match="."/>
+		</sqf:fix>
+	</sqf:fixes>
+	<sch:pattern id="items">
+		<sch:rule context="//item">
+			<sch:assert id="qty" flag="fatal" test="@qty" sqf:fix="add-qty delete-item replace-item append-note note-before skip">quantity missing</sch:assert>
+			<sqf:fix id="add-qty">
+				<sqf:description>
+					<sqf:title>add quantity</sqf:title>
+				</sqf:description>
+				<sqf:add node-type="attribute" target="qty">1</sqf:add>
+			</sqf:fix>
+			<sqf:fix id="replace-item">
+				<sqf:replace match="." node-type="element" target="entry" select="string(@name)"/>
+			</sqf:fix>
+			<sqf:fix id="append-note">
+				<sqf:add position="last-child"><note>check</note></sqf:add>
+			</sqf:fix>
+			<sqf:fix id="note-before">
+				<sqf:add position="before" node-type="comment">missing quantity</sqf:add>
+			</sqf:fix>
+			<sqf:fix id="skip" use-when="false()">
+				<sqf:delete match="."/>
+			</sqf:fix>
+		</sch:rule>
+	</sch:pattern>
+</sch:schema>`
+
+const fixDocument = `<root><item name="a" qty="2"/><item name="b"/></root>`
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		Fix  string
+		Want string
+	}{
+		{
+			Fix:  "add-qty",
+			Want: `<root><item name="a" qty="2"/><item name="b" qty="1"/></root>`,
+		},
+		{
+			Fix:  "delete-item",
+			Want: `<root><item name="a" qty="2"/></root>`,
+		},
+		{
+			Fix:  "replace-item",
+			Want: `<root><item name="a" qty="2"/><entry>b</entry></root>`,
+		},
+		{
+			Fix:  "append-note",
+			Want: `<root><item name="a" qty="2"/><item name="b"><note>check</note></item></root>`,
+		},
+		{
+			Fix:  "note-before",
+			Want: `<root><item name="a" qty="2"/><!--missing quantity--><item name="b"/></root>`,
+		},
+		{
+			Fix:  "skip",
+			Want: fixDocument,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Fix, func(t *testing.T) {
+			res, doc := runFixSchema(t)
+			if !slices.Contains(res.Fixes, c.Fix) {
+				t.Fatalf("%s: fix not available in %v", c.Fix, res.Fixes)
+			}
+			if err := res.Fix(c.Fix); err != nil {
+				t.Fatalf("fail to apply fix: %s", err)
+			}
+			var (
+				str strings.Builder
+				ws  = xml.NewWriter(&str)
+			)
+			ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+			if err := ws.Write(doc); err != nil {
+				t.Fatalf("fail to write document: %s", err)
+			}
+			if got := str.String(); got != c.Want {
+				t.Errorf("document mismatched!\nwant: %s\ngot:  %s", c.Want, got)
+			}
+		})
+	}
+}
+
+func TestFixUndefined(t *testing.T) {
+	res, _ := runFixSchema(t)
+	if err := res.Fix("unknown"); !errors.Is(err, ErrFix) {
+		t.Errorf("expected %v, got %v", ErrFix, err)
+	}
+}
+
+func runFixSchema(t *testing.T) (Result, *xml.Document) {
+	t.Helper()
+	schema, err := New(strings.NewReader(fixSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(fixDocument)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	results, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	if len(results) != 1 || results[0].Fail != 1 {
+		t.Fatalf("expected one failed assertion, got %+v", results)
+	}
+	return results[0], doc
+}
@@ -0,0 +1,86 @@
+package sch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+const functionSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+	<ns prefix="ext" uri="http://example.com/ext"/>
+	<pattern id="vat">
+		<rule context="//company">
+			<assert id="vat" flag="fatal" test="ext:valid-vat(@vat)">invalid vat number</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+func TestRegisterFunction(t *testing.T) {
+	schema, err := New(strings.NewReader(functionSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	known := map[string]bool{
+		"BE0123456789": true,
+	}
+	var calls int
+	valid := func(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+		calls++
+		seq, err := args[0].Find(ctx.Node)
+		if err != nil || seq.Empty() {
+			return xpath.Singleton(false), err
+		}
+		return xpath.Singleton(known[seq[0].Node().Value()]), nil
+	}
+	if err := schema.RegisterFunction("ext:valid-vat", valid); err != nil {
+		t.Fatalf("fail to register function: %s", err)
+	}
+	doc, err := xml.ParseString(`<companies><company vat="BE0123456789"/><company vat="FR000"/><company/></companies>`)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	res, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	if len(res) != 1 {
+		t.Fatalf("results mismatched! want 1, got %d", len(res))
+	}
+	if res[0].Pass != 1 || res[0].Fail != 2 {
+		t.Errorf("counts mismatched! want 1 pass and 2 failures, got %d/%d", res[0].Pass, res[0].Fail)
+	}
+	if calls != 3 {
+		t.Errorf("function should be called once per context node! got %d", calls)
+	}
+}
+
+func TestRegisterFunctionErrors(t *testing.T) {
+	schema, err := New(strings.NewReader(functionSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	noop := func(xpath.Context, []xpath.Expr) (xpath.Sequence, error) {
+		return xpath.Singleton(true), nil
+	}
+	if err := schema.RegisterFunction("other:fn", noop); err == nil {
+		t.Errorf("undeclared prefix should be rejected")
+	}
+	if err := schema.RegisterFunction("local-fn", noop); err != nil {
+		t.Errorf("unprefixed function should be accepted: %s", err)
+	}
+
+	sheet, err := schema.Compile("")
+	if err != nil {
+		t.Fatalf("fail to compile schema: %s", err)
+	}
+	compiled, err := New(strings.NewReader(xml.WriteNode(sheet)))
+	if err != nil {
+		t.Fatalf("fail to load compiled schema: %s", err)
+	}
+	if err := compiled.RegisterFunction("ext:valid-vat", noop); !errors.Is(err, ErrCompiled) {
+		t.Errorf("expected %v, got %v", ErrCompiled, err)
+	}
+}
@@ -0,0 +1,139 @@
+package sch
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const openMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type MetricsHook interface {
+	Validated(time.Duration, []Result, error)
+}
+
+type MetricsHookFunc func(time.Duration, []Result, error)
+
+func (h MetricsHookFunc) Validated(elapsed time.Duration, results []Result, err error) {
+	h(elapsed, results, err)
+}
+
+type Metrics struct {
+	mu sync.Mutex
+
+	documents int
+	errors    int
+	failures  map[string]int
+
+	buckets []float64
+	counts  []int
+	sum     float64
+	count   int
+}
+
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultBuckets)
+}
+
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Metrics{
+		failures: make(map[string]int),
+		buckets:  buckets,
+		counts:   make([]int, len(buckets)),
+	}
+}
+
+func (m *Metrics) Validated(elapsed time.Duration, results []Result, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.documents++
+	if err != nil {
+		m.errors++
+	}
+	for _, r := range results {
+		if r.Fail == 0 {
+			continue
+		}
+		ident := r.Ident
+		if ident == "" {
+			ident = r.Test
+		}
+		m.failures[ident] += r.Fail
+	}
+	secs := elapsed.Seconds()
+	for i, b := range m.buckets {
+		if secs <= b {
+			m.counts[i]++
+		}
+	}
+	m.sum += secs
+	m.count++
+}
+
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var str strings.Builder
+	str.WriteString("# TYPE sch_documents_validated counter\n")
+	str.WriteString("# HELP sch_documents_validated Number of documents validated.\n")
+	fmt.Fprintf(&str, "sch_documents_validated_total %d\n", m.documents)
+
+	str.WriteString("# TYPE sch_validation_errors counter\n")
+	str.WriteString("# HELP sch_validation_errors Number of validations that failed to complete.\n")
+	fmt.Fprintf(&str, "sch_validation_errors_total %d\n", m.errors)
+
+	str.WriteString("# TYPE sch_assertion_failures counter\n")
+	str.WriteString("# HELP sch_assertion_failures Number of failed assertions by assertion id.\n")
+	for _, ident := range slices.Sorted(maps.Keys(m.failures)) {
+		fmt.Fprintf(&str, "sch_assertion_failures_total{assert=\"%s\"} %d\n", escapeLabel(ident), m.failures[ident])
+	}
+
+	str.WriteString("# TYPE sch_validation_duration_seconds histogram\n")
+	str.WriteString("# UNIT sch_validation_duration_seconds seconds\n")
+	str.WriteString("# HELP sch_validation_duration_seconds Time spent validating a document.\n")
+	for i, b := range m.buckets {
+		fmt.Fprintf(&str, "sch_validation_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(b), m.counts[i])
+	}
+	fmt.Fprintf(&str, "sch_validation_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(&str, "sch_validation_duration_seconds_sum %s\n", formatFloat(m.sum))
+	fmt.Fprintf(&str, "sch_validation_duration_seconds_count %d\n", m.count)
+	str.WriteString("# EOF\n")
+
+	n, err := io.WriteString(w, str.String())
+	return int64(n), err
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	m.WriteTo(w)
+}
+
+func escapeLabel(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\\`)
+	str = strings.ReplaceAll(str, `"`, `\"`)
+	return strings.ReplaceAll(str, "\n", `\n`)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
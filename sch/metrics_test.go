@@ -0,0 +1,131 @@
+package sch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetricsWithBuckets([]float64{1, 0.1})
+	m.Validated(50*time.Millisecond, []Result{
+		{Ident: "qty", Fail: 2},
+		{Ident: "name", Pass: 1},
+		{Test: `@id = "x"`, Fail: 1},
+	}, nil)
+	m.Validated(500*time.Millisecond, []Result{{Ident: "qty", Fail: 1}}, nil)
+	m.Validated(2*time.Second, nil, errors.New("failure"))
+
+	want := `# TYPE sch_documents_validated counter
+# HELP sch_documents_validated Number of documents validated.
+sch_documents_validated_total 3
+# TYPE sch_validation_errors counter
+# HELP sch_validation_errors Number of validations that failed to complete.
+sch_validation_errors_total 1
+# TYPE sch_assertion_failures counter
+# HELP sch_assertion_failures Number of failed assertions by assertion id.
+sch_assertion_failures_total{assert="@id = \"x\""} 1
+sch_assertion_failures_total{assert="qty"} 3
+# TYPE sch_validation_duration_seconds histogram
+# UNIT sch_validation_duration_seconds seconds
+# HELP sch_validation_duration_seconds Time spent validating a document.
+sch_validation_duration_seconds_bucket{le="0.1"} 1
+sch_validation_duration_seconds_bucket{le="1"} 2
+sch_validation_duration_seconds_bucket{le="+Inf"} 3
+sch_validation_duration_seconds_sum 2.55
+sch_validation_duration_seconds_count 3
+# EOF
+`
+	var str strings.Builder
+	if _, err := m.WriteTo(&str); err != nil {
+		t.Fatalf("fail to write metrics: %s", err)
+	}
+	if got := str.String(); got != want {
+		t.Errorf("metrics mismatched!\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	schema, err := New(strings.NewReader(phaseSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(phaseDocument)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	var (
+		calls   int
+		results []Result
+	)
+	schema.SetHook(MetricsHookFunc(func(_ time.Duration, res []Result, err error) {
+		calls++
+		results = res
+	}))
+	want, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	if _, err := schema.RunXSLT(doc); err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	if calls != 2 {
+		t.Errorf("hook should be called once per validation! got %d", calls)
+	}
+	compareResults(t, results, want)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := NewMetrics()
+	m.Validated(time.Millisecond, nil, nil)
+
+	tests := []struct {
+		Method string
+		Code   int
+		Body   bool
+	}{
+		{
+			Method: http.MethodGet,
+			Code:   http.StatusOK,
+			Body:   true,
+		},
+		{
+			Method: http.MethodHead,
+			Code:   http.StatusOK,
+		},
+		{
+			Method: http.MethodPost,
+			Code:   http.StatusMethodNotAllowed,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Method, func(t *testing.T) {
+			var (
+				req = httptest.NewRequest(c.Method, "/metrics", nil)
+				rec = httptest.NewRecorder()
+			)
+			m.ServeHTTP(rec, req)
+			if rec.Code != c.Code {
+				t.Fatalf("status mismatched! want %d, got %d", c.Code, rec.Code)
+			}
+			if c.Code != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != openMetricsType {
+				t.Errorf("content type mismatched! got %s", ct)
+			}
+			body := rec.Body.String()
+			if c.Body && !strings.Contains(body, "sch_documents_validated_total 1\n") {
+				t.Errorf("metrics missing from body: %s", body)
+			}
+			if !c.Body && body != "" {
+				t.Errorf("body should be empty! got %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package sch
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrStopped = errors.New("validation stopped")
+
+type FailPolicy struct {
+	MaxErrors   int
+	StopOnFatal bool
+	SkipPattern bool
+}
+
+func FailFast() FailPolicy {
+	return FailPolicy{
+		MaxErrors: 1,
+	}
+}
+
+func (s *Schema) SetFailPolicy(policy FailPolicy) {
+	s.policy = policy
+}
+
+type runner struct {
+	FailPolicy
+	errors  int
+	stopped bool
+	skipped map[string]string
+}
+
+func (s *Schema) runner() *runner {
+	return &runner{
+		FailPolicy: s.policy,
+		skipped:    make(map[string]string),
+	}
+}
+
+func (r *runner) record(res Result) {
+	if res.Fail == 0 {
+		return
+	}
+	r.errors += res.Fail
+	if r.MaxErrors > 0 && r.errors >= r.MaxErrors {
+		r.stopped = true
+	}
+	if r.StopOnFatal && res.Severe {
+		r.stopped = true
+	}
+	if _, ok := r.skipped[res.Pattern]; r.SkipPattern && !ok {
+		r.skipped[res.Pattern] = res.Context
+	}
+}
+
+func (r *runner) skip(pattern, context string) bool {
+	ctx, ok := r.skipped[pattern]
+	return ok && ctx != context
+}
+
+func (r *runner) err() error {
+	if !r.stopped {
+		return nil
+	}
+	return fmt.Errorf("%w: %d error(s)", ErrStopped, r.errors)
+}
+
+func (s *Schema) applyPolicy(results []Result, err error) ([]Result, error) {
+	if err != nil {
+		return results, err
+	}
+	var (
+		run  = s.runner()
+		list []Result
+	)
+	for _, res := range results {
+		if run.skip(res.Pattern, res.Context) {
+			continue
+		}
+		list = append(list, res)
+		if run.record(res); run.stopped {
+			break
+		}
+	}
+	return list, run.err()
+}
@@ -0,0 +1,102 @@
+package sch
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const policySchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+	<pattern id="items">
+		<rule context="//item">
+			<assert id="item-id" flag="warning" test="@id">item without id</assert>
+			<assert id="item-price" flag="fatal" test="@price &gt; 0">item with invalid price</assert>
+		</rule>
+		<rule context="/order">
+			<assert id="order-ref" flag="error" test="@ref">order without reference</assert>
+		</rule>
+	</pattern>
+	<pattern id="order">
+		<rule context="/order">
+			<assert id="order-date" flag="error" test="@date">order without date</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+const policyDocument = `<order>
+	<item price="10"/>
+	<item id="2" price="0"/>
+	<item price="5"/>
+</order>`
+
+func TestFailPolicy(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Policy FailPolicy
+		Want   []string
+		Err    error
+	}{
+		{
+			Name: "default",
+			Want: []string{"item-id", "item-price", "order-ref", "order-date"},
+		},
+		{
+			Name:   "fail-fast",
+			Policy: FailFast(),
+			Want:   []string{"item-id"},
+			Err:    ErrStopped,
+		},
+		{
+			Name:   "max-errors",
+			Policy: FailPolicy{MaxErrors: 4},
+			Want:   []string{"item-id", "item-price", "order-ref"},
+			Err:    ErrStopped,
+		},
+		{
+			Name:   "max-errors-not-reached",
+			Policy: FailPolicy{MaxErrors: 10},
+			Want:   []string{"item-id", "item-price", "order-ref", "order-date"},
+		},
+		{
+			Name:   "stop-on-fatal",
+			Policy: FailPolicy{StopOnFatal: true},
+			Want:   []string{"item-id", "item-price"},
+			Err:    ErrStopped,
+		},
+		{
+			Name:   "skip-pattern",
+			Policy: FailPolicy{SkipPattern: true},
+			Want:   []string{"item-id", "item-price", "order-date"},
+		},
+	}
+	doc, err := xml.ParseString(policyDocument)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			schema, err := New(strings.NewReader(policySchema))
+			if err != nil {
+				t.Fatalf("fail to parse schema: %s", err)
+			}
+			schema.SetFailPolicy(c.Policy)
+			res, err := schema.Run(doc)
+			if c.Err == nil && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.Err != nil && !errors.Is(err, c.Err) {
+				t.Fatalf("expected %v, got %v", c.Err, err)
+			}
+			var got []string
+			for _, r := range res {
+				got = append(got, r.Ident)
+			}
+			if !slices.Equal(got, c.Want) {
+				t.Errorf("results mismatched! want %q, got %q", c.Want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package sch
+
+import (
+	"encoding/csv"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const svrlNS = "http://purl.oclc.org/dsdl/svrl"
+
+type Reporter interface {
+	Report(io.Writer, string, []Result) error
+}
+
+type ReporterFunc func(io.Writer, string, []Result) error
+
+func (r ReporterFunc) Report(w io.Writer, file string, results []Result) error {
+	return r(w, file, results)
+}
+
+var csvHeader = []string{
+	"file",
+	"pattern",
+	"assert",
+	"flag",
+	"total",
+	"pass",
+	"fail",
+	"message",
+}
+
+func CsvReporter() Reporter {
+	var header bool
+	fn := func(w io.Writer, file string, results []Result) error {
+		err := reportCsv(w, file, results, !header)
+		header = true
+		return err
+	}
+	return ReporterFunc(fn)
+}
+
+func SvrlReporter(schema *Schema) Reporter {
+	fn := func(w io.Writer, file string, results []Result) error {
+		return reportSvrl(w, schema, file, results)
+	}
+	return ReporterFunc(fn)
+}
+
+func reportCsv(w io.Writer, file string, results []Result, header bool) error {
+	ws := csv.NewWriter(w)
+	if header {
+		if err := ws.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, r := range results {
+		row := []string{
+			file,
+			r.Pattern,
+			r.Ident,
+			r.Flag,
+			strconv.Itoa(r.Total),
+			strconv.Itoa(r.Pass),
+			strconv.Itoa(r.Fail),
+			strings.Join(strings.Fields(r.Message), " "),
+		}
+		if err := ws.Write(row); err != nil {
+			return err
+		}
+	}
+	ws.Flush()
+	return ws.Error()
+}
+
+func reportSvrl(w io.Writer, schema *Schema, file string, results []Result) error {
+	root := svrlElement("schematron-output")
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("svrl", "xmlns"), svrlNS))
+	if schema != nil && schema.Title != "" {
+		root.SetAttribute(xml.NewAttribute(xml.LocalName("title"), schema.Title))
+	}
+	var (
+		patterns []string
+		rules    []string
+	)
+	for _, r := range results {
+		if !slices.Contains(patterns, r.Pattern) {
+			patterns = append(patterns, r.Pattern)
+			rules = rules[:0]
+
+			el := svrlElement("active-pattern")
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("id"), r.Pattern))
+			if file != "" {
+				el.SetAttribute(xml.NewAttribute(xml.LocalName("document"), file))
+			}
+			root.Append(el)
+		}
+		if !slices.Contains(rules, r.Context) {
+			rules = append(rules, r.Context)
+
+			el := svrlElement("fired-rule")
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("context"), r.Context))
+			root.Append(el)
+		}
+		name := "failed-assert"
+		if r.Report {
+			name = "successful-report"
+		}
+		for i, loc := range r.Locations {
+			el := svrlElement(name)
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("id"), r.Ident))
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("test"), r.Test))
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("location"), loc))
+			if i < len(r.Positions) && !r.Positions[i].Zero() {
+				el.SetAttribute(xml.NewAttribute(xml.LocalName("line"), strconv.Itoa(r.Positions[i].Line)))
+				el.SetAttribute(xml.NewAttribute(xml.LocalName("column"), strconv.Itoa(r.Positions[i].Column)))
+			}
+			if r.Flag != "" {
+				el.SetAttribute(xml.NewAttribute(xml.LocalName("flag"), r.Flag))
+			}
+			msg := r.Message
+			if i < len(r.Details) {
+				msg = r.Details[i]
+			}
+			text := svrlElement("text")
+			text.Append(xml.NewText(strings.Join(strings.Fields(msg), " ")))
+			el.Append(text)
+			root.Append(el)
+		}
+	}
+	ws := xml.NewWriter(w)
+	return ws.Write(xml.NewDocument(root))
+}
+
+func svrlElement(name string) *xml.Element {
+	return xml.NewElement(xml.QualifiedName(name, "svrl"))
+}
@@ -0,0 +1,125 @@
+package sch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+var reportResults = []Result{
+	{
+		Pattern:   "item",
+		Ident:     "qty",
+		Context:   "//item",
+		Test:      "@qty > 0",
+		Flag:      LevelFatal,
+		Total:     2,
+		Pass:      1,
+		Fail:      1,
+		Message:   "quantity should\n\tbe positive",
+		Locations: []string{"/root[1]/item[1]"},
+		Details:   []string{"quantity of item 1 should be positive"},
+	},
+	{
+		Pattern: "root",
+		Ident:   "count",
+		Context: "/root",
+		Test:    "count(item) < 2",
+		Flag:    LevelWarn,
+		Total:   1,
+		Pass:    1,
+		Message: "too many items",
+	},
+	{
+		Pattern:   "root",
+		Ident:     "empty",
+		Context:   "/root",
+		Test:      "count(item) = 0",
+		Flag:      LevelWarn,
+		Report:    true,
+		Total:     1,
+		Fail:      1,
+		Message:   "no items",
+		Locations: []string{"/root[1]"},
+	},
+}
+
+func TestCsvReporter(t *testing.T) {
+	var (
+		str strings.Builder
+		rp  = CsvReporter()
+	)
+	if err := rp.Report(&str, "a.xml", reportResults); err != nil {
+		t.Fatalf("fail to report results: %s", err)
+	}
+	if err := rp.Report(&str, "b.xml", reportResults[1:]); err != nil {
+		t.Fatalf("fail to report results: %s", err)
+	}
+	want := []string{
+		"file,pattern,assert,flag,total,pass,fail,message",
+		"a.xml,item,qty,fatal,2,1,1,quantity should be positive",
+		"a.xml,root,count,warning,1,1,0,too many items",
+		"a.xml,root,empty,warning,1,0,1,no items",
+		"b.xml,root,count,warning,1,1,0,too many items",
+		"b.xml,root,empty,warning,1,0,1,no items",
+	}
+	got := strings.Split(strings.TrimSpace(str.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("rows mismatched! want %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d mismatched! want %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestSvrlReporter(t *testing.T) {
+	var str strings.Builder
+	if err := SvrlReporter(&Schema{Title: "stock"}).Report(&str, "a.xml", reportResults); err != nil {
+		t.Fatalf("fail to report results: %s", err)
+	}
+	doc, err := xml.ParseString(str.String())
+	if err != nil {
+		t.Fatalf("fail to parse svrl output: %s", err)
+	}
+	root, ok := doc.Root().(*xml.Element)
+	if !ok || root.LocalName() != "schematron-output" {
+		t.Fatalf("schematron-output element expected")
+	}
+	if title, _ := root.GetAttribute("title"); title != "stock" {
+		t.Errorf("title mismatched! want stock, got %s", title)
+	}
+	var names []string
+	for _, n := range root.Nodes {
+		names = append(names, n.LocalName())
+	}
+	want := []string{"active-pattern", "fired-rule", "failed-assert", "active-pattern", "fired-rule", "successful-report"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("elements mismatched! want %v, got %v", want, names)
+	}
+	failed := root.Nodes[2].(*xml.Element)
+	tests := map[string]string{
+		"id":       "qty",
+		"test":     "@qty > 0",
+		"location": "/root[1]/item[1]",
+		"flag":     LevelFatal,
+	}
+	for name, value := range tests {
+		if got, _ := failed.GetAttribute(name); got != value {
+			t.Errorf("%s: attribute mismatched! want %q, got %q", name, value, got)
+		}
+	}
+	if got := failed.Value(); got != "quantity of item 1 should be positive" {
+		t.Errorf("text mismatched! got %q", got)
+	}
+	report := root.Nodes[5].(*xml.Element)
+	if id, _ := report.GetAttribute("id"); id != "empty" || report.Value() != "no items" {
+		t.Errorf("successful report mismatched! got %s: %q", id, report.Value())
+	}
+	pattern := root.Nodes[0].(*xml.Element)
+	if got, _ := pattern.GetAttribute("document"); got != "a.xml" {
+		t.Errorf("document mismatched! want a.xml, got %q", got)
+	}
+}
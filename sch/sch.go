@@ -4,24 +4,57 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
+	"github.com/midbel/codecs/xslt"
 )
 
-var ErrAssert = errors.New("assertion error")
+var (
+	ErrAssert   = errors.New("assertion error")
+	ErrCompiled = errors.New("schema compiled to xslt")
+)
 
 type Result struct {
-	Pattern string
-	Ident   string
-	Message string
-	Severe  bool
-	Pass    int
-	Fail    int
-	Total   int
+	Pattern   string
+	Ident     string
+	Message   string
+	Context   string
+	Test      string
+	Flag      string
+	Severe    bool
+	Report    bool
+	Pass      int
+	Fail      int
+	Total     int
+	Locations []string
+	Positions []xml.Position
+	Details   []string
+	Nodes     []xml.Node
+	Fixes     []string
+
+	fixes []*Fix
+}
+
+func (r Result) Fix(ident string) error {
+	ix := slices.IndexFunc(r.fixes, func(f *Fix) bool {
+		return f.Ident == ident
+	})
+	if ix < 0 {
+		return fmt.Errorf("%s: %w", ident, ErrFix)
+	}
+	for _, n := range r.Nodes {
+		if err := r.fixes[ix].Apply(n); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type PatternInfo struct {
@@ -35,21 +68,42 @@ const (
 	LevelWarn  = "warning"
 )
 
+const (
+	phaseAll     = "#ALL"
+	phaseDefault = "#DEFAULT"
+)
+
 type Schema struct {
 	Title string
 
-	phases   map[string][]string
-	patterns []*Pattern
-	mode     string
-
-	eval *xpath.Evaluator
+	phases     map[string][]string
+	patterns   []*Pattern
+	namespaces []xml.NS
+	mode       string
+
+	defaultPhase  string
+	lets          []letValue
+	abstracts     map[string]*xml.Element
+	abstractRules map[string]*xml.Element
+	fixes         map[string]*Fix
+
+	eval    *xpath.Evaluator
+	sheet   *xslt.Stylesheet
+	source  *xml.Document
+	asserts map[string][]*Assert
+	hook    MetricsHook
+	policy  FailPolicy
 }
 
 func Default() *Schema {
 	s := Schema{
-		phases: make(map[string][]string),
-		eval:   xpath.NewEvaluator(),
+		phases:        make(map[string][]string),
+		abstracts:     make(map[string]*xml.Element),
+		abstractRules: make(map[string]*xml.Element),
+		fixes:         make(map[string]*Fix),
+		eval:          xpath.NewEvaluator(),
 	}
+	s.eval.EnableFuncSet(aglPrefix)
 	return &s
 }
 
@@ -59,11 +113,11 @@ func Open(file string) (*Schema, error) {
 		return nil, err
 	}
 	defer r.Close()
-	return New(r)
+	return parseSchema(r, filepath.Dir(file))
 }
 
 func New(r io.Reader) (*Schema, error) {
-	return parseSchema(r)
+	return parseSchema(r, "")
 }
 
 func (s *Schema) Patterns() []PatternInfo {
@@ -85,34 +139,188 @@ func (s *Schema) Patterns() []PatternInfo {
 }
 
 func (s *Schema) Run(node xml.Node) ([]Result, error) {
-	return s.runPhases(node, nil)
+	return s.RunPhase("", node)
+}
+
+func (s *Schema) SetHook(hook MetricsHook) {
+	s.hook = hook
+}
+
+// RegisterFunction makes fn callable from the rules and asserts of the schema.
+// A prefixed name must use a prefix declared with sch:ns. The function can be
+// called any number of times and in any order: it should be deterministic and
+// free of side effects, otherwise results of Run and Revalidate may disagree.
+func (s *Schema) RegisterFunction(name string, fn xpath.BuiltinFunc) error {
+	if s.Compiled() {
+		return fmt.Errorf("%s: %w", name, ErrCompiled)
+	}
+	qn, err := s.resolveName(name)
+	if err != nil {
+		return err
+	}
+	s.eval.RegisterFuncNS(qn, fn)
+	return nil
 }
 
 func (s *Schema) RunPhase(phase string, node xml.Node) ([]Result, error) {
-	if phase == "" {
-		return s.Run(node)
+	now := time.Now()
+	res, err := s.runPhase(phase, node)
+	s.observe(now, res, err)
+	return res, err
+}
+
+func (s *Schema) observe(now time.Time, results []Result, err error) {
+	if s.hook == nil {
+		return
 	}
-	phases, ok := s.phases[phase]
-	if !ok {
+	s.hook.Validated(time.Since(now), results, err)
+}
+
+func (s *Schema) runPhase(phase string, node xml.Node) ([]Result, error) {
+	if s.Compiled() {
+		return s.runCompiled(phase, node)
+	}
+	patterns, err := s.phasePatterns(phase)
+	if err != nil {
+		return nil, err
+	}
+	return s.runPatterns(node, patterns)
+}
+
+func (s *Schema) phasePatterns(phase string) ([]*Pattern, error) {
+	phase = s.resolvePhase(phase)
+	if _, ok := s.phases[phase]; phase != "" && !ok {
 		return nil, nil
 	}
-	return s.runPhases(node, phases)
+	return s.activePatterns(phase)
 }
 
-func (s *Schema) runPhases(node xml.Node, phases []string) ([]Result, error) {
-	var list []Result
-	for _, p := range s.patterns {
-		ok := slices.Contains(phases, p.Ident)
-		if !ok && len(phases) > 0 {
-			continue
-		}
-		res, err := p.Run(node)
+func (s *Schema) runPatterns(node xml.Node, patterns []*Pattern) ([]Result, error) {
+	var (
+		run  = s.runner()
+		list []Result
+	)
+	for _, p := range patterns {
+		res, err := p.run(node, run)
 		if err != nil {
 			return nil, err
 		}
 		list = slices.Concat(list, res)
+		if run.stopped {
+			break
+		}
+	}
+	return list, run.err()
+}
+
+func (s *Schema) Revalidate(node xml.Node, prev []Result, paths []string) ([]Result, error) {
+	var changed []xml.Node
+	for _, p := range paths {
+		seq, err := s.eval.Find(p, node)
+		if err != nil {
+			return nil, err
+		}
+		for i := range seq {
+			changed = append(changed, seq[i].Node())
+		}
+	}
+	return s.RevalidateNodes(node, prev, changed)
+}
+
+// RevalidateNodes re-runs the patterns having at least one rule that matches a
+// changed node, one of its ancestors or one of its descendants, and reuses the
+// results of prev for the other patterns. Affected patterns are run entirely,
+// so the fail policy applies as with Run.
+//
+// The dependencies of the asserts are not analyzed: a rule whose asserts (or
+// context predicates) read other parts of the document, e.g. a context like
+// /root/header[@total = sum(/root/item/@price)], keeps its previous results
+// when only the items change. Use Run when the schema has such rules.
+func (s *Schema) RevalidateNodes(node xml.Node, prev []Result, changed []xml.Node) ([]Result, error) {
+	if s.Compiled() {
+		return s.Run(node)
+	}
+	patterns, err := s.phasePatterns("")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		affected = affectedNodes(changed)
+		run      = s.runner()
+		list     []Result
+	)
+	for _, p := range patterns {
+		if p.intersects(affected) {
+			res, err := p.run(node, run)
+			if err != nil {
+				return nil, err
+			}
+			list = slices.Concat(list, res)
+		} else {
+			for i := range prev {
+				if prev[i].Pattern != p.Ident || run.skip(p.Ident, prev[i].Context) {
+					continue
+				}
+				list = append(list, prev[i])
+				if run.record(prev[i]); run.stopped {
+					break
+				}
+			}
+		}
+		if run.stopped {
+			break
+		}
+	}
+	return list, run.err()
+}
+
+func affectedNodes(changed []xml.Node) []xml.Node {
+	var (
+		list []xml.Node
+		seen = make(map[xml.Node]struct{})
+	)
+	add := func(n xml.Node) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		list = append(list, n)
+	}
+	var descend func(xml.Node)
+	descend = func(n xml.Node) {
+		add(n)
+		el, ok := n.(*xml.Element)
+		if !ok {
+			return
+		}
+		for i := range el.Attrs {
+			add(&el.Attrs[i])
+		}
+		for _, c := range el.Nodes {
+			descend(c)
+		}
+	}
+	for _, n := range changed {
+		descend(n)
+		for p := n.Parent(); p != nil; p = p.Parent() {
+			add(p)
+		}
+	}
+	return list
+}
+
+func (s *Schema) resolvePhase(phase string) string {
+	switch phase {
+	case "", phaseDefault:
+		if s.defaultPhase == phaseAll {
+			return ""
+		}
+		return s.defaultPhase
+	case phaseAll:
+		return ""
+	default:
+		return phase
 	}
-	return list, nil
 }
 
 func (s *Schema) xslMode() bool {
@@ -123,40 +331,97 @@ type Pattern struct {
 	Ident string
 	Title string
 	Rules []*Rule
+
+	lets []letValue
 }
 
 func (p *Pattern) Run(node xml.Node) ([]Result, error) {
-	var list []Result
+	return p.run(node, &runner{})
+}
+
+func (p *Pattern) intersects(nodes []xml.Node) bool {
+	return slices.ContainsFunc(p.Rules, func(r *Rule) bool {
+		return r.intersects(nodes)
+	})
+}
+
+// run checks the rules of the pattern in order. As in schematron, a node
+// matched by several rules is only checked by the first one.
+func (p *Pattern) run(node xml.Node, run *runner) ([]Result, error) {
+	var (
+		list []Result
+		seen = make(map[xml.Node]struct{})
+	)
 	for _, r := range p.Rules {
-		res, err := r.Run(node)
+		if run.skip(p.Ident, r.Context) {
+			break
+		}
+		res, err := r.run(node, p.Ident, run, seen)
 		if err != nil {
 			return nil, err
 		}
-		for i := range res {
-			res[i].Pattern = p.Ident
-		}
 		list = slices.Concat(list, res)
+		if run.stopped {
+			break
+		}
 	}
 	return list, nil
 }
 
 type Rule struct {
-	Query xpath.Expr
-	Tests []*Assert
+	Context string
+	Query   xpath.Expr
+	Tests   []*Assert
+
+	lets  []letValue
+	fixes map[string]*Fix
+	match xslt.Matcher
+}
+
+func (r *Rule) intersects(nodes []xml.Node) bool {
+	if r.match == nil {
+		return true
+	}
+	return slices.ContainsFunc(nodes, r.match.Match)
 }
 
 func (r *Rule) Run(node xml.Node) ([]Result, error) {
+	return r.run(node, "", &runner{}, nil)
+}
+
+func (r *Rule) run(node xml.Node, pattern string, run *runner, seen map[xml.Node]struct{}) ([]Result, error) {
 	seq, err := r.Query.Find(node)
-	if err != nil || seq.Empty() {
+	if err != nil {
 		return nil, err
 	}
+	if seen != nil {
+		seq = slices.DeleteFunc(seq, func(i xpath.Item) bool {
+			if _, ok := seen[i.Node()]; ok {
+				return true
+			}
+			seen[i.Node()] = struct{}{}
+			return false
+		})
+	}
+	if seq.Empty() {
+		return nil, nil
+	}
 	var list []Result
 	for _, t := range r.Tests {
 		res := Result{
+			Pattern: pattern,
 			Ident:   t.Ident,
+			Context: r.Context,
+			Test:    t.Source,
+			Flag:    t.Flag,
 			Severe:  t.Flag == LevelFatal,
+			Report:  t.Report,
 			Total:   seq.Len(),
 			Message: t.Message,
+			fixes:   t.Fixes,
+		}
+		for _, f := range t.Fixes {
+			res.Fixes = append(res.Fixes, f.Ident)
 		}
 		for i := range seq {
 			err := t.Run(seq[i].Node())
@@ -166,10 +431,21 @@ func (r *Rule) Run(node xml.Node) ([]Result, error) {
 			if err == nil {
 				res.Pass++
 			} else {
+				msg, err := t.Format(seq[i].Node())
+				if err != nil {
+					return nil, err
+				}
 				res.Fail++
+				res.Locations = append(res.Locations, xml.PathOf(seq[i].Node(), xml.PathOptions{Indexed: true}))
+				res.Positions = append(res.Positions, xml.LocationOf(seq[i].Node()))
+				res.Details = append(res.Details, msg)
+				res.Nodes = append(res.Nodes, seq[i].Node())
 			}
 		}
 		list = append(list, res)
+		if run.record(res); run.stopped {
+			break
+		}
 	}
 	return list, nil
 }
@@ -177,8 +453,46 @@ func (r *Rule) Run(node xml.Node) ([]Result, error) {
 type Assert struct {
 	Ident   string
 	Flag    string
+	Source  string
 	Test    xpath.Expr
 	Message string
+	Fixes   []*Fix
+	// Report is set for report elements: they fire when their test is true
+	// instead of when it is false.
+	Report bool
+
+	fixes []string
+	parts []messagePart
+}
+
+func (r *Assert) Format(node xml.Node) (string, error) {
+	var list []string
+	for _, p := range r.parts {
+		if p.expr == nil {
+			list = append(list, p.text)
+			continue
+		}
+		seq, err := p.expr.Find(node)
+		if err != nil {
+			return "", err
+		}
+		for i := range seq {
+			list = append(list, seq[i].Node().Value())
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(list, " ")), " "), nil
+}
+
+func (r *Assert) dynamic() bool {
+	return slices.ContainsFunc(r.parts, func(p messagePart) bool {
+		return p.expr != nil
+	})
+}
+
+type messagePart struct {
+	text   string
+	source string
+	expr   xpath.Expr
 }
 
 func (r *Assert) Run(node xml.Node) error {
@@ -186,21 +500,21 @@ func (r *Assert) Run(node xml.Node) error {
 	if err != nil {
 		return err
 	}
-	if !seq.True() {
+	if seq.True() == r.Report {
 		return ErrAssert
 	}
 	return nil
 }
 
-func parseSchema(r io.Reader) (*Schema, error) {
+func parseSchema(r io.Reader, contextDir string) (*Schema, error) {
 	doc, err := xml.ParseReader(r)
 	if err != nil {
 		return nil, err
 	}
-	return createSchemaFromDocument(doc)
+	return createSchemaFromDocument(doc, contextDir)
 }
 
-func createSchemaFromDocument(doc *xml.Document) (*Schema, error) {
+func createSchemaFromDocument(doc *xml.Document, contextDir string) (*Schema, error) {
 	var (
 		sch  = Default()
 		root = doc.Root()
@@ -212,9 +526,21 @@ func createSchemaFromDocument(doc *xml.Document) (*Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	if isCompiledSchema(el) {
+		return createSchemaFromStylesheet(doc, contextDir)
+	}
 	if mode, err := getAttribute(el, "queryBinding"); err == nil {
 		sch.mode = mode
 	}
+	if phase, err := getAttribute(el, "defaultPhase"); err == nil {
+		sch.defaultPhase = phase
+	}
+	if err := collectAbstracts(sch, el); err != nil {
+		return nil, err
+	}
+	if err := collectFixes(sch, el); err != nil {
+		return nil, err
+	}
 	for _, n := range el.Nodes {
 		sub, err := getElementFromNode(n)
 		if err != nil {
@@ -225,13 +551,22 @@ func createSchemaFromDocument(doc *xml.Document) (*Schema, error) {
 			sch.Title = n.Value()
 		case "ns":
 			err = loadNsFromElement(sch, sub)
+		case "let":
+			var let letValue
+			if let, err = loadLetFromElement(sch, sub); err == nil {
+				sch.lets = append(sch.lets, let)
+			}
 		case "phase":
 			err = loadPhaseFromElement(sch, sub)
 			if err != nil {
 				return nil, err
 			}
 		case "pattern":
+			if isAbstract(sub) {
+				break
+			}
 			err = loadPatternFromElement(sch, sub)
+		case "rules", "fixes":
 		default:
 			return nil, fmt.Errorf("unexpected element %s", name)
 		}
@@ -255,6 +590,21 @@ func loadValueFromElement(sch *Schema, el *xml.Element) (string, xpath.Expr, err
 	return ident, expr, err
 }
 
+type letValue struct {
+	ident  string
+	source string
+}
+
+func loadLetFromElement(sch *Schema, el *xml.Element) (letValue, error) {
+	ident, expr, err := loadValueFromElement(sch, el)
+	if err != nil {
+		return letValue{}, err
+	}
+	sch.eval.Set(ident, expr)
+	source, _ := getAttribute(el, "value")
+	return letValue{ident: ident, source: source}, nil
+}
+
 func loadPatternFromElement(sch *Schema, el *xml.Element) error {
 	ident, err := getAttribute(el, "id")
 	if err != nil {
@@ -263,33 +613,176 @@ func loadPatternFromElement(sch *Schema, el *xml.Element) error {
 	pat := Pattern{
 		Ident: ident,
 	}
-
-	var ix int
-	if el.Nodes[ix].LocalName() == "title" {
-		ix++
+	if base, err := getAttribute(el, "is-a"); err == nil {
+		if el, err = instantiatePattern(sch, el, base); err != nil {
+			return err
+		}
 	}
-	for ; ix < len(el.Nodes); ix++ {
-		n := el.Nodes[ix]
-		if n.Type() == xml.TypeComment {
+
+	prev := sch.eval
+	sch.eval = prev.Sub()
+	defer func() {
+		sch.eval = prev
+	}()
+
+	for _, n := range el.Nodes {
+		if n.Type() != xml.TypeElement {
 			continue
 		}
-		if n.LocalName() != "rule" {
-			return fmt.Errorf("expected rule element instead of %s", n.LocalName())
-		}
 		sub, err := getElementFromNode(n)
 		if err != nil {
 			return err
 		}
-		rule, err := loadRuleFromElement(sub, sch)
+		switch n.LocalName() {
+		case "title", "param":
+		case "let":
+			var let letValue
+			if let, err = loadLetFromElement(sch, sub); err == nil {
+				pat.lets = append(pat.lets, let)
+			}
+		case "rule":
+			if isAbstract(sub) {
+				break
+			}
+			var rule *Rule
+			if rule, err = loadRuleFromElement(sub, sch); err == nil {
+				pat.Rules = append(pat.Rules, rule)
+			}
+		default:
+			err = fmt.Errorf("expected rule element instead of %s", n.LocalName())
+		}
 		if err != nil {
 			return err
 		}
-		pat.Rules = append(pat.Rules, rule)
 	}
 	sch.patterns = append(sch.patterns, &pat)
 	return nil
 }
 
+func isAbstract(el *xml.Element) bool {
+	abstract, _ := el.AttrBool("abstract", false)
+	return abstract
+}
+
+func collectAbstracts(sch *Schema, root *xml.Element) error {
+	collectRules := func(el *xml.Element) error {
+		for _, n := range el.Nodes {
+			sub, ok := n.(*xml.Element)
+			if !ok || sub.LocalName() != "rule" || !isAbstract(sub) {
+				continue
+			}
+			ident, err := getAttribute(sub, "id")
+			if err != nil {
+				return fmt.Errorf("abstract rule: %w", err)
+			}
+			sch.abstractRules[ident] = sub
+		}
+		return nil
+	}
+	for _, n := range root.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		switch el.LocalName() {
+		case "pattern":
+			if isAbstract(el) {
+				ident, err := getAttribute(el, "id")
+				if err != nil {
+					return fmt.Errorf("abstract pattern: %w", err)
+				}
+				sch.abstracts[ident] = el
+			}
+		case "rules":
+		default:
+			continue
+		}
+		if err := collectRules(el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func instantiatePattern(sch *Schema, el *xml.Element, base string) (*xml.Element, error) {
+	abstract, ok := sch.abstracts[base]
+	if !ok {
+		return nil, fmt.Errorf("%s: abstract pattern not defined", base)
+	}
+	params := make(map[string]string)
+	for _, n := range el.Nodes {
+		sub, ok := n.(*xml.Element)
+		if !ok || sub.LocalName() != "param" {
+			continue
+		}
+		name, err := getAttribute(sub, "name")
+		if err != nil {
+			return nil, err
+		}
+		value, err := getAttribute(sub, "value")
+		if err != nil {
+			return nil, err
+		}
+		params[name] = value
+	}
+	names := slices.Collect(maps.Keys(params))
+	slices.SortFunc(names, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	inst := abstract.Clone().(*xml.Element)
+	queue := []*xml.Element{inst}
+	for len(queue) > 0 {
+		curr := queue[0]
+		queue = queue[1:]
+		attrs := slices.Clone(curr.Attrs)
+		for i := range attrs {
+			attrs[i].Datum = replaceParams(attrs[i].Datum, names, params)
+		}
+		curr.SetAttributes(attrs)
+		for _, n := range curr.Nodes {
+			if sub, ok := n.(*xml.Element); ok {
+				queue = append(queue, sub)
+			}
+		}
+	}
+	return inst, nil
+}
+
+func replaceParams(str string, names []string, params map[string]string) string {
+	var (
+		buf  strings.Builder
+		rest = str
+	)
+	for {
+		ix := strings.IndexByte(rest, '$')
+		if ix < 0 {
+			buf.WriteString(rest)
+			break
+		}
+		buf.WriteString(rest[:ix])
+		rest = rest[ix+1:]
+
+		found := slices.IndexFunc(names, func(n string) bool {
+			if !strings.HasPrefix(rest, n) {
+				return false
+			}
+			return len(rest) == len(n) || !isNameChar(rest[len(n)])
+		})
+		if found < 0 {
+			buf.WriteByte('$')
+			continue
+		}
+		buf.WriteString(params[names[found]])
+		rest = rest[len(names[found]):]
+	}
+	return buf.String()
+}
+
+func isNameChar(b byte) bool {
+	return b == '-' || b == '_' || b == '.' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
 func loadRuleFromElement(el *xml.Element, sch *Schema) (*Rule, error) {
 	context, err := getAttribute(el, "context")
 	if err != nil {
@@ -300,29 +793,81 @@ func loadRuleFromElement(el *xml.Element, sch *Schema) (*Rule, error) {
 		return nil, err
 	}
 	rule := Rule{
-		Query: query,
+		Context: context,
+		Query:   query,
+		fixes:   make(map[string]*Fix),
+	}
+	if m, err := xslt.CompileMatch(context); err == nil {
+		rule.match = m
 	}
 	if sch.xslMode() {
 		rule.Query = xpath.FromRoot(rule.Query)
 	}
+
+	prev := sch.eval
+	sch.eval = prev.Sub()
+	defer func() {
+		sch.eval = prev
+	}()
+	if err := loadRuleBody(&rule, el, sch, nil); err != nil {
+		return nil, err
+	}
+	if err := resolveFixes(&rule, sch.fixes); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func loadRuleBody(rule *Rule, el *xml.Element, sch *Schema, seen []string) error {
 	for _, n := range el.Nodes {
-		if n.Type() == xml.TypeComment {
+		if n.Type() != xml.TypeElement {
 			continue
 		}
-		if n.LocalName() != "assert" {
-			return nil, fmt.Errorf("expected assert element instead of %s", n.LocalName())
-		}
 		sub, err := getElementFromNode(n)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		switch n.LocalName() {
+		case "let":
+			var let letValue
+			if let, err = loadLetFromElement(sch, sub); err == nil {
+				rule.lets = append(rule.lets, let)
+			}
+		case "assert", "report":
+			var ass *Assert
+			if ass, err = loadAssertFromElement(sub, sch); err == nil {
+				ass.Report = n.LocalName() == "report"
+				rule.Tests = append(rule.Tests, ass)
+			}
+		case "extends":
+			err = extendRule(rule, sub, sch, seen)
+		case "fix":
+			err = loadFixFromElement(sch, sub, rule.fixes)
+		case "group":
+			err = loadFixes(sch, sub, rule.fixes)
+		default:
+			err = fmt.Errorf("expected assert element instead of %s", n.LocalName())
 		}
-		ass, err := loadAssertFromElement(sub, sch)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rule.Tests = append(rule.Tests, ass)
 	}
-	return &rule, nil
+	return nil
+}
+
+func extendRule(rule *Rule, el *xml.Element, sch *Schema, seen []string) error {
+	ident, err := getAttribute(el, "rule")
+	if err != nil {
+		return err
+	}
+	if slices.Contains(seen, ident) {
+		return fmt.Errorf("%s: recursive rule extension", ident)
+	}
+	base, ok := sch.abstractRules[ident]
+	if !ok {
+		return fmt.Errorf("%s: abstract rule not defined", ident)
+	}
+	return loadRuleBody(rule, base, sch, append(seen, ident))
 }
 
 func loadAssertFromElement(el *xml.Element, sch *Schema) (*Assert, error) {
@@ -337,6 +882,7 @@ func loadAssertFromElement(el *xml.Element, sch *Schema) (*Assert, error) {
 	if err != nil {
 		return nil, err
 	}
+	ass.Source = query
 	ass.Test, err = sch.eval.Create(query)
 	if err != nil {
 		return nil, err
@@ -344,10 +890,70 @@ func loadAssertFromElement(el *xml.Element, sch *Schema) (*Assert, error) {
 	if ass.Flag, err = getAttribute(el, "flag"); err != nil {
 		return nil, err
 	}
+	if fixes, err := getAttribute(el, "fix"); err == nil {
+		ass.fixes = strings.Fields(fixes)
+	}
 	ass.Message = el.Value()
+	if ass.parts, err = loadMessageParts(sch, el); err != nil {
+		return nil, err
+	}
+	if ass.dynamic() {
+		var list []string
+		for _, p := range ass.parts {
+			if p.expr == nil {
+				list = append(list, p.text)
+			} else {
+				list = append(list, "{"+p.source+"}")
+			}
+		}
+		ass.Message = strings.Join(list, " ")
+	}
 	return &ass, nil
 }
 
+func loadMessageParts(sch *Schema, el *xml.Element) ([]messagePart, error) {
+	var parts []messagePart
+	for _, n := range el.Nodes {
+		switch n.Type() {
+		case xml.TypeText:
+			parts = append(parts, messagePart{text: n.Value()})
+			continue
+		case xml.TypeElement:
+		default:
+			continue
+		}
+		sub, err := getElementFromNode(n)
+		if err != nil {
+			return nil, err
+		}
+		var query string
+		switch n.LocalName() {
+		case "value-of":
+			if query, err = getAttribute(sub, "select"); err != nil {
+				return nil, err
+			}
+		case "name":
+			query = "name()"
+			if path, err := getAttribute(sub, "path"); err == nil {
+				query = fmt.Sprintf("name(%s)", path)
+			}
+		default:
+			parts = append(parts, messagePart{text: n.Value()})
+			continue
+		}
+		expr, err := sch.eval.Create(query)
+		if err != nil {
+			return nil, err
+		}
+		part := messagePart{
+			source: query,
+			expr:   expr,
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
 func loadNsFromElement(sch *Schema, el *xml.Element) error {
 	prefix, err := getAttribute(el, "prefix")
 	if err != nil {
@@ -358,6 +964,7 @@ func loadNsFromElement(sch *Schema, el *xml.Element) error {
 		return err
 	}
 	sch.eval.RegisterNS(prefix, uri)
+	sch.namespaces = append(sch.namespaces, xml.NS{Prefix: prefix, Uri: uri})
 	return nil
 }
 
@@ -367,6 +974,9 @@ func loadPhaseFromElement(sch *Schema, el *xml.Element) error {
 		return err
 	}
 	for _, n := range el.Nodes {
+		if n.Type() != xml.TypeElement {
+			continue
+		}
 		if n.LocalName() != "active" {
 			return fmt.Errorf("expected active element")
 		}
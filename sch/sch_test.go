@@ -0,0 +1,326 @@
+package sch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const phaseSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron" defaultPhase="items">
+	<phase id="items">
+		<active pattern="item"/>
+	</phase>
+	<pattern id="item">
+		<rule context="//item">
+			<assert id="qty" flag="fatal" test="@qty > 0">quantity should be positive</assert>
+		</rule>
+	</pattern>
+	<pattern id="root">
+		<rule context="/root">
+			<assert id="count" flag="warning" test="count(item) &lt; 2">too many items</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+const phaseDocument = `<root><item qty="0"/><item qty="2"/></root>`
+
+func TestRevalidate(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Path  string
+		Value string
+	}{
+		{
+			Name:  "fix-item",
+			Path:  "/root/item[1]",
+			Value: "5",
+		},
+		{
+			Name:  "break-item",
+			Path:  "/root/item[2]",
+			Value: "0",
+		},
+		{
+			Name:  "unchanged",
+			Path:  "/root/item[2]",
+			Value: "2",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			schema, err := New(strings.NewReader(phaseSchema))
+			if err != nil {
+				t.Fatalf("fail to parse schema: %s", err)
+			}
+			doc, err := xml.ParseString(phaseDocument)
+			if err != nil {
+				t.Fatalf("fail to parse document: %s", err)
+			}
+			prev, err := schema.Run(doc)
+			if err != nil {
+				t.Fatalf("fail to validate document: %s", err)
+			}
+			seq, err := schema.eval.Find(c.Path, doc)
+			if err != nil || seq.Len() != 1 {
+				t.Fatalf("%s: node not found", c.Path)
+			}
+			el := seq[0].Node().(*xml.Element)
+			el.SetAttribute(xml.NewAttribute(xml.LocalName("qty"), c.Value))
+
+			got, err := schema.Revalidate(doc, prev, []string{c.Path})
+			if err != nil {
+				t.Fatalf("fail to revalidate document: %s", err)
+			}
+			want, err := schema.Run(doc)
+			if err != nil {
+				t.Fatalf("fail to validate document: %s", err)
+			}
+			compareResults(t, got, want)
+		})
+	}
+}
+
+func TestRevalidatePolicy(t *testing.T) {
+	schema, err := New(strings.NewReader(policySchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(policyDocument)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	prev, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	schema.SetFailPolicy(FailPolicy{SkipPattern: true, MaxErrors: 3})
+
+	got, err := schema.Revalidate(doc, prev, []string{"/order/item[2]"})
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("revalidation should stop with the fail policy, got %v", err)
+	}
+	want, _ := schema.Run(doc)
+	compareResults(t, got, want)
+}
+
+const revalidateSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+	<pattern id="items">
+		<rule context="/root/item">
+			<assert id="price" flag="error" test="@price &gt; 0">invalid price</assert>
+		</rule>
+	</pattern>
+	<pattern id="header">
+		<rule context="/root/header">
+			<assert id="total" flag="error" test="@total = sum(/root/item/@price)">invalid total</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+func TestRevalidateNonLocal(t *testing.T) {
+	schema, err := New(strings.NewReader(revalidateSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(`<root><header total="3"/><item price="1"/><item price="2"/></root>`)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	prev, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	seq, err := schema.eval.Find("/root/item[1]", doc)
+	if err != nil || seq.Len() != 1 {
+		t.Fatalf("item not found")
+	}
+	el := seq[0].Node().(*xml.Element)
+	el.SetAttribute(xml.NewAttribute(xml.LocalName("price"), "5"))
+
+	// the header rule reads the items but does not match them: its previous
+	// result is reused even if the total is now wrong.
+	got, err := schema.Revalidate(doc, prev, []string{"/root/item[1]"})
+	if err != nil {
+		t.Fatalf("fail to revalidate document: %s", err)
+	}
+	compareResults(t, got, prev)
+
+	want, err := schema.Run(doc)
+	if err != nil {
+		t.Fatalf("fail to validate document: %s", err)
+	}
+	if want[1].Fail != 1 {
+		t.Fatalf("run should report the invalid total")
+	}
+}
+
+func compareResults(t *testing.T, got, want []Result) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("results mismatched! want %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Pattern != w.Pattern || g.Context != w.Context || g.Test != w.Test {
+			t.Errorf("result %d: assertion mismatched! want %s/%s, got %s/%s", i, w.Pattern, w.Test, g.Pattern, g.Test)
+		}
+		if g.Report != w.Report {
+			t.Errorf("result %d: report mismatched! want %t, got %t", i, w.Report, g.Report)
+		}
+		if g.Pass != w.Pass || g.Fail != w.Fail || g.Total != w.Total {
+			t.Errorf("result %d: counts mismatched! want %d/%d/%d, got %d/%d/%d", i, w.Pass, w.Fail, w.Total, g.Pass, g.Fail, g.Total)
+		}
+	}
+}
+
+const abstractSchema = `<schema xmlns="http://purl.oclc.org/dsdl/schematron" defaultPhase="#ALL">
+	<phase id="names">
+		<active pattern="item-name"/>
+	</phase>
+	<phase id="quantities">
+		<active pattern="item-qty"/>
+		<active pattern="order-qty"/>
+	</phase>
+	<let name="min" value="1"/>
+	<rules>
+		<rule abstract="true" id="positive">
+			<assert id="qty" flag="fatal" test="@qty >= $min">quantity too low</assert>
+		</rule>
+	</rules>
+	<pattern abstract="true" id="required">
+		<rule context="$parent">
+			<assert id="required-$field" flag="warning" test="$field">$field is missing</assert>
+		</rule>
+	</pattern>
+	<pattern id="item-name" is-a="required">
+		<param name="parent" value="//item"/>
+		<param name="field" value="@name"/>
+	</pattern>
+	<pattern id="item-qty">
+		<rule context="//item">
+			<extends rule="positive"/>
+		</rule>
+	</pattern>
+	<pattern id="order-qty">
+		<let name="min" value="3"/>
+		<rule context="/order">
+			<let name="total" value="sum(item/@qty)"/>
+			<assert id="total" flag="warning" test="$total >= $min">order too small</assert>
+		</rule>
+	</pattern>
+</schema>`
+
+func TestPhases(t *testing.T) {
+	schema, err := New(strings.NewReader(abstractSchema))
+	if err != nil {
+		t.Fatalf("fail to parse schema: %s", err)
+	}
+	doc, err := xml.ParseString(`<order><item name="a" qty="0"/><item qty="1"/></order>`)
+	if err != nil {
+		t.Fatalf("fail to parse document: %s", err)
+	}
+	type result struct {
+		Pattern string
+		Ident   string
+		Fail    int
+	}
+	tests := []struct {
+		Phase string
+		Want  []result
+	}{
+		{
+			Phase: "",
+			Want: []result{
+				{Pattern: "item-name", Ident: "required-@name", Fail: 1},
+				{Pattern: "item-qty", Ident: "qty", Fail: 1},
+				{Pattern: "order-qty", Ident: "total", Fail: 1},
+			},
+		},
+		{
+			Phase: "#ALL",
+			Want: []result{
+				{Pattern: "item-name", Ident: "required-@name", Fail: 1},
+				{Pattern: "item-qty", Ident: "qty", Fail: 1},
+				{Pattern: "order-qty", Ident: "total", Fail: 1},
+			},
+		},
+		{
+			Phase: "names",
+			Want: []result{
+				{Pattern: "item-name", Ident: "required-@name", Fail: 1},
+			},
+		},
+		{
+			Phase: "quantities",
+			Want: []result{
+				{Pattern: "item-qty", Ident: "qty", Fail: 1},
+				{Pattern: "order-qty", Ident: "total", Fail: 1},
+			},
+		},
+		{
+			Phase: "unknown",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Phase, func(t *testing.T) {
+			res, err := schema.RunPhase(c.Phase, doc)
+			if err != nil {
+				t.Fatalf("fail to validate document: %s", err)
+			}
+			var got []result
+			for _, r := range res {
+				got = append(got, result{Pattern: r.Pattern, Ident: r.Ident, Fail: r.Fail})
+			}
+			if len(got) != len(c.Want) {
+				t.Fatalf("results mismatched! want %v, got %v", c.Want, got)
+			}
+			for i := range c.Want {
+				if got[i] != c.Want[i] {
+					t.Errorf("result %d mismatched! want %v, got %v", i, c.Want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAbstractErrors(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Schema string
+	}{
+		{
+			Name: "undefined-pattern",
+			Schema: `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+				<pattern id="p" is-a="missing"/>
+			</schema>`,
+		},
+		{
+			Name: "undefined-rule",
+			Schema: `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+				<pattern id="p">
+					<rule context="/"><extends rule="missing"/></rule>
+				</pattern>
+			</schema>`,
+		},
+		{
+			Name: "recursive-rule",
+			Schema: `<schema xmlns="http://purl.oclc.org/dsdl/schematron">
+				<rules>
+					<rule abstract="true" id="a"><extends rule="b"/></rule>
+					<rule abstract="true" id="b"><extends rule="a"/></rule>
+				</rules>
+				<pattern id="p">
+					<rule context="/"><extends rule="a"/></rule>
+				</pattern>
+			</schema>`,
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			if _, err := New(strings.NewReader(c.Schema)); err == nil {
+				t.Errorf("expected error loading schema")
+			}
+		})
+	}
+}
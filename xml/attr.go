@@ -0,0 +1,68 @@
+package xml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02Z07:00",
+	"2006-01-02",
+}
+
+func (e *Element) AttrInt(name string, def int) (int, error) {
+	value, ok := e.GetAttribute(name)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return def, fmt.Errorf("%w: %s: %q is not a valid integer", ErrType, name, value)
+	}
+	return n, nil
+}
+
+func (e *Element) AttrFloat(name string, def float64) (float64, error) {
+	value, ok := e.GetAttribute(name)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return def, fmt.Errorf("%w: %s: %q is not a valid number", ErrType, name, value)
+	}
+	return n, nil
+}
+
+func (e *Element) AttrBool(name string, def bool) (bool, error) {
+	value, ok := e.GetAttribute(name)
+	if !ok {
+		return def, nil
+	}
+	switch strings.TrimSpace(value) {
+	case "yes", "true", "1":
+		return true, nil
+	case "no", "false", "0":
+		return false, nil
+	default:
+		return def, fmt.Errorf("%w: %s: %q is not a boolean", ErrType, name, value)
+	}
+}
+
+func (e *Element) AttrTime(name string, def time.Time) (time.Time, error) {
+	value, ok := e.GetAttribute(name)
+	if !ok {
+		return def, nil
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return def, fmt.Errorf("%w: %s: %q is not a valid date/time", ErrType, name, value)
+}
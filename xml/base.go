@@ -0,0 +1,60 @@
+package xml
+
+import (
+	"net/url"
+	"path/filepath"
+)
+
+const AttrBase = "base"
+
+func BaseURI(node Node) string {
+	var bases []string
+	for ; node != nil; node = node.Parent() {
+		switch n := node.(type) {
+		case *Element:
+			if base, ok := xmlAttr(n.Attrs, AttrBase); ok {
+				bases = append(bases, base)
+			}
+		case *Document:
+			if n.URI != "" {
+				bases = append(bases, n.URI)
+			}
+		default:
+		}
+	}
+	var uri string
+	for i := len(bases) - 1; i >= 0; i-- {
+		res, err := ResolveURI(bases[i], uri)
+		if err != nil {
+			return ""
+		}
+		uri = res
+	}
+	return uri
+}
+
+func ResolveURI(ref, base string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == "" || u.IsAbs() {
+		return u.String(), nil
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(u).String(), nil
+}
+
+func FileURI(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	u := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(file),
+	}
+	return u.String()
+}
@@ -0,0 +1,254 @@
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type Canonicalizer struct {
+	writer *bufio.Writer
+
+	WithComments      bool
+	InclusivePrefixes []string
+}
+
+func Canonicalize(node Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewCanonicalizer(&buf).Write(node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func NewCanonicalizer(w io.Writer) *Canonicalizer {
+	return &Canonicalizer{
+		writer: bufio.NewWriter(w),
+	}
+}
+
+func (c *Canonicalizer) Write(node Node) error {
+	var err error
+	if doc, ok := node.(*Document); ok {
+		err = c.writeDocument(doc)
+	} else {
+		err = c.writeNode(node, nil)
+	}
+	if err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+func (c *Canonicalizer) writeDocument(doc *Document) error {
+	var seen bool
+	for _, n := range doc.Nodes {
+		switch n.(type) {
+		case *Element:
+			seen = true
+			if err := c.writeNode(n, nil); err != nil {
+				return err
+			}
+		case *Comment, *Instruction:
+			if _, ok := n.(*Comment); ok && !c.WithComments {
+				continue
+			}
+			if seen {
+				c.writer.WriteString("\n")
+			}
+			if err := c.writeNode(n, nil); err != nil {
+				return err
+			}
+			if !seen {
+				c.writer.WriteString("\n")
+			}
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *Canonicalizer) writeNode(node Node, rendered map[string]string) error {
+	switch node := node.(type) {
+	case *Element:
+		return c.writeElement(node, rendered)
+	case *Text:
+		c.writer.WriteString(escapeCanonicalText(node.Content))
+	case *CharData:
+		c.writer.WriteString(escapeCanonicalText(node.Content))
+	case *Comment:
+		if c.WithComments {
+			c.writer.WriteString("<!--")
+			c.writer.WriteString(node.Content)
+			c.writer.WriteString("-->")
+		}
+	case *Instruction:
+		c.writer.WriteString("<?")
+		c.writer.WriteString(node.QualifiedName())
+		for _, a := range node.Attrs {
+			fmt.Fprintf(c.writer, " %s=\"%s\"", a.QualifiedName(), a.Value())
+		}
+		c.writer.WriteString("?>")
+	default:
+		return fmt.Errorf("node: unknown type (%T)", node)
+	}
+	return nil
+}
+
+func (c *Canonicalizer) writeElement(node *Element, rendered map[string]string) error {
+	var (
+		attrs = node.Attributes()
+		used  = []string{node.Space}
+		list  []NS
+	)
+	for _, a := range attrs {
+		if a.Space != "" && a.Space != "xml" {
+			used = append(used, a.Space)
+		}
+	}
+	for _, ns := range inScopeNamespaces(node) {
+		if !slices.Contains(used, ns.Prefix) && !slices.Contains(c.InclusivePrefixes, ns.Prefix) {
+			continue
+		}
+		uri, ok := rendered[ns.Prefix]
+		if ok && uri == ns.Uri {
+			continue
+		}
+		if !ok && ns.Default() && ns.Uri == "" {
+			continue
+		}
+		list = append(list, ns)
+	}
+	slices.SortFunc(list, func(a, b NS) int {
+		return cmp.Compare(a.Prefix, b.Prefix)
+	})
+	slices.SortFunc(attrs, func(a, b Attribute) int {
+		if n := cmp.Compare(resolveAttrNS(node, a), resolveAttrNS(node, b)); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	c.writer.WriteString("<")
+	c.writer.WriteString(node.QualifiedName())
+	if len(list) > 0 {
+		rendered = cloneRendered(rendered)
+	}
+	for _, ns := range list {
+		if ns.Default() {
+			fmt.Fprintf(c.writer, " xmlns=\"%s\"", escapeCanonicalAttr(ns.Uri))
+		} else {
+			fmt.Fprintf(c.writer, " xmlns:%s=\"%s\"", ns.Prefix, escapeCanonicalAttr(ns.Uri))
+		}
+		rendered[ns.Prefix] = ns.Uri
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(c.writer, " %s=\"%s\"", a.QualifiedName(), escapeCanonicalAttr(a.Value()))
+	}
+	c.writer.WriteString(">")
+	for _, n := range node.Nodes {
+		if err := c.writeNode(n, rendered); err != nil {
+			return err
+		}
+	}
+	c.writer.WriteString("</")
+	c.writer.WriteString(node.QualifiedName())
+	c.writer.WriteString(">")
+	return nil
+}
+
+func inScopeNamespaces(node Node) []NS {
+	var list []NS
+	for n := node; n != nil; n = n.Parent() {
+		el, ok := n.(*Element)
+		if !ok {
+			continue
+		}
+		for _, ns := range el.Namespaces() {
+			ok := slices.ContainsFunc(list, func(other NS) bool {
+				return other.Prefix == ns.Prefix
+			})
+			if !ok {
+				list = append(list, ns)
+			}
+		}
+	}
+	if el, ok := node.(*Element); ok && el.Uri != "" {
+		ok := slices.ContainsFunc(list, func(other NS) bool {
+			return other.Prefix == el.Space
+		})
+		if !ok {
+			list = append(list, NS{Prefix: el.Space, Uri: el.Uri})
+		}
+	}
+	return list
+}
+
+func resolveAttrNS(node *Element, attr Attribute) string {
+	if attr.Space == "" {
+		return ""
+	}
+	if attr.Uri != "" {
+		return attr.Uri
+	}
+	for _, ns := range inScopeNamespaces(node) {
+		if ns.Prefix == attr.Space {
+			return ns.Uri
+		}
+	}
+	return attr.Space
+}
+
+func cloneRendered(rendered map[string]string) map[string]string {
+	other := make(map[string]string)
+	for k, v := range rendered {
+		other[k] = v
+	}
+	return other
+}
+
+func escapeCanonicalText(str string) string {
+	var buf strings.Builder
+	for _, r := range str {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+func escapeCanonicalAttr(str string) string {
+	var buf strings.Builder
+	for _, r := range str {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
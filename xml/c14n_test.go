@@ -0,0 +1,63 @@
+package xml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestCanonicalize(t *testing.T) {
+	data := []struct {
+		Input        string
+		Want         string
+		WithComments bool
+	}{
+		{
+			Input: `<?xml version="1.0"?><root b="2" a="1"><empty/></root>`,
+			Want:  `<root a="1" b="2"><empty></empty></root>`,
+		},
+		{
+			Input: `<root xmlns:a="urn:a" xmlns:b="urn:b"><a:item b:z="1" y="2" a:x="3"/></root>`,
+			Want:  `<root><a:item xmlns:a="urn:a" xmlns:b="urn:b" y="2" a:x="3" b:z="1"></a:item></root>`,
+		},
+		{
+			Input: `<a:root xmlns:a="urn:a"><a:item>text</a:item></a:root>`,
+			Want:  `<a:root xmlns:a="urn:a"><a:item>text</a:item></a:root>`,
+		},
+		{
+			Input: `<root attr="a&amp;b&lt;c&quot;">1 &lt; 2 &amp;&amp; 3 &gt; 2</root>`,
+			Want:  `<root attr="a&amp;b&lt;c&quot;">1 &lt; 2 &amp;&amp; 3 &gt; 2</root>`,
+		},
+		{
+			Input: `<root><!--comment--><item/></root>`,
+			Want:  `<root><item></item></root>`,
+		},
+		{
+			Input:        `<root><!--comment--><item/></root>`,
+			Want:         `<root><!--comment--><item></item></root>`,
+			WithComments: true,
+		},
+	}
+	for _, d := range data {
+		doc, err := parseDocument(d.Input)
+		if err != nil {
+			t.Errorf("fail to parse input document: %s", err)
+			continue
+		}
+		var (
+			buf strings.Builder
+			c   = xml.NewCanonicalizer(&buf)
+		)
+		c.WithComments = d.WithComments
+		if err := c.Write(doc); err != nil {
+			t.Errorf("error canonicalizing document: %s", err)
+			continue
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("result mismatched")
+			t.Logf("want: %s", d.Want)
+			t.Logf("got : %s", got)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package xml_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+	"github.com/midbel/codecs/xml"
+)
+
+func FuzzParse(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		`<root/>`,
+		`<?xml version="1.0" encoding="UTF-8"?><root attr="value">text</root>`,
+		`<ns:root xmlns:ns="http://midbel.org"><ns:item ns:id="1"/></ns:root>`,
+		`<root><![CDATA[<data>]]><!-- comment --><?pi target?></root>`,
+		`<root>&lt;&#65;&#x42;</root>`,
+		`<root><item>`,
+		`<root></item>`,
+	)
+	fuzzing.AddFiles(f, "../xslt/testdata", ".xml")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			doc, err := xml.NewParser(bytes.NewReader(data)).Parse()
+			if err != nil {
+				return err
+			}
+			_, err = doc.WriteString()
+			return err
+		})
+	})
+}
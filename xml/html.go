@@ -0,0 +1,212 @@
+package xml
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const htmlStyle = `
+.xml { font-family: monospace; line-height: 1.4; }
+.xml span { white-space: pre-wrap; }
+.xml details { margin-left: 1.5em; }
+.xml details > summary { list-style: none; cursor: pointer; margin-left: -1em; }
+.xml details > summary::before { content: "- "; color: #999; }
+.xml details:not([open]) > summary::before { content: "+ "; }
+.xml .line { margin-left: 1.5em; }
+.xml .tag { color: #881280; }
+.xml .attr { color: #994500; }
+.xml .value { color: #1a1aa6; }
+.xml .text { color: #000; }
+.xml .comment { color: #236e25; font-style: italic; }
+.xml .pi { color: #6a737d; }
+.xml .cdata { color: #6f42c1; }
+.xml .marked { background: #fdd; outline: 1px solid #e66; }
+`
+
+type HTMLOptions struct {
+	Title      string
+	Standalone bool
+	Collapse   int
+	MaxDepth   int
+	Marked     []Node
+}
+
+type htmlRenderer struct {
+	writer *bufio.Writer
+	HTMLOptions
+
+	// marked nodes and the nodes having a marked descendant (including the
+	// marked nodes themselves).
+	marks   map[Node]struct{}
+	holders map[Node]struct{}
+}
+
+func RenderHTML(w io.Writer, node Node, opts HTMLOptions) error {
+	r := htmlRenderer{
+		writer:      bufio.NewWriter(w),
+		HTMLOptions: opts,
+		marks:       make(map[Node]struct{}),
+		holders:     make(map[Node]struct{}),
+	}
+	for _, m := range opts.Marked {
+		r.marks[m] = struct{}{}
+		for p := m; p != nil; p = p.Parent() {
+			if _, ok := r.holders[p]; ok {
+				break
+			}
+			r.holders[p] = struct{}{}
+		}
+	}
+	if opts.Standalone {
+		r.writer.WriteString("<!doctype html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+		if opts.Title != "" {
+			fmt.Fprintf(r.writer, "<title>%s</title>\n", html.EscapeString(opts.Title))
+		}
+		fmt.Fprintf(r.writer, "<style>%s</style>\n", htmlStyle)
+		r.writer.WriteString("</head>\n<body>\n")
+	}
+	var path string
+	if node.Type() == TypeElement {
+		path = PathOf(node, PathOptions{Indexed: true})
+	}
+	r.writer.WriteString("<div class=\"xml\">\n")
+	if err := r.render(node, 0, path); err != nil {
+		return err
+	}
+	r.writer.WriteString("</div>\n")
+	if opts.Standalone {
+		r.writer.WriteString("</body>\n</html>\n")
+	}
+	return r.writer.Flush()
+}
+
+func HTMLStyle() string {
+	return htmlStyle
+}
+
+// render writes node in html. path is the indexed path of node when it is
+// an element, built incrementally while walking down the tree.
+func (r *htmlRenderer) render(node Node, depth int, path string) error {
+	switch node := node.(type) {
+	case *Document:
+		return r.renderNodes(node.Nodes, depth, "")
+	case *Element:
+		return r.renderElement(node, depth, path)
+	case *Text:
+		content := strings.TrimSpace(node.Content)
+		if content == "" {
+			return nil
+		}
+		r.renderLine(node, "text", html.EscapeString(content))
+	case *CharData:
+		r.renderLine(node, "cdata", html.EscapeString("<![CDATA["+node.Content+"]]>"))
+	case *Comment:
+		r.renderLine(node, "comment", html.EscapeString("<!--"+node.Content+"-->"))
+	case *Instruction:
+		var str strings.Builder
+		str.WriteString(html.EscapeString("<?" + node.QualifiedName()))
+		r.writeAttributes(&str, node.Attrs)
+		str.WriteString(html.EscapeString("?>"))
+		r.renderLine(node, "pi", str.String())
+	case *Attribute:
+		var str strings.Builder
+		r.writeAttributes(&str, []Attribute{*node})
+		r.renderLine(node, "attr", str.String())
+	default:
+		return fmt.Errorf("node: unknown type (%T)", node)
+	}
+	return nil
+}
+
+func (r *htmlRenderer) renderNodes(nodes []Node, depth int, base string) error {
+	index := make(map[string]int)
+	for _, n := range nodes {
+		var path string
+		if n.Type() == TypeElement {
+			name := n.QualifiedName()
+			index[name]++
+			path = fmt.Sprintf("%s/%s[%d]", base, name, index[name])
+		}
+		if err := r.render(n, depth, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *htmlRenderer) renderLine(node Node, class, content string) {
+	fmt.Fprintf(r.writer, "<div class=\"line%s\"><span class=\"%s\">%s</span></div>\n", r.markClass(node), class, content)
+}
+
+func (r *htmlRenderer) renderElement(node *Element, depth int, path string) error {
+	var start strings.Builder
+	start.WriteString("<span class=\"tag\">")
+	start.WriteString(html.EscapeString("<" + node.QualifiedName()))
+	start.WriteString("</span>")
+	r.writeAttributes(&start, node.Attrs)
+
+	anchor := r.anchor(path)
+	if len(node.Nodes) == 0 || (r.MaxDepth > 0 && depth >= r.MaxDepth) {
+		start.WriteString("<span class=\"tag\">/&gt;</span>")
+		fmt.Fprintf(r.writer, "<div%s class=\"line%s\">%s</div>\n", anchor, r.markClass(node), start.String())
+		return nil
+	}
+	start.WriteString("<span class=\"tag\">&gt;</span>")
+	if node.Leaf() {
+		if text, ok := node.Nodes[0].(*Text); ok {
+			fmt.Fprintf(r.writer, "<div%s class=\"line%s\">%s<span class=\"text\">%s</span>%s</div>\n", anchor, r.markClass(node), start.String(), html.EscapeString(text.Content), r.endTag(node))
+			return nil
+		}
+	}
+	var open string
+	if r.Collapse <= 0 || depth < r.Collapse || r.containsMarked(node) {
+		open = " open"
+	}
+	if r.marked(node) {
+		open += " class=\"marked\""
+	}
+	fmt.Fprintf(r.writer, "<details%s%s><summary>%s</summary>\n", anchor, open, start.String())
+	if err := r.renderNodes(node.Nodes, depth+1, path); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.writer, "<div>%s</div></details>\n", r.endTag(node))
+	return nil
+}
+
+func (r *htmlRenderer) endTag(node *Element) string {
+	return "<span class=\"tag\">" + html.EscapeString("</"+node.QualifiedName()+">") + "</span>"
+}
+
+func (r *htmlRenderer) writeAttributes(str *strings.Builder, attrs []Attribute) {
+	for _, a := range attrs {
+		str.WriteString(" <span class=\"attr\">")
+		str.WriteString(html.EscapeString(a.QualifiedName()))
+		str.WriteString("</span>=<span class=\"value\">&quot;")
+		str.WriteString(html.EscapeString(a.Value()))
+		str.WriteString("&quot;</span>")
+	}
+}
+
+func (r *htmlRenderer) markClass(node Node) string {
+	if r.marked(node) {
+		return " marked"
+	}
+	return ""
+}
+
+func (r *htmlRenderer) marked(node Node) bool {
+	_, ok := r.marks[node]
+	return ok
+}
+
+func (r *htmlRenderer) containsMarked(node Node) bool {
+	_, ok := r.holders[node]
+	return ok
+}
+
+func (r *htmlRenderer) anchor(path string) string {
+	return fmt.Sprintf(" id=\"%s\"", html.EscapeString(path))
+}
@@ -0,0 +1,70 @@
+package xml_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestRenderHTMLAnchors(t *testing.T) {
+	doc, err := xml.ParseString(`<root><item>foo</item><item>bar</item><other><item>qux</item></other></root>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	var str strings.Builder
+	if err := xml.RenderHTML(&str, doc, xml.HTMLOptions{}); err != nil {
+		t.Fatalf("error rendering document: %s", err)
+	}
+	tests := []string{
+		`id="/root[1]"`,
+		`id="/root[1]/item[1]"`,
+		`id="/root[1]/item[2]"`,
+		`id="/root[1]/other[1]/item[1]"`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(str.String(), want) {
+			t.Errorf("anchor %s not found in output", want)
+		}
+	}
+}
+
+func TestRenderHTMLMarked(t *testing.T) {
+	doc, err := xml.ParseString(`<root><a><b><c>foo</c><c>bar</c></b></a><d><e/></d></root>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	var (
+		root = doc.Root().(*xml.Element)
+		b    = root.Nodes[0].(*xml.Element).Nodes[0].(*xml.Element)
+		c    = b.Nodes[1]
+	)
+	var str strings.Builder
+	opts := xml.HTMLOptions{
+		Collapse: 1,
+		Marked:   []xml.Node{c},
+	}
+	if err := xml.RenderHTML(&str, doc, opts); err != nil {
+		t.Fatalf("error rendering document: %s", err)
+	}
+	tests := []string{
+		`<details id="/root[1]/a[1]" open>`,
+		`<details id="/root[1]/a[1]/b[1]" open>`,
+		`<details id="/root[1]/d[1]">`,
+		`<div id="/root[1]/a[1]/b[1]/c[1]" class="line">`,
+		`<div id="/root[1]/a[1]/b[1]/c[2]" class="line marked">`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(str.String(), want) {
+			t.Errorf("%s not found in output", want)
+		}
+	}
+
+	str.Reset()
+	if err := xml.RenderHTML(&str, b, xml.HTMLOptions{}); err != nil {
+		t.Fatalf("error rendering node: %s", err)
+	}
+	if want := `id="/root[1]/a[1]/b[1]/c[2]"`; !strings.Contains(str.String(), want) {
+		t.Errorf("anchor %s not found in output of subtree", want)
+	}
+}
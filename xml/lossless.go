@@ -0,0 +1,84 @@
+package xml
+
+import (
+	"strings"
+)
+
+type nodeSource struct {
+	raw string
+	end string
+	sig string
+}
+
+func signature(name QName, attrs []Attribute, rename func(QName) QName) string {
+	if rename == nil {
+		rename = func(qn QName) QName { return qn }
+	}
+	var str strings.Builder
+	str.WriteString(rename(name).QualifiedName())
+	for _, a := range attrs {
+		str.WriteRune(0)
+		str.WriteString(rename(a.QName).QualifiedName())
+		str.WriteRune(equal)
+		str.WriteString(a.Value())
+	}
+	return str.String()
+}
+
+func (w *Writer) unchanged(src *nodeSource, name QName, attrs []Attribute) bool {
+	if src == nil || w.NoNamespace() {
+		return false
+	}
+	return src.sig == signature(name, attrs, w.rewriteQName)
+}
+
+func (w *Writer) writeElementSource(node *Element, depth int) error {
+	if w.Limit > 0 && depth/2 >= w.Limit {
+		return ErrDepth
+	}
+	src := node.source
+	if !w.unchanged(src, node.QName, node.Attrs) || (src.end == "" && len(node.Nodes) > 0) {
+		src = nil
+	}
+	name := w.rewriteQName(node.QName)
+	if src != nil {
+		w.writer.WriteString(src.raw)
+		if src.end == "" {
+			return w.writer.Flush()
+		}
+	} else {
+		w.writer.WriteRune(langle)
+		if w.NoNamespace() {
+			w.writer.WriteString(name.LocalName())
+		} else {
+			w.writer.WriteString(name.QualifiedName())
+		}
+		if err := w.writeAttributes(node.Attrs, 0); err != nil {
+			return err
+		}
+		if len(node.Nodes) == 0 && !w.isPairedElement(node) {
+			w.writer.WriteRune(slash)
+			w.writer.WriteRune(rangle)
+			return w.writer.Flush()
+		}
+		w.writer.WriteRune(rangle)
+	}
+	for _, n := range node.Nodes {
+		if err := w.writeChild(node, n, depth+1); err != nil {
+			return err
+		}
+	}
+	if src != nil {
+		w.writer.WriteString(src.end)
+		return w.writer.Flush()
+	}
+	w.writer.WriteRune(langle)
+	w.writer.WriteRune(slash)
+	if w.NoNamespace() {
+		w.writer.WriteString(name.LocalName())
+	} else {
+		w.writer.WriteString(name.QualifiedName())
+	}
+	w.writer.WriteRune(rangle)
+	return w.writer.Flush()
+}
@@ -0,0 +1,278 @@
+package xml
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+)
+
+const XmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+type NamespaceMap struct {
+	list   []NS
+	prefix map[string]int
+}
+
+func createNamespaceMap(attrs []Attribute) *NamespaceMap {
+	nm := NamespaceMap{
+		prefix: make(map[string]int),
+	}
+	for _, a := range attrs {
+		if a.Name != AttrXmlNS && a.Space != AttrXmlNS {
+			continue
+		}
+		ns := NS{
+			Prefix: a.Name,
+			Uri:    a.Value(),
+		}
+		if ns.Prefix == AttrXmlNS {
+			ns.Prefix = ""
+		}
+		if ix, ok := nm.prefix[ns.Prefix]; ok {
+			nm.list[ix] = ns
+			continue
+		}
+		nm.prefix[ns.Prefix] = len(nm.list)
+		nm.list = append(nm.list, ns)
+	}
+	return &nm
+}
+
+func (m *NamespaceMap) Len() int {
+	return len(m.list)
+}
+
+func (m *NamespaceMap) List() []NS {
+	return slices.Clone(m.list)
+}
+
+func (m *NamespaceMap) All() iter.Seq2[string, string] {
+	fn := func(yield func(string, string) bool) {
+		for _, ns := range m.list {
+			if !yield(ns.Prefix, ns.Uri) {
+				return
+			}
+		}
+	}
+	return fn
+}
+
+func (m *NamespaceMap) Resolve(prefix string) (string, bool) {
+	ix, ok := m.prefix[prefix]
+	if !ok {
+		return "", false
+	}
+	return m.list[ix].Uri, true
+}
+
+func (m *NamespaceMap) Prefix(uri string) (string, bool) {
+	ix := slices.IndexFunc(m.list, func(ns NS) bool {
+		return ns.Uri == uri
+	})
+	if ix < 0 {
+		return "", false
+	}
+	return m.list[ix].Prefix, true
+}
+
+func InScopeNamespaces(node Node) []NS {
+	var (
+		list []NS
+		seen = make(map[string]struct{})
+	)
+	if node != nil && node.Type() != TypeElement {
+		node = node.Parent()
+	}
+	for ; node != nil; node = node.Parent() {
+		el, ok := node.(*Element)
+		if !ok {
+			continue
+		}
+		for _, ns := range el.Namespaces() {
+			if _, ok := seen[ns.Prefix]; ok {
+				continue
+			}
+			seen[ns.Prefix] = struct{}{}
+			if ns.Prefix == "" && ns.Uri == "" {
+				continue
+			}
+			list = append(list, ns)
+		}
+	}
+	return append(list, NS{Prefix: "xml", Uri: XmlNamespace})
+}
+
+func NamespaceNodes(node Node) []*Attribute {
+	if node == nil || node.Type() != TypeElement {
+		return nil
+	}
+	var list []*Attribute
+	for i, ns := range InScopeNamespaces(node) {
+		a := NewAttribute(LocalName(ns.Prefix), ns.Uri)
+		a.setParent(node)
+		a.setPosition(i)
+		list = append(list, &a)
+	}
+	return list
+}
+
+type nsFix struct {
+	space string
+	attrs map[int]string
+	decls []Attribute
+}
+
+func (f *nsFix) apply(elem *Element) {
+	elem.Space = f.space
+	for i, p := range f.attrs {
+		elem.Attrs[i].Space = p
+	}
+	for _, a := range f.decls {
+		elem.SetAttribute(a)
+	}
+}
+
+func (f *nsFix) element(elem *Element) (QName, []Attribute) {
+	name := elem.QName
+	name.Space = f.space
+	attrs := slices.Clone(elem.Attrs)
+	for i, p := range f.attrs {
+		attrs[i].Space = p
+	}
+	for _, a := range f.decls {
+		ix := slices.IndexFunc(attrs, func(x Attribute) bool {
+			return x.QualifiedName() == a.QualifiedName()
+		})
+		if ix < 0 {
+			attrs = append(attrs, a)
+		} else {
+			attrs[ix] = a
+		}
+	}
+	return name, attrs
+}
+
+type nsFixer struct {
+	count int
+	fixes map[*Element]*nsFix
+}
+
+func FixNamespaces(node Node) {
+	for elem, fx := range namespaceFixes(node) {
+		fx.apply(elem)
+	}
+}
+
+func namespaceFixes(node Node) map[*Element]*nsFix {
+	scope := make(map[string]string)
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		el, ok := p.(*Element)
+		if !ok {
+			continue
+		}
+		for _, ns := range el.Namespaces() {
+			if _, ok := scope[ns.Prefix]; !ok {
+				scope[ns.Prefix] = ns.Uri
+			}
+		}
+	}
+	fx := nsFixer{
+		fixes: make(map[*Element]*nsFix),
+	}
+	fx.fix(node, scope)
+	return fx.fixes
+}
+
+func (f *nsFixer) get(elem *Element) *nsFix {
+	fx, ok := f.fixes[elem]
+	if !ok {
+		fx = &nsFix{
+			space: elem.Space,
+			attrs: make(map[int]string),
+		}
+		f.fixes[elem] = fx
+	}
+	return fx
+}
+
+func (f *nsFixer) fix(node Node, scope map[string]string) {
+	type frame struct {
+		node  Node
+		scope map[string]string
+	}
+	stack := []frame{{node: node, scope: scope}}
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		var children []Node
+		switch n := curr.node.(type) {
+		case *Document:
+			children = n.Nodes
+		case *Element:
+			curr.scope = maps.Clone(curr.scope)
+			for _, ns := range n.Namespaces() {
+				curr.scope[ns.Prefix] = ns.Uri
+			}
+			if n.Uri != "" {
+				if p := f.resolve(n, n.Space, n.Uri, curr.scope, true); p != n.Space {
+					f.get(n).space = p
+				}
+			} else if n.Space == "" && curr.scope[""] != "" {
+				f.declare(n, "", "", curr.scope)
+			}
+			for i, a := range n.Attrs {
+				if a.Name == AttrXmlNS || a.Space == AttrXmlNS || a.Space == "" || a.Space == "xml" || a.Uri == "" {
+					continue
+				}
+				if p := f.resolve(n, a.Space, a.Uri, curr.scope, false); p != a.Space {
+					f.get(n).attrs[i] = p
+				}
+			}
+			children = n.Nodes
+		default:
+		}
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, frame{node: children[i], scope: curr.scope})
+		}
+	}
+}
+
+func (f *nsFixer) resolve(elem *Element, prefix, uri string, scope map[string]string, allowDefault bool) string {
+	if prefix != "" || allowDefault {
+		if u, ok := scope[prefix]; ok && u == uri {
+			return prefix
+		}
+	}
+	if prefix != "" {
+		if _, ok := scope[prefix]; !ok {
+			f.declare(elem, prefix, uri, scope)
+			return prefix
+		}
+	}
+	for _, p := range slices.Sorted(maps.Keys(scope)) {
+		if p != "" && scope[p] == uri {
+			return p
+		}
+	}
+	for {
+		f.count++
+		prefix = fmt.Sprintf("ns%d", f.count)
+		if _, ok := scope[prefix]; !ok {
+			break
+		}
+	}
+	f.declare(elem, prefix, uri, scope)
+	return prefix
+}
+
+func (f *nsFixer) declare(elem *Element, prefix, uri string, scope map[string]string) {
+	attr := NewAttribute(QualifiedName(prefix, AttrXmlNS), uri)
+	if prefix == "" {
+		attr = NewAttribute(LocalName(AttrXmlNS), uri)
+	}
+	fx := f.get(elem)
+	fx.decls = append(fx.decls, attr)
+	scope[prefix] = uri
+}
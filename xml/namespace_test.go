@@ -0,0 +1,139 @@
+package xml_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestFixNamespaces(t *testing.T) {
+	root := xml.NewElement(xml.ExpandedName("root", "", "urn:a"))
+	child := xml.NewElement(xml.ExpandedName("child", "b", "urn:b"))
+	child.SetAttribute(xml.NewAttribute(xml.ExpandedName("attr", "c", "urn:c"), "1"))
+	child.Append(xml.NewElement(xml.ExpandedName("inner", "", "urn:a")))
+	root.Append(child)
+
+	var (
+		buf strings.Builder
+		ws  = xml.NewWriter(&buf)
+	)
+	ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+	if err := ws.Write(xml.NewDocument(root)); err != nil {
+		t.Errorf("error writing document: %s", err)
+		return
+	}
+	want := `<ns1:root xmlns:ns1="urn:a"><b:child xmlns:b="urn:b" xmlns:c="urn:c" c:attr="1"><ns1:inner/></b:child></ns1:root>`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatched")
+		t.Logf("want: %s", want)
+		t.Logf("got : %s", got)
+	}
+	if root.Space != "" || len(root.Attrs) != 0 || len(child.Attrs) != 1 {
+		t.Errorf("writing document should not modify its nodes")
+	}
+	buf.Reset()
+	if err := ws.Write(xml.NewDocument(root)); err != nil {
+		t.Fatalf("error writing document: %s", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("second write mismatched! got %s", got)
+	}
+
+	xml.FixNamespaces(root)
+	if root.Space != "ns1" {
+		t.Errorf("namespaces should be fixed in place! got prefix %q", root.Space)
+	}
+}
+
+func TestFixNamespacesReusePrefix(t *testing.T) {
+	root := xml.NewElement(xml.LocalName("root"))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("z", "xmlns"), "urn:a"))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("b", "xmlns"), "urn:a"))
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("m", "xmlns"), "urn:a"))
+	root.Append(xml.NewElement(xml.ExpandedName("child", "", "urn:a")))
+
+	want := `<root xmlns:b="urn:a" xmlns:m="urn:a" xmlns:z="urn:a"><b:child/></root>`
+	for range 20 {
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+		if err := ws.Write(xml.NewDocument(root)); err != nil {
+			t.Fatalf("error writing document: %s", err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("result mismatched! want %s, got %s", want, got)
+		}
+	}
+}
+
+func TestParseStrictNamespaces(t *testing.T) {
+	doc := `<root xmlns="urn:d"><a x:b="1" xmlns:x="urn:x" c="2" xml:lang="en"><x:c/></a></root>`
+	p := xml.NewParser(strings.NewReader(doc))
+	p.StrictNS = true
+	res, err := p.Parse()
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := res.Root().(*xml.Element)
+	if root.Uri != "urn:d" {
+		t.Errorf("root: namespace mismatched! want urn:d, got %s", root.Uri)
+	}
+	elem := root.Nodes[0].(*xml.Element)
+	want := map[string]string{
+		"x:b":      "urn:x",
+		"c":        "",
+		"xml:lang": xml.XmlNamespace,
+	}
+	for _, a := range elem.Attributes() {
+		if uri := want[a.QualifiedName()]; uri != a.Uri {
+			t.Errorf("%s: namespace mismatched! want %q, got %q", a.QualifiedName(), uri, a.Uri)
+		}
+	}
+	scope := xml.InScopeNamespaces(elem.Nodes[0])
+	if len(scope) != 3 {
+		t.Errorf("in scope namespaces mismatched! got %v", scope)
+	}
+
+	for _, doc := range []string{`<root><y:a/></root>`, `<root y:a="1"/>`} {
+		p := xml.NewParser(strings.NewReader(doc))
+		p.StrictNS = true
+		if _, err := p.Parse(); err == nil {
+			t.Errorf("%s: expected error for undeclared prefix", doc)
+		}
+	}
+}
+
+func TestNamespaceMap(t *testing.T) {
+	doc := `<root xmlns="urn:d" xmlns:b="urn:c" id="1" xmlns:a="urn:a"/>`
+	res, err := xml.NewParser(strings.NewReader(doc)).Parse()
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := res.Root().(*xml.Element)
+	want := []xml.NS{
+		{Prefix: "", Uri: "urn:d"},
+		{Prefix: "b", Uri: "urn:c"},
+		{Prefix: "a", Uri: "urn:a"},
+	}
+	for i := 0; i < 3; i++ {
+		got := root.Namespaces()
+		if !slices.Equal(got, want) {
+			t.Fatalf("namespaces mismatched! want %v, got %v", want, got)
+		}
+	}
+	if uri, ok := root.NamespaceMap().Resolve("a"); !ok || uri != "urn:a" {
+		t.Errorf("prefix a: want urn:a, got %q", uri)
+	}
+	root.SetAttribute(xml.NewAttribute(xml.QualifiedName("a", "xmlns"), "urn:e"))
+	if uri, _ := root.NamespaceMap().Resolve("a"); uri != "urn:e" {
+		t.Errorf("prefix a: namespace not updated after mutation! got %q", uri)
+	}
+	root.RemoveAttribute(xml.QualifiedName("b", "xmlns"))
+	if _, ok := root.NamespaceMap().Prefix("urn:c"); ok {
+		t.Errorf("urn:c: namespace still available after removing its declaration")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"slices"
 	"strconv"
 	"strings"
@@ -125,7 +126,10 @@ func (n NS) Default() bool {
 	return n.Prefix == ""
 }
 
-var ErrElement = errors.New("element expected")
+var (
+	ErrElement = errors.New("element expected")
+	ErrDepth   = errors.New("maximum depth reached")
+)
 
 type DocType struct {
 	Name     string
@@ -146,8 +150,13 @@ type Document struct {
 	Version    string
 	Encoding   string
 	Standalone string
+	BOM        bool
+	URI        string
+
+	Preamble []Node
+	Nodes    []Node
 
-	Nodes []Node
+	source *nodeSource
 }
 
 func NewDocument(root Node) *Document {
@@ -216,18 +225,43 @@ func (d *Document) Append(node Node) error {
 	root := d.Root()
 	if el, ok := root.(*Element); ok {
 		el.Append(node)
+		return nil
 	}
 	return ErrElement
 }
 
+func (d *Document) AppendNode(node Node) {
+	d.attach(node)
+}
+
 func (d *Document) Insert(node Node, index int) error {
 	root := d.Root()
 	if el, ok := root.(*Element); ok {
-		el.Insert(node, index)
+		return el.Insert(node, index)
 	}
 	return ErrElement
 }
 
+// InsertBefore inserts node in the children of the document just before ref.
+// If node is already attached to a parent, it is detached first.
+func (d *Document) InsertBefore(node, ref Node) error {
+	at, err := indexOf(d, ref)
+	if err != nil {
+		return err
+	}
+	return insertAt(d, at, node)
+}
+
+// InsertAfter inserts node in the children of the document just after ref.
+// If node is already attached to a parent, it is detached first.
+func (d *Document) InsertAfter(node, ref Node) error {
+	at, err := indexOf(d, ref)
+	if err != nil {
+		return err
+	}
+	return insertAt(d, at+1, node)
+}
+
 func (d *Document) Map() (map[string]any, error) {
 	root := d.Root()
 	if el, ok := root.(*Element); ok {
@@ -310,6 +344,7 @@ type Attribute struct {
 	QName
 	SchemaType QName
 	Datum      string
+	Location   Position
 
 	parent   Node
 	position int
@@ -387,9 +422,14 @@ type Element struct {
 	SchemaType QName
 	Attrs      []Attribute
 	Nodes      []Node
+	Location   Position
 
-	parent   Node
-	position int
+	end        Position
+	source     *nodeSource
+	parent     Node
+	position   int
+	index      *attrIndex
+	namespaces *NamespaceMap
 }
 
 func NewElement(name QName) *Element {
@@ -411,20 +451,14 @@ func (e *Element) Path() []PathInfo {
 }
 
 func (e *Element) Namespaces() []NS {
-	var ns []NS
-	for _, a := range e.Attrs {
-		if a.Name == "xmlns" || a.Space == "xmlns" {
-			n := NS{
-				Prefix: a.Name,
-				Uri:    a.Value(),
-			}
-			if n.Prefix == "xmlns" {
-				n.Prefix = ""
-			}
-			ns = append(ns, n)
-		}
+	return e.NamespaceMap().List()
+}
+
+func (e *Element) NamespaceMap() *NamespaceMap {
+	if e.namespaces == nil {
+		e.namespaces = createNamespaceMap(e.Attrs)
 	}
-	return ns
+	return e.namespaces
 }
 
 func (e *Element) Attributes() []Attribute {
@@ -442,6 +476,7 @@ func (e *Element) Copy() Node {
 	c := &Element{
 		QName:    e.QName,
 		Attrs:    slices.Clone(e.Attrs),
+		Location: e.Location,
 		parent:   e.parent,
 		position: e.position,
 	}
@@ -449,22 +484,34 @@ func (e *Element) Copy() Node {
 }
 
 func (e *Element) Clone() Node {
-	c := &Element{
-		QName:    e.QName,
-		Attrs:    slices.Clone(e.Attrs),
-		parent:   e.parent,
-		position: e.position,
+	type pair struct {
+		src *Element
+		dst *Element
 	}
-	for i := range e.Nodes {
-		if x, ok := e.Nodes[i].(Cloner); ok {
-			if y := x.Clone(); y != nil {
-				c.Append(y)
+	var (
+		root  = e.Copy().(*Element)
+		stack = []pair{{src: e, dst: root}}
+	)
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, n := range curr.src.Nodes {
+			if el, ok := n.(*Element); ok {
+				c := el.Copy().(*Element)
+				curr.dst.Append(c)
+				stack = append(stack, pair{src: el, dst: c})
+				continue
+			}
+			if x, ok := n.(Cloner); ok {
+				if y := x.Clone(); y != nil {
+					curr.dst.Append(y)
+				}
+			} else {
+				curr.dst.Append(n)
 			}
-		} else {
-			c.Append(e.Nodes[i])
 		}
 	}
-	return c
+	return root
 }
 
 func (e *Element) Clear() {
@@ -516,6 +563,195 @@ func (e *Element) InsertNodes(at int, nodes []Node) error {
 	return nil
 }
 
+// InsertBefore inserts node in the children of e just before ref. If node
+// is already attached to a parent, it is detached first.
+func (e *Element) InsertBefore(node, ref Node) error {
+	at, err := indexOf(e, ref)
+	if err != nil {
+		return err
+	}
+	return insertAt(e, at, node)
+}
+
+// InsertAfter inserts node in the children of e just after ref. If node
+// is already attached to a parent, it is detached first.
+func (e *Element) InsertAfter(node, ref Node) error {
+	at, err := indexOf(e, ref)
+	if err != nil {
+		return err
+	}
+	return insertAt(e, at+1, node)
+}
+
+// ReplaceChild replaces the child old of e by node. old is detached from e.
+func (e *Element) ReplaceChild(node, old Node) error {
+	if node == old {
+		return nil
+	}
+	if err := e.InsertBefore(node, old); err != nil {
+		return err
+	}
+	return Detach(old)
+}
+
+// SetText replaces all the children of e by a single text node.
+func (e *Element) SetText(text string) {
+	for _, n := range e.Nodes {
+		n.setParent(nil)
+	}
+	e.Nodes = e.Nodes[:0]
+	e.Append(NewText(text))
+}
+
+// Detach removes node from its parent element or document and updates the
+// position of its former siblings.
+func Detach(node Node) error {
+	parent := node.Parent()
+	list, ok := childrenOf(parent)
+	if !ok {
+		return ErrElement
+	}
+	at, err := indexOf(parent, node)
+	if err != nil {
+		return err
+	}
+	*list = slices.Delete(*list, at, at+1)
+	reindex(*list, at)
+	node.setParent(nil)
+	return nil
+}
+
+// Wrap replaces node in its parent by wrapper and moves node into wrapper as
+// its last child.
+func Wrap(node Node, wrapper *Element) error {
+	parent := node.Parent()
+	list, ok := childrenOf(parent)
+	if !ok {
+		return ErrElement
+	}
+	for p := parent; p != nil; p = p.Parent() {
+		if p == wrapper {
+			return fmt.Errorf("%s: node can not be wrapped into its ancestor", wrapper.QualifiedName())
+		}
+	}
+	if wrapper.Parent() != nil {
+		if err := Detach(wrapper); err != nil {
+			return err
+		}
+	}
+	at, err := indexOf(parent, node)
+	if err != nil {
+		return err
+	}
+	(*list)[at] = wrapper
+	wrapper.setParent(parent)
+	wrapper.setPosition(at)
+	node.setParent(nil)
+	wrapper.Append(node)
+	return nil
+}
+
+// Unwrap replaces elem in its parent by the children of elem. When the
+// parent is a document, elem should have exactly one element child.
+func Unwrap(elem *Element) error {
+	parent := elem.Parent()
+	list, ok := childrenOf(parent)
+	if !ok {
+		return ErrElement
+	}
+	at, err := indexOf(parent, elem)
+	if err != nil {
+		return err
+	}
+	if _, ok := parent.(*Document); ok {
+		var count int
+		for _, n := range elem.Nodes {
+			switch n.Type() {
+			case TypeElement:
+				count++
+			case TypeComment, TypeInstruction:
+			default:
+				return fmt.Errorf("%s: %s can not be moved into document", elem.QualifiedName(), n.Type())
+			}
+		}
+		if count != 1 {
+			return fmt.Errorf("%s: document should have exactly one root element", elem.QualifiedName())
+		}
+	}
+	nodes := elem.Nodes
+	elem.Nodes = nil
+	*list = slices.Concat((*list)[:at], nodes, (*list)[at+1:])
+	for _, n := range nodes {
+		n.setParent(parent)
+	}
+	reindex(*list, at)
+	elem.setParent(nil)
+	return nil
+}
+
+// childrenOf gives access to the list of children of node if it can have
+// some, that is when node is an element or a document.
+func childrenOf(node Node) (*[]Node, bool) {
+	switch n := node.(type) {
+	case *Element:
+		return &n.Nodes, true
+	case *Document:
+		return &n.Nodes, true
+	default:
+		return nil, false
+	}
+}
+
+func insertAt(parent Node, at int, node Node) error {
+	list, ok := childrenOf(parent)
+	if !ok {
+		return ErrElement
+	}
+	if node.Type() == TypeAttribute || node.Type() == TypeDocument {
+		return fmt.Errorf("%s: %s can not be inserted as child", parent.QualifiedName(), node.Type())
+	}
+	for p := parent; p != nil; p = p.Parent() {
+		if p == node {
+			return fmt.Errorf("%s: node can not be inserted into itself", parent.QualifiedName())
+		}
+	}
+	if doc, ok := parent.(*Document); ok && node.Type() == TypeElement {
+		if root := doc.Root(); root != nil && root != node {
+			return fmt.Errorf("document already has a root element")
+		}
+	}
+	if curr := node.Parent(); curr != nil {
+		if ix, err := indexOf(curr, node); err == nil {
+			if curr == parent && ix < at {
+				at--
+			}
+			Detach(node)
+		}
+	}
+	*list = slices.Insert(*list, at, node)
+	node.setParent(parent)
+	reindex(*list, at)
+	return nil
+}
+
+func indexOf(parent, node Node) (int, error) {
+	list, ok := childrenOf(parent)
+	if !ok {
+		return -1, ErrElement
+	}
+	ix := slices.Index(*list, node)
+	if ix < 0 {
+		return ix, fmt.Errorf("%s: node is not a child", parent.QualifiedName())
+	}
+	return ix, nil
+}
+
+func reindex(nodes []Node, from int) {
+	for i := from; i < len(nodes); i++ {
+		nodes[i].setPosition(i)
+	}
+}
+
 func (_ *Element) Type() NodeType {
 	return TypeElement
 }
@@ -614,42 +850,46 @@ func (e *Element) FindAll(name string) []Node {
 }
 
 func (e *Element) GetElementById(id string) (Node, error) {
-	for _, n := range e.Nodes {
-		sub, ok := n.(*Element)
-		if !ok {
-			continue
-		}
+	for sub := range e.descendants() {
 		x := slices.IndexFunc(sub.Attrs, func(a Attribute) bool {
 			return a.Name == "id" && a.Value() == id
 		})
 		if x >= 0 {
 			return sub, nil
 		}
-		other, err := sub.GetElementById(id)
-		if err == nil && other != nil {
-			return other, nil
-		}
 	}
 	return nil, fmt.Errorf("element with id not found")
 }
 
 func (e *Element) GetElementsByTagName(tag string) ([]Node, error) {
 	var list []Node
-	for _, n := range e.Nodes {
-		sub, ok := n.(*Element)
-		if !ok {
-			continue
-		}
+	for sub := range e.descendants() {
 		if sub.LocalName() == tag {
 			list = append(list, sub)
 		}
-		if others, _ := e.GetElementsByTagName(tag); len(others) > 0 {
-			list = append(list, others...)
-		}
 	}
 	return list, nil
 }
 
+func (e *Element) descendants() iter.Seq[*Element] {
+	fn := func(yield func(*Element) bool) {
+		stack := []*Element{e}
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for i := len(curr.Nodes) - 1; i >= 0; i-- {
+				if sub, ok := curr.Nodes[i].(*Element); ok {
+					stack = append(stack, sub)
+				}
+			}
+			if curr != e && !yield(curr) {
+				return
+			}
+		}
+	}
+	return fn
+}
+
 func (e *Element) Append(node Node) {
 	node.setParent(e)
 	node.setPosition(len(e.Nodes))
@@ -660,11 +900,11 @@ func (e *Element) Append(node Node) {
 	}
 }
 
-func (e *Element) Insert(node Node, index int) {
+func (e *Element) Insert(node Node, index int) error {
 	if index < 0 || index > len(e.Nodes) {
-		return
+		return fmt.Errorf("%s: inserting node with bad index (%d - %d)", e.QualifiedName(), index, len(e.Nodes))
 	}
-	e.Nodes = slices.Insert(e.Nodes, index, node)
+	return insertAt(e, index, node)
 }
 
 func (e *Element) NextSibling() Node {
@@ -728,6 +968,8 @@ func (e *Element) RemoveAttr(at int) error {
 	a := e.Attrs[at]
 	a.setParent(nil)
 	e.Attrs = slices.Delete(e.Attrs, at, at+1)
+	e.index = nil
+	e.namespaces = nil
 	for i := range e.Attrs {
 		e.Attrs[i].setPosition(i)
 	}
@@ -739,31 +981,115 @@ func (e *Element) ClearAttributes() {
 		e.Attrs[i].setParent(nil)
 	}
 	e.Attrs = nil
+	e.index = nil
+	e.namespaces = nil
 }
 
-func (e *Element) GetAttribute(name string) Attribute {
-	ix := slices.IndexFunc(e.Attrs, func(a Attribute) bool {
-		return a.Name == name
-	})
-	var attr Attribute
+func (e *Element) GetAttribute(name string) (string, bool) {
+	ix := e.lookupAttr(name, false)
 	if ix < 0 {
-		return attr
+		return "", false
 	}
-	return e.Attrs[ix]
+	return e.Attrs[ix].Value(), true
+}
+
+func (e *Element) GetAttributeNS(uri, local string) (string, bool) {
+	ix := e.lookupAttr(ExpandedName(local, "", uri).ExpandedName(), true)
+	if ix < 0 {
+		return "", false
+	}
+	return e.Attrs[ix].Value(), true
+}
+
+func (e *Element) SetAttributeValue(name QName, value string) error {
+	return e.SetAttribute(NewAttribute(name, value))
+}
+
+func (e *Element) RemoveAttributeByName(name string) error {
+	ix := e.lookupAttr(name, false)
+	if ix < 0 {
+		return nil
+	}
+	return e.RemoveAttr(ix)
+}
+
+func (e *Element) SetAttributes(attrs []Attribute) {
+	e.Attrs = attrs
+	for i := range e.Attrs {
+		e.Attrs[i].setParent(e)
+		e.Attrs[i].setPosition(i)
+	}
+	e.index = nil
+	e.namespaces = nil
 }
 
 func (e *Element) SetAttribute(attr Attribute) error {
-	ix := slices.IndexFunc(e.Attrs, func(a Attribute) bool {
-		return a.QualifiedName() == attr.QualifiedName()
-	})
+	ix := e.lookupAttr(attr.QualifiedName(), false)
 	if ix < 0 {
+		attr.setPosition(len(e.Attrs))
 		e.Attrs = append(e.Attrs, attr)
 	} else {
+		attr.setPosition(ix)
 		e.Attrs[ix] = attr
 	}
+	e.index = nil
+	e.namespaces = nil
 	return nil
 }
 
+const attrIndexThreshold = 16
+
+type attrIndex struct {
+	names    map[string]int
+	expanded map[string]int
+	size     int
+}
+
+func (e *Element) lookupAttr(name string, expanded bool) int {
+	match := func(a Attribute) bool {
+		if expanded {
+			return a.ExpandedName() == name
+		}
+		return a.QualifiedName() == name
+	}
+	if len(e.Attrs) < attrIndexThreshold {
+		return slices.IndexFunc(e.Attrs, match)
+	}
+	if e.index == nil || e.index.size != len(e.Attrs) {
+		e.buildIndex()
+	}
+	get := func() (int, bool) {
+		if expanded {
+			ix, ok := e.index.expanded[name]
+			return ix, ok
+		}
+		ix, ok := e.index.names[name]
+		return ix, ok
+	}
+	ix, ok := get()
+	if ok && ix < len(e.Attrs) && match(e.Attrs[ix]) {
+		return ix
+	}
+	e.buildIndex()
+	if ix, ok = get(); !ok {
+		return -1
+	}
+	return ix
+}
+
+func (e *Element) buildIndex() {
+	x := attrIndex{
+		names:    make(map[string]int),
+		expanded: make(map[string]int),
+		size:     len(e.Attrs),
+	}
+	for i := len(e.Attrs) - 1; i >= 0; i-- {
+		x.names[e.Attrs[i].QualifiedName()] = i
+		x.expanded[e.Attrs[i].ExpandedName()] = i
+	}
+	e.index = &x
+}
+
 func (e *Element) path() []int {
 	if e.parent == nil {
 		return []int{e.position}
@@ -788,7 +1114,9 @@ type Instruction struct {
 	QName
 	SchemaType QName
 	Attrs      []Attribute
+	Location   Position
 
+	source   *nodeSource
 	parent   Node
 	position int
 }
@@ -900,7 +1228,8 @@ func (i *Instruction) Accept(v Visitor) {
 }
 
 type CharData struct {
-	Content string
+	Content  string
+	Location Position
 
 	parent   Node
 	position int
@@ -912,6 +1241,16 @@ func NewCharacterData(chardata string) *CharData {
 	}
 }
 
+func (c *CharData) Clone() Node {
+	x := &CharData{
+		Content:  c.Content,
+		Location: c.Location,
+		parent:   c.parent,
+		position: c.position,
+	}
+	return x
+}
+
 func (c *CharData) Path() []PathInfo {
 	var (
 		ps = c.parent.Path()
@@ -980,8 +1319,10 @@ func (c *CharData) Accept(v Visitor) {
 }
 
 type Text struct {
-	Content string
+	Content  string
+	Location Position
 
+	source   *nodeSource
 	parent   Node
 	position int
 }
@@ -1006,6 +1347,7 @@ func (t *Text) Path() []PathInfo {
 func (t *Text) Clone() Node {
 	c := &Text{
 		Content:  t.Content,
+		Location: t.Location,
 		parent:   t.parent,
 		position: t.position,
 	}
@@ -1069,7 +1411,8 @@ func (t *Text) Accept(v Visitor) {
 }
 
 type Comment struct {
-	Content string
+	Content  string
+	Location Position
 
 	parent   Node
 	position int
@@ -0,0 +1,273 @@
+package xml_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestElementMutation(t *testing.T) {
+	doc, err := xml.ParseString(`<root><a/><b/><c/></root>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	var (
+		root = doc.Root().(*xml.Element)
+		a    = root.Nodes[0]
+		b    = root.Nodes[1]
+		c    = root.Nodes[2]
+	)
+	steps := []struct {
+		Name string
+		Func func() error
+		Want string
+	}{
+		{
+			Name: "insert-before",
+			Func: func() error { return root.InsertBefore(xml.NewElement(xml.LocalName("x")), b) },
+			Want: `<root><a/><x/><b/><c/></root>`,
+		},
+		{
+			Name: "insert-after",
+			Func: func() error { return root.InsertAfter(a, c) },
+			Want: `<root><x/><b/><c/><a/></root>`,
+		},
+		{
+			Name: "replace-child",
+			Func: func() error { return root.ReplaceChild(xml.NewElement(xml.LocalName("y")), root.Nodes[0]) },
+			Want: `<root><y/><b/><c/><a/></root>`,
+		},
+		{
+			Name: "wrap",
+			Func: func() error { return xml.Wrap(b, xml.NewElement(xml.LocalName("w"))) },
+			Want: `<root><y/><w><b/></w><c/><a/></root>`,
+		},
+		{
+			Name: "set-text",
+			Func: func() error {
+				b.(*xml.Element).SetText("text")
+				return nil
+			},
+			Want: `<root><y/><w><b>text</b></w><c/><a/></root>`,
+		},
+		{
+			Name: "unwrap",
+			Func: func() error { return xml.Unwrap(b.Parent().(*xml.Element)) },
+			Want: `<root><y/><b>text</b><c/><a/></root>`,
+		},
+		{
+			Name: "detach",
+			Func: func() error { return xml.Detach(c) },
+			Want: `<root><y/><b>text</b><a/></root>`,
+		},
+	}
+	for _, s := range steps {
+		if err := s.Func(); err != nil {
+			t.Errorf("%s: unexpected error: %s", s.Name, err)
+			continue
+		}
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+		if err := ws.Write(doc); err != nil {
+			t.Fatalf("%s: error writing document: %s", s.Name, err)
+		}
+		if got := buf.String(); got != s.Want {
+			t.Errorf("%s: result mismatched! want %s, got %s", s.Name, s.Want, got)
+		}
+		for i, n := range root.Nodes {
+			if n.Position() != i || n.Parent() != root {
+				t.Errorf("%s: node %d has inconsistent position or parent", s.Name, i)
+			}
+		}
+	}
+	if c.Parent() != nil {
+		t.Errorf("detached node still has a parent")
+	}
+	if err := root.InsertBefore(root, a); err == nil {
+		t.Errorf("expected error when inserting node into itself")
+	}
+}
+
+func TestDocumentMutation(t *testing.T) {
+	doc, err := xml.ParseString(`<!--head--><root><a/></root>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := doc.Root().(*xml.Element)
+	steps := []struct {
+		Name string
+		Func func() error
+		Want string
+	}{
+		{
+			Name: "wrap",
+			Func: func() error { return xml.Wrap(root, xml.NewElement(xml.LocalName("w"))) },
+			Want: `<!--head--><w><root><a/></root></w>`,
+		},
+		{
+			Name: "unwrap",
+			Func: func() error { return xml.Unwrap(root.Parent().(*xml.Element)) },
+			Want: `<!--head--><root><a/></root>`,
+		},
+		{
+			Name: "insert-after",
+			Func: func() error { return doc.InsertAfter(doc.Nodes[0], root) },
+			Want: `<root><a/></root><!--head-->`,
+		},
+		{
+			Name: "detach",
+			Func: func() error { return xml.Detach(doc.Nodes[1]) },
+			Want: `<root><a/></root>`,
+		},
+		{
+			Name: "insert",
+			Func: func() error { return root.Insert(xml.NewElement(xml.LocalName("b")), 0) },
+			Want: `<root><b/><a/></root>`,
+		},
+	}
+	for _, s := range steps {
+		if err := s.Func(); err != nil {
+			t.Errorf("%s: unexpected error: %s", s.Name, err)
+			continue
+		}
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+		if err := ws.Write(doc); err != nil {
+			t.Fatalf("%s: error writing document: %s", s.Name, err)
+		}
+		if got := buf.String(); got != s.Want {
+			t.Errorf("%s: result mismatched! want %s, got %s", s.Name, s.Want, got)
+		}
+		for i, n := range doc.Nodes {
+			if n.Position() != i || n.Parent() != doc {
+				t.Errorf("%s: node %d has inconsistent position or parent", s.Name, i)
+			}
+		}
+	}
+	if err := doc.InsertBefore(xml.NewElement(xml.LocalName("x")), root); err == nil {
+		t.Errorf("expected error when inserting a second root element")
+	}
+	if err := xml.Unwrap(root); err == nil {
+		t.Errorf("expected error when unwrapping root with multiple elements")
+	}
+	if err := root.Insert(xml.NewElement(xml.LocalName("x")), 10); err == nil {
+		t.Errorf("expected error when inserting with bad index")
+	}
+}
+
+func TestElementAttributes(t *testing.T) {
+	var str strings.Builder
+	str.WriteString(`<root xmlns:x="http://x.org" x:lang="fr" lang="en"`)
+	for i := range 20 {
+		fmt.Fprintf(&str, ` a%d="%d"`, i, i)
+	}
+	str.WriteString(`/>`)
+
+	doc, err := xml.ParseString(str.String())
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := doc.Root().(*xml.Element)
+	if v, ok := root.GetAttribute("lang"); !ok || v != "en" {
+		t.Errorf("lang: want en, got %s", v)
+	}
+	if v, ok := root.GetAttribute("x:lang"); !ok || v != "fr" {
+		t.Errorf("x:lang: want fr, got %s", v)
+	}
+	if v, ok := root.GetAttributeNS("http://x.org", "lang"); !ok || v != "fr" {
+		t.Errorf("{http://x.org}lang: want fr, got %s", v)
+	}
+	if v, ok := root.GetAttribute("a19"); !ok || v != "19" {
+		t.Errorf("a19: want 19, got %s", v)
+	}
+	if _, ok := root.GetAttribute("a20"); ok {
+		t.Errorf("a20: attribute should not be found")
+	}
+	root.SetAttributeValue(xml.LocalName("a20"), "20")
+	if v, ok := root.GetAttribute("a20"); !ok || v != "20" {
+		t.Errorf("a20: want 20, got %s", v)
+	}
+	if err := root.RemoveAttributeByName("a0"); err != nil {
+		t.Fatalf("error removing attribute: %s", err)
+	}
+	if _, ok := root.GetAttribute("a0"); ok {
+		t.Errorf("a0: attribute should have been removed")
+	}
+	if v, ok := root.GetAttribute("a1"); !ok || v != "1" {
+		t.Errorf("a1: want 1, got %s", v)
+	}
+}
+
+func TestElementNamespaceMap(t *testing.T) {
+	doc, err := xml.ParseString(`<root xmlns:a="http://a.org" xmlns:b="http://b.org"/>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := doc.Root().(*xml.Element)
+	if uri, ok := root.NamespaceMap().Resolve("a"); !ok || uri != "http://a.org" {
+		t.Errorf("a: want http://a.org, got %s", uri)
+	}
+	root.SetAttributeValue(xml.QualifiedName("a", "xmlns"), "http://x.org")
+	if uri, ok := root.NamespaceMap().Resolve("a"); !ok || uri != "http://x.org" {
+		t.Errorf("a: want http://x.org after update, got %s", uri)
+	}
+	attrs := []xml.Attribute{
+		xml.NewAttribute(xml.QualifiedName("a", "xmlns"), "http://x.org"),
+		xml.NewAttribute(xml.QualifiedName("c", "xmlns"), "http://c.org"),
+	}
+	root.SetAttributes(attrs)
+	if _, ok := root.NamespaceMap().Resolve("b"); ok {
+		t.Errorf("b: namespace should have been replaced")
+	}
+	if uri, ok := root.NamespaceMap().Resolve("c"); !ok || uri != "http://c.org" {
+		t.Errorf("c: want http://c.org, got %s", uri)
+	}
+}
+
+func TestElementTypedAttributes(t *testing.T) {
+	doc, err := xml.ParseString(`<root count=" 42 " ratio="0.5" indent="yes" strict="false" since="2024-03-01" at="2024-03-01T10:30:00Z" bad="foo"/>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	root := doc.Root().(*xml.Element)
+	if v, err := root.AttrInt("count", 0); err != nil || v != 42 {
+		t.Errorf("count: want 42, got %d (%v)", v, err)
+	}
+	if v, err := root.AttrInt("missing", 7); err != nil || v != 7 {
+		t.Errorf("missing: want default 7, got %d (%v)", v, err)
+	}
+	if v, err := root.AttrFloat("ratio", 0); err != nil || v != 0.5 {
+		t.Errorf("ratio: want 0.5, got %f (%v)", v, err)
+	}
+	if v, err := root.AttrBool("indent", false); err != nil || !v {
+		t.Errorf("indent: want true, got %t (%v)", v, err)
+	}
+	if v, err := root.AttrBool("strict", true); err != nil || v {
+		t.Errorf("strict: want false, got %t (%v)", v, err)
+	}
+	if v, err := root.AttrTime("since", time.Time{}); err != nil || !v.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("since: unexpected date %s (%v)", v, err)
+	}
+	if v, err := root.AttrTime("at", time.Time{}); err != nil || !v.Equal(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("at: unexpected time %s (%v)", v, err)
+	}
+	if _, err := root.AttrInt("bad", 0); !errors.Is(err, xml.ErrType) {
+		t.Errorf("bad: expected type error, got %v", err)
+	}
+	if _, err := root.AttrBool("bad", false); !errors.Is(err, xml.ErrType) {
+		t.Errorf("bad: expected type error, got %v", err)
+	}
+	if v, err := root.AttrFloat("bad", 1.5); err == nil || v != 1.5 {
+		t.Errorf("bad: expected error and default value, got %f (%v)", v, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -52,20 +53,41 @@ type Parser struct {
 
 	depth int
 
+	lossless  bool
+	capturing bool
+	source    strings.Builder
+
 	TrimSpace  bool
 	KeepEmpty  bool
 	OmitProlog bool
 	StrictNS   bool
 	MaxDepth   int
 
+	PreserveSpace []string
+	spaces        []bool
+
 	namespaces environ.Environ[string]
 
 	piFuncs map[string]PiFunc
 }
 
 func NewParser(r io.Reader) *Parser {
+	return createParser(Scan(r))
+}
+
+func NewLosslessParser(r io.Reader) *Parser {
+	scan := Scan(r)
+	scan.keepRaw = true
+
+	p := createParser(scan)
+	p.TrimSpace = false
+	p.lossless = true
+	return p
+}
+
+func createParser(scan *Scanner) *Parser {
 	p := Parser{
-		scan:       Scan(r),
+		scan:       scan,
 		TrimSpace:  true,
 		MaxDepth:   MaxDepth,
 		piFuncs:    make(map[string]PiFunc),
@@ -102,16 +124,41 @@ func (p *Parser) UnregisterPI(name string) {
 }
 
 func (p *Parser) Parse() (*Document, error) {
-	if _, err := p.parseProlog(); err != nil {
-		return nil, err
-	}
-	for p.is(Literal) {
-		p.next()
-	}
 	var (
 		doc Document
 		err error
 	)
+	doc.BOM = p.scan.bom
+	preamble, err := p.parsePreamble()
+	if err != nil {
+		return nil, err
+	}
+	prolog, err := p.parseProlog()
+	if err != nil {
+		return nil, err
+	}
+	if prolog != nil {
+		doc.Preamble = preamble
+		if pi, ok := prolog.(*Instruction); ok {
+			doc.source = pi.source
+		}
+	} else {
+		for _, n := range preamble {
+			if n.Type() == TypeComment || p.lossless {
+				doc.attach(n)
+			}
+		}
+	}
+	for p.is(Literal) {
+		if !p.lossless {
+			p.next()
+			continue
+		}
+		node, _ := p.parseLiteral()
+		if node != nil {
+			doc.attach(node)
+		}
+	}
 	doc.Version = SupportedVersion
 	doc.Encoding = SupportedEncoding
 	for !p.done() {
@@ -123,14 +170,20 @@ func (p *Parser) Parse() (*Document, error) {
 			continue
 		}
 		switch node.Type() {
-		case TypeComment, TypeElement:
+		case TypeComment:
+		case TypeElement:
+			if doc.Root() != nil {
+				return nil, p.createError("document", "unexpected element after root element")
+			}
 		case TypeText:
-			continue
+			if !p.lossless {
+				continue
+			}
 		default:
 			return nil, p.createError("document", "invalid node type")
 		}
 		doc.attach(node)
-		if node.Type() == TypeElement {
+		if node.Type() == TypeElement && !p.lossless {
 			break
 		}
 	}
@@ -140,6 +193,26 @@ func (p *Parser) Parse() (*Document, error) {
 	return &doc, err
 }
 
+func (p *Parser) parsePreamble() ([]Node, error) {
+	var nodes []Node
+	for p.is(Literal) || p.is(CommentTag) {
+		if p.is(CommentTag) {
+			node, err := p.parseComment()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			continue
+		}
+		if strings.TrimSpace(p.curr.Literal) != "" {
+			return nil, p.createError("document", "unexpected content before root element")
+		}
+		nodes = append(nodes, NewText(p.curr.Literal))
+		p.next()
+	}
+	return nodes, nil
+}
+
 func (p *Parser) parseProlog() (Node, error) {
 	if !p.is(ProcInstTag) {
 		// if !p.OmitProlog {
@@ -211,11 +284,13 @@ func (p *Parser) parseElement() (Node, error) {
 		}
 		p.namespaces = u.Unwrap()
 	}()
-	p.next()
 	var (
 		elem Element
 		err  error
 	)
+	elem.Location = p.curr.Position
+	p.startCapture()
+	p.next()
 	if p.is(Namespace) {
 		elem.Space = p.getCurrentLiteral()
 		p.next()
@@ -233,16 +308,28 @@ func (p *Parser) parseElement() (Node, error) {
 		return nil, err
 	}
 
-	if elem.Uri, err = p.isDefined(elem.QName); err != nil {
+	if elem.Uri, err = p.resolveNS(elem.QName, false); err != nil {
+		return nil, err
+	}
+	for i := range elem.Attrs {
+		if elem.Attrs[i].Uri, err = p.resolveNS(elem.Attrs[i].QName, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.enterSpace(&elem); err != nil {
 		return nil, err
 	}
+	defer p.leaveSpace()
 
 	switch p.curr.Type {
 	case EmptyElemTag:
 		p.next()
+		elem.source = p.stopCapture(signature(elem.QName, elem.Attrs, nil))
+		elem.end = p.curr.Position
 		return &elem, nil
 	case EndTag:
 		p.next()
+		elem.source = p.stopCapture(signature(elem.QName, elem.Attrs, nil))
 		var pos int
 		for !p.done() && !p.is(CloseTag) {
 			child, err := p.parseNode()
@@ -259,8 +346,16 @@ func (p *Parser) parseElement() (Node, error) {
 		if !p.is(CloseTag) {
 			return nil, p.createError("element", "closing element is missing")
 		}
+		p.startCapture()
 		p.next()
-		return &elem, p.parseCloseElement(elem)
+		if err := p.parseCloseElement(elem); err != nil {
+			return nil, err
+		}
+		if end := p.stopCapture(""); end != nil {
+			elem.source.end = end.raw
+		}
+		elem.end = p.curr.Position
+		return &elem, nil
 	default:
 		return nil, p.createError("element", "end of element expected")
 	}
@@ -271,9 +366,6 @@ func (p *Parser) parseCloseElement(elem Element) error {
 		return p.createError("element", "closing element without namespace")
 	}
 	if p.is(Namespace) {
-		if _, err := p.isDefined(elem.QName); err != nil {
-			return err
-		}
 		if elem.Space != p.getCurrentLiteral() {
 			return p.createError("element", "namespace mismatched with opening element")
 		}
@@ -294,11 +386,13 @@ func (p *Parser) parseCloseElement(elem Element) error {
 }
 
 func (p *Parser) parsePI() (Node, error) {
+	var elem Instruction
+	elem.Location = p.curr.Position
+	p.startCapture()
 	p.next()
 	if !p.is(Name) {
 		return nil, p.createError("processing instruction", "name is missing")
 	}
-	var elem Instruction
 	elem.Name = p.getCurrentLiteral()
 	p.next()
 	var err error
@@ -312,6 +406,7 @@ func (p *Parser) parsePI() (Node, error) {
 		return nil, p.createError("processing instruction", "end of element expected")
 	}
 	p.next()
+	elem.source = p.stopCapture(signature(elem.QName, elem.Attrs, nil))
 	fn, ok := p.piFuncs[elem.Name]
 	if ok {
 		return fn(elem.Name, elem.Attrs)
@@ -340,10 +435,8 @@ func (p *Parser) parseAttributes(parent Node, done func() bool) ([]Attribute, er
 }
 
 func (p *Parser) parseAttr() (Attribute, error) {
-	var (
-		attr Attribute
-		err  error
-	)
+	var attr Attribute
+	attr.Location = p.curr.Position
 	if p.is(Namespace) {
 		attr.Space = p.getCurrentLiteral()
 		p.next()
@@ -358,21 +451,19 @@ func (p *Parser) parseAttr() (Attribute, error) {
 	}
 	attr.Datum = p.getCurrentLiteral()
 	p.next()
-	if attr.Name == AttrXmlNS {
+	if attr.Name == AttrXmlNS && attr.Space == "" {
 		p.defineNS("", attr.Datum)
 	} else if attr.Space == AttrXmlNS {
 		p.defineNS(attr.Name, attr.Datum)
 	}
-	if attr.Uri, err = p.isDefined(attr.QName); err != nil {
-		return attr, err
-	}
 	return attr, nil
 }
 
 func (p *Parser) parseComment() (Node, error) {
 	defer p.next()
 	node := Comment{
-		Content: p.getCurrentLiteral(),
+		Content:  p.getCurrentLiteral(),
+		Location: p.curr.Position,
 	}
 	return &node, nil
 }
@@ -380,18 +471,26 @@ func (p *Parser) parseComment() (Node, error) {
 func (p *Parser) parseCharData() (Node, error) {
 	defer p.next()
 	char := CharData{
-		Content: p.getCurrentLiteral(),
+		Content:  p.getCurrentLiteral(),
+		Location: p.curr.Position,
 	}
 	return &char, nil
 }
 
 func (p *Parser) parseLiteral() (Node, error) {
 	text := Text{
-		Content: p.getCurrentLiteral(),
+		Content:  p.getCurrentLiteral(),
+		Location: p.curr.Position,
 	}
-	if p.TrimSpace {
+	if p.TrimSpace && !p.preserveSpace() {
 		text.Content = strings.TrimSpace(text.Content)
 	}
+	if p.lossless {
+		text.source = &nodeSource{
+			raw: p.curr.Raw,
+			sig: text.Content,
+		}
+	}
 	p.next()
 	if !p.KeepEmpty && text.Content == "" {
 		return nil, nil
@@ -399,15 +498,23 @@ func (p *Parser) parseLiteral() (Node, error) {
 	return &text, nil
 }
 
-func (p *Parser) isDefined(qn QName) (string, error) {
-	if qn.Name == AttrXmlNS {
+func (p *Parser) resolveNS(qn QName, attr bool) (string, error) {
+	switch {
+	case qn.Space == AttrXmlNS || (qn.Space == "" && qn.Name == AttrXmlNS):
+		return "", nil
+	case qn.Space == "xml":
+		return XmlNamespace, nil
+	case qn.Space == "" && attr:
 		return "", nil
 	}
 	uri, err := p.namespaces.Resolve(qn.Space)
-	if err != nil && p.StrictNS {
-		return "", fmt.Errorf("%s: namespace is not defined", qn.Space)
+	if err == nil || qn.Space == "" {
+		return uri, nil
+	}
+	if p.StrictNS {
+		return "", p.createError(qn.QualifiedName(), fmt.Sprintf("namespace prefix %s is not declared", qn.Space))
 	}
-	return uri, nil
+	return "", nil
 }
 
 func (p *Parser) defineNS(ident, uri string) {
@@ -438,7 +545,52 @@ func (p *Parser) leave() {
 	p.depth--
 }
 
+func (p *Parser) enterSpace(elem *Element) error {
+	preserve, ok, err := spaceMode(elem.Attrs)
+	if err != nil {
+		return p.createError("attribute", err.Error())
+	}
+	if !ok {
+		preserve = p.preserveSpace() || matchElementName(p.PreserveSpace, elem)
+	}
+	p.spaces = append(p.spaces, preserve)
+	return nil
+}
+
+func (p *Parser) leaveSpace() {
+	if n := len(p.spaces); n > 0 {
+		p.spaces = p.spaces[:n-1]
+	}
+}
+
+func (p *Parser) preserveSpace() bool {
+	n := len(p.spaces)
+	return n > 0 && p.spaces[n-1]
+}
+
+func (p *Parser) startCapture() {
+	if !p.lossless {
+		return
+	}
+	p.source.Reset()
+	p.capturing = true
+}
+
+func (p *Parser) stopCapture(sig string) *nodeSource {
+	if !p.capturing {
+		return nil
+	}
+	p.capturing = false
+	return &nodeSource{
+		raw: p.source.String(),
+		sig: sig,
+	}
+}
+
 func (p *Parser) next() {
+	if p.capturing {
+		p.source.WriteString(p.curr.Raw)
+	}
 	p.curr = p.peek
 	p.peek = p.scan.Scan()
 }
@@ -463,10 +615,45 @@ const (
 type Position struct {
 	Line   int
 	Column int
+	Offset int
+}
+
+func (p Position) Zero() bool {
+	return p.Line == 0 && p.Column == 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+func LocationOf(node Node) Position {
+	for ; node != nil; node = node.Parent() {
+		var pos Position
+		switch n := node.(type) {
+		case *Element:
+			pos = n.Location
+		case *Attribute:
+			pos = n.Location
+		case *Text:
+			pos = n.Location
+		case *CharData:
+			pos = n.Location
+		case *Comment:
+			pos = n.Location
+		case *Instruction:
+			pos = n.Location
+		default:
+		}
+		if !pos.Zero() {
+			return pos
+		}
+	}
+	return Position{}
 }
 
 type Token struct {
 	Literal string
+	Raw     string
 	Type    rune
 	Position
 }
@@ -544,6 +731,10 @@ type Scanner struct {
 	input io.RuneScanner
 	char  rune
 	str   bytes.Buffer
+	bom   bool
+
+	keepRaw bool
+	raw     strings.Builder
 
 	Position
 	old Position
@@ -556,19 +747,43 @@ func Scan(r io.Reader) *Scanner {
 		rs    = bufio.NewReader(r)
 		pk, _ = rs.Peek(3)
 	)
-	if bytes.Equal(pk, []byte{0xEF, 0xBB, 0xBF}) {
+	bom := bytes.Equal(pk, []byte{0xEF, 0xBB, 0xBF})
+	if bom {
 		rs.Discard(3)
 	}
 
 	scan := &Scanner{
 		input: rs,
+		bom:   bom,
 	}
 	scan.Position.Line = 1
+	if bom {
+		scan.Position.Offset = 3
+	}
+	scan.read()
+	return scan
+}
+
+func scanFrom(r io.Reader, pos Position) *Scanner {
+	scan := &Scanner{
+		input: bufio.NewReader(r),
+	}
+	scan.Position = pos
+	scan.Column--
 	scan.read()
 	return scan
 }
 
 func (s *Scanner) Scan() Token {
+	s.raw.Reset()
+	tok := s.scan()
+	if s.keepRaw {
+		tok.Raw = s.raw.String()
+	}
+	return tok
+}
+
+func (s *Scanner) scan() Token {
 	var tok Token
 	tok.Position = s.Position
 	if s.done() {
@@ -714,19 +929,20 @@ func (s *Scanner) scanClosingTag(tok *Token) {
 func (s *Scanner) scanValue(tok *Token) {
 	q := s.char
 	s.read()
+	tok.Type = Literal
 	for !s.done() && s.char != q {
-		s.write()
-		s.read()
 		if s.char == ampersand {
 			str := s.scanEntity()
 			if str == "" {
+				tok.Type = Invalid
 				break
 			}
 			s.str.WriteString(str)
 			continue
 		}
+		s.write()
+		s.read()
 	}
-	tok.Type = Literal
 	tok.Literal = s.str.String()
 	if s.char != q {
 		tok.Type = Invalid
@@ -749,14 +965,51 @@ func (s *Scanner) scanEntity() string {
 	}
 	str.WriteRune(semicolon)
 	s.read()
-	return html.UnescapeString(str.String())
+	ref := str.String()
+	if strings.HasPrefix(ref, "&#") {
+		return decodeCharRef(ref[2 : len(ref)-1])
+	}
+	return html.UnescapeString(ref)
+}
+
+func decodeCharRef(ref string) string {
+	var (
+		n   int64
+		err error
+	)
+	if x, ok := strings.CutPrefix(ref, "x"); ok {
+		n, err = strconv.ParseInt(x, 16, 32)
+	} else {
+		n, err = strconv.ParseInt(ref, 10, 32)
+	}
+	if err != nil || !isChar(rune(n)) {
+		return ""
+	}
+	return string(rune(n))
+}
+
+func isChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r >= 0x20 && r <= 0xD7FF:
+		return true
+	case r >= 0xE000 && r <= 0xFFFD:
+		return true
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	default:
+		return false
+	}
 }
 
 func (s *Scanner) scanLiteral(tok *Token) {
+	tok.Type = Literal
 	for !s.done() && s.char != langle {
 		if s.char == ampersand {
 			str := s.scanEntity()
 			if str == "" {
+				tok.Type = Invalid
 				break
 			}
 			s.str.WriteString(str)
@@ -766,7 +1019,6 @@ func (s *Scanner) scanLiteral(tok *Token) {
 		}
 
 	}
-	tok.Type = Literal
 	tok.Literal = s.str.String()
 	if s.char == langle {
 		s.state = 0
@@ -801,7 +1053,13 @@ func (s *Scanner) write() {
 }
 
 func (s *Scanner) read() {
+	if s.keepRaw && s.char != 0 && !s.done() {
+		s.raw.WriteRune(s.char)
+	}
 	s.old = s.Position
+	if s.char != 0 && !s.done() {
+		s.Offset += utf8.RuneLen(s.char)
+	}
 	if s.char == '\n' {
 		s.Column = 0
 		s.Line++
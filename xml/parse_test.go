@@ -0,0 +1,393 @@
+package xml_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestParseCharacterReference(t *testing.T) {
+	data := []struct {
+		Input string
+		Want  string
+		Fail  bool
+	}{
+		{
+			Input: `<root>&#65;&#x42;&#x1F600;&#233;</root>`,
+			Want:  "AB😀é",
+		},
+		{
+			Input: `<root>&lt;&amp;&gt;</root>`,
+			Want:  "<&>",
+		},
+		{
+			Input: `<root>&#xZZ;</root>`,
+			Fail:  true,
+		},
+		{
+			Input: `<root>&#xD800;</root>`,
+			Fail:  true,
+		},
+		{
+			Input: `<root>&#1;</root>`,
+			Fail:  true,
+		},
+	}
+	for _, d := range data {
+		doc, err := parseDocument(d.Input)
+		if d.Fail {
+			if err == nil {
+				t.Errorf("%s: expected error but parsing pass", d.Input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: fail to parse input document: %s", d.Input, err)
+			continue
+		}
+		if got := doc.Root().Value(); got != d.Want {
+			t.Errorf("%s: result mismatched: want %q, got %q", d.Input, d.Want, got)
+		}
+	}
+}
+
+func TestParsePreamble(t *testing.T) {
+	data := []struct {
+		Input    string
+		Want     string
+		Fail     bool
+		Lossless bool
+	}{
+		{
+			Input: "\uFEFF<?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Want:  "\uFEFF<?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+		},
+		{
+			Input: "\n  <?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Want:  "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n  <root/>",
+		},
+		{
+			Input: "\uFEFF <!--exported--> <?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Want:  "\uFEFF<?xml version=\"1.0\" encoding=\"UTF-8\"?> <!--exported--> <root/>",
+		},
+		{
+			Input:    "\uFEFF <!--exported--> <?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Want:     "\uFEFF <!--exported--> <?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Lossless: true,
+		},
+		{
+			Input: "\n<!--first--><root/>",
+			Want:  "<?xml version=\"1.0\" encoding=\"UTF-8\"?><!--first--><root/>",
+		},
+		{
+			Input: "text<?xml version=\"1.0\" encoding=\"UTF-8\"?><root/>",
+			Fail:  true,
+		},
+	}
+	for _, d := range data {
+		parse := parseDocument
+		if d.Lossless {
+			parse = func(str string) (*xml.Document, error) {
+				return xml.NewLosslessParser(strings.NewReader(str)).Parse()
+			}
+		}
+		doc, err := parse(d.Input)
+		if d.Fail {
+			if err == nil {
+				t.Errorf("%q: expected error but parsing pass", d.Input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: fail to parse input document: %s", d.Input, err)
+			continue
+		}
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions |= xml.OptionCompact
+		if d.Lossless {
+			ws.WriterOptions = xml.OptionLossless
+		}
+		if err := ws.Write(doc); err != nil {
+			t.Errorf("%q: fail to write document: %s", d.Input, err)
+			continue
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("%q: result mismatched: want %q, got %q", d.Input, d.Want, got)
+		}
+	}
+}
+
+func TestDeepDocument(t *testing.T) {
+	var (
+		root = xml.NewElement(xml.LocalName("root"))
+		curr = root
+	)
+	for range 100_000 {
+		child := xml.NewElement(xml.LocalName("item"))
+		curr.Append(child)
+		curr = child
+	}
+	curr.SetAttribute(xml.NewAttribute(xml.LocalName("id"), "last"))
+
+	clone := root.Clone().(*xml.Element)
+	if n, err := clone.GetElementById("last"); err != nil || n == nil {
+		t.Errorf("cloned element with id not found: %v", err)
+	}
+	if list, _ := root.GetElementsByTagName("item"); len(list) != 100_000 {
+		t.Errorf("elements count mismatched! want %d, got %d", 100_000, len(list))
+	}
+	var buf strings.Builder
+	if err := xml.NewWriter(&buf).Write(xml.NewDocument(root)); !errors.Is(err, xml.ErrDepth) {
+		t.Errorf("expected %s, got %v", xml.ErrDepth, err)
+	}
+}
+
+func TestParseLossless(t *testing.T) {
+	tests := []string{
+		`<root/>`,
+		"<?xml version='1.0'  encoding=\"utf-8\" ?>\n<!-- top -->\n<root  b='2' a=\"1\"   >\n\t<item id=\"x\"  />\n</root>\n",
+		"<x:root xmlns:x=\"urn:x\" x:k='v &amp; &#x41; &quot;'>a &lt; b &gt; c&#10;</x:root  >",
+		"\n\n<root>\r\n<empty></empty><?pi data=\"1\" ?><![CDATA[ <raw> ]]>\r\n</root>\r\n<!-- tail -->\n",
+	}
+	for _, str := range tests {
+		doc, err := xml.NewLosslessParser(strings.NewReader(str)).Parse()
+		if err != nil {
+			t.Errorf("fail to parse input document: %s", err)
+			continue
+		}
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions = xml.OptionLossless
+		if err := ws.Write(doc); err != nil {
+			t.Errorf("error writing document: %s", err)
+			continue
+		}
+		if got := buf.String(); got != str {
+			t.Errorf("round trip mismatched")
+			t.Logf("want: %q", str)
+			t.Logf("got : %q", got)
+		}
+	}
+}
+
+func TestParseLosslessEdit(t *testing.T) {
+	const str = "<root  b='2' a=\"1\">\n  <item id='x'  />\n  <empty></empty>\n  <keep k='&#65;'/>\n</root>"
+
+	doc, err := xml.NewLosslessParser(strings.NewReader(str)).Parse()
+	if err != nil {
+		t.Fatalf("fail to parse input document: %s", err)
+	}
+	root := doc.Root().(*xml.Element)
+	root.Nodes[1].(*xml.Element).SetAttribute(xml.NewAttribute(xml.LocalName("id"), "y"))
+	root.Nodes[3].(*xml.Element).Append(xml.NewText("a & b"))
+
+	var (
+		buf  strings.Builder
+		ws   = xml.NewWriter(&buf)
+		want = "<root  b='2' a=\"1\">\n  <item id=\"y\"/>\n  <empty>a &amp; b</empty>\n  <keep k='&#65;'/>\n</root>"
+	)
+	ws.WriterOptions = xml.OptionLossless
+	if err := ws.Write(doc); err != nil {
+		t.Fatalf("error writing document: %s", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatched")
+		t.Logf("want: %q", want)
+		t.Logf("got : %q", got)
+	}
+}
+
+func TestParseSpace(t *testing.T) {
+	const str = `<root xml:lang="en"><a>  trimmed  </a><pre xml:space="preserve">  keep <b> this </b> <c xml:space="default">  x  <d/></c></pre><p>Some <em>mixed</em> content</p></root>`
+
+	p := xml.NewParser(strings.NewReader(str))
+	p.PreserveSpace = []string{"p"}
+	doc, err := p.Parse()
+	if err != nil {
+		t.Fatalf("fail to parse input document: %s", err)
+	}
+	var (
+		buf  strings.Builder
+		ws   = xml.NewWriter(&buf)
+		want = "<root xml:lang=\"en\">\n    <a>trimmed</a>\n    <pre xml:space=\"preserve\">  keep <b> this </b> <c xml:space=\"default\">x\n            <d/>\n        </c></pre>\n    <p>Some <em>mixed</em> content</p>\n</root>"
+	)
+	ws.WriterOptions = xml.OptionNoProlog
+	ws.PreserveSpace = []string{"p"}
+	if err := ws.Write(doc); err != nil {
+		t.Fatalf("error writing document: %s", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("result mismatched")
+		t.Logf("want: %q", want)
+		t.Logf("got : %q", got)
+	}
+
+	root := doc.Root().(*xml.Element)
+	b := root.Nodes[1].(*xml.Element).Nodes[1].(*xml.Element)
+	if !b.PreserveSpace() {
+		t.Errorf("%s: expected whitespace to be preserved", b.QualifiedName())
+	}
+	if lang := b.Lang(); lang != "en" {
+		t.Errorf("%s: lang mismatched! want %s, got %s", b.QualifiedName(), "en", lang)
+	}
+
+	_, err = xml.NewParser(strings.NewReader(`<root xml:space="keep"/>`)).Parse()
+	if err == nil {
+		t.Errorf("expected error for invalid xml:space value")
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	const str = "<?xml version=\"1.0\"?>\n<root a=\"1\">\n  <é x:b='2' xmlns:x=\"urn:x\">text<!--c--><![CDATA[d]]><?pi x=\"1\"?></é>\n</root>"
+
+	doc, err := xml.NewParser(strings.NewReader(str)).Parse()
+	if err != nil {
+		t.Fatalf("fail to parse input document: %s", err)
+	}
+	var (
+		root = doc.Root().(*xml.Element)
+		elem = root.Nodes[0].(*xml.Element)
+	)
+	tests := []struct {
+		Node xml.Node
+		Want xml.Position
+	}{
+		{Node: root, Want: xml.Position{Line: 2, Column: 1, Offset: 22}},
+		{Node: &root.Attrs[0], Want: xml.Position{Line: 2, Column: 7, Offset: 28}},
+		{Node: elem, Want: xml.Position{Line: 3, Column: 3, Offset: 37}},
+		{Node: &elem.Attrs[0], Want: xml.Position{Line: 3, Column: 6, Offset: 41}},
+		{Node: elem.Nodes[0], Want: xml.Position{Line: 3, Column: 30, Offset: 65}},
+		{Node: elem.Nodes[1], Want: xml.Position{Line: 3, Column: 34, Offset: 69}},
+		{Node: elem.Nodes[2], Want: xml.Position{Line: 3, Column: 42, Offset: 77}},
+		{Node: elem.Nodes[3], Want: xml.Position{Line: 3, Column: 55, Offset: 90}},
+	}
+	for _, c := range tests {
+		got := xml.LocationOf(c.Node)
+		if got != c.Want {
+			t.Errorf("%s: location mismatched! want %s (%d), got %s (%d)", c.Node.QualifiedName(), c.Want, c.Want.Offset, got, got.Offset)
+			continue
+		}
+		if !strings.HasPrefix(str[got.Offset:], "<") && c.Node.Type() != xml.TypeText && c.Node.Type() != xml.TypeAttribute {
+			t.Errorf("%s: offset does not point to the start of the node", c.Node.QualifiedName())
+		}
+	}
+}
+
+func TestReparse(t *testing.T) {
+	const str = "<root xmlns:x=\"urn:x\">\n  <item>foo</item>\n  <x:item>bar</x:item>\n  <item>baz</item>\n</root>"
+
+	tests := []struct {
+		Name    string
+		Old     string
+		New     string
+		Changed int
+	}{
+		{
+			Name:    "text",
+			Old:     "bar",
+			New:     "hello\nworld",
+			Changed: 1,
+		},
+		{
+			Name:    "split",
+			Old:     "bar</x:item>",
+			New:     "bar</x:item><x:item>qux</x:item>",
+			Changed: -1,
+		},
+	}
+	for _, c := range tests {
+		p := xml.NewParser(strings.NewReader(str))
+		doc, err := p.Parse()
+		if err != nil {
+			t.Fatalf("fail to parse input document: %s", err)
+		}
+		var (
+			root   = doc.Root().(*xml.Element)
+			before = slices.Clone(root.Nodes)
+			offset = strings.Index(str, c.Old)
+			src    = str[:offset] + c.New + str[offset+len(c.Old):]
+			edit   = xml.Edit{
+				Offset:   offset,
+				Removed:  len(c.Old),
+				Inserted: len(c.New),
+			}
+		)
+		if _, err := p.Reparse(doc, []byte(src), edit); err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Name, err)
+			continue
+		}
+		want, err := xml.ParseString(src)
+		if err != nil {
+			t.Fatalf("%s: fail to parse edited document: %s", c.Name, err)
+		}
+		if got, want := doc.Root().(*xml.Element), want.Root().(*xml.Element); !compareLocations(got, want) {
+			t.Errorf("%s: reparsed document does not match edited document", c.Name)
+		}
+		if c.Changed < 0 {
+			continue
+		}
+		for i, n := range before {
+			if same := root.Nodes[i] == n; same == (i == c.Changed) {
+				t.Errorf("%s: node %d identity mismatched", c.Name, i)
+			}
+		}
+		if got := root.Nodes[c.Changed].(*xml.Element).Uri; got != "urn:x" {
+			t.Errorf("%s: namespace not resolved in reparsed element, got %q", c.Name, got)
+		}
+	}
+}
+
+func TestReparseInvalid(t *testing.T) {
+	const str = "<root><item>foo</item></root>"
+
+	p := xml.NewParser(strings.NewReader(str))
+	doc, err := p.Parse()
+	if err != nil {
+		t.Fatalf("fail to parse input document: %s", err)
+	}
+	item := doc.Root().(*xml.Element).Nodes[0]
+
+	src := "<root><item>foo</itm></root>"
+	_, err = p.Reparse(doc, []byte(src), xml.Edit{Offset: 17, Removed: 1})
+	if err == nil {
+		t.Fatalf("expected error when reparsing invalid edit")
+	}
+	if doc.Root().(*xml.Element).Nodes[0] != item {
+		t.Errorf("document should not be modified after failed reparse")
+	}
+	_, err = p.Reparse(doc, []byte(" "+str), xml.Edit{Offset: 0, Inserted: 1})
+	if !errors.Is(err, xml.ErrEdit) {
+		t.Errorf("expected edit error for edit outside root element, got %v", err)
+	}
+}
+
+func compareLocations(got, want xml.Node) bool {
+	if got.QualifiedName() != want.QualifiedName() || got.Value() != want.Value() {
+		return false
+	}
+	if xml.LocationOf(got) != xml.LocationOf(want) {
+		return false
+	}
+	g, ok1 := got.(*xml.Element)
+	w, ok2 := want.(*xml.Element)
+	if !ok1 || !ok2 {
+		return ok1 == ok2
+	}
+	if len(g.Nodes) != len(w.Nodes) {
+		return false
+	}
+	for i := range g.Nodes {
+		if !compareLocations(g.Nodes[i], w.Nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
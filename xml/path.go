@@ -0,0 +1,105 @@
+package xml
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type PathOptions struct {
+	Indexed     bool
+	UseID       bool
+	NoNamespace bool
+}
+
+func PathOf(node Node, opts PathOptions) string {
+	if node == nil {
+		return ""
+	}
+	var (
+		parts []string
+		base  = "/"
+	)
+	for n := node; n != nil && n.Type() != TypeDocument; n = n.Parent() {
+		if opts.UseID {
+			if id, ok := xmlID(n); ok {
+				base = fmt.Sprintf("id('%s')", id)
+				break
+			}
+		}
+		parts = append(parts, pathStep(n, opts))
+	}
+	slices.Reverse(parts)
+	if base != "/" && len(parts) > 0 {
+		base += "/"
+	}
+	return base + strings.Join(parts, "/")
+}
+
+func pathStep(node Node, opts PathOptions) string {
+	var step string
+	switch node.Type() {
+	case TypeAttribute:
+		if opts.NoNamespace {
+			return "@" + node.LocalName()
+		}
+		return "@" + node.QualifiedName()
+	case TypeElement:
+		step = node.QualifiedName()
+		if opts.NoNamespace {
+			step = node.LocalName()
+		}
+	case TypeText:
+		step = "text()"
+	case TypeComment:
+		step = "comment()"
+	case TypeInstruction:
+		step = fmt.Sprintf("processing-instruction(%s)", node.LocalName())
+	default:
+		step = "node()"
+	}
+	if ix, count := stepIndex(node); opts.Indexed || count > 1 {
+		step = fmt.Sprintf("%s[%d]", step, ix)
+	}
+	return step
+}
+
+func stepIndex(node Node) (int, int) {
+	var nodes []Node
+	switch p := node.Parent().(type) {
+	case *Element:
+		nodes = p.Nodes
+	case *Document:
+		nodes = p.Nodes
+	default:
+		return 1, 1
+	}
+	var ix, count int
+	for _, n := range nodes {
+		if n.Type() != node.Type() {
+			continue
+		}
+		if (n.Type() == TypeElement || n.Type() == TypeInstruction) && n.QualifiedName() != node.QualifiedName() {
+			continue
+		}
+		count++
+		if n == node {
+			ix = count
+		}
+	}
+	return ix, count
+}
+
+func xmlID(node Node) (string, bool) {
+	el, ok := node.(*Element)
+	if !ok {
+		return "", false
+	}
+	ix := slices.IndexFunc(el.Attrs, func(a Attribute) bool {
+		return a.Space == "xml" && a.Name == "id"
+	})
+	if ix < 0 || strings.ContainsAny(el.Attrs[ix].Value(), "'") {
+		return "", false
+	}
+	return el.Attrs[ix].Value(), true
+}
@@ -0,0 +1,79 @@
+package xml_test
+
+import (
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestPathOf(t *testing.T) {
+	doc, err := xml.ParseString(`<root><item id="a">foo</item><item>bar<sub xml:id="s1"><leaf/></sub></item><other/></root>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	var (
+		root  = doc.Root().(*xml.Element)
+		first = root.Nodes[0].(*xml.Element)
+		snd   = root.Nodes[1].(*xml.Element)
+		sub   = snd.Nodes[1].(*xml.Element)
+		attr  = first.Attrs[0]
+	)
+	tests := []struct {
+		Node    xml.Node
+		Options xml.PathOptions
+		Want    string
+	}{
+		{
+			Node: doc,
+			Want: "/",
+		},
+		{
+			Node: root,
+			Want: "/root",
+		},
+		{
+			Node:    root,
+			Options: xml.PathOptions{Indexed: true},
+			Want:    "/root[1]",
+		},
+		{
+			Node: first,
+			Want: "/root/item[1]",
+		},
+		{
+			Node: &attr,
+			Want: "/root/item[1]/@id",
+		},
+		{
+			Node: first.Nodes[0],
+			Want: "/root/item[1]/text()",
+		},
+		{
+			Node: snd.Nodes[0],
+			Want: "/root/item[2]/text()",
+		},
+		{
+			Node: root.Nodes[2],
+			Want: "/root/other",
+		},
+		{
+			Node: sub.Nodes[0],
+			Want: "/root/item[2]/sub/leaf",
+		},
+		{
+			Node:    sub.Nodes[0],
+			Options: xml.PathOptions{UseID: true},
+			Want:    "id('s1')/leaf",
+		},
+		{
+			Node:    sub,
+			Options: xml.PathOptions{UseID: true},
+			Want:    "id('s1')",
+		},
+	}
+	for _, c := range tests {
+		if got := xml.PathOf(c.Node, c.Options); got != c.Want {
+			t.Errorf("path mismatched! want %s, got %s", c.Want, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrEdit = errors.New("edit can not be applied")
+
+type Edit struct {
+	Offset   int
+	Removed  int
+	Inserted int
+}
+
+func (e Edit) delta() int {
+	return e.Inserted - e.Removed
+}
+
+func (p *Parser) Reparse(doc *Document, src []byte, edit Edit) (*Element, error) {
+	if edit.Offset < 0 || edit.Removed < 0 || edit.Inserted < 0 {
+		return nil, fmt.Errorf("%w: invalid range", ErrEdit)
+	}
+	chain := enclosingElements(doc, edit)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: edit outside of root element", ErrEdit)
+	}
+	var err error
+	for i := len(chain) - 1; i >= 0; i-- {
+		var (
+			old  = chain[i]
+			end  = old.end.Offset + edit.delta()
+			elem *Element
+		)
+		if end > len(src) {
+			return nil, fmt.Errorf("%w: source shorter than edited document", ErrEdit)
+		}
+		elem, err = p.parseRegion(src[old.Location.Offset:end], old.Location, chain[:i])
+		if err != nil {
+			continue
+		}
+		if err := replaceNode(old, elem); err != nil {
+			return nil, err
+		}
+		shiftPositions(doc, elem, old.end, elem.end)
+		return elem, nil
+	}
+	return nil, err
+}
+
+func (p *Parser) parseRegion(src []byte, start Position, ancestors []*Element) (*Element, error) {
+	scan := scanFrom(bytes.NewReader(src), start)
+	scan.keepRaw = p.lossless
+
+	sub := createParser(scan)
+	sub.TrimSpace = p.TrimSpace
+	sub.KeepEmpty = p.KeepEmpty
+	sub.StrictNS = p.StrictNS
+	sub.MaxDepth = p.MaxDepth
+	sub.PreserveSpace = p.PreserveSpace
+	sub.lossless = p.lossless
+	sub.piFuncs = p.piFuncs
+	sub.depth = len(ancestors)
+
+	var preserve bool
+	for _, el := range ancestors {
+		for _, a := range el.Attrs {
+			if a.Name == AttrXmlNS && a.Space == "" {
+				sub.defineNS("", a.Value())
+			} else if a.Space == AttrXmlNS {
+				sub.defineNS(a.Name, a.Value())
+			}
+		}
+		if mode, ok, _ := spaceMode(el.Attrs); ok {
+			preserve = mode
+		} else {
+			preserve = preserve || matchElementName(p.PreserveSpace, el)
+		}
+	}
+	if len(ancestors) > 0 {
+		sub.spaces = append(sub.spaces, preserve)
+	}
+	if !sub.is(OpenTag) {
+		return nil, sub.createError("element", "element expected")
+	}
+	node, err := sub.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	if !sub.done() {
+		return nil, sub.createError("element", "unexpected content after element")
+	}
+	elem, ok := node.(*Element)
+	if !ok {
+		return nil, sub.createError("element", "element expected")
+	}
+	return elem, nil
+}
+
+func enclosingElements(doc *Document, edit Edit) []*Element {
+	var (
+		chain []*Element
+		nodes = doc.Nodes
+	)
+	for {
+		ix := slices.IndexFunc(nodes, func(n Node) bool {
+			el, ok := n.(*Element)
+			return ok && containsEdit(el, edit)
+		})
+		if ix < 0 {
+			break
+		}
+		el := nodes[ix].(*Element)
+		chain = append(chain, el)
+		nodes = el.Nodes
+	}
+	return chain
+}
+
+func containsEdit(el *Element, edit Edit) bool {
+	if el.end.Zero() {
+		return false
+	}
+	return el.Location.Offset < edit.Offset && edit.Offset+edit.Removed < el.end.Offset
+}
+
+func replaceNode(old, node *Element) error {
+	var nodes []Node
+	switch p := old.Parent().(type) {
+	case *Element:
+		nodes = p.Nodes
+	case *Document:
+		nodes = p.Nodes
+	default:
+		return fmt.Errorf("%w: element is detached", ErrEdit)
+	}
+	ix := slices.Index(nodes, Node(old))
+	if ix < 0 {
+		return fmt.Errorf("%w: element not found in its parent", ErrEdit)
+	}
+	nodes[ix] = node
+	node.setParent(old.Parent())
+	node.setPosition(ix)
+	return nil
+}
+
+func shiftPositions(doc *Document, skip *Element, from, to Position) {
+	shift := func(pos *Position) {
+		if pos.Zero() || pos.Offset < from.Offset {
+			return
+		}
+		if pos.Line == from.Line {
+			pos.Column += to.Column - from.Column
+		}
+		pos.Line += to.Line - from.Line
+		pos.Offset += to.Offset - from.Offset
+	}
+	var walk func(Node)
+	walk = func(node Node) {
+		switch n := node.(type) {
+		case *Element:
+			if n == skip {
+				return
+			}
+			shift(&n.Location)
+			shift(&n.end)
+			for i := range n.Attrs {
+				shift(&n.Attrs[i].Location)
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *Instruction:
+			shift(&n.Location)
+			for i := range n.Attrs {
+				shift(&n.Attrs[i].Location)
+			}
+		case *Text:
+			shift(&n.Location)
+		case *CharData:
+			shift(&n.Location)
+		case *Comment:
+			shift(&n.Location)
+		default:
+		}
+	}
+	for _, n := range doc.Nodes {
+		walk(n)
+	}
+}
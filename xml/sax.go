@@ -156,7 +156,11 @@ func (w *StreamWriter) Close(qn QName) error {
 
 func (w *StreamWriter) Text(str string) error {
 	defer w.pushText()
-	_, err := w.writer.WriteString(escapeText(str))
+	str, err := escapeText(str)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.WriteString(str)
 	return err
 }
 
@@ -186,17 +190,25 @@ func (w *StreamWriter) pop(qn QName) error {
 
 func (w *StreamWriter) open(qn QName, attrs []A, closed bool) error {
 	defer w.popText()
+	values := make([]string, len(attrs))
+	for i, a := range attrs {
+		str, err := escapeAttr(a.Value)
+		if err != nil {
+			return err
+		}
+		values[i] = str
+	}
 	if !closed {
 		w.push(qn)
 	}
 	w.writer.WriteRune(langle)
 	w.writer.WriteString(qn.QualifiedName())
-	for _, a := range attrs {
+	for i, a := range attrs {
 		w.writer.WriteRune(' ')
 		w.writer.WriteString(a.QualifiedName())
 		w.writer.WriteRune(equal)
 		w.writer.WriteRune(quote)
-		w.writer.WriteString(escapeText(a.Value))
+		w.writer.WriteString(values[i])
 		w.writer.WriteRune(quote)
 	}
 	if closed {
@@ -0,0 +1,76 @@
+package xml
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	AttrSpace = "space"
+	AttrLang  = "lang"
+)
+
+const (
+	SpaceDefault  = "default"
+	SpacePreserve = "preserve"
+)
+
+var ErrSpace = errors.New("invalid xml:space value")
+
+func (e *Element) Lang() string {
+	for n := Node(e); n != nil; n = n.Parent() {
+		el, ok := n.(*Element)
+		if !ok {
+			break
+		}
+		if lang, ok := xmlAttr(el.Attrs, AttrLang); ok {
+			return lang
+		}
+	}
+	return ""
+}
+
+func (e *Element) PreserveSpace() bool {
+	for n := Node(e); n != nil; n = n.Parent() {
+		el, ok := n.(*Element)
+		if !ok {
+			break
+		}
+		if mode, ok := xmlAttr(el.Attrs, AttrSpace); ok {
+			return mode == SpacePreserve
+		}
+	}
+	return false
+}
+
+func spaceMode(attrs []Attribute) (bool, bool, error) {
+	mode, ok := xmlAttr(attrs, AttrSpace)
+	if !ok {
+		return false, false, nil
+	}
+	switch mode {
+	case SpacePreserve:
+		return true, true, nil
+	case SpaceDefault:
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("%w: %s", ErrSpace, mode)
+	}
+}
+
+func xmlAttr(attrs []Attribute, name string) (string, bool) {
+	ix := slices.IndexFunc(attrs, func(a Attribute) bool {
+		return a.Space == "xml" && a.Name == name
+	})
+	if ix < 0 {
+		return "", false
+	}
+	return attrs[ix].Value(), true
+}
+
+func matchElementName(list []string, node *Element) bool {
+	return slices.ContainsFunc(list, func(name string) bool {
+		return name == node.QualifiedName() || name == node.LocalName()
+	})
+}
@@ -3,6 +3,7 @@ package xml
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
@@ -12,6 +13,8 @@ import (
 	"github.com/midbel/codecs/casing"
 )
 
+var ErrChar = errors.New("invalid xml character")
+
 type WriterOptions uint64
 
 const (
@@ -26,6 +29,8 @@ const (
 	OptionNamespaceSnakeCase
 	OptionNamespaceKebabCase
 	OptionNamespaceLowerCase
+	OptionASCII
+	OptionLossless
 )
 
 func (w WriterOptions) Compact() bool {
@@ -72,6 +77,14 @@ func (w WriterOptions) NameToLowerCase() bool {
 	return w&OptionNameLowerCase > 0
 }
 
+func (w WriterOptions) ASCII() bool {
+	return w&OptionASCII > 0
+}
+
+func (w WriterOptions) Lossless() bool {
+	return w&OptionLossless > 0
+}
+
 func (w WriterOptions) rewriteQName(name QName) QName {
 	if w.NameToKebabCase() {
 		name.Name = casing.To(casing.KebabCase, name.Name)
@@ -90,13 +103,40 @@ func (w WriterOptions) rewriteQName(name QName) QName {
 	return name
 }
 
+type EscapeMode int8
+
+const (
+	EscapeFull EscapeMode = iota
+	EscapeMinimal
+)
+
+type EmptyElementStyle int8
+
+const (
+	EmptySelfClosing EmptyElementStyle = iota
+	EmptyExplicit
+)
+
 type Writer struct {
 	writer *bufio.Writer
 
 	Indent   string
 	Doctype  string
 	MaxDepth int
+	Limit    int
 	WriterOptions
+
+	Escape     EscapeMode
+	EmptyStyle EmptyElementStyle
+	Newline    string
+	Quote      rune
+
+	CharDataElements []string
+	PairedElements   []string
+	PreserveSpace    []string
+
+	preserving bool
+	nsfixes    map[*Element]*nsFix
 }
 
 func WriteNode(node Node) string {
@@ -119,14 +159,34 @@ func writeNode(node Node, maxdepth int) string {
 
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
-		writer: bufio.NewWriter(w),
-		Indent: "  ",
+		writer:  bufio.NewWriter(w),
+		Indent:  "  ",
+		Limit:   MaxDepth,
+		Newline: "\n",
+		Quote:   quote,
 	}
 }
 
 func (w *Writer) Write(doc *Document) error {
-	if err := w.writeProlog(); err != nil {
-		return err
+	if !w.NoNamespace() && !w.Lossless() {
+		w.nsfixes = namespaceFixes(doc)
+		defer func() {
+			w.nsfixes = nil
+		}()
+	}
+	if doc.BOM {
+		w.writer.WriteString("\uFEFF")
+	}
+	if !w.Lossless() {
+		if err := w.writeProlog(); err != nil {
+			return err
+		}
+		w.writePreamble(doc.Preamble)
+	} else {
+		w.writePreamble(doc.Preamble)
+		if doc.source != nil {
+			w.writer.WriteString(doc.source.raw)
+		}
 	}
 	if err := w.writeDocumentType(doc.DocType); err != nil {
 		return err
@@ -136,7 +196,24 @@ func (w *Writer) Write(doc *Document) error {
 			return err
 		}
 	}
-	return nil
+	return w.writer.Flush()
+}
+
+func (w *Writer) writePreamble(nodes []Node) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *Text:
+			w.writer.WriteString(n.Content)
+		case *Comment:
+			if w.NoComment() {
+				continue
+			}
+			w.writer.WriteString("<!--")
+			w.writer.WriteString(n.Content)
+			w.writer.WriteString("-->")
+		default:
+		}
+	}
 }
 
 func (w *Writer) writeDocumentType(doctype *DocType) error {
@@ -188,6 +265,12 @@ func (w *Writer) writeNode(node Node, depth int) error {
 }
 
 func (w *Writer) writeElement(node *Element, depth int) error {
+	if w.Lossless() {
+		return w.writeElementSource(node, depth)
+	}
+	if w.Limit > 0 && depth/2 >= w.Limit {
+		return ErrDepth
+	}
 	w.writeNL()
 
 	prefix := w.getIndent(depth)
@@ -195,7 +278,11 @@ func (w *Writer) writeElement(node *Element, depth int) error {
 		w.writer.WriteString(prefix)
 	}
 	w.writer.WriteRune(langle)
-	name := w.rewriteQName(node.QName)
+	qname, attrs := node.QName, node.Attrs
+	if fx, ok := w.nsfixes[node]; ok {
+		qname, attrs = fx.element(node)
+	}
+	name := w.rewriteQName(qname)
 	if w.NoNamespace() {
 		w.writer.WriteString(name.LocalName())
 	} else {
@@ -203,13 +290,18 @@ func (w *Writer) writeElement(node *Element, depth int) error {
 		w.writer.WriteString(name.QualifiedName())
 	}
 	level := depth + 1
-	if len(node.Attrs) == 1 {
+	if len(attrs) == 1 {
 		level = 0
 	}
-	if err := w.writeAttributes(node.Attrs, level); err != nil {
+	if err := w.writeAttributes(attrs, level); err != nil {
 		return err
 	}
-	if len(node.Nodes) == 0 {
+	defer func(preserving bool) {
+		w.preserving = preserving
+	}(w.preserving)
+	w.preserving = w.isPreserveElement(node)
+
+	if len(node.Nodes) == 0 && !w.isPairedElement(node) {
 		w.writer.WriteRune(slash)
 		w.writer.WriteRune(rangle)
 		return w.writer.Flush()
@@ -217,23 +309,24 @@ func (w *Writer) writeElement(node *Element, depth int) error {
 	if w.MaxDepth == 0 || depth < w.MaxDepth {
 		w.writer.WriteRune(rangle)
 		for _, n := range node.Nodes {
-			if err := w.writeNode(n, depth+1); err != nil {
+			if err := w.writeChild(node, n, depth+1); err != nil {
 				return err
 			}
 		}
 	} else if node.Leaf() {
 		w.writer.WriteRune(rangle)
-		w.writeNode(node.Nodes[0], depth+1)
+		w.writeChild(node, node.Nodes[0], depth+1)
 	} else {
 		w.writer.WriteRune(slash)
 		w.writer.WriteRune(rangle)
 		return w.writer.Flush()
 	}
 	if n := len(node.Nodes); n > 0 {
-		_, ok := node.Nodes[n-1].(*Text)
-		if !ok {
+		switch node.Nodes[n-1].(type) {
+		case *Text, *CharData:
+		default:
 			w.writeNL()
-			w.writer.WriteString(prefix)
+			w.writer.WriteString(w.getIndent(depth))
 		}
 	}
 	w.writer.WriteRune(langle)
@@ -248,22 +341,68 @@ func (w *Writer) writeElement(node *Element, depth int) error {
 	return w.writer.Flush()
 }
 
+func (w *Writer) writeChild(parent *Element, node Node, depth int) error {
+	if t, ok := node.(*Text); ok && w.isCharDataElement(parent) {
+		w.writeCData(t.Content)
+		return nil
+	}
+	return w.writeNode(node, depth)
+}
+
+func (w *Writer) isCharDataElement(node *Element) bool {
+	return matchElementName(w.CharDataElements, node)
+}
+
+func (w *Writer) isPairedElement(node *Element) bool {
+	if w.EmptyStyle == EmptyExplicit {
+		return true
+	}
+	return matchElementName(w.PairedElements, node)
+}
+
+func (w *Writer) isPreserveElement(node *Element) bool {
+	if preserve, ok, _ := spaceMode(node.Attrs); ok {
+		return preserve
+	}
+	return w.preserving || matchElementName(w.PreserveSpace, node)
+}
+
 func (w *Writer) writeLiteral(node *Text, _ int) error {
-	_, err := w.writer.WriteString(escapeText(node.Content))
+	if w.Lossless() && !w.ASCII() && node.source != nil && node.source.sig == node.Content {
+		_, err := w.writer.WriteString(node.source.raw)
+		return err
+	}
+	str, err := w.escapeText(node.Content)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.WriteString(str)
 	return err
 }
 
 func (w *Writer) writeCharData(node *CharData, _ int) error {
+	if w.CharDataToText() {
+		str, err := w.escapeText(node.Content)
+		if err != nil {
+			return err
+		}
+		_, err = w.writer.WriteString(str)
+		return err
+	}
+	w.writeCData(node.Content)
+	return nil
+}
+
+func (w *Writer) writeCData(str string) {
 	w.writer.WriteRune(langle)
 	w.writer.WriteRune(bang)
 	w.writer.WriteRune(lsquare)
 	w.writer.WriteString("CDATA")
 	w.writer.WriteRune(lsquare)
-	w.writer.WriteString(node.Content)
+	w.writer.WriteString(strings.ReplaceAll(str, "]]>", "]]]]><![CDATA[>"))
 	w.writer.WriteRune(rsquare)
 	w.writer.WriteRune(rsquare)
 	w.writer.WriteRune(rangle)
-	return nil
 }
 
 func (w *Writer) writeComment(node *Comment, depth int) error {
@@ -285,6 +424,10 @@ func (w *Writer) writeComment(node *Comment, depth int) error {
 }
 
 func (w *Writer) writeInstruction(node *Instruction, depth int) error {
+	if w.Lossless() && w.unchanged(node.source, node.QName, node.Attrs) {
+		w.writer.WriteString(node.source.raw)
+		return w.writer.Flush()
+	}
 	if depth > 0 {
 		w.writeNL()
 	}
@@ -324,19 +467,10 @@ func (w *Writer) writeAttributeAsNode(attr *Attribute, depth int) error {
 }
 
 func (w *Writer) writeAttributes(attrs []Attribute, depth int) error {
-	slices.SortFunc(attrs, func(a, b Attribute) int {
-		if a.Space == "xmlns" && b.Space == "xmlns" {
-			c := strings.Compare(a.Name, b.Name)
-			if c == 0 {
-				c = strings.Compare(a.Datum, b.Datum)
-			}
-			return c
-		}
-		if a.Space == "xmlns" || b.Space == "xmlns" {
-			return -1
-		}
-		return 0
-	})
+	if !w.Lossless() {
+		attrs = slices.Clone(attrs)
+		sortAttributes(attrs)
+	}
 	prefix := w.getIndent(depth)
 	for i, a := range attrs {
 		if w.NoNamespace() && (a.Space == "xmlns" || a.Name == "xmlns") && a.Value() != "" {
@@ -354,52 +488,122 @@ func (w *Writer) writeAttributes(attrs []Attribute, depth int) error {
 		} else {
 			w.writer.WriteString(name.QualifiedName())
 		}
+		value, err := w.escapeAttr(a.Value())
+		if err != nil {
+			return err
+		}
 		w.writer.WriteRune(equal)
-		w.writer.WriteRune(quote)
-		w.writer.WriteString(escapeText(a.Value()))
-		w.writer.WriteRune(quote)
+		w.writer.WriteRune(w.quote())
+		w.writer.WriteString(value)
+		w.writer.WriteRune(w.quote())
 	}
 	return nil
 }
 
+func sortAttributes(attrs []Attribute) {
+	slices.SortFunc(attrs, func(a, b Attribute) int {
+		if a.Space == "xmlns" && b.Space == "xmlns" {
+			c := strings.Compare(a.Name, b.Name)
+			if c == 0 {
+				c = strings.Compare(a.Datum, b.Datum)
+			}
+			return c
+		}
+		if a.Space == "xmlns" || b.Space == "xmlns" {
+			return -1
+		}
+		return 0
+	})
+}
+
 func (w *Writer) writeNL() {
-	if w.Compact() {
+	if w.Compact() || w.Lossless() || w.preserving {
+		return
+	}
+	if w.Newline == "" {
+		w.writer.WriteRune('\n')
 		return
 	}
-	w.writer.WriteRune('\n')
+	w.writer.WriteString(w.Newline)
+}
+
+func (w *Writer) quote() rune {
+	if w.Quote == apos {
+		return apos
+	}
+	return quote
+}
+
+func (w *Writer) escapeText(str string) (string, error) {
+	if w.Escape == EscapeMinimal {
+		return escapeChars(str, "<>&", w.ASCII())
+	}
+	return escapeString(str, w.ASCII())
+}
+
+func (w *Writer) escapeAttr(str string) (string, error) {
+	if w.Escape == EscapeMinimal {
+		return escapeChars(str, "<&\t\n"+string(w.quote()), w.ASCII())
+	}
+	return escapeChars(str, "<>&\"'\t\n", w.ASCII())
 }
 
 func (w *Writer) getIndent(depth int) string {
-	if w.Compact() {
+	if w.Compact() || w.Lossless() || w.preserving {
 		return ""
 	}
 	return strings.Repeat(w.Indent, depth)
 }
 
-func escapeText(str string) string {
+func escapeText(str string) (string, error) {
+	return escapeString(str, false)
+}
+
+func escapeAttr(str string) (string, error) {
+	return escapeChars(str, "<>&\"'\t\n", false)
+}
+
+func escapeString(str string, ascii bool) (string, error) {
+	return escapeChars(str, "<>&\"'", ascii)
+}
+
+func escapeChars(str, special string, ascii bool) (string, error) {
 	var buf bytes.Buffer
 	for i := 0; i < len(str); {
 		r, z := utf8.DecodeRuneInString(str[i:])
 		i += z
 
-		if r == utf8.RuneError {
+		if r == utf8.RuneError && z <= 1 {
+			return "", fmt.Errorf("%w: invalid utf-8 sequence at offset %d", ErrChar, i-z)
+		}
+		if strings.ContainsRune(special, r) {
+			buf.WriteString(entities[r])
 			continue
 		}
 
 		switch r {
-		case '<':
-			buf.WriteString("&lt;")
-		case '>':
-			buf.WriteString("&gt;")
-		case '&':
-			buf.WriteString("&amp;")
-		case '"':
-			buf.WriteString("&quot;")
-		case '\'':
-			buf.WriteString("&apos;")
+		case '\t', '\n':
+			buf.WriteRune(r)
 		default:
+			if !isChar(r) {
+				return "", fmt.Errorf("%w: %U at offset %d", ErrChar, r, i-z)
+			}
+			if r == '\r' || (r >= 0x7F && r <= 0x9F) || r == 0x2028 || (ascii && r >= utf8.RuneSelf) {
+				fmt.Fprintf(&buf, "&#x%X;", r)
+				continue
+			}
 			buf.WriteRune(r)
 		}
 	}
-	return buf.String()
+	return buf.String(), nil
+}
+
+var entities = map[rune]string{
+	'<':  "&lt;",
+	'>':  "&gt;",
+	'&':  "&amp;",
+	'"':  "&quot;",
+	'\'': "&apos;",
+	'\t': "&#x9;",
+	'\n': "&#xA;",
 }
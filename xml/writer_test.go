@@ -1,6 +1,7 @@
 package xml_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -34,7 +35,6 @@ func TestWriterWrite(t *testing.T) {
 		{
 			Want: strings.Join([]string{
 				`<?xml version="1.0" encoding="UTF-8"?>`,
-				``,
 				`<test:root id="1">`,
 				`    <test:a attr="text">text</test:a>`,
 				`    <test:a attr="self"/>`,
@@ -44,7 +44,6 @@ func TestWriterWrite(t *testing.T) {
 		{
 			Want: strings.Join([]string{
 				`<?xml version="1.0" encoding="UTF-8"?>`,
-				``,
 				`<root id="1">`,
 				`    <a attr="text">text</a>`,
 				`    <a attr="self"/>`,
@@ -59,9 +58,15 @@ func TestWriterWrite(t *testing.T) {
 			buf strings.Builder
 			ws  = xml.NewWriter(&buf)
 		)
-		ws.Compact = d.Compact
-		ws.NoProlog = d.NoProlog
-		ws.NoNamespace = d.NoNamespace
+		if d.Compact {
+			ws.WriterOptions |= xml.OptionCompact
+		}
+		if d.NoProlog {
+			ws.WriterOptions |= xml.OptionNoProlog
+		}
+		if d.NoNamespace {
+			ws.WriterOptions |= xml.OptionNoNamespace
+		}
 		if err := ws.Write(doc); err != nil {
 			t.Errorf("error writing document: %s", err)
 			return
@@ -78,3 +83,186 @@ func TestWriterWrite(t *testing.T) {
 func parseDocument(doc string) (*xml.Document, error) {
 	return xml.NewParser(strings.NewReader(doc)).Parse()
 }
+
+func TestWriterCharData(t *testing.T) {
+	const str = `<root><script>if (a &lt; b) { run() }</script><style><![CDATA[a > b]]></style><p>a &amp; b</p></root>`
+
+	doc, err := parseDocument(str)
+	if err != nil {
+		t.Errorf("fail to parse input document: %s", err)
+		return
+	}
+
+	data := []struct {
+		Want     string
+		Elements []string
+		Options  xml.WriterOptions
+	}{
+		{
+			Want: `<root><script>if (a &lt; b) { run() }</script><style><![CDATA[a > b]]></style><p>a &amp; b</p></root>`,
+		},
+		{
+			Want:     `<root><script><![CDATA[if (a < b) { run() }]]></script><style><![CDATA[a > b]]></style><p>a &amp; b</p></root>`,
+			Elements: []string{"script", "style"},
+		},
+		{
+			Want:    `<root><script>if (a &lt; b) { run() }</script><style>a &gt; b</style><p>a &amp; b</p></root>`,
+			Options: xml.OptionCharDataToText,
+		},
+		{
+			Want:     `<root><script>if (a &lt; b) { run() }</script><style>a &gt; b</style><p><![CDATA[a & b]]></p></root>`,
+			Elements: []string{"p"},
+			Options:  xml.OptionCharDataToText,
+		},
+	}
+	for _, d := range data {
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions = d.Options | xml.OptionCompact | xml.OptionNoProlog
+		ws.CharDataElements = d.Elements
+		if err := ws.Write(doc); err != nil {
+			t.Errorf("error writing document: %s", err)
+			return
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("result mismatched")
+			t.Logf("want: %s", d.Want)
+			t.Logf("got : %s", got)
+		}
+	}
+}
+
+func TestWriterStyle(t *testing.T) {
+	const str = `<root><a title="say &quot;hi&quot; &amp; 'bye'">x &gt; y</a><script src="app.js"/><br/></root>`
+
+	doc, err := parseDocument(str)
+	if err != nil {
+		t.Errorf("fail to parse input document: %s", err)
+		return
+	}
+
+	data := []struct {
+		Want    string
+		Escape  xml.EscapeMode
+		Empty   xml.EmptyElementStyle
+		Paired  []string
+		Newline string
+		Quote   rune
+		Compact bool
+	}{
+		{
+			Want:    `<root><a title="say &quot;hi&quot; &amp; &apos;bye&apos;">x &gt; y</a><script src="app.js"/><br/></root>`,
+			Compact: true,
+		},
+		{
+			Want:    `<root><a title="say &quot;hi&quot; &amp; 'bye'">x &gt; y</a><script src="app.js"/><br/></root>`,
+			Escape:  xml.EscapeMinimal,
+			Compact: true,
+		},
+		{
+			Want:    `<root><a title='say "hi" &amp; &apos;bye&apos;'>x &gt; y</a><script src='app.js'/><br/></root>`,
+			Escape:  xml.EscapeMinimal,
+			Quote:   '\'',
+			Compact: true,
+		},
+		{
+			Want:    `<root><a title="say &quot;hi&quot; &amp; &apos;bye&apos;">x &gt; y</a><script src="app.js"></script><br></br></root>`,
+			Empty:   xml.EmptyExplicit,
+			Compact: true,
+		},
+		{
+			Want:    `<root><a title="say &quot;hi&quot; &amp; &apos;bye&apos;">x &gt; y</a><script src="app.js"></script><br/></root>`,
+			Paired:  []string{"script"},
+			Compact: true,
+		},
+		{
+			Want:    "\r\n<root>\r\n    <a title=\"say &quot;hi&quot; &amp; &apos;bye&apos;\">x &gt; y</a>\r\n    <script src=\"app.js\"/>\r\n    <br/>\r\n</root>",
+			Newline: "\r\n",
+		},
+	}
+	for _, d := range data {
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions = xml.OptionNoProlog
+		if d.Compact {
+			ws.WriterOptions |= xml.OptionCompact
+		}
+		ws.Escape = d.Escape
+		ws.EmptyStyle = d.Empty
+		ws.PairedElements = d.Paired
+		if d.Newline != "" {
+			ws.Newline = d.Newline
+		}
+		if d.Quote != 0 {
+			ws.Quote = d.Quote
+		}
+		if err := ws.Write(doc); err != nil {
+			t.Errorf("error writing document: %s", err)
+			return
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("result mismatched")
+			t.Logf("want: %q", d.Want)
+			t.Logf("got : %q", got)
+		}
+	}
+}
+
+func TestWriterEscape(t *testing.T) {
+	data := []struct {
+		Attr string
+		Text string
+		Want string
+		Fail bool
+	}{
+		{
+			Attr: "a\tb\nc",
+			Text: "a\tb\nc",
+			Want: "<root attr=\"a&#x9;b&#xA;c\">a\tb\nc</root>",
+		},
+		{
+			Attr: "a\x01b",
+			Fail: true,
+		},
+		{
+			Text: "a\uFFFEb",
+			Fail: true,
+		},
+		{
+			Text: "a\xffb",
+			Fail: true,
+		},
+	}
+	for _, d := range data {
+		root := xml.NewElement(xml.LocalName("root"))
+		if d.Attr != "" {
+			root.SetAttribute(xml.NewAttribute(xml.LocalName("attr"), d.Attr))
+		}
+		if d.Text != "" {
+			root.Append(xml.NewText(d.Text))
+		}
+		var (
+			buf strings.Builder
+			ws  = xml.NewWriter(&buf)
+		)
+		ws.WriterOptions = xml.OptionNoProlog | xml.OptionCompact
+		err := ws.Write(xml.NewDocument(root))
+		if d.Fail {
+			if !errors.Is(err, xml.ErrChar) {
+				t.Errorf("%q: expected invalid character error, got %v", d.Attr+d.Text, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("error writing document: %s", err)
+			continue
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("result mismatched! want %q, got %q", d.Want, got)
+		}
+	}
+}
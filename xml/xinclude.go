@@ -0,0 +1,212 @@
+package xml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const XIncludeNS = "http://www.w3.org/2001/XInclude"
+
+var ErrInclude = errors.New("xinclude")
+
+type includer struct {
+	files []string
+	doc   *Document
+}
+
+func ResolveIncludes(doc *Document, file string) error {
+	i := includer{
+		doc: doc,
+	}
+	if file != "" {
+		file, _ = filepath.Abs(file)
+		i.files = append(i.files, file)
+	}
+	return i.resolve(doc, filepath.Dir(file))
+}
+
+func (i *includer) resolve(node Node, base string) error {
+	var nodes *[]Node
+	switch n := node.(type) {
+	case *Document:
+		nodes = &n.Nodes
+	case *Element:
+		nodes = &n.Nodes
+	default:
+		return nil
+	}
+	for j := 0; j < len(*nodes); j++ {
+		el, ok := (*nodes)[j].(*Element)
+		if !ok {
+			continue
+		}
+		if !isXInclude(el, "include") {
+			if err := i.resolve(el, base); err != nil {
+				return err
+			}
+			continue
+		}
+		list, err := i.include(el, base)
+		if err != nil {
+			return err
+		}
+		*nodes = slices.Concat((*nodes)[:j], list, (*nodes)[j+1:])
+		for k := range list {
+			list[k].setParent(node)
+		}
+		for k := j; k < len(*nodes); k++ {
+			(*nodes)[k].setPosition(k)
+		}
+		j += len(list) - 1
+	}
+	return nil
+}
+
+func (i *includer) include(el *Element, base string) ([]Node, error) {
+	list, err := i.load(el, base)
+	if err == nil {
+		return list, nil
+	}
+	ix := slices.IndexFunc(el.Nodes, func(n Node) bool {
+		e, ok := n.(*Element)
+		return ok && isXInclude(e, "fallback")
+	})
+	if ix < 0 {
+		return nil, err
+	}
+	fallback := el.Nodes[ix].(*Element)
+	if err := i.resolve(fallback, base); err != nil {
+		return nil, err
+	}
+	return slices.Clone(fallback.Nodes), nil
+}
+
+func (i *includer) load(el *Element, base string) ([]Node, error) {
+	var (
+		href    = includeAttr(el, "href")
+		parse   = includeAttr(el, "parse")
+		pointer = includeAttr(el, "xpointer")
+	)
+	if parse == "" {
+		parse = "xml"
+	}
+	if href == "" && pointer == "" {
+		return nil, fmt.Errorf("%w: href or xpointer attribute expected", ErrInclude)
+	}
+	if href != "" && !filepath.IsAbs(href) {
+		href = filepath.Join(base, href)
+	}
+	switch parse {
+	case "text":
+		if href == "" {
+			return nil, fmt.Errorf("%w: href attribute expected for text inclusion", ErrInclude)
+		}
+		buf, err := os.ReadFile(href)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInclude, err)
+		}
+		return []Node{NewText(string(buf))}, nil
+	case "xml":
+	default:
+		return nil, fmt.Errorf("%w: %s: unsupported parse value", ErrInclude, parse)
+	}
+	if href == "" {
+		node, err := resolvePointer(i.doc, pointer)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{node.(Cloner).Clone()}, nil
+	}
+	if slices.Contains(i.files, href) {
+		return nil, fmt.Errorf("%w: %s: recursive inclusion", ErrInclude, href)
+	}
+	doc, err := ParseFile(href)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInclude, href, err)
+	}
+	sub := includer{
+		files: append(slices.Clone(i.files), href),
+		doc:   doc,
+	}
+	if err := sub.resolve(doc, filepath.Dir(href)); err != nil {
+		return nil, err
+	}
+	if pointer == "" {
+		return []Node{doc.Root()}, nil
+	}
+	node, err := resolvePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return []Node{node}, nil
+}
+
+func resolvePointer(doc *Document, pointer string) (Node, error) {
+	scheme, ok := strings.CutPrefix(pointer, "element(")
+	if !ok {
+		return doc.GetElementById(pointer)
+	}
+	scheme, ok = strings.CutSuffix(scheme, ")")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: invalid xpointer", ErrInclude, pointer)
+	}
+	var (
+		parts = strings.Split(scheme, "/")
+		curr  Node
+	)
+	if parts[0] != "" {
+		n, err := doc.GetElementById(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		curr = n
+	} else {
+		curr = doc
+	}
+	for _, p := range parts[1:] {
+		ix, err := strconv.Atoi(p)
+		if err != nil || ix <= 0 {
+			return nil, fmt.Errorf("%w: %s: invalid xpointer", ErrInclude, pointer)
+		}
+		var nodes []Node
+		switch c := curr.(type) {
+		case *Document:
+			nodes = c.Nodes
+		case *Element:
+			nodes = c.Nodes
+		}
+		var found Node
+		for _, n := range nodes {
+			if n.Type() != TypeElement {
+				continue
+			}
+			ix--
+			if ix == 0 {
+				found = n
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%w: %s: element not found", ErrInclude, pointer)
+		}
+		curr = found
+	}
+	if curr.Type() != TypeElement {
+		return nil, fmt.Errorf("%w: %s: element expected", ErrInclude, pointer)
+	}
+	return curr, nil
+}
+
+func isXInclude(el *Element, name string) bool {
+	return el.Name == name && el.Uri == XIncludeNS
+}
+
+func includeAttr(el *Element, name string) string {
+	value, _ := el.GetAttribute(name)
+	return value
+}
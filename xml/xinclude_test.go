@@ -0,0 +1,68 @@
+package xml_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.xml": `<root xmlns:xi="http://www.w3.org/2001/XInclude">
+<xi:include href="part.xml"/>
+<xi:include href="part.xml" xpointer="second"/>
+<xi:include href="note.txt" parse="text"/>
+<xi:include href="missing.xml"><xi:fallback><empty/></xi:fallback></xi:include>
+<xi:include xpointer="element(/1/1)"/>
+</root>`,
+		"part.xml": `<part><item id="first">1</item><item id="second">2</item></part>`,
+		"note.txt": `a & b`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("error writing %s: %s", name, err)
+		}
+	}
+	file := filepath.Join(dir, "main.xml")
+	doc, err := xml.ParseFile(file)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	if err := xml.ResolveIncludes(doc, file); err != nil {
+		t.Fatalf("error resolving includes: %s", err)
+	}
+	var (
+		buf strings.Builder
+		ws  = xml.NewWriter(&buf)
+	)
+	ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+	if err := ws.Write(doc); err != nil {
+		t.Fatalf("error writing document: %s", err)
+	}
+	want := `<root xmlns:xi="http://www.w3.org/2001/XInclude"><part><item id="first">1</item><item id="second">2</item></part><item id="second">2</item>a &amp; b<empty/><part><item id="first">1</item><item id="second">2</item></part></root>`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatched")
+		t.Logf("want: %s", want)
+		t.Logf("got : %s", got)
+	}
+}
+
+func TestResolveIncludesRecursive(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "self.xml")
+	content := `<root xmlns:xi="http://www.w3.org/2001/XInclude"><xi:include href="self.xml"/></root>`
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("error writing file: %s", err)
+	}
+	doc, err := xml.ParseFile(file)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	if err := xml.ResolveIncludes(doc, file); err == nil {
+		t.Errorf("recursive inclusion should fail")
+	}
+}
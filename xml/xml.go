@@ -1,7 +1,25 @@
 package xml
 
 import (
+	"encoding"
+	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrType   = errors.New("type error")
+	ErrTarget = errors.New("invalid target")
+)
+
+var (
+	qnameType           = reflect.TypeFor[QName]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
 )
 
 func Decode(r io.Reader) (any, error) {
@@ -11,3 +29,371 @@ func Decode(r io.Reader) (any, error) {
 	}
 	return doc.Map()
 }
+
+func DecodeNode(node Node, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: non-nil pointer expected (%T)", ErrTarget, v)
+	}
+	if doc, ok := node.(*Document); ok {
+		node = doc.Root()
+	}
+	el, ok := node.(*Element)
+	if !ok {
+		return ErrElement
+	}
+	return decodeElement(el, rv.Elem())
+}
+
+func Encode(v any) (Node, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("%w: nil value", ErrType)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: struct expected (%s)", ErrType, rv.Type())
+	}
+	return encodeElement(LocalName(rv.Type().Name()), rv)
+}
+
+type fieldKind int8
+
+const (
+	fieldElement fieldKind = iota
+	fieldAttr
+	fieldCharData
+	fieldXMLName
+)
+
+type structField struct {
+	name      string
+	index     []int
+	kind      fieldKind
+	omitEmpty bool
+}
+
+func (f structField) qname() QName {
+	qn, err := ParseName(f.name)
+	if err != nil {
+		return LocalName(f.name)
+	}
+	return qn
+}
+
+func structFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft, append(slices.Clone(index), i))...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		f := structField{
+			name:  name,
+			index: append(slices.Clone(index), i),
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		if sf.Name == "XMLName" && sf.Type == qnameType {
+			f.kind = fieldXMLName
+		}
+		for _, o := range strings.Split(opts, ",") {
+			switch o {
+			case "attr":
+				f.kind = fieldAttr
+			case "chardata":
+				f.kind = fieldCharData
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func decodeElement(el *Element, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeElement(el, v.Elem())
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(el.Value()))
+	}
+	if v.Kind() != reflect.Struct {
+		return decodeText(el.Value(), v)
+	}
+	for _, f := range structFields(v.Type(), nil) {
+		fv := fieldByIndex(v, f.index)
+		switch f.kind {
+		case fieldXMLName:
+			fv.Set(reflect.ValueOf(el.QName))
+		case fieldAttr:
+			ix := slices.IndexFunc(el.Attrs, func(a Attribute) bool {
+				return a.Name == f.name || a.QualifiedName() == f.name
+			})
+			if ix < 0 {
+				continue
+			}
+			if err := decodeText(el.Attrs[ix].Value(), fv); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		case fieldCharData:
+			if err := decodeText(charData(el), fv); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+		default:
+			if err := decodeChildren(el, f, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeChildren(el *Element, f structField, v reflect.Value) error {
+	var nodes []*Element
+	for _, n := range el.Nodes {
+		c, ok := n.(*Element)
+		if !ok || (c.Name != f.name && c.QualifiedName() != f.name) {
+			continue
+		}
+		nodes = append(nodes, c)
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for _, c := range nodes {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeElement(c, item); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+			v.Set(reflect.Append(v, item))
+		}
+		return nil
+	}
+	if err := decodeElement(nodes[0], v); err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	return nil
+}
+
+func decodeText(str string, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeText(str, v.Elem())
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(str))
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a boolean", ErrType, str)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(str), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s", ErrType, str, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(strings.TrimSpace(str), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s", ErrType, str, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(str), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a valid %s", ErrType, str, v.Type())
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("%w: unsupported type %s", ErrType, v.Type())
+		}
+		v.SetBytes([]byte(str))
+	default:
+		return fmt.Errorf("%w: unsupported type %s", ErrType, v.Type())
+	}
+	return nil
+}
+
+func encodeElement(name QName, v reflect.Value) (*Element, error) {
+	el := NewElement(name)
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		el.Append(NewText(string(text)))
+		return el, nil
+	}
+	if v.Kind() != reflect.Struct {
+		str, err := encodeText(v)
+		if err != nil {
+			return nil, err
+		}
+		if str != "" {
+			el.Append(NewText(str))
+		}
+		return el, nil
+	}
+	for _, f := range structFields(v.Type(), nil) {
+		fv, ok := encodeFieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		switch f.kind {
+		case fieldXMLName:
+			if qn := fv.Interface().(QName); !qn.Zero() {
+				el.QName = qn
+			}
+		case fieldAttr:
+			str, err := encodeText(fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+			attr := NewAttribute(f.qname(), str)
+			el.Append(&attr)
+		case fieldCharData:
+			str, err := encodeText(fv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+			el.Append(NewText(str))
+		default:
+			if err := encodeChildren(el, f, fv); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return el, nil
+}
+
+func encodeChildren(el *Element, f structField, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeChildren(el, f, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	child, err := encodeElement(f.qname(), v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	el.Append(child)
+	return nil
+}
+
+func encodeText(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported type %s", ErrType, v.Type())
+}
+
+func charData(el *Element) string {
+	var str strings.Builder
+	for _, n := range el.Nodes {
+		switch n.(type) {
+		case *Text, *CharData:
+			str.WriteString(n.Value())
+		}
+	}
+	return str.String()
+}
+
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func encodeFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,81 @@
+package xml_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+)
+
+type testItem struct {
+	Id    int    `xml:"id,attr"`
+	Label string `xml:",chardata"`
+}
+
+type testMeta struct {
+	Author string `xml:"author,omitempty"`
+}
+
+type testBook struct {
+	XMLName xml.QName
+	testMeta
+	Lang   string     `xml:"lang,attr"`
+	Title  string     `xml:"title"`
+	Price  float64    `xml:"price"`
+	Stock  *bool      `xml:"stock"`
+	Items  []testItem `xml:"item"`
+	Ignore string     `xml:"-"`
+}
+
+func TestDecodeNode(t *testing.T) {
+	doc, err := xml.ParseString(`<book lang="en"><title>codecs</title><author>midbel</author><price> 9.5 </price><stock>true</stock><item id="1">first</item><item id="2">second</item><Ignore>x</Ignore></book>`)
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	var b testBook
+	if err := xml.DecodeNode(doc, &b); err != nil {
+		t.Fatalf("error decoding document: %s", err)
+	}
+	if b.XMLName.Name != "book" || b.Lang != "en" || b.Title != "codecs" || b.Author != "midbel" {
+		t.Errorf("fields mismatched: %+v", b)
+	}
+	if b.Price != 9.5 || b.Stock == nil || !*b.Stock || b.Ignore != "" {
+		t.Errorf("fields mismatched: %+v", b)
+	}
+	want := []testItem{{Id: 1, Label: "first"}, {Id: 2, Label: "second"}}
+	if !slices.Equal(b.Items, want) {
+		t.Errorf("items mismatched! want %v, got %v", want, b.Items)
+	}
+	if err := xml.DecodeNode(doc, b); err == nil {
+		t.Errorf("expected error when decoding into non pointer value")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	b := testBook{
+		XMLName: xml.LocalName("book"),
+		Lang:    "en",
+		Title:   "codecs",
+		Price:   9.5,
+		Items:   []testItem{{Id: 1, Label: "first"}},
+	}
+	node, err := xml.Encode(b)
+	if err != nil {
+		t.Fatalf("error encoding value: %s", err)
+	}
+	var (
+		buf strings.Builder
+		ws  = xml.NewWriter(&buf)
+	)
+	ws.WriterOptions |= xml.OptionCompact | xml.OptionNoProlog
+	if err := ws.Write(xml.NewDocument(node)); err != nil {
+		t.Fatalf("error writing document: %s", err)
+	}
+	want := `<book lang="en"><title>codecs</title><price>9.5</price><item id="1">first</item></book>`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatched")
+		t.Logf("want: %s", want)
+		t.Logf("got : %s", got)
+	}
+}
@@ -0,0 +1,198 @@
+package avt
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+var ErrTemplate = errors.New("invalid attribute value template")
+
+type Compiler interface {
+	Create(string) (xpath.Expr, error)
+}
+
+type Evaluator interface {
+	Eval(xpath.Expr, xml.Node) (xpath.Sequence, error)
+}
+
+type Template struct {
+	parts []part
+}
+
+type part struct {
+	text   string
+	source string
+	expr   xpath.Expr
+}
+
+func Compile(str string, cp Compiler) (*Template, error) {
+	if err := Check(str); err != nil {
+		return nil, err
+	}
+	var tpl Template
+	for q, ok := range Split(str) {
+		if !ok {
+			tpl.parts = append(tpl.parts, part{text: q})
+			continue
+		}
+		if strings.TrimSpace(q) == "" {
+			return nil, fmt.Errorf("%w: %q: empty expression", ErrTemplate, str)
+		}
+		expr, err := cp.Create(q)
+		if err != nil {
+			return nil, err
+		}
+		tpl.parts = append(tpl.parts, part{source: q, expr: expr})
+	}
+	return &tpl, nil
+}
+
+func Eval(str string, cp Compiler, node xml.Node) (string, error) {
+	tpl, err := Compile(str, cp)
+	if err != nil {
+		return "", err
+	}
+	return tpl.Eval(node)
+}
+
+func (t *Template) Eval(node xml.Node) (string, error) {
+	return t.Expand(node, func(seq xpath.Sequence) (string, error) {
+		list, err := seq.Strings()
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(list, " "), nil
+	})
+}
+
+func (t *Template) Expand(node xml.Node, format func(xpath.Sequence) (string, error)) (string, error) {
+	return t.expand(node, xpath.Expr.Find, format)
+}
+
+// ExpandWith is like Expand but evaluates the expressions of the template with
+// eval instead of the environment they have been compiled with.
+func (t *Template) ExpandWith(eval Evaluator, node xml.Node, format func(xpath.Sequence) (string, error)) (string, error) {
+	return t.expand(node, eval.Eval, format)
+}
+
+func (t *Template) expand(node xml.Node, find func(xpath.Expr, xml.Node) (xpath.Sequence, error), format func(xpath.Sequence) (string, error)) (string, error) {
+	var str strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			str.WriteString(p.text)
+			continue
+		}
+		seq, err := find(p.expr, node)
+		if err != nil {
+			return "", err
+		}
+		res, err := format(seq)
+		if err != nil {
+			return "", err
+		}
+		str.WriteString(res)
+	}
+	return str.String(), nil
+}
+
+func (t *Template) Static() bool {
+	for _, p := range t.parts {
+		if p.expr != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Template) String() string {
+	var str strings.Builder
+	for _, p := range t.parts {
+		if p.expr != nil {
+			str.WriteString("{")
+			str.WriteString(p.source)
+			str.WriteString("}")
+			continue
+		}
+		str.WriteString(strings.ReplaceAll(strings.ReplaceAll(p.text, "{", "{{"), "}", "}}"))
+	}
+	return str.String()
+}
+
+func Check(str string) error {
+	return scan(str, nil)
+}
+
+func Split(str string) iter.Seq2[string, bool] {
+	fn := func(yield func(string, bool) bool) {
+		scan(str, yield)
+	}
+	return fn
+}
+
+func scan(str string, yield func(string, bool) bool) error {
+	var (
+		text strings.Builder
+		emit = func(str string, expr bool) bool {
+			return yield == nil || yield(str, expr)
+		}
+	)
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '{' && i+1 < len(str) && str[i+1] == '{':
+			text.WriteByte(c)
+			i++
+		case c == '}' && i+1 < len(str) && str[i+1] == '}':
+			text.WriteByte(c)
+			i++
+		case c == '}':
+			return fmt.Errorf("%w: %q: unescaped '}' at offset %d", ErrTemplate, str, i)
+		case c == '{':
+			end, err := skipExpr(str, i+1)
+			if err != nil {
+				return err
+			}
+			if text.Len() > 0 && !emit(text.String(), false) {
+				return nil
+			}
+			text.Reset()
+			if !emit(str[i+1:end], true) {
+				return nil
+			}
+			i = end
+		default:
+			text.WriteByte(c)
+		}
+	}
+	if text.Len() > 0 || len(str) == 0 {
+		emit(text.String(), false)
+	}
+	return nil
+}
+
+func skipExpr(str string, offset int) (int, error) {
+	var depth int
+	for i := offset; i < len(str); i++ {
+		switch c := str[i]; c {
+		case '\'', '"':
+			ix := strings.IndexByte(str[i+1:], c)
+			if ix < 0 {
+				return 0, fmt.Errorf("%w: %q: unterminated string literal", ErrTemplate, str)
+			}
+			i += ix + 1
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		default:
+		}
+	}
+	return 0, fmt.Errorf("%w: %q: missing '}'", ErrTemplate, str)
+}
@@ -0,0 +1,77 @@
+package avt_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+	"github.com/midbel/codecs/xpath/avt"
+)
+
+func TestEval(t *testing.T) {
+	const doc = `<root><item id="a">foo</item><item id="b">bar</item></root>`
+
+	root, err := xml.NewParser(strings.NewReader(doc)).Parse()
+	if err != nil {
+		t.Fatalf("fail to parse input document: %s", err)
+	}
+	tests := []struct {
+		Template string
+		Want     string
+	}{
+		{
+			Template: "static",
+			Want:     "static",
+		},
+		{
+			Template: "{count(/root/item)} items",
+			Want:     "2 items",
+		},
+		{
+			Template: "item-{/root/item[1]/@id}-{/root/item[2]}",
+			Want:     "item-a-bar",
+		},
+		{
+			Template: "{{escaped}} {'}'}",
+			Want:     "{escaped} }",
+		},
+		{
+			Template: "{/root/item}",
+			Want:     "foo bar",
+		},
+		{
+			Template: "{map{'k': 'v'}('k')}",
+			Want:     "v",
+		},
+	}
+	eval := xpath.NewEvaluator()
+	for _, c := range tests {
+		got, err := avt.Eval(c.Template, eval, root)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.Template, err)
+			continue
+		}
+		if got != c.Want {
+			t.Errorf("%s: result mismatched! want %q, got %q", c.Template, c.Want, got)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []string{
+		"{",
+		"}",
+		"text {count(",
+		"{}",
+		"{'unterminated}",
+	}
+	eval := xpath.NewEvaluator()
+	for _, str := range tests {
+		_, err := avt.Compile(str, eval)
+		if !errors.Is(err, avt.ErrTemplate) {
+			t.Errorf("%s: expected invalid template error, got %v", str, err)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package xpath
 
 import (
 	"errors"
+	"math"
+	"math/big"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/midbel/codecs/xml"
@@ -26,10 +30,17 @@ var (
 	xsAtomic   = &anyAtomicType{}
 	xsString   = &stringType{}
 	xsBool     = &booleanType{}
+	xsDouble   = &doubleType{}
+	xsFloat    = &floatType{}
 	xsDecimal  = &decimalType{}
 	xsInteger  = &integerType{}
 	xsDateTime = &datetimeType{}
 	xsDate     = &dateType{}
+	xsDuration = &durationType{}
+	xsAnyURI   = &anyURIType{}
+
+	xsDayTimeDuration   = &dayTimeDurationType{}
+	xsYearMonthDuration = &yearMonthDurationType{}
 )
 
 var supportedTypes = map[xml.QName]XdmType{
@@ -38,10 +49,17 @@ var supportedTypes = map[xml.QName]XdmType{
 	xsAtomic.Name():   xsAtomic,
 	xsString.Name():   xsString,
 	xsBool.Name():     xsBool,
+	xsDouble.Name():   xsDouble,
+	xsFloat.Name():    xsFloat,
 	xsDecimal.Name():  xsDecimal,
 	xsInteger.Name():  xsInteger,
 	xsDateTime.Name(): xsDateTime,
 	xsDate.Name():     xsDate,
+	xsDuration.Name(): xsDuration,
+	xsAnyURI.Name():   xsAnyURI,
+
+	xsDayTimeDuration.Name():   xsDayTimeDuration,
+	xsYearMonthDuration.Name(): xsYearMonthDuration,
 }
 
 func init() {
@@ -55,10 +73,29 @@ func init() {
 	xsAny.append(xsAtomic)
 	xsAtomic.append(xsBool)
 	xsAtomic.append(xsString)
+	xsAtomic.append(xsDouble)
+	xsAtomic.append(xsFloat)
 	xsAtomic.append(xsDecimal)
 	xsAtomic.append(xsDateTime)
+	xsAtomic.append(xsDuration)
+	xsAtomic.append(xsAnyURI)
 	xsDecimal.append(xsInteger)
 	xsDateTime.append(xsDate)
+	xsDuration.append(xsDayTimeDuration)
+	xsDuration.append(xsYearMonthDuration)
+}
+
+func formatFloat(f float64, size int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "INF"
+	case math.IsInf(f, -1):
+		return "-INF"
+	default:
+		return strconv.FormatFloat(f, 'f', -1, size)
+	}
 }
 
 func toString(value any) (string, error) {
@@ -66,6 +103,10 @@ func toString(value any) (string, error) {
 }
 
 func toFloat(value any) (float64, error) {
+	return xsDouble.To(value)
+}
+
+func toDecimal(value any) (Decimal, error) {
 	return xsDecimal.To(value)
 }
 
@@ -81,6 +122,14 @@ func toTime(value any) (time.Time, error) {
 	return xsDateTime.To(value)
 }
 
+func toDate(value any) (time.Time, error) {
+	return xsDate.To(value)
+}
+
+func toDuration(value any) (Duration, error) {
+	return xsDuration.To(value)
+}
+
 type untypedType struct {
 	sub []XdmType
 }
@@ -167,7 +216,7 @@ func (t *anyAtomicType) InstanceOf(e Expr) bool {
 
 func (*anyAtomicType) To(v any) (any, error) {
 	switch v.(type) {
-	case int64, float64, bool, string, time.Time:
+	case int64, float64, bool, string, time.Time, Duration, Decimal:
 		return v, nil
 	default:
 		return nil, ErrCast
@@ -220,15 +269,23 @@ func (*stringType) To(v any) (string, error) {
 	var str string
 	switch v := v.(type) {
 	case int64:
-		str = strconv.FormatInt(v, 64)
+		str = strconv.FormatInt(v, 10)
+	case Integer:
+		str = v.String()
 	case float64:
-		str = strconv.FormatFloat(v, 'f', -1, 64)
+		str = formatFloat(v, 64)
+	case float32:
+		str = formatFloat(float64(v), 32)
+	case Decimal:
+		str = v.String()
 	case bool:
 		str = strconv.FormatBool(v)
 	case string:
 		str = v
 	case time.Time:
-		str = v.Format(time.RFC3339)
+		str = formatTimeValue(v)
+	case Duration:
+		str = v.String()
 	default:
 		return str, ErrCast
 	}
@@ -260,32 +317,38 @@ func (t *stringType) append(xt XdmType) {
 	// pass
 }
 
-type decimalType struct {
+type doubleType struct {
 	parent XdmType
 	sub    []XdmType
 }
 
-func (*decimalType) Name() xml.QName {
-	return xml.QualifiedName("decimal", "xs")
+func (*doubleType) Name() xml.QName {
+	return xml.QualifiedName("double", "xs")
 }
 
-func (t *decimalType) InstanceOf(e Expr) bool {
+func (t *doubleType) InstanceOf(e Expr) bool {
 	return instanceOf(e, t)
 }
 
-func (*decimalType) To(v any) (float64, error) {
+func (*doubleType) To(v any) (float64, error) {
 	var res float64
 	switch v := v.(type) {
 	case int64:
 		res = float64(v)
+	case Integer:
+		res = v.Float64()
 	case float64:
 		res = v
+	case float32:
+		res = float64(v)
+	case Decimal:
+		res = v.Float64()
 	case bool:
 		if v {
 			res += 1
 		}
 	case string:
-		d, err := strconv.ParseFloat(v, 64)
+		d, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
 		if err != nil {
 			return 0, err
 		}
@@ -298,6 +361,115 @@ func (*decimalType) To(v any) (float64, error) {
 	return res, nil
 }
 
+func (t *doubleType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *doubleType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *doubleType) subTypes() []XdmType {
+	return t.sub
+}
+
+func (t *doubleType) derived() XdmType {
+	return t.parent
+}
+
+func (t *doubleType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *doubleType) append(xt XdmType) {
+	xt.setParent(t)
+	t.sub = append(t.sub, xt)
+}
+
+type floatType struct {
+	parent XdmType
+}
+
+func (*floatType) Name() xml.QName {
+	return xml.QualifiedName("float", "xs")
+}
+
+func (t *floatType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*floatType) To(v any) (float32, error) {
+	f, err := xsDouble.To(v)
+	return float32(f), err
+}
+
+func (t *floatType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *floatType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *floatType) derived() XdmType {
+	return t.parent
+}
+
+func (t *floatType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *floatType) append(xt XdmType) {
+	// pass
+}
+
+type decimalType struct {
+	parent XdmType
+	sub    []XdmType
+}
+
+func (*decimalType) Name() xml.QName {
+	return xml.QualifiedName("decimal", "xs")
+}
+
+func (t *decimalType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*decimalType) To(v any) (Decimal, error) {
+	switch v := v.(type) {
+	case int64:
+		return DecimalFromInt(v), nil
+	case Integer:
+		return v.Decimal(), nil
+	case float64:
+		return DecimalFromFloat(v)
+	case float32:
+		return DecimalFromFloat(float64(v))
+	case Decimal:
+		return v, nil
+	case bool:
+		if v {
+			return DecimalFromInt(1), nil
+		}
+		return DecimalFromInt(0), nil
+	case string:
+		return ParseDecimal(v)
+	default:
+		return Decimal{}, ErrCast
+	}
+}
+
 func (t *decimalType) Cast(v any) (Sequence, error) {
 	x, err := t.To(v)
 	if err == nil {
@@ -345,14 +517,26 @@ func (*integerType) To(v any) (int64, error) {
 	switch v := v.(type) {
 	case int64:
 		res = v
+	case Integer:
+		if !v.get().IsInt64() {
+			return 0, ErrCast
+		}
+		res = v.get().Int64()
 	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, ErrCast
+		}
 		res = int64(v)
+	case float32:
+		return xsInteger.To(float64(v))
+	case Decimal:
+		res = v.Int64()
 	case bool:
 		if v {
 			res++
 		}
 	case string:
-		d, err := strconv.ParseInt(v, 0, 64)
+		d, err := strconv.ParseInt(strings.TrimSpace(v), 0, 64)
 		if err != nil {
 			return 0, err
 		}
@@ -366,6 +550,19 @@ func (*integerType) To(v any) (int64, error) {
 }
 
 func (t *integerType) Cast(v any) (Sequence, error) {
+	switch v := v.(type) {
+	case Integer:
+		return Singleton(v), nil
+	case string:
+		n, err := ParseInteger(v)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(n), nil
+	case Decimal:
+		rat := v.get()
+		return Singleton(createInteger(new(big.Int).Quo(rat.Num(), rat.Denom()))), nil
+	}
 	x, err := t.To(v)
 	if err == nil {
 		return Singleton(x), nil
@@ -409,6 +606,8 @@ func (*booleanType) To(v any) (bool, error) {
 		res = v != 0
 	case float64:
 		res = v != 0
+	case Decimal:
+		res = v.Sign() != 0
 	case bool:
 		res = v
 	case string:
@@ -467,7 +666,7 @@ func (*datetimeType) To(v any) (time.Time, error) {
 	case float64:
 		res = time.Unix(int64(v), 0)
 	case string:
-		x, err := time.Parse(time.RFC3339, v)
+		x, err := parseTime(v, dateTimeLayouts)
 		if err != nil {
 			return res, err
 		}
@@ -527,19 +726,29 @@ func (t *dateType) InstanceOf(e Expr) bool {
 }
 
 func (t *dateType) To(v any) (time.Time, error) {
-	if t, ok := t.parent.(interface{ To(any) (time.Time, error) }); ok {
-		return t.To(v)
+	if str, ok := v.(string); ok {
+		if res, err := parseTime(str, dateLayouts); err == nil {
+			return res, nil
+		}
 	}
-	var ret time.Time
-	return ret, ErrCast
+	res, err := toTime(v)
+	if err != nil {
+		return res, err
+	}
+	return truncateDate(res), nil
 }
 
 func (t *dateType) Cast(v any) (Sequence, error) {
-	return t.parent.Cast(v)
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
 }
 
 func (t *dateType) Castable(v any) bool {
-	return t.parent.Castable(v)
+	_, err := t.Cast(v)
+	return err == nil
 }
 
 func (t *dateType) setParent(parent XdmType) {
@@ -550,6 +759,270 @@ func (t *dateType) append(xt XdmType) {
 	// pass
 }
 
+type durationType struct {
+	parent XdmType
+	sub    []XdmType
+}
+
+func (*durationType) Name() xml.QName {
+	return xml.QualifiedName("duration", "xs")
+}
+
+func (t *durationType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*durationType) To(v any) (Duration, error) {
+	var res Duration
+	switch v := v.(type) {
+	case string:
+		return parseDuration(v)
+	case Duration:
+		res = v
+	default:
+		return res, ErrCast
+	}
+	return res, nil
+}
+
+func (t *durationType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		x.kind = anyDuration
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *durationType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *durationType) subTypes() []XdmType {
+	return t.sub
+}
+
+func (t *durationType) derived() XdmType {
+	return t.parent
+}
+
+func (t *durationType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *durationType) append(xt XdmType) {
+	xt.setParent(t)
+	t.sub = append(t.sub, xt)
+}
+
+type dayTimeDurationType struct {
+	parent XdmType
+}
+
+func (*dayTimeDurationType) Name() xml.QName {
+	return xml.QualifiedName("dayTimeDuration", "xs")
+}
+
+func (t *dayTimeDurationType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*dayTimeDurationType) To(v any) (Duration, error) {
+	var res Duration
+	switch v := v.(type) {
+	case string:
+		d, err := parseDuration(v)
+		if err != nil {
+			return res, err
+		}
+		if d.Months != 0 {
+			return res, ErrCast
+		}
+		res = d
+	case Duration:
+		res = v
+	default:
+		return res, ErrCast
+	}
+	return createDayTimeDuration(res.DayTime), nil
+}
+
+func (t *dayTimeDurationType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *dayTimeDurationType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *dayTimeDurationType) derived() XdmType {
+	return t.parent
+}
+
+func (t *dayTimeDurationType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *dayTimeDurationType) append(xt XdmType) {
+	// pass
+}
+
+type yearMonthDurationType struct {
+	parent XdmType
+}
+
+func (*yearMonthDurationType) Name() xml.QName {
+	return xml.QualifiedName("yearMonthDuration", "xs")
+}
+
+func (t *yearMonthDurationType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*yearMonthDurationType) To(v any) (Duration, error) {
+	var res Duration
+	switch v := v.(type) {
+	case string:
+		d, err := parseDuration(v)
+		if err != nil {
+			return res, err
+		}
+		if d.DayTime != 0 {
+			return res, ErrCast
+		}
+		res = d
+	case Duration:
+		res = v
+	default:
+		return res, ErrCast
+	}
+	return createYearMonthDuration(res.Months), nil
+}
+
+func (t *yearMonthDurationType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *yearMonthDurationType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *yearMonthDurationType) derived() XdmType {
+	return t.parent
+}
+
+func (t *yearMonthDurationType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *yearMonthDurationType) append(xt XdmType) {
+	// pass
+}
+
+type anyURIType struct {
+	parent XdmType
+}
+
+func (*anyURIType) Name() xml.QName {
+	return xml.QualifiedName("anyURI", "xs")
+}
+
+func (t *anyURIType) InstanceOf(e Expr) bool {
+	return instanceOf(e, t)
+}
+
+func (*anyURIType) To(v any) (string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return "", ErrCast
+	}
+	str = strings.TrimSpace(str)
+	if _, err := url.Parse(str); err != nil {
+		return "", ErrCast
+	}
+	return str, nil
+}
+
+func (t *anyURIType) Cast(v any) (Sequence, error) {
+	x, err := t.To(v)
+	if err == nil {
+		return Singleton(x), nil
+	}
+	return nil, err
+}
+
+func (t *anyURIType) Castable(v any) bool {
+	_, err := t.Cast(v)
+	return err == nil
+}
+
+func (t *anyURIType) derived() XdmType {
+	return t.parent
+}
+
+func (t *anyURIType) setParent(parent XdmType) {
+	t.parent = parent
+}
+
+func (t *anyURIType) append(xt XdmType) {
+	// pass
+}
+
+func itemInstanceOf(item Item, typ XdmType) bool {
+	if !item.Atomic() {
+		return typ == xsUntyped
+	}
+	source := typeOfValue(item.Value())
+	if source == nil {
+		return false
+	}
+	return isInstanceOf(source, typ)
+}
+
+func typeOfValue(value any) XdmType {
+	switch v := value.(type) {
+	case string:
+		return xsString
+	case bool:
+		return xsBool
+	case int64, Integer:
+		return xsInteger
+	case Decimal:
+		return xsDecimal
+	case float64:
+		return xsDouble
+	case float32:
+		return xsFloat
+	case Duration:
+		switch v.kind {
+		case dayTimeDuration:
+			return xsDayTimeDuration
+		case yearMonthDuration:
+			return xsYearMonthDuration
+		default:
+			return xsDuration
+		}
+	case time.Time:
+		if v.Equal(truncateDate(v)) {
+			return xsDate
+		}
+		return xsDateTime
+	default:
+		return nil
+	}
+}
+
 func instanceOf(expr Expr, typ XdmType) bool {
 	t, ok := expr.(TypedExpr)
 	if !ok {
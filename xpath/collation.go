@@ -0,0 +1,242 @@
+package xpath
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const (
+	CodepointCollation       = functionNS + "/collation/codepoint"
+	CaseInsensitiveCollation = functionNS + "/collation/html-ascii-case-insensitive"
+)
+
+var ErrCollation = errors.New("collation not supported")
+
+type collation func(string, string) int
+
+func getCollation(uri string) (collation, error) {
+	switch uri {
+	case "", CodepointCollation:
+		return strings.Compare, nil
+	case CaseInsensitiveCollation:
+		return compareFold, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCollation, uri)
+	}
+}
+
+func getCollationFromArgs(ctx Context, args []Expr, ix int) (collation, error) {
+	if len(args) <= ix {
+		return getCollation("")
+	}
+	uri, err := getStringFromExpr(args[ix], ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getCollation(uri)
+}
+
+func compareFold(left, right string) int {
+	return strings.Compare(asciiLower(left), asciiLower(right))
+}
+
+func asciiLower(str string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, str)
+}
+
+func atomizeSequence(seq Sequence) (Sequence, error) {
+	var list Sequence
+	for _, item := range seq {
+		switch i := item.(type) {
+		case arrayItem:
+			vs, err := atomizeSequence(i.Sequence())
+			if err != nil {
+				return nil, err
+			}
+			list.Concat(vs)
+		case nodeItem:
+			list.Append(createLiteral(i.Value()))
+		default:
+			if !item.Atomic() {
+				return nil, fmt.Errorf("%w: item can not be atomized", ErrType)
+			}
+			list.Append(item)
+		}
+	}
+	return list, nil
+}
+
+func equalValues(left, right any, cmp collation, nan bool) bool {
+	switch {
+	case isNumberValue(left) || isNumberValue(right):
+		if !isNumberValue(left) || !isNumberValue(right) {
+			return false
+		}
+		x, _ := toFloat(left)
+		y, _ := toFloat(right)
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return nan && math.IsNaN(x) && math.IsNaN(y)
+		}
+	case isBoolValue(left) || isBoolValue(right):
+		if !isBoolValue(left) || !isBoolValue(right) {
+			return false
+		}
+	case isTimeValue(left) || isTimeValue(right):
+		if !isTimeValue(left) || !isTimeValue(right) {
+			return false
+		}
+	case isDurationValue(left) || isDurationValue(right):
+		if !isDurationValue(left) || !isDurationValue(right) {
+			return false
+		}
+	default:
+		x, err1 := toString(left)
+		y, err2 := toString(right)
+		return err1 == nil && err2 == nil && cmp(x, y) == 0
+	}
+	c, err := compareAtomic(left, right)
+	return err == nil && c == 0
+}
+
+func deepEqualSequence(left, right Sequence, cmp collation) (bool, error) {
+	if len(left) != len(right) {
+		return false, nil
+	}
+	for i := range left {
+		ok, err := deepEqualItem(left[i], right[i], cmp)
+		if !ok || err != nil {
+			return ok, err
+		}
+	}
+	return true, nil
+}
+
+func deepEqualItem(left, right Item, cmp collation) (bool, error) {
+	switch x := left.(type) {
+	case funcItem:
+		return false, fmt.Errorf("%w: function items can not be compared", ErrType)
+	case nodeItem:
+		y, ok := right.(nodeItem)
+		return ok && deepEqualNode(x.Node(), y.Node(), cmp), nil
+	case arrayItem:
+		y, ok := right.(arrayItem)
+		if !ok || len(x.values) != len(y.values) {
+			return false, nil
+		}
+		for j := range x.values {
+			if ok, err := deepEqualSequence(x.values[j], y.values[j], cmp); !ok || err != nil {
+				return ok, err
+			}
+		}
+		return true, nil
+	case mapItem:
+		y, ok := right.(mapItem)
+		if !ok || len(x.values) != len(y.values) {
+			return false, nil
+		}
+		for k, v := range x.values {
+			var found bool
+			for j, w := range y.values {
+				if !equalValues(k.Value(), j.Value(), strings.Compare, true) {
+					continue
+				}
+				found = true
+				if ok, err := deepEqualSequence(v, w, cmp); !ok || err != nil {
+					return ok, err
+				}
+				break
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		if _, ok := right.(funcItem); ok {
+			return false, fmt.Errorf("%w: function items can not be compared", ErrType)
+		}
+		if !left.Atomic() || !right.Atomic() {
+			return false, nil
+		}
+		return equalValues(left.Value(), right.Value(), cmp, true), nil
+	}
+}
+
+func deepEqualNode(left, right xml.Node, cmp collation) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+	switch x := left.(type) {
+	case *xml.Document:
+		y, ok := right.(*xml.Document)
+		return ok && deepEqualNodes(x.Nodes, y.Nodes, cmp)
+	case *xml.Element:
+		y, ok := right.(*xml.Element)
+		if !ok || !sameName(x.QName, y.QName) || len(x.Attrs) != len(y.Attrs) {
+			return false
+		}
+		for _, a := range x.Attrs {
+			ok := false
+			for _, b := range y.Attrs {
+				if sameName(a.QName, b.QName) {
+					ok = cmp(a.Value(), b.Value()) == 0
+					break
+				}
+			}
+			if !ok {
+				return false
+			}
+		}
+		return deepEqualNodes(x.Nodes, y.Nodes, cmp)
+	case *xml.Attribute:
+		y, ok := right.(*xml.Attribute)
+		return ok && sameName(x.QName, y.QName) && cmp(x.Value(), y.Value()) == 0
+	case *xml.Instruction:
+		return left.LocalName() == right.LocalName() && cmp(left.Value(), right.Value()) == 0
+	default:
+		return cmp(left.Value(), right.Value()) == 0
+	}
+}
+
+func deepEqualNodes(left, right []xml.Node, cmp collation) bool {
+	var (
+		xs = significantNodes(left)
+		ys = significantNodes(right)
+	)
+	if len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !deepEqualNode(xs[i], ys[i], cmp) {
+			return false
+		}
+	}
+	return true
+}
+
+func significantNodes(nodes []xml.Node) []xml.Node {
+	var list []xml.Node
+	for _, n := range nodes {
+		if t := n.Type(); t == xml.TypeComment || t == xml.TypeInstruction {
+			continue
+		}
+		list = append(list, n)
+	}
+	return list
+}
+
+func sameName(left, right xml.QName) bool {
+	if left.Uri != "" || right.Uri != "" {
+		return left.Uri == right.Uri && left.Name == right.Name
+	}
+	return left.QualifiedName() == right.QualifiedName()
+}
@@ -45,7 +45,12 @@ const (
 )
 
 var angleNS = map[string]string{
-	"agl": "http://midbel.org/angle",
+	"agl":     "http://midbel.org/angle",
+	"file":    "http://expath.org/ns/file",
+	"http":    "http://expath.org/ns/http-client",
+	"binary":  "http://expath.org/ns/binary",
+	"crypto":  "http://expath.org/ns/crypto",
+	"process": "http://midbel.org/angle/process",
 }
 
 var defaultNS = map[string]string{
@@ -104,6 +109,7 @@ func createCompiler(r io.Reader) *Compiler {
 		opSub:        cp.compileBinary,
 		opMul:        cp.compileBinary,
 		opDiv:        cp.compileBinary,
+		opIdiv:       cp.compileBinary,
 		opMod:        cp.compileBinary,
 		opValEq:      cp.compileBinary,
 		opValNe:      cp.compileBinary,
@@ -128,6 +134,7 @@ func createCompiler(r io.Reader) *Compiler {
 		opCastAs:     cp.compileCast,
 		opCastableAs: cp.compileCastable,
 		opInstanceOf: cp.compileInstanceOf,
+		opTreatAs:    cp.compileTreat,
 	}
 	cp.postfix = map[rune]func(Expr) (Expr, error){
 		begPred:    cp.compileFilter,
@@ -170,7 +177,7 @@ func (c *Compiler) Compile() (Expr, error) {
 		return nil, err
 	}
 	q := query{
-		expr: expr,
+		expr: optimize(expr),
 	}
 	return q, err
 }
@@ -196,9 +203,20 @@ func (c *Compiler) compile() (Expr, error) {
 func (c *Compiler) compileReservedPrefix() (Expr, error) {
 	switch c.getCurrentLiteral() {
 	case kwMap:
+		if c.peek.Type == Namespace {
+			return c.compileName()
+		}
 		return c.compileMap()
 	case kwArray:
+		if c.peek.Type == Namespace {
+			return c.compileName()
+		}
 		return c.compileArrayFunc()
+	case kwFunction:
+		if c.peek.Type != begGrp {
+			return c.compileName()
+		}
+		return c.compileFunction()
 	case kwLet:
 		return c.compileLet()
 	case kwIf:
@@ -213,31 +231,40 @@ func (c *Compiler) compileReservedPrefix() (Expr, error) {
 }
 
 func (c *Compiler) compileLookupPrefix() (Expr, error) {
-	c.next()
-	key, err := c.compileQName()
-	if err != nil {
-		return nil, err
-	}
-	expr := lookup{
-		expr: current{},
-		key:  key,
-	}
-	return expr, nil
+	return c.compileLookupPostfix(current{})
 }
 
 func (c *Compiler) compileLookupPostfix(left Expr) (Expr, error) {
+	c.Enter("lookup")
+	defer c.Leave("lookup")
 	c.next()
-	index, err := c.compileExpr(powLowest)
+	key, err := c.compileKeySpecifier()
 	if err != nil {
 		return nil, err
 	}
 	expr := lookup{
 		expr: left,
-		key:  index,
+		key:  key,
 	}
 	return expr, nil
 }
 
+func (c *Compiler) compileKeySpecifier() (Expr, error) {
+	switch {
+	case c.is(Name) || c.is(reserved):
+		return c.compileLiteral()
+	case c.is(Digit):
+		return c.compileNumber()
+	case c.is(begGrp):
+		return c.compileSequence()
+	case c.is(opMul):
+		c.next()
+		return nil, nil
+	default:
+		return nil, c.syntaxError("lookup", "expected key specifier")
+	}
+}
+
 func (c *Compiler) compileMap() (Expr, error) {
 	c.Enter("map")
 	defer c.Leave("map")
@@ -285,10 +312,55 @@ func (c *Compiler) compileMap() (Expr, error) {
 	if !c.is(endCurl) {
 		return nil, ErrSyntax
 	}
+	c.scan.DiscardBlanks()
 	c.next()
 	return expr, nil
 }
 
+func (c *Compiler) compileFunction() (Expr, error) {
+	c.Enter("function")
+	defer c.Leave("function")
+
+	c.next()
+	c.next()
+	var fn inline
+	for !c.done() && !c.is(endGrp) {
+		if !c.is(variable) {
+			return nil, c.syntaxError("function", "identifier expected")
+		}
+		fn.params = append(fn.params, c.getCurrentLiteral())
+		c.next()
+		switch {
+		case c.is(opSeq):
+			c.next()
+			if c.is(endGrp) {
+				return nil, c.syntaxError("function", "unexpected ',' before ')'")
+			}
+		case c.is(endGrp):
+		default:
+			return nil, c.unexpectedError("function")
+		}
+	}
+	if !c.is(endGrp) {
+		return nil, c.syntaxError("function", "expected ')'")
+	}
+	c.next()
+	if !c.is(begCurl) {
+		return nil, c.syntaxError("function", "expected '{'")
+	}
+	c.next()
+	body, err := c.compileExpr(powLowest)
+	if err != nil {
+		return nil, err
+	}
+	if !c.is(endCurl) {
+		return nil, c.syntaxError("function", "expected '}'")
+	}
+	c.next()
+	fn.body = body
+	return fn, nil
+}
+
 func (c *Compiler) compileArray() (Expr, error) {
 	c.Enter("array")
 	defer c.Leave("array")
@@ -332,7 +404,9 @@ func (c *Compiler) compileArrayFunc() (Expr, error) {
 	}
 	c.next()
 
-	var arr array
+	arr := array{
+		curly: true,
+	}
 	for !c.done() && !c.is(endCurl) {
 		c.skipBlank()
 		e, err := c.compileExpr(powLowest)
@@ -425,10 +499,10 @@ func (c *Compiler) compileFor() (Expr, error) {
 		}
 	}
 	if !c.is(reserved) && c.getCurrentLiteral() != kwReturn {
-		return nil, fmt.Errorf("expected return keyword")
+		return nil, c.syntaxError("for", "expected 'return'")
 	}
 	c.next()
-	expr, err := c.compile()
+	expr, err := c.compileExpr(powLowest)
 	if err != nil {
 		return nil, err
 	}
@@ -572,49 +646,80 @@ func (c *Compiler) compileInstanceOf(left Expr) (Expr, error) {
 	defer c.Leave("instanceof")
 	c.next()
 
+	types, occurrence, err := c.compileSequenceType("instance of")
+	if err != nil {
+		return nil, err
+	}
 	expr := instanceof{
-		expr: left,
+		expr:       left,
+		types:      types,
+		occurrence: occurrence,
 	}
+	return expr, nil
+}
+
+func (c *Compiler) compileTreat(left Expr) (Expr, error) {
+	c.Enter("treat")
+	defer c.Leave("treat")
+	c.next()
+
+	types, occurrence, err := c.compileSequenceType("treat as")
+	if err != nil {
+		return nil, err
+	}
+	expr := treat{
+		expr:       left,
+		types:      types,
+		occurrence: occurrence,
+	}
+	return expr, nil
+}
+
+func (c *Compiler) compileSequenceType(ctx string) ([]XdmType, OccurrenceType, error) {
+	var (
+		types      []XdmType
+		occurrence OccurrenceType
+	)
 	if c.is(begGrp) {
 		c.next()
 		for !c.done() && !c.is(endGrp) {
 			t, err := c.compileType()
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			}
-			expr.types = append(expr.types, t)
+			types = append(types, t)
 			switch {
 			case c.is(opUnion):
 				c.next()
 			case c.is(endGrp):
 			default:
-				return nil, c.syntaxError("instance of", "expected '|' or ')'")
+				return nil, 0, c.syntaxError(ctx, "expected '|' or ')'")
 			}
 		}
 		if !c.is(endGrp) {
-			return nil, c.syntaxError("instance of", "expected ')'")
+			return nil, 0, c.syntaxError(ctx, "expected ')'")
 		}
 		c.next()
 	} else {
 		t, err := c.compileType()
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		expr.types = append(expr.types, t)
+		types = append(types, t)
 	}
 	switch {
 	case c.is(opQuestion):
-		expr.occurrence = ZeroOrOneOccurrence
+		occurrence = ZeroOrOneOccurrence
 	case c.is(opAdd):
-		expr.occurrence = OneOrMoreOccurrence
+		occurrence = OneOrMoreOccurrence
 	case c.is(opMul):
-		expr.occurrence = ZeroOrMoreOccurrence
+		occurrence = ZeroOrMoreOccurrence
 	default:
 	}
-	if expr.occurrence != 0 {
+	if occurrence != 0 {
 		c.next()
 	}
-	return expr, nil
+	return types, occurrence, nil
 }
 
 func (c *Compiler) compileCast(left Expr) (Expr, error) {
@@ -728,6 +833,7 @@ func (c *Compiler) compileSequence() (Expr, error) {
 	c.next()
 	var seq sequence
 	for !c.done() && !c.is(endGrp) {
+		c.skipBlank()
 		expr, err := c.compileExpr(powLowest)
 		if err != nil {
 			return nil, err
@@ -848,7 +954,23 @@ func (c *Compiler) compileNumber() (Expr, error) {
 	defer c.Leave("number")
 
 	defer c.next()
-	f, err := strconv.ParseFloat(c.getCurrentLiteral(), 64)
+	str := c.getCurrentLiteral()
+	if !strings.ContainsAny(str, "eE") {
+		if !strings.Contains(str, ".") {
+			if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+				return integer{expr: n}, nil
+			}
+			if n, err := ParseInteger(str); err == nil {
+				return NewValue(createLiteral(n)), nil
+			}
+		}
+		d, err := ParseDecimal(str)
+		if err != nil {
+			return nil, err
+		}
+		return decimal{expr: d}, nil
+	}
+	f, err := strconv.ParseFloat(str, 64)
 	if err != nil {
 		return nil, err
 	}
@@ -875,7 +997,25 @@ func (c *Compiler) compileReverse() (Expr, error) {
 func (c *Compiler) compileAttr() (Expr, error) {
 	c.Enter("attribute")
 	defer c.Leave("attribute")
+	if c.peek.Type == Namespace {
+		name, err := c.compileQName()
+		if err != nil {
+			return nil, err
+		}
+		a := axis{
+			kind: attributeAxis,
+			next: name,
+		}
+		return a, nil
+	}
 	defer c.next()
+	if c.getCurrentLiteral() == "*" {
+		a := axis{
+			kind: attributeAxis,
+			next: wildcard{},
+		}
+		return a, nil
+	}
 	a := attr{
 		ident: c.getCurrentLiteral(),
 	}
@@ -958,7 +1098,7 @@ func (c *Compiler) compileCall(left Expr) (Expr, error) {
 	defer c.Leave("call")
 
 	switch left.(type) {
-	case array, hashmap, identifier, subscript:
+	case array, hashmap, identifier, subscript, call:
 		return c.compileSubscriptCall(left)
 	default:
 		return c.compileFunctionCall(left)
@@ -1349,8 +1489,9 @@ const (
 	powAssign // variable assignment
 	powOr
 	powAnd
-	powCast
 	powInstanceOf
+	powTreat
+	powCast
 	powIdentity
 	powRange
 	powEqual
@@ -1372,6 +1513,7 @@ var bindings = map[rune]int{
 	currLevel:    powStep,
 	anyLevel:     powStep,
 	opInstanceOf: powInstanceOf,
+	opTreatAs:    powTreat,
 	opCastAs:     powCast,
 	opCastableAs: powCast,
 	opUnion:      powUnion,
@@ -1400,6 +1542,7 @@ var bindings = map[rune]int{
 	opSub:        powAdd,
 	opMul:        powMul,
 	opDiv:        powMul,
+	opIdiv:       powMul,
 	opMod:        powMul,
 	opRange:      powRange,
 	opArrow:      powArrow,
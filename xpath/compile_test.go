@@ -1,7 +1,11 @@
 package xpath
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/midbel/codecs/xml"
 )
 
 func TestCompile(t *testing.T) {
@@ -37,3 +41,155 @@ func TestCompile(t *testing.T) {
 		}
 	}
 }
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		Expr string
+		Want Expr
+	}{
+		{
+			Expr: "1 + 2 * 3",
+			Want: integer{expr: 7},
+		},
+		{
+			Expr: "-(1 + 1)",
+			Want: integer{expr: -2},
+		},
+		{
+			Expr: "1.5e0 * 2",
+			Want: number{expr: 3},
+		},
+		{
+			Expr: "'foo' || 'bar'",
+			Want: literal{expr: "foobar"},
+		},
+		{
+			Expr: "concat('foo', '-', 'bar')",
+			Want: literal{expr: "foo-bar"},
+		},
+		{
+			Expr: "'foo' = 'bar'",
+			Want: boolean{expr: false},
+		},
+		{
+			Expr: "false() and $undefined",
+			Want: boolean{expr: false},
+		},
+		{
+			Expr: "1 = 1 or $undefined",
+			Want: boolean{expr: true},
+		},
+		{
+			Expr: "if (2 > 1) then 'yes' else $undefined",
+			Want: literal{expr: "yes"},
+		},
+	}
+	eval := NewEvaluator()
+	for _, tt := range tests {
+		expr, err := eval.Create(tt.Expr)
+		if err != nil {
+			t.Errorf("%s: fail to compile expression: %s", tt.Expr, err)
+			continue
+		}
+		q, ok := expr.(query)
+		if !ok {
+			t.Errorf("%s: query expected but got %T", tt.Expr, expr)
+			continue
+		}
+		if q.expr != tt.Want {
+			t.Errorf("%s: expressions mismatched! want %#v, got %#v", tt.Expr, tt.Want, q.expr)
+		}
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		Expr string
+		Want bool
+	}{
+		{
+			Expr: "count(.) = 0 and error()",
+			Want: false,
+		},
+		{
+			Expr: "count(.) = 1 or error()",
+			Want: true,
+		},
+	}
+	eval := NewEvaluator()
+	for _, tt := range tests {
+		expr, err := eval.Create(tt.Expr)
+		if err != nil {
+			t.Errorf("%s: fail to compile expression: %s", tt.Expr, err)
+			continue
+		}
+		seq, err := expr.Find(xml.NewElement(xml.LocalName("root")))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.Expr, err)
+			continue
+		}
+		if got := seq.True(); got != tt.Want {
+			t.Errorf("%s: result mismatched! want %t, got %t", tt.Expr, tt.Want, got)
+		}
+	}
+}
+
+// benchAsserts mimics the tests of schematron asserts once their variables
+// have been replaced by the values of the schema: they mix node accesses with
+// constant parts that the optimizer can fold.
+var benchAsserts = []string{
+	"@qty > 0 and string-length(normalize-space(@name)) > 0",
+	"count(@*) <= 2 * 2",
+	"if (1 = 1) then @qty >= 0 - 1 else false()",
+	"@code = concat('x', '-', 'y') or @lang = upper-case('fr')",
+	"true() and not(@qty > 10 * 10)",
+}
+
+func BenchmarkOptimize(b *testing.B) {
+	var str strings.Builder
+	str.WriteString("<root>")
+	for i := range 200 {
+		fmt.Fprintf(&str, `<item name=" item-%d " qty="%d" lang="fr" code="x-y"/>`, i, i%7)
+	}
+	str.WriteString("</root>")
+	doc, err := xml.ParseString(str.String())
+	if err != nil {
+		b.Fatalf("fail to parse document: %s", err)
+	}
+	eval := NewEvaluator()
+	items, err := eval.Find("//item", doc)
+	if err != nil {
+		b.Fatalf("fail to select items: %s", err)
+	}
+	for _, optimized := range []bool{false, true} {
+		name := "raw"
+		if optimized {
+			name = "optimized"
+		}
+		b.Run(name, func(b *testing.B) {
+			var exprs []Expr
+			for _, a := range benchAsserts {
+				cp := NewCompiler(strings.NewReader(a))
+				cp.typeNS, cp.funcNS = schemaNS, functionNS
+				expr, err := cp.compile()
+				if err != nil {
+					b.Fatalf("%s: fail to compile expression: %s", a, err)
+				}
+				if optimized {
+					expr = optimize(expr)
+				}
+				exprs = append(exprs, query{expr: expr, ctx: defaultContext(nil)})
+			}
+			b.ResetTimer()
+			for range b.N {
+				for _, e := range exprs {
+					for _, i := range items {
+						if _, err := eval.Eval(e, i.Node()); err != nil {
+							b.Fatalf("fail to evaluate expression: %s", err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
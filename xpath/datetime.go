@@ -0,0 +1,268 @@
+package xpath
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midbel/codecs/internal/datefmt"
+)
+
+var implicitZone = datefmt.NoZone
+
+var (
+	dateTimeLayouts = []string{
+		"2006-01-02T15:04:05.999999999Z07:00",
+		"2006-01-02T15:04:05.999999999",
+	}
+	dateLayouts = []string{
+		"2006-01-02Z07:00",
+		"2006-01-02",
+	}
+	timeLayouts = []string{
+		"15:04:05.999999999Z07:00",
+		"15:04:05.999999999",
+	}
+)
+
+func parseTime(str string, layouts []string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	for i, layout := range layouts {
+		t, err := time.ParseInLocation(layout, str, implicitZone)
+		if err != nil {
+			continue
+		}
+		if i == 0 && !hasTimezone(t) {
+			t = t.In(time.UTC)
+		}
+		return t, nil
+	}
+	return time.Time{}, ErrCast
+}
+
+func hasTimezone(t time.Time) bool {
+	return t.Location() != implicitZone
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func formatTimeValue(t time.Time) string {
+	if !hasTimezone(t) {
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+type durationKind int8
+
+const (
+	anyDuration durationKind = iota
+	dayTimeDuration
+	yearMonthDuration
+)
+
+type Duration struct {
+	Months  int
+	DayTime time.Duration
+
+	kind durationKind
+}
+
+func createDayTimeDuration(d time.Duration) Duration {
+	return Duration{
+		DayTime: d,
+		kind:    dayTimeDuration,
+	}
+}
+
+func createYearMonthDuration(months int) Duration {
+	return Duration{
+		Months: months,
+		kind:   yearMonthDuration,
+	}
+}
+
+func parseDuration(str string) (Duration, error) {
+	var (
+		dur   Duration
+		neg   bool
+		clock bool
+		seen  bool
+	)
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "-") {
+		neg = true
+		str = str[1:]
+	}
+	if !strings.HasPrefix(str, "P") {
+		return dur, ErrCast
+	}
+	str = str[1:]
+	for len(str) > 0 {
+		if str[0] == 'T' {
+			if clock {
+				return dur, ErrCast
+			}
+			clock = true
+			str = str[1:]
+			if str == "" {
+				return dur, ErrCast
+			}
+			continue
+		}
+		i := strings.IndexFunc(str, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return dur, ErrCast
+		}
+		num, unit := str[:i], str[i]
+		str = str[i+1:]
+		if unit != 'S' && strings.Contains(num, ".") {
+			return dur, ErrCast
+		}
+		switch {
+		case !clock && unit == 'Y':
+			n, _ := strconv.Atoi(num)
+			dur.Months += n * 12
+		case !clock && unit == 'M':
+			n, _ := strconv.Atoi(num)
+			dur.Months += n
+		case !clock && unit == 'D':
+			n, _ := strconv.ParseInt(num, 10, 64)
+			dur.DayTime += time.Duration(n) * 24 * time.Hour
+		case clock && unit == 'H':
+			n, _ := strconv.ParseInt(num, 10, 64)
+			dur.DayTime += time.Duration(n) * time.Hour
+		case clock && unit == 'M':
+			n, _ := strconv.ParseInt(num, 10, 64)
+			dur.DayTime += time.Duration(n) * time.Minute
+		case clock && unit == 'S':
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return dur, ErrCast
+			}
+			dur.DayTime += time.Duration(n * float64(time.Second))
+		default:
+			return dur, ErrCast
+		}
+		seen = true
+	}
+	if !seen {
+		return dur, ErrCast
+	}
+	if neg {
+		dur.Months = -dur.Months
+		dur.DayTime = -dur.DayTime
+	}
+	return dur, nil
+}
+
+func (d Duration) add(other Duration) Duration {
+	res := Duration{
+		Months:  d.Months + other.Months,
+		DayTime: d.DayTime + other.DayTime,
+	}
+	if d.kind == other.kind {
+		res.kind = d.kind
+	}
+	return res
+}
+
+func (d Duration) negate() Duration {
+	d.Months = -d.Months
+	d.DayTime = -d.DayTime
+	return d
+}
+
+func (d Duration) scale(factor float64) Duration {
+	d.Months = int(math.Round(float64(d.Months) * factor))
+	d.DayTime = time.Duration(math.Round(float64(d.DayTime) * factor))
+	return d
+}
+
+func (d Duration) addTo(t time.Time) time.Time {
+	if d.Months != 0 {
+		y, m, day := t.Date()
+		first := time.Date(y, m+time.Month(d.Months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		last := first.AddDate(0, 1, -1).Day()
+		t = first.AddDate(0, 0, min(day, last)-1)
+	}
+	return t.Add(d.DayTime)
+}
+
+func (d Duration) Years() int {
+	return d.Months / 12
+}
+
+func (d Duration) Days() int {
+	return int(d.DayTime / (24 * time.Hour))
+}
+
+func (d Duration) Hours() int {
+	return int(d.DayTime % (24 * time.Hour) / time.Hour)
+}
+
+func (d Duration) Minutes() int {
+	return int(d.DayTime % time.Hour / time.Minute)
+}
+
+func (d Duration) Seconds() float64 {
+	return float64(d.DayTime%time.Minute) / float64(time.Second)
+}
+
+func (d Duration) Compare(other Duration) int {
+	if d.Months != other.Months {
+		if d.Months < other.Months {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case d.DayTime < other.DayTime:
+		return -1
+	case d.DayTime > other.DayTime:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d Duration) String() string {
+	if d.Months == 0 && d.DayTime == 0 {
+		if d.kind == yearMonthDuration {
+			return "P0M"
+		}
+		return "PT0S"
+	}
+	var str strings.Builder
+	if d.Months < 0 || d.DayTime < 0 {
+		str.WriteByte('-')
+		d.Months = -d.Months
+		d.DayTime = -d.DayTime
+	}
+	str.WriteByte('P')
+	writePart := func(n int, unit byte) {
+		if n != 0 {
+			str.WriteString(strconv.Itoa(n))
+			str.WriteByte(unit)
+		}
+	}
+	writePart(d.Years(), 'Y')
+	writePart(d.Months%12, 'M')
+	writePart(d.Days(), 'D')
+	if d.DayTime%(24*time.Hour) == 0 {
+		return str.String()
+	}
+	str.WriteByte('T')
+	writePart(d.Hours(), 'H')
+	writePart(d.Minutes(), 'M')
+	if secs := d.Seconds(); secs != 0 {
+		str.WriteString(strconv.FormatFloat(secs, 'f', -1, 64))
+		str.WriteByte('S')
+	}
+	return str.String()
+}
@@ -0,0 +1,162 @@
+package xpath
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const decimalPrecision = 18
+
+type Decimal struct {
+	rat *big.Rat
+}
+
+func ParseDecimal(str string) (Decimal, error) {
+	str = strings.TrimSpace(str)
+	digits := strings.TrimLeft(str, "+-")
+	if digits == "" || len(str)-len(digits) > 1 {
+		return Decimal{}, ErrCast
+	}
+	var seen bool
+	for i, c := range digits {
+		switch {
+		case c >= '0' && c <= '9':
+			seen = true
+		case c == '.' && !strings.Contains(digits[i+1:], "."):
+		default:
+			return Decimal{}, ErrCast
+		}
+	}
+	if !seen {
+		return Decimal{}, ErrCast
+	}
+	rat, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return Decimal{}, ErrCast
+	}
+	return Decimal{rat: rat}, nil
+}
+
+func DecimalFromInt(n int64) Decimal {
+	return Decimal{rat: new(big.Rat).SetInt64(n)}
+}
+
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, ErrCast
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func (d Decimal) get() *big.Rat {
+	if d.rat == nil {
+		return new(big.Rat)
+	}
+	return d.rat
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Add(d.get(), other.get())}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Sub(d.get(), other.get())}
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{rat: new(big.Rat).Mul(d.get(), other.get())}
+}
+
+func (d Decimal) Div(other Decimal) (Decimal, error) {
+	if other.Sign() == 0 {
+		return Decimal{}, ErrZero
+	}
+	res := Decimal{rat: new(big.Rat).Quo(d.get(), other.get())}
+	if _, ok := res.precision(); !ok {
+		res, _ = ParseDecimal(res.get().FloatString(decimalPrecision))
+	}
+	return res, nil
+}
+
+func (d Decimal) IDiv(other Decimal) (int64, error) {
+	if other.Sign() == 0 {
+		return 0, ErrZero
+	}
+	res := new(big.Rat).Quo(d.get(), other.get())
+	return new(big.Int).Quo(res.Num(), res.Denom()).Int64(), nil
+}
+
+func (d Decimal) Mod(other Decimal) (Decimal, error) {
+	n, err := d.IDiv(other)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return d.Sub(other.Mul(DecimalFromInt(n))), nil
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{rat: new(big.Rat).Neg(d.get())}
+}
+
+func (d Decimal) Sign() int {
+	return d.get().Sign()
+}
+
+func (d Decimal) Cmp(other Decimal) int {
+	return d.get().Cmp(other.get())
+}
+
+func (d Decimal) IsInteger() bool {
+	return d.get().IsInt()
+}
+
+func (d Decimal) Int64() int64 {
+	rat := d.get()
+	return new(big.Int).Quo(rat.Num(), rat.Denom()).Int64()
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := d.get().Float64()
+	return f
+}
+
+func (d Decimal) String() string {
+	rat := d.get()
+	if rat.IsInt() {
+		return rat.Num().String()
+	}
+	prec, _ := d.precision()
+	str := rat.FloatString(prec)
+	return strings.TrimRight(strings.TrimRight(str, "0"), ".")
+}
+
+func (d Decimal) precision() (int, bool) {
+	var (
+		den  = new(big.Int).Set(d.get().Denom())
+		mod  = new(big.Int)
+		two  = big.NewInt(2)
+		five = big.NewInt(5)
+		n2   int
+		n5   int
+	)
+	for {
+		if _, m := new(big.Int).QuoRem(den, two, mod); m.Sign() != 0 {
+			break
+		}
+		den.Quo(den, two)
+		n2++
+	}
+	for {
+		if _, m := new(big.Int).QuoRem(den, five, mod); m.Sign() != 0 {
+			break
+		}
+		den.Quo(den, five)
+		n5++
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return decimalPrecision, false
+	}
+	return max(n2, n5), true
+}
@@ -176,6 +176,16 @@ func debugExpr(w io.Writer, expr Expr) {
 		io.WriteString(w, "(")
 		io.WriteString(w, strconv.FormatFloat(v.expr, 'f', -1, 64))
 		io.WriteString(w, ")")
+	case integer:
+		io.WriteString(w, "integer")
+		io.WriteString(w, "(")
+		io.WriteString(w, strconv.FormatInt(v.expr, 10))
+		io.WriteString(w, ")")
+	case decimal:
+		io.WriteString(w, "decimal")
+		io.WriteString(w, "(")
+		io.WriteString(w, v.expr.String())
+		io.WriteString(w, ")")
 	case call:
 		io.WriteString(w, "call")
 		io.WriteString(w, "(")
@@ -223,6 +233,8 @@ func debugOp(op rune) string {
 		return "multiply"
 	case opDiv:
 		return "divide"
+	case opIdiv:
+		return "integer-divide"
 	case opMod:
 		return "modulo"
 	case opEq:
@@ -4,8 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"maps"
+	"math"
+	"math/big"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/midbel/codecs/environ"
 	"github.com/midbel/codecs/xml"
@@ -145,6 +149,17 @@ func (e *Evaluator) Find(query string, node xml.Node) (Sequence, error) {
 	return expr.Find(node)
 }
 
+// Eval evaluates an expression created by Create, possibly from another
+// evaluator, with the variables and functions of e.
+func (e *Evaluator) Eval(expr Expr, node xml.Node) (Sequence, error) {
+	if q, ok := expr.(query); ok {
+		q.ctx.Environ = environ.ReadOnly(e.variables)
+		q.ctx.Builtins = environ.ReadOnly(e.builtins)
+		expr = q
+	}
+	return expr.Find(node)
+}
+
 func (e *Evaluator) RegisterFunc(ident string, fn BuiltinFunc) {
 	qn, err := xml.ParseName(ident)
 	if err == nil {
@@ -160,10 +175,97 @@ func (e *Evaluator) RegisterFunc(ident string, fn BuiltinFunc) {
 	e.builtins.Define(ident, fn)
 }
 
+func (e *Evaluator) RegisterFuncNS(name xml.QName, fn BuiltinFunc) {
+	if name.Uri == "" {
+		name.Uri = functionNS
+	}
+	e.builtins.Define(name.ExpandedName(), fn)
+}
+
+func (e *Evaluator) EnableFuncSet(name string) error {
+	sets, ok := extensionFuncs[name]
+	if !ok {
+		return fmt.Errorf("%s: unknown function set", name)
+	}
+	for _, set := range sets {
+		enableFuncSet(e.builtins, set)
+	}
+	return nil
+}
+
+func (e *Evaluator) SetSeed(seed int64) {
+	e.RegisterFunc("random-number-generator", seededRandomNumberGenerator(uint64(seed)))
+}
+
+func (e *Evaluator) SetBaseURI(uri string) {
+	e.baseURI = uri
+	e.RegisterFunc("static-base-uri", staticBaseURI(uri))
+}
+
+func (e *Evaluator) BaseURI() string {
+	return e.baseURI
+}
+
 func (e *Evaluator) ResolveFunc(ident string) (BuiltinFunc, error) {
 	return e.builtins.Resolve(ident)
 }
 
+func (e *Evaluator) Functions() []FuncInfo {
+	var list []FuncInfo
+	for _, ident := range sortedNames(e.builtins) {
+		list = append(list, e.funcInfo(ident))
+	}
+	slices.SortFunc(list, func(f1, f2 FuncInfo) int {
+		return strings.Compare(f1.QualifiedName(), f2.QualifiedName())
+	})
+	return list
+}
+
+func (e *Evaluator) funcInfo(ident string) FuncInfo {
+	if b, ok := knownFuncs[ident]; ok {
+		info := FuncInfo{
+			QName:  b.QName,
+			MinArg: b.MinArg,
+			MaxArg: b.MaxArg,
+		}
+		return info
+	}
+	info := FuncInfo{
+		QName:  xml.LocalName(ident),
+		MaxArg: -1,
+	}
+	uri, name, ok := strings.Cut(strings.TrimPrefix(ident, "{"), "}")
+	if !ok || !strings.HasPrefix(ident, "{") {
+		return info
+	}
+	info.QName = xml.ExpandedName(name, "", uri)
+	ns := e.Namespaces()
+	for _, prefix := range slices.Sorted(maps.Keys(ns)) {
+		if ns[prefix] == uri {
+			info.Space = prefix
+			break
+		}
+	}
+	return info
+}
+
+func (e *Evaluator) Namespaces() map[string]string {
+	list := maps.Clone(defaultNS)
+	maps.Copy(list, angleNS)
+	for _, n := range e.namespaces.Names() {
+		list[n], _ = e.namespaces.Resolve(n)
+	}
+	return list
+}
+
+func (e *Evaluator) Variables() []string {
+	return sortedNames(e.variables)
+}
+
+func sortedNames[T any](env environ.Environ[T]) []string {
+	return slices.Compact(slices.Sorted(slices.Values(env.Names())))
+}
+
 func (e *Evaluator) RegisterNS(prefix, uri string) {
 	e.namespaces.Define(prefix, uri)
 }
@@ -180,6 +282,25 @@ func (e *Evaluator) Define(ident, value string) {
 	e.Set(ident, NewValueFromLiteral(value))
 }
 
+func (e *Evaluator) SetValue(ident string, value any) error {
+	seq, err := NewSequenceFromValue(value)
+	if err != nil {
+		return err
+	}
+	e.Set(ident, NewValueFromSequence(seq))
+	return nil
+}
+
+func (e *Evaluator) WithVars(vars map[string]any) (*Evaluator, error) {
+	x := e.Sub()
+	for ident, value := range vars {
+		if err := x.SetValue(ident, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", ident, err)
+		}
+	}
+	return x, nil
+}
+
 func (e *Evaluator) Resolve(ident string) (Expr, error) {
 	return e.variables.Resolve(ident)
 }
@@ -231,6 +352,21 @@ func (q query) find(ctx Context) (Sequence, error) {
 	return q.expr.find(ctx)
 }
 
+func Bind(expr Expr, ident string, value any) (Expr, error) {
+	q, ok := expr.(query)
+	if !ok {
+		return nil, fmt.Errorf("%w: variables can only be bound to compiled queries", ErrType)
+	}
+	seq, err := NewSequenceFromValue(value)
+	if err != nil {
+		return nil, err
+	}
+	env := environ.Enclosed[Expr](q.ctx.Environ)
+	env.Define(ident, NewValueFromSequence(seq))
+	q.ctx.Environ = env
+	return q, nil
+}
+
 type wildcard struct{}
 
 func (w wildcard) Find(node xml.Node) (Sequence, error) {
@@ -304,6 +440,7 @@ const (
 	nextAxis           = "following"
 	nextSiblingAxis    = "following-sibling"
 	attributeAxis      = "attribute"
+	namespaceAxis      = "namespace"
 
 	childTopAxis = "child-or-top"
 	attrTopAxis  = "attribute-or-top"
@@ -320,7 +457,7 @@ func (a axis) Find(node xml.Node) (Sequence, error) {
 
 func (a axis) principalType() xml.NodeType {
 	switch a.kind {
-	case attributeAxis:
+	case attributeAxis, namespaceAxis:
 		return xml.TypeAttribute
 	default:
 		return xml.TypeElement
@@ -394,6 +531,8 @@ func (a axis) find(ctx Context) (Sequence, error) {
 		return a.followingSiblings(ctx)
 	case attributeAxis:
 		return a.attribute(ctx)
+	case namespaceAxis:
+		return a.namespace(ctx)
 	default:
 		return nil, ErrImplemented
 	}
@@ -522,12 +661,41 @@ func (a axis) attribute(ctx Context) (Sequence, error) {
 		return nil, nil
 	}
 	var (
-		seq Sequence
-		el  = ctx.Node.(*xml.Element)
+		seq   Sequence
+		el    = ctx.Node.(*xml.Element)
+		attrs []*xml.Attribute
 	)
-	ctx.Size = len(el.Attrs)
 	for i := range el.Attrs {
-		ctx.Node = &el.Attrs[i]
+		if isNamespaceAttr(el.Attrs[i]) {
+			continue
+		}
+		attrs = append(attrs, &el.Attrs[i])
+	}
+	ctx.Size = len(attrs)
+	for i := range attrs {
+		ctx.Node = attrs[i]
+		ctx.Index = i + 1
+		matches, err := a.next.find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		seq.Concat(matches)
+	}
+	return seq, nil
+}
+
+func isNamespaceAttr(a xml.Attribute) bool {
+	return a.Space == xml.AttrXmlNS || (a.Space == "" && a.Name == xml.AttrXmlNS)
+}
+
+func (a axis) namespace(ctx Context) (Sequence, error) {
+	var (
+		seq   Sequence
+		nodes = xml.NamespaceNodes(ctx.Node)
+	)
+	ctx.Size = len(nodes)
+	for i := range nodes {
+		ctx.Node = nodes[i]
 		ctx.Index = i + 1
 		matches, err := a.next.find(ctx)
 		if err != nil {
@@ -696,6 +864,17 @@ func (b binary) find(ctx Context) (Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
+	switch b.op {
+	case opAnd:
+		if !left.True() {
+			return Singleton(false), nil
+		}
+	case opOr:
+		if left.True() {
+			return Singleton(true), nil
+		}
+	default:
+	}
 	right, err := b.right.find(ctx)
 	if err != nil {
 		return nil, err
@@ -751,11 +930,25 @@ func (r reverse) find(ctx Context) (Sequence, error) {
 	if v.Empty() {
 		return v, nil
 	}
-	x, err := toFloat(v[0].Value())
-	if err == nil {
-		x = -x
+	switch x := v[0].Value().(type) {
+	case int64:
+		if x == math.MinInt64 {
+			return Singleton(Integer{num: new(big.Int).Neg(big.NewInt(x))}), nil
+		}
+		return Singleton(-x), nil
+	case Integer:
+		return Singleton(createInteger(x.Neg().get())), nil
+	case float32:
+		return Singleton(-x), nil
+	case Decimal:
+		return Singleton(x.Neg()), nil
+	default:
+		f, err := toFloat(x)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(-f), nil
 	}
-	return Singleton(x), err
 }
 
 type literal struct {
@@ -787,6 +980,38 @@ func (n number) find(_ Context) (Sequence, error) {
 }
 
 func (n number) Type() XdmType {
+	return xsDouble
+}
+
+type integer struct {
+	expr int64
+}
+
+func (i integer) Find(node xml.Node) (Sequence, error) {
+	return i.find(defaultContext(node))
+}
+
+func (i integer) find(_ Context) (Sequence, error) {
+	return Singleton(i.expr), nil
+}
+
+func (i integer) Type() XdmType {
+	return xsInteger
+}
+
+type decimal struct {
+	expr Decimal
+}
+
+func (d decimal) Find(node xml.Node) (Sequence, error) {
+	return d.find(defaultContext(node))
+}
+
+func (d decimal) find(_ Context) (Sequence, error) {
+	return Singleton(d.expr), nil
+}
+
+func (d decimal) Type() XdmType {
 	return xsDecimal
 }
 
@@ -1111,7 +1336,57 @@ func (i lookup) Find(node xml.Node) (Sequence, error) {
 }
 
 func (i lookup) find(ctx Context) (Sequence, error) {
-	return nil, nil
+	items, err := i.expr.find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var keys Sequence
+	if i.key != nil {
+		if keys, err = i.key.find(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var list Sequence
+	for _, it := range items {
+		switch x := it.(type) {
+		case mapItem:
+			if i.key == nil {
+				all := slices.Collect(maps.Keys(x.values))
+				slices.SortFunc(all, func(a, b Item) int {
+					return strings.Compare(fmt.Sprint(a.Value()), fmt.Sprint(b.Value()))
+				})
+				for _, k := range all {
+					list.Concat(x.values[k])
+				}
+				continue
+			}
+			for _, k := range keys {
+				if v, ok := x.values[mapKey(k)]; ok {
+					list.Concat(v)
+				}
+			}
+		case arrayItem:
+			if i.key == nil {
+				for _, v := range x.values {
+					list.Concat(v)
+				}
+				continue
+			}
+			for _, k := range keys {
+				ix, err := toInt(k.Value())
+				if err != nil {
+					return nil, err
+				}
+				if ix < 1 || int(ix) > len(x.values) {
+					return nil, fmt.Errorf("%d: array index out of bounds", ix)
+				}
+				list.Concat(x.values[ix-1])
+			}
+		default:
+			return nil, fmt.Errorf("%w: lookup requires a map or an array", ErrType)
+		}
+	}
+	return list, nil
 }
 
 type subscript struct {
@@ -1155,11 +1430,56 @@ func (i subscript) subscriptExpr(ctx Context, expr Expr) (Expr, error) {
 			expr, err = i.subscriptExpr(ctx, expr)
 		}
 	default:
-		err = fmt.Errorf("expression is not subscriptable")
+		expr, err = i.subscriptItem(ctx, e)
 	}
 	return expr, err
 }
 
+func (i subscript) subscriptItem(ctx Context, expr Expr) (Expr, error) {
+	seq, err := expr.find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !seq.Singleton() {
+		return nil, fmt.Errorf("expression is not subscriptable")
+	}
+	if fn, ok := seq.First().(funcItem); ok {
+		arg, err := i.index.find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res, err := fn.call([]Sequence{arg})
+		if err != nil {
+			return nil, err
+		}
+		return NewValueFromSequence(res), nil
+	}
+	index, err := i.at(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch it := seq.First().(type) {
+	case mapItem:
+		v, ok := it.values[mapKey(createLiteral(index))]
+		if !ok {
+			return nil, nil
+		}
+		return NewValueFromSequence(v), nil
+	case arrayItem:
+		x, err := toInt(index)
+		if err != nil {
+			return nil, err
+		}
+		x--
+		if x < 0 || int(x) >= len(it.values) {
+			return nil, nil
+		}
+		return NewValueFromSequence(it.values[x]), nil
+	default:
+		return nil, fmt.Errorf("expression is not subscriptable")
+	}
+}
+
 func (i subscript) subscriptHashmap(ctx Context, arr hashmap) (Expr, error) {
 	index, err := i.at(ctx)
 	if err != nil {
@@ -1172,13 +1492,17 @@ func (i subscript) subscriptHashmap(ctx Context, arr hashmap) (Expr, error) {
 			expr: v,
 		}
 	case int64:
-		sub = number{
-			expr: float64(v),
+		sub = integer{
+			expr: v,
 		}
 	case float64:
 		sub = number{
 			expr: v,
 		}
+	case Decimal:
+		sub = decimal{
+			expr: v,
+		}
 	default:
 		return nil, fmt.Errorf("map key can only be atomic value")
 	}
@@ -1205,6 +1529,8 @@ func (i subscript) validIndex() error {
 	switch i.index.(type) {
 	case literal:
 	case number:
+	case integer:
+	case decimal:
 	case identifier:
 	default:
 		return fmt.Errorf("expression can not be used as index")
@@ -1261,6 +1587,8 @@ func (f filter) find(ctx Context) (Sequence, error) {
 			ok = ctx.Index == int(x)
 		case int64:
 			ok = ctx.Index == int(x)
+		case Decimal:
+			ok = x.IsInteger() && ctx.Index == int(x.Int64())
 		default:
 			ok = EffectiveBooleanValue(res)
 		}
@@ -1283,7 +1611,11 @@ func (e let) Find(node xml.Node) (Sequence, error) {
 func (e let) find(ctx Context) (Sequence, error) {
 	nest := ctx.Nest()
 	for _, b := range e.binds {
-		nest.Define(b.ident, b.expr)
+		seq, err := b.expr.find(nest)
+		if err != nil {
+			return nil, err
+		}
+		nest.Define(b.ident, NewValueFromSequence(seq))
 	}
 	return e.expr.find(nest)
 }
@@ -1318,14 +1650,32 @@ func (r rng) find(ctx Context) (Sequence, error) {
 		return nil, err
 	}
 	var list Sequence
-	if beg < end {
+	if beg <= end {
 		for i := int(beg); i <= int(end); i++ {
-			list.Append(createLiteral(float64(i)))
+			list.Append(createLiteral(int64(i)))
 		}
 	}
 	return list, nil
 }
 
+type inline struct {
+	params []string
+	body   Expr
+}
+
+func (i inline) Find(node xml.Node) (Sequence, error) {
+	return i.find(defaultContext(node))
+}
+
+func (i inline) find(ctx Context) (Sequence, error) {
+	fn := funcItem{
+		params: i.params,
+		body:   i.body,
+		ctx:    ctx,
+	}
+	return Singleton(fn), nil
+}
+
 type binding struct {
 	ident string
 	expr  Expr
@@ -1341,7 +1691,28 @@ func (o loop) Find(node xml.Node) (Sequence, error) {
 }
 
 func (o loop) find(ctx Context) (Sequence, error) {
-	return nil, ErrImplemented
+	return o.iterate(ctx, o.binds)
+}
+
+func (o loop) iterate(ctx Context, binds []binding) (Sequence, error) {
+	if len(binds) == 0 {
+		return o.body.find(ctx)
+	}
+	items, err := binds[0].expr.find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list Sequence
+	for i := range items {
+		nest := ctx.Nest()
+		nest.Define(binds[0].ident, NewValue(items[i]))
+		res, err := o.iterate(nest, binds[1:])
+		if err != nil {
+			return nil, err
+		}
+		list.Concat(res)
+	}
+	return list, nil
 }
 
 type conditional struct {
@@ -1480,6 +1851,30 @@ func (v value) Type() XdmType {
 	return xsUntyped
 }
 
+type deferredValue struct {
+	get  func() (Sequence, error)
+	seq  Sequence
+	err  error
+	once sync.Once
+}
+
+func NewDeferredValue(get func() (Sequence, error)) Expr {
+	return &deferredValue{
+		get: get,
+	}
+}
+
+func (v *deferredValue) Find(node xml.Node) (Sequence, error) {
+	return v.find(defaultContext(node))
+}
+
+func (v *deferredValue) find(_ Context) (Sequence, error) {
+	v.once.Do(func() {
+		v.seq, v.err = v.get()
+	})
+	return slices.Clone(v.seq), v.err
+}
+
 type hashmap struct {
 	values map[Expr]Expr
 }
@@ -1489,7 +1884,7 @@ func (a hashmap) Find(node xml.Node) (Sequence, error) {
 }
 
 func (a hashmap) find(ctx Context) (Sequence, error) {
-	vs := make(map[Item]Item)
+	vs := make(map[Item]Sequence)
 	for k, v := range a.values {
 		i, err := k.find(ctx)
 		if err != nil {
@@ -1502,13 +1897,14 @@ func (a hashmap) find(ctx Context) (Sequence, error) {
 		if err != nil {
 			return nil, err
 		}
-		vs[i.First()] = j.First()
+		vs[mapKey(i.First())] = j
 	}
 	return Singleton(vs), nil
 }
 
 type array struct {
-	all []Expr
+	all   []Expr
+	curly bool
 }
 
 func (a array) Find(node xml.Node) (Sequence, error) {
@@ -1516,17 +1912,18 @@ func (a array) Find(node xml.Node) (Sequence, error) {
 }
 
 func (a array) find(ctx Context) (Sequence, error) {
-	var list []Item
+	var list []Sequence
 	for i := range a.all {
 		others, err := a.all[i].find(ctx)
 		if err != nil {
 			return nil, err
 		}
-		if others.Empty() {
+		if !a.curly {
+			list = append(list, others)
 			continue
 		}
 		for j := range others {
-			list = append(list, others[j])
+			list = append(list, Singleton(others[j]))
 		}
 	}
 	return Singleton(list), nil
@@ -1555,33 +1952,51 @@ func (i instanceof) find(ctx Context) (Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
-	var success int
-	for _, item := range seq {
-		sub, err := exprFromItem(item)
-		if err != nil {
-			return nil, err
-		}
-		var ok bool
-		for _, t := range i.types {
-			ok = t.InstanceOf(sub)
-			if ok {
-				success++
-				break
-			}
-		}
+	return Singleton(matchSequenceType(seq, i.types, i.occurrence)), nil
+}
+
+type treat struct {
+	expr       Expr
+	types      []XdmType
+	occurrence OccurrenceType
+}
+
+func (t treat) Find(node xml.Node) (Sequence, error) {
+	return t.find(defaultContext(node))
+}
+
+func (t treat) find(ctx Context) (Sequence, error) {
+	seq, err := t.expr.find(ctx)
+	if err != nil {
+		return nil, err
 	}
-	var ok bool
-	switch i.occurrence {
+	if !matchSequenceType(seq, t.types, t.occurrence) {
+		return nil, fmt.Errorf("%w: sequence does not match required type", ErrType)
+	}
+	return seq, nil
+}
+
+func matchSequenceType(seq Sequence, types []XdmType, occurrence OccurrenceType) bool {
+	switch n := seq.Len(); occurrence {
 	case ZeroOrOneOccurrence:
-		ok = success <= 1
+		if n > 1 {
+			return false
+		}
 	case ZeroOrMoreOccurrence:
-		ok = true
 	case OneOrMoreOccurrence:
-		ok = success >= 1
+		if n == 0 {
+			return false
+		}
 	default:
-		ok = success == 1
+		if n != 1 {
+			return false
+		}
 	}
-	return Singleton(ok), nil
+	return seq.Every(func(item Item) bool {
+		return slices.ContainsFunc(types, func(t XdmType) bool {
+			return itemInstanceOf(item, t)
+		})
+	})
 }
 
 type cast struct {
@@ -1643,13 +2058,17 @@ func exprFromItem(it Item) (Expr, error) {
 	var e Expr
 	switch v := it.Value().(type) {
 	case int64:
-		e = number{
-			expr: float64(v),
+		e = integer{
+			expr: v,
 		}
 	case float64:
 		e = number{
 			expr: v,
 		}
+	case Decimal:
+		e = decimal{
+			expr: v,
+		}
 	case string:
 		e = literal{
 			expr: v,
@@ -1,8 +1,10 @@
 package xpath
 
 import (
+	"errors"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -174,6 +176,64 @@ func TestFor(t *testing.T) {
 			Query: "for $i in 1 to 5 return $i",
 			Want:  []string{"1", "2", "3", "4", "5"},
 		},
+		{
+			Query: "for $i in 1 to 2, $j in $i to 2 return $i * 10 + $j",
+			Want:  []string{"11", "12", "22"},
+		},
+		{
+			Query: "for $i in /root/item return upper-case($i)",
+			Want:  []string{"FOO", "BAR"},
+		},
+		{
+			Query: "count((for $i in 1 to 3 return $i, 4))",
+			Want:  []string{"4"},
+		},
+		{
+			Query: "for $i in () return $i",
+			Want:  []string{},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
+func TestLookup(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "map{'foo': 1, 'bar': 2}?bar",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "map{'foo': 1, 'bar': 2}?*",
+			Want:  []string{"2", "1"},
+		},
+		{
+			Query: "[1, 2, 3]?2",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "[1, [2, 3]]?2?1",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "let $m := map{'foo': 'bar'} return $m?foo = 'bar'",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "[(1, 2), 3]?1",
+			Want:  []string{"1", "2"},
+		},
+		{
+			Query: "map{'k': (1, 2)}('k')",
+			Want:  []string{"1", "2"},
+		},
+		{
+			Query: "let $items := /root/item return $items/@id",
+			Want:  []string{"fst", "snd"},
+		},
+		{
+			Query: "let $root := /root return $root/item[2]",
+			Want:  []string{"bar"},
+		},
 	}
 	runTests(t, docBase, tests)
 }
@@ -188,6 +248,30 @@ func TestLet(t *testing.T) {
 			Query: "let $x := 1, $y := 1 return $x+$y",
 			Want:  []string{"2"},
 		},
+		{
+			Query: "let $x := 1, $y := $x + 1 return $x+$y",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "let $id := /root/item[2]/@id return /root/item[@id = $id]",
+			Want:  []string{"bar"},
+		},
+		{
+			Query: "let $x := 2 return if ($x > 1) then 'big' else 'small'",
+			Want:  []string{"big"},
+		},
+		{
+			Query: "let $m := map{'k': (1, 2)} return count($m('k'))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "let $s := (1, 2, 3), $t := $s[. > 1] return count($t)",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "let $a := [(1, 2), 3] return count($a?1)",
+			Want:  []string{"2"},
+		},
 	}
 	runTests(t, docBase, tests)
 }
@@ -280,6 +364,191 @@ func TestOperators(t *testing.T) {
 	runTests(t, docBase, tests)
 }
 
+func TestArithmetic(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "0.1 + 0.2 = 0.3",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "0.1e0 + 0.2e0 instance of xs:double",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "10 idiv 3",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "-7 idiv 2",
+			Want:  []string{"-3"},
+		},
+		{
+			Query: "1 div 4",
+			Want:  []string{"0.25"},
+		},
+		{
+			Query: "1 div 3 * 3",
+			Want:  []string{"0.999999999999999999"},
+		},
+		{
+			Query: "7 mod 3",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "1.5 * 2 instance of xs:decimal",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "1.5 * 2 instance of xs:integer",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "count((1, 2, 3)) instance of xs:integer",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "(1, 2, 3)[position() = last()] instance of xs:integer",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "sum((0.1, 0.2))",
+			Want:  []string{"0.3"},
+		},
+		{
+			Query: "avg((1, 2))",
+			Want:  []string{"1.5"},
+		},
+		{
+			Query: "xs:decimal('1.10') + 1",
+			Want:  []string{"2.1"},
+		},
+		{
+			Query: "string(xs:double(1) div 0)",
+			Want:  []string{"INF"},
+		},
+		{
+			Query: "string(xs:double(-1) div 0)",
+			Want:  []string{"-INF"},
+		},
+		{
+			Query: "string(xs:double(0) div 0)",
+			Want:  []string{"NaN"},
+		},
+		{
+			Query: "string(xs:float(1) div 0)",
+			Want:  []string{"INF"},
+		},
+		{
+			Query: "string(xs:double(5) mod 0)",
+			Want:  []string{"NaN"},
+		},
+		{
+			Query: "xs:float(1.5) + 1 instance of xs:float",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "xs:float(1.5) + xs:double(1) instance of xs:double",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "string(9223372036854775807 + 1)",
+			Want:  []string{"9223372036854775808"},
+		},
+		{
+			Query: "9223372036854775807 + 1 instance of xs:integer",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "string(xs:integer('123456789012345678901234567890') * 10)",
+			Want:  []string{"1234567890123456789012345678900"},
+		},
+		{
+			Query: "string(123456789012345678901234567890 - 123456789012345678901234567889)",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "string(-9223372036854775808 - 1)",
+			Want:  []string{"-9223372036854775809"},
+		},
+		{
+			Query: "string(100000000000000000000 idiv 3)",
+			Want:  []string{"33333333333333333333"},
+		},
+		{
+			Query: "100000000000000000000 > 99999999999999999999",
+			Want:  []string{"true"},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	tests := []string{
+		"1 div 0",
+		"xs:decimal(1) div 0",
+		"1 idiv 0",
+		"1 mod 0",
+		"xs:double(1) idiv 0",
+	}
+	eval := NewEvaluator()
+	for _, q := range tests {
+		_, err := eval.Find(q, nil)
+		if !errors.Is(err, ErrZero) {
+			t.Errorf("%s: expected division by zero error, got %v", q, err)
+		}
+	}
+}
+
+func TestGeneralComparison(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "//item/star = //item/star",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item/star = (5, 20)",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item/star = (5, 15)",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "//item/star != //item/star",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item/star > 15",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item/star < 10",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "//item/star <= 10",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item[1]/star >= //item[2]/star",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "//item/label = 'foo'",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "//item/label = //item/star",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "//item/missing = //item/star",
+			Want:  []string{"false"},
+		},
+	}
+	runTests(t, docNumbers, tests)
+}
+
 func TestArrayMap(t *testing.T) {
 	tests := []TestCase{
 		{
@@ -334,6 +603,86 @@ func TestArrayMap(t *testing.T) {
 			Query: "[map{'name': 'foobar', 'age': 42}](1)('name')",
 			Want:  []string{"foobar"},
 		},
+		{
+			Query: "map:size(map{'a': 1, 'b': 2})",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "map:get(map{'a': 1, 'b': 2}, 'b')",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "map:keys(map:put(map{'a': 1}, 'b', 2))[last()]",
+			Want:  []string{"b"},
+		},
+		{
+			Query: "map:get(map:merge((map{'a': 1}, map{'a': 2, 'b': 3})), 'a')",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "map:contains(map:merge((map{'a': 1}, map{'b': 2})), 'b')",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "array:size([1, 2, 3])",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "array:get(['a', 'b', 'c'], 2)",
+			Want:  []string{"b"},
+		},
+		{
+			Query: "array:append([1, 2], 3)",
+			Want:  []string{"1", "2", "3"},
+		},
+		{
+			Query: "array:for-each([1, 2, 3], function($x) { $x * 2 })",
+			Want:  []string{"2", "4", "6"},
+		},
+		{
+			Query: "let $arr := array:append([1], 2) return $arr(2)",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "count(map:get(map{'k': (1, 2)}, 'k'))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "count(map:get(map:put(map{}, 'k', (1, 2, 3)), 'k'))",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "count(map:get(map:entry('k', ()), 'k'))",
+			Want:  []string{"0"},
+		},
+		{
+			Query: "array:size([(1, 2), 3])",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "array:size(array{(1, 2), 3})",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "count(array:get([(1, 2), 3], 1))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "count(array:get(array:put([1], 1, ('a', 'b')), 1))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "array:size(array:append([1], (2, 3)))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "count(array:head(array:insert-before([1], 1, (2, 3))))",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "array:size(array:for-each([1, 2], function($x) { ($x, $x) }))",
+			Want:  []string{"2"},
+		},
 	}
 	runArrayTests(t, docBase, tests)
 }
@@ -352,6 +701,64 @@ func TestSequence(t *testing.T) {
 	runTests(t, docBase, tests)
 }
 
+func TestSequenceAccessors(t *testing.T) {
+	root, err := xml.ParseString(docNumbers)
+	if err != nil {
+		t.Errorf("fail to parse xml document: %s", err)
+		return
+	}
+	find := func(query string) Sequence {
+		q, err := CompileString(query)
+		if err != nil {
+			t.Fatalf("%s: fail to build xpath query: %s", query, err)
+		}
+		res, err := q.Find(root)
+		if err != nil {
+			t.Fatalf("%s: error finding node in document: %s", query, err)
+		}
+		return res
+	}
+
+	res := find("//star")
+	floats, err := res.Floats()
+	if err != nil || !slices.Equal(floats, []float64{10, 20}) {
+		t.Errorf("floats mismatched: got %v (%v)", floats, err)
+	}
+	strs, err := res.Strings()
+	if err != nil || !slices.Equal(strs, []string{"10", "20"}) {
+		t.Errorf("strings mismatched: got %v (%v)", strs, err)
+	}
+	if nodes := res.NodesOfType(xml.TypeElement); len(nodes) != 2 {
+		t.Errorf("nodes mismatched: want 2 elements, got %d", len(nodes))
+	}
+	if nodes := res.NodesOfType(xml.TypeAttribute); len(nodes) != 0 {
+		t.Errorf("nodes mismatched: want 0 attributes, got %d", len(nodes))
+	}
+
+	res = find("(1, 0, 'foo', '')")
+	bools, err := res.Bools()
+	if err != nil || !slices.Equal(bools, []bool{true, false, true, false}) {
+		t.Errorf("bools mismatched: got %v (%v)", bools, err)
+	}
+
+	res = find("([1, [2, 3]], //label)")
+	atoms, err := res.AtomizeAll()
+	if err != nil {
+		t.Errorf("fail to atomize sequence: %s", err)
+	} else if got := getValuesFromSequence(atoms); !slices.Equal(got, []string{"1", "2", "3", "foo", "foo"}) {
+		t.Errorf("atoms mismatched: got %v", got)
+	}
+
+	res = find("//label")
+	if _, err := res.Floats(); err == nil {
+		t.Errorf("expected error when converting labels to float")
+	}
+	res = find("map{'a': 1}")
+	if _, err := res.AtomizeAll(); err == nil {
+		t.Errorf("expected error when atomizing map")
+	}
+}
+
 func TestPathWithNS(t *testing.T) {
 	spaces := []xml.NS{
 		{
@@ -412,15 +819,15 @@ func TestInstanceOf(t *testing.T) {
 		},
 		{
 			Query: "'test' instance of xs:integer?",
-			Want:  []string{"true"},
+			Want:  []string{"false"},
 		},
 		{
 			Query: "'test' instance of xs:integer*",
-			Want:  []string{"true"},
+			Want:  []string{"false"},
 		},
 		{
 			Query: "(1, 'test') instance of xs:integer*",
-			Want:  []string{"true"},
+			Want:  []string{"false"},
 		},
 		{
 			Query: "'test' instance of xs:integer+",
@@ -428,7 +835,7 @@ func TestInstanceOf(t *testing.T) {
 		},
 		{
 			Query: "(1, 'test') instance of xs:integer+",
-			Want:  []string{"true"},
+			Want:  []string{"false"},
 		},
 		{
 			Query: "(1, 2) instance of (xs:integer | xs:string)*",
@@ -438,8 +845,75 @@ func TestInstanceOf(t *testing.T) {
 			Query: "(1, 2) instance of (xs:boolean | xs:string)*",
 			Want:  []string{"false"},
 		},
+		{
+			Query: "() instance of xs:integer?",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "(1, 2.5) instance of xs:decimal+",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "1e0 instance of xs:decimal",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "xs:date('2024-01-02') instance of xs:date",
+			Want:  []string{"true"},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
+func TestCastAndTreat(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "'12' cast as xs:integer + 1",
+			Want:  []string{"13"},
+		},
+		{
+			Query: "'1.5' cast as xs:decimal instance of xs:decimal",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "'1.5' cast as xs:double instance of xs:double",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "1 cast as xs:boolean",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "'2024-01-02' cast as xs:date",
+			Want:  []string{"2024-01-02"},
+		},
+		{
+			Query: "() cast as xs:string?",
+			Want:  []string{},
+		},
+		{
+			Query: "'abc' castable as xs:integer",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "'42' castable as xs:integer",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "'http://example.com/a b' castable as xs:anyURI",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "(1, 2) treat as xs:integer+",
+			Want:  []string{"1", "2"},
+		},
 	}
 	runTests(t, docBase, tests)
+
+	_, err := NewEvaluator().Find("'test' treat as xs:integer", nil)
+	if !errors.Is(err, ErrType) {
+		t.Errorf("treat as: expected type error, got %v", err)
+	}
 }
 
 func TestVariables(t *testing.T) {
@@ -499,9 +973,56 @@ func TestPath(t *testing.T) {
 	t.Run("combine", testPathCombine)
 	t.Run("filter", testPathFilter)
 	t.Run("axis", testPathAxis)
+	t.Run("attribute", testPathAttribute)
 	t.Run("type", testPathType)
 }
 
+func testPathAttribute(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "/root/item[1]/@*",
+			Want:  []string{"fst", "en"},
+		},
+		{
+			Query: "/root/item[1]/@*[1]",
+			Want:  []string{"fst"},
+		},
+		{
+			Query: "/root/item[1]/@*[last()]",
+			Want:  []string{"en"},
+		},
+		{
+			Query: "/root/item[1]/@*[position() = 2]",
+			Want:  []string{"en"},
+		},
+		{
+			Query: "/root/item[1]/attribute::*[last()]",
+			Want:  []string{"en"},
+		},
+		{
+			Query: "/root/item/@*[starts-with(name(), 'l')]",
+			Want:  []string{"en", "en"},
+		},
+		{
+			Query: "/root/item[2]/@*[local-name() = 'id']",
+			Want:  []string{"snd"},
+		},
+		{
+			Query: "/root/item[1]/@*/name()",
+			Want:  []string{"id", "lang"},
+		},
+		{
+			Query: "/root/item[@*[2] = 'en'][last()]",
+			Want:  []string{"bar"},
+		},
+		{
+			Query: "count(//@*[. = 'en'])",
+			Want:  []string{"2"},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
 func testPathAxis(t *testing.T) {
 	tests := []TestCase{
 		{
@@ -548,6 +1069,14 @@ func testPathAxis(t *testing.T) {
 			Query: "/root/group/item/preceding::item",
 			Want:  []string{"bar", "foo"},
 		},
+		{
+			Query: "/root/namespace::*",
+			Want:  []string{"http://www.w3.org/XML/1998/namespace"},
+		},
+		{
+			Query: "name(/root/item[1]/namespace::xml)",
+			Want:  []string{"xml"},
+		},
 	}
 	runTests(t, docBase, tests)
 }
@@ -780,6 +1309,14 @@ func testNodeFunctions(t *testing.T) {
 			Query: "path(/root)",
 			Want:  []string{"/root"},
 		},
+		{
+			Query: "id('snd')",
+			Want:  []string{"bar"},
+		},
+		{
+			Query: "id(('nest fst', 'unknown'))/@lang",
+			Want:  []string{"ung", "en"},
+		},
 		{
 			Query: "has-children(/root)",
 			Want:  []string{"true"},
@@ -788,6 +1325,30 @@ func testNodeFunctions(t *testing.T) {
 			Query: "has-children(/root/group/item/text())",
 			Want:  []string{"false"},
 		},
+		{
+			Query: "has-children(/root/item[1]/@id)",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "has-children(())",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "innermost((/root, /root/group, /root/group/item))/@id",
+			Want:  []string{"nest"},
+		},
+		{
+			Query: "count(innermost(//item | /root))",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "count(outermost(//item | /root/group))",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "outermost(//item)/@id",
+			Want:  []string{"fst", "snd", "nest"},
+		},
 	}
 	runTests(t, docBase, tests)
 }
@@ -814,6 +1375,38 @@ func testNumberFunctions(t *testing.T) {
 			Query: "max(/root/item/star)",
 			Want:  []string{"20"},
 		},
+		{
+			Query: "max((9, 10))",
+			Want:  []string{"10"},
+		},
+		{
+			Query: "min((9, 10))",
+			Want:  []string{"9"},
+		},
+		{
+			Query: "max((9, 10)) instance of xs:integer",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "max((1.5, 10))",
+			Want:  []string{"10"},
+		},
+		{
+			Query: "max((1.5, 10)) instance of xs:decimal",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "min((1.5, 10, 2e0))",
+			Want:  []string{"1.5"},
+		},
+		{
+			Query: "min((3, 10, 2e0)) instance of xs:double",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "max(('a', 'b'))",
+			Want:  []string{"b"},
+		},
 		{
 			Query: "round(2.5)",
 			Want:  []string{"3"},
@@ -842,6 +1435,50 @@ func testNumberFunctions(t *testing.T) {
 			Query: "abs(1)",
 			Want:  []string{"1"},
 		},
+		{
+			Query: "math:pow(2, 10)",
+			Want:  []string{"1024"},
+		},
+		{
+			Query: "math:sqrt(16)",
+			Want:  []string{"4"},
+		},
+		{
+			Query: "round(math:pi() * 100)",
+			Want:  []string{"314"},
+		},
+		{
+			Query: "math:cos(0) + math:sin(0)",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "math:exp(0) + math:log(1)",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "math:exp10(2)",
+			Want:  []string{"100"},
+		},
+		{
+			Query: "math:atan2(0, 1)",
+			Want:  []string{"0"},
+		},
+		{
+			Query: "empty(math:sqrt(()))",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "map:get(random-number-generator(42), 'number') = map:get(random-number-generator(42), 'number')",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "let $n := map:get(random-number-generator(), 'number') return $n >= 0 and $n < 1",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "sum(map:get(random-number-generator('seed'), 'permute')((1, 2, 3, 4)))",
+			Want:  []string{"10"},
+		},
 		{
 			Query: "number(/root/item[1]/star)",
 			Want:  []string{"10"},
@@ -882,6 +1519,34 @@ func testNumberFunctions(t *testing.T) {
 			Query: "format-integer(1479632, '0.000')",
 			Want:  []string{"1.479.632"},
 		},
+		{
+			Query: "format-number(12345.6, '#,###.00')",
+			Want:  []string{"12,345.60"},
+		},
+		{
+			Query: "format-number(123.9, '9999')",
+			Want:  []string{"0124"},
+		},
+		{
+			Query: "format-number(0.14, '01%')",
+			Want:  []string{"14%"},
+		},
+		{
+			Query: "format-number(-6, '000')",
+			Want:  []string{"-006"},
+		},
+		{
+			Query: "format-number(-1, '#;(#)')",
+			Want:  []string{"(1)"},
+		},
+		{
+			Query: "format-number(1234.5678, '#,##0.00 euros')",
+			Want:  []string{"1,234.57 euros"},
+		},
+		{
+			Query: "format-number(0.234, '#.00e0')",
+			Want:  []string{"0.23e0"},
+		},
 	}
 	runTests(t, docNumbers, tests)
 }
@@ -944,6 +1609,10 @@ func testStringFunctions(t *testing.T) {
 			Query: "compare('bar', 'foo')",
 			Want:  []string{"-1"},
 		},
+		{
+			Query: "compare('FOO', 'foo', 'http://www.w3.org/2005/xpath-functions/collation/html-ascii-case-insensitive')",
+			Want:  []string{"0"},
+		},
 		{
 			Query: "concat('foo', 'bar')",
 			Want:  []string{"foobar"},
@@ -1053,6 +1722,62 @@ func testAngleStringFunctions(t *testing.T) {
 			Query: "agl:string-indexof('foo', 'foo')",
 			Want:  []string{"1"},
 		},
+		{
+			Query: "agl:iequals('Foo', 'fOO')",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "agl:iequals('foo', 'bar')",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "agl:icontains('FooBar', 'obA')",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "agl:normalize-all('  Foo \t  BAR  ')",
+			Want:  []string{"foo bar"},
+		},
+		{
+			Query: "agl:coalesce((), /root/unknown, /root/item[2], 'qux')",
+			Want:  []string{"bar"},
+		},
+		{
+			Query: "agl:coalesce((), ())",
+			Want:  []string{},
+		},
+		{
+			Query: "agl:format('value {1} exceeds {2}', 10, 5)",
+			Want:  []string{"value 10 exceeds 5"},
+		},
+		{
+			Query: "agl:format('{2}-{1} {{{1}}}', /root/item[1], 'x')",
+			Want:  []string{"x-foo {foo}"},
+		},
+		{
+			Query: "agl:format('items: {1}', /root/item)",
+			Want:  []string{"items: foo bar"},
+		},
+		{
+			Query: "agl:lookup(agl:index(//item, @lang), 'en')",
+			Want:  []string{"foo", "bar"},
+		},
+		{
+			Query: "agl:lookup(agl:index(//item, @id), ('nest', 'fst', 'nest'))",
+			Want:  []string{"qux", "foo"},
+		},
+		{
+			Query: "agl:lookup(agl:index(//item, function($n) { upper-case($n) }), 'BAR')/@id",
+			Want:  []string{"snd"},
+		},
+		{
+			Query: "let $idx := agl:index(//item, (@id, @lang)) return count(agl:lookup($idx, 'ung'))",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "agl:lookup(agl:index(//item, @id), 'unknown')",
+			Want:  []string{},
+		},
 	}
 	runTests(t, docBase, tests)
 }
@@ -1103,6 +1828,58 @@ func testSequenceFunctions(t *testing.T) {
 			Query: "tail(1 to 5)",
 			Want:  []string{"2", "3", "4", "5"},
 		},
+		{
+			Query: "insert-before(('a', 'b', 'c'), 0, 'z')",
+			Want:  []string{"z", "a", "b", "c"},
+		},
+		{
+			Query: "insert-before(('a', 'b', 'c'), 2, 'z')",
+			Want:  []string{"a", "z", "b", "c"},
+		},
+		{
+			Query: "insert-before(('a', 'b', 'c'), 4, ('y', 'z'))",
+			Want:  []string{"a", "b", "c", "y", "z"},
+		},
+		{
+			Query: "remove(('a', 'b', 'c'), 0)",
+			Want:  []string{"a", "b", "c"},
+		},
+		{
+			Query: "remove(('a', 'b', 'c'), 1)",
+			Want:  []string{"b", "c"},
+		},
+		{
+			Query: "remove(('a', 'b', 'c'), 6)",
+			Want:  []string{"a", "b", "c"},
+		},
+		{
+			Query: "remove((), 3)",
+			Want:  []string{},
+		},
+		{
+			Query: "subsequence(('item1', 'item2', 'item3', 'item4', 'item5'), 4)",
+			Want:  []string{"item4", "item5"},
+		},
+		{
+			Query: "subsequence(('item1', 'item2', 'item3', 'item4', 'item5'), 3, 2)",
+			Want:  []string{"item3", "item4"},
+		},
+		{
+			Query: "subsequence(('item1', 'item2', 'item3', 'item4', 'item5'), 0, 3)",
+			Want:  []string{"item1", "item2"},
+		},
+		{
+			Query: "subsequence(('item1', 'item2', 'item3', 'item4', 'item5'), 1.5, 2)",
+			Want:  []string{"item2", "item3"},
+		},
+		{
+			Query: "subsequence(/root/item, 2)",
+			Want:  []string{"bar"},
+		},
+		{
+			Query: "unordered((1, 2, 3))",
+			Want:  []string{"1", "2", "3"},
+		},
 		{
 			Query: "zero-or-one(())",
 			Want:  []string{},
@@ -1119,8 +1896,298 @@ func testSequenceFunctions(t *testing.T) {
 			Query: "exactly-one(/root/group/item)",
 			Want:  []string{"qux"},
 		},
+		{
+			Query: "distinct-values(//item/@lang)",
+			Want:  []string{"en", "ung"},
+		},
+		{
+			Query: "distinct-values((1, 2.0, '1', 1, 'a', 'A'))",
+			Want:  []string{"1", "2", "1", "a", "A"},
+		},
+		{
+			Query: "distinct-values(('a', 'A', 'b'), 'http://www.w3.org/2005/xpath-functions/collation/html-ascii-case-insensitive')",
+			Want:  []string{"a", "b"},
+		},
+		{
+			Query: "distinct-values(())",
+			Want:  []string{},
+		},
+		{
+			Query: "index-of((10, 20, 30, 20), 20)",
+			Want:  []string{"2", "4"},
+		},
+		{
+			Query: "index-of(//item, 'bar')",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "index-of(('a', 'b'), 'c')",
+			Want:  []string{},
+		},
+		{
+			Query: "index-of(('a', 'B'), 'b', 'http://www.w3.org/2005/xpath-functions/collation/html-ascii-case-insensitive')",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "deep-equal((1, 2, 3), (1, 2, 3))",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "deep-equal((1, 2), (1, '2'))",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "deep-equal(/root/item[1], /root/item[1])",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "deep-equal(/root/item[1], /root/item[2])",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "deep-equal([1, [2, 3]], [1, [2, 3]])",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "deep-equal(map{'a': 1}, map{'a': 1})",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "deep-equal(map{'a': 1}, map{'a': 2})",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "deep-equal('a', 'A', 'http://www.w3.org/2005/xpath-functions/collation/html-ascii-case-insensitive')",
+			Want:  []string{"true"},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
+func testDateFunctions(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "year-from-dateTime(xs:dateTime('1999-05-31T13:20:00-05:00'))",
+			Want:  []string{"1999"},
+		},
+		{
+			Query: "month-from-date(xs:date('1999-05-31'))",
+			Want:  []string{"5"},
+		},
+		{
+			Query: "day-from-date(xs:date('2000-01-01+05:00'))",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "hours-from-dateTime(xs:dateTime('1999-12-31T21:20:00-05:00'))",
+			Want:  []string{"21"},
+		},
+		{
+			Query: "minutes-from-dateTime(xs:dateTime('1999-05-31T13:30:00+05:30'))",
+			Want:  []string{"30"},
+		},
+		{
+			Query: "seconds-from-dateTime(xs:dateTime('1999-05-31T13:20:12.5-05:00'))",
+			Want:  []string{"12.5"},
+		},
+		{
+			Query: "timezone-from-dateTime(xs:dateTime('1999-05-31T13:20:00-05:00'))",
+			Want:  []string{"-PT5H"},
+		},
+		{
+			Query: "timezone-from-dateTime(xs:dateTime('2000-06-12T13:20:00Z'))",
+			Want:  []string{"PT0S"},
+		},
+		{
+			Query: "timezone-from-date(xs:date('2000-06-12'))",
+			Want:  []string{},
+		},
+		{
+			Query: "string(xs:duration('P1Y14M3DT10H30M'))",
+			Want:  []string{"P2Y2M3DT10H30M"},
+		},
+		{
+			Query: "years-from-duration(xs:duration('P20Y15M'))",
+			Want:  []string{"21"},
+		},
+		{
+			Query: "hours-from-duration(xs:duration('P3DT10H'))",
+			Want:  []string{"10"},
+		},
+		{
+			Query: "seconds-from-duration(xs:duration('-P256DT10H5M20.5S'))",
+			Want:  []string{"-20.5"},
+		},
+		{
+			Query: "xs:duration('P1D') < xs:duration('PT25H')",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "string(xs:dayTimeDuration('P1DT2H'))",
+			Want:  []string{"P1DT2H"},
+		},
+		{
+			Query: "string(xs:yearMonthDuration('P1Y14M'))",
+			Want:  []string{"P2Y2M"},
+		},
+		{
+			Query: "string(xs:dayTimeDuration(xs:duration('P1Y2DT1H')))",
+			Want:  []string{"P2DT1H"},
+		},
+		{
+			Query: "string(xs:yearMonthDuration(xs:duration('P1Y2DT1H')))",
+			Want:  []string{"P1Y"},
+		},
+		{
+			Query: "xs:yearMonthDuration('P1Y') instance of xs:duration",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "xs:duration('P1Y') instance of xs:yearMonthDuration",
+			Want:  []string{"false"},
+		},
+		{
+			Query: "days-from-duration(xs:dayTimeDuration('P3DT10H'))",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "hours-from-duration(xs:dayTimeDuration('P3DT10H'))",
+			Want:  []string{"10"},
+		},
+		{
+			Query: "seconds-from-duration(xs:dayTimeDuration('PT1M30.5S'))",
+			Want:  []string{"30.5"},
+		},
+		{
+			Query: "years-from-duration(xs:dayTimeDuration('P3DT10H'))",
+			Want:  []string{"0"},
+		},
+		{
+			Query: "years-from-duration(xs:yearMonthDuration('P20Y15M'))",
+			Want:  []string{"21"},
+		},
+		{
+			Query: "months-from-duration(xs:yearMonthDuration('P20Y15M'))",
+			Want:  []string{"3"},
+		},
+		{
+			Query: "days-from-duration(xs:yearMonthDuration('P1Y'))",
+			Want:  []string{"0"},
+		},
+		{
+			Query: "string(xs:dateTime('2024-01-02T12:00:00Z') - xs:dateTime('2024-01-02T00:00:00Z'))",
+			Want:  []string{"PT12H"},
+		},
+		{
+			Query: "string(xs:date('2024-03-01') - xs:date('2024-02-28'))",
+			Want:  []string{"P2D"},
+		},
+		{
+			Query: "(xs:date('2024-03-01') - xs:date('2024-02-28')) instance of xs:dayTimeDuration",
+			Want:  []string{"true"},
+		},
+		{
+			Query: "string(xs:dateTime('2024-01-01T00:00:00Z') + xs:dayTimeDuration('PT36H'))",
+			Want:  []string{"2024-01-02T12:00:00Z"},
+		},
+		{
+			Query: "format-date(xs:date('2024-01-31') + xs:yearMonthDuration('P1M'), '[Y0001]-[M01]-[D01]')",
+			Want:  []string{"2024-02-29"},
+		},
+		{
+			Query: "string(xs:dayTimeDuration('PT2H') * 3)",
+			Want:  []string{"PT6H"},
+		},
+		{
+			Query: "xs:dayTimeDuration('PT12H') div xs:dayTimeDuration('PT6H')",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "string(dateTime(xs:date('1999-12-31'), '12:00:00'))",
+			Want:  []string{"1999-12-31T12:00:00"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[Y0001]-[M01]-[D01]')",
+			Want:  []string{"2002-12-31"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[M]-[D]-[Y]')",
+			Want:  []string{"12-31-2002"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[D1o] [MNn], [Y]')",
+			Want:  []string{"31st December, 2002"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[FNn,*-3], [MNn,*-3] [D] [Y01]')",
+			Want:  []string{"Tue, Dec 31 02"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[Y] [[week [W]]]')",
+			Want:  []string{"2002 [week 1]"},
+		},
+		{
+			Query: "format-date(xs:date('2002-12-31'), '[YI]')",
+			Want:  []string{"MMII"},
+		},
+		{
+			Query: "format-dateTime(xs:dateTime('2002-12-31T15:58:45.762+02:00'), '[h]:[m01] [PN] [Z]')",
+			Want:  []string{"3:58 PM +02:00"},
+		},
+		{
+			Query: "format-dateTime(xs:dateTime('2002-12-31T15:58:45.762+02:00'), '[H01]:[m01]:[s01].[f001] [z]')",
+			Want:  []string{"15:58:45.762 GMT+02:00"},
+		},
+		{
+			Query: "format-time(xs:dateTime('2002-12-31T09:05:00Z'), '[H01]:[m][Z0000]')",
+			Want:  []string{"09:05+0000"},
+		},
+	}
+	runTests(t, docBase, tests)
+}
+
+func testJsonFunctions(t *testing.T) {
+	tests := []TestCase{
+		{
+			Query: "local-name(json-to-xml('{\"a\": 1}')/*)",
+			Want:  []string{"map"},
+		},
+		{
+			Query: "json-to-xml('{\"a\": 1}')/*/*/@key",
+			Want:  []string{"a"},
+		},
+		{
+			Query: "json-to-xml('[1, \"foo\", null]')/*/*[2]",
+			Want:  []string{"foo"},
+		},
+		{
+			Query: "xml-to-json(json-to-xml('{\"b\": [1, true, null], \"a\": \"x\"}'))",
+			Want:  []string{`{"b":[1,true,null],"a":"x"}`},
+		},
+		{
+			Query: "count(json-to-xml('{\"a\": 1, \"a\": 2}')/*/*)",
+			Want:  []string{"2"},
+		},
+		{
+			Query: "json-to-xml('{\"a\": 1, \"b\": 0, \"a\": 2}', map{'duplicates': 'use-first'})/*/*[@key='a']",
+			Want:  []string{"1"},
+		},
+		{
+			Query: "xml-to-json(json-to-xml('[1.5, false]'), map{'indent': false()})",
+			Want:  []string{`[1.5,false]`},
+		},
 	}
 	runTests(t, docBase, tests)
+
+	invalid := []string{
+		"json-to-xml('{\"a\": 1, \"a\": 2}', map{'duplicates': 'reject'})",
+		"json-to-xml('{}', map{'duplicates': 'merge'})",
+	}
+	for _, q := range invalid {
+		if _, err := NewEvaluator().Find(q, nil); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
 }
 
 func TestFunctions(t *testing.T) {
@@ -1129,8 +2196,213 @@ func TestFunctions(t *testing.T) {
 	t.Run("number", testNumberFunctions)
 	t.Run("string", testStringFunctions)
 	t.Run("sequence", testSequenceFunctions)
+	t.Run("date", testDateFunctions)
 	t.Run("angle-string", testAngleStringFunctions)
 	t.Run("arrows", testArrows)
+	t.Run("json", testJsonFunctions)
+}
+
+func TestEvaluatorSeed(t *testing.T) {
+	const query = "map:get(random-number-generator(), 'permute')((1 to 10))"
+
+	var res []string
+	for range 2 {
+		eval := NewEvaluator()
+		eval.SetSeed(42)
+		seq, err := eval.Find(query, nil)
+		if err != nil {
+			t.Fatalf("error evaluating query: %s", err)
+		}
+		got := strings.Join(getValuesFromSequence(seq), ",")
+		if len(res) > 0 && res[0] != got {
+			t.Errorf("seeded evaluators mismatched! want %s, got %s", res[0], got)
+		}
+		res = append(res, got)
+	}
+}
+
+func TestEvaluatorBaseURI(t *testing.T) {
+	const doc = `<root xml:base="http://example.com/data/"><item xml:base="sub/">foo</item><item>bar</item></root>`
+
+	root, err := xml.ParseString(doc)
+	if err != nil {
+		t.Fatalf("fail to parse xml document: %s", err)
+	}
+	root.URI = "http://example.com/index.xml"
+
+	eval := NewEvaluator()
+	eval.SetBaseURI("http://example.com/sheets/main.xsl")
+
+	tests := []struct {
+		Query string
+		Want  []string
+	}{
+		{
+			Query: "static-base-uri()",
+			Want:  []string{"http://example.com/sheets/main.xsl"},
+		},
+		{
+			Query: "base-uri(/root)",
+			Want:  []string{"http://example.com/data/"},
+		},
+		{
+			Query: "base-uri(/root/item[1]/text())",
+			Want:  []string{"http://example.com/data/sub/"},
+		},
+		{
+			Query: "base-uri(/root/item[2])",
+			Want:  []string{"http://example.com/data/"},
+		},
+		{
+			Query: "document-uri(root())",
+			Want:  []string{"http://example.com/index.xml"},
+		},
+		{
+			Query: "resolve-uri('other.xml')",
+			Want:  []string{"http://example.com/sheets/other.xml"},
+		},
+		{
+			Query: "resolve-uri('../other.xml', base-uri(/root/item[1]))",
+			Want:  []string{"http://example.com/data/other.xml"},
+		},
+		{
+			Query: "resolve-uri('file:///tmp/other.xml')",
+			Want:  []string{"file:///tmp/other.xml"},
+		},
+		{
+			Query: "resolve-uri(())",
+			Want:  []string{},
+		},
+	}
+	for _, c := range tests {
+		seq, err := eval.Find(c.Query, root)
+		if err != nil {
+			t.Errorf("%s: error evaluating query: %s", c.Query, err)
+			continue
+		}
+		if got := getValuesFromSequence(seq); !slices.Equal(got, c.Want) {
+			t.Errorf("%s: result mismatched! want %q, got %q", c.Query, c.Want, got)
+		}
+	}
+	if seq, _ := NewEvaluator().Find("static-base-uri()", root); !seq.Empty() {
+		t.Errorf("static-base-uri should be empty when not set")
+	}
+}
+
+func TestEvaluatorIntrospection(t *testing.T) {
+	eval := NewEvaluator()
+	eval.RegisterNS("ex", "http://example.com/ns")
+	eval.Define("lang", "en")
+	eval.Define("id", "fst")
+	eval.RegisterFuncNS(xml.ExpandedName("custom", "", "http://example.com/ns"), callTrue)
+
+	sub := eval.Sub()
+	sub.Define("lang", "fr")
+
+	if got, want := sub.Variables(), []string{"id", "lang"}; !slices.Equal(got, want) {
+		t.Errorf("variables mismatched! want %q, got %q", want, got)
+	}
+	ns := sub.Namespaces()
+	if uri := ns["ex"]; uri != "http://example.com/ns" {
+		t.Errorf("namespace ex mismatched! got %q", uri)
+	}
+	if uri := ns["fn"]; uri != functionNS {
+		t.Errorf("namespace fn mismatched! got %q", uri)
+	}
+
+	funcs := make(map[string]FuncInfo)
+	for _, f := range sub.Functions() {
+		funcs[f.QualifiedName()] = f
+	}
+	tests := []struct {
+		Name     string
+		Min      int
+		Max      int
+		Variadic bool
+	}{
+		{Name: "fn:position", Min: 0, Max: 0},
+		{Name: "fn:substring", Min: 2, Max: 3},
+		{Name: "fn:concat", Min: 2, Variadic: true},
+		{Name: "map:get", Min: 2, Max: 2},
+		{Name: "ex:custom", Variadic: true},
+	}
+	for _, c := range tests {
+		f, ok := funcs[c.Name]
+		if !ok {
+			t.Errorf("%s: function not listed", c.Name)
+			continue
+		}
+		if f.MinArg != c.Min || f.Variadic() != c.Variadic || (!c.Variadic && f.MaxArg != c.Max) {
+			t.Errorf("%s: arity mismatched! want %d-%d, got %d-%d", c.Name, c.Min, c.Max, f.MinArg, f.MaxArg)
+		}
+	}
+}
+
+func TestBindVariables(t *testing.T) {
+	root, err := xml.ParseString(docBase)
+	if err != nil {
+		t.Fatalf("fail to parse xml document: %s", err)
+	}
+	eval, err := NewEvaluator().WithVars(map[string]any{
+		"id":    "fst",
+		"langs": []any{"en", "fr"},
+	})
+	if err != nil {
+		t.Fatalf("error binding variables: %s", err)
+	}
+	seq, err := eval.Find("/root/item[@id=$id and @lang=$langs]", root)
+	if err != nil {
+		t.Fatalf("error finding node in document: %s", err)
+	}
+	if got := getValuesFromSequence(seq); !slices.Equal(got, []string{"foo"}) {
+		t.Errorf("nodes mismatched! want foo, got %s", got)
+	}
+
+	expr, err := NewEvaluator().Create("/root/item[@id=$id]")
+	if err != nil {
+		t.Fatalf("fail to build xpath query: %s", err)
+	}
+	for id, want := range map[string]string{"fst": "foo", "snd": "bar"} {
+		q, err := Bind(expr, "id", id)
+		if err != nil {
+			t.Fatalf("error binding variable: %s", err)
+		}
+		seq, err := q.Find(root)
+		if err != nil {
+			t.Fatalf("error finding node in document: %s", err)
+		}
+		if got := getValuesFromSequence(seq); !slices.Equal(got, []string{want}) {
+			t.Errorf("nodes mismatched! want %s, got %s", want, got)
+		}
+	}
+	if seq, _ := expr.Find(root); !seq.Empty() {
+		t.Errorf("binding variable should not modify original query")
+	}
+}
+
+func TestFreeVariables(t *testing.T) {
+	tests := []struct {
+		Query string
+		Want  []string
+	}{
+		{Query: "$a + $b", Want: []string{"a", "b"}},
+		{Query: "for $x in 1 to 3 return $x", Want: nil},
+		{Query: "for $x in $x return $x * $y", Want: []string{"x", "y"}},
+		{Query: "let $a := $b, $b := $a return $b", Want: []string{"b"}},
+		{Query: "some $i in $list satisfies $i = $v", Want: []string{"list", "v"}},
+		{Query: "function($p) { $p + $q }", Want: []string{"q"}},
+		{Query: "/root/item[@id = $id][position() = $pos]", Want: []string{"id", "pos"}},
+	}
+	for _, c := range tests {
+		expr, err := NewEvaluator().Create(c.Query)
+		if err != nil {
+			t.Errorf("%s: fail to build xpath query: %s", c.Query, err)
+			continue
+		}
+		if got := FreeVariables(expr); !slices.Equal(got, c.Want) {
+			t.Errorf("%s: variables mismatched! want %q, got %q", c.Query, c.Want, got)
+		}
+	}
 }
 
 func testArrows(t *testing.T) {
@@ -1300,8 +2572,14 @@ func getValuesFromSequence(seq Sequence) []string {
 		default:
 		case time.Time:
 			str = v.Format("2006-01-02")
+		case Duration:
+			str = v.String()
 		case float64:
 			str = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			str = strconv.FormatInt(v, 10)
+		case Decimal:
+			str = v.String()
 		case bool:
 			str = strconv.FormatBool(v)
 		case string:
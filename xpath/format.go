@@ -7,6 +7,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/midbel/codecs/internal/datefmt"
+	"github.com/midbel/codecs/internal/numfmt"
 )
 
 func formatInteger(value int64, picture string) (string, error) {
@@ -82,19 +85,17 @@ func formatInteger(value int64, picture string) (string, error) {
 }
 
 func formatNumber(value float64, picture string) (string, error) {
-	positive, negative, ok := strings.Cut(picture, ";")
-	if ok && value < 0 {
-		picture = negative
-	} else {
-		picture = positive
-	}
-	return "", nil
+	return numfmt.Default().Format(value, picture)
 }
 
 func formatDate(value time.Time, picture string) (string, error) {
-	return "", nil
+	return datefmt.Format(value, picture, datefmt.DateComponents)
 }
 
 func formatDateTime(value time.Time, picture string) (string, error) {
-	return "", nil
+	return datefmt.Format(value, picture, datefmt.DateTimeComponents)
+}
+
+func formatTime(value time.Time, picture string) (string, error) {
+	return datefmt.Format(value, picture, datefmt.TimeComponents)
 }
@@ -11,9 +11,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"hash/fnv"
 	"io"
 	"maps"
 	"math"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"regexp"
@@ -28,6 +30,17 @@ import (
 
 func init() {
 	builtinEnv = defaultFuncset()
+	knownFuncs = make(map[string]registeredBuiltin)
+	for _, b := range builtins {
+		knownFuncs[b.ExpandedName()] = b
+	}
+	for _, sets := range extensionFuncs {
+		for _, set := range sets {
+			for _, b := range set {
+				knownFuncs[b.ExpandedName()] = b
+			}
+		}
+	}
 }
 
 type BuiltinFunc func(Context, []Expr) (Sequence, error)
@@ -48,6 +61,18 @@ func DefaultBuiltin() environ.Environ[BuiltinFunc] {
 	return builtinEnv
 }
 
+var knownFuncs map[string]registeredBuiltin
+
+type FuncInfo struct {
+	xml.QName
+	MinArg int
+	MaxArg int
+}
+
+func (f FuncInfo) Variadic() bool {
+	return f.MaxArg < 0
+}
+
 type registeredBuiltin struct {
 	xml.QName
 	MinArg int
@@ -64,11 +89,18 @@ func registerFunc(name, space string, fn BuiltinFunc) registeredBuiltin {
 	}
 
 	return registeredBuiltin{
-		QName: qn,
-		Func:  fn,
+		QName:  qn,
+		MaxArg: -1,
+		Func:   fn,
 	}
 }
 
+func (r registeredBuiltin) arity(min, max int) registeredBuiltin {
+	r.MinArg = min
+	r.MaxArg = max
+	return r
+}
+
 type funcset struct {
 	environ.Environ[BuiltinFunc]
 }
@@ -119,134 +151,202 @@ func (f *funcset) EnableAngle() {
 }
 
 func (f *funcset) enableFuncSet(set []registeredBuiltin) {
+	enableFuncSet(f, set)
+}
+
+var extensionFuncs = map[string][][]registeredBuiltin{
+	"agl":     {angleFuncs, angleStringFuncs},
+	"file":    {fileFuncs},
+	"http":    {httpFuncs},
+	"binary":  {binaryFuncs},
+	"crypto":  {cryptoFuncs},
+	"process": {processFuncs},
+}
+
+func FuncSets() []string {
+	return slices.Sorted(maps.Keys(extensionFuncs))
+}
+
+func FuncSetFromNS(uri string) (string, bool) {
+	for name, ns := range angleNS {
+		if ns != uri {
+			continue
+		}
+		_, ok := extensionFuncs[name]
+		return name, ok
+	}
+	return "", false
+}
+
+func enableFuncSet(env environ.Environ[BuiltinFunc], set []registeredBuiltin) {
 	for _, b := range set {
-		f.Define(b.ExpandedName(), b.Func)
+		env.Define(b.ExpandedName(), b.Func)
 	}
 }
 
 var builtins = []registeredBuiltin{
-	registerFunc("namespace-uri", "fn", callNamespaceUri),
-	registerFunc("uri-collection", "fn", callUriCollection),
+	registerFunc("namespace-uri", "fn", callNamespaceUri).arity(0, 1),
+	registerFunc("uri-collection", "fn", callUriCollection).arity(0, 1),
 	// node and document functiosn
-	registerFunc("name", "fn", callName),
-	registerFunc("local-name", "fn", callLocalName),
-	registerFunc("root", "fn", callRoot),
-	registerFunc("path", "fn", callPath),
-	registerFunc("has-children", "fn", callHasChildren),
-	registerFunc("innermost", "fn", callInnermost),
-	registerFunc("outermost", "fn", callOutermost),
-	registerFunc("doc", "fn", callDoc),
-	registerFunc("collection", "fn", callCollection),
-	registerFunc("position", "fn", callPosition),
-	registerFunc("last", "fn", callLast),
+	registerFunc("name", "fn", callName).arity(0, 1),
+	registerFunc("local-name", "fn", callLocalName).arity(0, 1),
+	registerFunc("root", "fn", callRoot).arity(0, 1),
+	registerFunc("id", "fn", callId).arity(1, 2),
+	registerFunc("path", "fn", callPath).arity(0, 1),
+	registerFunc("has-children", "fn", callHasChildren).arity(0, 1),
+	registerFunc("innermost", "fn", callInnermost).arity(1, 1),
+	registerFunc("outermost", "fn", callOutermost).arity(1, 1),
+	registerFunc("doc", "fn", callDoc).arity(1, 1),
+	registerFunc("json-to-xml", "fn", callJsonToXml).arity(1, 2),
+	registerFunc("xml-to-json", "fn", callXmlToJson).arity(1, 2),
+	registerFunc("base-uri", "fn", callBaseURI).arity(0, 1),
+	registerFunc("document-uri", "fn", callDocumentURI).arity(0, 1),
+	registerFunc("resolve-uri", "fn", callResolveURI).arity(1, 2),
+	registerFunc("static-base-uri", "fn", staticBaseURI("")).arity(0, 0),
+	registerFunc("collection", "fn", callCollection).arity(0, 1),
+	registerFunc("position", "fn", callPosition).arity(0, 0),
+	registerFunc("last", "fn", callLast).arity(0, 0),
 	// string functions
-	registerFunc("string", "fn", callString),
-	registerFunc("compare", "fn", callCompare),
-	registerFunc("concat", "fn", callConcat),
-	registerFunc("string-join", "fn", callStringJoin),
-	registerFunc("substring", "fn", callSubstring),
-	registerFunc("string-length", "fn", callStringLength),
-	registerFunc("normalize-space", "fn", callNormalizeSpace),
-	registerFunc("upper-case", "fn", callUppercase),
-	registerFunc("lower-case", "fn", callLowercase),
-	registerFunc("translate", "fn", callTranslate),
-	registerFunc("contains", "fn", callContains),
-	registerFunc("starts-with", "fn", callStartsWith),
-	registerFunc("ends-with", "fn", callEndsWith),
-	registerFunc("substring-before", "fn", callSubstringBefore),
-	registerFunc("substring-after", "fn", callSubstringAfter),
-	registerFunc("replace", "fn", callReplace),
-	registerFunc("matches", "fn", callMatches),
-	registerFunc("tokenize", "fn", callTokenize),
+	registerFunc("string", "fn", callString).arity(0, 1),
+	registerFunc("compare", "fn", callCompare).arity(2, 3),
+	registerFunc("concat", "fn", callConcat).arity(2, -1),
+	registerFunc("string-join", "fn", callStringJoin).arity(1, 2),
+	registerFunc("substring", "fn", callSubstring).arity(2, 3),
+	registerFunc("string-length", "fn", callStringLength).arity(0, 1),
+	registerFunc("normalize-space", "fn", callNormalizeSpace).arity(0, 1),
+	registerFunc("upper-case", "fn", callUppercase).arity(1, 1),
+	registerFunc("lower-case", "fn", callLowercase).arity(1, 1),
+	registerFunc("translate", "fn", callTranslate).arity(3, 3),
+	registerFunc("contains", "fn", callContains).arity(2, 3),
+	registerFunc("starts-with", "fn", callStartsWith).arity(2, 3),
+	registerFunc("ends-with", "fn", callEndsWith).arity(2, 3),
+	registerFunc("substring-before", "fn", callSubstringBefore).arity(2, 3),
+	registerFunc("substring-after", "fn", callSubstringAfter).arity(2, 3),
+	registerFunc("replace", "fn", callReplace).arity(3, 4),
+	registerFunc("matches", "fn", callMatches).arity(2, 3),
+	registerFunc("tokenize", "fn", callTokenize).arity(1, 3),
 	// sequence function
-	registerFunc("empty", "fn", callEmpty),
-	registerFunc("tail", "fn", callTail),
-	registerFunc("head", "fn", callHead),
-	registerFunc("exists", "fn", callExists),
-	registerFunc("insert-before", "fn", callXYZ),
-	registerFunc("remove", "fn", callXYZ),
-	registerFunc("reverse", "fn", callReverse),
-	registerFunc("subsequence", "fn", callXYZ),
-	registerFunc("unordered", "fn", callXYZ),
-	registerFunc("zero-or-one", "fn", callZeroOrOne),
-	registerFunc("one-or-more", "fn", callOneOrMore),
-	registerFunc("exactly-one", "fn", callExactlyOne),
-	registerFunc("distinct-values", "fn", callDistinctValues),
+	registerFunc("empty", "fn", callEmpty).arity(1, 1),
+	registerFunc("tail", "fn", callTail).arity(1, 1),
+	registerFunc("head", "fn", callHead).arity(1, 1),
+	registerFunc("exists", "fn", callExists).arity(1, 1),
+	registerFunc("insert-before", "fn", callInsertBefore).arity(3, 3),
+	registerFunc("remove", "fn", callRemove).arity(2, 2),
+	registerFunc("reverse", "fn", callReverse).arity(1, 1),
+	registerFunc("subsequence", "fn", callSubsequence).arity(2, 3),
+	registerFunc("unordered", "fn", callUnordered).arity(1, 1),
+	registerFunc("zero-or-one", "fn", callZeroOrOne).arity(1, 1),
+	registerFunc("one-or-more", "fn", callOneOrMore).arity(1, 1),
+	registerFunc("exactly-one", "fn", callExactlyOne).arity(1, 1),
+	registerFunc("distinct-values", "fn", callDistinctValues).arity(1, 2),
+	registerFunc("index-of", "fn", callIndexOf).arity(2, 3),
+	registerFunc("deep-equal", "fn", callDeepEqual).arity(2, 3),
 	// boolean functions
-	registerFunc("true", "fn", callTrue),
-	registerFunc("false", "fn", callFalse),
-	registerFunc("boolean", "fn", callBoolean),
-	registerFunc("not", "fn", callNot),
+	registerFunc("true", "fn", callTrue).arity(0, 0),
+	registerFunc("false", "fn", callFalse).arity(0, 0),
+	registerFunc("boolean", "fn", callBoolean).arity(1, 1),
+	registerFunc("not", "fn", callNot).arity(1, 1),
 	// number + aggregate functions
-	registerFunc("number", "fn", callNumber),
-	registerFunc("round", "fn", callRound),
-	registerFunc("floor", "fn", callFloor),
-	registerFunc("ceiling", "fn", callCeil),
-	registerFunc("abs", "fn", callAbs),
-	registerFunc("sum", "fn", callSum),
-	registerFunc("count", "fn", callCount),
-	registerFunc("avg", "fn", callAvg),
-	registerFunc("min", "fn", callMin),
-	registerFunc("max", "fn", callMax),
-	registerFunc("format-number", "fn", callFormatNumber),
-	registerFunc("format-integer", "fn", callFormatInteger),
+	registerFunc("number", "fn", callNumber).arity(0, 1),
+	registerFunc("round", "fn", callRound).arity(1, 2),
+	registerFunc("floor", "fn", callFloor).arity(1, 1),
+	registerFunc("ceiling", "fn", callCeil).arity(1, 1),
+	registerFunc("abs", "fn", callAbs).arity(1, 1),
+	registerFunc("sum", "fn", callSum).arity(1, 2),
+	registerFunc("count", "fn", callCount).arity(1, 1),
+	registerFunc("avg", "fn", callAvg).arity(1, 1),
+	registerFunc("min", "fn", callMin).arity(1, 2),
+	registerFunc("max", "fn", callMax).arity(1, 2),
+	registerFunc("format-number", "fn", callFormatNumber).arity(2, 3),
+	registerFunc("format-integer", "fn", callFormatInteger).arity(2, 3),
 	// date functions
-	registerFunc("dateTime", "fn", callDateTime),
-	registerFunc("year-from-dateTime", "fn", callYearFromDateTime),
-	registerFunc("year-from-date", "fn", callYearFromDate),
-	registerFunc("month-from-dateTime", "fn", callMonthFromDateTime),
-	registerFunc("month-from-date", "fn", callMonthFromDate),
-	registerFunc("day-from-dateTime", "fn", callDayFromDateTime),
-	registerFunc("day-from-date", "fn", callDayFromDate),
-	registerFunc("hours-from-dateTime", "fn", callHoursFromDateTime),
-	registerFunc("minutes-from-dateTime", "fn", callMinutesFromDateTime),
-	registerFunc("seconds-from-dateTime", "fn", callSecondsFromDateTime),
-	registerFunc("format-date", "fn", callFormatDate),
-	registerFunc("format-dateTime", "fn", callFormatDateTime),
-	registerFunc("current-date", "fn", callCurrentDate),
-	registerFunc("current-dateTime", "fn", callCurrentDatetime),
+	registerFunc("dateTime", "fn", callDateTime).arity(2, 2),
+	registerFunc("year-from-dateTime", "fn", callYearFromDateTime).arity(1, 1),
+	registerFunc("year-from-date", "fn", callYearFromDate).arity(1, 1),
+	registerFunc("month-from-dateTime", "fn", callMonthFromDateTime).arity(1, 1),
+	registerFunc("month-from-date", "fn", callMonthFromDate).arity(1, 1),
+	registerFunc("day-from-dateTime", "fn", callDayFromDateTime).arity(1, 1),
+	registerFunc("day-from-date", "fn", callDayFromDate).arity(1, 1),
+	registerFunc("hours-from-dateTime", "fn", callHoursFromDateTime).arity(1, 1),
+	registerFunc("minutes-from-dateTime", "fn", callMinutesFromDateTime).arity(1, 1),
+	registerFunc("seconds-from-dateTime", "fn", callSecondsFromDateTime).arity(1, 1),
+	registerFunc("timezone-from-dateTime", "fn", callTimezoneFromDateTime).arity(1, 1),
+	registerFunc("timezone-from-date", "fn", callTimezoneFromDate).arity(1, 1),
+	registerFunc("years-from-duration", "fn", callYearsFromDuration).arity(1, 1),
+	registerFunc("months-from-duration", "fn", callMonthsFromDuration).arity(1, 1),
+	registerFunc("days-from-duration", "fn", callDaysFromDuration).arity(1, 1),
+	registerFunc("hours-from-duration", "fn", callHoursFromDuration).arity(1, 1),
+	registerFunc("minutes-from-duration", "fn", callMinutesFromDuration).arity(1, 1),
+	registerFunc("seconds-from-duration", "fn", callSecondsFromDuration).arity(1, 1),
+	registerFunc("format-date", "fn", callFormatDate).arity(2, 5),
+	registerFunc("format-dateTime", "fn", callFormatDateTime).arity(2, 5),
+	registerFunc("format-time", "fn", callFormatTime).arity(2, 5),
+	registerFunc("current-date", "fn", callCurrentDate).arity(0, 0),
+	registerFunc("current-dateTime", "fn", callCurrentDatetime).arity(0, 0),
 	// function related functions
-	registerFunc("function-arity", "fn", callXYZ),
-	registerFunc("function-name", "fn", callXYZ),
-	registerFunc("function-lookup", "fn", callXYZ),
+	registerFunc("function-arity", "fn", callXYZ).arity(1, 1),
+	registerFunc("function-name", "fn", callXYZ).arity(1, 1),
+	registerFunc("function-lookup", "fn", callXYZ).arity(2, 2),
+	registerFunc("random-number-generator", "fn", callRandomNumberGenerator).arity(0, 1),
+	// math functions
+	registerFunc("pi", "math", callPi).arity(0, 0),
+	registerFunc("exp", "math", mathUnary(math.Exp)).arity(1, 1),
+	registerFunc("exp10", "math", mathUnary(exp10)).arity(1, 1),
+	registerFunc("log", "math", mathUnary(math.Log)).arity(1, 1),
+	registerFunc("log10", "math", mathUnary(math.Log10)).arity(1, 1),
+	registerFunc("sqrt", "math", mathUnary(math.Sqrt)).arity(1, 1),
+	registerFunc("sin", "math", mathUnary(math.Sin)).arity(1, 1),
+	registerFunc("cos", "math", mathUnary(math.Cos)).arity(1, 1),
+	registerFunc("tan", "math", mathUnary(math.Tan)).arity(1, 1),
+	registerFunc("asin", "math", mathUnary(math.Asin)).arity(1, 1),
+	registerFunc("acos", "math", mathUnary(math.Acos)).arity(1, 1),
+	registerFunc("atan", "math", mathUnary(math.Atan)).arity(1, 1),
+	registerFunc("pow", "math", callPow).arity(2, 2),
+	registerFunc("atan2", "math", callAtan2).arity(2, 2),
 	// array functions
-	registerFunc("append", "array", callXYZ),
-	registerFunc("filter", "array", callXYZ),
-	registerFunc("flatten", "array", callXYZ),
-	registerFunc("fold-left", "array", callXYZ),
-	registerFunc("fold-right", "array", callXYZ),
-	registerFunc("for-each", "array", callXYZ),
-	registerFunc("for-each-pair", "array", callXYZ),
-	registerFunc("get", "array", callXYZ),
-	registerFunc("head", "array", callXYZ),
-	registerFunc("insert-before", "array", callXYZ),
-	registerFunc("insert-after", "array", callXYZ),
-	registerFunc("join", "array", callXYZ),
-	registerFunc("put", "array", callXYZ),
-	registerFunc("remove", "array", callXYZ),
-	registerFunc("reverse", "array", callXYZ),
-	registerFunc("size", "array", callXYZ),
-	registerFunc("sort", "array", callXYZ),
-	registerFunc("subarray", "array", callXYZ),
-	registerFunc("tail", "array", callXYZ),
+	registerFunc("append", "array", callAppendArray).arity(2, 2),
+	registerFunc("filter", "array", callFilterArray).arity(2, 2),
+	registerFunc("flatten", "array", callFlattenArray).arity(1, 1),
+	registerFunc("fold-left", "array", callFoldLeftArray).arity(3, 3),
+	registerFunc("fold-right", "array", callFoldRightArray).arity(3, 3),
+	registerFunc("for-each", "array", callForeachArray).arity(2, 2),
+	registerFunc("for-each-pair", "array", callForeachPairArray).arity(3, 3),
+	registerFunc("get", "array", callGetArray).arity(2, 2),
+	registerFunc("head", "array", callHeadArray).arity(1, 1),
+	registerFunc("insert-before", "array", callInsertBeforeArray).arity(3, 3),
+	registerFunc("insert-after", "array", callXYZ).arity(3, 3),
+	registerFunc("join", "array", callJoinArray).arity(1, 1),
+	registerFunc("put", "array", callPutArray).arity(3, 3),
+	registerFunc("remove", "array", callRemoveArray).arity(2, 2),
+	registerFunc("reverse", "array", callReverseArray).arity(1, 1),
+	registerFunc("size", "array", callSizeArray).arity(1, 1),
+	registerFunc("sort", "array", callXYZ).arity(1, 3),
+	registerFunc("subarray", "array", callSubarrayArray).arity(2, 3),
+	registerFunc("tail", "array", callTailArray).arity(1, 1),
 	// map functions
-	registerFunc("contains", "map", callContainsMap),
-	registerFunc("entry", "map", calEntryMap),
-	registerFunc("find", "map", callFindMap),
-	registerFunc("for-each", "map", callForeachMap),
-	registerFunc("get", "map", callGetMap),
-	registerFunc("keys", "map", callKeysMap),
-	registerFunc("merge", "map", callMergeMap),
-	registerFunc("put", "map", callPutMap),
-	registerFunc("remove", "map", callRemoveMap),
-	registerFunc("size", "map", callSizeMap),
+	registerFunc("contains", "map", callContainsMap).arity(2, 2),
+	registerFunc("entry", "map", calEntryMap).arity(2, 2),
+	registerFunc("find", "map", callFindMap).arity(2, 2),
+	registerFunc("for-each", "map", callForeachMap).arity(2, 2),
+	registerFunc("get", "map", callGetMap).arity(2, 2),
+	registerFunc("keys", "map", callKeysMap).arity(1, 1),
+	registerFunc("merge", "map", callMergeMap).arity(1, 2),
+	registerFunc("put", "map", callPutMap).arity(3, 3),
+	registerFunc("remove", "map", callRemoveMap).arity(2, 2),
+	registerFunc("size", "map", callSizeMap).arity(1, 1),
 	// constructor functions
-	registerFunc("string", "xs", callConstructor(xsString)),
-	registerFunc("decimal", "xs", callConstructor(xsDecimal)),
-	registerFunc("integer", "xs", callConstructor(xsInteger)),
-	registerFunc("boolean", "xs", callConstructor(xsBool)),
-	registerFunc("dateTime", "xs", callConstructor(xsDateTime)),
-	registerFunc("date", "xs", callConstructor(xsDate)),
+	registerFunc("string", "xs", callConstructor(xsString)).arity(1, 1),
+	registerFunc("double", "xs", callConstructor(xsDouble)).arity(1, 1),
+	registerFunc("decimal", "xs", callConstructor(xsDecimal)).arity(1, 1),
+	registerFunc("integer", "xs", callConstructor(xsInteger)).arity(1, 1),
+	registerFunc("boolean", "xs", callConstructor(xsBool)).arity(1, 1),
+	registerFunc("dateTime", "xs", callConstructor(xsDateTime)).arity(1, 1),
+	registerFunc("date", "xs", callConstructor(xsDate)).arity(1, 1),
+	registerFunc("float", "xs", callConstructor(xsFloat)).arity(1, 1),
+	registerFunc("duration", "xs", callConstructor(xsDuration)).arity(1, 1),
+	registerFunc("dayTimeDuration", "xs", callConstructor(xsDayTimeDuration)).arity(1, 1),
+	registerFunc("yearMonthDuration", "xs", callConstructor(xsYearMonthDuration)).arity(1, 1),
+	registerFunc("anyURI", "xs", callConstructor(xsAnyURI)).arity(1, 1),
 }
 
 var fileFuncs = []registeredBuiltin{
@@ -258,12 +358,18 @@ var fileFuncs = []registeredBuiltin{
 }
 
 var angleFuncs = []registeredBuiltin{
-	registerFunc("coalesce", "agl", callXYZ),
+	registerFunc("coalesce", "agl", callCoalesce),
+	registerFunc("index", "agl", callIndex),
+	registerFunc("lookup", "agl", callLookup),
 }
 
 var angleStringFuncs = []registeredBuiltin{
 	registerFunc("string-indexof", "agl", callStringIndexOf),
 	registerFunc("string-reverse", "agl", callStringReverse),
+	registerFunc("iequals", "agl", callIEquals),
+	registerFunc("icontains", "agl", callIContains),
+	registerFunc("normalize-all", "agl", callNormalizeAll),
+	registerFunc("format", "agl", callFormat),
 }
 
 var envFuncs = []registeredBuiltin{
@@ -581,60 +687,224 @@ func callRound(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) < 1 && len(args) > 2 {
 		return nil, ErrArgument
 	}
-	val, err := getFloatFromExpr(args[0], ctx)
-	if err != nil {
-		return nil, err
-	}
-	return Singleton(math.Round(val)), nil
+	return numericUnary(ctx, args[0], math.Round, nil)
 }
 
 func callFloor(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) != 1 {
 		return nil, ErrArgument
 	}
-	val, err := getFloatFromExpr(args[0], ctx)
+	return numericUnary(ctx, args[0], math.Floor, nil)
+}
+
+func callCeil(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	return numericUnary(ctx, args[0], math.Ceil, nil)
+}
+
+func callAbs(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	abs := func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	return numericUnary(ctx, args[0], math.Abs, abs)
+}
+
+func numericUnary(ctx Context, expr Expr, fn func(float64) float64, fi func(int64) int64) (Sequence, error) {
+	items, err := expr.find(ctx)
+	if err != nil || !items.Singleton() {
+		return Singleton(math.NaN()), err
+	}
+	switch v := items[0].Value().(type) {
+	case int64:
+		if fi != nil {
+			v = fi(v)
+		}
+		return Singleton(v), nil
+	case Decimal:
+		res, err := DecimalFromFloat(fn(v.Float64()))
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(res), nil
+	default:
+		val, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(fn(val)), nil
+	}
+}
+
+func callPi(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 0 {
+		return nil, ErrArgument
+	}
+	return Singleton(math.Pi), nil
+}
+
+func exp10(val float64) float64 {
+	return math.Pow(10, val)
+}
+
+func mathUnary(fn func(float64) float64) BuiltinFunc {
+	return func(ctx Context, args []Expr) (Sequence, error) {
+		if len(args) != 1 {
+			return nil, ErrArgument
+		}
+		val, ok, err := getOptionalFloatFromExpr(args[0], ctx)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return Singleton(fn(val)), nil
+	}
+}
+
+func callPow(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	base, ok, err := getOptionalFloatFromExpr(args[0], ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	exp, err := getFloatFromExpr(args[1], ctx)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(math.Floor(val)), nil
+	return Singleton(math.Pow(base, exp)), nil
 }
 
-func callCeil(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callAtan2(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
 		return nil, ErrArgument
 	}
-	val, err := getFloatFromExpr(args[0], ctx)
+	y, err := getFloatFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	x, err := getFloatFromExpr(args[1], ctx)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(math.Ceil(val)), nil
+	return Singleton(math.Atan2(y, x)), nil
 }
 
-func callAbs(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callRandomNumberGenerator(ctx Context, args []Expr) (Sequence, error) {
+	return generateRandom(ctx, args, uint64(ctx.Now.UnixNano()))
+}
+
+func seededRandomNumberGenerator(seed uint64) BuiltinFunc {
+	return func(ctx Context, args []Expr) (Sequence, error) {
+		return generateRandom(ctx, args, seed)
+	}
+}
+
+func generateRandom(ctx Context, args []Expr, seed uint64) (Sequence, error) {
+	if len(args) > 1 {
 		return nil, ErrArgument
 	}
-	val, err := getFloatFromExpr(args[0], ctx)
+	if len(args) == 1 {
+		items, err := args[0].find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !items.Empty() {
+			h := fnv.New64a()
+			io.WriteString(h, fmt.Sprint(items.First().Value()))
+			seed = h.Sum64()
+		}
+	}
+	return Singleton(createRandomGenerator(ctx, seed)), nil
+}
+
+func createRandomGenerator(ctx Context, seed uint64) Item {
+	r := rand.New(rand.NewPCG(seed, seed))
+	values := map[Item]Sequence{
+		createLiteral("number"): Singleton(r.Float64()),
+		createLiteral("next"): Singleton(funcItem{
+			body: randomNext{seed: r.Uint64()},
+			ctx:  ctx,
+		}),
+		createLiteral("permute"): Singleton(funcItem{
+			params: []string{"arg"},
+			body:   randomPermute{seed: r.Uint64()},
+			ctx:    ctx,
+		}),
+	}
+	return createMap(values)
+}
+
+type randomNext struct {
+	seed uint64
+}
+
+func (r randomNext) Find(node xml.Node) (Sequence, error) {
+	return r.find(defaultContext(node))
+}
+
+func (r randomNext) find(ctx Context) (Sequence, error) {
+	return Singleton(createRandomGenerator(ctx, r.seed)), nil
+}
+
+type randomPermute struct {
+	seed uint64
+}
+
+func (r randomPermute) Find(node xml.Node) (Sequence, error) {
+	return r.find(defaultContext(node))
+}
+
+func (r randomPermute) find(ctx Context) (Sequence, error) {
+	expr, err := ctx.Resolve("arg")
+	if err != nil {
+		return nil, err
+	}
+	items, err := expr.find(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(math.Abs(val)), nil
+	items = slices.Clone(items)
+	rg := rand.New(rand.NewPCG(r.seed, r.seed))
+	rg.Shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+	return items, nil
 }
 
 func callFormatNumber(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 2 {
+	if len(args) != 2 && len(args) != 3 {
 		return nil, ErrArgument
 	}
 	val, err := getFloatFromExpr(args[0], ctx)
 	if err != nil {
 		return nil, err
 	}
-	// picture, err := getStringFromExpr(args[1], ctx)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// res, err := formatNumber(val, picture)
-	return Singleton(val), nil
+	picture, err := getStringFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 3 {
+		name, err := getStringFromExpr(args[2], ctx)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			return nil, fmt.Errorf("%s: decimal format %w", name, ErrUndefined)
+		}
+	}
+	res, err := formatNumber(val, picture)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(res), nil
 }
 
 func callFormatInteger(ctx Context, args []Expr) (Sequence, error) {
@@ -685,7 +955,87 @@ func callExists(ctx Context, args []Expr) (Sequence, error) {
 }
 
 func callDistinctValues(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	cmp, err := getCollationFromArgs(ctx, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items, err = atomizeSequence(items); err != nil {
+		return nil, err
+	}
+	var list Sequence
+	for _, i := range items {
+		ok := slices.ContainsFunc(list, func(other Item) bool {
+			return equalValues(other.Value(), i.Value(), cmp, true)
+		})
+		if !ok {
+			list.Append(i)
+		}
+	}
+	return list, nil
+}
+
+func callIndexOf(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, ErrArgument
+	}
+	cmp, err := getCollationFromArgs(ctx, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items, err = atomizeSequence(items); err != nil {
+		return nil, err
+	}
+	search, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if search, err = atomizeSequence(search); err != nil {
+		return nil, err
+	}
+	if !search.Singleton() {
+		return nil, fmt.Errorf("%w: single search value expected", ErrType)
+	}
+	var list Sequence
+	for j, i := range items {
+		if equalValues(i.Value(), search.First().Value(), cmp, false) {
+			list.Append(createLiteral(int64(j + 1)))
+		}
+	}
+	return list, nil
+}
+
+func callDeepEqual(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, ErrArgument
+	}
+	cmp, err := getCollationFromArgs(ctx, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	left, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := deepEqualSequence(left, right, cmp)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(ok), nil
 }
 
 func callEmpty(ctx Context, args []Expr) (Sequence, error) {
@@ -739,91 +1089,101 @@ func callReverse(ctx Context, args []Expr) (Sequence, error) {
 	return items, nil
 }
 
-func callSum(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callInsertBefore(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 3 {
 		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
+	items, err := args[0].find(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var result float64
-	for _, n := range items {
-		v, err := strconv.ParseFloat(n.Node().Value(), 64)
-		if err != nil {
-			return nil, err
-		}
-		result += v
+	pos, err := getIntFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
 	}
-	return Singleton(result), nil
+	inserts, err := args[2].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos = max(pos-1, 0)
+	pos = min(pos, int64(len(items)))
+	return slices.Concat(items[:pos], inserts, items[pos:]), nil
 }
 
-func callAvg(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callRemove(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
 		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
+	items, err := args[0].find(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if len(items) == 0 {
+	pos, err := getIntFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pos < 1 || pos > int64(len(items)) {
+		return items, nil
+	}
+	return slices.Delete(items, int(pos-1), int(pos)), nil
+}
+
+func callSubsequence(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
 		return nil, ErrArgument
 	}
-	var result float64
-	for _, n := range items {
-		v, err := strconv.ParseFloat(n.Node().Value(), 64)
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	beg, err := getFloatFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	beg = roundHalfUp(beg)
+	end := math.Inf(1)
+	if len(args) == 3 {
+		size, err := getFloatFromExpr(args[2], ctx)
 		if err != nil {
 			return nil, err
 		}
-		result += v
+		end = beg + roundHalfUp(size)
 	}
-	return Singleton(result / float64(len(items))), nil
+	var seq Sequence
+	for i := range items {
+		if pos := float64(i + 1); pos >= beg && pos < end {
+			seq.Append(items[i])
+		}
+	}
+	return seq, nil
 }
 
-func callCount(ctx Context, args []Expr) (Sequence, error) {
+func callUnordered(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) != 1 {
 		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
-	if err != nil {
-		return nil, err
-	}
-	return Singleton(float64(len(items))), nil
+	return args[0].find(ctx)
 }
 
-func callMin(ctx Context, args []Expr) (Sequence, error) {
-	items, err := expandArgs(ctx, args)
-	if err != nil {
-		return nil, err
-	}
-	var res any
-	if items.Every(isFloat) {
-		list, _ := convert[float64](items, toFloat)
-		res = lowestValue(list)
-	} else {
-		list, _ := convert[string](items, toString)
-		res = lowestValue(list)
+func roundHalfUp(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
 	}
-	return Singleton(res), nil
+	return math.Floor(f + 0.5)
 }
 
-func callMax(ctx Context, args []Expr) (Sequence, error) {
+func callSum(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
 	items, err := expandArgs(ctx, args)
 	if err != nil {
 		return nil, err
 	}
-	var res any
-	if items.Every(isFloat) {
-		list, _ := convert[float64](items, toFloat)
-		res = greatestValue(list)
-	} else {
-		list, _ := convert[string](items, toString)
-		res = greatestValue(list)
-	}
-	return Singleton(res), nil
+	return sumItems(items)
 }
 
-func callZeroOrOne(ctx Context, args []Expr) (Sequence, error) {
+func callAvg(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) != 1 {
 		return nil, ErrArgument
 	}
@@ -831,7 +1191,144 @@ func callZeroOrOne(ctx Context, args []Expr) (Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(items) > 1 {
+	if len(items) == 0 {
+		return nil, ErrArgument
+	}
+	sum, err := sumItems(items)
+	if err != nil {
+		return nil, err
+	}
+	return doDiv(sum, Singleton(int64(len(items))))
+}
+
+func sumItems(items Sequence) (Sequence, error) {
+	result := Singleton(int64(0))
+	for _, n := range items {
+		var (
+			v   = n.Value()
+			err error
+		)
+		if !n.Atomic() {
+			if v, err = toFloat(v); err != nil {
+				return nil, err
+			}
+		}
+		if result, err = doAdd(result, Singleton(v)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func callCount(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(int64(len(items))), nil
+}
+
+func callMin(ctx Context, args []Expr) (Sequence, error) {
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if list, ok := numericItems(items); ok {
+		return extremeNumber(list, func(c int) bool {
+			return c < 0
+		})
+	}
+	list, _ := convert[string](items, toString)
+	return Singleton(lowestValue(list)), nil
+}
+
+func callMax(ctx Context, args []Expr) (Sequence, error) {
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if list, ok := numericItems(items); ok {
+		return extremeNumber(list, func(c int) bool {
+			return c > 0
+		})
+	}
+	list, _ := convert[string](items, toString)
+	return Singleton(greatestValue(list)), nil
+}
+
+func numericItems(items Sequence) ([]any, bool) {
+	list := make([]any, 0, len(items))
+	for _, i := range items {
+		v := i.Value()
+		if !i.Atomic() {
+			f, err := toFloat(v)
+			if err != nil {
+				return nil, false
+			}
+			v = f
+		}
+		if !isNumberValue(v) {
+			return nil, false
+		}
+		list = append(list, v)
+	}
+	return list, len(list) > 0
+}
+
+func extremeNumber(list []any, accept func(int) bool) (Sequence, error) {
+	var (
+		res     = list[0]
+		double  bool
+		decimal bool
+	)
+	for _, v := range list {
+		switch v.(type) {
+		case float64, float32:
+			double = true
+		case Decimal:
+			decimal = true
+		}
+		if f, err := toFloat(v); err == nil && math.IsNaN(f) {
+			return Singleton(math.NaN()), nil
+		}
+		c, err := compareAtomic(v, res)
+		if err != nil {
+			return nil, err
+		}
+		if accept(c) {
+			res = v
+		}
+	}
+	switch {
+	case double:
+		f, err := toFloat(res)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(f), nil
+	case decimal:
+		d, err := toDecimal(res)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(d), nil
+	default:
+		return Singleton(res), nil
+	}
+}
+
+func callZeroOrOne(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > 1 {
 		return nil, fmt.Errorf("too many elements")
 	}
 	return items, nil
@@ -866,19 +1363,26 @@ func callExactlyOne(ctx Context, args []Expr) (Sequence, error) {
 }
 
 func callPosition(ctx Context, args []Expr) (Sequence, error) {
-	return Singleton(float64(ctx.Index)), nil
+	return Singleton(int64(ctx.Index)), nil
 }
 
 func callLast(ctx Context, args []Expr) (Sequence, error) {
-	return Singleton(float64(ctx.Size)), nil
+	return Singleton(int64(ctx.Size)), nil
 }
 
 func callCurrentDate(ctx Context, args []Expr) (Sequence, error) {
-	return callCurrentDatetime(ctx, args)
+	return Singleton(truncateDate(currentTime(ctx))), nil
 }
 
 func callCurrentDatetime(ctx Context, args []Expr) (Sequence, error) {
-	return Singleton(time.Now()), nil
+	return Singleton(currentTime(ctx)), nil
+}
+
+func currentTime(ctx Context) time.Time {
+	if ctx.Now.IsZero() {
+		return time.Now()
+	}
+	return ctx.Now
 }
 
 func callDate(ctx Context, args []Expr) (Sequence, error) {
@@ -897,51 +1401,204 @@ func callDate(ctx Context, args []Expr) (Sequence, error) {
 }
 
 func callDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	date, ok, err := getTimeFromExpr(args[0], ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	items, err := args[1].find(ctx)
+	if err != nil || items.Empty() {
+		return nil, err
+	}
+	var clock time.Time
+	switch v := items.First().Value().(type) {
+	case time.Time:
+		clock = v
+	case string:
+		clock, err = parseTime(v, timeLayouts)
+	default:
+		err = ErrType
+	}
+	if err != nil {
+		return nil, err
+	}
+	loc := date.Location()
+	if hasTimezone(clock) {
+		if hasTimezone(date) {
+			_, x := date.Zone()
+			_, y := clock.Zone()
+			if x != y {
+				return nil, fmt.Errorf("dateTime: date and time have different timezones")
+			}
+		}
+		loc = clock.Location()
+	}
+	res := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), loc)
+	return Singleton(res), nil
 }
 
 func callYearFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Year())
+	})
 }
 
 func callYearFromDate(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return callYearFromDateTime(ctx, args)
 }
 
 func callMonthFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Month())
+	})
 }
 
 func callMonthFromDate(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return callMonthFromDateTime(ctx, args)
 }
 
 func callDayFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Day())
+	})
 }
 
 func callDayFromDate(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return callDayFromDateTime(ctx, args)
 }
 
 func callHoursFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Hour())
+	})
 }
 
 func callMinutesFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Minute())
+	})
 }
 
 func callSecondsFromDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	return timeComponent(ctx, args, func(t time.Time) any {
+		return float64(t.Second()) + float64(t.Nanosecond())/float64(time.Second)
+	})
+}
+
+func callTimezoneFromDateTime(ctx Context, args []Expr) (Sequence, error) {
+	return timeComponent(ctx, args, func(t time.Time) any {
+		if !hasTimezone(t) {
+			return nil
+		}
+		_, offset := t.Zone()
+		return createDayTimeDuration(time.Duration(offset) * time.Second)
+	})
+}
+
+func callTimezoneFromDate(ctx Context, args []Expr) (Sequence, error) {
+	return callTimezoneFromDateTime(ctx, args)
+}
+
+func timeComponent(ctx Context, args []Expr, get func(time.Time) any) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	t, ok, err := getTimeFromExpr(args[0], ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	value := get(t)
+	if value == nil {
+		return nil, nil
+	}
+	return Singleton(value), nil
+}
+
+func callYearsFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return float64(d.Years())
+	})
+}
+
+func callMonthsFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return float64(d.Months % 12)
+	})
+}
+
+func callDaysFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return float64(d.Days())
+	})
+}
+
+func callHoursFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return float64(d.Hours())
+	})
+}
+
+func callMinutesFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return float64(d.Minutes())
+	})
+}
+
+func callSecondsFromDuration(ctx Context, args []Expr) (Sequence, error) {
+	return durationComponent(ctx, args, func(d Duration) float64 {
+		return d.Seconds()
+	})
+}
+
+func durationComponent(ctx Context, args []Expr, get func(Duration) float64) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	items, err := args[0].find(ctx)
+	if err != nil || items.Empty() {
+		return nil, err
+	}
+	if !items.Singleton() {
+		return nil, ErrType
+	}
+	d, err := toDuration(items.First().Value())
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(get(d)), nil
 }
 
 func callFormatDate(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
+	return formatTimeWith(ctx, args, formatDate)
 }
 
 func callFormatDateTime(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
+	return formatTimeWith(ctx, args, formatDateTime)
+}
+
+func callFormatTime(ctx Context, args []Expr) (Sequence, error) {
+	return formatTimeWith(ctx, args, formatTime)
+}
+
+func formatTimeWith(ctx Context, args []Expr, format func(time.Time, string) (string, error)) (Sequence, error) {
+	if len(args) != 2 && len(args) != 5 {
+		return nil, ErrArgument
+	}
+	value, ok, err := getTimeFromExpr(args[0], ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	picture, err := getStringFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	str, err := format(value, picture)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(str), nil
 }
 
 func callStringReverse(ctx Context, args []Expr) (Sequence, error) {
@@ -975,34 +1632,29 @@ func callStringIndexOf(ctx Context, args []Expr) (Sequence, error) {
 	return Singleton(float64(ix)), nil
 }
 
-func callString(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) == 0 {
-		return Singleton(ctx.Value()), nil
+func callIEquals(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
+	fst, err := getStringFromExpr(args[0], ctx)
 	if err != nil {
 		return nil, err
 	}
-	if items.Empty() {
-		return Singleton(""), nil
-	}
-	if !items[0].Atomic() {
-		ctx.Node = items[0].Node()
-		ctx.Size = 1
-		ctx.Index = 1
-		return callString(ctx, nil)
-	}
-	str, err := toString(items[0].Value())
+	snd, err := getStringFromExpr(args[1], ctx)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(str), nil
+	return Singleton(strings.EqualFold(fst, snd)), nil
 }
 
-func callCompare(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 2 {
+func callIContains(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 {
 		return nil, ErrArgument
 	}
+	if len(args) == 1 {
+		list := []Expr{NewValueFromNode(ctx.Node)}
+		return callIContains(ctx, append(list, args...))
+	}
 	fst, err := getStringFromExpr(args[0], ctx)
 	if err != nil {
 		return nil, err
@@ -1011,62 +1663,266 @@ func callCompare(ctx Context, args []Expr) (Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
-	cmp := strings.Compare(fst, snd)
-	return Singleton(float64(cmp)), nil
+	res := strings.Contains(strings.ToLower(fst), strings.ToLower(snd))
+	return Singleton(res), nil
 }
 
-func callConcat(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) < 2 {
-		return nil, ErrArgument
+func callNormalizeAll(ctx Context, args []Expr) (Sequence, error) {
+	var (
+		str string
+		err error
+	)
+	switch len(args) {
+	case 0:
+		str = ctx.Value()
+	case 1:
+		str, err = getStringFromExpr(args[0], ctx)
+	default:
+		err = ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
 	if err != nil {
 		return nil, err
 	}
-	var list []string
-	for _, i := range items {
-		str, err := toString(i.Value())
-		if err != nil {
-			return nil, err
-		}
-		list = append(list, str)
-	}
-	str := strings.Join(list, "")
-	return Singleton(str), nil
+	str = strings.Join(strings.Fields(str), " ")
+	return Singleton(strings.ToLower(str)), nil
 }
 
-func callStringJoin(ctx Context, args []Expr) (Sequence, error) {
+func callFormat(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) < 1 {
 		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args[:1])
+	tpl, err := getStringFromExpr(args[0], ctx)
 	if err != nil {
 		return nil, err
 	}
-	list, err := convert(items, toString)
+	var values []string
+	for _, a := range args[1:] {
+		items, err := expandArgs(ctx, []Expr{a})
+		if err != nil {
+			return nil, err
+		}
+		list, err := convert(items, toString)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, strings.Join(list, " "))
+	}
+	str, err := formatString(tpl, values)
 	if err != nil {
 		return nil, err
 	}
-	var sep string
-	if len(args) >= 2 {
-		sep, err = getStringFromExpr(args[1], ctx)
+	return Singleton(str), nil
+}
+
+func formatString(tpl string, values []string) (string, error) {
+	var str strings.Builder
+	for i := 0; i < len(tpl); i++ {
+		switch c := tpl[i]; {
+		case c == '}':
+			if i+1 < len(tpl) && tpl[i+1] == '}' {
+				i++
+			}
+			str.WriteByte(c)
+		case c != '{':
+			str.WriteByte(c)
+		case i+1 < len(tpl) && tpl[i+1] == '{':
+			str.WriteByte(c)
+			i++
+		default:
+			end := strings.IndexByte(tpl[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("%w: unterminated placeholder", ErrSyntax)
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(tpl[i+1 : i+end]))
+			if err != nil {
+				return "", fmt.Errorf("%s: %w: invalid placeholder", tpl[i:i+end+1], ErrSyntax)
+			}
+			if n < 1 || n > len(values) {
+				return "", fmt.Errorf("%s: %w", tpl[i:i+end+1], ErrIndex)
+			}
+			str.WriteString(values[n-1])
+			i += end
+		}
+	}
+	return str.String(), nil
+}
+
+func callCoalesce(ctx Context, args []Expr) (Sequence, error) {
+	for _, a := range args {
+		is, err := a.find(ctx)
 		if err != nil {
 			return nil, err
 		}
+		if !is.Empty() {
+			return is, nil
+		}
 	}
-	str := strings.Join(list, sep)
-	return Singleton(str), nil
+	return nil, nil
 }
 
-func callSubstring(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) < 2 || len(args) > 3 {
+func callIndex(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
 		return nil, ErrArgument
 	}
-	str, err := getStringFromExpr(args[0], ctx)
+	items, err := args[0].find(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if str == "" {
+	idx := indexItem{
+		values: make(map[Item]Sequence),
+	}
+	for i, it := range items {
+		if it.Atomic() {
+			return nil, ErrType
+		}
+		keys, err := args[1].find(ctx.Sub(it.Node(), i+1, len(items)))
+		if err != nil {
+			return nil, err
+		}
+		if keys.Singleton() {
+			if fn, ok := keys.First().(funcItem); ok {
+				keys, err = fn.call([]Sequence{Singleton(it)})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, k := range keys {
+			key := mapKey(k)
+			if vs := idx.values[key]; len(vs) > 0 && vs[len(vs)-1] == it {
+				continue
+			}
+			idx.values[key] = append(idx.values[key], it)
+		}
+	}
+	return Singleton(idx), nil
+}
+
+func callLookup(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !items.Singleton() {
+		return nil, ErrType
+	}
+	idx, ok := items.First().(indexItem)
+	if !ok {
+		return nil, ErrType
+	}
+	values, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var seq Sequence
+	for _, v := range values {
+		for _, it := range idx.values[mapKey(v)] {
+			if !slices.Contains(seq, it) {
+				seq = append(seq, it)
+			}
+		}
+	}
+	return seq, nil
+}
+
+func callString(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) == 0 {
+		return Singleton(ctx.Value()), nil
+	}
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return Singleton(""), nil
+	}
+	if !items[0].Atomic() {
+		ctx.Node = items[0].Node()
+		ctx.Size = 1
+		ctx.Index = 1
+		return callString(ctx, nil)
+	}
+	str, err := toString(items[0].Value())
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(str), nil
+}
+
+func callCompare(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, ErrArgument
+	}
+	cmp, err := getCollationFromArgs(ctx, args, 2)
+	if err != nil {
+		return nil, err
+	}
+	fst, err := getStringFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	snd, err := getStringFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(float64(cmp(fst, snd))), nil
+}
+
+func callConcat(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 {
+		return nil, ErrArgument
+	}
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, i := range items {
+		str, err := toString(i.Value())
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, str)
+	}
+	str := strings.Join(list, "")
+	return Singleton(str), nil
+}
+
+func callStringJoin(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 {
+		return nil, ErrArgument
+	}
+	items, err := expandArgs(ctx, args[:1])
+	if err != nil {
+		return nil, err
+	}
+	list, err := convert(items, toString)
+	if err != nil {
+		return nil, err
+	}
+	var sep string
+	if len(args) >= 2 {
+		sep, err = getStringFromExpr(args[1], ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	str := strings.Join(list, sep)
+	return Singleton(str), nil
+}
+
+func callSubstring(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, ErrArgument
+	}
+	str, err := getStringFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	if str == "" {
 		return Singleton(""), nil
 	}
 	beg, err := getFloatFromExpr(args[1], ctx)
@@ -1428,6 +2284,51 @@ func callRoot(ctx Context, args []Expr) (Sequence, error) {
 	return Singleton(root), nil
 }
 
+func callId(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	node := ctx.Node
+	if len(args) == 2 {
+		items, err := args[1].find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !items.Singleton() || items.First().Atomic() {
+			return nil, ErrType
+		}
+		node = items.First().Node()
+	}
+	root := ctx.Sub(node, 1, 1).Root().Node
+	finder, ok := root.(interface {
+		GetElementById(string) (xml.Node, error)
+	})
+	if !ok {
+		return nil, nil
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var seq Sequence
+	for _, it := range items {
+		str, err := toString(it.Value())
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range strings.Fields(str) {
+			n, err := finder.GetElementById(id)
+			if err != nil || slices.ContainsFunc(seq, func(i Item) bool {
+				return i.Node() == n
+			}) {
+				continue
+			}
+			seq.Append(createNode(n))
+		}
+	}
+	return seq, nil
+}
+
 func callPath(ctx Context, args []Expr) (Sequence, error) {
 	var (
 		paths []xml.PathInfo
@@ -1452,30 +2353,105 @@ func callPath(ctx Context, args []Expr) (Sequence, error) {
 }
 
 func callHasChildren(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) == 0 {
-		nodes := ctx.Nodes()
-		return Singleton(len(nodes) > 0), nil
+	if len(args) > 1 {
+		return nil, ErrArgument
 	}
-	items, err := expandArgs(ctx, args)
-	if err != nil {
-		return nil, err
+	node := ctx.Node
+	if len(args) == 1 {
+		items, err := expandArgs(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		if items.Empty() {
+			return Singleton(false), nil
+		}
+		n, ok := items[0].(nodeItem)
+		if !ok {
+			return nil, ErrType
+		}
+		node = n.Node()
 	}
-	n, ok := items[0].(nodeItem)
-	if !ok {
-		return nil, ErrType
+	switch n := node.(type) {
+	case *xml.Document:
+		return Singleton(len(n.Nodes) > 0), nil
+	case *xml.Element:
+		return Singleton(len(n.Nodes) > 0), nil
+	default:
+		return Singleton(false), nil
 	}
-	ctx.Node = n.Node()
-	ctx.Size = 1
-	ctx.Index = 1
-	return callHasChildren(ctx, nil)
 }
 
 func callInnermost(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	nodes, err := getNodesInOrder(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	ancestors := make(map[xml.Node]struct{})
+	for _, n := range nodes {
+		for p := n.Parent(); p != nil; p = p.Parent() {
+			ancestors[p] = struct{}{}
+		}
+	}
+	var seq Sequence
+	for _, n := range nodes {
+		if _, ok := ancestors[n]; ok {
+			continue
+		}
+		seq.Append(createNode(n))
+	}
+	return seq, nil
 }
 
 func callOutermost(ctx Context, args []Expr) (Sequence, error) {
-	return nil, ErrImplemented
+	nodes, err := getNodesInOrder(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[xml.Node]struct{})
+	for _, n := range nodes {
+		set[n] = struct{}{}
+	}
+	var seq Sequence
+	for _, n := range nodes {
+		var nested bool
+		for p := n.Parent(); p != nil && !nested; p = p.Parent() {
+			_, nested = set[p]
+		}
+		if !nested {
+			seq.Append(createNode(n))
+		}
+	}
+	return seq, nil
+}
+
+func getNodesInOrder(ctx Context, args []Expr) ([]xml.Node, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	items, err := expandArgs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []xml.Node
+	for _, i := range items {
+		n, ok := i.(nodeItem)
+		if !ok {
+			return nil, ErrType
+		}
+		if !slices.Contains(nodes, n.Node()) {
+			nodes = append(nodes, n.Node())
+		}
+	}
+	slices.SortStableFunc(nodes, func(a, b xml.Node) int {
+		if xml.Before(a, b) {
+			return -1
+		}
+		if xml.Before(b, a) {
+			return 1
+		}
+		return 0
+	})
+	return nodes, nil
 }
 
 func callBoolean(ctx Context, args []Expr) (Sequence, error) {
@@ -1528,87 +2504,684 @@ func callDoc(ctx Context, args []Expr) (Sequence, error) {
 	if err != nil {
 		return nil, err
 	}
+	n.URI = xml.FileURI(file)
 	return Singleton(n), nil
 }
 
-func callContainsMap(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callBaseURI(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) > 1 {
 		return nil, ErrArgument
 	}
-	arr, ok := args[0].(hashmap)
-	if !ok {
-		return nil, ErrType
-	}
-	_, ok = arr.values[args[1]]
-	return Singleton(ok), nil
-}
-
-func calEntryMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
-}
-
-func callFindMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
-}
-
-func callForeachMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
-}
-
-func callGetMap(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 2 {
-		return nil, ErrArgument
+	node := ctx.Node
+	if len(args) == 1 {
+		items, err := args[0].find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if items.Empty() {
+			return nil, nil
+		}
+		if !items.Singleton() || items.First().Atomic() {
+			return nil, ErrType
+		}
+		node = items.First().Node()
 	}
-	arr, ok := args[0].(hashmap)
-	if !ok {
+	if node == nil {
 		return nil, ErrType
 	}
-	return arr.values[args[1]].find(ctx)
+	uri := xml.BaseURI(node)
+	if uri == "" {
+		return nil, nil
+	}
+	return Singleton(uri), nil
 }
 
-func callKeysMap(ctx Context, args []Expr) (Sequence, error) {
-	if len(args) != 1 {
+func callDocumentURI(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) > 1 {
 		return nil, ErrArgument
 	}
-	arr, ok := args[0].(hashmap)
-	if !ok {
-		return nil, ErrType
-	}
-	var seq Sequence
-	for k := range maps.Keys(arr.values) {
-		i, err := k.find(ctx)
+	node := ctx.Node
+	if len(args) == 1 {
+		items, err := args[0].find(ctx)
 		if err != nil {
 			return nil, err
 		}
-		if i.Empty() || !i.Singleton() {
-			return nil, fmt.Errorf("invalid map key")
+		if items.Empty() {
+			return nil, nil
 		}
-		seq.Append(i.First())
+		node = items.First().Node()
 	}
-	return seq, nil
-}
-
-func callMergeMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
+	doc, ok := node.(*xml.Document)
+	if !ok || doc.URI == "" {
+		return nil, nil
+	}
+	return Singleton(doc.URI), nil
 }
 
-func callPutMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
-}
+func callResolveURI(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, nil
+	}
+	ref, err := toString(items.First().Value())
+	if err != nil {
+		return nil, err
+	}
+	base := getStaticBaseURI(ctx)
+	if len(args) == 2 {
+		if base, err = getStringFromExpr(args[1], ctx); err != nil {
+			return nil, err
+		}
+	}
+	uri, err := xml.ResolveURI(ref, base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrArgument, err)
+	}
+	return Singleton(uri), nil
+}
+
+func staticBaseURI(uri string) BuiltinFunc {
+	return func(_ Context, _ []Expr) (Sequence, error) {
+		if uri == "" {
+			return nil, nil
+		}
+		return Singleton(uri), nil
+	}
+}
+
+func getStaticBaseURI(ctx Context) string {
+	if ctx.Builtins == nil {
+		return ""
+	}
+	name := xml.ExpandedName("static-base-uri", "", functionNS)
+	fn, err := ctx.Builtins.Resolve(name.ExpandedName())
+	if err != nil || fn == nil {
+		return ""
+	}
+	items, err := fn(ctx, nil)
+	if err != nil || items.Empty() {
+		return ""
+	}
+	str, _ := toString(items.First().Value())
+	return str
+}
+
+func callContainsMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getKeyFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := m.values[key]
+	return Singleton(ok), nil
+}
+
+func calEntryMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	key, err := getKeyFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	val, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vs := map[Item]Sequence{
+		key: val,
+	}
+	return Singleton(vs), nil
+}
+
+func callFindMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	seq, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getKeyFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list []Sequence
+		find func(Sequence)
+	)
+	find = func(seq Sequence) {
+		for _, item := range seq {
+			switch it := item.(type) {
+			case mapItem:
+				if v, ok := it.values[key]; ok {
+					list = append(list, v)
+				}
+				for _, k := range sortedKeys(it) {
+					find(it.values[k])
+				}
+			case arrayItem:
+				for _, v := range it.values {
+					find(v)
+				}
+			}
+		}
+	}
+	find(seq)
+	return Singleton(list), nil
+}
+
+func callForeachMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := getFuncFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	var seq Sequence
+	for _, k := range sortedKeys(m) {
+		res, err := fn.call([]Sequence{Singleton(k), m.values[k]})
+		if err != nil {
+			return nil, err
+		}
+		seq.Concat(res)
+	}
+	return seq, nil
+}
+
+func callGetMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getKeyFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := m.values[key]
+	if !ok {
+		return nil, nil
+	}
+	return slices.Clone(v), nil
+}
+
+func callKeysMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Sequence(sortedKeys(m)), nil
+}
+
+func callMergeMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	seq, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var last bool
+	if len(args) == 2 {
+		opts, err := getMapFromExpr(args[1], ctx)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := opts.values[createLiteral("duplicates")]; ok {
+			switch dup := fmt.Sprint(memberValue(v)); dup {
+			case "use-first":
+			case "use-last":
+				last = true
+			default:
+				return nil, fmt.Errorf("%s: unsupported duplicates option", dup)
+			}
+		}
+	}
+	vs := make(map[Item]Sequence)
+	for _, item := range seq {
+		m, ok := item.(mapItem)
+		if !ok {
+			return nil, ErrType
+		}
+		for k, v := range m.values {
+			if _, ok := vs[k]; ok && !last {
+				continue
+			}
+			vs[k] = v
+		}
+	}
+	return Singleton(vs), nil
+}
+
+func callPutMap(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 3 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := getKeyFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	val, err := args[2].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vs := maps.Clone(m.values)
+	vs[key] = val
+	return Singleton(vs), nil
+}
 
 func callRemoveMap(ctx Context, args []Expr) (Sequence, error) {
-	return nil, nil
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vs := maps.Clone(m.values)
+	for _, k := range keys {
+		delete(vs, mapKey(k))
+	}
+	return Singleton(vs), nil
 }
 
 func callSizeMap(ctx Context, args []Expr) (Sequence, error) {
 	if len(args) != 1 {
 		return nil, ErrArgument
 	}
-	arr, ok := args[0].(hashmap)
-	if !ok {
-		return nil, ErrType
+	m, err := getMapFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(float64(len(m.values))), nil
+}
+
+func callSizeArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Singleton(float64(len(arr.values))), nil
+}
+
+func callGetArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := getPositionFromExpr(args[1], ctx, len(arr.values))
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(arr.values[pos]), nil
+}
+
+func callPutArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 3 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := getPositionFromExpr(args[1], ctx, len(arr.values))
+	if err != nil {
+		return nil, err
+	}
+	val, err := args[2].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := slices.Clone(arr.values)
+	list[pos] = val
+	return Singleton(list), nil
+}
+
+func callAppendArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	val, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := append(slices.Clone(arr.values), val)
+	return Singleton(list), nil
+}
+
+func callInsertBeforeArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 3 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := getPositionFromExpr(args[1], ctx, len(arr.values)+1)
+	if err != nil {
+		return nil, err
+	}
+	val, err := args[2].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := slices.Insert(slices.Clone(arr.values), pos, val)
+	return Singleton(list), nil
+}
+
+func callRemoveArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[int]struct{})
+	for _, i := range seq {
+		x, err := toInt(i.Value())
+		if err != nil {
+			return nil, err
+		}
+		if x < 1 || int(x) > len(arr.values) {
+			return nil, ErrIndex
+		}
+		skip[int(x)-1] = struct{}{}
+	}
+	var list []Sequence
+	for i, v := range arr.values {
+		if _, ok := skip[i]; !ok {
+			list = append(list, v)
+		}
+	}
+	return Singleton(list), nil
+}
+
+func callHeadArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(arr.values) == 0 {
+		return nil, ErrEmpty
+	}
+	return slices.Clone(arr.values[0]), nil
+}
+
+func callTailArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(arr.values) == 0 {
+		return nil, ErrEmpty
+	}
+	return Singleton(slices.Clone(arr.values[1:])), nil
+}
+
+func callSubarrayArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	beg, err := getPositionFromExpr(args[1], ctx, len(arr.values)+1)
+	if err != nil {
+		return nil, err
+	}
+	end := len(arr.values)
+	if len(args) == 3 {
+		size, err := getIntFromExpr(args[2], ctx)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 || beg+int(size) > len(arr.values) {
+			return nil, ErrIndex
+		}
+		end = beg + int(size)
+	}
+	return Singleton(slices.Clone(arr.values[beg:end])), nil
+}
+
+func callReverseArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := slices.Clone(arr.values)
+	slices.Reverse(list)
+	return Singleton(list), nil
+}
+
+func callJoinArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	seq, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []Sequence{}
+	for _, item := range seq {
+		arr, ok := item.(arrayItem)
+		if !ok {
+			return nil, ErrType
+		}
+		list = append(list, arr.values...)
+	}
+	return Singleton(list), nil
+}
+
+func callFlattenArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 1 {
+		return nil, ErrArgument
+	}
+	seq, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var flatten func(Item) Sequence
+	flatten = func(item Item) Sequence {
+		arr, ok := item.(arrayItem)
+		if !ok {
+			return Singleton(item)
+		}
+		var seq Sequence
+		for _, v := range arr.values {
+			for _, i := range v {
+				seq.Concat(flatten(i))
+			}
+		}
+		return seq
+	}
+	var res Sequence
+	for _, item := range seq {
+		res.Concat(flatten(item))
+	}
+	return res, nil
+}
+
+func callForeachArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := getFuncFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []Sequence{}
+	for _, v := range arr.values {
+		res, err := fn.call([]Sequence{v})
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+	return Singleton(list), nil
+}
+
+func callForeachPairArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 3 {
+		return nil, ErrArgument
+	}
+	left, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := getArrayFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := getFuncFromExpr(args[2], ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []Sequence{}
+	for i := 0; i < len(left.values) && i < len(right.values); i++ {
+		res, err := fn.call([]Sequence{left.values[i], right.values[i]})
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, res)
+	}
+	return Singleton(list), nil
+}
+
+func callFilterArray(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) != 2 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := getFuncFromExpr(args[1], ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := []Sequence{}
+	for _, v := range arr.values {
+		res, err := fn.call([]Sequence{v})
+		if err != nil {
+			return nil, err
+		}
+		if res.True() {
+			list = append(list, v)
+		}
+	}
+	return Singleton(list), nil
+}
+
+func callFoldLeftArray(ctx Context, args []Expr) (Sequence, error) {
+	return foldArray(ctx, args, false)
+}
+
+func callFoldRightArray(ctx Context, args []Expr) (Sequence, error) {
+	return foldArray(ctx, args, true)
+}
+
+func foldArray(ctx Context, args []Expr, right bool) (Sequence, error) {
+	if len(args) != 3 {
+		return nil, ErrArgument
+	}
+	arr, err := getArrayFromExpr(args[0], ctx)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := args[1].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := getFuncFromExpr(args[2], ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := slices.Clone(arr.values)
+	if right {
+		slices.Reverse(list)
+	}
+	for _, v := range list {
+		params := []Sequence{acc, v}
+		if right {
+			params[0], params[1] = params[1], params[0]
+		}
+		if acc, err = fn.call(params); err != nil {
+			return nil, err
+		}
 	}
-	return Singleton(len(arr.values)), nil
+	return acc, nil
+}
+
+func sortedKeys(m mapItem) []Item {
+	keys := slices.Collect(maps.Keys(m.values))
+	slices.SortFunc(keys, func(a, b Item) int {
+		cmp, err := compareAtomic(a.Value(), b.Value())
+		if err != nil || cmp == incomparable {
+			return strings.Compare(fmt.Sprint(a.Value()), fmt.Sprint(b.Value()))
+		}
+		return cmp
+	})
+	return keys
 }
 
 func callConstructor(xt XdmType) BuiltinFunc {
@@ -1635,6 +3208,85 @@ func getFloatFromExpr(expr Expr, ctx Context) (float64, error) {
 	return toFloat(items[0].Value())
 }
 
+func getOptionalFloatFromExpr(expr Expr, ctx Context) (float64, bool, error) {
+	items, err := expr.find(ctx)
+	if err != nil || items.Empty() {
+		return 0, false, err
+	}
+	if !items.Singleton() {
+		return 0, false, ErrType
+	}
+	val, err := toFloat(items[0].Value())
+	return val, err == nil, err
+}
+
+func getMapFromExpr(expr Expr, ctx Context) (mapItem, error) {
+	items, err := expr.find(ctx)
+	if err != nil {
+		return mapItem{}, err
+	}
+	if !items.Singleton() {
+		return mapItem{}, ErrType
+	}
+	m, ok := items.First().(mapItem)
+	if !ok {
+		return mapItem{}, ErrType
+	}
+	return m, nil
+}
+
+func getArrayFromExpr(expr Expr, ctx Context) (arrayItem, error) {
+	items, err := expr.find(ctx)
+	if err != nil {
+		return arrayItem{}, err
+	}
+	if !items.Singleton() {
+		return arrayItem{}, ErrType
+	}
+	arr, ok := items.First().(arrayItem)
+	if !ok {
+		return arrayItem{}, ErrType
+	}
+	return arr, nil
+}
+
+func getFuncFromExpr(expr Expr, ctx Context) (funcItem, error) {
+	items, err := expr.find(ctx)
+	if err != nil {
+		return funcItem{}, err
+	}
+	if !items.Singleton() {
+		return funcItem{}, ErrType
+	}
+	fn, ok := items.First().(funcItem)
+	if !ok {
+		return funcItem{}, ErrType
+	}
+	return fn, nil
+}
+
+func getKeyFromExpr(expr Expr, ctx Context) (Item, error) {
+	items, err := expr.find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !items.Singleton() {
+		return nil, fmt.Errorf("map key can only be a single atomic value")
+	}
+	return mapKey(items.First()), nil
+}
+
+func getPositionFromExpr(expr Expr, ctx Context, size int) (int, error) {
+	pos, err := getIntFromExpr(expr, ctx)
+	if err != nil {
+		return 0, err
+	}
+	if pos < 1 || int(pos) > size {
+		return 0, ErrIndex
+	}
+	return int(pos) - 1, nil
+}
+
 func getIntFromExpr(expr Expr, ctx Context) (int64, error) {
 	items, err := expr.find(ctx)
 	if err != nil || !items.Singleton() {
@@ -1643,6 +3295,22 @@ func getIntFromExpr(expr Expr, ctx Context) (int64, error) {
 	return toInt(items[0].Value())
 }
 
+func getTimeFromExpr(expr Expr, ctx Context) (time.Time, bool, error) {
+	items, err := expr.find(ctx)
+	if err != nil || items.Empty() {
+		return time.Time{}, false, err
+	}
+	if !items.Singleton() {
+		return time.Time{}, false, ErrType
+	}
+	value := items.First().Value()
+	t, err := toTime(value)
+	if err != nil {
+		t, err = toDate(value)
+	}
+	return t, err == nil, err
+}
+
 func getStringFromExpr(expr Expr, ctx Context) (string, error) {
 	items, err := expr.find(ctx)
 	if err != nil {
@@ -0,0 +1,28 @@
+package xpath
+
+import (
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzCompile(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"/root/item[1]",
+		"//item[@id = 'foo']/text()",
+		"for $i in 1 to 10 return $i * 2",
+		"let $m := map{'foo': [1, 2]} return $m?foo?*",
+		"if (count(//item) > 2) then 'many' else 'few'",
+		"some $x in (1, 2, 3) satisfies $x eq 2",
+		"string-join(reverse(tokenize('a,b,c', ',')), '-')",
+		"(1, 2, 3)[. instance of xs:integer]",
+		"ancestor-or-self::*[last()]",
+		"[1, 2, [1, 2]](2)(0)",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			_, err := CompileString(string(data))
+			return err
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package xpath
+
+import (
+	"math/big"
+	"strings"
+)
+
+type Integer struct {
+	num *big.Int
+}
+
+func ParseInteger(str string) (any, error) {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(str), 10)
+	if !ok {
+		return nil, ErrCast
+	}
+	return createInteger(n), nil
+}
+
+func createInteger(n *big.Int) any {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return Integer{num: n}
+}
+
+func toBigInt(value any) (*big.Int, bool) {
+	switch v := value.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case Integer:
+		return new(big.Int).Set(v.get()), true
+	default:
+		return nil, false
+	}
+}
+
+func (i Integer) get() *big.Int {
+	if i.num == nil {
+		return new(big.Int)
+	}
+	return i.num
+}
+
+func (i Integer) Sign() int {
+	return i.get().Sign()
+}
+
+func (i Integer) Neg() Integer {
+	return Integer{num: new(big.Int).Neg(i.get())}
+}
+
+func (i Integer) Decimal() Decimal {
+	return Decimal{rat: new(big.Rat).SetInt(i.get())}
+}
+
+func (i Integer) Float64() float64 {
+	f, _ := new(big.Float).SetInt(i.get()).Float64()
+	return f
+}
+
+func (i Integer) String() string {
+	return i.get().String()
+}
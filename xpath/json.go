@@ -0,0 +1,297 @@
+package xpath
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/codecs/json"
+	"github.com/midbel/codecs/xml"
+)
+
+func callJsonToXml(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, nil
+	}
+	str, err := toString(items.First().Value())
+	if err != nil {
+		return nil, err
+	}
+	var (
+		liberal    bool
+		duplicates = jsonRetain
+	)
+	if len(args) == 2 {
+		opts, err := getJsonOptions(ctx, args[1])
+		if err != nil {
+			return nil, err
+		}
+		liberal, _ = opts["liberal"].(bool)
+		if str, ok := opts["duplicates"].(string); ok {
+			duplicates = str
+		}
+	}
+	switch duplicates {
+	case jsonReject, jsonUseFirst, jsonRetain:
+	default:
+		return nil, fmt.Errorf("%s: invalid value for duplicates option", duplicates)
+	}
+	var p *json.Parser
+	if liberal {
+		p = json.NewParser5(strings.NewReader(str))
+	} else {
+		p = json.NewParser(strings.NewReader(str))
+	}
+	p.KeepOrder = true
+	value, err := p.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("json-to-xml: %w", err)
+	}
+	root, err := jsonToXml(value, duplicates)
+	if err != nil {
+		return nil, err
+	}
+	root.SetAttribute(xml.NewAttribute(xml.LocalName(xml.AttrXmlNS), functionNS))
+	return Singleton(xml.NewDocument(root)), nil
+}
+
+func callXmlToJson(ctx Context, args []Expr) (Sequence, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, ErrArgument
+	}
+	items, err := args[0].find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, nil
+	}
+	node := items.First().Node()
+	if node == nil {
+		return nil, ErrType
+	}
+	var indent bool
+	if len(args) == 2 {
+		opts, err := getJsonOptions(ctx, args[1])
+		if err != nil {
+			return nil, err
+		}
+		indent, _ = opts["indent"].(bool)
+	}
+	value, err := XmlToJson(node)
+	if err != nil {
+		return nil, fmt.Errorf("xml-to-json: %w", err)
+	}
+	var (
+		str strings.Builder
+		ws  = json.NewWriter(&str)
+	)
+	ws.Compact = !indent
+	if err := ws.Write(value); err != nil {
+		return nil, err
+	}
+	return Singleton(str.String()), nil
+}
+
+func getJsonOptions(ctx Context, expr Expr) (map[any]any, error) {
+	items, err := expr.find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, nil
+	}
+	opts, ok := items.First().Value().(map[any]any)
+	if !ok {
+		return nil, ErrType
+	}
+	return opts, nil
+}
+
+// values of the duplicates option of json-to-xml.
+const (
+	jsonReject   = "reject"
+	jsonUseFirst = "use-first"
+	jsonRetain   = "retain"
+)
+
+// JsonToXml converts a decoded json value to its xml representation. Objects
+// given as json.Object keep the order of their fields, including duplicates.
+func JsonToXml(value any) *xml.Element {
+	el, _ := jsonToXml(value, jsonRetain)
+	return el
+}
+
+func jsonToXml(value any, duplicates string) (*xml.Element, error) {
+	var el *xml.Element
+	switch v := value.(type) {
+	case map[string]any:
+		el = xml.NewElement(xml.ExpandedName("map", "", functionNS))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			child, err := jsonToXml(v[k], duplicates)
+			if err != nil {
+				return nil, err
+			}
+			child.SetAttribute(xml.NewAttribute(xml.LocalName("key"), k))
+			el.Append(child)
+		}
+	case json.Object:
+		el = xml.NewElement(xml.ExpandedName("map", "", functionNS))
+		seen := make(map[string]struct{})
+		for _, f := range v {
+			if _, ok := seen[f.Key]; ok {
+				if duplicates == jsonReject {
+					return nil, fmt.Errorf("%s: duplicate key in object", f.Key)
+				}
+				if duplicates == jsonUseFirst {
+					continue
+				}
+			}
+			seen[f.Key] = struct{}{}
+			child, err := jsonToXml(f.Value, duplicates)
+			if err != nil {
+				return nil, err
+			}
+			child.SetAttribute(xml.NewAttribute(xml.LocalName("key"), f.Key))
+			el.Append(child)
+		}
+	case []any:
+		el = xml.NewElement(xml.ExpandedName("array", "", functionNS))
+		for i := range v {
+			child, err := jsonToXml(v[i], duplicates)
+			if err != nil {
+				return nil, err
+			}
+			el.Append(child)
+		}
+	case string:
+		el = xml.NewElement(xml.ExpandedName("string", "", functionNS))
+		el.Append(xml.NewText(v))
+	case float64:
+		el = xml.NewElement(xml.ExpandedName("number", "", functionNS))
+		el.Append(xml.NewText(strconv.FormatFloat(v, 'f', -1, 64)))
+	case bool:
+		el = xml.NewElement(xml.ExpandedName("boolean", "", functionNS))
+		el.Append(xml.NewText(strconv.FormatBool(v)))
+	default:
+		el = xml.NewElement(xml.ExpandedName("null", "", functionNS))
+	}
+	return el, nil
+}
+
+func XmlToJson(node xml.Node) (any, error) {
+	if doc, ok := node.(*xml.Document); ok {
+		node = doc.Root()
+	}
+	el, ok := node.(*xml.Element)
+	if !ok || el.Uri != functionNS {
+		return nil, fmt.Errorf("%s: %w: element in %s namespace expected", node.QualifiedName(), ErrType, functionNS)
+	}
+	switch el.LocalName() {
+	case "map":
+		return xmlToJsonObject(el)
+	case "array":
+		return xmlToJsonArray(el)
+	case "string":
+		return el.Value(), nil
+	case "number":
+		n, err := strconv.ParseFloat(strings.TrimSpace(el.Value()), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid number", el.Value())
+		}
+		return n, nil
+	case "boolean":
+		switch str := strings.TrimSpace(el.Value()); str {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		default:
+			return nil, fmt.Errorf("%s: invalid boolean", str)
+		}
+	case "null":
+		if strings.TrimSpace(el.Value()) != "" {
+			return nil, fmt.Errorf("null element can not have content")
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported element", el.QualifiedName())
+	}
+}
+
+type jsonField struct {
+	key   string
+	value any
+}
+
+func xmlToJsonObject(el *xml.Element) (any, error) {
+	children, err := xmlToJsonChildren(el)
+	if err != nil {
+		return nil, err
+	}
+	var fields []jsonField
+	for _, c := range children {
+		key, ok := c.GetAttribute("key")
+		if !ok {
+			return nil, fmt.Errorf("%s: missing key attribute", c.QualifiedName())
+		}
+		if slices.ContainsFunc(fields, func(f jsonField) bool { return f.key == key }) {
+			return nil, fmt.Errorf("%s: duplicate key in map", key)
+		}
+		value, err := XmlToJson(c)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: key, value: value})
+	}
+	seq := func(yield func(string, any) bool) {
+		for _, f := range fields {
+			if !yield(f.key, f.value) {
+				return
+			}
+		}
+	}
+	return iter.Seq2[string, any](seq), nil
+}
+
+func xmlToJsonArray(el *xml.Element) (any, error) {
+	children, err := xmlToJsonChildren(el)
+	if err != nil {
+		return nil, err
+	}
+	list := []any{}
+	for _, c := range children {
+		value, err := XmlToJson(c)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+func xmlToJsonChildren(el *xml.Element) ([]*xml.Element, error) {
+	var list []*xml.Element
+	for _, n := range el.Nodes {
+		switch n := n.(type) {
+		case *xml.Element:
+			list = append(list, n)
+		case *xml.Text:
+			if strings.TrimSpace(n.Content) != "" {
+				return nil, fmt.Errorf("%s: unexpected text content", el.QualifiedName())
+			}
+		default:
+		}
+	}
+	return list, nil
+}
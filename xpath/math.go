@@ -2,6 +2,8 @@ package xpath
 
 import (
 	"math"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/midbel/codecs/xml"
@@ -14,6 +16,7 @@ var binaryOp = map[rune]BinaryFunc{
 	opSub:    doSub,
 	opMul:    doMul,
 	opDiv:    doDiv,
+	opIdiv:   doIdiv,
 	opMod:    doMod,
 	opConcat: doConcat,
 	opAnd:    doAnd,
@@ -34,39 +37,112 @@ var binaryOp = map[rune]BinaryFunc{
 	opValGe:  doGreatEq,
 }
 
+type arithmetic struct {
+	integer  func(left, right *big.Int) (any, error)
+	decimal  func(left, right Decimal) (any, error)
+	double   func(left, right float64) (any, error)
+	temporal func(left, right any) (any, error)
+}
+
 func doAdd(left, right Sequence) (Sequence, error) {
-	return apply(left, right, func(left, right float64) (float64, error) {
-		return left + right, nil
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			return createInteger(left.Add(left, right)), nil
+		},
+		decimal: func(left, right Decimal) (any, error) {
+			return left.Add(right), nil
+		},
+		double: func(left, right float64) (any, error) {
+			return left + right, nil
+		},
+		temporal: addTemporal,
 	})
 }
 
 func doSub(left, right Sequence) (Sequence, error) {
-	return apply(left, right, func(left, right float64) (float64, error) {
-		return left - right, nil
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			return createInteger(left.Sub(left, right)), nil
+		},
+		decimal: func(left, right Decimal) (any, error) {
+			return left.Sub(right), nil
+		},
+		double: func(left, right float64) (any, error) {
+			return left - right, nil
+		},
+		temporal: subTemporal,
 	})
 }
 
 func doMul(left, right Sequence) (Sequence, error) {
-	return apply(left, right, func(left, right float64) (float64, error) {
-		return left * right, nil
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			return createInteger(left.Mul(left, right)), nil
+		},
+		decimal: func(left, right Decimal) (any, error) {
+			return left.Mul(right), nil
+		},
+		double: func(left, right float64) (any, error) {
+			return left * right, nil
+		},
+		temporal: mulTemporal,
 	})
 }
 
 func doDiv(left, right Sequence) (Sequence, error) {
-	return apply(left, right, func(left, right float64) (float64, error) {
-		if right == 0 {
-			return 0, ErrZero
-		}
-		return left / right, nil
+	div := func(left, right Decimal) (any, error) {
+		return left.Div(right)
+	}
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			return div(Integer{num: left}.Decimal(), Integer{num: right}.Decimal())
+		},
+		decimal: div,
+		double: func(left, right float64) (any, error) {
+			return left / right, nil
+		},
+		temporal: divTemporal,
+	})
+}
+
+func doIdiv(left, right Sequence) (Sequence, error) {
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			if right.Sign() == 0 {
+				return nil, ErrZero
+			}
+			return createInteger(left.Quo(left, right)), nil
+		},
+		decimal: func(left, right Decimal) (any, error) {
+			return left.IDiv(right)
+		},
+		double: func(left, right float64) (any, error) {
+			if right == 0 {
+				return nil, ErrZero
+			}
+			res := math.Trunc(left / right)
+			if math.IsNaN(res) || math.IsInf(res, 0) {
+				return nil, ErrCast
+			}
+			return int64(res), nil
+		},
 	})
 }
 
 func doMod(left, right Sequence) (Sequence, error) {
-	return apply(left, right, func(left, right float64) (float64, error) {
-		if right == 0 {
-			return 0, ErrZero
-		}
-		return math.Mod(left, right), nil
+	return apply(left, right, arithmetic{
+		integer: func(left, right *big.Int) (any, error) {
+			if right.Sign() == 0 {
+				return nil, ErrZero
+			}
+			return createInteger(left.Rem(left, right)), nil
+		},
+		decimal: func(left, right Decimal) (any, error) {
+			return left.Mod(right)
+		},
+		double: func(left, right float64) (any, error) {
+			return math.Mod(left, right), nil
+		},
 	})
 }
 
@@ -108,94 +184,207 @@ func doAfter(left, right Sequence) (Sequence, error) {
 }
 
 func doEqual(left, right Sequence) (Sequence, error) {
-	res, err := isEqual(left, right)
+	if ok, done := equalStrings(left, right); done {
+		return Singleton(ok), nil
+	}
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c == 0
+	})
 	return Singleton(res), err
 }
 
 func doNotEqual(left, right Sequence) (Sequence, error) {
-	res, err := isEqual(left, right)
-	if err != nil {
-		return nil, err
-	}
-	return Singleton(!res), nil
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c != 0
+	})
+	return Singleton(res), err
 }
 
 func doLesser(left, right Sequence) (Sequence, error) {
-	res, err := isLess(left, right)
-	if err != nil {
-		return nil, err
-	}
-	return Singleton(res), nil
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c == -1
+	})
+	return Singleton(res), err
 }
 
 func doLessEq(left, right Sequence) (Sequence, error) {
-	ok, err := isEqual(left, right)
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c == -1 || c == 0
+	})
+	return Singleton(res), err
+}
+
+func doGreater(left, right Sequence) (Sequence, error) {
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c == 1
+	})
+	return Singleton(res), err
+}
+
+func doGreatEq(left, right Sequence) (Sequence, error) {
+	res, err := generalCompare(left, right, func(c int) bool {
+		return c == 1 || c == 0
+	})
+	return Singleton(res), err
+}
+
+func apply(left, right Sequence, op arithmetic) (Sequence, error) {
+	if left.Empty() || right.Empty() {
+		return Singleton(math.NaN()), nil
+	}
+	var res Sequence
+	for i := range left {
+		for j := range right {
+			v, err := op.apply(left[i].Value(), right[j].Value())
+			if err != nil {
+				return nil, err
+			}
+			res.Append(NewLiteralItem(v))
+		}
+	}
+	return res, nil
+}
+
+func (a arithmetic) apply(left, right any) (any, error) {
+	if a.temporal != nil && isTemporalValue(left, right) {
+		return a.temporal(left, right)
+	}
+	if x, ok := toBigInt(left); ok {
+		if y, ok := toBigInt(right); ok {
+			return a.integer(x, y)
+		}
+	}
+	if isDecimalValue(left) && isDecimalValue(right) {
+		x, _ := toDecimal(left)
+		y, _ := toDecimal(right)
+		return a.decimal(x, y)
+	}
+	x, err := toFloat(left)
 	if err != nil {
 		return nil, err
 	}
-	if ok {
-		return Singleton(ok), nil
-	}
-	ok, err = isLess(left, right)
+	y, err := toFloat(right)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(ok), nil
+	res, err := a.double(x, y)
+	if f, ok := res.(float64); ok && isFloatValue(left, right) {
+		return float32(f), err
+	}
+	return res, err
 }
 
-func doGreater(left, right Sequence) (Sequence, error) {
-	ok, err := isEqual(left, right)
-	if err != nil {
-		return nil, err
+func addTemporal(left, right any) (any, error) {
+	switch {
+	case isTimeValue(left) && isTimeValue(right):
+		return nil, ErrType
+	case isTimeValue(left):
+		return shiftTime(left, right, false)
+	case isTimeValue(right):
+		return shiftTime(right, left, false)
+	default:
+		x, y, err := getDurations(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return x.add(y), nil
 	}
-	if ok {
-		return Singleton(false), nil
+}
+
+func subTemporal(left, right any) (any, error) {
+	switch {
+	case isTimeValue(left) && isTimeValue(right), isTimeValue(right) && !isDurationValue(left):
+		x, err := toTime(left)
+		if err != nil {
+			return nil, err
+		}
+		y, err := toTime(right)
+		if err != nil {
+			return nil, err
+		}
+		return createDayTimeDuration(x.Sub(y)), nil
+	case isTimeValue(left):
+		return shiftTime(left, right, true)
+	case isTimeValue(right):
+		return nil, ErrType
+	default:
+		x, y, err := getDurations(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return x.add(y.negate()), nil
+	}
+}
+
+func mulTemporal(left, right any) (any, error) {
+	if !isDurationValue(left) {
+		left, right = right, left
 	}
-	ok, err = isLess(left, right)
+	d, err := toDuration(left)
 	if err != nil {
-		return nil, err
+		return nil, ErrType
+	}
+	f, err := toFloat(right)
+	if err != nil {
+		return nil, ErrType
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrCast
 	}
-	return Singleton(!ok), nil
+	return d.scale(f), nil
 }
 
-func doGreatEq(left, right Sequence) (Sequence, error) {
-	ok, err := isEqual(left, right)
+func divTemporal(left, right any) (any, error) {
+	d, err := toDuration(left)
 	if err != nil {
-		return nil, err
+		return nil, ErrType
 	}
-	if ok {
-		return Singleton(ok), nil
+	if isDurationValue(right) {
+		other, _ := toDuration(right)
+		switch {
+		case other.Months != 0:
+			return float64(d.Months) / float64(other.Months), nil
+		case other.DayTime != 0:
+			return float64(d.DayTime) / float64(other.DayTime), nil
+		default:
+			return nil, ErrZero
+		}
 	}
-	ok, err = isLess(left, right)
+	f, err := toFloat(right)
+	if err != nil {
+		return nil, ErrType
+	}
+	if f == 0 || math.IsNaN(f) {
+		return nil, ErrZero
+	}
+	return d.scale(1 / f), nil
+}
+
+func shiftTime(value, dur any, neg bool) (any, error) {
+	t, err := toTime(value)
 	if err != nil {
 		return nil, err
 	}
-	return Singleton(!ok), nil
+	d, err := toDuration(dur)
+	if err != nil {
+		return nil, ErrType
+	}
+	if neg {
+		d = d.negate()
+	}
+	return d.addTo(t), nil
 }
 
-func apply(left, right Sequence, do func(left, right float64) (float64, error)) (Sequence, error) {
-	if left.Empty() || right.Empty() {
-		return Singleton(math.NaN()), nil
+func getDurations(left, right any) (Duration, Duration, error) {
+	x, err := toDuration(left)
+	if err != nil {
+		return x, x, ErrType
 	}
-	var res Sequence
-	for i := range left {
-		x, err := toFloat(left[i].Value())
-		if err != nil {
-			return nil, err
-		}
-		for j := range right {
-			y, err := toFloat(right[j].Value())
-			if err != nil {
-				return nil, err
-			}
-			v, err := do(x, y)
-			if err != nil {
-				return nil, err
-			}
-			res.Append(NewLiteralItem(v))
-		}
+	y, err := toDuration(right)
+	if err != nil {
+		return x, y, ErrType
 	}
-	return res, nil
+	return x, y, nil
 }
 
 func compareItems(left, right Sequence, cmp func(left, right Item) (bool, error)) (bool, error) {
@@ -213,43 +402,149 @@ func compareItems(left, right Sequence, cmp func(left, right Item) (bool, error)
 	return false, nil
 }
 
-func isLess(left, right Sequence) (bool, error) {
+const incomparable = 2
+
+func generalCompare(left, right Sequence, accept func(int) bool) (bool, error) {
 	return compareItems(left, right, func(left, right Item) (bool, error) {
-		switch x := left.Value().(type) {
-		case float64:
-			y, err := toFloat(right.Value())
-			return x < y, err
-		case string:
-			y, err := toString(right.Value())
-			return x < y, err
-		case time.Time:
-			y, err := toTime(right.Value())
-			return x.Before(y), err
-		default:
-			return false, ErrType
+		c, err := compareAtomic(left.Value(), right.Value())
+		if err != nil {
+			return false, err
 		}
+		return accept(c), nil
 	})
-
 }
 
-func isEqual(left, right Sequence) (bool, error) {
-	return compareItems(left, right, func(left, right Item) (bool, error) {
-		switch x := left.Value().(type) {
-		case float64:
-			y, err := toFloat(right.Value())
-			return nearlyEqual(x, y), err
-		case string:
-			y, err := toString(right.Value())
-			return x == y, err
-		case bool:
-			return toBool(right.Value())
-		case time.Time:
-			y, err := toTime(right.Value())
-			return x.Equal(y), err
+func compareAtomic(left, right any) (int, error) {
+	switch {
+	case isBoolValue(left) || isBoolValue(right):
+		x, err1 := toBool(left)
+		y, err2 := toBool(right)
+		if err1 != nil || err2 != nil {
+			return incomparable, nil
+		}
+		switch {
+		case x == y:
+			return 0, nil
+		case !x:
+			return -1, nil
 		default:
-			return false, ErrType
+			return 1, nil
 		}
-	})
+	case isDecimalValue(left) && isDecimalValue(right):
+		x, _ := toDecimal(left)
+		y, _ := toDecimal(right)
+		return x.Cmp(y), nil
+	case isNumberValue(left) || isNumberValue(right):
+		x, err1 := toFloat(left)
+		y, err2 := toFloat(right)
+		if err1 != nil || err2 != nil || math.IsNaN(x) || math.IsNaN(y) {
+			return incomparable, nil
+		}
+		switch {
+		case nearlyEqual(x, y):
+			return 0, nil
+		case x < y:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	case isTimeValue(left) || isTimeValue(right):
+		x, err1 := toTime(left)
+		y, err2 := toTime(right)
+		if err1 != nil || err2 != nil {
+			return incomparable, nil
+		}
+		return x.Compare(y), nil
+	case isDurationValue(left) || isDurationValue(right):
+		x, err1 := toDuration(left)
+		y, err2 := toDuration(right)
+		if err1 != nil || err2 != nil {
+			return incomparable, nil
+		}
+		return x.Compare(y), nil
+	default:
+		x, err := toString(left)
+		if err != nil {
+			return 0, ErrType
+		}
+		y, err := toString(right)
+		if err != nil {
+			return 0, ErrType
+		}
+		return strings.Compare(x, y), nil
+	}
+}
+
+func equalStrings(left, right Sequence) (bool, bool) {
+	if len(left)*len(right) < 64 {
+		return false, false
+	}
+	set := make(map[string]struct{})
+	for i := range right {
+		str, ok := right[i].Value().(string)
+		if !ok {
+			return false, false
+		}
+		set[str] = struct{}{}
+	}
+	for i := range left {
+		str, ok := left[i].Value().(string)
+		if !ok {
+			return false, false
+		}
+		if _, ok := set[str]; ok {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func isBoolValue(value any) bool {
+	_, ok := value.(bool)
+	return ok
+}
+
+func isNumberValue(value any) bool {
+	switch value.(type) {
+	case float64, float32, int64, int, Integer, Decimal:
+		return true
+	default:
+		return false
+	}
+}
+
+func isDecimalValue(value any) bool {
+	switch value.(type) {
+	case int64, Integer, Decimal:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFloatValue(left, right any) bool {
+	_, x := left.(float32)
+	_, y := right.(float32)
+	if !x && !y {
+		return false
+	}
+	_, x = left.(float64)
+	_, y = right.(float64)
+	return !x && !y
+}
+
+func isTimeValue(value any) bool {
+	_, ok := value.(time.Time)
+	return ok
+}
+
+func isDurationValue(value any) bool {
+	_, ok := value.(Duration)
+	return ok
+}
+
+func isTemporalValue(left, right any) bool {
+	return isTimeValue(left) || isTimeValue(right) || isDurationValue(left) || isDurationValue(right)
 }
 
 func nearlyEqual(left, right float64) bool {
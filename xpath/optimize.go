@@ -0,0 +1,151 @@
+package xpath
+
+import (
+	"slices"
+)
+
+var pureFunctions = []string{
+	"concat",
+	"true",
+	"false",
+	"not",
+	"string-length",
+	"upper-case",
+	"lower-case",
+	"normalize-space",
+	"string-join",
+}
+
+func optimize(expr Expr) Expr {
+	switch e := expr.(type) {
+	case query:
+		e.expr = optimize(e.expr)
+		return e
+	case binary:
+		return optimizeBinary(e)
+	case reverse:
+		e.expr = optimize(e.expr)
+		switch n := e.expr.(type) {
+		case number:
+			return number{expr: -n.expr}
+		case integer:
+			return integer{expr: -n.expr}
+		case decimal:
+			return decimal{expr: n.expr.Neg()}
+		default:
+			return e
+		}
+	case call:
+		return optimizeCall(e)
+	case conditional:
+		e.test = optimize(e.test)
+		e.csq = optimize(e.csq)
+		e.alt = optimize(e.alt)
+		if ok, isConst := constantBool(e.test); isConst {
+			if ok {
+				return e.csq
+			}
+			return e.alt
+		}
+		return e
+	case sequence:
+		e.all = slices.Clone(e.all)
+		for i := range e.all {
+			e.all[i] = optimize(e.all[i])
+		}
+		if len(e.all) == 1 && isConstant(e.all[0]) {
+			return e.all[0]
+		}
+		return e
+	case filter:
+		e.expr = optimize(e.expr)
+		e.check = optimize(e.check)
+		return e
+	case step:
+		e.curr = optimize(e.curr)
+		e.next = optimize(e.next)
+		return e
+	default:
+		return expr
+	}
+}
+
+func optimizeBinary(b binary) Expr {
+	b.left = optimize(b.left)
+	b.right = optimize(b.right)
+	switch b.op {
+	case opAnd:
+		if ok, isConst := constantBool(b.left); isConst && !ok {
+			return boolean{expr: false}
+		}
+	case opOr:
+		if ok, isConst := constantBool(b.left); isConst && ok {
+			return boolean{expr: true}
+		}
+	default:
+	}
+	if !isConstant(b.left) || !isConstant(b.right) {
+		return b
+	}
+	return fold(b)
+}
+
+func optimizeCall(c call) Expr {
+	c.args = slices.Clone(c.args)
+	for i := range c.args {
+		c.args[i] = optimize(c.args[i])
+	}
+	if c.Uri != functionNS || !slices.Contains(pureFunctions, c.Name) {
+		return c
+	}
+	for i := range c.args {
+		if !isConstant(c.args[i]) {
+			return c
+		}
+	}
+	return fold(c)
+}
+
+func fold(expr Expr) Expr {
+	seq, err := expr.find(defaultContext(nil))
+	if err != nil || len(seq) != 1 {
+		return expr
+	}
+	if _, ok := seq[0].(literalItem); !ok {
+		return expr
+	}
+	switch v := seq[0].Value().(type) {
+	case string:
+		return literal{expr: v}
+	case float64:
+		return number{expr: v}
+	case int64:
+		return integer{expr: v}
+	case Decimal:
+		return decimal{expr: v}
+	case bool:
+		return boolean{expr: v}
+	default:
+		return expr
+	}
+}
+
+func isConstant(expr Expr) bool {
+	switch expr.(type) {
+	case literal, number, integer, decimal, boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+func constantBool(expr Expr) (bool, bool) {
+	if !isConstant(expr) {
+		return false, false
+	}
+	seq, err := expr.find(defaultContext(nil))
+	if err != nil {
+		return false, false
+	}
+	return seq.True(), true
+}
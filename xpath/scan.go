@@ -33,14 +33,17 @@ const (
 	kwOr        = "or"
 	kwDiv       = "div"
 	kwMod       = "mod"
+	kwIdiv      = "idiv"
 	kwAs        = "as"
 	kwIs        = "is"
 	kwCast      = "cast"
 	kwCastable  = "castable"
 	kwInstance  = "instance"
+	kwTreat     = "treat"
 	kwOf        = "of"
 	kwMap       = "map"
 	kwArray     = "array"
+	kwFunction  = "function"
 	kwEq        = "eq"
 	kwNe        = "ne"
 	kwLt        = "lt"
@@ -63,6 +66,7 @@ func isReserved(str string) bool {
 	case kwSatisfies:
 	case kwMap:
 	case kwArray:
+	case kwFunction:
 	default:
 		return false
 	}
@@ -105,6 +109,7 @@ const (
 	opSub
 	opMul
 	opDiv
+	opIdiv
 	opMod
 	opValEq
 	opValNe
@@ -127,6 +132,7 @@ const (
 	opSeq
 	opAxis
 	opInstanceOf
+	opTreatAs
 	opCastAs
 	opCastableAs
 )
@@ -147,6 +153,8 @@ func (t Token) String() string {
 		return "<castable-as>"
 	case opInstanceOf:
 		return "<instance-of>"
+	case opTreatAs:
+		return "<treat-as>"
 	case opIs:
 		return "<identity>"
 	case opIntersect:
@@ -189,6 +197,8 @@ func (t Token) String() string {
 		return "<multiply>"
 	case opDiv:
 		return "<divide>"
+	case opIdiv:
+		return "<integer-divide>"
 	case opMod:
 		return "<modulo>"
 	case opAssign:
@@ -446,27 +456,33 @@ func (s *Scanner) scanLiteral(tok *Token) {
 
 func (s *Scanner) scanAttr(tok *Token) {
 	s.read()
-	s.scanIdent(tok)
+	if s.char == star {
+		s.write()
+		s.read()
+		tok.Literal = s.str.String()
+	} else {
+		s.scanIdent(tok)
+	}
 	tok.Type = attrNode
 }
 
 func (s *Scanner) scanNumber(tok *Token) {
+	defer func() {
+		tok.Type = Digit
+		tok.Literal = s.str.String()
+	}()
 	for !s.done() && unicode.IsDigit(s.char) {
 		s.write()
 		s.read()
 	}
-	tok.Type = Digit
-	tok.Literal = s.str.String()
-	if s.char != dot {
-		return
-	}
-	s.write()
-	s.read()
-	for !s.done() && unicode.IsDigit(s.char) {
+	if s.char == dot {
 		s.write()
 		s.read()
+		for !s.done() && unicode.IsDigit(s.char) {
+			s.write()
+			s.read()
+		}
 	}
-	tok.Literal = s.str.String()
 	if s.char != 'e' && s.char != 'E' {
 		return
 	}
@@ -519,6 +535,8 @@ func (s *Scanner) scanIdent(tok *Token) {
 		tok.Type = opRange
 	case kwDiv:
 		tok.Type = opDiv
+	case kwIdiv:
+		tok.Type = opIdiv
 	case kwMod:
 		tok.Type = opMod
 	case kwEq:
@@ -551,6 +569,12 @@ func (s *Scanner) scanIdent(tok *Token) {
 		if ok {
 			tok.Type = opInstanceOf
 		}
+	case kwTreat:
+		tok.Type = Name
+		ok := s.lookForward("as")
+		if ok {
+			tok.Type = opTreatAs
+		}
 	default:
 		if isReserved(tok.Literal) {
 			tok.Type = reserved
@@ -36,9 +36,17 @@ func Singleton(value any) Sequence {
 		item = value
 	case nodeItem:
 		item = value
-	case []Item:
+	case mapItem:
+		item = value
+	case arrayItem:
+		item = value
+	case indexItem:
+		item = value
+	case funcItem:
+		item = value
+	case []Sequence:
 		item = createArray(value)
-	case map[Item]Item:
+	case map[Item]Sequence:
 		item = createMap(value)
 	default:
 		item = createLiteral(value)
@@ -107,6 +115,57 @@ func (s *Sequence) Atomize() ([]string, error) {
 	return list, nil
 }
 
+func (s *Sequence) Strings() ([]string, error) {
+	return convertSequence(*s, toString)
+}
+
+func (s *Sequence) Floats() ([]float64, error) {
+	return convertSequence(*s, toFloat)
+}
+
+func (s *Sequence) Bools() ([]bool, error) {
+	return convertSequence(*s, toBool)
+}
+
+func (s *Sequence) NodesOfType(kind xml.NodeType) []xml.Node {
+	var list []xml.Node
+	for _, i := range *s {
+		if i.Atomic() {
+			continue
+		}
+		n, ok := i.(nodeItem)
+		if !ok || n.Node().Type()&kind == 0 {
+			continue
+		}
+		list = append(list, n.Node())
+	}
+	return list
+}
+
+func (s *Sequence) AtomizeAll() (Sequence, error) {
+	var seq Sequence
+	for j, i := range *s {
+		switch i := i.(type) {
+		case nodeItem:
+			seq.Append(createLiteral(i.Value()))
+		case arrayItem:
+			arr := i.Sequence()
+			others, err := arr.AtomizeAll()
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", j+1, err)
+			}
+			seq.Concat(others)
+		case mapItem:
+			return nil, fmt.Errorf("item %d: map can not be atomized", j+1)
+		case funcItem:
+			return nil, fmt.Errorf("item %d: function can not be atomized", j+1)
+		default:
+			seq.Append(i)
+		}
+	}
+	return seq, nil
+}
+
 func (s *Sequence) Every(test func(i Item) bool) bool {
 	for i := range *s {
 		if !test((*s)[i]) {
@@ -145,6 +204,12 @@ func (s *Sequence) Compare(other *Sequence) int {
 				break
 			}
 			res += int(v1 - s)
+		case Decimal:
+			s, ok := v2.Value().(Decimal)
+			if !ok {
+				break
+			}
+			res += v1.Cmp(s)
 		case time.Time:
 		case bool:
 			s, ok := v2.Value().(bool)
@@ -180,6 +245,14 @@ func (s *Sequence) CanonicalizeString() string {
 			str.WriteString("float(")
 			str.WriteString(strconv.FormatFloat(x, 'f', -1, 64))
 			str.WriteString(")")
+		case int64:
+			str.WriteString("int(")
+			str.WriteString(strconv.FormatInt(x, 10))
+			str.WriteString(")")
+		case Decimal:
+			str.WriteString("decimal(")
+			str.WriteString(x.String())
+			str.WriteString(")")
 		case bool:
 			str.WriteString("bool(")
 			str.WriteString(strconv.FormatBool(x))
@@ -206,6 +279,8 @@ func EffectiveBooleanValue(seq Sequence) bool {
 			return x != 0 && !math.IsNaN(x)
 		case int64:
 			return x != 0
+		case Decimal:
+			return x.Sign() != 0
 		case bool:
 			return x
 		default:
@@ -240,6 +315,72 @@ func atomicItem(item Item) (Item, error) {
 	return createLiteral(n.Value()), nil
 }
 
+func NewSequenceFromValue(value any) (Sequence, error) {
+	switch v := value.(type) {
+	case Sequence:
+		return slices.Clone(v), nil
+	case []any:
+		var seq Sequence
+		for i := range v {
+			item, err := NewItemFromValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			seq.Append(item)
+		}
+		return seq, nil
+	case []xml.Node:
+		var seq Sequence
+		for i := range v {
+			seq.Append(createNode(v[i]))
+		}
+		return seq, nil
+	default:
+		item, err := NewItemFromValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return Singleton(item), nil
+	}
+}
+
+func NewItemFromValue(value any) (Item, error) {
+	switch v := value.(type) {
+	case Item:
+		return v, nil
+	case xml.Node:
+		return createNode(v), nil
+	case string, float64, int64, bool, time.Time, Duration, Decimal, Integer:
+		return createLiteral(v), nil
+	case int:
+		return createLiteral(int64(v)), nil
+	case float32:
+		return createLiteral(float64(v)), nil
+	case []any:
+		list := make([]Sequence, 0, len(v))
+		for i := range v {
+			item, err := NewItemFromValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, Singleton(item))
+		}
+		return createArray(list), nil
+	case map[string]any:
+		values := make(map[Item]Sequence)
+		for k := range v {
+			item, err := NewItemFromValue(v[k])
+			if err != nil {
+				return nil, err
+			}
+			values[createLiteral(k)] = Singleton(item)
+		}
+		return createMap(values), nil
+	default:
+		return nil, fmt.Errorf("%w: %T can not be converted to item", ErrType, value)
+	}
+}
+
 type literalItem struct {
 	value any
 }
@@ -258,6 +399,10 @@ func createLiteral(value any) Item {
 		v = value
 	case number:
 		v = e.expr
+	case integer:
+		v = e.expr
+	case decimal:
+		v = e.expr
 	case literal:
 		v = e.expr
 	}
@@ -279,15 +424,23 @@ func (i literalItem) True() bool {
 	case []byte:
 		return len(v) != 0
 	case float64:
-		return v != 0
+		return v != 0 && !math.IsNaN(v)
+	case float32:
+		return v != 0 && !math.IsNaN(float64(v))
 	case int64:
 		return v != 0
+	case Integer:
+		return v.Sign() != 0
+	case Decimal:
+		return v.Sign() != 0
 	case string:
 		return v != ""
 	case bool:
 		return v
 	case time.Time:
 		return !v.IsZero()
+	case Duration:
+		return v.Months != 0 || v.DayTime != 0
 	default:
 		return false
 	}
@@ -307,10 +460,10 @@ func (i literalItem) Assert(_ Expr, _ environ.Environ[Expr]) (Sequence, error) {
 }
 
 type mapItem struct {
-	values map[Item]Item
+	values map[Item]Sequence
 }
 
-func createMap(vs map[Item]Item) Item {
+func createMap(vs map[Item]Sequence) Item {
 	return mapItem{
 		values: maps.Clone(vs),
 	}
@@ -328,7 +481,7 @@ func (i mapItem) Node() xml.Node {
 func (i mapItem) Value() any {
 	list := make(map[any]any)
 	for k, v := range i.values {
-		list[k.Value()] = v.Value()
+		list[k.Value()] = memberValue(v)
 	}
 	return list
 }
@@ -338,7 +491,7 @@ func (i mapItem) True() bool {
 		return false
 	}
 	for j := range i.values {
-		if !i.values[j].True() {
+		if !EffectiveBooleanValue(i.values[j]) {
 			return false
 		}
 	}
@@ -349,11 +502,58 @@ func (i mapItem) Atomic() bool {
 	return false
 }
 
+func mapKey(item Item) Item {
+	switch v := item.Value().(type) {
+	case int64:
+		return createLiteral(float64(v))
+	case int:
+		return createLiteral(float64(v))
+	case Decimal:
+		return createLiteral(v.Float64())
+	case Integer:
+		return createLiteral(v.Float64())
+	case float32:
+		return createLiteral(float64(v))
+	case float64, string, bool, time.Time, Duration:
+		return createLiteral(v)
+	default:
+		return createLiteral(fmt.Sprint(v))
+	}
+}
+
+type indexItem struct {
+	values map[Item]Sequence
+}
+
+func (i indexItem) Node() xml.Node {
+	return nil
+}
+
+func (i indexItem) Value() any {
+	list := make(map[any]any)
+	for k, vs := range i.values {
+		var arr []any
+		for _, v := range vs {
+			arr = append(arr, v.Value())
+		}
+		list[k.Value()] = arr
+	}
+	return list
+}
+
+func (i indexItem) True() bool {
+	return len(i.values) > 0
+}
+
+func (i indexItem) Atomic() bool {
+	return false
+}
+
 type arrayItem struct {
-	values []Item
+	values []Sequence
 }
 
-func createArray(vs []Item) Item {
+func createArray(vs []Sequence) Item {
 	return arrayItem{
 		values: slices.Clone(vs),
 	}
@@ -362,7 +562,7 @@ func createArray(vs []Item) Item {
 func (i arrayItem) Sequence() Sequence {
 	s := NewSequence()
 	for j := range i.values {
-		s.Append(i.values[j])
+		s.Concat(i.values[j])
 	}
 	return s
 }
@@ -374,17 +574,32 @@ func (i arrayItem) Node() xml.Node {
 func (i arrayItem) Value() any {
 	var list []any
 	for j := range i.values {
-		list = append(list, i.values[j].Value())
+		list = append(list, memberValue(i.values[j]))
 	}
 	return list
 }
 
+func memberValue(seq Sequence) any {
+	switch len(seq) {
+	case 0:
+		return nil
+	case 1:
+		return seq[0].Value()
+	default:
+		list := make([]any, 0, len(seq))
+		for _, v := range seq {
+			list = append(list, v.Value())
+		}
+		return list
+	}
+}
+
 func (i arrayItem) True() bool {
 	if len(i.values) == 0 {
 		return false
 	}
 	for j := range i.values {
-		if !i.values[j].True() {
+		if !EffectiveBooleanValue(i.values[j]) {
 			return false
 		}
 	}
@@ -395,6 +610,39 @@ func (i arrayItem) Atomic() bool {
 	return false
 }
 
+type funcItem struct {
+	params []string
+	body   Expr
+	ctx    Context
+}
+
+func (i funcItem) call(args []Sequence) (Sequence, error) {
+	if len(args) != len(i.params) {
+		return nil, ErrArgument
+	}
+	nest := i.ctx.Nest()
+	for j := range i.params {
+		nest.Define(i.params[j], NewValueFromSequence(args[j]))
+	}
+	return i.body.find(nest)
+}
+
+func (i funcItem) Node() xml.Node {
+	return nil
+}
+
+func (i funcItem) Value() any {
+	return i
+}
+
+func (i funcItem) True() bool {
+	return true
+}
+
+func (i funcItem) Atomic() bool {
+	return false
+}
+
 type nodeItem struct {
 	node xml.Node
 }
@@ -463,6 +711,22 @@ func isFloat(i Item) bool {
 	return ok
 }
 
+func convertSequence[T string | float64 | bool](items Sequence, do func(any) (T, error)) ([]T, error) {
+	atoms, err := items.AtomizeAll()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]T, 0, len(atoms))
+	for i := range atoms {
+		x, err := do(atoms[i].Value())
+		if err != nil {
+			return nil, fmt.Errorf("item %d (%v): %w", i+1, atoms[i].Value(), ErrCast)
+		}
+		list = append(list, x)
+	}
+	return list, nil
+}
+
 func convert[T string | float64](items []Item, do func(any) (T, error)) ([]T, error) {
 	var list []T
 	for i := range items {
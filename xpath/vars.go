@@ -0,0 +1,107 @@
+package xpath
+
+import (
+	"slices"
+)
+
+// FreeVariables returns the names of the variables referenced by expr that
+// are not bound by the expression itself (for, let, some, every and inline
+// function parameters). Names are returned sorted and without duplicates.
+func FreeVariables(expr Expr) []string {
+	var (
+		seen = make(map[string]struct{})
+		list []string
+	)
+	collectVariables(expr, nil, func(ident string) {
+		if _, ok := seen[ident]; ok {
+			return
+		}
+		seen[ident] = struct{}{}
+		list = append(list, ident)
+	})
+	slices.Sort(list)
+	return list
+}
+
+func collectVariables(expr Expr, bound []string, yield func(string)) {
+	walk := func(all ...Expr) {
+		for _, e := range all {
+			if e != nil {
+				collectVariables(e, bound, yield)
+			}
+		}
+	}
+	switch e := expr.(type) {
+	case query:
+		walk(e.expr)
+	case identifier:
+		if !slices.Contains(bound, e.ident) {
+			yield(e.ident)
+		}
+	case step:
+		walk(e.curr, e.next)
+	case axis:
+		walk(e.next)
+	case sequence:
+		walk(e.all...)
+	case union:
+		walk(e.all...)
+	case except:
+		walk(e.all...)
+	case intersect:
+		walk(e.all...)
+	case binary:
+		walk(e.left, e.right)
+	case identity:
+		walk(e.left, e.right)
+	case rng:
+		walk(e.left, e.right)
+	case reverse:
+		walk(e.expr)
+	case call:
+		walk(e.args...)
+	case lookup:
+		walk(e.expr, e.key)
+	case subscript:
+		walk(e.expr, e.index)
+	case filter:
+		walk(e.expr, e.check)
+	case conditional:
+		walk(e.test, e.csq, e.alt)
+	case hashmap:
+		for k, v := range e.values {
+			walk(k, v)
+		}
+	case array:
+		walk(e.all...)
+	case instanceof:
+		walk(e.expr)
+	case treat:
+		walk(e.expr)
+	case cast:
+		walk(e.expr)
+	case castable:
+		walk(e.expr)
+	case let:
+		bound = collectBindings(e.binds, bound, yield)
+		collectVariables(e.expr, bound, yield)
+	case loop:
+		bound = collectBindings(e.binds, bound, yield)
+		collectVariables(e.body, bound, yield)
+	case quantified:
+		bound = collectBindings(e.binds, bound, yield)
+		collectVariables(e.test, bound, yield)
+	case inline:
+		bound = append(slices.Clip(bound), e.params...)
+		collectVariables(e.body, bound, yield)
+	default:
+	}
+}
+
+func collectBindings(binds []binding, bound []string, yield func(string)) []string {
+	for _, b := range binds {
+		collectVariables(b.expr, bound, yield)
+		bound = append(slices.Clip(bound), b.ident)
+	}
+	return bound
+}
@@ -0,0 +1,620 @@
+package xsd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+type loader struct {
+	*Schema
+	dir string
+}
+
+func Open(file string) (*Schema, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	ld := loader{
+		Schema: Default(),
+		dir:    filepath.Dir(file),
+	}
+	return ld.Schema, ld.load(r)
+}
+
+func New(r io.Reader) (*Schema, error) {
+	ld := loader{
+		Schema: Default(),
+	}
+	return ld.Schema, ld.load(r)
+}
+
+func (ld *loader) load(r io.Reader) error {
+	doc, err := xml.ParseReader(r)
+	if err != nil {
+		return err
+	}
+	root, err := getElementFromNode(doc.Root())
+	if err != nil {
+		return err
+	}
+	if root.LocalName() != "schema" {
+		return fmt.Errorf("%s: schema element expected", root.QualifiedName())
+	}
+	if ns, err := getAttribute(root, "targetNamespace"); err == nil && ld.TargetNamespace == "" {
+		ld.TargetNamespace = ns
+	}
+	for _, el := range childElements(root) {
+		var name string
+		switch el.LocalName() {
+		case "element":
+			e, err := ld.loadElement(el)
+			if err != nil {
+				return err
+			}
+			ld.Elements[e.Ident] = e
+			continue
+		case "complexType":
+			var t *ComplexType
+			if t, err = ld.loadComplexType(el); err == nil {
+				ld.types[t.Ident] = t
+			}
+		case "simpleType":
+			var t *SimpleType
+			if t, err = ld.loadSimpleType(el); err == nil {
+				ld.types[t.Ident] = t
+			}
+		case "attribute":
+			var a *Attribute
+			if a, err = ld.loadAttribute(el); err == nil {
+				ld.attributes[a.Ident] = a
+			}
+		case "group":
+			if name, err = getAttribute(el, "name"); err != nil {
+				break
+			}
+			var p Particle
+			if p, err = ld.loadGroup(el); err == nil {
+				ld.groups[name] = p
+			}
+		case "attributeGroup":
+			var g *AttributeGroup
+			if g, err = ld.loadAttributeGroup(el); err == nil {
+				ld.attrGroups[g.Ident] = g
+			}
+		case "include":
+			err = ld.loadInclude(el)
+		case "annotation", "import":
+		default:
+			err = fmt.Errorf("%s: unexpected element", el.QualifiedName())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ld *loader) loadInclude(el *xml.Element) error {
+	loc, err := getAttribute(el, "schemaLocation")
+	if err != nil {
+		return err
+	}
+	if ld.dir == "" && !filepath.IsAbs(loc) {
+		return fmt.Errorf("%s: can not resolve relative schema location", loc)
+	}
+	if !filepath.IsAbs(loc) {
+		loc = filepath.Join(ld.dir, loc)
+	}
+	r, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sub := loader{
+		Schema: ld.Schema,
+		dir:    filepath.Dir(loc),
+	}
+	return sub.load(r)
+}
+
+func (ld *loader) loadElement(el *xml.Element) (*Element, error) {
+	occ, err := ld.loadOccurs(el)
+	if err != nil {
+		return nil, err
+	}
+	e := Element{
+		Occurs: occ,
+	}
+	if ref, err := getAttribute(el, "ref"); err == nil {
+		e.Ref = ld.localName(ref)
+		return &e, nil
+	}
+	if e.Ident, err = getAttribute(el, "name"); err != nil {
+		return nil, err
+	}
+	if typ, err := getAttribute(el, "type"); err == nil {
+		if e.TypeName, err = ld.typeName(el, typ); err != nil {
+			return nil, err
+		}
+	}
+	if n, err := getAttribute(el, "nillable"); err == nil {
+		e.Nillable = n == "true" || n == "1"
+	}
+	if f, err := getAttribute(el, "fixed"); err == nil {
+		e.Fixed = f
+	}
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "complexType":
+			e.Type, err = ld.loadComplexType(c)
+		case "simpleType":
+			e.Type, err = ld.loadSimpleType(c)
+		case "annotation", "unique", "key", "keyref":
+		default:
+			err = fmt.Errorf("%s: unexpected element in element declaration", c.QualifiedName())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+func (ld *loader) loadComplexType(el *xml.Element) (*ComplexType, error) {
+	var t ComplexType
+	t.Ident, _ = getAttribute(el, "name")
+	if m, err := getAttribute(el, "mixed"); err == nil {
+		t.Mixed = m == "true" || m == "1"
+	}
+	for _, c := range childElements(el) {
+		var err error
+		switch c.LocalName() {
+		case "simpleContent":
+			err = ld.loadSimpleContent(&t, c)
+		case "complexContent":
+			err = ld.loadComplexContent(&t, c)
+		case "annotation":
+		default:
+			err = ld.loadTypeContent(&t, c)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
+
+func (ld *loader) loadTypeContent(t *ComplexType, el *xml.Element) error {
+	var err error
+	switch el.LocalName() {
+	case "sequence", "choice", "all", "group":
+		t.Content, err = ld.loadParticle(el)
+	case "attribute":
+		var a *Attribute
+		if a, err = ld.loadAttribute(el); err == nil {
+			t.Attributes = append(t.Attributes, a)
+		}
+	case "attributeGroup":
+		var ref string
+		if ref, err = getAttribute(el, "ref"); err == nil {
+			t.Groups = append(t.Groups, ld.localName(ref))
+		}
+	case "anyAttribute":
+		t.AnyAttribute = true
+	case "annotation":
+	default:
+		err = fmt.Errorf("%s: unexpected element in complex type", el.QualifiedName())
+	}
+	return err
+}
+
+func (ld *loader) loadSimpleContent(t *ComplexType, el *xml.Element) error {
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "extension", "restriction":
+		case "annotation":
+			continue
+		default:
+			return fmt.Errorf("%s: unexpected element in simple content", c.QualifiedName())
+		}
+		base, err := getAttribute(c, "base")
+		if err != nil {
+			return err
+		}
+		t.Simple = createSimpleType("")
+		if t.Simple.BaseName, err = ld.typeName(c, base); err != nil {
+			return err
+		}
+		for _, f := range childElements(c) {
+			switch f.LocalName() {
+			case "attribute", "attributeGroup", "anyAttribute", "annotation":
+				err = ld.loadTypeContent(t, f)
+			default:
+				err = ld.loadFacet(t.Simple, f)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ld *loader) loadComplexContent(t *ComplexType, el *xml.Element) error {
+	if m, err := getAttribute(el, "mixed"); err == nil {
+		t.Mixed = m == "true" || m == "1"
+	}
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "extension":
+			base, err := getAttribute(c, "base")
+			if err != nil {
+				return err
+			}
+			if t.BaseName, err = ld.typeName(c, base); err != nil {
+				return err
+			}
+		case "restriction":
+		case "annotation":
+			continue
+		default:
+			return fmt.Errorf("%s: unexpected element in complex content", c.QualifiedName())
+		}
+		for _, f := range childElements(c) {
+			if err := ld.loadTypeContent(t, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ld *loader) loadParticle(el *xml.Element) (Particle, error) {
+	occ, err := ld.loadOccurs(el)
+	if err != nil {
+		return nil, err
+	}
+	switch el.LocalName() {
+	case "element":
+		return ld.loadElement(el)
+	case "any":
+		a := Any{
+			Occurs: occ,
+		}
+		a.Namespace, _ = getAttribute(el, "namespace")
+		return &a, nil
+	case "group":
+		ref, err := getAttribute(el, "ref")
+		if err != nil {
+			return nil, err
+		}
+		g := GroupRef{
+			Ref:    ld.localName(ref),
+			Occurs: occ,
+		}
+		return &g, nil
+	case "sequence", "choice", "all":
+	default:
+		return nil, fmt.Errorf("%s: unexpected element in model group", el.QualifiedName())
+	}
+	var list []Particle
+	for _, c := range childElements(el) {
+		if c.LocalName() == "annotation" {
+			continue
+		}
+		p, err := ld.loadParticle(c)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	switch el.LocalName() {
+	case "sequence":
+		return &Sequence{List: list, Occurs: occ}, nil
+	case "choice":
+		return &Choice{List: list, Occurs: occ}, nil
+	default:
+		return &All{List: list, Occurs: occ}, nil
+	}
+}
+
+func (ld *loader) loadGroup(el *xml.Element) (Particle, error) {
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "sequence", "choice", "all":
+			return ld.loadParticle(c)
+		case "annotation":
+		default:
+			return nil, fmt.Errorf("%s: unexpected element in group", c.QualifiedName())
+		}
+	}
+	return nil, fmt.Errorf("group without model group")
+}
+
+func (ld *loader) loadAttribute(el *xml.Element) (*Attribute, error) {
+	var a Attribute
+	if use, err := getAttribute(el, "use"); err == nil {
+		a.Required = use == "required"
+	}
+	if f, err := getAttribute(el, "fixed"); err == nil {
+		a.Fixed = f
+	}
+	if ref, err := getAttribute(el, "ref"); err == nil {
+		a.Ref = ld.localName(ref)
+		a.Ident = a.Ref
+		return &a, nil
+	}
+	var err error
+	if a.Ident, err = getAttribute(el, "name"); err != nil {
+		return nil, err
+	}
+	if typ, err := getAttribute(el, "type"); err == nil {
+		if a.TypeName, err = ld.typeName(el, typ); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "simpleType":
+			a.Type, err = ld.loadSimpleType(c)
+		case "annotation":
+		default:
+			err = fmt.Errorf("%s: unexpected element in attribute", c.QualifiedName())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &a, nil
+}
+
+func (ld *loader) loadAttributeGroup(el *xml.Element) (*AttributeGroup, error) {
+	var (
+		g   AttributeGroup
+		err error
+	)
+	if g.Ident, err = getAttribute(el, "name"); err != nil {
+		return nil, err
+	}
+	for _, c := range childElements(el) {
+		switch c.LocalName() {
+		case "attribute":
+			var a *Attribute
+			if a, err = ld.loadAttribute(c); err == nil {
+				g.Attributes = append(g.Attributes, a)
+			}
+		case "attributeGroup":
+			var ref string
+			if ref, err = getAttribute(c, "ref"); err == nil {
+				g.Groups = append(g.Groups, ld.localName(ref))
+			}
+		case "annotation", "anyAttribute":
+		default:
+			err = fmt.Errorf("%s: unexpected element in attribute group", c.QualifiedName())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &g, nil
+}
+
+func (ld *loader) loadSimpleType(el *xml.Element) (*SimpleType, error) {
+	name, _ := getAttribute(el, "name")
+	t := createSimpleType(name)
+	for _, c := range childElements(el) {
+		var err error
+		switch c.LocalName() {
+		case "restriction":
+			err = ld.loadRestriction(t, c)
+		case "list":
+			if item, err1 := getAttribute(c, "itemType"); err1 == nil {
+				t.ItemName, err = ld.typeName(c, item)
+				break
+			}
+			for _, s := range childElements(c) {
+				if s.LocalName() == "simpleType" {
+					t.Item, err = ld.loadSimpleType(s)
+				}
+			}
+		case "union":
+			err = ld.loadUnion(t, c)
+		case "annotation":
+		default:
+			err = fmt.Errorf("%s: unexpected element in simple type", c.QualifiedName())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (ld *loader) loadUnion(t *SimpleType, el *xml.Element) error {
+	if members, err := getAttribute(el, "memberTypes"); err == nil {
+		for _, m := range strings.Fields(members) {
+			name, err := ld.typeName(el, m)
+			if err != nil {
+				return err
+			}
+			t.Union = append(t.Union, name)
+		}
+	}
+	for _, c := range childElements(el) {
+		if c.LocalName() != "simpleType" {
+			continue
+		}
+		m, err := ld.loadSimpleType(c)
+		if err != nil {
+			return err
+		}
+		t.Members = append(t.Members, m)
+	}
+	return nil
+}
+
+func (ld *loader) loadRestriction(t *SimpleType, el *xml.Element) error {
+	if base, err := getAttribute(el, "base"); err == nil {
+		if t.BaseName, err = ld.typeName(el, base); err != nil {
+			return err
+		}
+	}
+	for _, c := range childElements(el) {
+		var err error
+		if c.LocalName() == "simpleType" {
+			var base *SimpleType
+			if base, err = ld.loadSimpleType(c); err != nil {
+				return err
+			}
+			t.Item, t.ItemName = base.Item, base.ItemName
+			t.Union, t.Members = base.Union, base.Members
+			t.BaseName = base.BaseName
+			continue
+		}
+		if err = ld.loadFacet(t, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ld *loader) loadFacet(t *SimpleType, el *xml.Element) error {
+	if uri, _ := lookupNS(el, el.Space); uri != xsdNS {
+		return fmt.Errorf("%s: unexpected element in restriction", el.QualifiedName())
+	}
+	if el.LocalName() == "annotation" {
+		return nil
+	}
+	value, err := getAttribute(el, "value")
+	if err != nil {
+		return err
+	}
+	switch el.LocalName() {
+	case "enumeration":
+		t.Enum = append(t.Enum, value)
+	case "pattern":
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return err
+		}
+		t.Patterns = append(t.Patterns, re)
+	case "length":
+		t.Length, err = strconv.Atoi(value)
+	case "minLength":
+		t.MinLength, err = strconv.Atoi(value)
+	case "maxLength":
+		t.MaxLength, err = strconv.Atoi(value)
+	case "minInclusive":
+		t.MinInclusive, err = parseBound(value)
+	case "maxInclusive":
+		t.MaxInclusive, err = parseBound(value)
+	case "minExclusive":
+		t.MinExclusive, err = parseBound(value)
+	case "maxExclusive":
+		t.MaxExclusive, err = parseBound(value)
+	case "whiteSpace":
+		t.WhiteSpace = value
+	case "totalDigits":
+		t.TotalDigits, err = strconv.Atoi(value)
+	case "fractionDigits":
+		t.FractionDigits, err = strconv.Atoi(value)
+	default:
+		err = fmt.Errorf("%s: unsupported facet", el.QualifiedName())
+	}
+	return err
+}
+
+func (ld *loader) loadOccurs(el *xml.Element) (Occurs, error) {
+	occ := defaultOccurs()
+	if str, err := getAttribute(el, "minOccurs"); err == nil {
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return occ, fmt.Errorf("minOccurs: %w", ErrFormat)
+		}
+		occ.Min = n
+	}
+	if str, err := getAttribute(el, "maxOccurs"); err == nil {
+		if str == "unbounded" {
+			occ.Max = Unbounded
+			return occ, nil
+		}
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return occ, fmt.Errorf("maxOccurs: %w", ErrFormat)
+		}
+		occ.Max = n
+	}
+	return occ, nil
+}
+
+// typeName resolves the QName of a type against the namespaces in scope of
+// el. Types defined in the xsd namespace get the builtin prefix.
+func (ld *loader) typeName(el *xml.Element, name string) (string, error) {
+	prefix, local, ok := strings.Cut(name, ":")
+	if !ok {
+		local, prefix = prefix, ""
+	}
+	uri, ok := lookupNS(el, prefix)
+	if !ok && prefix != "" {
+		return "", fmt.Errorf("%s: undeclared namespace prefix %q", name, prefix)
+	}
+	if uri == xsdNS {
+		return builtinPrefix + local, nil
+	}
+	return local, nil
+}
+
+func lookupNS(el *xml.Element, prefix string) (string, bool) {
+	for _, ns := range xml.InScopeNamespaces(el) {
+		if ns.Prefix == prefix {
+			return ns.Uri, true
+		}
+	}
+	return "", false
+}
+
+func (ld *loader) localName(name string) string {
+	_, local, ok := strings.Cut(name, ":")
+	if !ok {
+		return name
+	}
+	return local
+}
+
+func parseBound(str string) (*float64, error) {
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func getAttribute(el *xml.Element, ident string) (string, error) {
+	ix := slices.IndexFunc(el.Attrs, func(a xml.Attribute) bool {
+		return a.Name == ident
+	})
+	if ix < 0 {
+		return "", fmt.Errorf("%s: attribute not available", ident)
+	}
+	return el.Attrs[ix].Value(), nil
+}
+
+func getElementFromNode(node xml.Node) (*xml.Element, error) {
+	el, ok := node.(*xml.Element)
+	if !ok {
+		return nil, fmt.Errorf("element expected")
+	}
+	return el, nil
+}
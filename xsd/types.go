@@ -0,0 +1,375 @@
+package xsd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const builtinPrefix = "xs:"
+
+const (
+	wsPreserve = "preserve"
+	wsReplace  = "replace"
+	wsCollapse = "collapse"
+)
+
+type SimpleType struct {
+	Ident    string
+	BaseName string
+	builtin  func(string) error
+
+	Enum         []string
+	Patterns     []*regexp.Regexp
+	Length       int
+	MinLength    int
+	MaxLength    int
+	MinInclusive *float64
+	MaxInclusive *float64
+	MinExclusive *float64
+	MaxExclusive *float64
+	WhiteSpace   string
+
+	TotalDigits    int
+	FractionDigits int
+
+	ItemName string
+	Item     *SimpleType
+	Union    []string
+	Members  []*SimpleType
+}
+
+func createSimpleType(name string) *SimpleType {
+	return &SimpleType{
+		Ident:          name,
+		Length:         -1,
+		MinLength:      -1,
+		MaxLength:      -1,
+		TotalDigits:    -1,
+		FractionDigits: -1,
+	}
+}
+
+func (t *SimpleType) Name() string {
+	return t.Ident
+}
+
+func (t *SimpleType) validateValue(str string, s *Schema) error {
+	str = normalizeSpace(str, t.whitespace(s))
+	if t.builtin != nil {
+		return t.builtin(str)
+	}
+	switch {
+	case t.ItemName != "" || t.Item != nil:
+		if err := t.validateList(str, s); err != nil {
+			return err
+		}
+	case len(t.Union) > 0 || len(t.Members) > 0:
+		if err := t.validateUnion(str, s); err != nil {
+			return err
+		}
+	case t.BaseName != "":
+		base, err := s.resolveSimpleType(t.BaseName)
+		if err != nil {
+			return err
+		}
+		if err := base.validateValue(str, s); err != nil {
+			return err
+		}
+	}
+	return t.validateFacets(str)
+}
+
+func (t *SimpleType) whitespace(s *Schema) string {
+	if t.WhiteSpace != "" {
+		return t.WhiteSpace
+	}
+	if t.ItemName != "" || t.Item != nil {
+		return wsCollapse
+	}
+	if t.BaseName == "" {
+		return wsPreserve
+	}
+	base, err := s.resolveSimpleType(t.BaseName)
+	if err != nil {
+		return wsPreserve
+	}
+	return base.whitespace(s)
+}
+
+func (t *SimpleType) validateList(str string, s *Schema) error {
+	item := t.Item
+	if item == nil {
+		var err error
+		if item, err = s.resolveSimpleType(t.ItemName); err != nil {
+			return err
+		}
+	}
+	for _, f := range strings.Fields(str) {
+		if err := item.validateValue(f, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *SimpleType) validateUnion(str string, s *Schema) error {
+	members := slices.Clone(t.Members)
+	for _, n := range t.Union {
+		m, err := s.resolveSimpleType(n)
+		if err != nil {
+			return err
+		}
+		members = append(members, m)
+	}
+	for _, m := range members {
+		if err := m.validateValue(str, s); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q: value does not match any member types", str)
+}
+
+func (t *SimpleType) validateFacets(str string) error {
+	if len(t.Enum) > 0 && !slices.Contains(t.Enum, str) {
+		return fmt.Errorf("%q: value not allowed (%s)", str, strings.Join(t.Enum, ", "))
+	}
+	if len(t.Patterns) > 0 {
+		ok := slices.ContainsFunc(t.Patterns, func(re *regexp.Regexp) bool {
+			return re.MatchString(str)
+		})
+		if !ok {
+			return ErrFormat
+		}
+	}
+	size := utf8.RuneCountInString(str)
+	if t.ItemName != "" || t.Item != nil {
+		size = len(strings.Fields(str))
+	}
+	if t.Length >= 0 && size != t.Length {
+		return ErrLength
+	}
+	if t.MinLength >= 0 && size < t.MinLength {
+		return ErrLength
+	}
+	if t.MaxLength >= 0 && size > t.MaxLength {
+		return ErrLength
+	}
+	if t.TotalDigits >= 0 || t.FractionDigits >= 0 {
+		total, fraction, ok := countDigits(str)
+		if !ok {
+			return ErrFormat
+		}
+		if t.TotalDigits >= 0 && total > t.TotalDigits {
+			return ErrRange
+		}
+		if t.FractionDigits >= 0 && fraction > t.FractionDigits {
+			return ErrRange
+		}
+	}
+	if t.MinInclusive == nil && t.MaxInclusive == nil && t.MinExclusive == nil && t.MaxExclusive == nil {
+		return nil
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return ErrFormat
+	}
+	if t.MinInclusive != nil && val < *t.MinInclusive {
+		return ErrRange
+	}
+	if t.MaxInclusive != nil && val > *t.MaxInclusive {
+		return ErrRange
+	}
+	if t.MinExclusive != nil && val <= *t.MinExclusive {
+		return ErrRange
+	}
+	if t.MaxExclusive != nil && val >= *t.MaxExclusive {
+		return ErrRange
+	}
+	return nil
+}
+
+// countDigits gives the number of significant digits of a decimal value and
+// the number of digits of its fractional part.
+func countDigits(str string) (int, int, bool) {
+	str = strings.TrimLeft(str, "+-")
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return 0, 0, false
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, 0, false
+		}
+	}
+	whole = strings.TrimLeft(whole, "0")
+	frac = strings.TrimRight(frac, "0")
+	return len(whole) + len(frac), len(frac), true
+}
+
+func normalizeSpace(str, mode string) string {
+	switch mode {
+	case wsReplace:
+		return strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, str)
+	case wsCollapse:
+		return strings.Join(strings.Fields(str), " ")
+	default:
+		return str
+	}
+}
+
+var builtinTypes = map[string]*SimpleType{
+	"anySimpleType":      builtinType("anySimpleType", wsPreserve, acceptAny),
+	"string":             builtinType("string", wsPreserve, acceptAny),
+	"normalizedString":   builtinType("normalizedString", wsReplace, acceptAny),
+	"token":              builtinType("token", wsCollapse, acceptAny),
+	"language":           builtinType("language", wsCollapse, acceptPattern(`[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*`)),
+	"Name":               builtinType("Name", wsCollapse, acceptPattern(`[\pL_:][\pL\pN._:-]*`)),
+	"NCName":             builtinType("NCName", wsCollapse, acceptPattern(`[\pL_][\pL\pN._-]*`)),
+	"ID":                 builtinType("ID", wsCollapse, acceptPattern(`[\pL_][\pL\pN._-]*`)),
+	"IDREF":              builtinType("IDREF", wsCollapse, acceptPattern(`[\pL_][\pL\pN._-]*`)),
+	"IDREFS":             builtinType("IDREFS", wsCollapse, acceptPattern(`[\pL_][\pL\pN._-]*( [\pL_][\pL\pN._-]*)*`)),
+	"NMTOKEN":            builtinType("NMTOKEN", wsCollapse, acceptPattern(`[\pL\pN._:-]+`)),
+	"NMTOKENS":           builtinType("NMTOKENS", wsCollapse, acceptPattern(`[\pL\pN._:-]+( [\pL\pN._:-]+)*`)),
+	"QName":              builtinType("QName", wsCollapse, acceptPattern(`([\pL_][\pL\pN._-]*:)?[\pL_][\pL\pN._-]*`)),
+	"anyURI":             builtinType("anyURI", wsCollapse, acceptURI),
+	"boolean":            builtinType("boolean", wsCollapse, acceptBool),
+	"decimal":            builtinType("decimal", wsCollapse, acceptPattern(`[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)`)),
+	"float":              builtinType("float", wsCollapse, acceptFloat),
+	"double":             builtinType("double", wsCollapse, acceptFloat),
+	"integer":            builtinType("integer", wsCollapse, acceptInt(math.MinInt64, math.MaxInt64)),
+	"long":               builtinType("long", wsCollapse, acceptInt(math.MinInt64, math.MaxInt64)),
+	"int":                builtinType("int", wsCollapse, acceptInt(math.MinInt32, math.MaxInt32)),
+	"short":              builtinType("short", wsCollapse, acceptInt(math.MinInt16, math.MaxInt16)),
+	"byte":               builtinType("byte", wsCollapse, acceptInt(math.MinInt8, math.MaxInt8)),
+	"nonNegativeInteger": builtinType("nonNegativeInteger", wsCollapse, acceptInt(0, math.MaxInt64)),
+	"positiveInteger":    builtinType("positiveInteger", wsCollapse, acceptInt(1, math.MaxInt64)),
+	"nonPositiveInteger": builtinType("nonPositiveInteger", wsCollapse, acceptInt(math.MinInt64, 0)),
+	"negativeInteger":    builtinType("negativeInteger", wsCollapse, acceptInt(math.MinInt64, -1)),
+	"unsignedLong":       builtinType("unsignedLong", wsCollapse, acceptUint(math.MaxUint64)),
+	"unsignedInt":        builtinType("unsignedInt", wsCollapse, acceptUint(math.MaxUint32)),
+	"unsignedShort":      builtinType("unsignedShort", wsCollapse, acceptUint(math.MaxUint16)),
+	"unsignedByte":       builtinType("unsignedByte", wsCollapse, acceptUint(math.MaxUint8)),
+	"date":               builtinType("date", wsCollapse, acceptTime("2006-01-02", "2006-01-02Z07:00")),
+	"time":               builtinType("time", wsCollapse, acceptTime("15:04:05", "15:04:05Z07:00", "15:04:05.999999999", "15:04:05.999999999Z07:00")),
+	"dateTime":           builtinType("dateTime", wsCollapse, acceptTime("2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999", time.RFC3339Nano)),
+	"gYear":              builtinType("gYear", wsCollapse, acceptPattern(`-?[0-9]{4,}(Z|[+-][0-9]{2}:[0-9]{2})?`)),
+	"gYearMonth":         builtinType("gYearMonth", wsCollapse, acceptPattern(`-?[0-9]{4,}-[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?`)),
+	"duration":           builtinType("duration", wsCollapse, acceptPattern(`-?P([0-9]+Y)?([0-9]+M)?([0-9]+D)?(T([0-9]+H)?([0-9]+M)?([0-9]+(\.[0-9]+)?S)?)?`)),
+	"hexBinary":          builtinType("hexBinary", wsCollapse, acceptHex),
+	"base64Binary":       builtinType("base64Binary", wsCollapse, acceptBase64),
+}
+
+func builtinType(name, space string, fn func(string) error) *SimpleType {
+	t := createSimpleType(name)
+	t.WhiteSpace = space
+	t.builtin = fn
+	return t
+}
+
+func acceptAny(_ string) error {
+	return nil
+}
+
+func acceptPattern(pattern string) func(string) error {
+	re := regexp.MustCompile("^(?:" + pattern + ")$")
+	return func(str string) error {
+		if !re.MatchString(str) {
+			return ErrFormat
+		}
+		return nil
+	}
+}
+
+func acceptURI(str string) error {
+	if _, err := url.Parse(str); err != nil {
+		return ErrFormat
+	}
+	return nil
+}
+
+func acceptBool(str string) error {
+	switch str {
+	case "true", "false", "1", "0":
+		return nil
+	default:
+		return ErrFormat
+	}
+}
+
+func acceptFloat(str string) error {
+	switch str {
+	case "INF", "-INF", "NaN":
+		return nil
+	default:
+	}
+	if _, err := strconv.ParseFloat(str, 64); err != nil {
+		return ErrFormat
+	}
+	return nil
+}
+
+func acceptInt(min, max int64) func(string) error {
+	return func(str string) error {
+		val, err := strconv.ParseInt(strings.TrimPrefix(str, "+"), 10, 64)
+		if err != nil {
+			return ErrFormat
+		}
+		if val < min || val > max {
+			return ErrRange
+		}
+		return nil
+	}
+}
+
+func acceptUint(max uint64) func(string) error {
+	return func(str string) error {
+		val, err := strconv.ParseUint(strings.TrimPrefix(str, "+"), 10, 64)
+		if err != nil {
+			return ErrFormat
+		}
+		if val > max {
+			return ErrRange
+		}
+		return nil
+	}
+}
+
+func acceptTime(layouts ...string) func(string) error {
+	return func(str string) error {
+		for _, layout := range layouts {
+			if _, err := time.Parse(layout, str); err == nil {
+				return nil
+			}
+		}
+		return ErrFormat
+	}
+}
+
+func acceptHex(str string) error {
+	if _, err := hex.DecodeString(str); err != nil {
+		return ErrFormat
+	}
+	return nil
+}
+
+func acceptBase64(str string) error {
+	str = strings.Join(strings.Fields(str), "")
+	if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+		return ErrFormat
+	}
+	return nil
+}
@@ -0,0 +1,643 @@
+package xsd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+)
+
+const (
+	xsdNS = "http://www.w3.org/2001/XMLSchema"
+	xsiNS = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+var (
+	ErrRange     = errors.New("value out of range")
+	ErrLength    = errors.New("invalid length")
+	ErrFormat    = errors.New("invalid format")
+	ErrUndefined = errors.New("undefined")
+
+	errMismatch = errors.New("mismatch")
+)
+
+type NodeError struct {
+	Node  xml.Node
+	Cause string
+}
+
+func createError(cause string, node xml.Node) error {
+	return NodeError{
+		Node:  node,
+		Cause: cause,
+	}
+}
+
+func (n NodeError) Error() string {
+	return n.Cause
+}
+
+const Unbounded = -1
+
+type Occurs struct {
+	Min int
+	Max int
+}
+
+func defaultOccurs() Occurs {
+	return Occurs{
+		Min: 1,
+		Max: 1,
+	}
+}
+
+func (o Occurs) Zero() bool {
+	return o.Min == 0
+}
+
+func (o Occurs) More(count int) bool {
+	return o.Max == Unbounded || count < o.Max
+}
+
+type Type interface {
+	Name() string
+}
+
+type Schema struct {
+	TargetNamespace string
+	Elements        map[string]*Element
+
+	types      map[string]Type
+	attributes map[string]*Attribute
+	groups     map[string]Particle
+	attrGroups map[string]*AttributeGroup
+}
+
+func Default() *Schema {
+	return &Schema{
+		Elements:   make(map[string]*Element),
+		types:      make(map[string]Type),
+		attributes: make(map[string]*Attribute),
+		groups:     make(map[string]Particle),
+		attrGroups: make(map[string]*AttributeGroup),
+	}
+}
+
+func (s *Schema) Validate(node xml.Node) error {
+	if doc, ok := node.(*xml.Document); ok {
+		node = doc.Root()
+	}
+	el, ok := node.(*xml.Element)
+	if !ok {
+		return createError("xml element expected", node)
+	}
+	decl, ok := s.Elements[el.LocalName()]
+	if !ok {
+		msg := fmt.Sprintf("%s: no global element declaration found", el.QualifiedName())
+		return createError(msg, node)
+	}
+	return decl.validate(el, s)
+}
+
+func (s *Schema) resolveType(name string) (Type, error) {
+	if local, ok := strings.CutPrefix(name, builtinPrefix); ok {
+		t, ok := builtinTypes[local]
+		if !ok {
+			return nil, fmt.Errorf("%s: builtin type %w", local, ErrUndefined)
+		}
+		return t, nil
+	}
+	if t, ok := s.types[name]; ok {
+		return t, nil
+	}
+	if t, ok := builtinTypes[name]; ok && s.TargetNamespace == "" {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%s: type %w", name, ErrUndefined)
+}
+
+func (s *Schema) resolveSimpleType(name string) (*SimpleType, error) {
+	t, err := s.resolveType(name)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := t.(*SimpleType)
+	if !ok {
+		return nil, fmt.Errorf("%s: simple type expected", name)
+	}
+	return st, nil
+}
+
+func (s *Schema) resolveElement(name string) (*Element, error) {
+	e, ok := s.Elements[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: element %w", name, ErrUndefined)
+	}
+	return e, nil
+}
+
+func (s *Schema) resolveAttribute(name string) (*Attribute, error) {
+	a, ok := s.attributes[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: attribute %w", name, ErrUndefined)
+	}
+	return a, nil
+}
+
+func (s *Schema) resolveGroup(name string) (Particle, error) {
+	g, ok := s.groups[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: group %w", name, ErrUndefined)
+	}
+	return g, nil
+}
+
+func (s *Schema) resolveAttributeGroup(name string) (*AttributeGroup, error) {
+	g, ok := s.attrGroups[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: attribute group %w", name, ErrUndefined)
+	}
+	return g, nil
+}
+
+type Particle interface {
+	occurs() Occurs
+	matchOnce([]*xml.Element, *Schema) (int, error)
+}
+
+type Element struct {
+	Ident    string
+	Ref      string
+	TypeName string
+	Type     Type
+	Nillable bool
+	Fixed    string
+	Occurs
+}
+
+func (e *Element) Name() string {
+	return e.Ident
+}
+
+func (e *Element) occurs() Occurs {
+	return e.Occurs
+}
+
+func (e *Element) matchOnce(nodes []*xml.Element, s *Schema) (int, error) {
+	decl, err := e.resolve(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(nodes) == 0 || nodes[0].LocalName() != decl.Ident {
+		return 0, errMismatch
+	}
+	return 1, decl.validate(nodes[0], s)
+}
+
+func (e *Element) resolve(s *Schema) (*Element, error) {
+	if e.Ref == "" {
+		return e, nil
+	}
+	return s.resolveElement(e.Ref)
+}
+
+func (e *Element) validate(el *xml.Element, s *Schema) error {
+	typ, err := e.getType(s)
+	if err != nil {
+		return err
+	}
+	if e.Nillable && isNil(el) {
+		if len(childElements(el)) > 0 || strings.TrimSpace(el.Value()) != "" {
+			return createError("nil element should be empty", el)
+		}
+		return nil
+	}
+	if e.Fixed != "" && el.Value() != e.Fixed {
+		msg := fmt.Sprintf("%s: value should be %q", el.QualifiedName(), e.Fixed)
+		return createError(msg, el)
+	}
+	switch t := typ.(type) {
+	case *SimpleType:
+		if err := validateAttributes(el, nil, nil, false, s); err != nil {
+			return err
+		}
+		if len(childElements(el)) > 0 {
+			msg := fmt.Sprintf("%s: element can not have children elements", el.QualifiedName())
+			return createError(msg, el)
+		}
+		return wrapValueError(t.validateValue(el.Value(), s), el)
+	case *ComplexType:
+		return t.validate(el, s)
+	case nil:
+		return nil
+	default:
+		return fmt.Errorf("%s: unsupported type", e.Ident)
+	}
+}
+
+func (e *Element) getType(s *Schema) (Type, error) {
+	if e.Type != nil {
+		return e.Type, nil
+	}
+	if e.TypeName == "" {
+		return nil, nil
+	}
+	return s.resolveType(e.TypeName)
+}
+
+type Sequence struct {
+	List []Particle
+	Occurs
+}
+
+func (q *Sequence) occurs() Occurs {
+	return q.Occurs
+}
+
+func (q *Sequence) matchOnce(nodes []*xml.Element, s *Schema) (int, error) {
+	var offset int
+	for _, p := range q.List {
+		n, err := matchParticle(p, nodes[offset:], s)
+		if err != nil {
+			return 0, err
+		}
+		offset += n
+	}
+	return offset, nil
+}
+
+type Choice struct {
+	List []Particle
+	Occurs
+}
+
+func (c *Choice) occurs() Occurs {
+	return c.Occurs
+}
+
+func (c *Choice) matchOnce(nodes []*xml.Element, s *Schema) (int, error) {
+	var (
+		empty bool
+		last  = errMismatch
+	)
+	for _, p := range c.List {
+		n, err := matchParticle(p, nodes, s)
+		if err != nil {
+			if !errors.Is(err, errMismatch) {
+				last = err
+			}
+			continue
+		}
+		if n > 0 {
+			return n, nil
+		}
+		empty = true
+	}
+	if empty {
+		return 0, nil
+	}
+	return 0, last
+}
+
+type All struct {
+	List []Particle
+	Occurs
+}
+
+func (a *All) occurs() Occurs {
+	return a.Occurs
+}
+
+func (a *All) matchOnce(nodes []*xml.Element, s *Schema) (int, error) {
+	var (
+		seen   = make(map[int]bool)
+		offset int
+	)
+	for ; offset < len(nodes); offset++ {
+		ix := -1
+		for i, p := range a.List {
+			e, ok := p.(*Element)
+			if !ok || seen[i] {
+				continue
+			}
+			decl, err := e.resolve(s)
+			if err != nil {
+				return 0, err
+			}
+			if decl.Ident == nodes[offset].LocalName() {
+				ix = i
+				if err := decl.validate(nodes[offset], s); err != nil {
+					return 0, err
+				}
+				break
+			}
+		}
+		if ix < 0 {
+			break
+		}
+		seen[ix] = true
+	}
+	for i, p := range a.List {
+		if !seen[i] && !p.occurs().Zero() {
+			return 0, errMismatch
+		}
+	}
+	return offset, nil
+}
+
+type GroupRef struct {
+	Ref string
+	Occurs
+}
+
+func (g *GroupRef) occurs() Occurs {
+	return g.Occurs
+}
+
+func (g *GroupRef) matchOnce(nodes []*xml.Element, s *Schema) (int, error) {
+	p, err := s.resolveGroup(g.Ref)
+	if err != nil {
+		return 0, err
+	}
+	return p.matchOnce(nodes, s)
+}
+
+type Any struct {
+	Namespace string
+	Occurs
+}
+
+func (a *Any) occurs() Occurs {
+	return a.Occurs
+}
+
+func (a *Any) matchOnce(nodes []*xml.Element, _ *Schema) (int, error) {
+	if len(nodes) == 0 {
+		return 0, errMismatch
+	}
+	return 1, nil
+}
+
+func matchParticle(p Particle, nodes []*xml.Element, s *Schema) (int, error) {
+	var (
+		occ    = p.occurs()
+		count  int
+		offset int
+	)
+	for occ.More(count) {
+		n, err := p.matchOnce(nodes[offset:], s)
+		if err != nil {
+			if errors.Is(err, errMismatch) {
+				break
+			}
+			return 0, err
+		}
+		count++
+		if n == 0 {
+			break
+		}
+		offset += n
+	}
+	if count < occ.Min {
+		return 0, errMismatch
+	}
+	return offset, nil
+}
+
+type Attribute struct {
+	Ident    string
+	Ref      string
+	TypeName string
+	Type     *SimpleType
+	Required bool
+	Fixed    string
+}
+
+func (a *Attribute) resolve(s *Schema) (*Attribute, error) {
+	if a.Ref == "" {
+		return a, nil
+	}
+	attr, err := s.resolveAttribute(a.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if a.Required || a.Fixed != "" {
+		tmp := *attr
+		tmp.Required = tmp.Required || a.Required
+		if a.Fixed != "" {
+			tmp.Fixed = a.Fixed
+		}
+		attr = &tmp
+	}
+	return attr, nil
+}
+
+func (a *Attribute) validateValue(str string, s *Schema) error {
+	if a.Fixed != "" && str != a.Fixed {
+		return fmt.Errorf("%s: value should be %q", a.Ident, a.Fixed)
+	}
+	if a.Type != nil {
+		return a.Type.validateValue(str, s)
+	}
+	if a.TypeName == "" {
+		return nil
+	}
+	t, err := s.resolveSimpleType(a.TypeName)
+	if err != nil {
+		return err
+	}
+	return t.validateValue(str, s)
+}
+
+type AttributeGroup struct {
+	Ident      string
+	Attributes []*Attribute
+	Groups     []string
+}
+
+type ComplexType struct {
+	Ident        string
+	Mixed        bool
+	BaseName     string
+	Content      Particle
+	Simple       *SimpleType
+	Attributes   []*Attribute
+	Groups       []string
+	AnyAttribute bool
+}
+
+func (t *ComplexType) Name() string {
+	return t.Ident
+}
+
+func (t *ComplexType) validate(el *xml.Element, s *Schema) error {
+	var (
+		content = t.Content
+		attrs   = t.Attributes
+		groups  = t.Groups
+		simple  = t.Simple
+		mixed   = t.Mixed
+		anyAttr = t.AnyAttribute
+	)
+	if t.BaseName != "" {
+		base, err := s.resolveType(t.BaseName)
+		if err != nil {
+			return err
+		}
+		switch b := base.(type) {
+		case *ComplexType:
+			if b.Content != nil && content != nil {
+				content = &Sequence{
+					List:   []Particle{b.Content, content},
+					Occurs: defaultOccurs(),
+				}
+			} else if content == nil {
+				content = b.Content
+			}
+			attrs = slices.Concat(b.Attributes, attrs)
+			groups = slices.Concat(b.Groups, groups)
+			mixed = mixed || b.Mixed
+			anyAttr = anyAttr || b.AnyAttribute
+			if simple == nil {
+				simple = b.Simple
+			}
+		case *SimpleType:
+			if simple == nil {
+				simple = b
+			}
+		}
+	}
+	if err := validateAttributes(el, attrs, groups, anyAttr, s); err != nil {
+		return err
+	}
+	children := childElements(el)
+	if simple != nil {
+		if len(children) > 0 {
+			msg := fmt.Sprintf("%s: element can not have children elements", el.QualifiedName())
+			return createError(msg, el)
+		}
+		return wrapValueError(simple.validateValue(el.Value(), s), el)
+	}
+	if !mixed && hasText(el) {
+		msg := fmt.Sprintf("%s: text content not allowed", el.QualifiedName())
+		return createError(msg, el)
+	}
+	if content == nil {
+		if len(children) > 0 {
+			msg := fmt.Sprintf("%s: element should be empty", el.QualifiedName())
+			return createError(msg, el)
+		}
+		return nil
+	}
+	n, err := matchParticle(content, children, s)
+	if err != nil {
+		if errors.Is(err, errMismatch) {
+			msg := fmt.Sprintf("%s: content does not match its declaration", el.QualifiedName())
+			return createError(msg, el)
+		}
+		return err
+	}
+	if n < len(children) {
+		msg := fmt.Sprintf("%s: unexpected element", children[n].QualifiedName())
+		return createError(msg, children[n])
+	}
+	return nil
+}
+
+func validateAttributes(el *xml.Element, attrs []*Attribute, groups []string, anyAttr bool, s *Schema) error {
+	var err error
+	for _, g := range groups {
+		if attrs, err = appendGroupAttributes(attrs, g, s); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, a := range attrs {
+		if a, err = a.resolve(s); err != nil {
+			return err
+		}
+		seen[a.Ident] = true
+		ix := slices.IndexFunc(el.Attrs, func(attr xml.Attribute) bool {
+			return attr.LocalName() == a.Ident && !isSpecialAttr(attr)
+		})
+		if ix < 0 {
+			if a.Required {
+				msg := fmt.Sprintf("%s: attribute is missing", a.Ident)
+				return createError(msg, el)
+			}
+			continue
+		}
+		if err := a.validateValue(el.Attrs[ix].Value(), s); err != nil {
+			msg := fmt.Sprintf("%s: invalid attribute value: %s", a.Ident, err)
+			return createError(msg, el)
+		}
+	}
+	if anyAttr {
+		return nil
+	}
+	for _, a := range el.Attrs {
+		if isSpecialAttr(a) || seen[a.LocalName()] {
+			continue
+		}
+		msg := fmt.Sprintf("%s: attribute not allowed", a.QualifiedName())
+		return createError(msg, el)
+	}
+	return nil
+}
+
+func appendGroupAttributes(attrs []*Attribute, name string, s *Schema) ([]*Attribute, error) {
+	g, err := s.resolveAttributeGroup(name)
+	if err != nil {
+		return nil, err
+	}
+	attrs = append(attrs, g.Attributes...)
+	for _, n := range g.Groups {
+		if attrs, err = appendGroupAttributes(attrs, n, s); err != nil {
+			return nil, err
+		}
+	}
+	return attrs, nil
+}
+
+func isSpecialAttr(a xml.Attribute) bool {
+	return a.Name == "xmlns" || a.Space == "xmlns" || a.Space == "xml" || a.Space == "xsi" || a.Uri == xsiNS
+}
+
+func isNil(el *xml.Element) bool {
+	ix := slices.IndexFunc(el.Attrs, func(a xml.Attribute) bool {
+		return a.Name == "nil" && (a.Space == "xsi" || a.Uri == xsiNS)
+	})
+	return ix >= 0 && el.Attrs[ix].Value() == "true"
+}
+
+func childElements(el *xml.Element) []*xml.Element {
+	var list []*xml.Element
+	for _, n := range el.Nodes {
+		if c, ok := n.(*xml.Element); ok {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func hasText(el *xml.Element) bool {
+	for _, n := range el.Nodes {
+		if n.Type() != xml.TypeText {
+			continue
+		}
+		if strings.TrimSpace(n.Value()) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func wrapValueError(err error, node xml.Node) error {
+	if err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("%s: invalid value: %s", node.QualifiedName(), err)
+	return createError(msg, node)
+}
@@ -0,0 +1,211 @@
+package xsd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xsd"
+)
+
+const librarySchema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="code">
+		<xs:restriction base="xs:string">
+			<xs:enumeration value="fr"/>
+			<xs:enumeration value="en"/>
+		</xs:restriction>
+	</xs:simpleType>
+	<xs:simpleType name="short">
+		<xs:restriction base="xs:string">
+			<xs:maxLength value="5"/>
+		</xs:restriction>
+	</xs:simpleType>
+	<xs:element name="library">
+		<xs:complexType>
+			<xs:sequence>
+				<xs:element name="book" minOccurs="1" maxOccurs="2">
+					<xs:complexType>
+						<xs:sequence>
+							<xs:element name="title" type="short"/>
+							<xs:choice>
+								<xs:element name="author" type="xs:string"/>
+								<xs:element name="editor" type="xs:string"/>
+							</xs:choice>
+							<xs:element name="year" type="xs:integer" minOccurs="0"/>
+						</xs:sequence>
+						<xs:attribute name="isbn" type="xs:string" use="required"/>
+						<xs:attribute name="lang" type="code"/>
+						<xs:attribute name="available" type="xs:boolean"/>
+					</xs:complexType>
+				</xs:element>
+			</xs:sequence>
+		</xs:complexType>
+	</xs:element>
+</xs:schema>`
+
+func TestValidate(t *testing.T) {
+	schema, err := xsd.New(strings.NewReader(librarySchema))
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	tests := []struct {
+		Name string
+		Doc  string
+		Want string
+	}{
+		{
+			Name: "valid",
+			Doc:  `<library><book isbn="1"><title>go</title><author>x</author></book></library>`,
+		},
+		{
+			Name: "valid-optional",
+			Doc:  `<library><book isbn="1" lang="fr" available="true"><title>go</title><editor>x</editor><year>2020</year></book><book isbn="2"><title>rust</title><author>y</author></book></library>`,
+		},
+		{
+			Name: "missing-attribute",
+			Doc:  `<library><book><title>go</title><author>x</author></book></library>`,
+			Want: "isbn: attribute is missing",
+		},
+		{
+			Name: "unknown-attribute",
+			Doc:  `<library><book isbn="1" extra="x"><title>go</title><author>x</author></book></library>`,
+			Want: "extra: attribute not allowed",
+		},
+		{
+			Name: "enumeration",
+			Doc:  `<library><book isbn="1" lang="de"><title>go</title><author>x</author></book></library>`,
+			Want: `lang: invalid attribute value: "de": value not allowed (fr, en)`,
+		},
+		{
+			Name: "boolean",
+			Doc:  `<library><book isbn="1" available="maybe"><title>go</title><author>x</author></book></library>`,
+			Want: "available: invalid attribute value: invalid format",
+		},
+		{
+			Name: "max-length",
+			Doc:  `<library><book isbn="1"><title>golang</title><author>x</author></book></library>`,
+			Want: "title: invalid value: invalid length",
+		},
+		{
+			Name: "integer",
+			Doc:  `<library><book isbn="1"><title>go</title><author>x</author><year>abc</year></book></library>`,
+			Want: "year: invalid value: invalid format",
+		},
+		{
+			Name: "missing-choice",
+			Doc:  `<library><book isbn="1"><title>go</title></book></library>`,
+			Want: "book: content does not match its declaration",
+		},
+		{
+			Name: "both-choices",
+			Doc:  `<library><book isbn="1"><title>go</title><author>x</author><editor>y</editor></book></library>`,
+			Want: "editor: unexpected element",
+		},
+		{
+			Name: "min-occurs",
+			Doc:  `<library></library>`,
+			Want: "library: content does not match its declaration",
+		},
+		{
+			Name: "max-occurs",
+			Doc:  `<library><book isbn="1"><title>a</title><author>x</author></book><book isbn="2"><title>b</title><author>x</author></book><book isbn="3"><title>c</title><author>x</author></book></library>`,
+			Want: "book: unexpected element",
+		},
+		{
+			Name: "undeclared-root",
+			Doc:  `<catalog/>`,
+			Want: "catalog: no global element declaration found",
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			doc, err := xml.ParseString(c.Doc)
+			if err != nil {
+				t.Fatalf("error parsing document: %s", err)
+			}
+			err = schema.Validate(doc.Root())
+			if c.Want == "" {
+				if err != nil {
+					t.Errorf("expected document to be valid: %s", err)
+				}
+				return
+			}
+			nerr, ok := err.(xsd.NodeError)
+			if !ok {
+				t.Fatalf("expected node error, got %v", err)
+			}
+			if nerr.Cause != c.Want {
+				t.Errorf("error mismatched! want %q, got %q", c.Want, nerr.Cause)
+			}
+		})
+	}
+}
+
+func TestLoadRestriction(t *testing.T) {
+	const schema = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<simpleType name="price" xmlns="http://www.w3.org/2001/XMLSchema">
+		<restriction base="decimal">
+			<totalDigits value="5"/>
+			<fractionDigits value="2"/>
+		</restriction>
+	</simpleType>
+	<xs:element name="price" type="price"/>
+</xs:schema>`
+
+	s, err := xsd.New(strings.NewReader(schema))
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	tests := []struct {
+		Doc   string
+		Valid bool
+	}{
+		{Doc: `<price>123.45</price>`, Valid: true},
+		{Doc: `<price>00123.450</price>`, Valid: true},
+		{Doc: `<price>1234.5</price>`, Valid: true},
+		{Doc: `<price>1234.56</price>`},
+		{Doc: `<price>1.234</price>`},
+		{Doc: `<price>abc</price>`},
+	}
+	for _, c := range tests {
+		doc, err := xml.ParseString(c.Doc)
+		if err != nil {
+			t.Fatalf("error parsing document: %s", err)
+		}
+		err = s.Validate(doc.Root())
+		if c.Valid && err != nil {
+			t.Errorf("%s: expected document to be valid: %s", c.Doc, err)
+		} else if !c.Valid && err == nil {
+			t.Errorf("%s: expected document to be invalid", c.Doc)
+		}
+	}
+
+	invalid := []struct {
+		Name   string
+		Schema string
+	}{
+		{
+			Name: "undeclared-prefix",
+			Schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="code"><xs:restriction base="xsd:string"/></xs:simpleType>
+</xs:schema>`,
+		},
+		{
+			Name: "unsupported-facet",
+			Schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:simpleType name="code"><xs:restriction base="xs:date"><xs:explicitTimezone value="required"/></xs:restriction></xs:simpleType>
+</xs:schema>`,
+		},
+		{
+			Name: "foreign-facet",
+			Schema: `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:x="http://x.org">
+	<xs:simpleType name="code"><xs:restriction base="xs:string"><x:enumeration value="fr"/></xs:restriction></xs:simpleType>
+</xs:schema>`,
+		},
+	}
+	for _, c := range invalid {
+		if _, err := xsd.New(strings.NewReader(c.Schema)); err == nil {
+			t.Errorf("%s: expected error loading schema", c.Name)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package xslt
+
+import (
+	"fmt"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+const (
+	phaseStart = "start"
+	phaseEnd   = "end"
+)
+
+type AccumulatorRule struct {
+	Match  Matcher
+	Phase  string
+	Select string
+	Nodes  []xml.Node
+
+	elem *xml.Element
+}
+
+type Accumulator struct {
+	Name    string
+	Initial string
+	As      string
+	Rules   []*AccumulatorRule
+
+	elem *xml.Element
+}
+
+type accumulatorValues struct {
+	before map[xml.Node]xpath.Sequence
+	after  map[xml.Node]xpath.Sequence
+}
+
+func (s *Stylesheet) loadAccumulator(node xml.Node) error {
+	elem, err := getElementFromNode(node)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.useWhen(elem); !ok {
+		return nil
+	}
+	acc := Accumulator{
+		elem: elem,
+	}
+	if acc.Name, err = getAttribute(elem, "name"); err != nil {
+		return err
+	}
+	if acc.Initial, err = getAttribute(elem, "initial-value"); err != nil {
+		return err
+	}
+	acc.As, _ = getAttribute(elem, "as")
+	for _, n := range elem.Nodes {
+		if n.Type() == xml.TypeComment {
+			continue
+		}
+		if n.QualifiedName() != s.getQualifiedName("accumulator-rule") {
+			return fmt.Errorf("%s: unexpected element in accumulator", n.QualifiedName())
+		}
+		el, err := getElementFromNode(n)
+		if err != nil {
+			return err
+		}
+		rule, err := s.loadAccumulatorRule(el)
+		if err != nil {
+			return err
+		}
+		acc.Rules = append(acc.Rules, rule)
+	}
+	if s.ownAccumulator(acc.Name) {
+		return fmt.Errorf("%s: accumulator already defined", acc.Name)
+	}
+	s.Accumulators = append(s.Accumulators, &acc)
+	return nil
+}
+
+func (s *Stylesheet) loadAccumulatorRule(elem *xml.Element) (*AccumulatorRule, error) {
+	rule := AccumulatorRule{
+		Phase: phaseStart,
+		elem:  elem,
+	}
+	match, err := getAttribute(elem, "match")
+	if err != nil {
+		return nil, err
+	}
+	if rule.Match, err = compileMatchWithEnv(s.env, match); err != nil {
+		return nil, err
+	}
+	if phase, err := getAttribute(elem, "phase"); err == nil {
+		if phase != phaseStart && phase != phaseEnd {
+			return nil, fmt.Errorf("%s: invalid value for phase attribute", phase)
+		}
+		rule.Phase = phase
+	}
+	if rule.Select, err = getAttribute(elem, "select"); err == nil {
+		if len(elem.Nodes) > 0 {
+			return nil, fmt.Errorf("select attribute can not be used with children")
+		}
+	} else {
+		rule.Nodes = elem.Nodes
+	}
+	return &rule, nil
+}
+
+func (s *Stylesheet) ownAccumulator(name string) bool {
+	for _, a := range s.Accumulators {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Stylesheet) findAccumulator(name string) *Accumulator {
+	for _, a := range s.Accumulators {
+		if a.Name == name {
+			return a
+		}
+	}
+	for i := len(s.Others) - 1; i >= 0; i-- {
+		if a := s.Others[i].findAccumulator(name); a != nil {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Stylesheet) callAccumulatorBefore(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	return s.NewSession().callAccumulatorBefore(ctx, args)
+}
+
+func (s *Stylesheet) callAccumulatorAfter(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	return s.NewSession().callAccumulatorAfter(ctx, args)
+}
+
+func (s *Session) callAccumulatorBefore(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	return s.callAccumulator(ctx, args, "accumulator-before", true)
+}
+
+func (s *Session) callAccumulatorAfter(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	return s.callAccumulator(ctx, args, "accumulator-after", false)
+}
+
+func (s *Session) callAccumulator(ctx xpath.Context, args []xpath.Expr, fn string, before bool) (xpath.Sequence, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s: invalid number of arguments", fn)
+	}
+	items, err := xpath.Call(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, fmt.Errorf("%s: name expected", fn)
+	}
+	name := toString(items.First())
+	acc := s.sheet.findAccumulator(name)
+	if acc == nil {
+		return nil, fmt.Errorf("%s: accumulator %w", name, errUndefined)
+	}
+	if ctx.Node == nil || ctx.Node.Type() == xml.TypeAttribute {
+		return nil, fmt.Errorf("%s: no accumulator value available for context node", fn)
+	}
+	root := ctx.Node
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+	values, err := s.valuesOf(acc, root)
+	if err != nil {
+		return nil, err
+	}
+	if before {
+		return values.before[ctx.Node], nil
+	}
+	return values.after[ctx.Node], nil
+}
+
+func (s *Session) valuesOf(acc *Accumulator, root xml.Node) (*accumulatorValues, error) {
+	id := accumulatorKey{
+		acc:  acc,
+		root: root,
+	}
+	if values, ok := s.accumulators[id]; ok {
+		return values, nil
+	}
+	ctx := s.createContext(root).WithXsl(acc.elem)
+	value, err := ctx.Execute(acc.Initial)
+	if err != nil {
+		return nil, err
+	}
+	if value, err = convertSequence(ctx, value, acc.As); err != nil {
+		return nil, fmt.Errorf("%s: initial value: %w", acc.Name, err)
+	}
+	values := accumulatorValues{
+		before: make(map[xml.Node]xpath.Sequence),
+		after:  make(map[xml.Node]xpath.Sequence),
+	}
+	if _, err := acc.accumulate(s, root, value, &values); err != nil {
+		return nil, err
+	}
+	if s.accumulators == nil {
+		s.accumulators = make(map[accumulatorKey]*accumulatorValues)
+	}
+	s.accumulators[id] = &values
+	return &values, nil
+}
+
+func (acc *Accumulator) accumulate(sess *Session, node xml.Node, value xpath.Sequence, values *accumulatorValues) (xpath.Sequence, error) {
+	value, err := acc.apply(sess, node, phaseStart, value)
+	if err != nil {
+		return nil, err
+	}
+	values.before[node] = value
+
+	var nodes []xml.Node
+	switch n := node.(type) {
+	case *xml.Document:
+		nodes = n.Nodes
+	case *xml.Element:
+		nodes = n.Nodes
+	default:
+	}
+	for _, c := range nodes {
+		if value, err = acc.accumulate(sess, c, value, values); err != nil {
+			return nil, err
+		}
+	}
+	if value, err = acc.apply(sess, node, phaseEnd, value); err != nil {
+		return nil, err
+	}
+	values.after[node] = value
+	return value, nil
+}
+
+func (acc *Accumulator) apply(sess *Session, node xml.Node, phase string, value xpath.Sequence) (xpath.Sequence, error) {
+	var (
+		rule     *AccumulatorRule
+		priority float64
+	)
+	for _, r := range acc.Rules {
+		if r.Phase != phase || !r.Match.Match(node) {
+			continue
+		}
+		if p := matchPriority(r.Match, node); rule == nil || p >= priority {
+			rule, priority = r, p
+		}
+	}
+	if rule == nil {
+		return value, nil
+	}
+	ctx := sess.createContext(node).WithXsl(rule.elem).Sub()
+	ctx.Set("value", xpath.NewValueFromSequence(value))
+
+	var err error
+	if rule.Select != "" {
+		value, err = ctx.Execute(rule.Select)
+	} else {
+		value, err = executeConstructor(ctx, rule.Nodes, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value, err = convertSequence(ctx, value, acc.As); err != nil {
+		return nil, fmt.Errorf("%s: %w", acc.Name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,109 @@
+package xslt
+
+import (
+	"io"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+type options struct {
+	contextDir string
+	mode       string
+	wrapName   string
+	permissive bool
+	extensions []string
+	params     map[string]xpath.Expr
+}
+
+type Option func(*options)
+
+func WithContextDir(dir string) Option {
+	return func(o *options) {
+		o.contextDir = dir
+	}
+}
+
+func WithMode(mode string) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+func WithWrapRoot(name string) Option {
+	return func(o *options) {
+		o.wrapName = name
+	}
+}
+
+func WithPermissive() Option {
+	return func(o *options) {
+		o.permissive = true
+	}
+}
+
+func WithExtensions(names ...string) Option {
+	return func(o *options) {
+		o.extensions = append(o.extensions, names...)
+	}
+}
+
+func WithParam(ident string, expr xpath.Expr) Option {
+	return func(o *options) {
+		if o.params == nil {
+			o.params = make(map[string]xpath.Expr)
+		}
+		o.params[ident] = expr
+	}
+}
+
+func New(r io.Reader, opts ...Option) (*Stylesheet, error) {
+	var cfg options
+	for _, o := range opts {
+		o(&cfg)
+	}
+	doc, err := xml.NewParser(r).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.contextDir == "" {
+		cfg.contextDir = "."
+	}
+	sheet, err := FromDocument(doc, cfg.contextDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.mode != "" {
+		sheet.Mode = cfg.mode
+	}
+	if cfg.wrapName != "" {
+		sheet.WrapRoot = true
+		sheet.WrapName = cfg.wrapName
+	}
+	sheet.Permissive = cfg.permissive
+	for ident, expr := range cfg.params {
+		sheet.SetParam(ident, expr)
+	}
+	if err := sheet.ApplyPolicy(AllowExtensions(cfg.extensions...)); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+func (s *Stylesheet) Transform(doc *xml.Document) (*xml.Document, error) {
+	nodes, err := s.Execute(doc)
+	if err != nil {
+		return nil, err
+	}
+	if s.WrapRoot {
+		root := getRootNode(nodes, s.WrapRoot, xml.LocalName(s.WrapName))
+		return xml.NewDocument(root), nil
+	}
+	res := xml.EmptyDocument()
+	res.Nodes = append(res.Nodes, nodes...)
+	return res, nil
+}
+
+func (s *Stylesheet) TransformTo(w io.Writer, doc *xml.Document) error {
+	return s.Generate(w, doc)
+}
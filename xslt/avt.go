@@ -1,64 +1,104 @@
 package xslt
 
 import (
-	"iter"
+	"slices"
 	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+	"github.com/midbel/codecs/xpath/avt"
 )
 
-func processAVT(ctx *Context) error {
-	elem, err := getElementFromNode(ctx.XslNode)
-	if err != nil {
-		return err
-	}
-	for i, a := range elem.Attributes() {
-		var (
-			value = a.Value()
-			str   strings.Builder
-		)
-		for q, ok := range iterAVT(value) {
-			if !ok {
-				str.WriteString(q)
-				continue
-			}
-			items, err := ctx.Execute(q)
+func expandAttributes(ctx *Context, elem *xml.Element) ([]xml.Attribute, error) {
+	attrs := make([]xml.Attribute, 0, len(elem.Attrs))
+	for _, a := range elem.Attrs {
+		if _, ok := declaredPrefix(a); !ok {
+			value, err := evalAVT(ctx, a.Value())
 			if err != nil {
-				return err
-			}
-			for i := range items {
-				str.WriteString(toString(items[i]))
+				return nil, err
 			}
+			a.Datum = value
 		}
-		elem.Attrs[i].Datum = str.String()
+		attrs = append(attrs, a)
 	}
-	return nil
+	return attrs, nil
 }
 
-func iterAVT(str string) iter.Seq2[string, bool] {
-	fn := func(yield func(string, bool) bool) {
-		var offset int
-		for {
-			var (
-				ix  = strings.IndexRune(str[offset:], '{')
-				ptr = offset
-			)
-			if ix < 0 {
-				yield(str[offset:], false)
-				break
-			}
-			offset += ix + 1
-			ix = strings.IndexRune(str[offset:], '}')
-			if ix < 0 {
-				yield(str[offset-1:], false)
-				break
-			}
-			if !yield(str[ptr:offset-1], false) {
-				break
-			}
-			if !yield(str[offset:offset+ix], true) {
-				break
-			}
-			offset += ix + 1
+func declaredPrefix(a xml.Attribute) (string, bool) {
+	switch {
+	case a.Space == xml.AttrXmlNS:
+		return a.Name, true
+	case a.Space == "" && a.Name == xml.AttrXmlNS:
+		return "", true
+	default:
+		return "", false
+	}
+}
+
+type avtKey struct {
+	value  string
+	elemNS string
+}
+
+// compileTemplates compiles the attribute value templates found in elem and
+// its descendants so that they are not compiled again on each evaluation.
+// Invalid templates are left out and reported when they are evaluated.
+func (s *Stylesheet) compileTemplates(elem *xml.Element, elemNS string) {
+	if ns, err := getAttribute(elem, s.getQualifiedName("xpath-default-namespace")); err == nil {
+		elemNS = ns
+	}
+	for _, a := range elem.Attrs {
+		value := a.Value()
+		if !s.isTemplateAttribute(elem, a) || !strings.ContainsAny(value, "{}") {
+			continue
+		}
+		key := avtKey{
+			value:  value,
+			elemNS: elemNS,
 		}
+		if _, ok := s.templates[key]; ok {
+			continue
+		}
+		env := s.env.Sub()
+		env.SetElemNS(elemNS)
+		if tpl, err := avt.Compile(value, env); err == nil {
+			s.templates[key] = tpl
+		}
+	}
+	for _, n := range elem.Nodes {
+		if el, ok := n.(*xml.Element); ok {
+			s.compileTemplates(el, elemNS)
+		}
+	}
+}
+
+func (s *Stylesheet) isTemplateAttribute(elem *xml.Element, a xml.Attribute) bool {
+	if _, ok := declaredPrefix(a); ok {
+		return false
 	}
-	return fn
+	if elem.Space != s.xsltNamespace {
+		return a.Space != s.xsltNamespace
+	}
+	name := a.QualifiedName()
+	return a.Space == "" && !slices.Contains(exprAttributes, name) && !slices.Contains(patternAttributes, name)
+}
+
+func evalAVT(ctx *Context, value string) (string, error) {
+	if !strings.ContainsAny(value, "{}") {
+		return value, nil
+	}
+	tpl, ok := ctx.templates[avtKey{value: value, elemNS: ctx.GetXpathNamespace()}]
+	if !ok {
+		var err error
+		if tpl, err = avt.Compile(value, ctx.env); err != nil {
+			return "", err
+		}
+	}
+	return tpl.ExpandWith(ctx.env, ctx.ContextNode, func(items xpath.Sequence) (string, error) {
+		list := make([]string, len(items))
+		for i := range items {
+			list[i] = toString(items[i])
+		}
+		return strings.Join(list, " "), nil
+	})
 }
@@ -2,7 +2,11 @@ package xslt
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 
+	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
 )
 
@@ -14,8 +18,185 @@ func callId(ctx xpath.Context, _ []xpath.Expr) (xpath.Sequence, error) {
 	return nil, nil
 }
 
-func callKey(ctx xpath.Context, _ []xpath.Expr) (xpath.Sequence, error) {
-	return nil, nil
+func (s *Stylesheet) callFormatNumber(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("format-number: invalid number of arguments")
+	}
+	items, err := xpath.Call(ctx, args[:1])
+	if err != nil {
+		return nil, err
+	}
+	value := math.NaN()
+	if items.Singleton() {
+		if v, ok := items.First().Value().(float64); ok {
+			value = v
+		} else if v, err := strconv.ParseFloat(toString(items.First()), 64); err == nil {
+			value = v
+		}
+	}
+	items, err = xpath.Call(ctx, args[1:2])
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, fmt.Errorf("format-number: picture expected")
+	}
+	picture := toString(items.First())
+
+	var name string
+	if len(args) == 3 {
+		items, err = xpath.Call(ctx, args[2:3])
+		if err != nil {
+			return nil, err
+		}
+		if !items.Empty() {
+			name = toString(items.First())
+		}
+	}
+	format, ok := s.getDecimalFormat(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: decimal format %w", name, errUndefined)
+	}
+	str, err := format.Format(value, picture)
+	if err != nil {
+		return nil, err
+	}
+	return xpath.Singleton(str), nil
+}
+
+func (s *Stylesheet) callKey(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	return s.NewSession().callKey(ctx, args)
+}
+
+func (s *Session) callKey(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("key: invalid number of arguments")
+	}
+	items, err := xpath.Call(ctx, args[:1])
+	if err != nil {
+		return nil, err
+	}
+	if items.Empty() {
+		return nil, fmt.Errorf("key: name expected")
+	}
+	name := toString(items.First())
+	values, err := xpath.Call(ctx, args[1:2])
+	if err != nil {
+		return nil, err
+	}
+	node := ctx.Node
+	if len(args) == 3 {
+		items, err := xpath.Call(ctx, args[2:3])
+		if err != nil {
+			return nil, err
+		}
+		if items.Empty() {
+			return nil, fmt.Errorf("key: node expected")
+		}
+		node = items.First().Node()
+	}
+	for node.Parent() != nil {
+		node = node.Parent()
+	}
+	var (
+		list  []xml.Node
+		found bool
+	)
+	for _, k := range s.sheet.Keys {
+		if k.Name != name {
+			continue
+		}
+		found = true
+		index, err := s.keyIndex(k, node)
+		if err != nil {
+			return nil, err
+		}
+		for i := range values {
+			for _, n := range index[toString(values[i])] {
+				if !slices.Contains(list, n) {
+					list = append(list, n)
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: key %w", name, errUndefined)
+	}
+	slices.SortFunc(list, func(a, b xml.Node) int {
+		if a == b {
+			return 0
+		}
+		if xml.Before(a, b) {
+			return -1
+		}
+		return 1
+	})
+	var seq xpath.Sequence
+	for _, n := range list {
+		seq.Append(xpath.NewNodeItem(n))
+	}
+	return seq, nil
+}
+
+func (s *Session) keyIndex(k *Key, root xml.Node) (map[string][]xml.Node, error) {
+	id := indexKey{
+		key:  k,
+		root: root,
+	}
+	if index, ok := s.keys[id]; ok {
+		return index, nil
+	}
+	index := make(map[string][]xml.Node)
+	if err := s.buildKeyIndex(k, root, index); err != nil {
+		return nil, err
+	}
+	if s.keys == nil {
+		s.keys = make(map[indexKey]map[string][]xml.Node)
+	}
+	s.keys[id] = index
+	return index, nil
+}
+
+func (s *Session) buildKeyIndex(k *Key, node xml.Node, index map[string][]xml.Node) error {
+	if k.Match.Match(node) {
+		var (
+			items xpath.Sequence
+			err   error
+		)
+		if k.Use != nil {
+			items, err = k.Use.Find(node)
+		} else {
+			items, err = executeConstructor(s.createContext(node), k.Nodes, 0)
+		}
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			str := toString(items[i])
+			index[str] = append(index[str], node)
+		}
+	}
+	switch n := node.(type) {
+	case *xml.Document:
+		for _, c := range n.Nodes {
+			if err := s.buildKeyIndex(k, c, index); err != nil {
+				return err
+			}
+		}
+	case *xml.Element:
+		for i := range n.Attrs {
+			if err := s.buildKeyIndex(k, &n.Attrs[i], index); err != nil {
+				return err
+			}
+		}
+		for _, c := range n.Nodes {
+			if err := s.buildKeyIndex(k, c, index); err != nil {
+				return err
+			}
+		}
+	default:
+	}
+	return nil
 }
 
 func callDocument(ctx xpath.Context, _ []xpath.Expr) (xpath.Sequence, error) {
@@ -0,0 +1,93 @@
+package xslt
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+type Pipeline struct {
+	sheets []*Stylesheet
+	params map[string]xpath.Expr
+
+	diagnostics []Diagnostic
+}
+
+func Chain(sheets ...*Stylesheet) *Pipeline {
+	return &Pipeline{
+		sheets: sheets,
+		params: make(map[string]xpath.Expr),
+	}
+}
+
+func (p *Pipeline) SetParam(ident string, expr xpath.Expr) {
+	p.params[ident] = expr
+}
+
+func (p *Pipeline) SetParamValue(ident string, value any) error {
+	seq, err := xpath.NewSequenceFromValue(value)
+	if err != nil {
+		return err
+	}
+	p.SetParam(ident, xpath.NewValueFromSequence(seq))
+	return nil
+}
+
+func (p *Pipeline) Execute(doc xml.Node) ([]xml.Node, error) {
+	if len(p.sheets) == 0 {
+		return nil, fmt.Errorf("no stylesheet to be executed")
+	}
+	p.diagnostics = p.diagnostics[:0]
+
+	var nodes []xml.Node
+	for i, sheet := range p.sheets {
+		run := sheet.NewSession()
+		for ident, expr := range p.params {
+			run.SetParam(ident, expr)
+		}
+		res, err := run.Execute(doc)
+		p.diagnostics = append(p.diagnostics, run.Diagnostics()...)
+		if err != nil {
+			return nil, fmt.Errorf("pass %d: %w", i+1, err)
+		}
+		nodes = res
+		doc = intermediateDocument(res)
+	}
+	return nodes, nil
+}
+
+func (p *Pipeline) Generate(w io.Writer, doc *xml.Document) error {
+	nodes, err := p.Execute(doc)
+	if err != nil {
+		return err
+	}
+	last := p.sheets[len(p.sheets)-1]
+	return last.getOutput("").Serialize(w, nodes)
+}
+
+func (p *Pipeline) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+func intermediateDocument(nodes []xml.Node) *xml.Document {
+	doc := xml.EmptyDocument()
+	for _, n := range nodes {
+		if d, ok := n.(*xml.Document); ok {
+			for _, c := range d.Nodes {
+				doc.AppendNode(intermediateNode(c))
+			}
+			continue
+		}
+		doc.AppendNode(intermediateNode(n))
+	}
+	return doc
+}
+
+func intermediateNode(node xml.Node) xml.Node {
+	if c := cloneNode(node); c != nil {
+		return c
+	}
+	return node
+}
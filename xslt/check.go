@@ -0,0 +1,318 @@
+package xslt
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath/avt"
+)
+
+type Problem struct {
+	File        string
+	Instruction string
+	Location    xml.Position
+	Err         error
+}
+
+func (p Problem) Error() string {
+	var prefix string
+	if p.File != "" {
+		prefix = p.File + ":"
+	}
+	if !p.Location.Zero() {
+		prefix += p.Location.String() + ":"
+	}
+	if prefix != "" {
+		prefix += " "
+	}
+	return fmt.Sprintf("%s%s: %s", prefix, p.Instruction, p.Err)
+}
+
+func (p Problem) Unwrap() error {
+	return p.Err
+}
+
+type ProblemList []Problem
+
+func (p ProblemList) Error() string {
+	list := make([]string, 0, len(p))
+	for i := range p {
+		list = append(list, p[i].Error())
+	}
+	return strings.Join(list, "\n")
+}
+
+func (p ProblemList) Unwrap() []error {
+	list := make([]error, 0, len(p))
+	for i := range p {
+		list = append(list, p[i])
+	}
+	return list
+}
+
+type sheetSource struct {
+	uri  string
+	root *xml.Element
+}
+
+var (
+	exprAttributes = []string{
+		"select",
+		"test",
+		"use",
+		"group-by",
+		"group-adjacent",
+		"initial-value",
+		"value",
+		"start-at",
+	}
+	patternAttributes = []string{
+		"match",
+		"group-starting-with",
+		"group-ending-with",
+		"count",
+		"from",
+	}
+	reservedModes = []string{
+		"",
+		currentMode,
+		defaultMode,
+		allValues,
+		"#unnamed",
+	}
+)
+
+var boundVariable = regexp.MustCompile(`\$([\pL_][\pL\pN_.\-]*)\s*(?:in\b|:=|as\b)`)
+
+// Check compiles every expression and pattern of the stylesheet and verifies
+// that the templates, modes, attribute sets and variables it references exist.
+// All the problems found are returned as a ProblemList.
+func (s *Stylesheet) Check() error {
+	c := checker{
+		top:     s,
+		globals: s.globalNames(),
+	}
+	c.checkSheet(s)
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return c.problems
+}
+
+func (s *Stylesheet) globalNames() []string {
+	list := slices.Concat(s.env.Variables(), s.static.Variables())
+	for _, o := range s.Others {
+		list = append(list, o.globalNames()...)
+	}
+	return list
+}
+
+func (s *Stylesheet) hasNamedTemplate(name string) bool {
+	for _, m := range s.Modes {
+		ok := slices.ContainsFunc(m.Templates, func(t *Template) bool {
+			return t.Name == name
+		})
+		if ok {
+			return true
+		}
+	}
+	return slices.ContainsFunc(s.Others, func(o *Stylesheet) bool {
+		return o.hasNamedTemplate(name)
+	})
+}
+
+func (s *Stylesheet) hasAttributeSet(name string) bool {
+	ok := slices.ContainsFunc(s.AttrSet, func(set *AttributeSet) bool {
+		return set.Name == name
+	})
+	return ok || slices.ContainsFunc(s.Others, func(o *Stylesheet) bool {
+		return o.hasAttributeSet(name)
+	})
+}
+
+type checker struct {
+	top      *Stylesheet
+	globals  []string
+	problems ProblemList
+
+	sheet *Stylesheet
+	file  string
+}
+
+func (c *checker) checkSheet(sheet *Stylesheet) {
+	c.sheet = sheet
+	for _, src := range sheet.sources {
+		c.file = src.uri
+		for _, n := range src.root.Nodes {
+			if el, ok := n.(*xml.Element); ok {
+				c.checkElement(el, nil)
+			}
+		}
+	}
+	for _, o := range sheet.Others {
+		c.checkSheet(o)
+	}
+}
+
+func (c *checker) checkElement(elem *xml.Element, scope []string) {
+	if elem.QualifiedName() == c.sheet.getQualifiedName("accumulator-rule") {
+		scope = append(slices.Clip(scope), "value")
+	}
+	if c.isXsl(elem) {
+		c.checkInstruction(elem, scope)
+	} else {
+		c.checkLiteral(elem, scope)
+	}
+	for _, n := range elem.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok || el.QualifiedName() != c.sheet.getQualifiedName("param") {
+			continue
+		}
+		if ident, err := getAttribute(el, "name"); err == nil {
+			scope = append(slices.Clip(scope), ident)
+		}
+	}
+	for _, n := range elem.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		c.checkElement(el, scope)
+		if el.QualifiedName() != c.sheet.getQualifiedName("variable") {
+			continue
+		}
+		if ident, err := getAttribute(el, "name"); err == nil {
+			scope = append(slices.Clip(scope), ident)
+		}
+	}
+}
+
+func (c *checker) checkInstruction(elem *xml.Element, scope []string) {
+	for _, a := range elem.Attrs {
+		switch name := a.QualifiedName(); {
+		case a.Space != "":
+		case slices.Contains(exprAttributes, name):
+			c.checkExpr(elem, a.Value(), scope)
+		case slices.Contains(patternAttributes, name):
+			if _, err := compileMatchWithEnv(c.sheet.env, a.Value()); err != nil {
+				c.report(elem, fmt.Errorf("%s: %w", name, err))
+			}
+		default:
+			c.checkAVT(elem, a.Value(), scope)
+		}
+	}
+	switch elem.QualifiedName() {
+	case c.sheet.getQualifiedName("call-template"):
+		name, err := getAttribute(elem, "name")
+		if err == nil && !c.top.hasNamedTemplate(name) {
+			c.report(elem, fmt.Errorf("%s: template %w", name, errUndefined))
+		}
+	case c.sheet.getQualifiedName("apply-templates"):
+		mode, _ := getAttribute(elem, "mode")
+		if !slices.Contains(reservedModes, mode) && c.top.declaredMode(mode) == nil {
+			c.report(elem, fmt.Errorf("%s: mode %w", mode, errUndefined))
+		}
+	case c.sheet.getQualifiedName("element"), c.sheet.getQualifiedName("copy"):
+		if sets, err := getAttribute(elem, "use-attribute-sets"); err == nil {
+			c.checkAttributeSets(elem, sets)
+		}
+	default:
+	}
+}
+
+func (c *checker) checkLiteral(elem *xml.Element, scope []string) {
+	for _, a := range elem.Attrs {
+		if _, ok := declaredPrefix(a); ok {
+			continue
+		}
+		if a.Space == c.sheet.xsltNamespace {
+			if a.Name == "use-attribute-sets" {
+				c.checkAttributeSets(elem, a.Value())
+			}
+			continue
+		}
+		c.checkAVT(elem, a.Value(), scope)
+	}
+}
+
+func (c *checker) checkAttributeSets(elem *xml.Element, sets string) {
+	for _, name := range strings.Fields(sets) {
+		if !c.top.hasAttributeSet(name) {
+			c.report(elem, fmt.Errorf("%s: attribute set %w", name, errUndefined))
+		}
+	}
+}
+
+func (c *checker) checkAVT(elem *xml.Element, value string, scope []string) {
+	if err := avt.Check(value); err != nil {
+		c.report(elem, err)
+		return
+	}
+	for expr, ok := range avt.Split(value) {
+		if ok {
+			c.checkExpr(elem, expr, scope)
+		}
+	}
+}
+
+func (c *checker) checkExpr(elem *xml.Element, expr string, scope []string) {
+	if _, err := c.sheet.env.Create(expr); err != nil {
+		c.report(elem, fmt.Errorf("%s: %w", expr, err))
+		return
+	}
+	var (
+		source = stripLiterals(expr)
+		bound  []string
+	)
+	for _, m := range boundVariable.FindAllStringSubmatch(source, -1) {
+		bound = append(bound, m[1])
+	}
+	for _, ident := range scanVariableRefs(source) {
+		if strings.Contains(ident, ":") || slices.Contains(bound, ident) {
+			continue
+		}
+		if slices.Contains(scope, ident) || slices.Contains(c.globals, ident) {
+			continue
+		}
+		c.report(elem, fmt.Errorf("$%s: variable %w", ident, errUndefined))
+	}
+}
+
+func (c *checker) isXsl(elem *xml.Element) bool {
+	return elem.Space == c.sheet.xsltNamespace
+}
+
+func (c *checker) report(elem *xml.Element, err error) {
+	p := Problem{
+		File:        c.file,
+		Instruction: elem.QualifiedName(),
+		Location:    elem.Location,
+		Err:         err,
+	}
+	c.problems = append(c.problems, p)
+}
+
+func stripLiterals(expr string) string {
+	var (
+		str   strings.Builder
+		quote rune
+	)
+	for _, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			continue
+		case r == '\'' || r == '"':
+			quote = r
+			continue
+		default:
+			str.WriteRune(r)
+		}
+	}
+	return str.String()
+}
@@ -1,8 +1,10 @@
 package xslt
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
@@ -12,6 +14,25 @@ func Catchable(err error) bool {
 	return true
 }
 
+func Recoverable(err error) bool {
+	return !errors.Is(err, ErrTerminate) && !errors.Is(err, errBreak) &&
+		!errors.Is(err, errIterate) && !errors.Is(err, errSkip)
+}
+
+type Diagnostic struct {
+	Instruction string
+	Context     string
+	Location    xml.Position
+	Err         error
+}
+
+func (d Diagnostic) Error() string {
+	if d.Location.Zero() {
+		return fmt.Sprintf("%s (context: %s): %s", d.Instruction, d.Context, d.Err)
+	}
+	return fmt.Sprintf("%s: %s (context: %s): %s", d.Location, d.Instruction, d.Context, d.Err)
+}
+
 type Context struct {
 	XslNode     xml.Node
 	ContextNode xml.Node
@@ -21,12 +42,20 @@ type Context struct {
 	Size  int
 	Depth int
 
+	catching  bool
+	iterating bool
+
 	*Stylesheet
 
-	env *xpath.Evaluator
+	session *Session
+	env     *xpath.Evaluator
+	tunnels map[string]xpath.Expr
 }
 
 func (c *Context) Serialize(file, format string, doc xml.Node) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
 	w, err := os.Create(file)
 	if err != nil {
 		return err
@@ -74,6 +103,9 @@ func (c *Context) ResolveAliasNS(ident string) (xml.NS, error) {
 		return ns, err
 	}
 	ns.Uri, err = c.env.ResolveNS(ns.Prefix)
+	if ns.Prefix == "" {
+		err = nil
+	}
 	return ns, err
 }
 
@@ -150,8 +182,12 @@ func (c *Context) clone(xslNode, ctxNode xml.Node) *Context {
 		Index:       1,
 		Size:        1,
 		Stylesheet:  c.Stylesheet,
+		session:     c.session,
 		env:         c.env,
+		tunnels:     c.tunnels,
 		Depth:       c.Depth + 1,
+		catching:    c.catching,
+		iterating:   c.iterating,
 	}
 	return &child
 }
@@ -174,6 +210,25 @@ func (c *Context) getMode(mode string) string {
 	}
 }
 
+func (c *Context) recover(err error) (xpath.Sequence, error) {
+	if !c.Permissive || c.catching || !Recoverable(err) {
+		return nil, err
+	}
+	d := Diagnostic{
+		Err: err,
+	}
+	if c.XslNode != nil {
+		d.Instruction = c.XslNode.QualifiedName()
+		d.Location = xml.LocationOf(c.XslNode)
+	}
+	if c.ContextNode != nil {
+		d.Context = c.ContextNode.QualifiedName()
+	}
+	c.session.diagnostics = append(c.session.diagnostics, d)
+	comment := xml.NewComment(fmt.Sprintf(" error: %s ", err))
+	return xpath.Singleton(comment), nil
+}
+
 func errorWithContext(ctx string, err error) error {
 	return fmt.Errorf("%s: %w", ctx, err)
 }
@@ -0,0 +1,190 @@
+package xslt
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/midbel/codecs/json"
+	"github.com/midbel/codecs/xml"
+)
+
+type Coverage struct {
+	mu    sync.Mutex
+	sheet *Stylesheet
+	runs  int
+	hits  map[*xml.Element]int
+}
+
+func (s *Stylesheet) EnableCoverage() *Coverage {
+	if s.coverage == nil {
+		s.coverage = &Coverage{
+			sheet: s,
+			hits:  make(map[*xml.Element]int),
+		}
+	}
+	return s.coverage
+}
+
+func (c *Coverage) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runs = 0
+	clear(c.hits)
+}
+
+func (c *Coverage) run() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runs++
+}
+
+func (c *Coverage) hit(t *Template) {
+	if t.elem == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits[t.elem]++
+}
+
+func (c *Coverage) Report() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := CoverageReport{
+		Runs: c.runs,
+	}
+	c.collect(c.sheet, &report)
+	slices.SortStableFunc(report.Modes, func(a, b ModeCoverage) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return report
+}
+
+func (c *Coverage) collect(sheet *Stylesheet, report *CoverageReport) {
+	for _, m := range sheet.Modes {
+		ix := slices.IndexFunc(report.Modes, func(mc ModeCoverage) bool {
+			return mc.Name == m.Name
+		})
+		if ix < 0 {
+			report.Modes = append(report.Modes, ModeCoverage{Name: m.Name})
+			ix = len(report.Modes) - 1
+		}
+		for _, t := range m.Templates {
+			if t.elem == nil {
+				continue
+			}
+			tc := TemplateCoverage{
+				Name:     t.Name,
+				Match:    t.Match,
+				Mode:     m.Name,
+				Priority: t.Priority,
+				File:     sheet.sourceOf(t.elem),
+				Location: t.elem.Location,
+				Count:    c.hits[t.elem],
+			}
+			report.Templates = append(report.Templates, tc)
+
+			mc := &report.Modes[ix]
+			mc.Templates++
+			mc.Count += tc.Count
+			if tc.Count > 0 {
+				mc.Fired++
+			}
+		}
+	}
+	for _, o := range sheet.Others {
+		c.collect(o, report)
+	}
+}
+
+func (s *Stylesheet) sourceOf(elem *xml.Element) string {
+	var root xml.Node = elem
+	for root.Parent() != nil && root.Parent().Type() == xml.TypeElement {
+		root = root.Parent()
+	}
+	for _, src := range s.sources {
+		if xml.Node(src.root) == root {
+			return src.uri
+		}
+	}
+	return ""
+}
+
+type TemplateCoverage struct {
+	Name     string
+	Match    string
+	Mode     string
+	Priority float64
+	File     string
+	Location xml.Position
+	Count    int
+}
+
+func (t TemplateCoverage) Fired() bool {
+	return t.Count > 0
+}
+
+type ModeCoverage struct {
+	Name      string
+	Templates int
+	Fired     int
+	Count     int
+}
+
+type CoverageReport struct {
+	Runs      int
+	Templates []TemplateCoverage
+	Modes     []ModeCoverage
+}
+
+func (r CoverageReport) Fired() int {
+	var n int
+	for _, t := range r.Templates {
+		if t.Fired() {
+			n++
+		}
+	}
+	return n
+}
+
+func (r CoverageReport) Unused() []TemplateCoverage {
+	return slices.DeleteFunc(slices.Clone(r.Templates), TemplateCoverage.Fired)
+}
+
+func (r CoverageReport) WriteJSON(w io.Writer) error {
+	templates := make([]any, 0, len(r.Templates))
+	for _, t := range r.Templates {
+		templates = append(templates, map[string]any{
+			"name":     t.Name,
+			"match":    t.Match,
+			"mode":     t.Mode,
+			"priority": t.Priority,
+			"file":     t.File,
+			"line":     int64(t.Location.Line),
+			"column":   int64(t.Location.Column),
+			"count":    int64(t.Count),
+		})
+	}
+	modes := make([]any, 0, len(r.Modes))
+	for _, m := range r.Modes {
+		modes = append(modes, map[string]any{
+			"name":      m.Name,
+			"templates": int64(m.Templates),
+			"fired":     int64(m.Fired),
+			"count":     int64(m.Count),
+		})
+	}
+	doc := map[string]any{
+		"runs":      int64(r.Runs),
+		"total":     int64(len(r.Templates)),
+		"fired":     int64(r.Fired()),
+		"templates": templates,
+		"modes":     modes,
+	}
+	ws := json.NewWriter(w)
+	ws.SortKeys = true
+	return ws.Write(doc)
+}
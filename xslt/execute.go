@@ -3,11 +3,13 @@ package xslt
 import (
 	"errors"
 	"fmt"
-	"iter"
 	"maps"
+	"math"
+	"regexp"
 	"slices"
-	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
@@ -17,7 +19,6 @@ type ExecuteFunc func(*Context) (xpath.Sequence, error)
 
 var (
 	executers         map[xml.QName]ExecuteFunc
-	iterateExecuters  map[xml.QName]ExecuteFunc
 	mergeExecuters    map[xml.QName]ExecuteFunc
 	forgroupExecuters map[xml.QName]ExecuteFunc
 )
@@ -39,14 +40,14 @@ func init() {
 		}
 		return fn
 	}
-	iterateExecuters = map[xml.QName]ExecuteFunc{
-		xsltQualifiedName("next-iteration"): single(executeNextIteration),
-		xsltQualifiedName("break"):          single(executeBreak),
-	}
 	executers = map[xml.QName]ExecuteFunc{
 		xsltQualifiedName("for-each"):               nest(executeForeach),
+		xsltQualifiedName("analyze-string"):         nest(executeAnalyzeString),
 		xsltQualifiedName("iterate"):                nest(executeIterate),
+		xsltQualifiedName("next-iteration"):         single(executeNextIteration),
+		xsltQualifiedName("break"):                  single(executeBreak),
 		xsltQualifiedName("value-of"):               single(executeValueOf),
+		xsltQualifiedName("number"):                 single(executeNumber),
 		xsltQualifiedName("call-template"):          nest(executeCallTemplate),
 		xsltQualifiedName("apply-templates"):        nest(executeApplyTemplates),
 		xsltQualifiedName("apply-imports"):          nest(executeApplyImport),
@@ -81,21 +82,12 @@ func init() {
 	}
 }
 
-func registerExecuters(set map[xml.QName]ExecuteFunc) bool {
-	for qn, fn := range set {
-		_, ok := executers[qn]
-		if ok {
-			return false
-		}
-		executers[qn] = fn
-	}
-	return true
-}
-
-func unregisterExecuters(set map[xml.QName]ExecuteFunc) {
-	for qn := range set {
-		delete(executers, qn)
+func getExecuter(name xml.QName, prefix string) (ExecuteFunc, bool) {
+	if name.Uri == xsltNamespaceUri || (name.Uri == "" && name.Space == prefix) {
+		name = xsltQualifiedName(name.Name)
 	}
+	fn, ok := executers[name]
+	return fn, ok
 }
 
 func executeSourceDocument(ctx *Context) (xpath.Sequence, error) {
@@ -107,7 +99,23 @@ func executeSourceDocument(ctx *Context) (xpath.Sequence, error) {
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
 	}
-	doc, err := ctx.LoadDocument(file)
+	if file, err = evalAVT(ctx, file); err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	streamable, err := elem.AttrBool("streamable", false)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if streamable {
+		if foreach, steps, ok := getStreamableForeach(ctx, elem.Nodes); ok {
+			seq, err := streamSourceDocument(ctx, ctx.documentPath(file), foreach, steps)
+			if err != nil {
+				return nil, ctx.errorWithContext(err)
+			}
+			return seq, nil
+		}
+	}
+	doc, err := ctx.session.loadDocument(file)
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
 	}
@@ -128,10 +136,20 @@ func executeResultDocument(ctx *Context) (xpath.Sequence, error) {
 		doc.Nodes = append(doc.Nodes, seq[i].Node())
 	}
 
-	file, err := getAttribute(elem, "href")
+	href, err := getAttribute(elem, "href")
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if href, err = evalAVT(ctx, href); err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	file, err := ctx.ResolveOutput(href)
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
 	}
+	if err := ctx.session.claimOutput(file); err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
 	format, _ := getAttribute(elem, "format")
 	if err := ctx.Serialize(file, format, &doc); err != nil {
 		return nil, ctx.errorWithContext(err)
@@ -175,7 +193,27 @@ func executeVariable(ctx *Context) (xpath.Sequence, error) {
 }
 
 func executeApplyImport(ctx *Context) (xpath.Sequence, error) {
-	return executeApply(ctx, ctx.MatchImport)
+	elem, err := getElementFromNode(ctx.XslNode)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	exec, err := ctx.MatchImport(ctx.ContextNode, currentMode)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	sub, err := applyTemplateParams(ctx, exec, elem.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := exec.Execute(sub)
+	if err != nil {
+		return nil, err
+	}
+	var seq xpath.Sequence
+	for i := range nodes {
+		seq.Append(xpath.NewNodeItem(nodes[i]))
+	}
+	return seq, nil
 }
 
 func executeApplyTemplates(ctx *Context) (xpath.Sequence, error) {
@@ -523,6 +561,10 @@ func executeMerge(ctx *Context) (xpath.Sequence, error) {
 }
 
 func executeBreak(ctx *Context) (xpath.Sequence, error) {
+	if !ctx.iterating {
+		err := fmt.Errorf("instruction not expected outside of iterate")
+		return nil, ctx.errorWithContext(err)
+	}
 	elem, err := getElementFromNode(ctx.XslNode)
 	if err != nil {
 		return nil, err
@@ -543,6 +585,10 @@ func executeBreak(ctx *Context) (xpath.Sequence, error) {
 }
 
 func executeNextIteration(ctx *Context) (xpath.Sequence, error) {
+	if !ctx.iterating {
+		err := fmt.Errorf("instruction not expected outside of iterate")
+		return nil, ctx.errorWithContext(err)
+	}
 	el, err := getElementFromNode(ctx.XslNode)
 	if err != nil {
 		return nil, err
@@ -578,10 +624,6 @@ func executeNextIteration(ctx *Context) (xpath.Sequence, error) {
 }
 
 func executeIterate(ctx *Context) (xpath.Sequence, error) {
-	if registerExecuters(iterateExecuters) {
-		defer unregisterExecuters(iterateExecuters)
-	}
-
 	elem, err := getElementFromNode(ctx.XslNode)
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
@@ -606,6 +648,7 @@ func executeIterate(ctx *Context) (xpath.Sequence, error) {
 		nodes = nodes[1:]
 	}
 	nest := ctx.Sub()
+	nest.iterating = true
 	for i := range nodes {
 		if nodes[i].QualifiedName() != ctx.getQualifiedName("param") {
 			nodes = nodes[i:]
@@ -677,10 +720,7 @@ func executeForeach(ctx *Context) (xpath.Sequence, error) {
 		return nil, ctx.errorWithContext(err)
 	}
 
-	var (
-		nodes = slices.Clone(elem.Nodes)
-		it    iter.Seq[xpath.Item]
-	)
+	nodes := slices.Clone(elem.Nodes)
 
 	items, err := ctx.Execute(query)
 	if err != nil {
@@ -693,18 +733,16 @@ func executeForeach(ctx *Context) (xpath.Sequence, error) {
 		}
 		return nil, nil
 	}
-	if len(nodes) > 0 && nodes[0].QualifiedName() == ctx.getQualifiedName("sort") {
-		it, err = applySort(nodes[0], items)
-		if err != nil {
-			return nil, ctx.errorWithContext(err)
-		}
-		nodes = nodes[1:]
-	} else {
-		it = slices.Values(items)
+	keys, nodes, err := getSortKeys(ctx, nodes)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if items, err = sortItems(ctx, items, keys); err != nil {
+		return nil, ctx.errorWithContext(err)
 	}
 
 	var seq xpath.Sequence
-	for i := range it {
+	for _, i := range items {
 		node := i.Node()
 		others, err := executeConstructor(ctx.WithXpath(node), nodes, AllowOnEmpty|AllowOnNonEmpty)
 		if err != nil {
@@ -752,7 +790,9 @@ func executeTry(ctx *Context) (xpath.Sequence, error) {
 		if !errors.Is(err, errMissed) {
 			return nil, ctx.errorWithContext(err)
 		}
-		seq, err = executeConstructor(ctx, body, 0)
+		sub := ctx.Copy()
+		sub.catching = len(catch) > 0
+		seq, err = executeConstructor(sub, body, 0)
 	}
 	if err == nil {
 		return seq, nil
@@ -902,6 +942,286 @@ func executeValueOf(ctx *Context) (xpath.Sequence, error) {
 	return xpath.Singleton(xml.NewText(str.String())), nil
 }
 
+func executeNumber(ctx *Context) (xpath.Sequence, error) {
+	elem, err := getElementFromNode(ctx.XslNode)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	format, err := getAttribute(elem, "format")
+	if err != nil {
+		format = "1"
+	}
+	var nums []int
+	if query, err1 := getAttribute(elem, "value"); err1 == nil {
+		items, err := ctx.Execute(query)
+		if err != nil {
+			return nil, ctx.errorWithContext(err)
+		}
+		for i := range items {
+			n, err := toNumber(items[i])
+			if err != nil {
+				return nil, ctx.errorWithContext(err)
+			}
+			nums = append(nums, n)
+		}
+	} else {
+		node := ctx.ContextNode
+		if query, err1 := getAttribute(elem, "select"); err1 == nil {
+			items, err := ctx.Execute(query)
+			if err != nil {
+				return nil, ctx.errorWithContext(err)
+			}
+			if len(items) != 1 || items[0].Node() == nil {
+				err := fmt.Errorf("select should return a single node")
+				return nil, ctx.errorWithContext(err)
+			}
+			node = items[0].Node()
+		}
+		count := func(other xml.Node) bool {
+			return other.Type() == node.Type() && other.QualifiedName() == node.QualifiedName()
+		}
+		if pattern, err1 := getAttribute(elem, "count"); err1 == nil {
+			m, err := compileMatchWithEnv(ctx.env, pattern)
+			if err != nil {
+				return nil, ctx.errorWithContext(err)
+			}
+			count = m.Match
+		}
+		from := func(_ xml.Node) bool {
+			return false
+		}
+		if pattern, err1 := getAttribute(elem, "from"); err1 == nil {
+			m, err := compileMatchWithEnv(ctx.env, pattern)
+			if err != nil {
+				return nil, ctx.errorWithContext(err)
+			}
+			from = m.Match
+		}
+		level, err := getAttribute(elem, "level")
+		if err != nil {
+			level = "single"
+		}
+		switch level {
+		case "single":
+			nums = numberSingle(node, count, from)
+		case "multiple":
+			nums = numberMultiple(node, count, from)
+		case "any":
+			nums = numberAny(node, count, from)
+		default:
+			err := fmt.Errorf("%s: invalid value for level attribute", level)
+			return nil, ctx.errorWithContext(err)
+		}
+	}
+	return xpath.Singleton(xml.NewText(formatNumbers(nums, format))), nil
+}
+
+func toNumber(item xpath.Item) (int, error) {
+	switch v := item.Value().(type) {
+	case float64:
+		return int(math.Round(v)), nil
+	case int64:
+		return int(v), nil
+	case xpath.Decimal:
+		return int(math.Round(v.Float64())), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, err
+		}
+		return int(math.Round(f)), nil
+	default:
+		return 0, fmt.Errorf("%v: number expected", v)
+	}
+}
+
+func numberSingle(node xml.Node, count, from func(xml.Node) bool) []int {
+	for n := node; n != nil && n.Type() != xml.TypeDocument; n = n.Parent() {
+		if count(n) {
+			return []int{siblingNumber(n, count)}
+		}
+		if from(n) {
+			break
+		}
+	}
+	return nil
+}
+
+func numberMultiple(node xml.Node, count, from func(xml.Node) bool) []int {
+	var nums []int
+	for n := node; n != nil && n.Type() != xml.TypeDocument; n = n.Parent() {
+		if count(n) {
+			nums = append(nums, siblingNumber(n, count))
+		}
+		if from(n) {
+			break
+		}
+	}
+	slices.Reverse(nums)
+	return nums
+}
+
+func numberAny(node xml.Node, count, from func(xml.Node) bool) []int {
+	root := node
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+	var (
+		num  int
+		done bool
+		walk func(xml.Node)
+	)
+	walk = func(n xml.Node) {
+		if done {
+			return
+		}
+		if from(n) {
+			num = 0
+		}
+		if count(n) {
+			num++
+		}
+		if n == node {
+			done = true
+			return
+		}
+		for _, c := range childNodes(n) {
+			walk(c)
+		}
+	}
+	walk(root)
+	if num == 0 {
+		return nil
+	}
+	return []int{num}
+}
+
+func siblingNumber(node xml.Node, count func(xml.Node) bool) int {
+	num := 1
+	for _, n := range childNodes(node.Parent()) {
+		if n == node {
+			break
+		}
+		if count(n) {
+			num++
+		}
+	}
+	return num
+}
+
+func childNodes(node xml.Node) []xml.Node {
+	switch n := node.(type) {
+	case *xml.Document:
+		return n.Nodes
+	case *xml.Element:
+		return n.Nodes
+	default:
+		return nil
+	}
+}
+
+func formatNumbers(nums []int, format string) string {
+	var (
+		tokens []string
+		seps   []string
+		prefix string
+		suffix string
+		offset int
+	)
+	isAlnum := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	for offset < len(format) {
+		ix := strings.IndexFunc(format[offset:], isAlnum)
+		if ix < 0 {
+			suffix = format[offset:]
+			break
+		}
+		if len(tokens) == 0 {
+			prefix = format[offset : offset+ix]
+		} else {
+			seps = append(seps, format[offset:offset+ix])
+		}
+		offset += ix
+		ix = strings.IndexFunc(format[offset:], func(r rune) bool {
+			return !isAlnum(r)
+		})
+		if ix < 0 {
+			ix = len(format) - offset
+		}
+		tokens = append(tokens, format[offset:offset+ix])
+		offset += ix
+	}
+	if len(tokens) == 0 {
+		tokens = append(tokens, "1")
+	}
+	var str strings.Builder
+	str.WriteString(prefix)
+	for i, n := range nums {
+		if i > 0 {
+			sep := "."
+			if len(seps) > 0 {
+				sep = seps[min(i-1, len(seps)-1)]
+			}
+			str.WriteString(sep)
+		}
+		str.WriteString(formatToken(n, tokens[min(i, len(tokens)-1)]))
+	}
+	str.WriteString(suffix)
+	return str.String()
+}
+
+func formatToken(num int, token string) string {
+	switch token {
+	case "a":
+		return formatAlpha(num, 'a')
+	case "A":
+		return formatAlpha(num, 'A')
+	case "i":
+		return strings.ToLower(formatRoman(num))
+	case "I":
+		return formatRoman(num)
+	default:
+		str := strconv.Itoa(num)
+		if n := len(token); n > len(str) && strings.Trim(token, "0123456789") == "" {
+			str = strings.Repeat("0", n-len(str)) + str
+		}
+		return str
+	}
+}
+
+func formatAlpha(num int, base rune) string {
+	if num <= 0 {
+		return strconv.Itoa(num)
+	}
+	var list []rune
+	for num > 0 {
+		num--
+		list = append(list, base+rune(num%26))
+		num /= 26
+	}
+	slices.Reverse(list)
+	return string(list)
+}
+
+func formatRoman(num int) string {
+	if num <= 0 || num >= 4000 {
+		return strconv.Itoa(num)
+	}
+	var (
+		values  = []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+		symbols = []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+		str     strings.Builder
+	)
+	for i := range values {
+		for num >= values[i] {
+			str.WriteString(symbols[i])
+			num -= values[i]
+		}
+	}
+	return str.String()
+}
+
 func executeCopy(ctx *Context) (xpath.Sequence, error) {
 	elem, err := getElementFromNode(ctx.ContextNode)
 	if err != nil {
@@ -954,7 +1274,11 @@ func executeMessage(ctx *Context) (xpath.Sequence, error) {
 	for _, n := range elem.Nodes {
 		parts = append(parts, n.Value())
 	}
-	if quit, err := getAttribute(elem, "terminate"); err == nil && quit == "yes" {
+	quit, err := elem.AttrBool("terminate", false)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if quit {
 		return nil, ErrTerminate
 	}
 	return nil, nil
@@ -1033,15 +1357,108 @@ func getMatchingElements(ctx *Context, elem *xml.Element) (xml.Node, xml.Node, e
 }
 
 func executeAnalyzeString(ctx *Context) (xpath.Sequence, error) {
-	return nil, errImplemented
+	elem, err := getElementFromNode(ctx.XslNode)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	query, err := getAttribute(elem, "select")
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	pattern, err := getAttribute(elem, "regex")
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	flags, _ := getAttribute(elem, "flags")
+	re, err := compileRegex(pattern, flags)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if re.MatchString("") {
+		err := fmt.Errorf("%s: regex matches zero-length string", pattern)
+		return nil, ctx.errorWithContext(err)
+	}
+
+	match, nomatch, err := getMatchingElements(ctx, elem)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := ctx.Execute(query)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if items.Empty() {
+		return nil, nil
+	}
+	var (
+		str    = toString(items.First())
+		seq    xpath.Sequence
+		offset int
+	)
+	analyze := func(node xml.Node, part string, groups []string) error {
+		if node == nil {
+			return nil
+		}
+		sub := ctx.WithXpath(xml.NewText(part)).Sub()
+		defineAnalyzeStringBuiltins(sub, groups)
+		others, err := executeSubstring(sub.WithXsl(node))
+		if err == nil {
+			seq.Concat(others)
+		}
+		return err
+	}
+	for _, m := range re.FindAllStringSubmatchIndex(str, -1) {
+		if m[0] > offset {
+			if err := analyze(nomatch, str[offset:m[0]], nil); err != nil {
+				return nil, err
+			}
+		}
+		groups := make([]string, len(m)/2)
+		for i := range groups {
+			if m[i*2] >= 0 {
+				groups[i] = str[m[i*2]:m[i*2+1]]
+			}
+		}
+		if err := analyze(match, groups[0], groups); err != nil {
+			return nil, err
+		}
+		offset = m[1]
+	}
+	if offset < len(str) {
+		if err := analyze(nomatch, str[offset:], nil); err != nil {
+			return nil, err
+		}
+	}
+	return seq, nil
 }
 
-func executeMatchingSubstring(ctx *Context) (xpath.Sequence, error) {
-	return nil, errImplemented
+func executeSubstring(ctx *Context) (xpath.Sequence, error) {
+	elem, err := getElementFromNode(ctx.XslNode)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	return executeConstructor(ctx, elem.Nodes, 0)
 }
 
-func executeNonMatchingSubstring(ctx *Context) (xpath.Sequence, error) {
-	return nil, errImplemented
+func compileRegex(pattern, flags string) (*regexp.Regexp, error) {
+	var mods string
+	for _, f := range flags {
+		switch f {
+		case 'i', 's', 'm':
+			mods += string(f)
+		case 'x':
+			pattern = strings.Join(strings.Fields(pattern), "")
+		case 'q':
+			pattern = regexp.QuoteMeta(pattern)
+		default:
+			return nil, fmt.Errorf("%c: invalid regex flag", f)
+		}
+	}
+	if mods != "" {
+		pattern = fmt.Sprintf("(?%s)%s", mods, pattern)
+	}
+	return regexp.Compile(pattern)
 }
 
 func executeWherePopulated(ctx *Context) (xpath.Sequence, error) {
@@ -1281,6 +1698,11 @@ func executeElement(ctx *Context) (xpath.Sequence, error) {
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
 	}
+	if uri, err := getAttribute(elem, "namespace"); err == nil {
+		qn.Uri = uri
+	} else {
+		qn.Uri = inScopeNamespace(elem, qn.Space)
+	}
 	seq, err := executeConstructor(ctx, elem.Nodes, AllowOnEmpty|AllowOnNonEmpty)
 	if err != nil {
 		return nil, err
@@ -1290,6 +1712,8 @@ func executeElement(ctx *Context) (xpath.Sequence, error) {
 		n := seq[i].Node()
 		curr.Append(n)
 	}
+	declareDefaultNS(curr)
+	stripDefaultNS(curr)
 	return xpath.Singleton(curr), nil
 }
 
@@ -1340,19 +1764,6 @@ func executeFallback(ctx *Context) (xpath.Sequence, error) {
 	return nil, errImplemented
 }
 
-func applySort(node xml.Node, items xpath.Sequence) (iter.Seq[xpath.Item], error) {
-	elem, err := getElementFromNode(node)
-	if err != nil {
-		return nil, err
-	}
-	query, err := getAttribute(elem, "select")
-	if err != nil {
-		return nil, err
-	}
-	order, _ := getAttribute(elem, "order")
-	return iterItems(items, query, order)
-}
-
 type matchFunc func(xml.Node, string) (Executer, error)
 
 func executeApply(ctx *Context, match matchFunc) (xpath.Sequence, error) {
@@ -1371,13 +1782,20 @@ func executeApply(ctx *Context, match matchFunc) (xpath.Sequence, error) {
 	if err == nil {
 		ctx = ctx.WithMode(mode)
 	}
+	keys, params, err := getSortKeys(ctx, elem.Nodes)
+	if err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
+	if nodes, err = sortNodes(ctx, nodes, keys); err != nil {
+		return nil, ctx.errorWithContext(err)
+	}
 	var seq xpath.Sequence
 	for _, n := range nodes {
 		exec, err := match(n, mode)
 		if err != nil {
 			return seq, err
 		}
-		sub, err := applyTemplateParams(ctx.WithXpath(n), exec, elem.Nodes)
+		sub, err := applyTemplateParams(ctx.WithXpath(n), exec, params)
 		if err != nil {
 			return nil, err
 		}
@@ -1393,11 +1811,12 @@ func executeApply(ctx *Context, match matchFunc) (xpath.Sequence, error) {
 }
 
 func applyTemplateParams(ctx *Context, exec Executer, nodes []xml.Node) (*Context, error) {
-	sub := ctx.Sub()
-	tpl, ok := exec.(*Template)
-	if !ok {
-		return sub, nil
-	}
+	var (
+		sub     = ctx.Sub()
+		tpl, _  = exec.(*Template)
+		tunnels = maps.Clone(ctx.tunnels)
+		bound   = make(map[string]bool)
+	)
 	for _, n := range nodes {
 		if n.QualifiedName() != ctx.getQualifiedName("with-param") {
 			return nil, fmt.Errorf("%s: invalid child node %s", ctx.XslNode.QualifiedName(), n.QualifiedName())
@@ -1410,66 +1829,66 @@ func applyTemplateParams(ctx *Context, exec Executer, nodes []xml.Node) (*Contex
 		if err != nil {
 			return nil, ctx.errorWithContext(err)
 		}
-		if !tpl.hasParam(ident) {
+		tunnel := isTunnel(el)
+		if !tunnel && (tpl == nil || !tpl.hasParam(ident)) {
 			continue
 		}
-		var seq xpath.Sequence
-		if query, err1 := getAttribute(el, "select"); err1 == nil {
-			if len(el.Nodes) != 0 {
-				return nil, fmt.Errorf("select attribute can not be used with children")
-			}
-			seq, err = ctx.Execute(query)
-		} else {
-			if len(el.Nodes) == 0 {
-				err := fmt.Errorf("no value given to param %q", ident)
-				return nil, ctx.errorWithContext(err)
+		seq, err := getParamValue(ctx, el, ident)
+		if err != nil {
+			return nil, err
+		}
+		if tunnel {
+			if tunnels == nil {
+				tunnels = make(map[string]xpath.Expr)
 			}
-			seq, err = executeConstructor(ctx, el.Nodes, 0)
+			tunnels[ident] = xpath.NewValueFromSequence(seq)
+			continue
+		}
+		if !tpl.isTunnel(ident) {
+			sub.Set(ident, xpath.NewValueFromSequence(seq))
+			bound[ident] = true
 		}
-		sub.Set(ident, xpath.NewValueFromSequence(seq))
 	}
-	return sub, nil
-}
-
-func iterItems(items []xpath.Item, orderBy, orderDir string) (iter.Seq[xpath.Item], error) {
-	expr, err := xpath.CompileString(orderBy)
-	if err != nil {
-		return nil, err
+	sub.tunnels = tunnels
+	if tpl == nil {
+		return sub, nil
 	}
-	getString := func(is []xpath.Item) string {
-		if len(is) == 0 {
-			return ""
+	for ident, expr := range tunnels {
+		if tpl.isTunnel(ident) {
+			sub.Set(ident, expr)
+			bound[ident] = true
 		}
-		val := is[0].Value()
-		return val.(string)
 	}
-	var compare func(string, string) bool
-	if orderDir == "descending" {
-		compare = func(str1, str2 string) bool {
-			return strings.Compare(str1, str2) >= 0
+	for ident, expr := range tpl.params {
+		if bound[ident] {
+			continue
 		}
-	} else {
-		compare = func(str1, str2 string) bool {
-			return strings.Compare(str1, str2) < 0
+		seq, err := expr.Find(sub.ContextNode)
+		if err != nil {
+			return nil, err
 		}
+		sub.Set(ident, xpath.NewValueFromSequence(seq))
 	}
-	fn := func(yield func(xpath.Item) bool) {
-		is := slices.Clone(items)
-		sort.Slice(is, func(i, j int) bool {
-			x1, err1 := expr.Find(is[i].Node())
-			x2, err2 := expr.Find(is[j].Node())
-			if err1 != nil || err2 != nil {
-				return false
-			}
-			return compare(getString(x1), getString(x2))
-		})
-		for i := range is {
-			if !yield(is[i]) {
-				break
-			}
+	return sub, nil
+}
+
+func getParamValue(ctx *Context, el *xml.Element, ident string) (xpath.Sequence, error) {
+	if query, err := getAttribute(el, "select"); err == nil {
+		if len(el.Nodes) != 0 {
+			return nil, fmt.Errorf("select attribute can not be used with children")
 		}
+		return ctx.Execute(query)
+	}
+	if len(el.Nodes) == 0 {
+		err := fmt.Errorf("no value given to param %q", ident)
+		return nil, ctx.errorWithContext(err)
 	}
-	return fn, nil
+	return executeConstructor(ctx, el.Nodes, 0)
+}
+
+func isTunnel(el *xml.Element) bool {
+	tunnel, _ := el.AttrBool("tunnel", false)
+	return tunnel
 }
 
 func getNodesForTemplate(ctx *Context) ([]xml.Node, error) {
@@ -1513,6 +1932,35 @@ func defineForeachGroupBuiltins(ctx *Context, key, items xpath.Sequence) {
 	ctx.RegisterFunc("current-grouping-key", currentKey)
 }
 
+func defineAnalyzeStringBuiltins(ctx *Context, groups []string) {
+	regexGroup := func(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("regex-group: invalid number of arguments")
+		}
+		seq, err := args[0].Find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if seq.Empty() {
+			return nil, fmt.Errorf("regex-group: group number expected")
+		}
+		var ix int
+		switch v := seq[0].Value().(type) {
+		case float64:
+			ix = int(v)
+		case int64:
+			ix = int(v)
+		default:
+			return nil, fmt.Errorf("regex-group: group number expected")
+		}
+		if ix < 0 || ix >= len(groups) {
+			return xpath.Singleton(""), nil
+		}
+		return xpath.Singleton(groups[ix]), nil
+	}
+	ctx.RegisterFunc("regex-group", regexGroup)
+}
+
 func defineMergeBuiltins(ctx *Context, key string, all []string, items []MergedItem) {
 	currentKey := func(_ xpath.Context, _ []xpath.Expr) (xpath.Sequence, error) {
 		return xpath.Singleton(key), nil
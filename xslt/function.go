@@ -0,0 +1,281 @@
+package xslt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+type funcParam struct {
+	Name string
+	As   string
+}
+
+type Function struct {
+	xml.QName
+	As     string
+	Params []funcParam
+	Nodes  []xml.Node
+
+	elem  *xml.Element
+	sheet *Stylesheet
+}
+
+func (f *Function) Arity() int {
+	return len(f.Params)
+}
+
+func (f *Function) call(ctx *Context, args []xpath.Sequence) (xpath.Sequence, error) {
+	fctx := ctx.WithXsl(f.elem).Sub()
+	for i, p := range f.Params {
+		seq, err := convertSequence(fctx, args[i], p.As)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", p.Name, err)
+		}
+		fctx.Set(p.Name, xpath.NewValueFromSequence(seq))
+	}
+	seq, err := executeConstructor(fctx, f.Nodes, 0)
+	if err != nil {
+		return nil, err
+	}
+	if seq, err = convertSequence(fctx, seq, f.As); err != nil {
+		return nil, fmt.Errorf("result: %w", err)
+	}
+	return seq, nil
+}
+
+func (s *Stylesheet) loadFunction(node xml.Node) error {
+	elem, err := getElementFromNode(node)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.useWhen(elem); !ok {
+		return nil
+	}
+	name, err := getAttribute(elem, "name")
+	if err != nil {
+		return err
+	}
+	fn := Function{
+		elem:  elem,
+		sheet: s,
+	}
+	if fn.QName, err = xml.ParseName(name); err != nil {
+		return err
+	}
+	if fn.Space == "" {
+		return fmt.Errorf("%s: function name must be prefixed", name)
+	}
+	if fn.Uri, err = s.env.ResolveNS(fn.Space); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fn.As, _ = getAttribute(elem, "as")
+
+	fn.Nodes = elem.Nodes
+	for len(fn.Nodes) > 0 && fn.Nodes[0].QualifiedName() == s.getQualifiedName("param") {
+		el, err := getElementFromNode(fn.Nodes[0])
+		if err != nil {
+			return err
+		}
+		var p funcParam
+		if p.Name, err = getAttribute(el, "name"); err != nil {
+			return err
+		}
+		p.As, _ = getAttribute(el, "as")
+		fn.Params = append(fn.Params, p)
+		fn.Nodes = fn.Nodes[1:]
+	}
+	return s.defineFunction(&fn)
+}
+
+func (s *Stylesheet) defineFunction(fn *Function) error {
+	if s.functions == nil {
+		s.functions = make(map[string][]*Function)
+	}
+	key := fn.ExpandedName()
+	for _, f := range s.functions[key] {
+		if f.Arity() == fn.Arity() {
+			return fmt.Errorf("%s#%d: function already defined", fn.QualifiedName(), fn.Arity())
+		}
+	}
+	s.functions[key] = append(s.functions[key], fn)
+	s.env.RegisterFuncNS(fn.QName, s.callFunction(key))
+	return nil
+}
+
+func (s *Stylesheet) includeFunctions(other *Stylesheet) error {
+	for _, list := range other.functions {
+		for _, fn := range list {
+			if err := s.defineFunction(fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Stylesheet) importFunctions(other *Stylesheet) {
+	for key, list := range other.allFunctions() {
+		s.env.RegisterFuncNS(list[0].QName, s.callFunction(key))
+	}
+}
+
+func (s *Stylesheet) allFunctions() map[string][]*Function {
+	all := make(map[string][]*Function)
+	for _, o := range s.Others {
+		for key, list := range o.allFunctions() {
+			all[key] = append(all[key], list...)
+		}
+	}
+	for key, list := range s.functions {
+		all[key] = append(all[key], list...)
+	}
+	return all
+}
+
+func (s *Stylesheet) findFunction(key string, arity int) *Function {
+	for _, f := range s.functions[key] {
+		if f.Arity() == arity {
+			return f
+		}
+	}
+	for i := len(s.Others) - 1; i >= 0; i-- {
+		if f := s.Others[i].findFunction(key, arity); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Stylesheet) callFunction(key string) xpath.BuiltinFunc {
+	return func(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+		return s.NewSession().invokeFunction(key, ctx, args)
+	}
+}
+
+func (s *Session) callFunction(key string) xpath.BuiltinFunc {
+	return func(ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+		return s.invokeFunction(key, ctx, args)
+	}
+}
+
+func (s *Session) invokeFunction(key string, ctx xpath.Context, args []xpath.Expr) (xpath.Sequence, error) {
+	fn := s.sheet.findFunction(key, len(args))
+	if fn == nil {
+		return nil, fmt.Errorf("%s: no function defined with %d argument(s)", key, len(args))
+	}
+	values := make([]xpath.Sequence, len(args))
+	for i := range args {
+		seq, err := xpath.Call(ctx, args[i:i+1])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = seq
+	}
+	fctx := s.createContext(ctx.Node)
+	fctx.Stylesheet = fn.sheet
+	fctx.SetXpathNamespace(fn.sheet.xpathNamespace)
+	return fn.call(fctx, values)
+}
+
+func convertSequence(ctx *Context, seq xpath.Sequence, as string) (xpath.Sequence, error) {
+	if as == "" {
+		return seq, nil
+	}
+	var (
+		typ = strings.TrimRight(as, "?*+")
+		occ = strings.TrimPrefix(as, typ)
+	)
+	switch n := len(seq); occ {
+	case "":
+		if n != 1 {
+			return nil, fmt.Errorf("%w: expected exactly one item of type %s, got %d", xpath.ErrType, as, n)
+		}
+	case "?":
+		if n > 1 {
+			return nil, fmt.Errorf("%w: expected at most one item of type %s, got %d", xpath.ErrType, as, n)
+		}
+	case "+":
+		if n == 0 {
+			return nil, fmt.Errorf("%w: expected at least one item of type %s", xpath.ErrType, as)
+		}
+	default:
+	}
+	var res xpath.Sequence
+	for _, i := range seq {
+		if isKindTest(typ) {
+			if !matchKindTest(i, typ) {
+				return nil, fmt.Errorf("%w: item is not an instance of %s", xpath.ErrType, typ)
+			}
+			res.Append(i)
+			continue
+		}
+		i, err := convertAtomic(ctx, i, typ)
+		if err != nil {
+			return nil, err
+		}
+		res.Append(i)
+	}
+	return res, nil
+}
+
+func convertAtomic(ctx *Context, item xpath.Item, typ string) (xpath.Item, error) {
+	node := item.Node() != nil
+	if node {
+		item = xpath.NewLiteralItem(item.Node().Value())
+	}
+	sub := ctx.Sub()
+	sub.Set("value", xpath.NewValueFromSequence(xpath.Singleton(item)))
+	if ok, err := sub.Test("$value instance of " + typ); err != nil || ok {
+		return item, err
+	}
+	if !node {
+		return nil, fmt.Errorf("%w: item is not an instance of %s", xpath.ErrType, typ)
+	}
+	seq, err := sub.Execute(typ + "($value)")
+	if err != nil || len(seq) != 1 {
+		return nil, fmt.Errorf("%w: item can not be converted to %s", xpath.ErrType, typ)
+	}
+	return seq.First(), nil
+}
+
+func isKindTest(typ string) bool {
+	return strings.HasSuffix(typ, ")")
+}
+
+func matchKindTest(item xpath.Item, typ string) bool {
+	kind, name, _ := strings.Cut(strings.TrimSuffix(typ, ")"), "(")
+	if kind == "item" {
+		return true
+	}
+	node := item.Node()
+	if node == nil {
+		return false
+	}
+	switch kind {
+	case "node":
+		return true
+	case "element":
+		return node.Type() == xml.TypeElement && matchKindName(node, name)
+	case "attribute":
+		return node.Type() == xml.TypeAttribute && matchKindName(node, name)
+	case "document-node":
+		return node.Type() == xml.TypeDocument
+	case "text":
+		return node.Type() == xml.TypeText
+	case "comment":
+		return node.Type() == xml.TypeComment
+	case "processing-instruction":
+		return node.Type() == xml.TypeInstruction
+	default:
+		return false
+	}
+}
+
+func matchKindName(node xml.Node, name string) bool {
+	name, _, _ = strings.Cut(name, ",")
+	name = strings.TrimSpace(name)
+	return name == "" || name == "*" || name == node.QualifiedName()
+}
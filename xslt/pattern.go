@@ -32,7 +32,7 @@ func (m wildcardMatcher) Match(node xml.Node) bool {
 }
 
 func (m wildcardMatcher) Priority() float64 {
-	return 0
+	return -0.5
 }
 
 type currentMatcher struct{}
@@ -42,7 +42,7 @@ func (m currentMatcher) Match(node xml.Node) bool {
 }
 
 func (m currentMatcher) Priority() float64 {
-	return 0
+	return -1
 }
 
 type rootMatcher struct {
@@ -55,7 +55,14 @@ func (m rootMatcher) Match(node xml.Node) bool {
 		if !ok {
 			return ok
 		}
-		return m.match(node.Parent())
+		depth := 1
+		if p, ok := m.next.(pathMatcher); ok {
+			depth = len(p.matchers)
+		}
+		for ; depth > 0 && node != nil; depth-- {
+			node = node.Parent()
+		}
+		return m.match(node)
 	}
 	return m.match(node)
 }
@@ -69,9 +76,9 @@ func (m rootMatcher) match(node xml.Node) bool {
 
 func (m rootMatcher) Priority() float64 {
 	if m.next != nil {
-		return m.next.Priority()
+		return 0.5
 	}
-	return 0
+	return -0.5
 }
 
 type axisMatcher struct {
@@ -84,7 +91,7 @@ func (m axisMatcher) Match(node xml.Node) bool {
 }
 
 func (m axisMatcher) Priority() float64 {
-	return 0
+	return m.next.Priority()
 }
 
 type nameMatcher struct {
@@ -107,7 +114,10 @@ func (m nameMatcher) Match(node xml.Node) bool {
 }
 
 func (m nameMatcher) Priority() float64 {
-	return 0.5
+	if m.name.Name == "*" || m.name.Space == "*" {
+		return -0.25
+	}
+	return 0
 }
 
 type attributeMatcher struct {
@@ -122,7 +132,7 @@ func (m attributeMatcher) Match(node xml.Node) bool {
 }
 
 func (m attributeMatcher) Priority() float64 {
-	return 0
+	return m.Matcher.Priority()
 }
 
 type pathMatcher struct {
@@ -144,7 +154,7 @@ func (m pathMatcher) Match(node xml.Node) bool {
 }
 
 func (m pathMatcher) Priority() float64 {
-	return 0
+	return 0.5
 }
 
 type nodeMatcher struct{}
@@ -154,7 +164,7 @@ func (m nodeMatcher) Match(node xml.Node) bool {
 }
 
 func (m nodeMatcher) Priority() float64 {
-	return 0
+	return -0.5
 }
 
 type textMatcher struct{}
@@ -164,7 +174,7 @@ func (m textMatcher) Match(node xml.Node) bool {
 }
 
 func (m textMatcher) Priority() float64 {
-	return 0
+	return -0.5
 }
 
 type unionMatcher struct {
@@ -180,6 +190,21 @@ func (m unionMatcher) Priority() float64 {
 	return max(m.left.Priority(), m.right.Priority())
 }
 
+func matchPriority(m Matcher, node xml.Node) float64 {
+	u, ok := m.(unionMatcher)
+	if !ok {
+		return m.Priority()
+	}
+	switch left, right := u.left.Match(node), u.right.Match(node); {
+	case left && right:
+		return max(matchPriority(u.left, node), matchPriority(u.right, node))
+	case left:
+		return matchPriority(u.left, node)
+	default:
+		return matchPriority(u.right, node)
+	}
+}
+
 type predicateMatcher struct {
 	curr   Matcher
 	filter xpath.Expr
@@ -195,7 +220,7 @@ func (m predicateMatcher) Match(node xml.Node) bool {
 }
 
 func (m predicateMatcher) Priority() float64 {
-	return m.curr.Priority()
+	return 0.5
 }
 
 type idMatcher struct {
@@ -217,7 +242,7 @@ func (m idMatcher) Match(node xml.Node) bool {
 }
 
 func (m idMatcher) Priority() float64 {
-	return 0
+	return 0.5
 }
 
 type keyMatcher struct {
@@ -230,7 +255,7 @@ func (m keyMatcher) Match(node xml.Node) bool {
 }
 
 func (m keyMatcher) Priority() float64 {
-	return 0
+	return 0.5
 }
 
 func isTest(n string) bool {
@@ -371,7 +396,13 @@ func (c *Compiler) compileFromAny() (Matcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	return m, nil
+	if _, ok := m.(unionMatcher); ok {
+		return m, nil
+	}
+	p := pathMatcher{
+		matchers: []Matcher{m},
+	}
+	return p, nil
 }
 
 func (c *Compiler) compilePath() (Matcher, error) {
@@ -486,7 +517,9 @@ func (c *Compiler) compileTest(qn xml.QName) (Matcher, error) {
 		m = rootMatcher{}
 	} else if qn.Name == "attribute" {
 		m = attributeMatcher{
-			Matcher: wildcardMatcher{},
+			Matcher: wildcardMatcher{
+				kind: xml.TypeAttribute,
+			},
 		}
 	} else {
 		m = nodeMatcher{}
@@ -527,7 +560,9 @@ func (c *Compiler) compileName() (Matcher, error) {
 	}
 	if c.is(opStar) && !c.peekIs(opNamespace) {
 		c.next()
-		var m wildcardMatcher
+		m := wildcardMatcher{
+			kind: xml.TypeElement,
+		}
 		return m, nil
 	}
 	qn, err := c.compileQN()
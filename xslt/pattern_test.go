@@ -55,6 +55,16 @@ func TestMatch(t *testing.T) {
 			Want:    true,
 			Node:    bar,
 		},
+		{
+			Pattern: "/root/item",
+			Want:    true,
+			Node:    doc.Root().(*xml.Element).Nodes[0],
+		},
+		{
+			Pattern: "/item",
+			Want:    false,
+			Node:    doc.Root().(*xml.Element).Nodes[0],
+		},
 		{
 			Pattern: "root",
 			Want:    false,
@@ -112,7 +122,7 @@ func TestMatch(t *testing.T) {
 		},
 		{
 			Pattern: "*",
-			Want:    true,
+			Want:    false,
 			Node:    doc,
 		},
 		{
@@ -2,16 +2,40 @@ package xslt
 
 import (
 	"fmt"
-	"html"
 	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
 
+	htm "github.com/midbel/codecs/html"
+	"github.com/midbel/codecs/json"
 	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
 )
 
 type Serializer interface {
 	Serialize(io.Writer, []xml.Node) error
 }
 
+type SequenceSerializer interface {
+	SerializeSequence(io.Writer, xpath.Sequence) error
+}
+
+func serializeSequence(s Serializer, w io.Writer, seq xpath.Sequence) error {
+	if o, ok := s.(*Output); ok {
+		s = o.Serializer
+	}
+	if ss, ok := s.(SequenceSerializer); ok {
+		return ss.SerializeSequence(w, seq)
+	}
+	nodes := make([]xml.Node, 0, len(seq))
+	for i := range seq {
+		nodes = append(nodes, seq[i].Node())
+	}
+	return s.Serialize(w, nodes)
+}
+
 func defaultSerializer(s *Stylesheet) Serializer {
 	return xmlSerializer{
 		Stylesheet: s,
@@ -24,11 +48,29 @@ func newTextSerializer(_ *Stylesheet, _ xml.Node) (Serializer, error) {
 	return textSerializer{}, nil
 }
 
-func (textSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
+func (s textSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
 	for i := range nodes {
-		str := nodes[i].Value()
-		_, err := io.WriteString(w, html.UnescapeString(str))
-		if err != nil {
+		if err := s.serializeNode(w, nodes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s textSerializer) serializeNode(w io.Writer, node xml.Node) error {
+	var nodes []xml.Node
+	switch n := node.(type) {
+	case *xml.Document:
+		nodes = n.Nodes
+	case *xml.Element:
+		nodes = n.Nodes
+	case *xml.Text, *xml.CharData:
+		_, err := io.WriteString(w, n.Value())
+		return err
+	default:
+	}
+	for i := range nodes {
+		if err := s.serializeNode(w, nodes[i]); err != nil {
 			return err
 		}
 	}
@@ -52,10 +94,18 @@ func newXmlSerializer(s *Stylesheet, n xml.Node) (Serializer, error) {
 	if err != nil {
 		return nil, err
 	}
-	if i, err := getAttribute(el, "indent"); err != nil || i != "yes" {
+	indent, err := el.AttrBool("indent", false)
+	if err != nil {
+		return nil, err
+	}
+	if !indent {
 		x.options |= xml.OptionCompact
 	}
-	if i, err := getAttribute(el, "omit-xml-declaration"); err == nil && i == "yes" {
+	omit, err := el.AttrBool("omit-xml-declaration", false)
+	if err != nil {
+		return nil, err
+	}
+	if omit {
 		x.options |= xml.OptionNoProlog
 	}
 	var doctype xml.DocType
@@ -88,6 +138,9 @@ func (s xmlSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
 	if el, ok := root.(*xml.Element); ok {
 		for _, n := range s.aliases.Names() {
 			pre, _ := s.aliases.Resolve(n)
+			if pre == "" {
+				continue
+			}
 			uri, err := s.env.ResolveNS(pre)
 			if err != nil {
 				continue
@@ -117,22 +170,23 @@ var (
 )
 
 type htmlSerializer struct {
-	options xml.WriterOptions
+	compact bool
 	doctype *xml.DocType
 }
 
 func newHtmlSerializer(_ *Stylesheet, n xml.Node) (Serializer, error) {
 	h := htmlSerializer{
 		doctype: doctypeHtml5,
-		options: xml.OptionNoProlog,
 	}
 	el, err := getElementFromNode(n)
 	if err != nil {
 		return nil, err
 	}
-	if i, err := getAttribute(el, "indent"); err != nil || i != "yes" {
-		h.options |= xml.OptionCompact
+	indent, err := el.AttrBool("indent", false)
+	if err != nil {
+		return nil, err
 	}
+	h.compact = !indent
 	if i, err := getAttribute(el, "html-version"); err == nil {
 		if i == "4" || i == "4.1" {
 			h.doctype = doctypeHtml4
@@ -143,10 +197,10 @@ func newHtmlSerializer(_ *Stylesheet, n xml.Node) (Serializer, error) {
 
 func (s htmlSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
 	var (
-		writer = xml.NewWriter(w)
+		writer = htm.NewWriter(w)
 		root   = getRootNode(nodes, false, xml.LocalName("html"))
 	)
-	writer.WriterOptions = s.options
+	writer.Compact = s.compact
 	var doc *xml.Document
 	if d, ok := root.(*xml.Document); !ok {
 		doc = xml.NewDocument(root)
@@ -157,14 +211,138 @@ func (s htmlSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
 	return writer.Write(doc)
 }
 
-type jsonSerializer struct{}
+type jsonSerializer struct {
+	compact bool
+}
 
-func newJsonSerializer(s *Stylesheet, n xml.Node) (Serializer, error) {
-	return jsonSerializer{}, nil
+func newJsonSerializer(_ *Stylesheet, n xml.Node) (Serializer, error) {
+	el, err := getElementFromNode(n)
+	if err != nil {
+		return nil, err
+	}
+	indent, err := el.AttrBool("indent", false)
+	if err != nil {
+		return nil, err
+	}
+	s := jsonSerializer{
+		compact: !indent,
+	}
+	return s, nil
+}
+
+func (s jsonSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
+	var seq xpath.Sequence
+	for _, n := range nodes {
+		if n != nil {
+			seq.Append(xpath.NewNodeItem(n))
+		}
+	}
+	return s.SerializeSequence(w, seq)
+}
+
+func (s jsonSerializer) SerializeSequence(w io.Writer, seq xpath.Sequence) error {
+	var items xpath.Sequence
+	for _, i := range seq {
+		if d, ok := i.Node().(*xml.Document); ok && !i.Atomic() {
+			for _, n := range d.Nodes {
+				items.Append(xpath.NewNodeItem(n))
+			}
+		} else {
+			items.Append(i)
+		}
+	}
+	items = slices.DeleteFunc(items, func(i xpath.Item) bool {
+		if i.Atomic() {
+			return false
+		}
+		n := i.Node()
+		return n != nil && n.Type() == xml.TypeText && strings.TrimSpace(n.Value()) == ""
+	})
+	switch len(items) {
+	case 0:
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("json output can not serialize a sequence of %d items", len(items))
+	}
+	value, err := jsonItem(items[0])
+	if err != nil {
+		return err
+	}
+	ws := json.NewWriter(w)
+	ws.Compact = s.compact
+	ws.SortKeys = true
+	return ws.Write(value)
+}
+
+func jsonItem(item xpath.Item) (any, error) {
+	if !item.Atomic() {
+		if node := item.Node(); node != nil {
+			return jsonNode(node)
+		}
+	}
+	return jsonValue(item.Value())
+}
+
+func jsonNode(node xml.Node) (any, error) {
+	el, ok := node.(*xml.Element)
+	if !ok {
+		return node.Value(), nil
+	}
+	if el.Uri != functionNamespaceUri {
+		return strings.TrimSpace(xml.WriteNode(el)), nil
+	}
+	return xpath.XmlToJson(el)
 }
 
-func (jsonSerializer) Serialize(w io.Writer, nodes []xml.Node) error {
-	return fmt.Errorf("not implemented")
+func jsonValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string, int64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("json output: %v can not be serialized", v)
+		}
+		return v, nil
+	case float32:
+		return jsonValue(float64(v))
+	case int:
+		return int64(v), nil
+	case xpath.Integer:
+		return json.Number(v.String()), nil
+	case xpath.Decimal:
+		return json.Number(v.String()), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case xpath.Duration:
+		return v.String(), nil
+	case xml.Node:
+		return jsonNode(v)
+	case []any:
+		arr := make([]any, 0, len(v))
+		for i := range v {
+			a, err := jsonValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, a)
+		}
+		return arr, nil
+	case map[any]any:
+		obj := make(map[string]any, len(v))
+		for k, a := range v {
+			key, err := jsonValue(k)
+			if err != nil {
+				return nil, err
+			}
+			if obj[fmt.Sprint(key)], err = jsonValue(a); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("json output: %T can not be serialized", value)
+	}
 }
 
 func getRootNode(nodes []xml.Node, wrapped bool, qname xml.QName) xml.Node {
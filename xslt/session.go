@@ -0,0 +1,148 @@
+package xslt
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+type Session struct {
+	sheet *Stylesheet
+	env   *xpath.Evaluator
+
+	outputs      []string
+	documents    map[string]*xml.Document
+	keys         map[indexKey]map[string][]xml.Node
+	accumulators map[accumulatorKey]*accumulatorValues
+	diagnostics  []Diagnostic
+}
+
+type indexKey struct {
+	key  *Key
+	root xml.Node
+}
+
+type accumulatorKey struct {
+	acc  *Accumulator
+	root xml.Node
+}
+
+func (s *Stylesheet) NewSession() *Session {
+	sess := &Session{
+		sheet: s,
+		env:   s.env.Sub(),
+	}
+	sess.env.RegisterFunc("key", sess.callKey)
+	sess.env.RegisterFunc("accumulator-before", sess.callAccumulatorBefore)
+	sess.env.RegisterFunc("accumulator-after", sess.callAccumulatorAfter)
+	for key, list := range s.allFunctions() {
+		sess.env.RegisterFuncNS(list[0].QName, sess.callFunction(key))
+	}
+	return sess
+}
+
+func (s *Session) Execute(doc xml.Node) ([]xml.Node, error) {
+	tpl, err := s.prepare(doc)
+	if err != nil {
+		return nil, err
+	}
+	return tpl.Execute(s.createContext(doc))
+}
+
+func (s *Session) prepare(doc xml.Node) (Executer, error) {
+	s.outputs = s.outputs[:0]
+	s.diagnostics = s.diagnostics[:0]
+	clear(s.documents)
+	clear(s.keys)
+	clear(s.accumulators)
+	if base, err := s.sheet.outputFile(s.sheet.OutputBase); err == nil && base != "" && !isDirURI(s.sheet.OutputBase) {
+		s.outputs = append(s.outputs, filepath.Clean(base))
+	}
+	tpl, err := s.sheet.getMainTemplate(doc)
+	if err != nil {
+		return nil, err
+	}
+	if s.sheet.coverage != nil {
+		s.sheet.coverage.run()
+	}
+	return tpl, nil
+}
+
+func (s *Session) Generate(w io.Writer, doc *xml.Document) error {
+	tpl, err := s.prepare(doc)
+	if err != nil {
+		return err
+	}
+	serializer := s.sheet.getOutput("")
+	if t, ok := tpl.(*Template); ok {
+		seq, err := t.execute(s.createContext(doc))
+		if err != nil {
+			return err
+		}
+		return serializeSequence(serializer, w, seq)
+	}
+	nodes, err := tpl.Execute(s.createContext(doc))
+	if err != nil {
+		return err
+	}
+	return serializer.Serialize(w, nodes)
+}
+
+func (s *Session) SetParam(ident string, expr xpath.Expr) {
+	s.env.Set(ident, expr)
+}
+
+func (s *Session) SetParamValue(ident string, value any) error {
+	seq, err := xpath.NewSequenceFromValue(value)
+	if err != nil {
+		return err
+	}
+	s.SetParam(ident, xpath.NewValueFromSequence(seq))
+	return nil
+}
+
+func (s *Session) Diagnostics() []Diagnostic {
+	return s.diagnostics
+}
+
+func (s *Session) claimOutput(file string) error {
+	if slices.Contains(s.outputs, file) {
+		return fmt.Errorf("%s: %w", file, errCollision)
+	}
+	s.outputs = append(s.outputs, file)
+	return nil
+}
+
+func (s *Session) loadDocument(href string) (*xml.Document, error) {
+	file := s.sheet.documentPath(href)
+	if doc, ok := s.documents[file]; ok {
+		return doc, nil
+	}
+	doc, err := loadDocument(file)
+	if err != nil {
+		return nil, err
+	}
+	if s.documents == nil {
+		s.documents = make(map[string]*xml.Document)
+	}
+	s.documents[file] = doc
+	return doc, nil
+}
+
+func (s *Session) createContext(node xml.Node) *Context {
+	ctx := &Context{
+		ContextNode: node,
+		Mode:        s.sheet.Mode,
+		Size:        1,
+		Index:       1,
+		Stylesheet:  s.sheet,
+		session:     s,
+		env:         s.env.Sub(),
+	}
+	ctx.SetXpathNamespace(s.sheet.xpathNamespace)
+	return ctx
+}
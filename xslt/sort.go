@@ -0,0 +1,224 @@
+package xslt
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+type sortKey struct {
+	query     string
+	order     string
+	dataType  string
+	caseOrder string
+}
+
+type sortValue struct {
+	empty bool
+	str   string
+	num   float64
+}
+
+func getSortKeys(ctx *Context, nodes []xml.Node) ([]sortKey, []xml.Node, error) {
+	var (
+		keys []sortKey
+		rest []xml.Node
+	)
+	for _, n := range nodes {
+		if n.QualifiedName() != ctx.getQualifiedName("sort") {
+			rest = append(rest, n)
+			continue
+		}
+		elem, err := getElementFromNode(n)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := createSortKey(ctx, elem)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rest, nil
+}
+
+func createSortKey(ctx *Context, elem *xml.Element) (sortKey, error) {
+	key := sortKey{
+		query:    ".",
+		order:    "ascending",
+		dataType: "text",
+	}
+	if query, err := getAttribute(elem, "select"); err == nil {
+		key.query = query
+	}
+	attrs := []struct {
+		Name   string
+		Ptr    *string
+		Values []string
+	}{
+		{Name: "order", Ptr: &key.order, Values: []string{"ascending", "descending"}},
+		{Name: "data-type", Ptr: &key.dataType, Values: []string{"text", "number"}},
+		{Name: "case-order", Ptr: &key.caseOrder, Values: []string{"upper-first", "lower-first"}},
+	}
+	for _, a := range attrs {
+		value, err := getAttribute(elem, a.Name)
+		if err != nil {
+			continue
+		}
+		if value, err = evalAVT(ctx, value); err != nil {
+			return key, err
+		}
+		if !slices.Contains(a.Values, value) {
+			return key, fmt.Errorf("%s: invalid value for %s attribute", value, a.Name)
+		}
+		*a.Ptr = value
+	}
+	return key, nil
+}
+
+func sortItems(ctx *Context, items xpath.Sequence, keys []sortKey) (xpath.Sequence, error) {
+	if len(keys) == 0 {
+		return items, nil
+	}
+	type sortable struct {
+		item   xpath.Item
+		values []sortValue
+	}
+	list := make([]sortable, 0, len(items))
+	for _, i := range items {
+		s := sortable{
+			item: i,
+		}
+		sub := ctx.WithXpath(i.Node())
+		for _, k := range keys {
+			v, err := k.value(sub, i)
+			if err != nil {
+				return nil, err
+			}
+			s.values = append(s.values, v)
+		}
+		list = append(list, s)
+	}
+	slices.SortStableFunc(list, func(s1, s2 sortable) int {
+		for i, k := range keys {
+			if c := k.compare(s1.values[i], s2.values[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	var res xpath.Sequence
+	for _, s := range list {
+		res.Append(s.item)
+	}
+	return res, nil
+}
+
+func sortNodes(ctx *Context, nodes []xml.Node, keys []sortKey) ([]xml.Node, error) {
+	if len(keys) == 0 {
+		return nodes, nil
+	}
+	var items xpath.Sequence
+	for _, n := range nodes {
+		items.Append(xpath.NewNodeItem(n))
+	}
+	items, err := sortItems(ctx, items, keys)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]xml.Node, 0, len(items))
+	for _, i := range items {
+		res = append(res, i.Node())
+	}
+	return res, nil
+}
+
+func (k sortKey) value(ctx *Context, item xpath.Item) (sortValue, error) {
+	var (
+		seq xpath.Sequence
+		err error
+	)
+	if item.Node() == nil && k.query == "." {
+		seq = xpath.Singleton(item)
+	} else if seq, err = ctx.Execute(k.query); err != nil {
+		return sortValue{}, err
+	}
+	if seq.Empty() {
+		return sortValue{empty: true}, nil
+	}
+	v := sortValue{
+		str: toString(seq.First()),
+	}
+	if k.dataType == "number" {
+		if f, ok := seq.First().Value().(float64); ok {
+			v.num = f
+		} else if v.num, err = strconv.ParseFloat(strings.TrimSpace(v.str), 64); err != nil {
+			v.num = math.NaN()
+		}
+	}
+	return v, nil
+}
+
+func (k sortKey) compare(v1, v2 sortValue) int {
+	var res int
+	switch {
+	case v1.empty || v2.empty:
+		res = compareEmpty(v1.empty, v2.empty)
+	case k.dataType == "number":
+		res = compareNumbers(v1.num, v2.num)
+	case k.caseOrder != "":
+		res = strings.Compare(strings.ToLower(v1.str), strings.ToLower(v2.str))
+		if res == 0 {
+			res = compareCase(v1.str, v2.str, k.caseOrder == "upper-first")
+		}
+	default:
+		res = strings.Compare(v1.str, v2.str)
+	}
+	if k.order == "descending" {
+		res = -res
+	}
+	return res
+}
+
+func compareEmpty(e1, e2 bool) int {
+	switch {
+	case e1 && e2:
+		return 0
+	case e1:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func compareNumbers(n1, n2 float64) int {
+	switch nan1, nan2 := math.IsNaN(n1), math.IsNaN(n2); {
+	case nan1 || nan2:
+		return compareEmpty(nan1, nan2)
+	case n1 < n2:
+		return -1
+	case n1 > n2:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareCase(s1, s2 string, upperFirst bool) int {
+	for r1, r2 := []rune(s1), []rune(s2); len(r1) > 0 && len(r2) > 0; r1, r2 = r1[1:], r2[1:] {
+		if r1[0] == r2[0] {
+			continue
+		}
+		if unicode.IsUpper(r1[0]) == upperFirst {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
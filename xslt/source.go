@@ -0,0 +1,128 @@
+package xslt
+
+import (
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/midbel/codecs/xml"
+	"github.com/midbel/codecs/xpath"
+)
+
+var streamPath = regexp.MustCompile(`^/?[\pL_][\pL\pN_.\-]*(:[\pL_][\pL\pN_.\-]*)?(/[\pL_][\pL\pN_.\-]*(:[\pL_][\pL\pN_.\-]*)?)*$`)
+
+func getStreamableForeach(ctx *Context, nodes []xml.Node) (*xml.Element, []string, bool) {
+	if len(nodes) != 1 || nodes[0].QualifiedName() != ctx.getQualifiedName("for-each") {
+		return nil, nil, false
+	}
+	elem, err := getElementFromNode(nodes[0])
+	if err != nil {
+		return nil, nil, false
+	}
+	query, err := getAttribute(elem, "select")
+	if err != nil || !streamPath.MatchString(query) {
+		return nil, nil, false
+	}
+	ok := slices.ContainsFunc(elem.Nodes, func(n xml.Node) bool {
+		switch n.QualifiedName() {
+		case ctx.getQualifiedName("sort"), ctx.getQualifiedName("on-empty"), ctx.getQualifiedName("on-non-empty"):
+			return true
+		default:
+			return false
+		}
+	})
+	if ok {
+		return nil, nil, false
+	}
+	return elem, strings.Split(strings.TrimPrefix(query, "/"), "/"), true
+}
+
+func streamSourceDocument(ctx *Context, file string, foreach *xml.Element, steps []string) (xpath.Sequence, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		rs    = xml.NewReader(r)
+		names []string
+		seq   xpath.Sequence
+	)
+	for {
+		node, err := rs.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		closed := errors.Is(err, xml.ErrClosed)
+		if err != nil && !closed {
+			return nil, err
+		}
+		e, ok := node.(xml.E)
+		if !ok || e.Type != xml.TypeElement {
+			continue
+		}
+		if closed && !e.SelfClosed {
+			names = names[:len(names)-1]
+			continue
+		}
+		if !slices.Equal(append(names, e.QualifiedName()), steps) {
+			if !closed {
+				names = append(names, e.QualifiedName())
+			}
+			continue
+		}
+		elem, err := readStreamElement(rs, e)
+		if err != nil {
+			return nil, err
+		}
+		others, err := executeConstructor(ctx.WithXsl(foreach).WithXpath(elem), foreach.Nodes, AllowOnEmpty|AllowOnNonEmpty)
+		if err != nil {
+			return nil, err
+		}
+		seq.Concat(others)
+	}
+	return seq, nil
+}
+
+func readStreamElement(rs *xml.Reader, e xml.E) (*xml.Element, error) {
+	elem := xml.NewElement(e.QName)
+	for _, a := range e.Attrs {
+		elem.SetAttribute(xml.NewAttribute(a.QName, a.Value))
+	}
+	if e.SelfClosed {
+		return elem, nil
+	}
+	for {
+		node, err := rs.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		closed := errors.Is(err, xml.ErrClosed)
+		if err != nil && !closed {
+			return nil, err
+		}
+		switch n := node.(type) {
+		case xml.E:
+			if n.Type != xml.TypeElement {
+				continue
+			}
+			if closed && !n.SelfClosed {
+				return elem, nil
+			}
+			child, err := readStreamElement(rs, n)
+			if err != nil {
+				return nil, err
+			}
+			elem.Append(child)
+		case xml.T:
+			elem.Append(xml.NewText(n.Content))
+		case xml.C:
+			elem.Append(xml.NewComment(n.Content))
+		default:
+		}
+	}
+}
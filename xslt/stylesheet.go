@@ -4,14 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/midbel/codecs/alpha"
 	"github.com/midbel/codecs/environ"
+	"github.com/midbel/codecs/internal/numfmt"
 	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
+	"github.com/midbel/codecs/xpath/avt"
 )
 
 const (
@@ -23,8 +27,9 @@ const (
 )
 
 const (
-	xsltNamespaceUri    = "http://www.w3.org/1999/XSL/Transform"
-	xsltNamespacePrefix = "xsl"
+	xsltNamespaceUri     = "http://www.w3.org/1999/XSL/Transform"
+	xsltNamespacePrefix  = "xsl"
+	functionNamespaceUri = "http://www.w3.org/2005/xpath-functions"
 )
 
 const (
@@ -38,6 +43,8 @@ var (
 	errSkip        = errors.New("skip")
 	errBreak       = errors.New("break")
 	errIterate     = errors.New("next-iteration")
+	errCollision   = errors.New("output uri already used")
+	errCircular    = errors.New("circular definition")
 	ErrTerminate   = errors.New("terminate")
 )
 
@@ -46,6 +53,27 @@ type AttributeSet struct {
 	Attrs []xml.Attribute
 }
 
+type Key struct {
+	Name  string
+	Match Matcher
+	Use   xpath.Expr
+	Nodes []xml.Node
+}
+
+type ExtensionPolicy struct {
+	Allow []string
+}
+
+func AllowExtensions(names ...string) ExtensionPolicy {
+	return ExtensionPolicy{
+		Allow: names,
+	}
+}
+
+func (p ExtensionPolicy) Allowed(name string) bool {
+	return slices.Contains(p.Allow, name) || slices.Contains(p.Allow, allValues)
+}
+
 type Output struct {
 	Name   string
 	Method string
@@ -162,51 +190,43 @@ func (m *Mode) callTemplate(name string) (Executer, error) {
 	return m.Templates[ix].Clone(), nil
 }
 
-func (m *Mode) matchTemplate(node xml.Node, env *xpath.Evaluator) (Executer, error) {
+func (m *Mode) findTemplate(node xml.Node) (*Template, error) {
 	type TemplateMatch struct {
 		*Template
 		Position int
 		Priority float64
 	}
-	var results []*TemplateMatch
+	var results []TemplateMatch
 	for i, t := range m.Templates {
 		if t.Name != "" || t.Matcher == nil {
 			continue
 		}
-		ok := t.Matcher.Match(node)
-		if !ok {
+		if !t.Matcher.Match(node) {
 			continue
 		}
 		match := TemplateMatch{
 			Template: t,
 			Position: i,
-			Priority: t.Matcher.Priority() + t.Priority,
+			Priority: t.priority(node),
 		}
-		results = append(results, &match)
+		results = append(results, match)
+	}
+	if len(results) == 0 {
+		return nil, nil
 	}
-	if len(results) > 0 {
-		if n := len(results); n > 1 && m.MultiMatch == MultiMatchFail {
-			return nil, fmt.Errorf("%s: more than one template match", node.QualifiedName())
+	slices.SortFunc(results, func(m1, m2 TemplateMatch) int {
+		if m1.Priority == m2.Priority {
+			return m2.Position - m1.Position
 		}
-		if m.MultiMatch == MultiMatchLast {
-			return results[len(results)-1].Template.Clone(), nil
+		if m1.Priority > m2.Priority {
+			return -1
 		}
-		slices.SortFunc(results, func(m1, m2 *TemplateMatch) int {
-			if m1.Priority == m2.Priority {
-				return m1.Position - m2.Position
-			}
-			if m1.Priority > m2.Priority {
-				return -1
-			}
-			return 1
-		})
-		return results[0].Template.Clone(), nil
-	}
-	exec, err := m.noMatch()
-	if err != nil {
-		err = fmt.Errorf("%s: %s", node.QualifiedName(), err)
+		return 1
+	})
+	if len(results) > 1 && results[0].Priority == results[1].Priority && m.MultiMatch == MultiMatchFail {
+		return nil, fmt.Errorf("more than one template match with priority %g", results[0].Priority)
 	}
-	return exec, err
+	return results[0].Template, nil
 }
 
 func (m *Mode) noMatch() (Executer, error) {
@@ -236,13 +256,23 @@ type Stylesheet struct {
 	WrapRoot              bool
 	WrapName              string
 	StrictModeDeclaration bool
+	Permissive            bool
+	OutputBase            string
 
 	excludeNamespaces []string
+	extensions        []string
 	xpathNamespace    string
 	xsltNamespace     string
 	Mode              string
 	Modes             []*Mode
 	AttrSet           []*AttributeSet
+	Keys              []*Key
+	Accumulators      []*Accumulator
+
+	decimalFormats map[string]numfmt.DecimalFormat
+	functions      map[string][]*Function
+	templates      map[avtKey]*avt.Template
+	coverage       *Coverage
 
 	output  []*Output
 	namer   alpha.Namer
@@ -251,7 +281,12 @@ type Stylesheet struct {
 	aliases environ.Environ[string]
 
 	contextDir string
+	globals    []globalVar
+	sources    []sheetSource
 	Others     []*Stylesheet
+
+	mu          sync.Mutex
+	diagnostics []Diagnostic
 }
 
 func Load(file, contextDir string) (*Stylesheet, error) {
@@ -259,6 +294,13 @@ func Load(file, contextDir string) (*Stylesheet, error) {
 	if err != nil {
 		return nil, err
 	}
+	if contextDir == "" {
+		contextDir = filepath.Dir(file)
+	}
+	return FromDocument(doc, contextDir)
+}
+
+func FromDocument(doc *xml.Document, contextDir string) (*Stylesheet, error) {
 	sheet := Stylesheet{
 		contextDir:    contextDir,
 		xsltNamespace: xsltNamespacePrefix,
@@ -266,16 +308,23 @@ func Load(file, contextDir string) (*Stylesheet, error) {
 		env:           xpath.NewEvaluator(),
 		aliases:       environ.Empty[string](),
 		namer:         alpha.Compose(alpha.NewLowerString(3), alpha.NewNumberString(2)),
+
+		decimalFormats: make(map[string]numfmt.DecimalFormat),
+		templates:      make(map[avtKey]*avt.Template),
 	}
 
 	sheet.defineBuiltins()
+	if doc.URI != "" {
+		sheet.static.SetBaseURI(doc.URI)
+		sheet.env.SetBaseURI(doc.URI)
+	}
 
 	sheet.Modes = append(sheet.Modes, unnamedMode())
-	if sheet.contextDir == "" {
-		sheet.contextDir = filepath.Dir(file)
-	}
 
 	root, err := getElementFromNode(doc.Root())
+	if err != nil {
+		return nil, err
+	}
 	if err := sheet.loadNamespacesFromRoot(root); err != nil {
 		return nil, err
 	}
@@ -291,6 +340,11 @@ func Load(file, contextDir string) (*Stylesheet, error) {
 	if list, err := getAttribute(root, sheet.getQualifiedName("exclude-result-prefixes")); err == nil {
 		sheet.excludeNamespaces = strings.Fields(list)
 	}
+	if list, err := getAttribute(root, "extension-element-prefixes"); err == nil {
+		if err := sheet.loadExtensions(root, strings.Fields(list)); err != nil {
+			return nil, err
+		}
+	}
 	if err := sheet.init(doc); err != nil {
 		return nil, err
 	}
@@ -317,40 +371,104 @@ func (s *Stylesheet) Find(name, mode string) (Executer, error) {
 }
 
 func (s *Stylesheet) MatchImport(node xml.Node, mode string) (Executer, error) {
-	for _, s := range s.Others {
-		if tpl, err := s.Match(node, mode); err == nil {
+	tpl, err := s.findImportTemplate(node, mode)
+	return s.matchOrBuiltin(node, mode, tpl, err)
+}
+
+func (s *Stylesheet) Match(node xml.Node, mode string) (Executer, error) {
+	tpl, err := s.findTemplate(node, mode)
+	return s.matchOrBuiltin(node, mode, tpl, err)
+}
+
+func (s *Stylesheet) matchOrBuiltin(node xml.Node, mode string, tpl *Template, err error) (Executer, error) {
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", node.QualifiedName(), err)
+	}
+	if tpl != nil {
+		return tpl.Clone(), nil
+	}
+	exec, err := s.lookupMode(mode).noMatch()
+	if err != nil {
+		err = fmt.Errorf("%s: %w", node.QualifiedName(), err)
+	}
+	return exec, err
+}
+
+func (s *Stylesheet) findTemplate(node xml.Node, mode string) (*Template, error) {
+	ix := slices.IndexFunc(s.Modes, func(m *Mode) bool {
+		return m.Name == mode
+	})
+	if ix >= 0 {
+		tpl, err := s.Modes[ix].findTemplate(node)
+		if err != nil || tpl != nil {
 			return tpl, err
 		}
 	}
-	return nil, fmt.Errorf("no template found matching given node (%s)", node.QualifiedName())
+	return s.findImportTemplate(node, mode)
 }
 
-func (s *Stylesheet) Match(node xml.Node, mode string) (Executer, error) {
+func (s *Stylesheet) findImportTemplate(node xml.Node, mode string) (*Template, error) {
+	for i := len(s.Others) - 1; i >= 0; i-- {
+		tpl, err := s.Others[i].findTemplate(node, mode)
+		if err != nil || tpl != nil {
+			return tpl, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *Stylesheet) lookupMode(name string) *Mode {
+	mode := s.declaredMode(name)
+	if mode == nil {
+		return namedMode(name)
+	}
+	unnamed := unnamedMode()
+	mode.setNoMatch(unnamed)
+	mode.setMultiMatch(unnamed)
+	return mode
+}
+
+func (s *Stylesheet) declaredMode(name string) *Mode {
 	ix := slices.IndexFunc(s.Modes, func(m *Mode) bool {
-		return m.Name == mode
+		return m.Name == name
 	})
 	if ix >= 0 {
-		tpl, err := s.Modes[ix].matchTemplate(node, s.env)
-		return tpl, err
+		return s.Modes[ix]
+	}
+	for i := len(s.Others) - 1; i >= 0; i-- {
+		if m := s.Others[i].declaredMode(name); m != nil {
+			return m
+		}
 	}
-	return s.MatchImport(node, mode)
+	return nil
 }
 
 func (s *Stylesheet) Generate(w io.Writer, doc *xml.Document) error {
-	nodes, err := s.Execute(doc)
-	if err != nil {
-		return err
-	}
-	serializer := s.getOutput("")
-	return serializer.Serialize(w, nodes)
+	sess := s.NewSession()
+	defer s.keepDiagnostics(sess)
+	return sess.Generate(w, doc)
 }
 
 func (s *Stylesheet) Execute(doc xml.Node) ([]xml.Node, error) {
-	tpl, err := s.getMainTemplate(doc)
-	if err != nil {
-		return nil, err
-	}
-	return tpl.Execute(s.createContext(doc))
+	sess := s.NewSession()
+	defer s.keepDiagnostics(sess)
+	return sess.Execute(doc)
+}
+
+// Diagnostics returns the diagnostics of the last run started with Execute or
+// Generate.
+//
+// Deprecated: use Session.Diagnostics on the session created by NewSession.
+func (s *Stylesheet) Diagnostics() []Diagnostic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.diagnostics
+}
+
+func (s *Stylesheet) keepDiagnostics(sess *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.diagnostics = slices.Clone(sess.Diagnostics())
 }
 
 func (s *Stylesheet) ImportSheet(file string) error {
@@ -359,6 +477,7 @@ func (s *Stylesheet) ImportSheet(file string) error {
 		return err
 	}
 	s.Others = append(s.Others, other)
+	s.importFunctions(other)
 	return nil
 }
 
@@ -379,13 +498,76 @@ func (s *Stylesheet) IncludeSheet(file string) error {
 			}
 		}
 	}
+	s.Keys = append(s.Keys, other.Keys...)
+	s.sources = append(s.sources, other.sources...)
+	for _, a := range other.Accumulators {
+		if s.ownAccumulator(a.Name) {
+			return fmt.Errorf("%s: accumulator already defined", a.Name)
+		}
+		s.Accumulators = append(s.Accumulators, a)
+	}
+	for name, format := range other.decimalFormats {
+		if _, ok := s.decimalFormats[name]; !ok {
+			s.decimalFormats[name] = format
+		}
+	}
+	for _, e := range other.extensions {
+		if !slices.Contains(s.extensions, e) {
+			s.extensions = append(s.extensions, e)
+		}
+	}
 	s.env.Merge(other.env)
+	if err := s.includeFunctions(other); err != nil {
+		return err
+	}
+	s.env.RegisterFunc("key", s.callKey)
+	s.env.RegisterFunc("format-number", s.callFormatNumber)
 	return nil
 }
 
 func (s *Stylesheet) LoadDocument(file string) (xml.Node, error) {
-	file = filepath.Join(s.contextDir, file)
-	return loadDocument(file)
+	return loadDocument(s.documentPath(file))
+}
+
+func (s *Stylesheet) documentPath(file string) string {
+	return filepath.Join(s.contextDir, file)
+}
+
+func (s *Stylesheet) ResolveOutput(href string) (string, error) {
+	file, err := s.outputFile(href)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(file) || s.OutputBase == "" {
+		return filepath.Clean(file), nil
+	}
+	base, err := s.outputFile(s.OutputBase)
+	if err != nil {
+		return "", err
+	}
+	if !isDirURI(s.OutputBase) {
+		base = filepath.Dir(base)
+	}
+	return filepath.Join(base, file), nil
+}
+
+func (s *Stylesheet) outputFile(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "":
+		return uri, nil
+	case "file":
+		return filepath.FromSlash(u.Path), nil
+	default:
+		return "", fmt.Errorf("%s: unsupported output uri scheme", u.Scheme)
+	}
+}
+
+func isDirURI(uri string) bool {
+	return strings.HasSuffix(uri, "/") || strings.HasSuffix(uri, string(filepath.Separator))
 }
 
 func (s *Stylesheet) SetAttributes(node xml.Node) error {
@@ -397,12 +579,9 @@ func (s *Stylesheet) SetAttributes(node xml.Node) error {
 	if err != nil {
 		return nil
 	}
-	ix := slices.IndexFunc(elem.Attrs, func(a xml.Attribute) bool {
-		return a.Name == "use-attribute-sets"
-	})
-	elem.RemoveAttr(ix)
+	elem.RemoveAttributeByName("use-attribute-sets")
 
-	ix = slices.IndexFunc(s.AttrSet, func(set *AttributeSet) bool {
+	ix := slices.IndexFunc(s.AttrSet, func(set *AttributeSet) bool {
 		return set.Name == ident
 	})
 	if ix < 0 {
@@ -417,15 +596,48 @@ func (s *Stylesheet) SetAttributes(node xml.Node) error {
 	return nil
 }
 
+func (s *Stylesheet) Extensions() []string {
+	return slices.Clone(s.extensions)
+}
+
+func (s *Stylesheet) ApplyPolicy(policy ExtensionPolicy) error {
+	for _, e := range s.extensions {
+		if !policy.Allowed(e) {
+			return fmt.Errorf("%s: extension functions not allowed", e)
+		}
+		if err := s.env.EnableFuncSet(e); err != nil {
+			return err
+		}
+		if err := s.static.EnableFuncSet(e); err != nil {
+			return err
+		}
+	}
+	for _, o := range s.Others {
+		if err := o.ApplyPolicy(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Stylesheet) SetParam(ident string, expr xpath.Expr) {
 	s.env.Set(ident, expr)
 }
 
+func (s *Stylesheet) SetParamValue(ident string, value any) error {
+	seq, err := xpath.NewSequenceFromValue(value)
+	if err != nil {
+		return err
+	}
+	s.SetParam(ident, xpath.NewValueFromSequence(seq))
+	return nil
+}
+
 func (s *Stylesheet) getOutput(name string) Serializer {
 	ix := slices.IndexFunc(s.output, func(o *Output) bool {
 		return o.Name == name
 	})
-	if ix < 0 && name == "" {
+	if ix < 0 {
 		return defaultSerializer(s)
 	}
 	return s.output[ix]
@@ -439,6 +651,7 @@ func (s *Stylesheet) staticContext(node xml.Node) *Context {
 		Size:        1,
 		Index:       1,
 		Stylesheet:  s,
+		session:     s.NewSession(),
 		env:         s.static,
 	}
 	ctx.SetXpathNamespace(s.xpathNamespace)
@@ -446,16 +659,7 @@ func (s *Stylesheet) staticContext(node xml.Node) *Context {
 }
 
 func (s *Stylesheet) createContext(node xml.Node) *Context {
-	ctx := &Context{
-		ContextNode: node,
-		Mode:        s.Mode,
-		Size:        1,
-		Index:       1,
-		Stylesheet:  s,
-		env:         s.env,
-	}
-	ctx.SetXpathNamespace(s.xpathNamespace)
-	return ctx
+	return s.NewSession().createContext(node)
 }
 
 func (s *Stylesheet) init(doc xml.Node) error {
@@ -477,15 +681,23 @@ func (s *Stylesheet) init(doc xml.Node) error {
 	if err != nil {
 		return err
 	}
+	s.sources = append(s.sources, sheetSource{
+		uri:  top.URI,
+		root: r,
+	})
 	for _, n := range r.Nodes {
 		if n.Type() == xml.TypeComment {
 			continue
 		}
-		ctx := s.staticContext(n)
-		if err := processAVT(ctx); err != nil {
+		el, err := getElementFromNode(n)
+		if err != nil {
+			return err
+		}
+		attrs, err := expandAttributes(s.staticContext(n), el)
+		if err != nil {
 			return err
 		}
-		var err error
+		el.SetAttributes(attrs)
 		switch name := n.QualifiedName(); name {
 		case s.getQualifiedName("include"):
 			err = s.includeSheet(n)
@@ -499,12 +711,20 @@ func (s *Stylesheet) init(doc xml.Node) error {
 			err = s.loadVariable(n)
 		case s.getQualifiedName("attribute-set"):
 			err = s.loadAttributeSet(n)
+		case s.getQualifiedName("key"):
+			err = s.loadKey(n)
 		case s.getQualifiedName("template"):
 			err = s.loadTemplate(n)
+		case s.getQualifiedName("function"):
+			err = s.loadFunction(n)
+		case s.getQualifiedName("accumulator"):
+			err = s.loadAccumulator(n)
 		case s.getQualifiedName("mode"):
 			err = s.loadMode(n)
 		case s.getQualifiedName("namespace-alias"):
 			err = s.loadNamespaceAlias(n)
+		case s.getQualifiedName("decimal-format"):
+			err = s.loadDecimalFormat(n)
 		default:
 			err = fmt.Errorf("%s: unexpected element", name)
 		}
@@ -512,7 +732,12 @@ func (s *Stylesheet) init(doc xml.Node) error {
 			return err
 		}
 	}
-	return nil
+	for _, n := range r.Nodes {
+		if el, ok := n.(*xml.Element); ok {
+			s.compileTemplates(el, s.xpathNamespace)
+		}
+	}
+	return s.defineGlobals()
 }
 
 func (s *Stylesheet) simplified(root xml.Node) (xml.Node, error) {
@@ -567,6 +792,10 @@ func (s *Stylesheet) defineBuiltins() {
 	s.static.RegisterFunc("system-property", callSystemProperty)
 	s.env.RegisterFunc("current", callCurrent)
 	s.env.RegisterFunc("current", callCurrent)
+	s.env.RegisterFunc("key", s.callKey)
+	s.env.RegisterFunc("format-number", s.callFormatNumber)
+	s.env.RegisterFunc("accumulator-before", s.callAccumulatorBefore)
+	s.env.RegisterFunc("accumulator-after", s.callAccumulatorAfter)
 }
 
 func (s *Stylesheet) useWhen(node *xml.Element) (bool, error) {
@@ -606,14 +835,33 @@ func (s *Stylesheet) importSheet(node xml.Node) error {
 }
 
 func (s *Stylesheet) loadNamespacesFromRoot(root *xml.Element) error {
-	for _, qn := range root.Namespaces() {
-		s.env.RegisterNS(qn.Prefix, qn.Uri)
+	ns := root.NamespaceMap()
+	for prefix, uri := range ns.All() {
+		if prefix == "" {
+			continue
+		}
+		s.env.RegisterNS(prefix, uri)
 	}
-	for e, fn := range executers {
-		delete(executers, e)
-		e.Space = s.xsltNamespace
-		e.Uri = xsltNamespaceUri
-		executers[e] = fn
+	if prefix, ok := ns.Prefix(xsltNamespaceUri); ok && prefix != "" {
+		s.xsltNamespace = prefix
+	}
+	return nil
+}
+
+func (s *Stylesheet) loadExtensions(root *xml.Element, prefixes []string) error {
+	list := root.Namespaces()
+	for _, p := range prefixes {
+		ix := slices.IndexFunc(list, func(ns xml.NS) bool {
+			return ns.Prefix == p
+		})
+		if ix < 0 {
+			return fmt.Errorf("%s: prefix not declared", p)
+		}
+		name, ok := xpath.FuncSetFromNS(list[ix].Uri)
+		if !ok || slices.Contains(s.extensions, name) {
+			continue
+		}
+		s.extensions = append(s.extensions, name)
 	}
 	return nil
 }
@@ -631,7 +879,7 @@ func (s *Stylesheet) loadNamespaceAlias(node xml.Node) error {
 	if err != nil {
 		return err
 	}
-	s.aliases.Define(source, target)
+	s.aliases.Define(aliasPrefix(source), aliasPrefix(target))
 	return nil
 }
 
@@ -669,6 +917,76 @@ func (s *Stylesheet) loadAttributeSet(node xml.Node) error {
 	return nil
 }
 
+func (s *Stylesheet) loadKey(node xml.Node) error {
+	elem, err := getElementFromNode(node)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.useWhen(elem); !ok {
+		return nil
+	}
+	var k Key
+	if k.Name, err = getAttribute(elem, "name"); err != nil {
+		return err
+	}
+	match, err := getAttribute(elem, "match")
+	if err != nil {
+		return err
+	}
+	if k.Match, err = compileMatchWithEnv(s.env, match); err != nil {
+		return err
+	}
+	if query, err := getAttribute(elem, "use"); err == nil {
+		if len(elem.Nodes) > 0 {
+			return fmt.Errorf("use attribute can not be used with children")
+		}
+		if k.Use, err = s.env.Create(query); err != nil {
+			return err
+		}
+	} else {
+		k.Nodes = elem.Nodes
+	}
+	s.Keys = append(s.Keys, &k)
+	return nil
+}
+
+func (s *Stylesheet) loadDecimalFormat(node xml.Node) error {
+	elem, err := getElementFromNode(node)
+	if err != nil {
+		return err
+	}
+	if ok, _ := s.useWhen(elem); !ok {
+		return nil
+	}
+	name, _ := getAttribute(elem, "name")
+	format, ok := s.decimalFormats[name]
+	if !ok {
+		format = numfmt.Default()
+	}
+	for _, a := range elem.Attributes() {
+		if a.Name == "name" || a.Name == "use-when" {
+			continue
+		}
+		if err := format.Set(a.Name, a.Value()); err != nil {
+			return fmt.Errorf("%s: %w", elem.QualifiedName(), err)
+		}
+	}
+	s.decimalFormats[name] = format
+	return nil
+}
+
+func (s *Stylesheet) getDecimalFormat(name string) (numfmt.DecimalFormat, bool) {
+	if format, ok := s.decimalFormats[name]; ok {
+		return format, true
+	}
+	for _, other := range s.Others {
+		if format, ok := other.getDecimalFormat(name); ok {
+			return format, true
+		}
+	}
+	return numfmt.Default(), name == ""
+}
+
 func (s *Stylesheet) loadMode(node xml.Node) error {
 	elem, err := getElementFromNode(node)
 	if err != nil {
@@ -717,9 +1035,9 @@ func (s *Stylesheet) loadVariable(node xml.Node) error {
 	if err != nil {
 		return err
 	}
-	var static bool
-	if yes, err := getAttribute(elem, "static"); err == nil && yes == "yes" {
-		static = true
+	static, err := elem.AttrBool("static", false)
+	if err != nil {
+		return err
 	}
 	if query, err := getAttribute(elem, "select"); err == nil {
 		if len(elem.Nodes) > 0 {
@@ -732,18 +1050,11 @@ func (s *Stylesheet) loadVariable(node xml.Node) error {
 			}
 			return err
 		}
-		expr, err := s.env.Create(query)
-		if err == nil {
-			s.env.Set(ident, expr)
-		}
-		return err
-	} else {
-		seq, err := executeConstructor(s.createContext(elem), elem.Nodes, 0)
-		if err != nil {
-			return err
-		}
-		s.env.Set(ident, xpath.NewValueFromSequence(seq))
 	}
+	s.globals = append(s.globals, globalVar{
+		ident: ident,
+		elem:  elem,
+	})
 	return nil
 }
 
@@ -759,9 +1070,9 @@ func (s *Stylesheet) loadParam(node xml.Node) error {
 	if err != nil {
 		return err
 	}
-	var static bool
-	if yes, err := getAttribute(elem, "static"); err == nil && yes == "yes" {
-		static = true
+	static, err := elem.AttrBool("static", false)
+	if err != nil {
+		return err
 	}
 	if query, err := getAttribute(elem, "select"); err == nil {
 		if len(elem.Nodes) > 0 {
@@ -774,21 +1085,205 @@ func (s *Stylesheet) loadParam(node xml.Node) error {
 			}
 			return err
 		}
-		expr, err := s.env.Create(query)
-		if err == nil {
-			s.env.Set(ident, expr)
-		}
+	}
+	s.globals = append(s.globals, globalVar{
+		ident: ident,
+		elem:  elem,
+	})
+	return nil
+}
+
+type globalVar struct {
+	ident string
+	elem  *xml.Element
+}
+
+func (s *Stylesheet) defineGlobals() error {
+	order, err := s.sortGlobals()
+	if err != nil {
 		return err
-	} else {
-		seq, err := executeConstructor(s.createContext(elem), elem.Nodes, 0)
-		if err != nil {
-			return err
+	}
+	for _, g := range order {
+		if query, err := getAttribute(g.elem, "select"); err == nil {
+			expr, err := s.env.Create(query)
+			if err != nil {
+				return err
+			}
+			s.env.Set(g.ident, expr)
+			continue
 		}
-		s.env.Set(ident, xpath.NewValueFromSequence(seq))
+		elem := g.elem
+		get := func() (xpath.Sequence, error) {
+			return executeConstructor(s.createContext(elem), elem.Nodes, 0)
+		}
+		s.env.Set(g.ident, xpath.NewDeferredValue(get))
 	}
+	s.globals = nil
 	return nil
 }
 
+func (s *Stylesheet) sortGlobals() ([]globalVar, error) {
+	var (
+		order  []globalVar
+		deps   = make(map[string][]string)
+		states = make(map[string]int)
+		visit  func(string, []string) error
+	)
+	for _, g := range s.globals {
+		deps[g.ident] = nil
+	}
+	for _, g := range s.globals {
+		refs, err := s.variableRefs(g.elem, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range refs {
+			if _, ok := deps[r]; ok && !slices.Contains(deps[g.ident], r) {
+				deps[g.ident] = append(deps[g.ident], r)
+			}
+		}
+	}
+	visit = func(ident string, path []string) error {
+		switch states[ident] {
+		case 1:
+			path = append(path, ident)
+			ix := slices.Index(path, ident)
+			return fmt.Errorf("%w: %s", errCircular, strings.Join(path[ix:], " -> "))
+		case 2:
+			return nil
+		}
+		states[ident] = 1
+		for _, d := range deps[ident] {
+			if err := visit(d, append(path, ident)); err != nil {
+				return err
+			}
+		}
+		states[ident] = 2
+		for _, g := range s.globals {
+			if g.ident == ident {
+				order = append(order, g)
+			}
+		}
+		return nil
+	}
+	for _, g := range s.globals {
+		if err := visit(g.ident, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// variableRefs returns the free variables referenced by the expressions of
+// elem and its descendants. Variables declared by the local xsl:variable and
+// xsl:param elements of the body are excluded from their following siblings.
+func (s *Stylesheet) variableRefs(elem *xml.Element, bound []string) ([]string, error) {
+	var list []string
+	for _, a := range elem.Attrs {
+		refs, err := s.attributeRefs(elem, a)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range refs {
+			if !slices.Contains(bound, r) {
+				list = append(list, r)
+			}
+		}
+	}
+	for _, n := range elem.Nodes {
+		el, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		refs, err := s.variableRefs(el, bound)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, refs...)
+
+		switch el.QualifiedName() {
+		case s.getQualifiedName("variable"), s.getQualifiedName("param"):
+			if ident, err := getAttribute(el, "name"); err == nil {
+				bound = append(slices.Clip(bound), ident)
+			}
+		default:
+		}
+	}
+	return list, nil
+}
+
+func (s *Stylesheet) attributeRefs(elem *xml.Element, a xml.Attribute) ([]string, error) {
+	if _, ok := declaredPrefix(a); ok {
+		return nil, nil
+	}
+	if elem.Space != s.xsltNamespace {
+		if a.Space == s.xsltNamespace {
+			return nil, nil
+		}
+		return s.templateRefs(a.Value())
+	}
+	switch name := a.QualifiedName(); {
+	case a.Space != "":
+		return nil, nil
+	case slices.Contains(exprAttributes, name):
+		return s.expressionRefs(a.Value())
+	case slices.Contains(patternAttributes, name):
+		return scanVariableRefs(stripLiterals(a.Value())), nil
+	default:
+		return s.templateRefs(a.Value())
+	}
+}
+
+func (s *Stylesheet) templateRefs(value string) ([]string, error) {
+	if err := avt.Check(value); err != nil {
+		return nil, err
+	}
+	var list []string
+	for query, ok := range avt.Split(value) {
+		if !ok {
+			continue
+		}
+		refs, err := s.expressionRefs(query)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, refs...)
+	}
+	return list, nil
+}
+
+func (s *Stylesheet) expressionRefs(query string) ([]string, error) {
+	expr, err := s.env.Create(query)
+	if err != nil {
+		return nil, err
+	}
+	return xpath.FreeVariables(expr), nil
+}
+
+func scanVariableRefs(str string) []string {
+	var list []string
+	for {
+		ix := strings.IndexByte(str, '$')
+		if ix < 0 {
+			break
+		}
+		str = str[ix+1:]
+		var n int
+		for n < len(str) && isNameChar(str[n]) {
+			n++
+		}
+		if ident := strings.TrimRight(str[:n], ":"); ident != "" {
+			list = append(list, ident)
+		}
+		str = str[n:]
+	}
+	return list
+}
+
+func isNameChar(b byte) bool {
+	return b == '-' || b == '_' || b == '.' || b == ':' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
 func (s *Stylesheet) loadOutput(node xml.Node) error {
 	elem, err := getElementFromNode(node)
 	if err != nil {
@@ -887,24 +1382,8 @@ func (s *Stylesheet) getQualifiedName(name string) string {
 	return qn.QualifiedName()
 }
 
-func (s *Stylesheet) getDefaultMode() *Mode {
-	unnamed := unnamedMode()
-
-	ix := slices.IndexFunc(s.Modes, func(m *Mode) bool {
-		return m.Name == s.Mode
-	})
-	if ix < 0 {
-		return unnamed
-	}
-	current := s.Modes[ix]
-	current.setNoMatch(unnamed)
-	current.setMultiMatch(unnamed)
-	return current
-}
-
 func (s *Stylesheet) getMainTemplate(node xml.Node) (Executer, error) {
-	mode := s.getDefaultMode()
-	return mode.matchTemplate(node, s.env)
+	return s.Match(node, s.Mode)
 }
 
 func importSheet(ctx *Context) error {
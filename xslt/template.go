@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"maps"
 	"slices"
-	"strconv"
 
 	"github.com/midbel/codecs/xml"
 	"github.com/midbel/codecs/xpath"
@@ -20,7 +19,10 @@ type Template struct {
 
 	Nodes []xml.Node
 
-	params map[string]xpath.Expr
+	explicit bool
+	params   map[string]xpath.Expr
+	tunnels  map[string]bool
+	elem     *xml.Element
 }
 
 func NewTemplate(env *xpath.Evaluator, node xml.Node) (*Template, error) {
@@ -28,15 +30,18 @@ func NewTemplate(env *xpath.Evaluator, node xml.Node) (*Template, error) {
 	if err != nil {
 		return nil, err
 	}
-	var tpl Template
+	tpl := Template{
+		elem: el,
+	}
 	for _, a := range el.Attributes() {
 		switch attr := a.Value(); a.Name {
 		case "priority":
-			p, err := strconv.ParseFloat(attr, 64)
+			p, err := el.AttrFloat(a.QualifiedName(), 0)
 			if err != nil {
 				return nil, err
 			}
 			tpl.Priority = p
+			tpl.explicit = true
 		case "name":
 			tpl.Name = attr
 		case "match":
@@ -51,6 +56,7 @@ func NewTemplate(env *xpath.Evaluator, node xml.Node) (*Template, error) {
 		}
 	}
 	tpl.params = make(map[string]xpath.Expr)
+	tpl.tunnels = make(map[string]bool)
 	for i, n := range el.Nodes {
 		if n.QualifiedName() != "xsl:param" {
 			tpl.Nodes = append(tpl.Nodes, el.Nodes[i:]...)
@@ -63,18 +69,41 @@ func NewTemplate(env *xpath.Evaluator, node xml.Node) (*Template, error) {
 	return &tpl, nil
 }
 
+func (t *Template) priority(node xml.Node) float64 {
+	if t.explicit {
+		return t.Priority
+	}
+	return matchPriority(t.Matcher, node)
+}
+
 func (t *Template) Clone() *Template {
 	tpl := *t
 	tpl.Nodes = slices.Clone(tpl.Nodes)
 	tpl.params = maps.Clone(t.params)
+	tpl.tunnels = maps.Clone(t.tunnels)
 	return &tpl
 }
 
 func (t *Template) Execute(ctx *Context) ([]xml.Node, error) {
+	seq, err := t.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]xml.Node, 0, len(seq))
+	for i := range seq {
+		nodes = append(nodes, seq[i].Node())
+	}
+	return nodes, nil
+}
+
+func (t *Template) execute(ctx *Context) (xpath.Sequence, error) {
 	if err := t.fillWithDefaults(ctx); err != nil {
 		return nil, err
 	}
-	var nodes []xml.Node
+	if ctx.session != nil && ctx.session.sheet.coverage != nil {
+		ctx.session.sheet.coverage.hit(t)
+	}
+	var seq xpath.Sequence
 	for _, n := range slices.Clone(t.Nodes) {
 		c := cloneNode(n)
 		if c == nil {
@@ -87,11 +116,9 @@ func (t *Template) Execute(ctx *Context) ([]xml.Node, error) {
 			}
 			return nil, err
 		}
-		for i := range res {
-			nodes = append(nodes, res[i].Node())
-		}
+		seq.Concat(res)
 	}
-	return nodes, nil
+	return seq, nil
 }
 
 func (t *Template) fillWithDefaults(ctx *Context) error {
@@ -114,6 +141,10 @@ func (t *Template) hasParam(ident string) bool {
 	return ok
 }
 
+func (t *Template) isTunnel(ident string) bool {
+	return t.tunnels[ident]
+}
+
 func (t *Template) setParam(parent *xpath.Evaluator, node xml.Node) error {
 	elem, err := getElementFromNode(node)
 	if err != nil {
@@ -141,6 +172,7 @@ func (t *Template) setParam(parent *xpath.Evaluator, node xml.Node) error {
 			return fmt.Errorf("%s: param already defined", ident)
 		}
 		t.params[ident] = expr
+		t.tunnels[ident] = isTunnel(elem)
 	}
 	return err
 }
@@ -152,27 +184,33 @@ type virtualApplyTemplate struct {
 type builtinNoMatch struct{}
 
 func (builtinNoMatch) Execute(ctx *Context) ([]xml.Node, error) {
+	switch n := ctx.ContextNode.(type) {
+	case *xml.Document:
+		return applyChildren(ctx, n.Nodes)
+	case *xml.Element:
+		return applyChildren(ctx, n.Nodes)
+	case *xml.Text:
+		return []xml.Node{n}, nil
+	case *xml.CharData:
+		return []xml.Node{n}, nil
+	case *xml.Attribute:
+		return []xml.Node{xml.NewText(n.Value())}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func applyChildren(ctx *Context, children []xml.Node) ([]xml.Node, error) {
 	var nodes []xml.Node
-	switch ctx.ContextNode.Type() {
-	case xml.TypeDocument:
-		doc := ctx.ContextNode.(*xml.Document)
-		return ctx.WithXpath(doc.Root()).ApplyTemplate()
-	case xml.TypeElement:
-		el := ctx.ContextNode.(*xml.Element)
-		for i := range el.Nodes {
-			t := el.Nodes[i].Type()
-			if t == xml.TypeComment || t == xml.TypeInstruction {
-				continue
-			}
-			others, err := ctx.WithXpath(el.Nodes[i]).ApplyTemplate()
-			if err != nil {
-				return nil, err
-			}
-			nodes = slices.Concat(nodes, others)
+	for _, c := range slices.Clone(children) {
+		if t := c.Type(); t == xml.TypeComment || t == xml.TypeInstruction {
+			continue
 		}
-	case xml.TypeText:
-		nodes = append(nodes, ctx.ContextNode)
-	default:
+		others, err := ctx.WithXpath(c).ApplyTemplate()
+		if err != nil {
+			return nil, err
+		}
+		nodes = slices.Concat(nodes, others)
 	}
 	return nodes, nil
 }
@@ -27,7 +27,7 @@ func transformNode(ctx *Context) (xpath.Sequence, error) {
 	if err != nil {
 		return nil, ctx.errorWithContext(err)
 	}
-	fn, ok := executers[elem.QName]
+	fn, ok := getExecuter(elem.QName, ctx.xsltNamespace)
 	if !ok {
 		if space := elem.QName.Space; space == ctx.xsltNamespace {
 			err := fmt.Errorf("%s: instruction/declaration not expected here", space)
@@ -37,9 +37,14 @@ func transformNode(ctx *Context) (xpath.Sequence, error) {
 		return seq, err
 	}
 	if fn == nil {
-		return nil, fmt.Errorf("%s: %w", elem.QualifiedName(), errImplemented)
+		err := fmt.Errorf("%s: %w", elem.QualifiedName(), errImplemented)
+		return ctx.recover(err)
 	}
-	return fn(ctx)
+	seq, err := fn(ctx)
+	if err != nil && ctx.Permissive && Recoverable(err) {
+		return ctx.recover(err)
+	}
+	return seq, err
 }
 
 func processNode(ctx *Context) (xpath.Sequence, error) {
@@ -60,9 +65,14 @@ func processNode(ctx *Context) (xpath.Sequence, error) {
 		nodes  = slices.Clone(elem.Nodes)
 	)
 	elem.Nodes = elem.Nodes[:0]
-	if err := processAVT(nested); err != nil {
+	attrs, err := expandAttributes(nested, elem)
+	if err != nil {
 		return nil, err
 	}
+	elem.ClearAttributes()
+	for _, a := range attrs {
+		elem.SetAttribute(a)
+	}
 	if err := nested.SetAttributes(elem); err != nil {
 		return nil, err
 	}
@@ -83,19 +93,73 @@ func processNode(ctx *Context) (xpath.Sequence, error) {
 			elem.Append(res[i].Node())
 		}
 	}
+	applyNamespaceAlias(ctx, elem)
+	declareDefaultNS(elem)
+	stripDefaultNS(elem)
+	return xpath.Singleton(elem), nil
+}
+
+func applyNamespaceAlias(ctx *Context, elem *xml.Element) {
 	if ns, err := ctx.ResolveAliasNS(elem.Space); err == nil {
 		elem.Space = ns.Prefix
 		elem.Uri = ns.Uri
 	}
-	for i, a := range elem.Attrs {
-		ns, err := ctx.ResolveAliasNS(a.Space)
-		if err == nil {
-			a.Space = ns.Prefix
-			a.Uri = ns.Uri
-			elem.Attrs[i] = a
+	var attrs []xml.Attribute
+	for _, a := range elem.Attrs {
+		if prefix, ok := declaredPrefix(a); ok {
+			if _, err := ctx.aliases.Resolve(prefix); err != nil {
+				attrs = append(attrs, a)
+			}
+			continue
+		}
+		if a.Space != "" && a.Space == ctx.xsltNamespace {
+			continue
+		}
+		if a.Space != "" {
+			if ns, err := ctx.ResolveAliasNS(a.Space); err == nil {
+				a.Space = ns.Prefix
+				a.Uri = ns.Uri
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	elem.ClearAttributes()
+	for _, a := range attrs {
+		elem.SetAttribute(a)
+	}
+}
+
+func aliasPrefix(prefix string) string {
+	if prefix == defaultMode {
+		return ""
+	}
+	return prefix
+}
+
+func declareDefaultNS(elem *xml.Element) {
+	if elem.Space != "" || elem.Uri == "" {
+		return
+	}
+	if _, ok := elem.NamespaceMap().Resolve(""); ok {
+		return
+	}
+	elem.SetAttribute(xml.NewAttribute(xml.LocalName(xml.AttrXmlNS), elem.Uri))
+}
+
+func stripDefaultNS(elem *xml.Element) {
+	uri, ok := elem.NamespaceMap().Resolve("")
+	if !ok {
+		return
+	}
+	for _, n := range elem.Nodes {
+		child, ok := n.(*xml.Element)
+		if !ok {
+			continue
+		}
+		if u, ok := child.NamespaceMap().Resolve(""); ok && u == uri {
+			child.RemoveAttribute(xml.LocalName(xml.AttrXmlNS))
 		}
 	}
-	return xpath.Singleton(elem), nil
 }
 
 func cloneNode(n xml.Node) xml.Node {
@@ -115,13 +179,20 @@ func getElementFromNode(node xml.Node) (*xml.Element, error) {
 }
 
 func getAttribute(el *xml.Element, ident string) (string, error) {
-	ix := slices.IndexFunc(el.Attrs, func(a xml.Attribute) bool {
-		return a.Name == ident
-	})
-	if ix < 0 {
+	value, ok := el.GetAttribute(ident)
+	if !ok {
 		return "", fmt.Errorf("%s: %w %q", el.QualifiedName(), errMissed, ident)
 	}
-	return el.Attrs[ix].Value(), nil
+	return value, nil
+}
+
+func inScopeNamespace(node xml.Node, prefix string) string {
+	for _, ns := range xml.InScopeNamespaces(node) {
+		if ns.Prefix == prefix {
+			return ns.Uri
+		}
+	}
+	return ""
 }
 
 func hasAttribute(name string, attrs []xml.Attribute) bool {
@@ -138,7 +209,11 @@ func loadDocument(file string) (*xml.Document, error) {
 	defer r.Close()
 
 	p := xml.NewParser(r)
-	return p.Parse()
+	doc, err := p.Parse()
+	if err == nil {
+		doc.URI = xml.FileURI(file)
+	}
+	return doc, err
 }
 
 func writeDoctypeHTML(w io.Writer) error {
@@ -153,6 +228,16 @@ func toString(item xpath.Item) string {
 		v = x.Format("2006-01-02")
 	case float64:
 		v = strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		v = strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case int64:
+		v = strconv.FormatInt(x, 10)
+	case xpath.Integer:
+		v = x.String()
+	case xpath.Decimal:
+		v = x.String()
+	case bool:
+		v = strconv.FormatBool(x)
 	case []byte:
 	case string:
 		v = x
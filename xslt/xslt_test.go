@@ -2,9 +2,13 @@ package xslt_test
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/midbel/codecs/xml"
@@ -12,10 +16,14 @@ import (
 )
 
 type TestCase struct {
-	Name    string
-	Dir     string
-	Context string
-	Failed  bool
+	Name       string
+	Dir        string
+	Context    string
+	Failed     bool
+	Permissive bool
+	Allow      []string
+	Output     bool
+	Params     map[string]any
 }
 
 func TestElement(t *testing.T) {
@@ -32,6 +40,14 @@ func TestElement(t *testing.T) {
 			Name: "element/basic-attribute",
 			Dir:  "testdata/element-basic-attribute",
 		},
+		{
+			Name: "element/literal-avt",
+			Dir:  "testdata/element-literal-avt",
+		},
+		{
+			Name: "element/avt-sequence",
+			Dir:  "testdata/element-avt-sequence",
+		},
 	}
 	runTests(t, tests)
 }
@@ -68,6 +84,20 @@ func TestNamespaceAlias(t *testing.T) {
 			Name: "namespaces-alias/basic",
 			Dir:  "testdata/namespaces-alias",
 		},
+		{
+			Name: "namespaces-alias/default",
+			Dir:  "testdata/namespaces-alias-default",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestLiteralNamespaces(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "namespaces-literal/default",
+			Dir:  "testdata/namespaces-literal-default",
+		},
 	}
 	runTests(t, tests)
 }
@@ -94,6 +124,28 @@ func TestApplyTemplates(t *testing.T) {
 			Name: "apply-templates/remove-element",
 			Dir:  "testdata/apply-templates-remove-el",
 		},
+		{
+			Name: "apply-templates/sort",
+			Dir:  "testdata/apply-templates-sort",
+		},
+		{
+			Name: "apply-templates/priority",
+			Dir:  "testdata/apply-templates-priority",
+		},
+		{
+			Name: "apply-templates/tunnel-param",
+			Dir:  "testdata/apply-templates-tunnel-param",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestSourceDocument(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "source-document/streamable",
+			Dir:  "testdata/source-document-streamable",
+		},
 	}
 	runTests(t, tests)
 }
@@ -283,6 +335,10 @@ func TestForEach(t *testing.T) {
 			Name: "foreach/sort",
 			Dir:  "testdata/foreach-sort",
 		},
+		{
+			Name: "foreach/sort-keys",
+			Dir:  "testdata/foreach-sort-keys",
+		},
 		{
 			Name: "foreach/empty",
 			Dir:  "testdata/foreach-empty",
@@ -301,6 +357,19 @@ func TestVariables(t *testing.T) {
 			Name: "variable/global",
 			Dir:  "testdata/variable-global",
 		},
+		{
+			Name: "variable/global-order",
+			Dir:  "testdata/variable-global-order",
+		},
+		{
+			Name:   "variable/global-circular",
+			Dir:    "testdata/variable-global-circular",
+			Failed: true,
+		},
+		{
+			Name: "variable/global-bound",
+			Dir:  "testdata/variable-global-bound",
+		},
 		{
 			Name: "variable/body",
 			Dir:  "testdata/variable-body",
@@ -359,12 +428,69 @@ func TestSequence(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestNew(t *testing.T) {
+	const dir = "testdata/apply-templates-mode"
+
+	r, err := os.Open(filepath.Join(dir, "transform.xslt"))
+	if err != nil {
+		t.Fatalf("error opening stylesheet: %s", err)
+	}
+	defer r.Close()
+
+	sheet, err := xslt.New(r, xslt.WithContextDir(dir))
+	if err != nil {
+		t.Fatalf("error loading stylesheet: %s", err)
+	}
+	doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+	if err != nil {
+		t.Fatalf("error loading document: %s", err)
+	}
+	res, err := sheet.Transform(doc)
+	if err != nil {
+		t.Fatalf("error executing transform: %s", err)
+	}
+	if root := res.Root(); root == nil || root.QualifiedName() != "item" {
+		t.Errorf("item element expected as root of result document")
+	}
+	var str bytes.Buffer
+	if err := sheet.TransformTo(&str, doc); err != nil {
+		t.Fatalf("error executing transform: %s", err)
+	}
+	if err := compareBytes(t, filepath.Join(dir, "result.xml"), str.Bytes()); err != nil {
+		t.Errorf("comparing results mismatched")
+	}
+}
+
 func TestStylesheet(t *testing.T) {
 	tests := []TestCase{
 		{
 			Name: "stylesheet/simplified",
 			Dir:  "testdata/style-simplified",
 		},
+		{
+			Name: "stylesheet/output-html",
+			Dir:  "testdata/output-html",
+		},
+		{
+			Name: "stylesheet/output-text",
+			Dir:  "testdata/output-text",
+		},
+		{
+			Name: "stylesheet/output-json",
+			Dir:  "testdata/output-json",
+		},
+		{
+			Name: "stylesheet/output-json-map",
+			Dir:  "testdata/output-json-map",
+		},
+		{
+			Name: "stylesheet/output-json-atomic",
+			Dir:  "testdata/output-json-atomic",
+		},
+		{
+			Name: "stylesheet/custom-prefix",
+			Dir:  "testdata/style-prefix",
+		},
 		{
 			Name:   "stylesheet/simplified-with-error",
 			Dir:    "testdata/style-simplified-error",
@@ -414,18 +540,393 @@ func TestIterate(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestAnalyzeString(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "analyze-string/basic",
+			Dir:  "testdata/analyze-string-basic",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestNumber(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "number/single",
+			Dir:  "testdata/number-single",
+		},
+		{
+			Name: "number/multiple",
+			Dir:  "testdata/number-multiple",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestDecimalFormat(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "decimal-format/basic",
+			Dir:  "testdata/decimal-format-basic",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestKey(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "key/basic",
+			Dir:  "testdata/key-basic",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestAccumulator(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "accumulator/basic",
+			Dir:  "testdata/accumulator-basic",
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestFunction(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "function/basic",
+			Dir:  "testdata/function-basic",
+		},
+		{
+			Name:   "function/type-error",
+			Dir:    "testdata/function-type-error",
+			Failed: true,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestExtensions(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name:  "extensions/allowed",
+			Dir:   "testdata/extension-basic",
+			Allow: []string{"agl"},
+		},
+		{
+			Name:  "extensions/all",
+			Dir:   "testdata/extension-basic",
+			Allow: []string{"#all"},
+		},
+		{
+			Name:   "extensions/denied",
+			Dir:    "testdata/extension-basic",
+			Failed: true,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestPermissive(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name:       "permissive/basic",
+			Dir:        "testdata/permissive-basic",
+			Permissive: true,
+		},
+		{
+			Name:   "permissive/disabled",
+			Dir:    "testdata/permissive-basic",
+			Failed: true,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestResultDocument(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name:   "result-document/basic",
+			Dir:    "testdata/result-document-basic",
+			Output: true,
+		},
+		{
+			Name:   "result-document/collision",
+			Dir:    "testdata/result-document-collision",
+			Output: true,
+			Failed: true,
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestParamValue(t *testing.T) {
+	tests := []TestCase{
+		{
+			Name: "param/value",
+			Dir:  "testdata/param-value",
+			Params: map[string]any{
+				"config": map[string]any{
+					"title": "angle",
+					"debug": true,
+					"tags":  []any{"xml", "xslt"},
+				},
+				"items": []any{"foo", "bar"},
+			},
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestConcurrentExecute(t *testing.T) {
+	dirs := []string{
+		"testdata/iterate-break",
+		"testdata/key-basic",
+		"testdata/variable-global-order",
+		"testdata/foreach-group-basic",
+	}
+	for _, dir := range dirs {
+		sheet, err := xslt.Load(filepath.Join(dir, "transform.xslt"), dir)
+		if err != nil {
+			t.Errorf("%s: error loading stylesheet: %s", dir, err)
+			continue
+		}
+		var (
+			wg   sync.WaitGroup
+			errs = make(chan error, 8)
+		)
+		for range cap(errs) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+				if err != nil {
+					errs <- err
+					return
+				}
+				var str bytes.Buffer
+				if err := sheet.NewSession().Generate(&str, doc); err != nil {
+					errs <- err
+					return
+				}
+				want, err := os.ReadFile(filepath.Join(dir, "result.xml"))
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !bytes.Equal(normalizeDoc(want), normalizeDoc(str.Bytes())) {
+					errs <- fmt.Errorf("results mismatched")
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("%s: %s", dir, err)
+		}
+	}
+}
+
+func TestSessionReuse(t *testing.T) {
+	const dir = "testdata/key-basic"
+	sheet, err := xslt.Load(filepath.Join(dir, "transform.xslt"), dir)
+	if err != nil {
+		t.Fatalf("error loading stylesheet: %s", err)
+	}
+	doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	run := sheet.NewSession()
+
+	var str bytes.Buffer
+	if err := run.Generate(&str, doc); err != nil {
+		t.Fatalf("first run: unexpected error: %s", err)
+	}
+	if want := `name="Asimov" count="2"`; !strings.Contains(strings.Join(strings.Fields(str.String()), " "), want) {
+		t.Errorf("first run: %s not found in result", want)
+	}
+	books := doc.Root().(*xml.Element).Nodes
+	book := books[len(books)-1].(*xml.Element)
+	if err := book.SetAttributeValue(xml.LocalName("author"), "a1"); err != nil {
+		t.Fatalf("error updating document: %s", err)
+	}
+	str.Reset()
+	if err := run.Generate(&str, doc); err != nil {
+		t.Fatalf("second run: unexpected error: %s", err)
+	}
+	if want := `name="Asimov" count="1"`; !strings.Contains(strings.Join(strings.Fields(str.String()), " "), want) {
+		t.Errorf("second run: %s not found in result", want)
+	}
+}
+
+func TestFunctionSession(t *testing.T) {
+	const dir = "testdata/function-session"
+	sheet, err := xslt.Load(filepath.Join(dir, "transform.xslt"), dir)
+	if err != nil {
+		t.Fatalf("error loading stylesheet: %s", err)
+	}
+	sheet.Permissive = true
+	doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+	if err != nil {
+		t.Fatalf("error parsing document: %s", err)
+	}
+	run := sheet.NewSession()
+	if err := run.SetParamValue("label", "session"); err != nil {
+		t.Fatalf("error setting parameter: %s", err)
+	}
+	var str bytes.Buffer
+	if err := run.Generate(&str, doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := strings.Join(strings.Fields(str.String()), " ")
+	for _, want := range []string{`direct="session" viafn="session:1"`, `direct="session" viafn="session:2"`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("%s not found in result", want)
+		}
+	}
+	if n := len(run.Diagnostics()); n != 2 {
+		t.Errorf("diagnostics from function body should be kept in session! want 2, got %d", n)
+	}
+	if _, err := sheet.Execute(doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := len(sheet.Diagnostics()); n != 2 {
+		t.Errorf("diagnostics of the last run should be kept in stylesheet! want 2, got %d", n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	sheet, err := xslt.Load("testdata/check-basic/transform.xslt", "")
+	if err != nil {
+		t.Fatalf("error loading stylesheet: %s", err)
+	}
+	err = sheet.Check()
+	if err == nil {
+		t.Fatalf("expected problems but check pass!")
+	}
+	var problems xslt.ProblemList
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected problem list, got %T", err)
+	}
+	want := []string{
+		"missing: attribute set",
+		"$prefix: variable",
+		"count: ",
+		"/root/item[[",
+		"unknown: mode",
+		"header: template",
+		"$items: variable",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems mismatched! want %d, got %d: %s", len(want), len(problems), err)
+	}
+	for i, p := range problems {
+		if !strings.Contains(p.Error(), want[i]) {
+			t.Errorf("problem mismatched! want %q in %q", want[i], p.Error())
+		}
+		if p.Location.Zero() {
+			t.Errorf("%s: location expected", p.Error())
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	const dir = "testdata/apply-templates-mode"
+
+	sheet, err := xslt.Load(filepath.Join(dir, "transform.xslt"), dir)
+	if err != nil {
+		t.Fatalf("error loading stylesheet: %s", err)
+	}
+	cov := sheet.EnableCoverage()
+	for range 2 {
+		doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+		if err != nil {
+			t.Fatalf("error loading document: %s", err)
+		}
+		if err := sheet.Generate(io.Discard, doc); err != nil {
+			t.Fatalf("error executing transform: %s", err)
+		}
+	}
+	report := cov.Report()
+	if report.Runs != 2 {
+		t.Errorf("runs mismatched! want 2, got %d", report.Runs)
+	}
+	want := map[string]int{
+		"/":      2,
+		"item":   0,
+		"alone":  2,
+		"foobar": 0,
+	}
+	if len(report.Templates) != len(want) {
+		t.Fatalf("templates mismatched! want %d, got %d", len(want), len(report.Templates))
+	}
+	for _, tc := range report.Templates {
+		key := tc.Match
+		if tc.Name != "" {
+			key = tc.Name
+		} else if tc.Mode != "" {
+			key = tc.Mode
+		}
+		if tc.Count != want[key] {
+			t.Errorf("%s: count mismatched! want %d, got %d", key, want[key], tc.Count)
+		}
+		if tc.File == "" || tc.Location.Zero() {
+			t.Errorf("%s: source location expected", key)
+		}
+	}
+	if unused := report.Unused(); len(unused) != 2 {
+		t.Errorf("unused templates mismatched! want 2, got %d", len(unused))
+	}
+}
+
+func TestChain(t *testing.T) {
+	const dir = "testdata/chain-basic"
+
+	var sheets []*xslt.Stylesheet
+	for _, file := range []string{"normalize.xslt", "render.xslt"} {
+		sheet, err := xslt.Load(filepath.Join(dir, file), dir)
+		if err != nil {
+			t.Fatalf("%s: error loading stylesheet: %s", file, err)
+		}
+		sheets = append(sheets, sheet)
+	}
+	doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
+	if err != nil {
+		t.Fatalf("error loading document: %s", err)
+	}
+	chain := xslt.Chain(sheets...)
+	if err := chain.SetParamValue("lang", "go"); err != nil {
+		t.Fatalf("error setting parameter: %s", err)
+	}
+	var str bytes.Buffer
+	if err := chain.Generate(&str, doc); err != nil {
+		t.Fatalf("error executing chain: %s", err)
+	}
+	if err := compareBytes(t, filepath.Join(dir, "result.xml"), str.Bytes()); err != nil {
+		t.Errorf("comparing results mismatched")
+	}
+}
+
 func runTests(t *testing.T, tests []TestCase) {
 	t.Helper()
 	for _, tt := range tests {
 		if tt.Context == "" {
 			tt.Context = tt.Dir
 		}
-		fn := executeTest(tt.Name, tt.Dir, tt.Failed)
+		fn := executeTest(tt)
 		t.Run(tt.Name, fn)
 	}
 }
 
-func executeTest(name, dir string, failure bool) func(*testing.T) {
+func executeTest(tt TestCase) func(*testing.T) {
+	var (
+		dir     = tt.Dir
+		failure = tt.Failed
+	)
 	return func(t *testing.T) {
 		doc, err := parseDocument(filepath.Join(dir, "doc.xml"))
 		if err != nil {
@@ -440,6 +941,24 @@ func executeTest(name, dir string, failure bool) func(*testing.T) {
 			t.Errorf("error loading stylesheet: %s", err)
 			return
 		}
+		sheet.Permissive = tt.Permissive
+		for ident, value := range tt.Params {
+			if err := sheet.SetParamValue(ident, value); err != nil {
+				t.Errorf("error setting parameter %s: %s", ident, err)
+				return
+			}
+		}
+		if tt.Output {
+			sheet.OutputBase = t.TempDir() + "/"
+		}
+		if err := sheet.ApplyPolicy(xslt.AllowExtensions(tt.Allow...)); err != nil {
+			if failure {
+				return
+			}
+			t.Errorf("error applying policy: %s", err)
+			return
+		}
+
 		var str bytes.Buffer
 		if err := sheet.Generate(&str, doc); err != nil {
 			if failure {
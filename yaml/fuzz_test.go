@@ -0,0 +1,41 @@
+package yaml
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/midbel/codecs/internal/fuzzing"
+)
+
+func FuzzDecode(f *testing.F) {
+	fuzzing.AddSeeds(f,
+		"name: angle\nlist:\n- a\n- b: c\n",
+		"base: &b {x: 1}\nderived:\n  <<: *b\n  y: 2\n",
+		"text: |\n  line 1\n    line 2\nfolded: >-\n  a\n  b\n",
+		"--- first\n...\n--- [second, 'doc']\n",
+		"\"esc\\ttab\": !!str 123 # comment\n",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzing.Run(t, data, func() error {
+			p, err := NewParser(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			nodes, err := p.ParseAll()
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			ws := NewWriter(&buf)
+			for _, n := range nodes {
+				if _, err := n.Value(); err != nil {
+					return err
+				}
+				if err := ws.Write(n); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,413 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	errSyntax = errors.New("syntax error")
+	errAlias  = errors.New("undefined alias")
+	errType   = errors.New("invalid type")
+)
+
+type Kind int8
+
+const (
+	ScalarNode Kind = iota
+	SequenceNode
+	MappingNode
+	AliasNode
+)
+
+func (k Kind) String() string {
+	switch k {
+	case ScalarNode:
+		return "scalar"
+	case SequenceNode:
+		return "sequence"
+	case MappingNode:
+		return "mapping"
+	case AliasNode:
+		return "alias"
+	default:
+		return "<unknown>"
+	}
+}
+
+type Style int8
+
+const (
+	PlainStyle Style = iota
+	SingleQuotedStyle
+	DoubleQuotedStyle
+	LiteralStyle
+	FoldedStyle
+	FlowStyle
+)
+
+const (
+	tagPrefix = "tag:yaml.org,2002:"
+
+	tagStr   = "str"
+	tagInt   = "int"
+	tagFloat = "float"
+	tagBool  = "bool"
+	tagNull  = "null"
+)
+
+type Node struct {
+	Kind   Kind
+	Style  Style
+	Tag    string
+	Anchor string
+
+	Literal string
+	Nodes   []*Node
+	Alias   *Node
+
+	Comment     string
+	LineComment string
+
+	Line   int
+	Column int
+}
+
+func NodeOf(value any) (*Node, error) {
+	switch v := value.(type) {
+	case *Node:
+		return v, nil
+	case nil:
+		return createScalar("null", PlainStyle), nil
+	case bool:
+		return createScalar(strconv.FormatBool(v), PlainStyle), nil
+	case string:
+		return createString(v), nil
+	case float64:
+		return createScalar(formatFloat(v), PlainStyle), nil
+	case float32:
+		return createScalar(formatFloat(float64(v)), PlainStyle), nil
+	case int:
+		return createScalar(strconv.Itoa(v), PlainStyle), nil
+	case int64:
+		return createScalar(strconv.FormatInt(v, 10), PlainStyle), nil
+	case uint64:
+		return createScalar(strconv.FormatUint(v, 10), PlainStyle), nil
+	case []any:
+		node := Node{
+			Kind: SequenceNode,
+		}
+		if len(v) == 0 {
+			node.Style = FlowStyle
+		}
+		for i := range v {
+			n, err := NodeOf(v[i])
+			if err != nil {
+				return nil, err
+			}
+			node.Nodes = append(node.Nodes, n)
+		}
+		return &node, nil
+	case map[string]any:
+		node := Node{
+			Kind: MappingNode,
+		}
+		if len(v) == 0 {
+			node.Style = FlowStyle
+		}
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			n, err := NodeOf(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Nodes = append(node.Nodes, createString(k), n)
+		}
+		return &node, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported yaml type %T", errType, value)
+	}
+}
+
+func (n *Node) Value() (any, error) {
+	d := decoder{
+		values: make(map[*Node]any),
+	}
+	return d.decode(n)
+}
+
+func (n *Node) ShortTag() string {
+	if n.Tag == "" {
+		return ""
+	}
+	if tag, ok := strings.CutPrefix(n.Tag, "!!"); ok {
+		return tag
+	}
+	if tag, ok := strings.CutPrefix(n.Tag, "!<"+tagPrefix); ok {
+		return strings.TrimSuffix(tag, ">")
+	}
+	if tag, ok := strings.CutPrefix(n.Tag, tagPrefix); ok {
+		return tag
+	}
+	return n.Tag
+}
+
+func (n *Node) isEmpty() bool {
+	return n.Kind == ScalarNode && n.Style == PlainStyle && n.Literal == "" && n.Tag == "" && n.Anchor == ""
+}
+
+func (n *Node) isBlock() bool {
+	switch n.Kind {
+	case SequenceNode, MappingNode:
+		return n.Style != FlowStyle && len(n.Nodes) > 0
+	default:
+		return false
+	}
+}
+
+type decoder struct {
+	values map[*Node]any
+}
+
+func (d decoder) decode(n *Node) (any, error) {
+	switch n.Kind {
+	case AliasNode:
+		if n.Alias == nil {
+			return nil, fmt.Errorf("%w: %s", errAlias, n.Literal)
+		}
+		if v, ok := d.values[n.Alias]; ok {
+			return v, nil
+		}
+		return d.decode(n.Alias)
+	case ScalarNode:
+		return resolveScalar(n)
+	case SequenceNode:
+		arr := make([]any, 0, len(n.Nodes))
+		for _, c := range n.Nodes {
+			v, err := d.decode(c)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		d.values[n] = arr
+		return arr, nil
+	case MappingNode:
+		return d.decodeMapping(n)
+	default:
+		return nil, fmt.Errorf("%w: unknown node kind", errType)
+	}
+}
+
+func (d decoder) decodeMapping(n *Node) (any, error) {
+	var (
+		obj    = make(map[string]any)
+		merges []*Node
+	)
+	for i := 0; i+1 < len(n.Nodes); i += 2 {
+		key, value := n.Nodes[i], n.Nodes[i+1]
+		if isMergeKey(key) {
+			merges = append(merges, value)
+			continue
+		}
+		k, err := d.decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = v
+	}
+	for _, m := range merges {
+		if err := d.merge(obj, m); err != nil {
+			return nil, err
+		}
+	}
+	d.values[n] = obj
+	return obj, nil
+}
+
+func (d decoder) merge(obj map[string]any, node *Node) error {
+	v, err := d.decode(node)
+	if err != nil {
+		return err
+	}
+	var list []any
+	switch v := v.(type) {
+	case map[string]any:
+		list = append(list, v)
+	case []any:
+		list = v
+	default:
+		return fmt.Errorf("%w: merge value should be a mapping or a sequence of mappings", errType)
+	}
+	for _, other := range list {
+		other, ok := other.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%w: merge value should be a mapping or a sequence of mappings", errType)
+		}
+		for k, v := range other {
+			if _, ok := obj[k]; !ok {
+				obj[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+func (d decoder) decodeKey(n *Node) (string, error) {
+	if n.Kind == AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if n.Kind == ScalarNode {
+		return n.Literal, nil
+	}
+	v, err := d.decode(n)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(v), nil
+}
+
+func isMergeKey(n *Node) bool {
+	return n.Kind == ScalarNode && n.Style == PlainStyle && n.Literal == "<<" && n.Tag == ""
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+func resolveScalar(n *Node) (any, error) {
+	switch tag := n.ShortTag(); tag {
+	case "":
+		if n.Style != PlainStyle {
+			return n.Literal, nil
+		}
+		return resolvePlain(n.Literal), nil
+	case tagStr:
+		return n.Literal, nil
+	case tagNull:
+		return nil, nil
+	case tagBool:
+		if v, ok := resolvePlain(n.Literal).(bool); ok {
+			return v, nil
+		}
+	case tagInt, tagFloat:
+		if v, ok := resolvePlain(n.Literal).(float64); ok {
+			return v, nil
+		}
+	default:
+		return resolvePlain(n.Literal), nil
+	}
+	return nil, fmt.Errorf("%w: %q can not be used as %s", errType, n.Literal, n.ShortTag())
+}
+
+func resolvePlain(str string) any {
+	switch str {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	if v, ok := strings.CutPrefix(str, "0x"); ok {
+		if n, err := strconv.ParseUint(v, 16, 64); err == nil {
+			return float64(n)
+		}
+		return str
+	}
+	if v, ok := strings.CutPrefix(str, "0o"); ok {
+		if n, err := strconv.ParseUint(v, 8, 64); err == nil {
+			return float64(n)
+		}
+		return str
+	}
+	if intPattern.MatchString(str) || floatPattern.MatchString(str) {
+		if n, err := strconv.ParseFloat(str, 64); err == nil {
+			return n
+		}
+	}
+	return str
+}
+
+func createScalar(str string, style Style) *Node {
+	return &Node{
+		Kind:    ScalarNode,
+		Style:   style,
+		Literal: str,
+	}
+}
+
+func createString(str string) *Node {
+	switch {
+	case !needQuote(str):
+		return createScalar(str, PlainStyle)
+	case strings.Contains(str, "\n") && canBlock(str):
+		return createScalar(str, LiteralStyle)
+	default:
+		return createScalar(str, DoubleQuotedStyle)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	default:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+}
+
+func needQuote(str string) bool {
+	if str == "" || str != strings.TrimSpace(str) {
+		return true
+	}
+	if _, ok := resolvePlain(str).(string); !ok {
+		return true
+	}
+	if strings.ContainsAny(str[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.HasPrefix(str, "...") || strings.Contains(str, ": ") || strings.Contains(str, " #") || strings.HasSuffix(str, ":") {
+		return true
+	}
+	return strings.ContainsFunc(str, func(r rune) bool {
+		return r < 0x20 || r == 0x7f || r == '\uFEFF'
+	})
+}
+
+func canBlock(str string) bool {
+	if str == "" || str[0] == ' ' || str[0] == '\n' {
+		return false
+	}
+	for _, line := range strings.Split(str, "\n") {
+		if line != strings.TrimRight(line, " \t") {
+			return false
+		}
+		if strings.ContainsFunc(line, func(r rune) bool {
+			return (r < 0x20 && r != '\t') || r == 0x7f
+		}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,997 @@
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func Decode(r io.Reader) (any, error) {
+	p, err := NewParser(r)
+	if err != nil {
+		return nil, err
+	}
+	node, err := p.Parse()
+	if err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		return nil, err
+	}
+	return node.Value()
+}
+
+func DecodeAll(r io.Reader) ([]any, error) {
+	p, err := NewParser(r)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := p.ParseAll()
+	if err != nil {
+		return nil, err
+	}
+	var list []any
+	for _, n := range nodes {
+		v, err := n.Value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type position struct {
+	offset int
+	line   int
+	column int
+}
+
+type Parser struct {
+	input string
+	position
+
+	anchors  map[string]*Node
+	comments []string
+}
+
+func NewParser(r io.Reader) (*Parser, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	buf = bytes.TrimPrefix(buf, []byte("\uFEFF"))
+	buf = bytes.ReplaceAll(buf, []byte("\r\n"), []byte("\n"))
+	p := Parser{
+		input: string(buf),
+		position: position{
+			line: 1,
+		},
+	}
+	return &p, nil
+}
+
+func (p *Parser) ParseAll() ([]*Node, error) {
+	var list []*Node
+	for {
+		n, err := p.Parse()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+func (p *Parser) Parse() (*Node, error) {
+	p.anchors = make(map[string]*Node)
+	p.comments = p.comments[:0]
+
+	p.skipBlank()
+	for p.column == 0 && p.peek() == '%' {
+		p.skipLine()
+		p.skipBlank()
+	}
+	for p.atMarker("...") {
+		p.skip(3)
+		p.skipBlank()
+	}
+	explicit := p.atMarker("---")
+	if explicit {
+		p.skip(3)
+	}
+	if p.done() && !explicit {
+		return nil, io.EOF
+	}
+	node, err := p.parseBlockNode(-1, false)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.atMarker("...") {
+		p.skip(3)
+		p.skipBlank()
+	}
+	if !p.done() && !p.atMarker("---") {
+		return nil, p.syntaxError("unexpected content at end of document")
+	}
+	return node, nil
+}
+
+func (p *Parser) parseBlockNode(parent int, compact bool) (*Node, error) {
+	p.skipBlank()
+	if !p.canStart(parent, compact) {
+		return p.emptyNode(), nil
+	}
+	line := p.line
+	anchor, tag, err := p.parseProperties(false)
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" || tag != "" {
+		if p.atEOL() {
+			p.skipBlank()
+		}
+	}
+	var node *Node
+	if !p.canStart(parent, compact) {
+		node = p.emptyNode()
+	} else {
+		node, err = p.parseContent(parent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	target := node
+	if node.Kind == MappingNode && node.Style != FlowStyle && node.Line == line && len(node.Nodes) > 0 {
+		target = node.Nodes[0]
+	}
+	if anchor != "" {
+		target.Anchor = anchor
+		p.anchors[anchor] = target
+	}
+	if tag != "" {
+		target.Tag = tag
+	}
+	return node, nil
+}
+
+func (p *Parser) parseContent(parent int) (*Node, error) {
+	col := p.column
+	if p.isSeqEntry() {
+		return p.parseBlockSequence(col)
+	}
+	if c := p.peek(); c == '|' || c == '>' {
+		node, err := p.parseBlockScalar(parent)
+		if err == nil {
+			node.Comment = p.takeComments()
+		}
+		return node, err
+	}
+	comment := p.takeComments()
+	node, err := p.parseInline(parent)
+	if err != nil {
+		return nil, err
+	}
+	if p.isMappingValue() {
+		node.Comment = comment
+		return p.parseBlockMapping(col, node)
+	}
+	node.Comment = comment
+	node.LineComment = p.scanLineComment()
+	return node, nil
+}
+
+func (p *Parser) parseBlockSequence(col int) (*Node, error) {
+	seq := Node{
+		Kind:   SequenceNode,
+		Line:   p.line,
+		Column: p.column,
+	}
+	for {
+		comment := p.takeComments()
+		p.skip(1)
+		item, err := p.parseBlockNode(col, false)
+		if err != nil {
+			return nil, err
+		}
+		item.Comment = joinComments(comment, item.Comment)
+		seq.Nodes = append(seq.Nodes, item)
+
+		p.skipBlank()
+		if p.done() || p.atDocumentMarker() || p.column != col || !p.isSeqEntry() {
+			break
+		}
+	}
+	return &seq, nil
+}
+
+func (p *Parser) parseBlockMapping(col int, key *Node) (*Node, error) {
+	mapping := Node{
+		Kind:   MappingNode,
+		Line:   key.Line,
+		Column: key.Column,
+	}
+	for {
+		p.skipSpaces()
+		p.skip(1)
+		p.skipSpaces()
+		key.LineComment = p.scanLineComment()
+
+		value, err := p.parseBlockNode(col, true)
+		if err != nil {
+			return nil, err
+		}
+		mapping.Nodes = append(mapping.Nodes, key, value)
+
+		p.skipBlank()
+		if p.done() || p.atDocumentMarker() || p.column < col {
+			break
+		}
+		if p.column > col {
+			return nil, p.syntaxError("bad indentation of mapping entry")
+		}
+		if p.isSeqEntry() {
+			break
+		}
+		if key, err = p.parseKey(); err != nil {
+			return nil, err
+		}
+		if !p.isMappingValue() {
+			return nil, p.syntaxError("missing ':' after mapping key")
+		}
+	}
+	return &mapping, nil
+}
+
+func (p *Parser) parseKey() (*Node, error) {
+	comment := p.takeComments()
+	anchor, tag, err := p.parseProperties(false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.parseInline(p.column)
+	if err != nil {
+		return nil, err
+	}
+	key.Anchor = anchor
+	key.Tag = tag
+	key.Comment = comment
+	if anchor != "" {
+		p.anchors[anchor] = key
+	}
+	return key, nil
+}
+
+func (p *Parser) parseProperties(flow bool) (string, string, error) {
+	var anchor, tag string
+	for {
+		var err error
+		switch p.peek() {
+		case '&':
+			anchor, err = p.scanAnchor()
+		case '!':
+			tag, err = p.scanTag(flow)
+		default:
+			return anchor, tag, nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if flow {
+			p.skipFlowBlank()
+		} else {
+			p.skipSpaces()
+		}
+	}
+}
+
+func (p *Parser) parseInline(parent int) (*Node, error) {
+	switch p.peek() {
+	case '[':
+		return p.parseFlowSequence()
+	case '{':
+		return p.parseFlowMapping()
+	case '*':
+		return p.parseAlias()
+	case '"':
+		return p.parseDoubleQuoted()
+	case '\'':
+		return p.parseSingleQuoted()
+	default:
+		return p.parsePlain(parent, false)
+	}
+}
+
+func (p *Parser) parseFlowNode() (*Node, error) {
+	p.skipFlowBlank()
+	anchor, tag, err := p.parseProperties(true)
+	if err != nil {
+		return nil, err
+	}
+	var node *Node
+	switch p.peek() {
+	case '[':
+		node, err = p.parseFlowSequence()
+	case '{':
+		node, err = p.parseFlowMapping()
+	case '*':
+		node, err = p.parseAlias()
+	case '"':
+		node, err = p.parseDoubleQuoted()
+	case '\'':
+		node, err = p.parseSingleQuoted()
+	case ',', ']', '}', ':':
+		node = p.emptyNode()
+	default:
+		node, err = p.parsePlain(-1, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		node.Anchor = anchor
+		p.anchors[anchor] = node
+	}
+	node.Tag = tag
+	return node, nil
+}
+
+func (p *Parser) parseFlowSequence() (*Node, error) {
+	seq := Node{
+		Kind:   SequenceNode,
+		Style:  FlowStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	p.skip(1)
+	for {
+		p.skipFlowBlank()
+		if p.done() {
+			return nil, p.syntaxError("missing ']' at end of sequence")
+		}
+		if p.peek() == ']' {
+			p.skip(1)
+			break
+		}
+		item, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		p.skipFlowBlank()
+		if p.peek() == ':' {
+			p.skip(1)
+			value, err := p.parseFlowNode()
+			if err != nil {
+				return nil, err
+			}
+			item = &Node{
+				Kind:   MappingNode,
+				Style:  FlowStyle,
+				Nodes:  []*Node{item, value},
+				Line:   item.Line,
+				Column: item.Column,
+			}
+			p.skipFlowBlank()
+		}
+		seq.Nodes = append(seq.Nodes, item)
+		switch p.peek() {
+		case ',':
+			p.skip(1)
+		case ']':
+		default:
+			return nil, p.syntaxError("expected ',' or ']'")
+		}
+	}
+	return &seq, nil
+}
+
+func (p *Parser) parseFlowMapping() (*Node, error) {
+	mapping := Node{
+		Kind:   MappingNode,
+		Style:  FlowStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	p.skip(1)
+	for {
+		p.skipFlowBlank()
+		if p.done() {
+			return nil, p.syntaxError("missing '}' at end of mapping")
+		}
+		if p.peek() == '}' {
+			p.skip(1)
+			break
+		}
+		key, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		p.skipFlowBlank()
+		value := p.emptyNode()
+		if p.peek() == ':' {
+			p.skip(1)
+			if value, err = p.parseFlowNode(); err != nil {
+				return nil, err
+			}
+			p.skipFlowBlank()
+		}
+		mapping.Nodes = append(mapping.Nodes, key, value)
+		switch p.peek() {
+		case ',':
+			p.skip(1)
+		case '}':
+		default:
+			return nil, p.syntaxError("expected ',' or '}'")
+		}
+	}
+	return &mapping, nil
+}
+
+func (p *Parser) parseAlias() (*Node, error) {
+	node := Node{
+		Kind:   AliasNode,
+		Line:   p.line,
+		Column: p.column,
+	}
+	p.skip(1)
+	node.Literal = p.scanName()
+	if node.Literal == "" {
+		return nil, p.syntaxError("missing alias name")
+	}
+	alias, ok := p.anchors[node.Literal]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s (line %d)", errAlias, node.Literal, node.Line)
+	}
+	node.Alias = alias
+	return &node, nil
+}
+
+func (p *Parser) parsePlain(parent int, flow bool) (*Node, error) {
+	node := Node{
+		Kind:   ScalarNode,
+		Style:  PlainStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	if !p.canStartPlain(flow) {
+		return nil, p.syntaxError(fmt.Sprintf("unexpected character %q", p.peek()))
+	}
+	var str strings.Builder
+	str.WriteString(p.scanPlainLine(flow))
+	for {
+		save := p.position
+		p.skipSpaces()
+		if p.peek() != '\n' {
+			p.position = save
+			break
+		}
+		var empty int
+		for {
+			p.skip(1)
+			p.skipSpaces()
+			if p.peek() != '\n' {
+				break
+			}
+			empty++
+		}
+		if p.done() || p.peek() == '#' || p.atDocumentMarker() || (!flow && p.column <= parent) || !p.canStartPlain(flow) {
+			p.position = save
+			break
+		}
+		line := p.scanPlainLine(flow)
+		if line == "" {
+			p.position = save
+			break
+		}
+		if empty == 0 {
+			str.WriteByte(' ')
+		} else {
+			str.WriteString(strings.Repeat("\n", empty))
+		}
+		str.WriteString(line)
+	}
+	node.Literal = str.String()
+	return &node, nil
+}
+
+func (p *Parser) scanPlainLine(flow bool) string {
+	beg := p.offset
+	for !p.done() {
+		c := p.peek()
+		if c == '\n' {
+			break
+		}
+		if c == ':' && (isBlank(p.peekAt(1)) || (flow && isFlowIndicator(p.peekAt(1)))) {
+			break
+		}
+		if c == '#' && p.offset > beg && isBlank(p.input[p.offset-1]) {
+			break
+		}
+		if flow && isFlowIndicator(c) {
+			break
+		}
+		p.skip(1)
+	}
+	return strings.TrimRight(p.input[beg:p.offset], " \t")
+}
+
+func (p *Parser) canStartPlain(flow bool) bool {
+	c := p.peek()
+	switch c {
+	case '-', '?', ':':
+		next := p.peekAt(1)
+		return !isBlank(next) && !(flow && isFlowIndicator(next))
+	case ',', '[', ']', '{', '}', '#', '&', '*', '!', '|', '>', '\'', '"', '%', '@', '`':
+		return false
+	default:
+		return !isBlank(c)
+	}
+}
+
+func (p *Parser) parseSingleQuoted() (*Node, error) {
+	node := Node{
+		Kind:   ScalarNode,
+		Style:  SingleQuotedStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	p.skip(1)
+	var buf []byte
+	for {
+		if p.done() {
+			return nil, p.syntaxError("unterminated single quoted string")
+		}
+		switch c := p.peek(); c {
+		case '\'':
+			p.skip(1)
+			if p.peek() != '\'' {
+				node.Literal = string(buf)
+				return &node, nil
+			}
+			buf = append(buf, c)
+			p.skip(1)
+		case '\n':
+			buf = p.foldLine(buf)
+		default:
+			buf = append(buf, c)
+			p.skip(1)
+		}
+	}
+}
+
+func (p *Parser) parseDoubleQuoted() (*Node, error) {
+	node := Node{
+		Kind:   ScalarNode,
+		Style:  DoubleQuotedStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	p.skip(1)
+	var buf []byte
+	for {
+		if p.done() {
+			return nil, p.syntaxError("unterminated double quoted string")
+		}
+		switch c := p.peek(); c {
+		case '"':
+			p.skip(1)
+			node.Literal = string(buf)
+			return &node, nil
+		case '\\':
+			p.skip(1)
+			if p.peek() == '\n' {
+				p.skip(1)
+				p.skipSpaces()
+				continue
+			}
+			var err error
+			if buf, err = p.scanEscape(buf); err != nil {
+				return nil, err
+			}
+		case '\n':
+			buf = p.foldLine(buf)
+		default:
+			buf = append(buf, c)
+			p.skip(1)
+		}
+	}
+}
+
+func (p *Parser) foldLine(buf []byte) []byte {
+	buf = bytes.TrimRight(buf, " \t")
+	var count int
+	for p.peek() == '\n' {
+		p.skip(1)
+		p.skipSpaces()
+		count++
+	}
+	if count == 1 {
+		return append(buf, ' ')
+	}
+	return append(buf, strings.Repeat("\n", count-1)...)
+}
+
+func (p *Parser) scanEscape(buf []byte) ([]byte, error) {
+	c := p.peek()
+	p.skip(1)
+	switch c {
+	case '0':
+		return append(buf, 0), nil
+	case 'a':
+		return append(buf, '\a'), nil
+	case 'b':
+		return append(buf, '\b'), nil
+	case 't', '\t':
+		return append(buf, '\t'), nil
+	case 'n':
+		return append(buf, '\n'), nil
+	case 'v':
+		return append(buf, '\v'), nil
+	case 'f':
+		return append(buf, '\f'), nil
+	case 'r':
+		return append(buf, '\r'), nil
+	case 'e':
+		return append(buf, 0x1b), nil
+	case ' ', '"', '/', '\\':
+		return append(buf, c), nil
+	case 'N':
+		return utf8.AppendRune(buf, '\u0085'), nil
+	case '_':
+		return utf8.AppendRune(buf, '\u00a0'), nil
+	case 'L':
+		return utf8.AppendRune(buf, '\u2028'), nil
+	case 'P':
+		return utf8.AppendRune(buf, '\u2029'), nil
+	case 'x':
+		return p.scanCodePoint(buf, 2)
+	case 'u':
+		return p.scanCodePoint(buf, 4)
+	case 'U':
+		return p.scanCodePoint(buf, 8)
+	default:
+		return nil, p.syntaxError(fmt.Sprintf("invalid escape sequence \\%c", c))
+	}
+}
+
+func (p *Parser) scanCodePoint(buf []byte, size int) ([]byte, error) {
+	if p.offset+size > len(p.input) {
+		return nil, p.syntaxError("invalid escape sequence")
+	}
+	n, err := strconv.ParseUint(p.input[p.offset:p.offset+size], 16, 32)
+	if err != nil {
+		return nil, p.syntaxError("invalid escape sequence")
+	}
+	p.skip(size)
+	return utf8.AppendRune(buf, rune(n)), nil
+}
+
+func (p *Parser) parseBlockScalar(parent int) (*Node, error) {
+	node := Node{
+		Kind:   ScalarNode,
+		Style:  LiteralStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+	if p.peek() == '>' {
+		node.Style = FoldedStyle
+	}
+	p.skip(1)
+
+	var (
+		chomp  byte
+		indent int
+	)
+	for i := 0; i < 2; i++ {
+		switch c := p.peek(); {
+		case c == '+' || c == '-':
+			chomp = c
+			p.skip(1)
+		case c >= '1' && c <= '9':
+			indent = max(parent, 0) + int(c-'0')
+			p.skip(1)
+		}
+	}
+	p.skipSpaces()
+	node.LineComment = p.scanLineComment()
+	if !p.atEOL() {
+		return nil, p.syntaxError("unexpected content after block scalar header")
+	}
+	p.skip(1)
+
+	if indent == 0 {
+		indent = p.detectIndent()
+		if indent <= parent {
+			indent = parent + 1
+		}
+	}
+	var lines []string
+	for !p.done() {
+		save := p.position
+		for p.column < indent && p.peek() == ' ' {
+			p.skip(1)
+		}
+		if p.done() || p.peek() == '\n' {
+			lines = append(lines, "")
+			p.skip(1)
+			continue
+		}
+		if p.column < indent || p.atDocumentMarker() {
+			p.position = save
+			break
+		}
+		beg := p.offset
+		p.skipLine()
+		lines = append(lines, p.input[beg:p.offset])
+		p.skip(1)
+	}
+	node.Literal = blockValue(lines, node.Style == FoldedStyle, chomp)
+	return &node, nil
+}
+
+func (p *Parser) detectIndent() int {
+	var (
+		save   = p.position
+		indent int
+	)
+	defer func() {
+		p.position = save
+	}()
+	for !p.done() {
+		p.skipSpaces()
+		if p.peek() != '\n' {
+			indent = p.column
+			break
+		}
+		p.skip(1)
+	}
+	return indent
+}
+
+func blockValue(lines []string, folded bool, chomp byte) string {
+	var trailing int
+	for i := len(lines) - 1; i >= 0 && lines[i] == ""; i-- {
+		trailing++
+	}
+	lines = lines[:len(lines)-trailing]
+	if len(lines) == 0 {
+		if chomp == '+' {
+			return strings.Repeat("\n", trailing)
+		}
+		return ""
+	}
+	var str string
+	if folded {
+		str = foldLines(lines)
+	} else {
+		str = strings.Join(lines, "\n")
+	}
+	switch chomp {
+	case '-':
+	case '+':
+		str += "\n" + strings.Repeat("\n", trailing)
+	default:
+		str += "\n"
+	}
+	return str
+}
+
+func foldLines(lines []string) string {
+	var (
+		buf    strings.Builder
+		first  = true
+		normal bool
+		empty  int
+	)
+	for _, line := range lines {
+		if line == "" {
+			empty++
+			continue
+		}
+		more := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case first:
+			buf.WriteString(strings.Repeat("\n", empty))
+		case normal && !more && empty == 0:
+			buf.WriteByte(' ')
+		case normal && !more:
+			buf.WriteString(strings.Repeat("\n", empty))
+		default:
+			buf.WriteString(strings.Repeat("\n", empty+1))
+		}
+		buf.WriteString(line)
+		first, normal, empty = false, !more, 0
+	}
+	return buf.String()
+}
+
+func (p *Parser) scanAnchor() (string, error) {
+	p.skip(1)
+	name := p.scanName()
+	if name == "" {
+		return "", p.syntaxError("missing anchor name")
+	}
+	return name, nil
+}
+
+func (p *Parser) scanTag(flow bool) (string, error) {
+	beg := p.offset
+	if p.peekAt(1) == '<' {
+		ix := strings.IndexByte(p.input[p.offset:], '>')
+		if ix < 0 {
+			return "", p.syntaxError("unterminated verbatim tag")
+		}
+		p.skip(ix + 1)
+		return p.input[beg:p.offset], nil
+	}
+	for !p.done() && !isBlank(p.peek()) && !(flow && isFlowIndicator(p.peek())) {
+		p.skip(1)
+	}
+	return p.input[beg:p.offset], nil
+}
+
+func (p *Parser) scanName() string {
+	beg := p.offset
+	for !p.done() && !isBlank(p.peek()) && !isFlowIndicator(p.peek()) {
+		p.skip(1)
+	}
+	return p.input[beg:p.offset]
+}
+
+func (p *Parser) scanLineComment() string {
+	save := p.position
+	p.skipSpaces()
+	if p.peek() != '#' {
+		p.position = save
+		return ""
+	}
+	return p.scanComment()
+}
+
+func (p *Parser) scanComment() string {
+	p.skip(1)
+	beg := p.offset
+	for !p.done() && p.peek() != '\n' {
+		p.skip(1)
+	}
+	return strings.TrimSpace(p.input[beg:p.offset])
+}
+
+func (p *Parser) emptyNode() *Node {
+	return &Node{
+		Kind:   ScalarNode,
+		Style:  PlainStyle,
+		Line:   p.line,
+		Column: p.column,
+	}
+}
+
+func (p *Parser) canStart(parent int, compact bool) bool {
+	if p.done() || p.atDocumentMarker() {
+		return false
+	}
+	return p.column > parent || (compact && p.column == parent && p.isSeqEntry())
+}
+
+func (p *Parser) isSeqEntry() bool {
+	return p.peek() == '-' && isBlank(p.peekAt(1))
+}
+
+func (p *Parser) isMappingValue() bool {
+	save := p.position
+	defer func() {
+		p.position = save
+	}()
+	p.skipSpaces()
+	return p.peek() == ':' && isBlank(p.peekAt(1))
+}
+
+func (p *Parser) atMarker(marker string) bool {
+	if p.column != 0 || !strings.HasPrefix(p.input[p.offset:], marker) {
+		return false
+	}
+	return isBlank(p.peekAt(len(marker)))
+}
+
+func (p *Parser) atDocumentMarker() bool {
+	return p.atMarker("---") || p.atMarker("...")
+}
+
+func (p *Parser) atEOL() bool {
+	c := p.peek()
+	return p.done() || c == '\n' || c == '#'
+}
+
+func (p *Parser) takeComments() string {
+	str := strings.Join(p.comments, "\n")
+	p.comments = p.comments[:0]
+	return str
+}
+
+func (p *Parser) skipBlank() {
+	for !p.done() {
+		p.skipSpaces()
+		switch p.peek() {
+		case '#':
+			p.comments = append(p.comments, p.scanComment())
+		case '\n':
+			p.skip(1)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Parser) skipFlowBlank() {
+	n := len(p.comments)
+	p.skipBlank()
+	p.comments = p.comments[:n]
+}
+
+func (p *Parser) skipSpaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.skip(1)
+	}
+}
+
+func (p *Parser) skipLine() {
+	for !p.done() && p.peek() != '\n' {
+		p.skip(1)
+	}
+}
+
+func (p *Parser) skip(n int) {
+	for ; n > 0 && !p.done(); n-- {
+		if p.input[p.offset] == '\n' {
+			p.line++
+			p.column = 0
+		} else {
+			p.column++
+		}
+		p.offset++
+	}
+}
+
+func (p *Parser) peek() byte {
+	return p.peekAt(0)
+}
+
+func (p *Parser) peekAt(n int) byte {
+	if p.offset+n >= len(p.input) {
+		return 0
+	}
+	return p.input[p.offset+n]
+}
+
+func (p *Parser) done() bool {
+	return p.offset >= len(p.input)
+}
+
+func (p *Parser) syntaxError(msg string) error {
+	return fmt.Errorf("%w: %s (line %d, column %d)", errSyntax, msg, p.line, p.column+1)
+}
+
+func joinComments(comments ...string) string {
+	var list []string
+	for _, c := range comments {
+		if c != "" {
+			list = append(list, c)
+		}
+	}
+	return strings.Join(list, "\n")
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == 0
+}
+
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
@@ -0,0 +1,342 @@
+package yaml
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+type Writer struct {
+	ws *bufio.Writer
+
+	Indent        string
+	ExplicitStart bool
+	NoComment     bool
+
+	count int
+}
+
+func NewWriter(w io.Writer) *Writer {
+	ws := Writer{
+		ws:     bufio.NewWriter(w),
+		Indent: "  ",
+	}
+	return &ws
+}
+
+func (w *Writer) Write(value any) error {
+	node, err := NodeOf(value)
+	if err != nil {
+		return err
+	}
+	defer w.ws.Flush()
+	if w.count > 0 || w.ExplicitStart {
+		w.ws.WriteString("---\n")
+	}
+	w.count++
+
+	if node.isBlock() {
+		w.writeBlock(node, 0, false)
+		return nil
+	}
+	w.writeComment(node.Comment, 0)
+	w.writeProperties(node)
+	if node.Anchor != "" || node.Tag != "" {
+		w.ws.WriteByte(' ')
+	}
+	w.writeScalar(node, 0)
+	return nil
+}
+
+func (w *Writer) writeBlock(node *Node, depth int, inline bool) {
+	switch node.Kind {
+	case MappingNode:
+		w.writeMapping(node, depth, inline)
+	case SequenceNode:
+		w.writeSequence(node, depth, inline)
+	}
+}
+
+func (w *Writer) writeMapping(node *Node, depth int, inline bool) {
+	if !inline {
+		w.writeComment(node.Comment, depth)
+	}
+	for i := 0; i+1 < len(node.Nodes); i += 2 {
+		key, value := node.Nodes[i], node.Nodes[i+1]
+		if !inline || i > 0 {
+			w.writeComment(key.Comment, depth)
+			w.writePrefix(depth)
+		}
+		w.writeProperties(key)
+		if key.Anchor != "" || key.Tag != "" {
+			w.ws.WriteByte(' ')
+		}
+		w.writeKey(key)
+		w.ws.WriteByte(':')
+		w.writeValue(value, key.LineComment, depth, false)
+	}
+}
+
+func (w *Writer) writeSequence(node *Node, depth int, inline bool) {
+	if !inline {
+		w.writeComment(node.Comment, depth)
+	}
+	for i, item := range node.Nodes {
+		if !inline || i > 0 {
+			w.writeComment(item.Comment, depth)
+			w.writePrefix(depth)
+		}
+		w.ws.WriteByte('-')
+		w.writeValue(item, "", depth, true)
+	}
+}
+
+func (w *Writer) writeValue(node *Node, comment string, depth int, entry bool) {
+	if node.isBlock() {
+		if node.Anchor != "" || node.Tag != "" {
+			w.ws.WriteByte(' ')
+			w.writeProperties(node)
+			w.writeLineComment(comment)
+			w.ws.WriteByte('\n')
+			w.writeBlock(node, depth+1, false)
+			return
+		}
+		if entry {
+			w.ws.WriteByte(' ')
+			w.writeBlock(node, depth+1, true)
+			return
+		}
+		w.writeLineComment(comment)
+		w.ws.WriteByte('\n')
+		w.writeBlock(node, depth+1, false)
+		return
+	}
+	if node.isEmpty() {
+		w.writeLineComment(joinComments(comment, node.LineComment))
+		w.ws.WriteByte('\n')
+		return
+	}
+	w.ws.WriteByte(' ')
+	w.writeProperties(node)
+	if node.Anchor != "" || node.Tag != "" {
+		w.ws.WriteByte(' ')
+	}
+	w.writeScalar(node, depth)
+}
+
+func (w *Writer) writeScalar(node *Node, depth int) {
+	switch {
+	case node.Kind == AliasNode:
+		w.ws.WriteByte('*')
+		w.ws.WriteString(node.Literal)
+	case node.Kind != ScalarNode:
+		w.writeFlow(node)
+	case node.Style == LiteralStyle || node.Style == FoldedStyle:
+		if canBlock(node.Literal) {
+			w.writeBlockScalar(node, depth)
+			return
+		}
+		w.writeDoubleQuoted(node.Literal)
+	default:
+		w.writeInline(node)
+	}
+	w.writeLineComment(node.LineComment)
+	w.ws.WriteByte('\n')
+}
+
+func (w *Writer) writeBlockScalar(node *Node, depth int) {
+	var (
+		str   = node.Literal
+		chomp string
+	)
+	switch {
+	case !strings.HasSuffix(str, "\n"):
+		chomp = "-"
+	case strings.HasSuffix(str, "\n\n"):
+		chomp = "+"
+		str = strings.TrimSuffix(str, "\n")
+	default:
+		str = strings.TrimSuffix(str, "\n")
+	}
+	if node.Style == FoldedStyle {
+		w.ws.WriteByte('>')
+	} else {
+		w.ws.WriteByte('|')
+	}
+	w.ws.WriteString(chomp)
+	w.writeLineComment(node.LineComment)
+	w.ws.WriteByte('\n')
+
+	var (
+		lines  = strings.Split(str, "\n")
+		normal bool
+	)
+	for _, line := range lines {
+		if line == "" {
+			w.ws.WriteByte('\n')
+			continue
+		}
+		more := line[0] == ' ' || line[0] == '\t'
+		if node.Style == FoldedStyle && normal && !more {
+			w.ws.WriteByte('\n')
+		}
+		w.writePrefix(depth + 1)
+		w.ws.WriteString(line)
+		w.ws.WriteByte('\n')
+		normal = !more
+	}
+}
+
+func (w *Writer) writeFlow(node *Node) {
+	switch node.Kind {
+	case SequenceNode:
+		w.ws.WriteByte('[')
+		for i, n := range node.Nodes {
+			if i > 0 {
+				w.ws.WriteString(", ")
+			}
+			w.writeFlowNode(n)
+		}
+		w.ws.WriteByte(']')
+	case MappingNode:
+		w.ws.WriteByte('{')
+		for i := 0; i+1 < len(node.Nodes); i += 2 {
+			if i > 0 {
+				w.ws.WriteString(", ")
+			}
+			w.writeFlowNode(node.Nodes[i])
+			w.ws.WriteString(": ")
+			w.writeFlowNode(node.Nodes[i+1])
+		}
+		w.ws.WriteByte('}')
+	case AliasNode:
+		w.ws.WriteByte('*')
+		w.ws.WriteString(node.Literal)
+	default:
+		if node.Style == LiteralStyle || node.Style == FoldedStyle || (node.Style == PlainStyle && strings.ContainsAny(node.Literal, ",[]{}")) {
+			w.writeDoubleQuoted(node.Literal)
+			return
+		}
+		w.writeInline(node)
+	}
+}
+
+func (w *Writer) writeFlowNode(node *Node) {
+	w.writeProperties(node)
+	if node.Anchor != "" || node.Tag != "" {
+		w.ws.WriteByte(' ')
+	}
+	w.writeFlow(node)
+}
+
+func (w *Writer) writeKey(node *Node) {
+	switch {
+	case node.Kind != ScalarNode:
+		w.writeFlow(node)
+	case node.Style == LiteralStyle || node.Style == FoldedStyle:
+		w.writeDoubleQuoted(node.Literal)
+	default:
+		w.writeInline(node)
+	}
+}
+
+func (w *Writer) writeInline(node *Node) {
+	switch node.Style {
+	case SingleQuotedStyle:
+		if strings.Contains(node.Literal, "\n") {
+			w.writeDoubleQuoted(node.Literal)
+			break
+		}
+		w.ws.WriteByte('\'')
+		w.ws.WriteString(strings.ReplaceAll(node.Literal, "'", "''"))
+		w.ws.WriteByte('\'')
+	case DoubleQuotedStyle:
+		w.writeDoubleQuoted(node.Literal)
+	default:
+		if node.Literal == "" {
+			w.ws.WriteString("null")
+			break
+		}
+		if strings.Contains(node.Literal, "\n") {
+			w.writeDoubleQuoted(node.Literal)
+			break
+		}
+		w.ws.WriteString(node.Literal)
+	}
+}
+
+func (w *Writer) writeDoubleQuoted(str string) {
+	w.ws.WriteByte('"')
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		i += size
+		switch {
+		case r == '"':
+			w.ws.WriteString(`\"`)
+		case r == '\\':
+			w.ws.WriteString(`\\`)
+		case r == '\n':
+			w.ws.WriteString(`\n`)
+		case r == '\r':
+			w.ws.WriteString(`\r`)
+		case r == '\t':
+			w.ws.WriteString(`\t`)
+		case r == 0:
+			w.ws.WriteString(`\0`)
+		case r == utf8.RuneError && size == 1:
+			w.ws.WriteString(`\ufffd`)
+		case r < 0x20 || r == 0x7f:
+			w.ws.WriteString(`\x`)
+			w.ws.WriteByte(hexDigits[r>>4])
+			w.ws.WriteByte(hexDigits[r&0xF])
+		case r == '\u0085' || r == '\u2028' || r == '\u2029' || r == '\uFEFF':
+			w.ws.WriteString(`\u`)
+			for shift := 12; shift >= 0; shift -= 4 {
+				w.ws.WriteByte(hexDigits[(r>>shift)&0xF])
+			}
+		default:
+			w.ws.WriteRune(r)
+		}
+	}
+	w.ws.WriteByte('"')
+}
+
+func (w *Writer) writeProperties(node *Node) {
+	if node.Anchor != "" {
+		w.ws.WriteByte('&')
+		w.ws.WriteString(node.Anchor)
+	}
+	if node.Tag != "" {
+		if node.Anchor != "" {
+			w.ws.WriteByte(' ')
+		}
+		w.ws.WriteString(node.Tag)
+	}
+}
+
+func (w *Writer) writeComment(comment string, depth int) {
+	if w.NoComment || comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		w.writePrefix(depth)
+		w.ws.WriteString(strings.TrimSpace("# " + line))
+		w.ws.WriteByte('\n')
+	}
+}
+
+func (w *Writer) writeLineComment(comment string) {
+	if w.NoComment || comment == "" {
+		return
+	}
+	comment = strings.ReplaceAll(comment, "\n", " ")
+	fmt.Fprintf(w.ws, " # %s", comment)
+}
+
+func (w *Writer) writePrefix(depth int) {
+	w.ws.WriteString(strings.Repeat(w.Indent, depth))
+}